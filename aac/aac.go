@@ -1,31 +1,70 @@
 package aac
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 
 	"github.com/jaypadia-frame/mp4ff/bits"
 )
 
 const (
+	// AACMain - AAC Main
+	AACMain = 1
 	// AAClc - AAC-LC Low Complexity
 	AAClc = 2
 	// HEAACv1 - HE-AAC version 1 with SBR
 	HEAACv1 = 5
+	// AACLD - ER AAC LD Low Delay
+	AACLD = 23
 	// HEAACv2 - HE-AAC version 2 with SBR and PS
 	HEAACv2 = 29
+	// MPEGLayer1 - MPEG-1/2 Layer 1
+	MPEGLayer1 = 32
+	// MPEGLayer2 - MPEG-1/2 Layer 2
+	MPEGLayer2 = 33
+	// MPEGLayer3 - MPEG-1/2 Layer 3 (MP3)
+	MPEGLayer3 = 34
+	// AACELD - ER AAC ELD Enhanced Low Delay
+	AACELD = 39
+	// USAC - Unified Speech and Audio Coding (xHE-AAC)
+	USAC = 42
+
+	syncExtensionSBR = 0x2b7 // syncExtensionType for backward-compatible SBR signalling
+	syncExtensionPS  = 0x548 // syncExtensionType for backward-compatible PS signalling
 )
 
 // AudioSpecificConfig according to ISO/IEC 14496-3
 // Syntax specified in Table 1.15
 type AudioSpecificConfig struct {
 	ObjectType           byte
-	ChannelConfiguration byte // Defined in Table 1.19
+	ChannelConfiguration byte // Defined in Table 1.19. If 0, the layout is in ProgramConfigElement or UsacConfig
 	SamplingFrequency    int
 	ExtensionFrequency   int
 	SBRPresentFlag       bool
 	PSPresentFlag        bool
+	FrameLengthFlag      bool // 960 instead of 1024 samples per frame (GASpecificConfig), or 480 instead of 512 (LD, ELD)
+	// SyncExtensionObjectType is the extensionAudioObjectType (5 or 22) of explicit backward-compatible
+	// signalling with syncExtensionType 0x2b7 after the core configuration. 0 if not present.
+	SyncExtensionObjectType       byte
+	ExtensionChannelConfiguration byte // For extensionAudioObjectType 22 (ER BSAC)
+	// GASpecificConfig fields
+	DependsOnCoreCoder   bool
+	CoreCoderDelay       uint16
+	ExtensionFlag        bool
+	ProgramConfigElement *ProgramConfigElement // If ChannelConfiguration is 0
+	LayerNr              byte                  // AAC Scalable
+	NumOfSubFrame        byte                  // ER BSAC
+	LayerLength          uint16                // ER BSAC
+	ResilienceFlags      byte                  // Section, scalefactor, and spectral data resilience flags (3 bits)
+	ExtensionFlag3       bool
+	EPConfig             byte // Error protection configuration of ER object types. Only 0 and 1 are supported
+	// Object type specific configurations
+	ELDSpecificConfig *ELDSpecificConfig // ER AAC ELD
+	UsacConfig        *UsacConfig        // USAC
+	MPEGExtension     bool               // extension bit of MPEG_1_2_SpecificConfig (Layer 1-3)
 }
 
 var frequencyTable = map[byte]int{
@@ -60,6 +99,58 @@ var reverseFrequencies = map[int]byte{
 	7350:  12,
 }
 
+// SamplesPerFrame - number of PCM samples per frame at SamplingFrequency (core frame, without SBR, for HE-AAC)
+func (a *AudioSpecificConfig) SamplesPerFrame() int {
+	switch a.ObjectType {
+	case AACLD, AACELD:
+		if a.FrameLengthFlag {
+			return 480
+		}
+		return 512
+	case USAC:
+		if a.UsacConfig != nil {
+			return a.UsacConfig.OutputFrameLength()
+		}
+	case MPEGLayer1:
+		return 384
+	case MPEGLayer2, MPEGLayer3:
+		return 1152
+	}
+	if a.FrameLengthFlag {
+		return 960
+	}
+	return 1024
+}
+
+// ChannelCount - number of output channels, from the channel configuration, program config element,
+// or USAC config. 0 if not known.
+func (a *AudioSpecificConfig) ChannelCount() int {
+	switch {
+	case a.UsacConfig != nil:
+		return a.UsacConfig.ChannelCount()
+	case a.ChannelConfiguration == 0 && a.ProgramConfigElement != nil:
+		return a.ProgramConfigElement.ChannelCount()
+	}
+	return channelCount(a.ChannelConfiguration)
+}
+
+// channelCount - number of channels for channel configuration (Table 1.19 and ISO/IEC 23001-8)
+func channelCount(channelConfiguration byte) int {
+	switch channelConfiguration {
+	case 1, 2, 3, 4, 5, 6:
+		return int(channelConfiguration)
+	case 7:
+		return 8
+	case 11:
+		return 7
+	case 12, 14:
+		return 8
+	case 13:
+		return 24
+	}
+	return 0
+}
+
 /* Channel configurations according to table 1.19 in ISO/IEC 14496-3
 0: Defined in AOT Specific Config
 1: 1 channel: front-center
@@ -72,24 +163,20 @@ var reverseFrequencies = map[int]byte{
 8-15: Reserved
 */
 
-// DecodeAudioSpecificConfig -
+// DecodeAudioSpecificConfig - decode an AudioSpecificConfig that is the rest of r
 func DecodeAudioSpecificConfig(r io.Reader) (*AudioSpecificConfig, error) {
-	br := bits.NewAccErrReader(r)
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	br := bits.NewAccErrReader(bytes.NewReader(data))
+	bitsLeft := func() int {
+		return 8*(len(data)-br.NrBytesRead()) + 8 - br.NrBitsReadInCurrentByte()
+	}
 
 	asc := &AudioSpecificConfig{}
-	audioObjectType := byte(br.Read(5))
+	audioObjectType := readAudioObjectType(br)
 	asc.ObjectType = audioObjectType
-	switch audioObjectType {
-	case AAClc:
-		// do nothing
-	case HEAACv1:
-		asc.SBRPresentFlag = true
-	case HEAACv2:
-		asc.SBRPresentFlag = true
-		asc.PSPresentFlag = true
-	default:
-		return asc, errors.New("Only LC, HE-AACv1, and HE-AACv2 supported")
-	}
 	frequency, ok := getFrequency(br)
 	if !ok {
 		return asc, fmt.Errorf("Strange frequency index")
@@ -98,59 +185,246 @@ func DecodeAudioSpecificConfig(r io.Reader) (*AudioSpecificConfig, error) {
 	asc.ChannelConfiguration = byte(br.Read(4))
 	switch audioObjectType {
 	case HEAACv1, HEAACv2:
+		asc.SBRPresentFlag = true
+		asc.PSPresentFlag = audioObjectType == HEAACv2
 		frequency, ok := getFrequency(br)
 		if !ok {
 			return asc, errors.New("Strange frequency index")
 		}
 		asc.ExtensionFrequency = frequency
-		audioObjectType = byte(br.Read(5)) // Shall be set to AAC-LC here again
-		if audioObjectType == 22 {
-			return asc, errors.New("ExtensionChannelConfiguration not supported")
+		audioObjectType = readAudioObjectType(br) // Shall be set to AAC-LC here again
+		if audioObjectType != AAClc {
+			return nil, fmt.Errorf("Base audioObjectType is %d instead of AAC-LC (2)", audioObjectType)
 		}
 	}
-	if audioObjectType != AAClc {
-		return nil, fmt.Errorf("Base audioObjectType is %d instead of AAC-LC (2)", audioObjectType)
+	switch audioObjectType {
+	case 1, 2, 3, 4, 6, 7, 17, 19, 20, 21, 22, 23:
+		asc.decodeGASpecificConfig(br, audioObjectType)
+	case AACELD:
+		asc.ELDSpecificConfig, err = decodeELDSpecificConfig(br, asc)
+		if err != nil {
+			return nil, err
+		}
+	case USAC:
+		asc.UsacConfig, err = decodeUsacConfig(br)
+		if err != nil {
+			return nil, err
+		}
+	case MPEGLayer1, MPEGLayer2, MPEGLayer3:
+		asc.MPEGExtension = br.ReadFlag()
+	default:
+		return asc, fmt.Errorf("audioObjectType %d not supported", audioObjectType)
+	}
+	switch audioObjectType {
+	case 17, 19, 20, 21, 22, 23, 24, 25, 26, 27, AACELD:
+		asc.EPConfig = byte(br.Read(2))
+		if asc.EPConfig > 1 {
+			return nil, fmt.Errorf("epConfig %d not supported", asc.EPConfig)
+		}
+	}
+	if err := br.AccError(); err != nil {
+		return nil, fmt.Errorf("AudioSpecificConfig: %w", err)
+	}
+	if !asc.SBRPresentFlag && bitsLeft() >= 16 {
+		asc.decodeSyncExtension(br, bitsLeft)
+		if err := br.AccError(); err != nil {
+			return nil, fmt.Errorf("AudioSpecificConfig sync extension: %w", err)
+		}
 	}
-	//GASpecificConfig()
-	_ = br.Read(3) //GASpecificConfig
 	// Done (there may be trailing bits)
 	return asc, nil
 }
 
-// Encode - write AudioSpecificConfig to w for AAC-LC and HE-AAC
+// decodeGASpecificConfig - GASpecificConfig() of Table 4.1
+func (a *AudioSpecificConfig) decodeGASpecificConfig(br *bits.AccErrReader, audioObjectType byte) {
+	a.FrameLengthFlag = br.ReadFlag()
+	a.DependsOnCoreCoder = br.ReadFlag()
+	if a.DependsOnCoreCoder {
+		a.CoreCoderDelay = uint16(br.Read(14))
+	}
+	a.ExtensionFlag = br.ReadFlag()
+	if a.ChannelConfiguration == 0 {
+		a.ProgramConfigElement = decodePCE(br)
+	}
+	if audioObjectType == 6 || audioObjectType == 20 {
+		a.LayerNr = byte(br.Read(3))
+	}
+	if a.ExtensionFlag {
+		if audioObjectType == 22 {
+			a.NumOfSubFrame = byte(br.Read(5))
+			a.LayerLength = uint16(br.Read(11))
+		}
+		switch audioObjectType {
+		case 17, 19, 20, 23:
+			a.ResilienceFlags = byte(br.Read(3))
+		}
+		a.ExtensionFlag3 = br.ReadFlag()
+	}
+}
+
+// decodeSyncExtension - backward-compatible SBR and PS signalling after the core configuration
+func (a *AudioSpecificConfig) decodeSyncExtension(br *bits.AccErrReader, bitsLeft func() int) {
+	if br.Read(11) != syncExtensionSBR {
+		return
+	}
+	extensionObjectType := readAudioObjectType(br)
+	switch extensionObjectType {
+	case HEAACv1:
+		a.SyncExtensionObjectType = extensionObjectType
+		a.SBRPresentFlag = br.ReadFlag()
+		if a.SBRPresentFlag {
+			a.ExtensionFrequency, _ = getFrequency(br)
+			if bitsLeft() >= 12 && br.Read(11) == syncExtensionPS {
+				a.PSPresentFlag = br.ReadFlag()
+			}
+		}
+	case 22:
+		a.SyncExtensionObjectType = extensionObjectType
+		a.SBRPresentFlag = br.ReadFlag()
+		if a.SBRPresentFlag {
+			a.ExtensionFrequency, _ = getFrequency(br)
+		}
+		a.ExtensionChannelConfiguration = byte(br.Read(4))
+	}
+}
+
+// Encode - write AudioSpecificConfig to w
 func (a *AudioSpecificConfig) Encode(w io.Writer) error {
+	audioObjectType := a.ObjectType
 	switch a.ObjectType {
-	case AAClc, HEAACv1, HEAACv2:
+	case HEAACv1, HEAACv2:
+		audioObjectType = AAClc
+	case 1, 2, 3, 4, 6, 7, 17, 19, 20, 21, 22, 23, AACELD, USAC, MPEGLayer1, MPEGLayer2, MPEGLayer3:
 		// fine
 	default:
 		return fmt.Errorf("audioObjectType %d not supported", a.ObjectType)
 	}
 	bw := bits.NewWriter(w)
-	bw.Write(uint(a.ObjectType), 5)
-	samplingIndex, ok := reverseFrequencies[a.SamplingFrequency]
-	if ok {
-		bw.Write(uint(samplingIndex), 4)
-	} else {
-		bw.Write(0x0f, 4)
-		bw.Write(uint(a.SamplingFrequency), 24)
-	}
+	writeAudioObjectType(bw, a.ObjectType)
+	writeFrequency(bw, a.SamplingFrequency)
 	bw.Write(uint(a.ChannelConfiguration), 4)
 	switch a.ObjectType {
 	case HEAACv1, HEAACv2:
-		samplingIndex, ok := reverseFrequencies[a.ExtensionFrequency]
-		if ok {
-			bw.Write(uint(samplingIndex), 4)
-		} else {
-			bw.Write(0x0f, 4)
-			bw.Write(uint(a.ExtensionFrequency), 24)
-		}
+		writeFrequency(bw, a.ExtensionFrequency)
 		bw.Write(AAClc, 5) // base audioObjectType
 	}
-	bw.Write(0x00, 3) // GASpecificConfig
+	switch audioObjectType {
+	case AACELD:
+		if a.ELDSpecificConfig == nil {
+			return fmt.Errorf("ELDSpecificConfig missing")
+		}
+		if err := a.ELDSpecificConfig.encode(bw, a); err != nil {
+			return err
+		}
+	case USAC:
+		if a.UsacConfig == nil {
+			return fmt.Errorf("UsacConfig missing")
+		}
+		if err := a.UsacConfig.encode(bw); err != nil {
+			return err
+		}
+	case MPEGLayer1, MPEGLayer2, MPEGLayer3:
+		writeFlag(bw, a.MPEGExtension)
+	default:
+		if err := a.encodeGASpecificConfig(bw, audioObjectType); err != nil {
+			return err
+		}
+	}
+	switch audioObjectType {
+	case 17, 19, 20, 21, 22, 23, AACELD:
+		if a.EPConfig > 1 {
+			return fmt.Errorf("epConfig %d not supported", a.EPConfig)
+		}
+		bw.Write(uint(a.EPConfig), 2)
+	}
+	switch a.SyncExtensionObjectType {
+	case 0:
+	case HEAACv1:
+		bw.Write(syncExtensionSBR, 11)
+		writeAudioObjectType(bw, a.SyncExtensionObjectType)
+		writeFlag(bw, a.SBRPresentFlag)
+		if a.SBRPresentFlag {
+			writeFrequency(bw, a.ExtensionFrequency)
+			if a.PSPresentFlag {
+				bw.Write(syncExtensionPS, 11)
+				bw.Write(1, 1)
+			}
+		}
+	case 22:
+		bw.Write(syncExtensionSBR, 11)
+		writeAudioObjectType(bw, a.SyncExtensionObjectType)
+		writeFlag(bw, a.SBRPresentFlag)
+		if a.SBRPresentFlag {
+			writeFrequency(bw, a.ExtensionFrequency)
+		}
+		bw.Write(uint(a.ExtensionChannelConfiguration), 4)
+	default:
+		return fmt.Errorf("sync extension audioObjectType %d not supported", a.SyncExtensionObjectType)
+	}
 	bw.Flush()
 	return bw.Error()
 }
 
+// encodeGASpecificConfig - GASpecificConfig() of Table 4.1
+func (a *AudioSpecificConfig) encodeGASpecificConfig(bw *bits.Writer, audioObjectType byte) error {
+	writeFlag(bw, a.FrameLengthFlag)
+	writeFlag(bw, a.DependsOnCoreCoder)
+	if a.DependsOnCoreCoder {
+		bw.Write(uint(a.CoreCoderDelay), 14)
+	}
+	writeFlag(bw, a.ExtensionFlag)
+	if a.ChannelConfiguration == 0 {
+		if a.ProgramConfigElement == nil {
+			return fmt.Errorf("channel configuration 0 without program config element")
+		}
+		a.ProgramConfigElement.encode(bw)
+	}
+	if audioObjectType == 6 || audioObjectType == 20 {
+		bw.Write(uint(a.LayerNr), 3)
+	}
+	if a.ExtensionFlag {
+		if audioObjectType == 22 {
+			bw.Write(uint(a.NumOfSubFrame), 5)
+			bw.Write(uint(a.LayerLength), 11)
+		}
+		switch audioObjectType {
+		case 17, 19, 20, 23:
+			bw.Write(uint(a.ResilienceFlags), 3)
+		}
+		writeFlag(bw, a.ExtensionFlag3)
+	}
+	return nil
+}
+
+// readAudioObjectType - GetAudioObjectType() with escape value 31
+func readAudioObjectType(br *bits.AccErrReader) byte {
+	audioObjectType := byte(br.Read(5))
+	if audioObjectType == 31 {
+		audioObjectType = 32 + byte(br.Read(6))
+	}
+	return audioObjectType
+}
+
+func writeAudioObjectType(bw *bits.Writer, audioObjectType byte) {
+	if audioObjectType >= 32 {
+		bw.Write(31, 5)
+		bw.Write(uint(audioObjectType-32), 6)
+		return
+	}
+	bw.Write(uint(audioObjectType), 5)
+}
+
+// writeFrequency - either as 4-bit index or 24-bit value
+func writeFrequency(bw *bits.Writer, frequency int) {
+	samplingIndex, ok := reverseFrequencies[frequency]
+	if ok {
+		bw.Write(uint(samplingIndex), 4)
+	} else {
+		bw.Write(0x0f, 4)
+		bw.Write(uint(frequency), 24)
+	}
+}
+
 // getFrequency - either from 4-bit index or 24-bit value
 func getFrequency(br *bits.AccErrReader) (frequency int, ok bool) {
 	frequencyIndex := br.Read(4)
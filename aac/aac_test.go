@@ -36,6 +36,12 @@ func TestAudioSpecificConfigEncodeDecode(t *testing.T) {
 			SBRPresentFlag:       true,
 			PSPresentFlag:        true,
 		},
+		{
+			ObjectType:           AAClc,
+			ChannelConfiguration: 6,
+			SamplingFrequency:    48000,
+			FrameLengthFlag:      true,
+		},
 	}
 
 	for _, asc := range testCases {
@@ -60,3 +66,135 @@ func TestAudioSpecificConfigEncodeDecode(t *testing.T) {
 	}
 
 }
+
+func TestAudioSpecificConfigExtensions(t *testing.T) {
+	pce51 := &ProgramConfigElement{
+		ObjectType:             1,
+		SamplingFrequencyIndex: 3,
+		FrontElements:          []PCEChannelElement{{IsCPE: false, TagSelect: 0}, {IsCPE: true, TagSelect: 0}},
+		BackElements:           []PCEChannelElement{{IsCPE: true, TagSelect: 1}},
+		LFEElementTags:         []byte{0},
+		Comment:                []byte("5.1"),
+	}
+	testCases := []struct {
+		desc            string
+		asc             AudioSpecificConfig
+		channelCount    int
+		samplesPerFrame int
+	}{
+		{
+			desc:            "PCE 5.1",
+			asc:             AudioSpecificConfig{ObjectType: AAClc, SamplingFrequency: 48000, ProgramConfigElement: pce51},
+			channelCount:    6,
+			samplesPerFrame: 1024,
+		},
+		{
+			desc:            "escape frequency",
+			asc:             AudioSpecificConfig{ObjectType: AAClc, SamplingFrequency: 37800, ChannelConfiguration: 2},
+			channelCount:    2,
+			samplesPerFrame: 1024,
+		},
+		{
+			desc: "backward-compatible SBR and PS",
+			asc: AudioSpecificConfig{ObjectType: AAClc, SamplingFrequency: 24000, ChannelConfiguration: 1,
+				SyncExtensionObjectType: HEAACv1, SBRPresentFlag: true, PSPresentFlag: true, ExtensionFrequency: 48000},
+			channelCount:    1,
+			samplesPerFrame: 1024,
+		},
+		{
+			desc: "AAC LD",
+			asc: AudioSpecificConfig{ObjectType: AACLD, SamplingFrequency: 48000, ChannelConfiguration: 2,
+				FrameLengthFlag: true, ExtensionFlag: true, ResilienceFlags: 5, EPConfig: 1},
+			channelCount:    2,
+			samplesPerFrame: 480,
+		},
+		{
+			desc: "AAC ELD with LD SBR",
+			asc: AudioSpecificConfig{ObjectType: AACELD, SamplingFrequency: 48000, ChannelConfiguration: 3,
+				ELDSpecificConfig: &ELDSpecificConfig{
+					LDSBRPresentFlag:  true,
+					LDSBRSamplingRate: true,
+					LDSBRHeaders: []SBRHeader{
+						{AmpRes: true, StartFreq: 5, StopFreq: 9, XoverBand: 2},
+						{StartFreq: 13, StopFreq: 4, HeaderExtra1: true, FreqScale: 2, NoiseBands: 1,
+							HeaderExtra2: true, LimiterBands: 3, SmoothingMode: true},
+					},
+					Extensions: []ELDExtension{{Type: 1, Data: bytes.Repeat([]byte{0x5a}, 40)}},
+				}},
+			channelCount:    3,
+			samplesPerFrame: 512,
+		},
+		{
+			desc: "USAC",
+			asc: AudioSpecificConfig{ObjectType: USAC, SamplingFrequency: 48000,
+				UsacConfig: &UsacConfig{
+					SamplingFrequency:         48000,
+					CoreSbrFrameLengthIndex:   3,
+					ChannelConfigurationIndex: 2,
+					Elements: []UsacElementConfig{
+						{Type: UsacElementEXT, Ext: &UsacExtElementConfig{Type: 4, DefaultLengthPresent: true,
+							DefaultLength: 300, Config: []byte{1, 2, 3}}},
+						{Type: UsacElementCPE, NoiseFilling: true,
+							Sbr: &UsacSbrConfig{HarmonicSBR: true, DefaultHeader: SBRHeader{
+								StartFreq: 1, StopFreq: 2, HeaderExtra2: true, LimiterGains: 2}},
+							StereoConfigIndex: 2,
+							Mps212: &Mps212Config{FreqRes: 3, TempShapeConfig: 2, EnvQuantMode: true,
+								OttBandsPhasePresent: true, OttBandsPhase: 17, ResidualBands: 9}},
+					},
+					ConfigExtensions: []UsacConfigExtension{{Type: 7, Data: bytes.Repeat([]byte{0xa5}, 20)}},
+				}},
+			channelCount:    2,
+			samplesPerFrame: 2048,
+		},
+		{
+			desc: "USAC mono without SBR and with output channel positions",
+			asc: AudioSpecificConfig{ObjectType: USAC, SamplingFrequency: 57600,
+				UsacConfig: &UsacConfig{
+					SamplingFrequency:      57600,
+					OutputChannelPositions: []byte{2},
+					Elements:               []UsacElementConfig{{Type: UsacElementSCE, TwMdct: true}},
+				}},
+			channelCount:    1,
+			samplesPerFrame: 768,
+		},
+	}
+	for _, tc := range testCases {
+		buf := &bytes.Buffer{}
+		if err := tc.asc.Encode(buf); err != nil {
+			t.Errorf("%s: %s", tc.desc, err)
+			continue
+		}
+		gotAsc, err := DecodeAudioSpecificConfig(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Errorf("%s: %s", tc.desc, err)
+			continue
+		}
+		if diff := deep.Equal(*gotAsc, tc.asc); diff != nil {
+			t.Errorf("%s: diff %v", tc.desc, diff)
+		}
+		if gotAsc.ChannelCount() != tc.channelCount || gotAsc.SamplesPerFrame() != tc.samplesPerFrame {
+			t.Errorf("%s: got %d channels and %d samples per frame", tc.desc, gotAsc.ChannelCount(), gotAsc.SamplesPerFrame())
+		}
+	}
+}
+
+func TestDecodeSyncExtension(t *testing.T) {
+	// AAC-LC 24 kHz stereo with backward-compatible signalling of SBR at 48 kHz
+	data, _ := hex.DecodeString("131056e598")
+	asc, err := DecodeAudioSpecificConfig(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := AudioSpecificConfig{ObjectType: AAClc, SamplingFrequency: 24000, ChannelConfiguration: 2,
+		SyncExtensionObjectType: HEAACv1, SBRPresentFlag: true, ExtensionFrequency: 48000}
+	if diff := deep.Equal(*asc, want); diff != nil {
+		t.Errorf("diff %v", diff)
+	}
+	buf := &bytes.Buffer{}
+	if err := asc.Encode(buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Errorf("got %s instead of %s", hex.EncodeToString(buf.Bytes()), hex.EncodeToString(data))
+	}
+}
@@ -18,6 +18,21 @@ type ADTSHeader struct {
 	ChannelConfig          byte
 	PayloadLength          uint16
 	BufferFullness         uint16
+	CRCPresent             bool   // protection_absent is 0. Not supported by Encode
+	CRC                    uint16 // crc_check value if CRCPresent
+}
+
+// Frequency - sampling frequency in Hz given by the sampling frequency index, or 0 if the index is not valid
+func (a ADTSHeader) Frequency() int {
+	return frequencyTable[a.SamplingFrequencyIndex]
+}
+
+// HeaderLength - length of header including CRC (7 or 9 bytes)
+func (a ADTSHeader) HeaderLength() int {
+	if a.CRCPresent {
+		return 9
+	}
+	return 7
 }
 
 // NewADTSHeader - create a new ADTS header
@@ -38,7 +53,7 @@ func NewADTSHeader(samplingFrequency int, channelConfig byte, objectType byte, p
 	}, nil
 }
 
-// Encode - encode ADTSHeader into byte slice
+// Encode - encode ADTSHeader into byte slice. The header is always written without CRC
 func (a ADTSHeader) Encode() []byte {
 	buf := bytes.Buffer{}
 	bw := bits.NewWriter(&buf)
@@ -87,23 +102,26 @@ func DecodeADTSHeader(r io.Reader) (header *ADTSHeader, offset int, err error) {
 		return nil, 0, fmt.Errorf("Non-permitted layer value %d", layer)
 	}
 	protectionAbsent := br.Read(1)
-	if protectionAbsent != 1 {
-		return nil, 0, fmt.Errorf("protection_absent not set. Not supported")
-	}
-	ah := &ADTSHeader{ID: mpegID}
+	ah := &ADTSHeader{ID: mpegID, CRCPresent: protectionAbsent == 0}
 	profile := br.Read(2)
 	ah.ObjectType = byte(profile + 1)
 	ah.SamplingFrequencyIndex = byte(br.Read(4))
 	_ = br.Read(1) // ignore private
 	ah.ChannelConfig = byte(br.Read(3))
 	_ = br.Read(4) // ignore original/copy, home, copyright
-	frameLength := br.Read(13)
-	ah.PayloadLength = uint16(frameLength - 7)
+	frameLength := int(br.Read(13))
 	ah.BufferFullness = uint16(br.Read(11))
 	nrRawBlocksMinus1 := br.Read(2)
 	if nrRawBlocksMinus1 != 0 {
 		return nil, 0, fmt.Errorf("only 1 raw block supported")
 	}
+	if ah.CRCPresent {
+		ah.CRC = uint16(br.Read(16))
+	}
+	if br.AccError() == nil && frameLength < ah.HeaderLength() {
+		return nil, 0, fmt.Errorf("frame length %d shorter than header", frameLength)
+	}
+	ah.PayloadLength = uint16(frameLength - ah.HeaderLength())
 	if br.AccError() != nil {
 		return nil, 0, br.AccError()
 	}
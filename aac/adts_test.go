@@ -34,6 +34,14 @@ func TestADTS(t *testing.T) {
 			wantedOffset: 1,
 			wantedError:  nil,
 		},
+		{
+			// protection_absent 0 with CRC 0x1234 after the fixed and variable header
+			adtsBytes: []byte{0xff, 0xf0, 0x4c, 0x80, 0x33, 0xdf, 0xfc, 0x12, 0x34},
+			wantedHdr: &ADTSHeader{ObjectType: AAClc, SamplingFrequencyIndex: 3, ChannelConfig: 2,
+				PayloadLength: 405, BufferFullness: 0x7ff, CRCPresent: true, CRC: 0x1234},
+			wantedOffset: 0,
+			wantedError:  nil,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -0,0 +1,188 @@
+package aac
+
+import (
+	"fmt"
+
+	"github.com/jaypadia-frame/mp4ff/bits"
+)
+
+// ELDSpecificConfig - ELDSpecificConfig() of ISO/IEC 14496-3 Table 4.180 for ER AAC ELD.
+// frameLengthFlag and the resilience flags are stored in the AudioSpecificConfig.
+type ELDSpecificConfig struct {
+	LDSBRPresentFlag  bool
+	LDSBRSamplingRate bool        // SBR at twice the core sampling rate (dual rate)
+	LDSBRCRCFlag      bool        // SBR CRC present
+	LDSBRHeaders      []SBRHeader // ld_sbr_header(), one per element given by the channel configuration
+	Extensions        []ELDExtension
+}
+
+// ELDExtension - extension in ELDSpecificConfig
+type ELDExtension struct {
+	Type byte // eldExtType, not ELDEXT_TERM (0)
+	Data []byte
+}
+
+// SBRHeader - sbr_header() of ISO/IEC 14496-3 Table 4.63.
+// AmpRes, XoverBand, and Reserved are not present in the SbrDfltHeader() of USAC.
+type SBRHeader struct {
+	AmpRes        bool
+	StartFreq     byte
+	StopFreq      byte
+	XoverBand     byte
+	Reserved      byte
+	HeaderExtra1  bool
+	HeaderExtra2  bool
+	FreqScale     byte // if HeaderExtra1
+	AlterScale    bool // if HeaderExtra1
+	NoiseBands    byte // if HeaderExtra1
+	LimiterBands  byte // if HeaderExtra2
+	LimiterGains  byte // if HeaderExtra2
+	InterpolFreq  bool // if HeaderExtra2
+	SmoothingMode bool // if HeaderExtra2
+}
+
+// nrLDSBRHeaders - number of SBR headers in ld_sbr_header() for channelConfiguration
+func nrLDSBRHeaders(channelConfiguration byte) int {
+	switch channelConfiguration {
+	case 1, 2:
+		return 1
+	case 3:
+		return 2
+	case 4, 5, 6:
+		return 3
+	case 7:
+		return 4
+	}
+	return 0
+}
+
+func decodeELDSpecificConfig(br *bits.AccErrReader, asc *AudioSpecificConfig) (*ELDSpecificConfig, error) {
+	e := &ELDSpecificConfig{}
+	asc.FrameLengthFlag = br.ReadFlag()
+	asc.ResilienceFlags = byte(br.Read(3))
+	e.LDSBRPresentFlag = br.ReadFlag()
+	if e.LDSBRPresentFlag {
+		e.LDSBRSamplingRate = br.ReadFlag()
+		e.LDSBRCRCFlag = br.ReadFlag()
+		for i := 0; i < nrLDSBRHeaders(asc.ChannelConfiguration); i++ {
+			e.LDSBRHeaders = append(e.LDSBRHeaders, decodeSBRHeader(br, false))
+		}
+	}
+	for {
+		extType := byte(br.Read(4))
+		if extType == 0 || br.AccError() != nil { // ELDEXT_TERM
+			break
+		}
+		length := int(br.Read(4))
+		if length == 15 {
+			lengthAdd := int(br.Read(8))
+			length += lengthAdd
+			if lengthAdd == 255 {
+				length += int(br.Read(16))
+			}
+		}
+		ext := ELDExtension{Type: extType, Data: make([]byte, length)}
+		for i := range ext.Data {
+			ext.Data[i] = byte(br.Read(8))
+		}
+		e.Extensions = append(e.Extensions, ext)
+	}
+	if err := br.AccError(); err != nil {
+		return nil, fmt.Errorf("ELDSpecificConfig: %w", err)
+	}
+	return e, nil
+}
+
+func (e *ELDSpecificConfig) encode(bw *bits.Writer, asc *AudioSpecificConfig) error {
+	writeFlag(bw, asc.FrameLengthFlag)
+	bw.Write(uint(asc.ResilienceFlags), 3)
+	writeFlag(bw, e.LDSBRPresentFlag)
+	if e.LDSBRPresentFlag {
+		writeFlag(bw, e.LDSBRSamplingRate)
+		writeFlag(bw, e.LDSBRCRCFlag)
+		if len(e.LDSBRHeaders) != nrLDSBRHeaders(asc.ChannelConfiguration) {
+			return fmt.Errorf("%d LD SBR headers instead of %d for channel configuration %d",
+				len(e.LDSBRHeaders), nrLDSBRHeaders(asc.ChannelConfiguration), asc.ChannelConfiguration)
+		}
+		for i := range e.LDSBRHeaders {
+			e.LDSBRHeaders[i].encode(bw, false)
+		}
+	}
+	for _, ext := range e.Extensions {
+		length := len(ext.Data)
+		if ext.Type == 0 || ext.Type > 15 || length > 15+255+0xffff {
+			return fmt.Errorf("bad ELD extension type %d or length %d", ext.Type, length)
+		}
+		bw.Write(uint(ext.Type), 4)
+		switch {
+		case length < 15:
+			bw.Write(uint(length), 4)
+		case length < 15+255:
+			bw.Write(15, 4)
+			bw.Write(uint(length-15), 8)
+		default:
+			bw.Write(15, 4)
+			bw.Write(255, 8)
+			bw.Write(uint(length-15-255), 16)
+		}
+		for _, b := range ext.Data {
+			bw.Write(uint(b), 8)
+		}
+	}
+	bw.Write(0, 4) // ELDEXT_TERM
+	return nil
+}
+
+// decodeSBRHeader - decode sbr_header(), or SbrDfltHeader() of USAC if dflt is set
+func decodeSBRHeader(br *bits.AccErrReader, dflt bool) SBRHeader {
+	h := SBRHeader{}
+	if !dflt {
+		h.AmpRes = br.ReadFlag()
+	}
+	h.StartFreq = byte(br.Read(4))
+	h.StopFreq = byte(br.Read(4))
+	if !dflt {
+		h.XoverBand = byte(br.Read(3))
+		h.Reserved = byte(br.Read(2))
+	}
+	h.HeaderExtra1 = br.ReadFlag()
+	h.HeaderExtra2 = br.ReadFlag()
+	if h.HeaderExtra1 {
+		h.FreqScale = byte(br.Read(2))
+		h.AlterScale = br.ReadFlag()
+		h.NoiseBands = byte(br.Read(2))
+	}
+	if h.HeaderExtra2 {
+		h.LimiterBands = byte(br.Read(2))
+		h.LimiterGains = byte(br.Read(2))
+		h.InterpolFreq = br.ReadFlag()
+		h.SmoothingMode = br.ReadFlag()
+	}
+	return h
+}
+
+// encode - write sbr_header(), or SbrDfltHeader() of USAC if dflt is set
+func (h *SBRHeader) encode(bw *bits.Writer, dflt bool) {
+	if !dflt {
+		writeFlag(bw, h.AmpRes)
+	}
+	bw.Write(uint(h.StartFreq), 4)
+	bw.Write(uint(h.StopFreq), 4)
+	if !dflt {
+		bw.Write(uint(h.XoverBand), 3)
+		bw.Write(uint(h.Reserved), 2)
+	}
+	writeFlag(bw, h.HeaderExtra1)
+	writeFlag(bw, h.HeaderExtra2)
+	if h.HeaderExtra1 {
+		bw.Write(uint(h.FreqScale), 2)
+		writeFlag(bw, h.AlterScale)
+		bw.Write(uint(h.NoiseBands), 2)
+	}
+	if h.HeaderExtra2 {
+		bw.Write(uint(h.LimiterBands), 2)
+		bw.Write(uint(h.LimiterGains), 2)
+		writeFlag(bw, h.InterpolFreq)
+		writeFlag(bw, h.SmoothingMode)
+	}
+}
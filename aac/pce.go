@@ -0,0 +1,163 @@
+package aac
+
+import (
+	"github.com/jaypadia-frame/mp4ff/bits"
+)
+
+// ProgramConfigElement - program_config_element() according to ISO/IEC 14496-3 Table 4.2.
+// Signals the channel layout in GASpecificConfig if the channel configuration is 0.
+type ProgramConfigElement struct {
+	ElementInstanceTag         byte
+	ObjectType                 byte // 2 bits, audioObjectType - 1
+	SamplingFrequencyIndex     byte
+	FrontElements              []PCEChannelElement
+	SideElements               []PCEChannelElement
+	BackElements               []PCEChannelElement
+	LFEElementTags             []byte
+	AssocDataElementTags       []byte
+	CCElements                 []PCECCElement
+	MonoMixdownPresent         bool
+	MonoMixdownElementNumber   byte
+	StereoMixdownPresent       bool
+	StereoMixdownElementNumber byte
+	MatrixMixdownIdxPresent    bool
+	MatrixMixdownIdx           byte
+	PseudoSurroundEnable       bool
+	Comment                    []byte
+}
+
+// PCEChannelElement - front, side, or back channel element in a program config element
+type PCEChannelElement struct {
+	IsCPE     bool // Channel pair element (2 channels) instead of single channel element
+	TagSelect byte
+}
+
+// PCECCElement - coupling channel element in a program config element
+type PCECCElement struct {
+	IsIndSw   bool
+	TagSelect byte
+}
+
+// ChannelCount - number of output channels including LFE channels
+func (p *ProgramConfigElement) ChannelCount() int {
+	nr := len(p.LFEElementTags)
+	for _, elems := range [][]PCEChannelElement{p.FrontElements, p.SideElements, p.BackElements} {
+		for _, e := range elems {
+			nr++
+			if e.IsCPE {
+				nr++
+			}
+		}
+	}
+	return nr
+}
+
+// decodePCE - decode program_config_element. The byte alignment is relative to the start of the reader
+func decodePCE(br *bits.AccErrReader) *ProgramConfigElement {
+	p := &ProgramConfigElement{}
+	p.ElementInstanceTag = byte(br.Read(4))
+	p.ObjectType = byte(br.Read(2))
+	p.SamplingFrequencyIndex = byte(br.Read(4))
+	nrFront := int(br.Read(4))
+	nrSide := int(br.Read(4))
+	nrBack := int(br.Read(4))
+	nrLFE := int(br.Read(2))
+	nrAssocData := int(br.Read(3))
+	nrValidCC := int(br.Read(4))
+	p.MonoMixdownPresent = br.ReadFlag()
+	if p.MonoMixdownPresent {
+		p.MonoMixdownElementNumber = byte(br.Read(4))
+	}
+	p.StereoMixdownPresent = br.ReadFlag()
+	if p.StereoMixdownPresent {
+		p.StereoMixdownElementNumber = byte(br.Read(4))
+	}
+	p.MatrixMixdownIdxPresent = br.ReadFlag()
+	if p.MatrixMixdownIdxPresent {
+		p.MatrixMixdownIdx = byte(br.Read(2))
+		p.PseudoSurroundEnable = br.ReadFlag()
+	}
+	p.FrontElements = decodePCEChannelElements(br, nrFront)
+	p.SideElements = decodePCEChannelElements(br, nrSide)
+	p.BackElements = decodePCEChannelElements(br, nrBack)
+	for i := 0; i < nrLFE; i++ {
+		p.LFEElementTags = append(p.LFEElementTags, byte(br.Read(4)))
+	}
+	for i := 0; i < nrAssocData; i++ {
+		p.AssocDataElementTags = append(p.AssocDataElementTags, byte(br.Read(4)))
+	}
+	for i := 0; i < nrValidCC; i++ {
+		p.CCElements = append(p.CCElements, PCECCElement{IsIndSw: br.ReadFlag(), TagSelect: byte(br.Read(4))})
+	}
+	_ = br.Read(8 - br.NrBitsReadInCurrentByte()) // byte_alignment()
+	commentLength := int(br.Read(8))
+	for i := 0; i < commentLength; i++ {
+		p.Comment = append(p.Comment, byte(br.Read(8)))
+	}
+	return p
+}
+
+func decodePCEChannelElements(br *bits.AccErrReader, nr int) []PCEChannelElement {
+	var elems []PCEChannelElement
+	for i := 0; i < nr; i++ {
+		elems = append(elems, PCEChannelElement{IsCPE: br.ReadFlag(), TagSelect: byte(br.Read(4))})
+	}
+	return elems
+}
+
+// encode - write program_config_element. The byte alignment is relative to the start of the writer
+func (p *ProgramConfigElement) encode(bw *bits.Writer) {
+	bw.Write(uint(p.ElementInstanceTag), 4)
+	bw.Write(uint(p.ObjectType), 2)
+	bw.Write(uint(p.SamplingFrequencyIndex), 4)
+	bw.Write(uint(len(p.FrontElements)), 4)
+	bw.Write(uint(len(p.SideElements)), 4)
+	bw.Write(uint(len(p.BackElements)), 4)
+	bw.Write(uint(len(p.LFEElementTags)), 2)
+	bw.Write(uint(len(p.AssocDataElementTags)), 3)
+	bw.Write(uint(len(p.CCElements)), 4)
+	writeFlag(bw, p.MonoMixdownPresent)
+	if p.MonoMixdownPresent {
+		bw.Write(uint(p.MonoMixdownElementNumber), 4)
+	}
+	writeFlag(bw, p.StereoMixdownPresent)
+	if p.StereoMixdownPresent {
+		bw.Write(uint(p.StereoMixdownElementNumber), 4)
+	}
+	writeFlag(bw, p.MatrixMixdownIdxPresent)
+	if p.MatrixMixdownIdxPresent {
+		bw.Write(uint(p.MatrixMixdownIdx), 2)
+		writeFlag(bw, p.PseudoSurroundEnable)
+	}
+	for _, elems := range [][]PCEChannelElement{p.FrontElements, p.SideElements, p.BackElements} {
+		for _, e := range elems {
+			writeFlag(bw, e.IsCPE)
+			bw.Write(uint(e.TagSelect), 4)
+		}
+	}
+	for _, tag := range p.LFEElementTags {
+		bw.Write(uint(tag), 4)
+	}
+	for _, tag := range p.AssocDataElementTags {
+		bw.Write(uint(tag), 4)
+	}
+	for _, e := range p.CCElements {
+		writeFlag(bw, e.IsIndSw)
+		bw.Write(uint(e.TagSelect), 4)
+	}
+	if n := bw.NrBitsInBuffer(); n > 0 {
+		bw.Write(0, 8-int(n)) // byte_alignment()
+	}
+	bw.Write(uint(len(p.Comment)), 8)
+	for _, b := range p.Comment {
+		bw.Write(uint(b), 8)
+	}
+}
+
+func writeFlag(bw *bits.Writer, flag bool) {
+	if flag {
+		bw.Write(1, 1)
+	} else {
+		bw.Write(0, 1)
+	}
+}
@@ -0,0 +1,393 @@
+package aac
+
+import (
+	"fmt"
+
+	"github.com/jaypadia-frame/mp4ff/bits"
+)
+
+// USAC element types (usacElementType)
+const (
+	UsacElementSCE = 0 // Single channel element
+	UsacElementCPE = 1 // Channel pair element
+	UsacElementLFE = 2 // LFE channel element
+	UsacElementEXT = 3 // Extension element
+)
+
+// usacFrequencyTable - usacSamplingFrequencyIndex values beyond frequencyTable (ISO/IEC 23003-3 Table 69)
+var usacFrequencyTable = map[byte]int{
+	13: 57600,
+	14: 51200,
+	15: 40000,
+	16: 38400,
+	17: 34150,
+	18: 28800,
+	19: 25600,
+	20: 20000,
+	21: 19200,
+	22: 17075,
+	23: 14400,
+	24: 12800,
+	25: 9600,
+}
+
+// UsacConfig - UsacConfig() of ISO/IEC 23003-3 for USAC (xHE-AAC)
+type UsacConfig struct {
+	SamplingFrequency         int
+	CoreSbrFrameLengthIndex   byte
+	ChannelConfigurationIndex byte
+	OutputChannelPositions    []byte // bsOutputChannelPos of UsacChannelConfig() if ChannelConfigurationIndex is 0
+	Elements                  []UsacElementConfig
+	ConfigExtensions          []UsacConfigExtension
+}
+
+// UsacElementConfig - configuration of one element in UsacDecoderConfig()
+type UsacElementConfig struct {
+	Type              byte // UsacElementSCE, UsacElementCPE, UsacElementLFE, or UsacElementEXT
+	TwMdct            bool // SCE and CPE
+	NoiseFilling      bool // SCE and CPE
+	Sbr               *UsacSbrConfig
+	StereoConfigIndex byte // CPE with SBR
+	Mps212            *Mps212Config
+	Ext               *UsacExtElementConfig
+}
+
+// UsacSbrConfig - SbrConfig() of USAC
+type UsacSbrConfig struct {
+	HarmonicSBR   bool
+	InterTes      bool
+	Pvc           bool
+	DefaultHeader SBRHeader // SbrDfltHeader()
+}
+
+// Mps212Config - Mps212Config() for MPEG Surround 2-1-2 in a CPE
+type Mps212Config struct {
+	FreqRes              byte
+	FixedGainDMX         byte
+	TempShapeConfig      byte
+	DecorrConfig         byte
+	HighRateMode         bool
+	PhaseCoding          bool
+	OttBandsPhasePresent bool
+	OttBandsPhase        byte
+	ResidualBands        byte // If stereoConfigIndex > 1
+	PseudoLr             bool // If stereoConfigIndex > 1
+	EnvQuantMode         bool // If TempShapeConfig is 2
+}
+
+// UsacExtElementConfig - UsacExtElementConfig(). The type-specific configuration is kept as bytes
+type UsacExtElementConfig struct {
+	Type                 uint32
+	DefaultLengthPresent bool
+	DefaultLength        uint32
+	PayloadFrag          bool
+	Config               []byte
+}
+
+// UsacConfigExtension - extension in UsacConfigExtension()
+type UsacConfigExtension struct {
+	Type uint32
+	Data []byte
+}
+
+// usacFrameLengths - coreCoderFrameLength, sbrRatioIndex and outputFrameLength for coreSbrFrameLengthIndex
+var usacFrameLengths = [5][3]int{
+	{768, 0, 768},
+	{1024, 0, 1024},
+	{768, 2, 2048},
+	{1024, 3, 2048},
+	{1024, 1, 4096},
+}
+
+// OutputFrameLength - number of output samples per frame given by CoreSbrFrameLengthIndex, or 0 if reserved
+func (u *UsacConfig) OutputFrameLength() int {
+	if int(u.CoreSbrFrameLengthIndex) >= len(usacFrameLengths) {
+		return 0
+	}
+	return usacFrameLengths[u.CoreSbrFrameLengthIndex][2]
+}
+
+// ChannelCount - number of output channels
+func (u *UsacConfig) ChannelCount() int {
+	if u.ChannelConfigurationIndex == 0 {
+		return len(u.OutputChannelPositions)
+	}
+	return channelCount(u.ChannelConfigurationIndex)
+}
+
+// readEscapedValue - escapedValue(nBits1, nBits2, nBits3) of ISO/IEC 23003-3
+func readEscapedValue(br *bits.AccErrReader, nBits1, nBits2, nBits3 int) uint32 {
+	value := uint32(br.Read(nBits1))
+	if value == 1<<uint(nBits1)-1 {
+		add := uint32(br.Read(nBits2))
+		value += add
+		if add == 1<<uint(nBits2)-1 && nBits3 > 0 {
+			value += uint32(br.Read(nBits3))
+		}
+	}
+	return value
+}
+
+// writeEscapedValue - write value as escapedValue(nBits1, nBits2, nBits3)
+func writeEscapedValue(bw *bits.Writer, value uint32, nBits1, nBits2, nBits3 int) error {
+	max1, max2 := uint32(1<<uint(nBits1)-1), uint32(1<<uint(nBits2)-1)
+	switch {
+	case value < max1:
+		bw.Write(uint(value), nBits1)
+	case value-max1 < max2 || nBits3 == 0 && value-max1 == max2:
+		bw.Write(uint(max1), nBits1)
+		bw.Write(uint(value-max1), nBits2)
+	case nBits3 > 0 && value-max1-max2 < 1<<uint(nBits3):
+		bw.Write(uint(max1), nBits1)
+		bw.Write(uint(max2), nBits2)
+		bw.Write(uint(value-max1-max2), nBits3)
+	default:
+		return fmt.Errorf("value %d too big for escapedValue(%d, %d, %d)", value, nBits1, nBits2, nBits3)
+	}
+	return nil
+}
+
+func decodeUsacConfig(br *bits.AccErrReader) (*UsacConfig, error) {
+	u := &UsacConfig{}
+	sfi := byte(br.Read(5))
+	switch f, ok := usacFrequency(sfi); {
+	case sfi == 0x1f:
+		u.SamplingFrequency = int(br.Read(24))
+	case ok:
+		u.SamplingFrequency = f
+	default:
+		return nil, fmt.Errorf("reserved usacSamplingFrequencyIndex %d", sfi)
+	}
+	u.CoreSbrFrameLengthIndex = byte(br.Read(3))
+	if int(u.CoreSbrFrameLengthIndex) >= len(usacFrameLengths) {
+		return nil, fmt.Errorf("reserved coreSbrFrameLengthIndex %d", u.CoreSbrFrameLengthIndex)
+	}
+	sbrRatioIndex := usacFrameLengths[u.CoreSbrFrameLengthIndex][1]
+	u.ChannelConfigurationIndex = byte(br.Read(5))
+	if u.ChannelConfigurationIndex == 0 {
+		nrOutChannels := int(readEscapedValue(br, 5, 8, 16))
+		for i := 0; i < nrOutChannels && br.AccError() == nil; i++ {
+			u.OutputChannelPositions = append(u.OutputChannelPositions, byte(br.Read(5)))
+		}
+	}
+	// UsacDecoderConfig()
+	nrElements := int(readEscapedValue(br, 4, 8, 16)) + 1
+	for i := 0; i < nrElements && br.AccError() == nil; i++ {
+		e := UsacElementConfig{Type: byte(br.Read(2))}
+		switch e.Type {
+		case UsacElementSCE, UsacElementCPE:
+			e.TwMdct = br.ReadFlag()
+			e.NoiseFilling = br.ReadFlag()
+			if sbrRatioIndex > 0 {
+				e.Sbr = &UsacSbrConfig{
+					HarmonicSBR: br.ReadFlag(),
+					InterTes:    br.ReadFlag(),
+					Pvc:         br.ReadFlag(),
+				}
+				e.Sbr.DefaultHeader = decodeSBRHeader(br, true)
+				if e.Type == UsacElementCPE {
+					e.StereoConfigIndex = byte(br.Read(2))
+				}
+			}
+			if e.StereoConfigIndex > 0 {
+				e.Mps212 = decodeMps212Config(br, e.StereoConfigIndex)
+			}
+		case UsacElementEXT:
+			ext := &UsacExtElementConfig{}
+			ext.Type = readEscapedValue(br, 4, 8, 16)
+			configLength := int(readEscapedValue(br, 4, 8, 16))
+			ext.DefaultLengthPresent = br.ReadFlag()
+			if ext.DefaultLengthPresent {
+				ext.DefaultLength = readEscapedValue(br, 8, 16, 0) + 1
+			}
+			ext.PayloadFrag = br.ReadFlag()
+			ext.Config = readBytes(br, configLength)
+			e.Ext = ext
+		}
+		u.Elements = append(u.Elements, e)
+	}
+	if br.ReadFlag() { // usacConfigExtensionPresent
+		nrExtensions := int(readEscapedValue(br, 2, 4, 8)) + 1
+		for i := 0; i < nrExtensions && br.AccError() == nil; i++ {
+			ext := UsacConfigExtension{Type: readEscapedValue(br, 4, 8, 16)}
+			length := int(readEscapedValue(br, 4, 8, 16))
+			ext.Data = readBytes(br, length)
+			u.ConfigExtensions = append(u.ConfigExtensions, ext)
+		}
+	}
+	if err := br.AccError(); err != nil {
+		return nil, fmt.Errorf("UsacConfig: %w", err)
+	}
+	return u, nil
+}
+
+func (u *UsacConfig) encode(bw *bits.Writer) error {
+	sfi, ok := usacFrequencyIndex(u.SamplingFrequency)
+	if ok {
+		bw.Write(uint(sfi), 5)
+	} else {
+		bw.Write(0x1f, 5)
+		bw.Write(uint(u.SamplingFrequency), 24)
+	}
+	if int(u.CoreSbrFrameLengthIndex) >= len(usacFrameLengths) {
+		return fmt.Errorf("reserved coreSbrFrameLengthIndex %d", u.CoreSbrFrameLengthIndex)
+	}
+	sbrRatioIndex := usacFrameLengths[u.CoreSbrFrameLengthIndex][1]
+	bw.Write(uint(u.CoreSbrFrameLengthIndex), 3)
+	bw.Write(uint(u.ChannelConfigurationIndex), 5)
+	if u.ChannelConfigurationIndex == 0 {
+		if err := writeEscapedValue(bw, uint32(len(u.OutputChannelPositions)), 5, 8, 16); err != nil {
+			return err
+		}
+		for _, pos := range u.OutputChannelPositions {
+			bw.Write(uint(pos), 5)
+		}
+	}
+	if len(u.Elements) == 0 {
+		return fmt.Errorf("no USAC elements")
+	}
+	if err := writeEscapedValue(bw, uint32(len(u.Elements)-1), 4, 8, 16); err != nil {
+		return err
+	}
+	for _, e := range u.Elements {
+		bw.Write(uint(e.Type), 2)
+		switch e.Type {
+		case UsacElementSCE, UsacElementCPE:
+			writeFlag(bw, e.TwMdct)
+			writeFlag(bw, e.NoiseFilling)
+			if (sbrRatioIndex > 0) != (e.Sbr != nil) {
+				return fmt.Errorf("SBR config does not match coreSbrFrameLengthIndex %d", u.CoreSbrFrameLengthIndex)
+			}
+			if e.Sbr != nil {
+				writeFlag(bw, e.Sbr.HarmonicSBR)
+				writeFlag(bw, e.Sbr.InterTes)
+				writeFlag(bw, e.Sbr.Pvc)
+				e.Sbr.DefaultHeader.encode(bw, true)
+				if e.Type == UsacElementCPE {
+					bw.Write(uint(e.StereoConfigIndex), 2)
+				}
+			}
+			if e.StereoConfigIndex > 0 {
+				if e.Mps212 == nil {
+					return fmt.Errorf("Mps212Config missing for stereoConfigIndex %d", e.StereoConfigIndex)
+				}
+				e.Mps212.encode(bw, e.StereoConfigIndex)
+			}
+		case UsacElementEXT:
+			ext := e.Ext
+			if ext == nil {
+				return fmt.Errorf("UsacExtElementConfig missing")
+			}
+			if err := writeEscapedValue(bw, ext.Type, 4, 8, 16); err != nil {
+				return err
+			}
+			if err := writeEscapedValue(bw, uint32(len(ext.Config)), 4, 8, 16); err != nil {
+				return err
+			}
+			writeFlag(bw, ext.DefaultLengthPresent)
+			if ext.DefaultLengthPresent {
+				if ext.DefaultLength == 0 {
+					return fmt.Errorf("usacExtElementDefaultLength 0")
+				}
+				if err := writeEscapedValue(bw, ext.DefaultLength-1, 8, 16, 0); err != nil {
+					return err
+				}
+			}
+			writeFlag(bw, ext.PayloadFrag)
+			for _, b := range ext.Config {
+				bw.Write(uint(b), 8)
+			}
+		}
+	}
+	writeFlag(bw, len(u.ConfigExtensions) > 0)
+	if len(u.ConfigExtensions) > 0 {
+		if err := writeEscapedValue(bw, uint32(len(u.ConfigExtensions)-1), 2, 4, 8); err != nil {
+			return err
+		}
+		for _, ext := range u.ConfigExtensions {
+			if err := writeEscapedValue(bw, ext.Type, 4, 8, 16); err != nil {
+				return err
+			}
+			if err := writeEscapedValue(bw, uint32(len(ext.Data)), 4, 8, 16); err != nil {
+				return err
+			}
+			for _, b := range ext.Data {
+				bw.Write(uint(b), 8)
+			}
+		}
+	}
+	return nil
+}
+
+func decodeMps212Config(br *bits.AccErrReader, stereoConfigIndex byte) *Mps212Config {
+	m := &Mps212Config{}
+	m.FreqRes = byte(br.Read(3))
+	m.FixedGainDMX = byte(br.Read(3))
+	m.TempShapeConfig = byte(br.Read(2))
+	m.DecorrConfig = byte(br.Read(2))
+	m.HighRateMode = br.ReadFlag()
+	m.PhaseCoding = br.ReadFlag()
+	m.OttBandsPhasePresent = br.ReadFlag()
+	if m.OttBandsPhasePresent {
+		m.OttBandsPhase = byte(br.Read(5))
+	}
+	if stereoConfigIndex > 1 {
+		m.ResidualBands = byte(br.Read(5))
+		m.PseudoLr = br.ReadFlag()
+	}
+	if m.TempShapeConfig == 2 {
+		m.EnvQuantMode = br.ReadFlag()
+	}
+	return m
+}
+
+func (m *Mps212Config) encode(bw *bits.Writer, stereoConfigIndex byte) {
+	bw.Write(uint(m.FreqRes), 3)
+	bw.Write(uint(m.FixedGainDMX), 3)
+	bw.Write(uint(m.TempShapeConfig), 2)
+	bw.Write(uint(m.DecorrConfig), 2)
+	writeFlag(bw, m.HighRateMode)
+	writeFlag(bw, m.PhaseCoding)
+	writeFlag(bw, m.OttBandsPhasePresent)
+	if m.OttBandsPhasePresent {
+		bw.Write(uint(m.OttBandsPhase), 5)
+	}
+	if stereoConfigIndex > 1 {
+		bw.Write(uint(m.ResidualBands), 5)
+		writeFlag(bw, m.PseudoLr)
+	}
+	if m.TempShapeConfig == 2 {
+		writeFlag(bw, m.EnvQuantMode)
+	}
+}
+
+// usacFrequency - sampling frequency for usacSamplingFrequencyIndex
+func usacFrequency(index byte) (int, bool) {
+	if f, ok := frequencyTable[index]; ok {
+		return f, true
+	}
+	f, ok := usacFrequencyTable[index]
+	return f, ok
+}
+
+// usacFrequencyIndex - usacSamplingFrequencyIndex for frequency
+func usacFrequencyIndex(frequency int) (byte, bool) {
+	if index, ok := reverseFrequencies[frequency]; ok {
+		return index, true
+	}
+	for index, f := range usacFrequencyTable {
+		if f == frequency {
+			return index, true
+		}
+	}
+	return 0, false
+}
+
+// readBytes - read n bytes at any bit position
+func readBytes(br *bits.AccErrReader, n int) []byte {
+	data := make([]byte, 0, n)
+	for i := 0; i < n && br.AccError() == nil; i++ {
+		data = append(data, byte(br.Read(8)))
+	}
+	return data
+}
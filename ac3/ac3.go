@@ -0,0 +1,212 @@
+package ac3
+
+import (
+	"fmt"
+
+	"github.com/jaypadia-frame/mp4ff/mp4"
+)
+
+// Config - configuration for importing an AC-3 or E-AC-3 stream
+type Config struct {
+	Language string // Default is und
+}
+
+// Track - AC-3 or E-AC-3 track imported from a stream of syncframes
+type Track struct {
+	Dac3       *mp4.Dac3Box // Set for AC-3
+	Dec3       *mp4.Dec3Box // Set for E-AC-3
+	SampleRate int
+	Language   string
+	Samples    []mp4.FullSample // 1536 audio samples each with durations in sampling frequency timescale
+}
+
+// Import - split a stream of AC-3 or E-AC-3 syncframes, as in an .ac3 or .ec3 file, into samples.
+// An E-AC-3 sample consists of all substream syncframes making up six audio blocks of independent substream 0.
+// The dac3 or dec3 box is derived from the syncframes of the first sample.
+// The sample data refers to the stream.
+func Import(stream []byte, cfg Config) (*Track, error) {
+	t := &Track{Language: cfg.Language}
+	if t.Language == "" {
+		t.Language = "und"
+	}
+	var enhanced bool
+	var frames []*SyncFrame // syncframes of current sample
+	var sampleStart, sampleBlocks int
+	var decodeTime uint64
+	addSample := func(end int) error {
+		if t.Samples == nil {
+			if err := t.setConfig(frames); err != nil {
+				return err
+			}
+		}
+		dur := uint32(256 * sampleBlocks)
+		t.Samples = append(t.Samples, mp4.FullSample{
+			Sample:     mp4.NewSample(mp4.SyncSampleFlags, dur, uint32(end-sampleStart), 0),
+			DecodeTime: decodeTime,
+			Data:       stream[sampleStart:end],
+		})
+		decodeTime += uint64(dur)
+		return nil
+	}
+	pos := 0
+	for pos < len(stream) {
+		f, err := DecodeSyncFrame(stream[pos:])
+		if err != nil {
+			return nil, fmt.Errorf("syncframe at byte %d: %w", pos, err)
+		}
+		if pos+f.Size > len(stream) {
+			return nil, fmt.Errorf("syncframe at byte %d: truncated", pos)
+		}
+		startsSample := f.IsIndependent() && f.SubstreamID == 0
+		if pos == 0 {
+			if !startsSample {
+				return nil, fmt.Errorf("stream does not start with independent substream 0")
+			}
+			enhanced = f.IsEnhanced()
+			if f.FSCod == 3 {
+				return nil, fmt.Errorf("reduced sampling frequency %d not supported", f.SampleRate())
+			}
+			t.SampleRate = f.SampleRate()
+		} else if f.IsEnhanced() != enhanced || f.SampleRate() != t.SampleRate {
+			return nil, fmt.Errorf("syncframe at byte %d: format or sampling frequency changed", pos)
+		}
+		if startsSample && sampleBlocks == 6 {
+			if err := addSample(pos); err != nil {
+				return nil, err
+			}
+			frames = nil
+			sampleStart, sampleBlocks = pos, 0
+		}
+		if startsSample {
+			sampleBlocks += f.NumBlocks
+		}
+		frames = append(frames, f)
+		pos += f.Size
+	}
+	if len(frames) == 0 {
+		return nil, fmt.Errorf("no syncframes found")
+	}
+	if err := addSample(len(stream)); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// setConfig - set dac3 or dec3 from the syncframes of a sample
+func (t *Track) setConfig(frames []*SyncFrame) error {
+	if !frames[0].IsEnhanced() {
+		dac3, err := Dac3(frames[0])
+		t.Dac3 = dac3
+		return err
+	}
+	dec3, err := Dec3(frames)
+	t.Dec3 = dec3
+	return err
+}
+
+// Dac3 - AC3SpecificBox for an AC-3 syncframe
+func Dac3(f *SyncFrame) (*mp4.Dac3Box, error) {
+	if f.IsEnhanced() {
+		return nil, fmt.Errorf("E-AC-3 syncframe")
+	}
+	return &mp4.Dac3Box{FSCod: f.FSCod, BSID: f.BSID, BSMod: f.BSMod, ACMod: f.ACMod, LFEOn: f.LFEOn,
+		BitRateCode: f.FrmSizeCod >> 1}, nil
+}
+
+// Dec3 - EC3SpecificBox for the E-AC-3 syncframes of one sample (1536 audio samples).
+// Each independent substream gets an entry with the number of following dependent substreams and
+// the channel locations of their custom channel maps. Joint object coding (Atmos) in
+// independent substream 0 is signalled after the entries.
+func Dec3(frames []*SyncFrame) (*mp4.Dec3Box, error) {
+	if len(frames) == 0 || !frames[0].IsEnhanced() || !frames[0].IsIndependent() || frames[0].SubstreamID != 0 {
+		return nil, fmt.Errorf("no E-AC-3 independent substream 0")
+	}
+	dec3 := &mp4.Dec3Box{}
+	var subs []*mp4.EC3Sub
+	depSubs := make(map[int]uint16) // dependent substream ids for each independent substream
+	indIdx := make(map[byte]int)    // entry for independent substream id
+	current := -1
+	var nrBytes, nrBlocks int
+	for _, f := range frames {
+		nrBytes += f.Size
+		if f.IsIndependent() {
+			if f.SubstreamID == 0 {
+				nrBlocks += f.NumBlocks
+			}
+			idx, ok := indIdx[f.SubstreamID]
+			if !ok {
+				idx = len(subs)
+				indIdx[f.SubstreamID] = idx
+				subs = append(subs, &mp4.EC3Sub{FSCod: f.FSCod, BSID: f.BSID, BSMod: f.BSMod,
+					ACMod: f.ACMod, LFEOn: f.LFEOn})
+			}
+			current = idx
+			continue
+		}
+		depSubs[current] |= 1 << f.SubstreamID
+		subs[current].ChanLoc |= chanLocFromChanMap(f.ChanMap)
+	}
+	if len(subs) > 8 {
+		return nil, fmt.Errorf("%d independent substreams", len(subs))
+	}
+	for i, es := range subs {
+		for ids := depSubs[i]; ids != 0; ids &= ids - 1 {
+			es.NumDepSub++
+		}
+		dec3.EC3Subs = append(dec3.EC3Subs, *es)
+	}
+	dec3.DataRate = uint16(nrBytes * 8 * frames[0].SampleRate() / (256 * nrBlocks) / 1000)
+	if frames[0].EC3ExtensionTypeA {
+		dec3.Reserved = []byte{0x01, frames[0].ComplexityIndexTypeA} // flag_ec3_extension_type_a
+	}
+	return dec3, nil
+}
+
+// chanLocFromChanMap - chan_loc of dec3 (ETSI TS 102 366 Table F.6.1) from chanmap (Table E.1.4)
+func chanLocFromChanMap(chanMap uint16) uint16 {
+	var chanLoc uint16
+	for i := 0; i < 8; i++ { // Lc/Rc to Vhc
+		if chanMap&(1<<(10-i)) != 0 {
+			chanLoc |= 1 << i
+		}
+	}
+	if chanMap&mp4.CustomChannelMapLocations["LFE2"] != 0 {
+		chanLoc |= 1 << 8
+	}
+	return chanLoc
+}
+
+// CreateInit - create an init segment with the audio track
+func (t *Track) CreateInit() (*mp4.InitSegment, error) {
+	init := mp4.CreateEmptyInit()
+	init.AddEmptyTrack(uint32(t.SampleRate), "audio", t.Language)
+	var err error
+	if t.Dac3 != nil {
+		err = init.Moov.Trak.SetAC3Descriptor(t.Dac3)
+	} else {
+		err = init.Moov.Trak.SetEC3Descriptor(t.Dec3)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return init, nil
+}
+
+// CreateProgressiveFile - create a progressive file with all samples in one chunk
+func (t *Track) CreateProgressiveFile() (*mp4.File, error) {
+	init, err := t.CreateInit()
+	if err != nil {
+		return nil, err
+	}
+	return mp4.CreateProgressiveFile(init, [][]mp4.FullSample{t.Samples})
+}
+
+// CreateFragmentedFile - create a fragmented file with one segment per fragment of fragmentDur
+// (in track timescale) as described for mp4.CreateFragmentedFile
+func (t *Track) CreateFragmentedFile(fragmentDur uint64) (*mp4.File, error) {
+	init, err := t.CreateInit()
+	if err != nil {
+		return nil, err
+	}
+	return mp4.CreateFragmentedFile(init, t.Samples, fragmentDur)
+}
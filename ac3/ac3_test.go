@@ -0,0 +1,252 @@
+package ac3
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/jaypadia-frame/mp4ff/bits"
+	"github.com/jaypadia-frame/mp4ff/mp4"
+)
+
+// encodeSyncFrame - create a syncframe of f.Size bytes with the header fields of f and zero audio blocks
+func encodeSyncFrame(t *testing.T, f *SyncFrame) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	w := bits.NewWriter(buf)
+	w.Write(0x0b77, 16)
+	flag := func(b bool) {
+		if b {
+			w.Write(1, 1)
+		} else {
+			w.Write(0, 1)
+		}
+	}
+	if !f.IsEnhanced() {
+		w.Write(0, 16) // crc1
+		w.Write(uint(f.FSCod), 2)
+		w.Write(uint(f.FrmSizeCod), 6)
+		w.Write(uint(f.BSID), 5)
+		w.Write(uint(f.BSMod), 3)
+		w.Write(uint(f.ACMod), 3)
+		if f.ACMod&0x01 != 0 && f.ACMod != 1 {
+			w.Write(0, 2) // cmixlev
+		}
+		if f.ACMod&0x04 != 0 {
+			w.Write(0, 2) // surmixlev
+		}
+		if f.ACMod == 2 {
+			w.Write(uint(f.DSurMod), 2)
+		}
+		w.Write(uint(f.LFEOn), 1)
+	} else {
+		numBlksCod := map[int]uint{1: 0, 2: 1, 3: 2, 6: 3}[f.NumBlocks]
+		w.Write(uint(f.StrmTyp), 2)
+		w.Write(uint(f.SubstreamID), 3)
+		w.Write(uint(f.Size/2-1), 11)
+		w.Write(uint(f.FSCod), 2)
+		w.Write(numBlksCod, 2)
+		w.Write(uint(f.ACMod), 3)
+		w.Write(uint(f.LFEOn), 1)
+		w.Write(uint(f.BSID), 5)
+		w.Write(31, 5) // dialnorm
+		w.Write(0, 1)  // compre
+		if f.StrmTyp == StreamTypeDependent {
+			flag(f.ChanMap != 0)
+			if f.ChanMap != 0 {
+				w.Write(uint(f.ChanMap), 16)
+			}
+		}
+		w.Write(0, 1) // mixmdate
+		w.Write(1, 1) // infomdate
+		w.Write(uint(f.BSMod), 3)
+		w.Write(0, 2) // copyrightb, origbs
+		if f.ACMod == 2 {
+			w.Write(uint(f.DSurMod), 2)
+			w.Write(0, 2) // dheadphonmod
+		}
+		if f.ACMod >= 6 {
+			w.Write(0, 2) // dsurexmod
+		}
+		w.Write(0, 1) // audprodie
+		w.Write(0, 1) // sourcefscod
+		if f.StrmTyp == StreamTypeIndependent && numBlksCod != 3 {
+			w.Write(0, 1) // convsync
+		}
+		flag(f.EC3ExtensionTypeA) // addbsie
+		if f.EC3ExtensionTypeA {
+			w.Write(1, 6) // addbsil
+			w.Write(1, 8)
+			w.Write(uint(f.ComplexityIndexTypeA), 8)
+		}
+	}
+	w.Flush()
+	if w.Error() != nil {
+		t.Fatal(w.Error())
+	}
+	data := make([]byte, f.Size)
+	copy(data, buf.Bytes())
+	return data
+}
+
+var (
+	ac3Frame51    = SyncFrame{Size: 1536, BSID: 8, FSCod: 0, FrmSizeCod: 28, NumBlocks: 6, BSMod: 0, ACMod: 7, LFEOn: 1}
+	eac3Frame51   = SyncFrame{Size: 768, BSID: 16, NumBlocks: 6, ACMod: 7, LFEOn: 1}
+	eac3FrameJOC  = SyncFrame{Size: 1024, BSID: 16, NumBlocks: 6, ACMod: 7, LFEOn: 1, EC3ExtensionTypeA: true, ComplexityIndexTypeA: 16}
+	eac3Dep71     = SyncFrame{Size: 256, BSID: 16, StrmTyp: StreamTypeDependent, NumBlocks: 6, ACMod: 2, ChanMap: 0xa200}
+	eac3Frame20x1 = SyncFrame{Size: 128, BSID: 16, NumBlocks: 1, BSMod: 2, ACMod: 2, DSurMod: 1}
+)
+
+func TestDecodeSyncFrame(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		frame SyncFrame
+	}{
+		{"AC-3 5.1", ac3Frame51},
+		{"AC-3 2.0 44.1kHz", SyncFrame{Size: 280, BSID: 6, FSCod: 1, FrmSizeCod: 9, NumBlocks: 6, BSMod: 1, ACMod: 2, DSurMod: 2}},
+		{"E-AC-3 5.1", eac3Frame51},
+		{"E-AC-3 JOC", eac3FrameJOC},
+		{"E-AC-3 dependent", eac3Dep71},
+		{"E-AC-3 one block", eac3Frame20x1},
+	}
+	for _, tc := range testCases {
+		got, err := DecodeSyncFrame(encodeSyncFrame(t, &tc.frame))
+		if err != nil {
+			t.Errorf("%s: %s", tc.desc, err)
+			continue
+		}
+		if diff := deep.Equal(*got, tc.frame); diff != nil {
+			t.Errorf("%s: %v", tc.desc, diff)
+		}
+	}
+	if _, err := DecodeSyncFrame([]byte{0x0b, 0x78, 0, 0, 0, 0}); err == nil {
+		t.Error("no error for bad syncword")
+	}
+}
+
+func TestImportAC3(t *testing.T) {
+	var stream []byte
+	for i := 0; i < 5; i++ {
+		stream = append(stream, encodeSyncFrame(t, &ac3Frame51)...)
+	}
+	track, err := Import(stream, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantDac3 := &mp4.Dac3Box{BSID: 8, ACMod: 7, LFEOn: 1, BitRateCode: 14}
+	if diff := deep.Equal(track.Dac3, wantDac3); diff != nil || track.Dec3 != nil {
+		t.Errorf("dac3 diff %v", diff)
+	}
+	if nrChannels, _ := track.Dac3.ChannelInfo(); nrChannels != 6 || track.Dac3.BitrateBps() != 384000 {
+		t.Errorf("got %d channels and bitrate %d", nrChannels, track.Dac3.BitrateBps())
+	}
+	if len(track.Samples) != 5 || track.SampleRate != 48000 {
+		t.Fatalf("got %d samples and sample rate %d", len(track.Samples), track.SampleRate)
+	}
+	for i, s := range track.Samples {
+		if s.Dur != SamplesPerSample || s.DecodeTime != uint64(i*SamplesPerSample) || len(s.Data) != 1536 || !s.IsSync() {
+			t.Errorf("sample %d: got dur %d time %d size %d", i+1, s.Dur, s.DecodeTime, len(s.Data))
+		}
+	}
+}
+
+func TestImportEAC3(t *testing.T) {
+	frameWithSubstream := func(f SyncFrame, id byte) *SyncFrame {
+		f.SubstreamID = id
+		return &f
+	}
+	testCases := []struct {
+		desc         string
+		sample       []*SyncFrame
+		wantDec3     *mp4.Dec3Box
+		wantChannels int
+	}{
+		{
+			desc:   "7.1 with Atmos",
+			sample: []*SyncFrame{&eac3FrameJOC, &eac3Dep71},
+			wantDec3: &mp4.Dec3Box{DataRate: 320, Reserved: []byte{1, 16},
+				EC3Subs: []mp4.EC3Sub{{BSID: 16, ACMod: 7, LFEOn: 1, NumDepSub: 1, ChanLoc: 0x02}}},
+			wantChannels: 8,
+		},
+		{
+			desc: "one block per syncframe and two independent substreams",
+			sample: []*SyncFrame{&eac3Frame20x1, frameWithSubstream(eac3Frame20x1, 1), &eac3Frame20x1,
+				frameWithSubstream(eac3Frame20x1, 1), &eac3Frame20x1, frameWithSubstream(eac3Frame20x1, 1),
+				&eac3Frame20x1, frameWithSubstream(eac3Frame20x1, 1), &eac3Frame20x1,
+				frameWithSubstream(eac3Frame20x1, 1), &eac3Frame20x1, frameWithSubstream(eac3Frame20x1, 1)},
+			wantDec3: &mp4.Dec3Box{DataRate: 384,
+				EC3Subs: []mp4.EC3Sub{{BSID: 16, BSMod: 2, ACMod: 2}, {BSID: 16, BSMod: 2, ACMod: 2}}},
+			wantChannels: 2,
+		},
+	}
+	for _, tc := range testCases {
+		var sample []byte
+		for _, f := range tc.sample {
+			sample = append(sample, encodeSyncFrame(t, f)...)
+		}
+		stream := bytes.Repeat(sample, 4)
+		track, err := Import(stream, Config{Language: "swe"})
+		if err != nil {
+			t.Fatalf("%s: %s", tc.desc, err)
+		}
+		if diff := deep.Equal(track.Dec3, tc.wantDec3); diff != nil || track.Dac3 != nil {
+			t.Errorf("%s: dec3 diff %v", tc.desc, diff)
+		}
+		if nrChannels, _ := track.Dec3.ChannelInfo(); nrChannels != tc.wantChannels {
+			t.Errorf("%s: got %d channels", tc.desc, nrChannels)
+		}
+		if len(track.Samples) != 4 {
+			t.Fatalf("%s: got %d samples", tc.desc, len(track.Samples))
+		}
+		for i, s := range track.Samples {
+			if s.Dur != SamplesPerSample || s.DecodeTime != uint64(i*SamplesPerSample) || !bytes.Equal(s.Data, sample) {
+				t.Errorf("%s sample %d: got dur %d time %d size %d", tc.desc, i+1, s.Dur, s.DecodeTime, len(s.Data))
+			}
+		}
+
+		f, err := track.CreateProgressiveFile()
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf := &bytes.Buffer{}
+		if err := f.Encode(buf); err != nil {
+			t.Fatal(err)
+		}
+		decFile, err := mp4.DecodeFile(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		trak := decFile.Moov.Trak
+		if trak.Mdia.Mdhd.Timescale != 48000 || trak.Mdia.Mdhd.GetLanguage() != "swe" {
+			t.Errorf("%s: got timescale %d", tc.desc, trak.Mdia.Mdhd.Timescale)
+		}
+		ec3 := trak.Mdia.Minf.Stbl.Stsd.EC3
+		if ec3 == nil || ec3.Dec3 == nil {
+			t.Fatalf("%s: no ec-3 sample entry", tc.desc)
+		}
+		if ec3.Dec3.DataRate != tc.wantDec3.DataRate || len(ec3.Dec3.EC3Subs) != len(tc.wantDec3.EC3Subs) ||
+			!bytes.Equal(ec3.Dec3.Reserved, tc.wantDec3.Reserved) {
+			t.Errorf("%s: got decoded dec3 %+v", tc.desc, ec3.Dec3)
+		}
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	dep := encodeSyncFrame(t, &eac3Dep71)
+	ind := encodeSyncFrame(t, &eac3Frame51)
+	ac3Data := encodeSyncFrame(t, &ac3Frame51)
+	testCases := []struct {
+		desc   string
+		stream []byte
+	}{
+		{"starts with dependent substream", append(append([]byte{}, dep...), ind...)},
+		{"mixed AC-3 and E-AC-3", append(append([]byte{}, ind...), ac3Data...)},
+		{"truncated", ind[:500]},
+		{"empty", nil},
+	}
+	for _, tc := range testCases {
+		if _, err := Import(tc.stream, Config{}); err == nil {
+			t.Errorf("%s: no error", tc.desc)
+		}
+	}
+}
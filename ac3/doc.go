@@ -0,0 +1,11 @@
+/*
+Package ac3 - parse AC-3 and E-AC-3 (Dolby Digital and Dolby Digital Plus) syncframes and import them into MP4 tracks.
+
+DecodeSyncFrame parses the syncinfo and bit stream information (BSI) of a syncframe,
+including E-AC-3 substream types, custom channel maps of dependent substreams, and the
+joint object coding (Atmos) signalling in the additional BSI.
+
+Import splits a raw stream, as in an .ac3 or .ec3 file, into samples of 1536 audio samples
+and creates the dac3 or dec3 box of the ac-3 or ec-3 sample entry from the syncframes.
+*/
+package ac3
@@ -0,0 +1,251 @@
+package ac3
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/jaypadia-frame/mp4ff/bits"
+)
+
+// Stream types (strmtyp) of E-AC-3 syncframes (ETSI TS 102 366 Table E.1.1)
+const (
+	StreamTypeIndependent = 0
+	StreamTypeDependent   = 1
+	StreamTypeAC3Convert  = 2
+)
+
+// SamplesPerSample - number of audio samples in an mp4 sample (six audio blocks)
+const SamplesPerSample = 1536
+
+// maxAC3BSID - highest bsid of AC-3. E-AC-3 uses 11-16
+const maxAC3BSID = 10
+
+// frameSizes - words per AC-3 syncframe for frmsizecod and fscod 48, 44.1, and 32 kHz (ETSI TS 102 366 Table 4.13)
+var frameSizes = [38][3]int{
+	{64, 69, 96}, {64, 70, 96}, {80, 87, 120}, {80, 88, 120}, {96, 104, 144}, {96, 105, 144},
+	{112, 121, 168}, {112, 122, 168}, {128, 139, 192}, {128, 140, 192}, {160, 174, 240}, {160, 175, 240},
+	{192, 208, 288}, {192, 209, 288}, {224, 243, 336}, {224, 244, 336}, {256, 278, 384}, {256, 279, 384},
+	{320, 348, 480}, {320, 349, 480}, {384, 417, 576}, {384, 418, 576}, {448, 487, 672}, {448, 488, 672},
+	{512, 557, 768}, {512, 558, 768}, {640, 696, 960}, {640, 697, 960}, {768, 835, 1152}, {768, 836, 1152},
+	{896, 975, 1344}, {896, 976, 1344}, {1024, 1114, 1536}, {1024, 1115, 1536}, {1152, 1253, 1728},
+	{1152, 1254, 1728}, {1280, 1393, 1920}, {1280, 1394, 1920},
+}
+
+// sampleRates - sampling frequency for fscod, and for fscod2 in reduced-rate E-AC-3 frames
+var sampleRates = [3]int{48000, 44100, 32000}
+var reducedSampleRates = [3]int{24000, 22050, 16000}
+
+// SyncFrame - information from the syncinfo and bsi of an AC-3 or E-AC-3 syncframe.
+// Fields only present in one of the formats are zero for the other.
+type SyncFrame struct {
+	Size        int  // bytes including syncword
+	BSID        byte // bit stream identification. Up to 10 for AC-3 and 11-16 for E-AC-3
+	StrmTyp     byte // E-AC-3 stream type
+	SubstreamID byte // E-AC-3 substream id
+	FSCod       byte
+	FSCod2      byte // E-AC-3 reduced sample rate code if FSCod == 3
+	FrmSizeCod  byte // AC-3 frame size code
+	NumBlocks   int  // number of audio blocks of 256 samples
+	BSMod       byte // bit stream mode. For E-AC-3 only present with informational metadata
+	ACMod       byte
+	LFEOn       byte
+	DSurMod     byte
+	ChanMap     uint16 // custom channel map of E-AC-3 dependent substreams (0 if not present)
+	// Joint object coding (Dolby Atmos) signalled in the first bytes of addbsi of E-AC-3
+	EC3ExtensionTypeA    bool
+	ComplexityIndexTypeA byte
+}
+
+// DecodeSyncFrame - decode the header of the syncframe at the start of data
+func DecodeSyncFrame(data []byte) (*SyncFrame, error) {
+	if len(data) < 6 || data[0] != 0x0b || data[1] != 0x77 {
+		return nil, fmt.Errorf("no AC-3 syncword")
+	}
+	bsid := data[5] >> 3
+	switch {
+	case bsid <= maxAC3BSID:
+		return decodeAC3Frame(data)
+	case bsid <= 16:
+		return decodeEAC3Frame(data)
+	default:
+		return nil, fmt.Errorf("bsid %d not supported", bsid)
+	}
+}
+
+// decodeAC3Frame - decode syncinfo() and the start of bsi() according to ETSI TS 102 366 Section 4.3
+func decodeAC3Frame(data []byte) (*SyncFrame, error) {
+	f := &SyncFrame{NumBlocks: 6}
+	r := bits.NewAccErrReader(bytes.NewReader(data[4:])) // After syncword and crc1
+	f.FSCod = byte(r.Read(2))
+	f.FrmSizeCod = byte(r.Read(6))
+	f.BSID = byte(r.Read(5))
+	f.BSMod = byte(r.Read(3))
+	f.ACMod = byte(r.Read(3))
+	if f.ACMod&0x01 != 0 && f.ACMod != 1 {
+		_ = r.Read(2) // cmixlev
+	}
+	if f.ACMod&0x04 != 0 {
+		_ = r.Read(2) // surmixlev
+	}
+	if f.ACMod == 2 {
+		f.DSurMod = byte(r.Read(2))
+	}
+	f.LFEOn = byte(r.Read(1))
+	if err := r.AccError(); err != nil {
+		return nil, fmt.Errorf("AC-3 bsi: %w", err)
+	}
+	if f.FSCod == 3 || int(f.FrmSizeCod) >= len(frameSizes) {
+		return nil, fmt.Errorf("bad AC-3 fscod %d or frmsizecod %d", f.FSCod, f.FrmSizeCod)
+	}
+	f.Size = 2 * frameSizes[f.FrmSizeCod][f.FSCod]
+	return f, nil
+}
+
+// decodeEAC3Frame - decode bsi() according to ETSI TS 102 366 Section E.1.2.2
+func decodeEAC3Frame(data []byte) (*SyncFrame, error) {
+	f := &SyncFrame{}
+	r := bits.NewAccErrReader(bytes.NewReader(data[2:])) // After syncword
+	f.StrmTyp = byte(r.Read(2))
+	f.SubstreamID = byte(r.Read(3))
+	f.Size = 2 * (int(r.Read(11)) + 1)
+	f.FSCod = byte(r.Read(2))
+	numBlksCod := byte(3)
+	if f.FSCod == 3 {
+		f.FSCod2 = byte(r.Read(2))
+	} else {
+		numBlksCod = byte(r.Read(2))
+	}
+	f.NumBlocks = []int{1, 2, 3, 6}[numBlksCod]
+	f.ACMod = byte(r.Read(3))
+	f.LFEOn = byte(r.Read(1))
+	f.BSID = byte(r.Read(5))
+	nrPrograms := 1 // Dual mono (acmod 0) has two sets of some parameters
+	if f.ACMod == 0 {
+		nrPrograms = 2
+	}
+	for i := 0; i < nrPrograms; i++ {
+		_ = r.Read(5) // dialnorm
+		if r.ReadFlag() {
+			_ = r.Read(8) // compr
+		}
+	}
+	if f.StrmTyp == StreamTypeDependent && r.ReadFlag() {
+		f.ChanMap = uint16(r.Read(16))
+	}
+	if r.ReadFlag() { // mixmdate
+		if f.ACMod > 2 {
+			_ = r.Read(2) // dmixmod
+		}
+		if f.ACMod&0x01 != 0 && f.ACMod > 2 {
+			_ = r.Read(6) // ltrtcmixlev, lorocmixlev
+		}
+		if f.ACMod&0x04 != 0 {
+			_ = r.Read(6) // ltrtsurmixlev, lorosurmixlev
+		}
+		if f.LFEOn == 1 && r.ReadFlag() {
+			_ = r.Read(5) // lfemixlevcod
+		}
+		if f.StrmTyp == StreamTypeIndependent {
+			for i := 0; i < nrPrograms; i++ {
+				if r.ReadFlag() {
+					_ = r.Read(6) // pgmscl
+				}
+			}
+			if r.ReadFlag() {
+				_ = r.Read(6) // extpgmscl
+			}
+			switch r.Read(2) { // mixdef
+			case 1:
+				_ = r.Read(5) // premixcmpsel, drcsrc, premixcmpscl
+			case 2:
+				_ = r.Read(12) // mixdata
+			case 3:
+				mixDefLen := int(r.Read(5))
+				_ = r.ReadBytes(mixDefLen + 2) // mixdata
+			}
+			if f.ACMod < 2 {
+				for i := 0; i < nrPrograms; i++ {
+					if r.ReadFlag() {
+						_ = r.Read(14) // panmean, paninfo
+					}
+				}
+			}
+			if r.ReadFlag() { // frmmixcfginfoe
+				if f.NumBlocks == 1 {
+					_ = r.Read(5) // blkmixcfginfo
+				} else {
+					for i := 0; i < f.NumBlocks; i++ {
+						if r.ReadFlag() {
+							_ = r.Read(5) // blkmixcfginfo
+						}
+					}
+				}
+			}
+		}
+	}
+	if r.ReadFlag() { // infomdate
+		f.BSMod = byte(r.Read(3))
+		_ = r.Read(2) // copyrightb, origbs
+		if f.ACMod == 2 {
+			f.DSurMod = byte(r.Read(2))
+			_ = r.Read(2) // dheadphonmod
+		}
+		if f.ACMod >= 6 {
+			_ = r.Read(2) // dsurexmod
+		}
+		for i := 0; i < nrPrograms; i++ {
+			if r.ReadFlag() {
+				_ = r.Read(8) // mixlevel, roomtyp, adconvtyp
+			}
+		}
+		if f.FSCod < 3 {
+			_ = r.Read(1) // sourcefscod
+		}
+	}
+	if f.StrmTyp == StreamTypeIndependent && numBlksCod != 3 {
+		_ = r.Read(1) // convsync
+	}
+	if f.StrmTyp == StreamTypeAC3Convert && (numBlksCod == 3 || r.ReadFlag()) {
+		_ = r.Read(6) // frmsizecod
+	}
+	if r.ReadFlag() { // addbsie
+		addBSILen := int(r.Read(6)) + 1
+		addBSI := r.ReadBytes(addBSILen)
+		if len(addBSI) > 0 {
+			f.EC3ExtensionTypeA = addBSI[0]&0x01 != 0 // flag_ec3_extension_type_a in ETSI TS 103 420
+			if f.EC3ExtensionTypeA && len(addBSI) > 1 {
+				f.ComplexityIndexTypeA = addBSI[1]
+			}
+		}
+	}
+	if err := r.AccError(); err != nil {
+		return nil, fmt.Errorf("E-AC-3 bsi: %w", err)
+	}
+	if f.FSCod == 3 && f.FSCod2 == 3 {
+		return nil, fmt.Errorf("bad E-AC-3 fscod2 3")
+	}
+	return f, nil
+}
+
+// IsEnhanced - true for E-AC-3 syncframes
+func (f *SyncFrame) IsEnhanced() bool {
+	return f.BSID > maxAC3BSID
+}
+
+// IsIndependent - true for AC-3 syncframes and E-AC-3 independent (or converted AC-3) substreams
+func (f *SyncFrame) IsIndependent() bool {
+	return f.StrmTyp != StreamTypeDependent
+}
+
+// SampleRate - sampling frequency in Hz
+func (f *SyncFrame) SampleRate() int {
+	if f.FSCod == 3 {
+		return reducedSampleRates[f.FSCod2]
+	}
+	return sampleRates[f.FSCod]
+}
+
+// NrSamples - number of audio samples per channel in the syncframe
+func (f *SyncFrame) NrSamples() int {
+	return 256 * f.NumBlocks
+}
@@ -0,0 +1,166 @@
+package adts
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/jaypadia-frame/mp4ff/aac"
+	"github.com/jaypadia-frame/mp4ff/mp4"
+)
+
+const maxFrameLength = 1<<13 - 1 // frame_length has 13 bits
+
+// Config - configuration for importing an ADTS stream
+type Config struct {
+	SamplesPerFrame int    // 1024 (default) or 960. Not signalled in ADTS
+	Language        string // Default is und
+}
+
+// Track - AAC track imported from an ADTS stream
+type Track struct {
+	Config   *aac.AudioSpecificConfig
+	Language string
+	Samples  []mp4.FullSample // Raw AAC frames with durations in sampling frequency timescale
+}
+
+// Import - split ADTS stream into AAC frames without headers.
+// All frames must have the same object type, sampling frequency and channel configuration.
+// The sample data refers to the stream.
+func Import(stream []byte, cfg Config) (*Track, error) {
+	t := &Track{Language: cfg.Language}
+	if t.Language == "" {
+		t.Language = "und"
+	}
+	var frameLength960 bool
+	switch cfg.SamplesPerFrame {
+	case 0, 1024:
+	case 960:
+		frameLength960 = true
+	default:
+		return nil, fmt.Errorf("%d samples per frame not supported", cfg.SamplesPerFrame)
+	}
+	var first *aac.ADTSHeader
+	var decodeTime uint64
+	pos := 0
+	for pos < len(stream) {
+		hdr, offset, err := aac.DecodeADTSHeader(bytes.NewReader(stream[pos:]))
+		if err != nil {
+			return nil, fmt.Errorf("ADTS header at byte %d: %w", pos, err)
+		}
+		if first == nil {
+			first = hdr
+			t.Config, err = audioSpecificConfig(hdr)
+			if err != nil {
+				return nil, err
+			}
+			t.Config.FrameLengthFlag = frameLength960
+		} else if hdr.ObjectType != first.ObjectType || hdr.SamplingFrequencyIndex != first.SamplingFrequencyIndex ||
+			hdr.ChannelConfig != first.ChannelConfig {
+			return nil, fmt.Errorf("ADTS header at byte %d: audio configuration changed", pos+offset)
+		}
+		start := pos + offset + hdr.HeaderLength()
+		end := start + int(hdr.PayloadLength)
+		if end > len(stream) {
+			return nil, fmt.Errorf("ADTS frame at byte %d: truncated", pos+offset)
+		}
+		dur := uint32(t.Config.SamplesPerFrame())
+		t.Samples = append(t.Samples, mp4.FullSample{
+			Sample:     mp4.NewSample(mp4.SyncSampleFlags, dur, uint32(end-start), 0),
+			DecodeTime: decodeTime,
+			Data:       stream[start:end],
+		})
+		decodeTime += uint64(dur)
+		pos = end
+	}
+	if first == nil {
+		return nil, fmt.Errorf("no ADTS frames found")
+	}
+	return t, nil
+}
+
+// audioSpecificConfig - AudioSpecificConfig corresponding to ADTS header
+func audioSpecificConfig(hdr *aac.ADTSHeader) (*aac.AudioSpecificConfig, error) {
+	if hdr.ObjectType != aac.AAClc {
+		return nil, fmt.Errorf("object type %d not supported", hdr.ObjectType)
+	}
+	if hdr.ChannelConfig == 0 {
+		return nil, fmt.Errorf("channel configuration 0 (program config element) not supported")
+	}
+	freq := hdr.Frequency()
+	if freq == 0 {
+		return nil, fmt.Errorf("sampling frequency index %d not supported", hdr.SamplingFrequencyIndex)
+	}
+	return &aac.AudioSpecificConfig{
+		ObjectType:           hdr.ObjectType,
+		ChannelConfiguration: hdr.ChannelConfig,
+		SamplingFrequency:    freq,
+	}, nil
+}
+
+// CreateInit - create an init segment with the audio track
+func (t *Track) CreateInit() (*mp4.InitSegment, error) {
+	init := mp4.CreateEmptyInit()
+	init.AddEmptyTrack(uint32(t.Config.SamplingFrequency), "audio", t.Language)
+	if err := init.Moov.Trak.SetAACDescriptorFromConfig(t.Config); err != nil {
+		return nil, err
+	}
+	return init, nil
+}
+
+// CreateProgressiveFile - create a progressive file with all samples in one chunk
+func (t *Track) CreateProgressiveFile() (*mp4.File, error) {
+	init, err := t.CreateInit()
+	if err != nil {
+		return nil, err
+	}
+	return mp4.CreateProgressiveFile(init, [][]mp4.FullSample{t.Samples})
+}
+
+// CreateFragmentedFile - create a fragmented file with one segment per fragment of fragmentDur
+// (in track timescale) as described for mp4.CreateFragmentedFile
+func (t *Track) CreateFragmentedFile(fragmentDur uint64) (*mp4.File, error) {
+	init, err := t.CreateInit()
+	if err != nil {
+		return nil, err
+	}
+	return mp4.CreateFragmentedFile(init, t.Samples, fragmentDur)
+}
+
+// GetAudioSpecificConfig - decode the AudioSpecificConfig in the esds box of the mp4a sample entry of trak
+func GetAudioSpecificConfig(trak *mp4.TrakBox) (*aac.AudioSpecificConfig, error) {
+	mp4a := trak.Mdia.Minf.Stbl.Stsd.Mp4a
+	if mp4a == nil || mp4a.Esds == nil {
+		return nil, fmt.Errorf("no mp4a sample entry with esds")
+	}
+	decConfig := mp4a.Esds.DecConfigDescriptor.DecSpecificInfo.DecConfig
+	return aac.DecodeAudioSpecificConfig(bytes.NewReader(decConfig))
+}
+
+// Export - write samples as ADTS frames to w. HE-AAC is signalled as AAC-LC with the base sampling frequency.
+func Export(w io.Writer, asc *aac.AudioSpecificConfig, samples []mp4.FullSample) error {
+	switch asc.ObjectType {
+	case aac.AAClc, aac.HEAACv1, aac.HEAACv2:
+	default:
+		return fmt.Errorf("object type %d not supported in ADTS", asc.ObjectType)
+	}
+	if asc.ChannelConfiguration == 0 {
+		return fmt.Errorf("channel configuration 0 (program config element) not supported")
+	}
+	for i, s := range samples {
+		if len(s.Data)+7 > maxFrameLength {
+			return fmt.Errorf("sample %d: size %d too big for ADTS", i+1, len(s.Data))
+		}
+		hdr, err := aac.NewADTSHeader(asc.SamplingFrequency, asc.ChannelConfiguration, aac.AAClc, uint16(len(s.Data)))
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(hdr.Encode()); err != nil {
+			return err
+		}
+		if _, err := w.Write(s.Data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
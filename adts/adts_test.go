@@ -0,0 +1,137 @@
+package adts
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/jaypadia-frame/mp4ff/aac"
+	"github.com/jaypadia-frame/mp4ff/mp4"
+)
+
+// createADTSStream - ADTS frames with 48kHz stereo AAC-LC payloads. Frames in crcFrames get a CRC
+func createADTSStream(t *testing.T, payloads [][]byte, crcFrames map[int]bool) []byte {
+	t.Helper()
+	var stream []byte
+	for i, pl := range payloads {
+		if !crcFrames[i] {
+			hdr, err := aac.NewADTSHeader(48000, 2, aac.AAClc, uint16(len(pl)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			stream = append(stream, hdr.Encode()...)
+			stream = append(stream, pl...)
+			continue
+		}
+		hdr, err := aac.NewADTSHeader(48000, 2, aac.AAClc, uint16(len(pl)+2))
+		if err != nil {
+			t.Fatal(err)
+		}
+		hdrBytes := hdr.Encode()
+		hdrBytes[1] &^= 0x01 // protection_absent = 0
+		stream = append(stream, hdrBytes...)
+		stream = append(stream, 0xab, 0xcd) // crc_check
+		stream = append(stream, pl...)
+	}
+	return stream
+}
+
+func createPayloads(nr int) [][]byte {
+	payloads := make([][]byte, nr)
+	for i := range payloads {
+		payloads[i] = bytes.Repeat([]byte{byte(i)}, 100+i%7*30)
+	}
+	return payloads
+}
+
+func TestImportExport(t *testing.T) {
+	payloads := createPayloads(100)
+	stream := createADTSStream(t, payloads, nil)
+	track, err := Import(stream, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantedASC := &aac.AudioSpecificConfig{ObjectType: aac.AAClc, ChannelConfiguration: 2, SamplingFrequency: 48000}
+	if diff := deep.Equal(track.Config, wantedASC); diff != nil {
+		t.Error(diff)
+	}
+	if len(track.Samples) != len(payloads) {
+		t.Fatalf("got %d samples instead of %d", len(track.Samples), len(payloads))
+	}
+	for i, s := range track.Samples {
+		if s.Dur != 1024 || s.DecodeTime != uint64(i*1024) || !s.IsSync() || !bytes.Equal(s.Data, payloads[i]) {
+			t.Errorf("sample %d: bad dur %d, decode time %d, or data", i+1, s.Dur, s.DecodeTime)
+		}
+	}
+
+	prog, err := track.CreateProgressiveFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := prog.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	prog, err = mp4.DecodeFile(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trak := prog.Moov.Trak
+	asc, err := GetAudioSpecificConfig(trak)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := deep.Equal(asc, wantedASC); diff != nil {
+		t.Error(diff)
+	}
+	if trak.Mdia.Mdhd.Timescale != 48000 || trak.Mdia.Mdhd.Duration != 100*1024 {
+		t.Errorf("got timescale %d and duration %d", trak.Mdia.Mdhd.Timescale, trak.Mdia.Mdhd.Duration)
+	}
+	stbl := trak.Mdia.Minf.Stbl
+	if stbl.Stss != nil {
+		t.Errorf("stss present although all samples are sync samples")
+	}
+	// All samples are in one chunk
+	var samples []mp4.FullSample
+	pos := uint64(stbl.Stco.ChunkOffset[0]) - prog.Mdat.PayloadAbsoluteOffset()
+	for nr := 1; nr <= int(stbl.Stsz.SampleNumber); nr++ {
+		size := uint64(stbl.Stsz.GetSampleSize(nr))
+		samples = append(samples, mp4.FullSample{Data: prog.Mdat.Data[pos : pos+size]})
+		pos += size
+	}
+	var out bytes.Buffer
+	if err := Export(&out, asc, samples); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Bytes(), stream) {
+		t.Errorf("exported ADTS stream differs from imported")
+	}
+}
+
+func TestImportCRCAnd960(t *testing.T) {
+	payloads := createPayloads(10)
+	stream := createADTSStream(t, payloads, map[int]bool{0: true, 3: true, 4: true})
+	track, err := Import(stream, Config{SamplesPerFrame: 960})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !track.Config.FrameLengthFlag {
+		t.Errorf("frameLengthFlag not set")
+	}
+	for i, s := range track.Samples {
+		if s.Dur != 960 || !bytes.Equal(s.Data, payloads[i]) {
+			t.Errorf("sample %d: bad dur %d or data", i+1, s.Dur)
+		}
+	}
+	frag, err := track.CreateFragmentedFile(5 * 960)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(frag.Segments) != 2 {
+		t.Errorf("got %d segments instead of 2", len(frag.Segments))
+	}
+
+	if _, err := Import(stream[:len(stream)-1], Config{}); err == nil {
+		t.Errorf("no error for truncated stream")
+	}
+}
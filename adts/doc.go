@@ -0,0 +1,10 @@
+/*
+Package adts - convert AAC audio between ADTS streams and MP4 tracks.
+
+Import reads a stream of ADTS frames, as in a .aac file, and creates an mp4a track where the
+ADTS headers (including any CRC) have been removed from the samples. The AudioSpecificConfig is
+derived from the first header, and the timescale is the sampling frequency.
+
+Export does the opposite, and writes the samples of an mp4a track as ADTS frames.
+*/
+package adts
@@ -0,0 +1,61 @@
+package annexb
+
+// SplitAccessUnits - split NAL units in decode order into access units.
+// A new access unit starts after a picture with an access unit delimiter, a parameter set, a prefix SEI
+// or other NAL unit that must precede the first slice, or with the first slice of a new picture.
+// For AVC, each field of an interlaced frame coded as two field pictures is an access unit.
+func SplitAccessUnits(nalus [][]byte, codec string) [][][]byte {
+	var aus [][][]byte
+	var au [][]byte
+	hasVCL := false
+	for _, nalu := range nalus {
+		if len(nalu) == 0 {
+			continue
+		}
+		var isVCL, startsAU bool
+		switch codec {
+		case "hevc":
+			isVCL, startsAU = classifyHEVC(nalu)
+		default:
+			isVCL, startsAU = classifyAVC(nalu)
+		}
+		if hasVCL && startsAU {
+			aus = append(aus, au)
+			au, hasVCL = nil, false
+		}
+		au = append(au, nalu)
+		if isVCL {
+			hasVCL = true
+		}
+	}
+	if len(au) > 0 {
+		aus = append(aus, au)
+	}
+	return aus
+}
+
+// classifyAVC - is NAL unit a slice, and does it start a new access unit if following a slice (7.4.1.2.3)
+func classifyAVC(nalu []byte) (isVCL, startsAU bool) {
+	naluType := nalu[0] & 0x1f
+	switch {
+	case naluType >= 1 && naluType <= 5:
+		// first_mb_in_slice is ue(v) which starts with a one bit if it is 0
+		return true, len(nalu) > 1 && nalu[1]&0x80 != 0
+	case naluType >= 6 && naluType <= 9, naluType >= 14 && naluType <= 18:
+		return false, true
+	}
+	return false, false
+}
+
+// classifyHEVC - is NAL unit a slice segment, and does it start a new access unit if following one (7.4.2.4.4)
+func classifyHEVC(nalu []byte) (isVCL, startsAU bool) {
+	naluType := (nalu[0] >> 1) & 0x3f
+	switch {
+	case naluType < 32:
+		// first_slice_segment_in_pic_flag is the first bit after the two-byte NAL unit header
+		return true, len(nalu) > 2 && nalu[2]&0x80 != 0
+	case naluType <= 35, naluType == 39, naluType >= 41 && naluType <= 44, naluType >= 48 && naluType <= 55:
+		return false, true
+	}
+	return false, false
+}
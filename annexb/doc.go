@@ -0,0 +1,16 @@
+/*
+Package annexb - import raw H.264/AVC and H.265/HEVC Annex B byte streams into MP4 tracks.
+
+The byte stream is split into access units at access unit delimiters, parameter sets and SEI
+NAL units, or at the first slice of a new picture (first_mb_in_slice equal to 0 for AVC and
+first_slice_segment_in_pic_flag set for HEVC). The start codes are replaced by 4-byte length fields.
+
+Parameter sets are put in the avcC or hvcC box, and removed from the samples for the avc1 and hvc1
+sample entries. For avc3 and hev1, they are also kept in-band.
+
+All samples get the same duration, derived from a configured frame rate or from the VUI timing_info
+of the first SPS. Composition time offsets are computed from the picture order count (POC) of the pictures.
+
+The resulting track can be written as a progressive or a fragmented file.
+*/
+package annexb
@@ -0,0 +1,272 @@
+package annexb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/jaypadia-frame/mp4ff/avc"
+	"github.com/jaypadia-frame/mp4ff/gop"
+	"github.com/jaypadia-frame/mp4ff/hevc"
+	"github.com/jaypadia-frame/mp4ff/mp4"
+)
+
+// Config - configuration for importing an Annex B byte stream
+type Config struct {
+	Codec        string // avc or hevc
+	SampleEntry  string // avc1, avc3, hvc1, or hev1. Default is avc1 or hvc1
+	Timescale    uint32 // Track timescale. Default is 90000
+	FrameRateNum uint32 // Frame rate is FrameRateNum/FrameRateDen. If 0, it is taken from VUI timing_info
+	FrameRateDen uint32 // Default is 1
+	Language     string // Default is und
+}
+
+// Track - video track imported from an Annex B byte stream
+type Track struct {
+	Codec       string
+	SampleEntry string
+	Timescale   uint32
+	Language    string
+	FrameDur    uint32   // Duration of every sample
+	VPSs        [][]byte // HEVC only
+	SPSs        [][]byte
+	PPSs        [][]byte
+	Samples     []mp4.FullSample // Samples in decode order with 4-byte NAL unit lengths
+}
+
+// Import - split an Annex B byte stream into samples and find parameter sets, durations, and composition time offsets
+func Import(stream []byte, cfg Config) (*Track, error) {
+	t := &Track{
+		Codec:       cfg.Codec,
+		SampleEntry: cfg.SampleEntry,
+		Timescale:   cfg.Timescale,
+		Language:    cfg.Language,
+	}
+	var psInBand bool
+	switch {
+	case cfg.Codec == "avc" && t.SampleEntry == "":
+		t.SampleEntry = "avc1"
+	case cfg.Codec == "hevc" && t.SampleEntry == "":
+		t.SampleEntry = "hvc1"
+	case cfg.Codec == "avc" && t.SampleEntry == "avc3", cfg.Codec == "hevc" && t.SampleEntry == "hev1":
+		psInBand = true
+	case cfg.Codec == "avc" && t.SampleEntry == "avc1", cfg.Codec == "hevc" && t.SampleEntry == "hvc1":
+	default:
+		return nil, fmt.Errorf("sample entry %q not supported for codec %q", t.SampleEntry, cfg.Codec)
+	}
+	if t.Timescale == 0 {
+		t.Timescale = 90000
+	}
+	if t.Language == "" {
+		t.Language = "und"
+	}
+
+	nalus := avc.ExtractNalusFromByteStream(stream)
+	if len(nalus) == 0 {
+		return nil, fmt.Errorf("no NAL units found")
+	}
+	aus := SplitAccessUnits(nalus, cfg.Codec)
+	for _, nalu := range nalus {
+		switch parameterSetType(nalu, cfg.Codec) {
+		case "VPS":
+			t.VPSs = appendUnique(t.VPSs, nalu)
+		case "SPS":
+			t.SPSs = appendUnique(t.SPSs, nalu)
+		case "PPS":
+			t.PPSs = appendUnique(t.PPSs, nalu)
+		}
+	}
+	if len(t.SPSs) == 0 || len(t.PPSs) == 0 || (cfg.Codec == "hevc" && len(t.VPSs) == 0) {
+		return nil, fmt.Errorf("parameter sets missing in stream")
+	}
+	var err error
+	t.FrameDur, err = frameDuration(cfg, t.Timescale, t.SPSs[0])
+	if err != nil {
+		return nil, err
+	}
+
+	a := gop.NewAnalyzer(cfg.Codec)
+	for i, au := range aus {
+		if i == len(aus)-1 && !hasVCL(au, cfg.Codec) {
+			break // Trailing NAL units such as end of stream
+		}
+		decTime := uint64(i) * uint64(t.FrameDur)
+		err := a.AddSample(mp4.FullSample{DecodeTime: decTime, Data: lengthPrefixed(au, nil)})
+		if err != nil {
+			return nil, err
+		}
+		skip := func(nalu []byte) bool { return !psInBand && parameterSetType(nalu, cfg.Codec) != "" }
+		data := lengthPrefixed(au, skip)
+		t.Samples = append(t.Samples, mp4.FullSample{
+			Sample:     mp4.NewSample(mp4.NonSyncSampleFlags, t.FrameDur, uint32(len(data)), 0),
+			DecodeTime: decTime,
+			Data:       data,
+		})
+	}
+
+	// Offsets are calculated in frames from the output order, and shifted so that
+	// no frame is presented before it is decoded.
+	ranks := a.OutputRanks()
+	shift := 0
+	for i, rank := range ranks {
+		if i-rank > shift {
+			shift = i - rank
+		}
+	}
+	for i, f := range a.Frames() {
+		s := &t.Samples[i]
+		s.CompositionTimeOffset = int32(ranks[i]-i+shift) * int32(t.FrameDur)
+		if f.Type.IsIntra() && f.Type != gop.FrameI {
+			s.Flags = mp4.SyncSampleFlags
+		}
+	}
+	return t, nil
+}
+
+// frameDuration - sample duration in timescale from configured frame rate or from the VUI timing_info of sps
+func frameDuration(cfg Config, timescale uint32, sps []byte) (uint32, error) {
+	num, den := uint64(cfg.FrameRateNum), uint64(cfg.FrameRateDen)
+	if num == 0 {
+		switch cfg.Codec {
+		case "avc":
+			s, err := avc.ParseSPSNALUnit(sps, true)
+			if err != nil {
+				return 0, err
+			}
+			if s.VUI != nil && s.VUI.TimingInfoPresentFlag && s.VUI.NumUnitsInTick > 0 {
+				// A frame is two ticks (fields) in AVC
+				num, den = uint64(s.VUI.TimeScale), 2*uint64(s.VUI.NumUnitsInTick)
+			}
+		case "hevc":
+			s, err := hevc.ParseSPSNALUnit(sps)
+			if err != nil {
+				return 0, err
+			}
+			if s.VUI != nil && s.VUI.TimingInfoPresentFlag && s.VUI.NumUnitsInTick > 0 {
+				num, den = uint64(s.VUI.TimeScale), uint64(s.VUI.NumUnitsInTick)
+			}
+		}
+		if num == 0 {
+			return 0, fmt.Errorf("no frame rate given and no timing info in SPS")
+		}
+	}
+	if den == 0 {
+		den = 1
+	}
+	dur := (uint64(timescale)*den + num/2) / num
+	if dur == 0 {
+		return 0, fmt.Errorf("frame rate %d/%d too high for timescale %d", num, den, timescale)
+	}
+	return uint32(dur), nil
+}
+
+// CreateInit - create an init segment with the video track
+func (t *Track) CreateInit() (*mp4.InitSegment, error) {
+	init := mp4.CreateEmptyInit()
+	init.AddEmptyTrack(t.Timescale, "video", t.Language)
+	trak := init.Moov.Trak
+	// avc3 and hev1 tracks have the parameter sets in-band, and do not need them in the decoder configuration
+	includePS := t.SampleEntry == "avc1" || t.SampleEntry == "hvc1"
+	var err error
+	switch t.Codec {
+	case "avc":
+		err = trak.SetAVCDescriptor(t.SampleEntry, t.SPSs, t.PPSs, includePS)
+	case "hevc":
+		err = trak.SetHEVCDescriptor(t.SampleEntry, t.VPSs, t.SPSs, t.PPSs, includePS)
+	default:
+		err = fmt.Errorf("unknown codec %q", t.Codec)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return init, nil
+}
+
+// CreateProgressiveFile - create a progressive file with all samples in one chunk
+func (t *Track) CreateProgressiveFile() (*mp4.File, error) {
+	init, err := t.CreateInit()
+	if err != nil {
+		return nil, err
+	}
+	return mp4.CreateProgressiveFile(init, [][]mp4.FullSample{t.Samples})
+}
+
+// CreateFragmentedFile - create a fragmented file with one segment per fragment.
+// A new fragment starts at the first sync sample after fragmentDur (in track timescale) has passed.
+// If fragmentDur is 0, every GOP becomes a fragment.
+func (t *Track) CreateFragmentedFile(fragmentDur uint64) (*mp4.File, error) {
+	init, err := t.CreateInit()
+	if err != nil {
+		return nil, err
+	}
+	if fragmentDur == 0 {
+		fragmentDur = 1 // Start a fragment at every sync sample
+	}
+	return mp4.CreateFragmentedFile(init, t.Samples, fragmentDur)
+}
+
+// parameterSetType - "VPS", "SPS", or "PPS" for parameter set NAL units, and "" for other NAL units
+func parameterSetType(nalu []byte, codec string) string {
+	switch codec {
+	case "avc":
+		switch avc.GetNaluType(nalu[0]) {
+		case avc.NALU_SPS:
+			return "SPS"
+		case avc.NALU_PPS:
+			return "PPS"
+		}
+	case "hevc":
+		switch hevc.GetNaluType(nalu[0]) {
+		case hevc.NALU_VPS:
+			return "VPS"
+		case hevc.NALU_SPS:
+			return "SPS"
+		case hevc.NALU_PPS:
+			return "PPS"
+		}
+	}
+	return ""
+}
+
+func hasVCL(au [][]byte, codec string) bool {
+	for _, nalu := range au {
+		var isVCL bool
+		switch codec {
+		case "hevc":
+			isVCL, _ = classifyHEVC(nalu)
+		default:
+			isVCL, _ = classifyAVC(nalu)
+		}
+		if isVCL {
+			return true
+		}
+	}
+	return false
+}
+
+func appendUnique(nalus [][]byte, nalu []byte) [][]byte {
+	for _, n := range nalus {
+		if bytes.Equal(n, nalu) {
+			return nalus
+		}
+	}
+	return append(nalus, nalu)
+}
+
+// lengthPrefixed - sample with 4-byte NAL unit lengths of the NAL units not skipped
+func lengthPrefixed(nalus [][]byte, skip func(nalu []byte) bool) []byte {
+	size := 0
+	for _, nalu := range nalus {
+		size += 4 + len(nalu)
+	}
+	sample := make([]byte, 0, size)
+	for _, nalu := range nalus {
+		if skip != nil && skip(nalu) {
+			continue
+		}
+		sample = append(sample, 0, 0, 0, 0)
+		binary.BigEndian.PutUint32(sample[len(sample)-4:], uint32(len(nalu)))
+		sample = append(sample, nalu...)
+	}
+	return sample
+}
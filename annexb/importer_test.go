@@ -0,0 +1,189 @@
+package annexb
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/jaypadia-frame/mp4ff/avc"
+	"github.com/jaypadia-frame/mp4ff/internal/mp4test"
+	"github.com/jaypadia-frame/mp4ff/mp4"
+)
+
+// readByteStream - AVC samples of a fragmented file and the samples converted to an Annex B byte stream
+func readByteStream(t *testing.T, path string) ([]mp4.FullSample, []byte) {
+	t.Helper()
+	fh, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fh.Close()
+	f, err := mp4.DecodeFile(fh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var samples []mp4.FullSample
+	var stream []byte
+	for _, seg := range f.Segments {
+		for _, frag := range seg.Fragments {
+			fs, err := frag.GetFullSamples(nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, s := range fs {
+				data := make([]byte, len(s.Data))
+				copy(data, s.Data)
+				stream = append(stream, avc.ConvertSampleToByteStream(data)...)
+			}
+			samples = append(samples, fs...)
+		}
+	}
+	return samples, stream
+}
+
+func TestImportAVC(t *testing.T) {
+	orig, stream := readByteStream(t, "../mp4/testdata/1.m4s")
+	track, err := Import(stream, Config{Codec: "avc", SampleEntry: "avc3", FrameRateNum: 30})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if track.FrameDur != 3000 {
+		t.Errorf("got frame duration %d instead of 3000", track.FrameDur)
+	}
+	if len(track.Samples) != len(orig) {
+		t.Fatalf("got %d samples instead of %d", len(track.Samples), len(orig))
+	}
+	shift := track.Samples[0].CompositionTimeOffset - orig[0].CompositionTimeOffset
+	var syncNrs []int
+	for i, s := range track.Samples {
+		o := orig[i]
+		if !bytes.Equal(s.Data, o.Data) {
+			t.Errorf("sample %d: data differs", i+1)
+		}
+		if s.DecodeTime != o.DecodeTime || s.Dur != o.Dur {
+			t.Errorf("sample %d: got time %d dur %d instead of %d %d", i+1, s.DecodeTime, s.Dur, o.DecodeTime, o.Dur)
+		}
+		if s.CompositionTimeOffset-o.CompositionTimeOffset != shift {
+			t.Errorf("sample %d: got cto %d, original %d", i+1, s.CompositionTimeOffset, o.CompositionTimeOffset)
+		}
+		if s.IsSync() {
+			syncNrs = append(syncNrs, i+1)
+		}
+	}
+	if diff := deep.Equal(syncNrs, []int{1, 31}); diff != nil {
+		t.Errorf("sync samples: %v", diff)
+	}
+
+	// Without parameter sets in samples, and with timing from the SPS VUI
+	track, err = Import(stream, Config{Codec: "avc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if track.SampleEntry != "avc1" || track.FrameDur != 3000 {
+		t.Errorf("got sample entry %s and frame duration %d", track.SampleEntry, track.FrameDur)
+	}
+	if avc.HasParameterSets(track.Samples[0].Data) {
+		t.Errorf("parameter sets not removed from first sample")
+	}
+
+	prog, err := track.CreateProgressiveFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	prog = mp4test.EncodeDecode(t, prog)
+	if prog.IsFragmented() {
+		t.Fatalf("progressive file decoded as fragmented")
+	}
+	stbl := prog.Moov.Trak.Mdia.Minf.Stbl
+	if stbl.Stsz.SampleNumber != 60 || stbl.Stss.EntryCount() != 2 || prog.Moov.Trak.Mdia.Mdhd.Duration != 60*3000 {
+		t.Errorf("got %d samples, %d sync samples, duration %d", stbl.Stsz.SampleNumber,
+			stbl.Stss.EntryCount(), prog.Moov.Trak.Mdia.Mdhd.Duration)
+	}
+	if stbl.Stsd.AvcX.Type() != "avc1" || len(stbl.Stsd.AvcX.AvcC.SPSnalus) != 1 {
+		t.Errorf("bad sample description")
+	}
+	offset := int(stbl.Stco.ChunkOffset[0])
+	for i, s := range track.Samples {
+		nr := uint32(i + 1)
+		if got := stbl.Ctts.GetCompositionTimeOffset(nr); got != s.CompositionTimeOffset {
+			t.Errorf("sample %d: got cto %d instead of %d", nr, got, s.CompositionTimeOffset)
+		}
+		mdatOffset := offset - int(prog.Mdat.PayloadAbsoluteOffset())
+		if !bytes.Equal(prog.Mdat.Data[mdatOffset:mdatOffset+len(s.Data)], s.Data) {
+			t.Errorf("sample %d: data differs", nr)
+		}
+		offset += len(s.Data)
+	}
+
+	frag, err := track.CreateFragmentedFile(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frag = mp4test.EncodeDecode(t, frag)
+	trex := frag.Init.Moov.Mvex.Trex
+	var fragSamples []mp4.FullSample
+	nrFrags := 0
+	for _, seg := range frag.Segments {
+		for _, fr := range seg.Fragments {
+			fs, err := fr.GetFullSamples(trex)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fragSamples = append(fragSamples, fs...)
+			nrFrags++
+		}
+	}
+	if nrFrags != 2 {
+		t.Errorf("got %d fragments instead of one per GOP", nrFrags)
+	}
+	if diff := deep.Equal(fragSamples, track.Samples); diff != nil {
+		t.Error(diff)
+	}
+}
+
+func TestSplitAccessUnits(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		codec   string
+		nalus   [][]byte
+		wantAUs []int // Number of NAL units per access unit
+	}{
+		{
+			desc:  "AVC with AUD, parameter sets and multiple slices",
+			codec: "avc",
+			nalus: [][]byte{
+				{0x09, 0xf0}, {0x67, 0x42}, {0x68, 0xce}, {0x65, 0x88}, {0x65, 0x40},
+				{0x09, 0xf0}, {0x41, 0x9a}, {0x41, 0x20},
+			},
+			wantAUs: []int{5, 3},
+		},
+		{
+			desc:  "AVC with first_mb_in_slice only",
+			codec: "avc",
+			nalus: [][]byte{
+				{0x65, 0x88}, {0x65, 0x40}, {0x41, 0x9a}, {0x01, 0x9e}, {0x06, 0x05}, {0x01, 0x9e}, {0x0b},
+			},
+			wantAUs: []int{2, 1, 1, 3},
+		},
+		{
+			desc:  "HEVC with prefix and suffix SEI and dependent slice segments",
+			codec: "hevc",
+			nalus: [][]byte{
+				{0x40, 0x01}, {0x42, 0x01}, {0x44, 0x01}, {0x4e, 0x01}, {0x26, 0x01, 0xaf}, {0x26, 0x01, 0x20},
+				{0x50, 0x01}, {0x02, 0x01, 0xd0}, {0x00, 0x01, 0xe0}, {0x02, 0x01, 0x40},
+			},
+			wantAUs: []int{7, 1, 2},
+		},
+	}
+	for _, tc := range testCases {
+		aus := SplitAccessUnits(tc.nalus, tc.codec)
+		var gotAUs []int
+		for _, au := range aus {
+			gotAUs = append(gotAUs, len(au))
+		}
+		if diff := deep.Equal(gotAUs, tc.wantAUs); diff != nil {
+			t.Errorf("%s: %v", tc.desc, diff)
+		}
+	}
+}
@@ -108,3 +108,21 @@ func ConvertSampleToByteStream(sample []byte) []byte {
 	}
 	return sample
 }
+
+// ConvertSampleToByteStreamWithLengthSize - Replace NALU length fields of lengthSize (1, 2, or 4) bytes
+// with start codes. A 4-byte length sample is converted in place, other samples are copied.
+func ConvertSampleToByteStreamWithLengthSize(sample []byte, lengthSize int) ([]byte, error) {
+	if lengthSize == 4 {
+		return ConvertSampleToByteStream(sample), nil
+	}
+	nalus, err := GetNalusFromSampleWithLengthSize(sample, lengthSize)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 0, len(sample)+len(nalus)*(4-lengthSize))
+	for _, nalu := range nalus {
+		out = append(out, 0, 0, 0, 1)
+		out = append(out, nalu...)
+	}
+	return out, nil
+}
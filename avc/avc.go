@@ -1,7 +1,6 @@
 package avc
 
 import (
-	"encoding/binary"
 	"fmt"
 )
 
@@ -68,37 +67,38 @@ func GetNalRefIDC(naluHeader byte) uint {
 
 // FindNaluTypes - find list of NAL unit types in sample
 func FindNaluTypes(sample []byte) []NaluType {
-	naluList := make([]NaluType, 0)
-	length := len(sample)
-	if length < 4 {
-		return naluList
-	}
-	var pos uint32 = 0
-	for pos < uint32(length-4) {
-		naluLength := binary.BigEndian.Uint32(sample[pos : pos+4])
-		pos += 4
-		naluType := GetNaluType(sample[pos])
-		naluList = append(naluList, naluType)
-		pos += naluLength
-	}
-	return naluList
+	return FindNaluTypesWithLengthSize(sample, 4)
+}
+
+// FindNaluTypesWithLengthSize - find list of NAL unit types in sample with lengthSize (1, 2, or 4) bytes length fields
+func FindNaluTypesWithLengthSize(sample []byte, lengthSize int) []NaluType {
+	return findNaluTypes(sample, lengthSize, false)
 }
 
 // FindNaluTypesUpToFirstVideoNALU - find list of NAL unit types in sample
 func FindNaluTypesUpToFirstVideoNALU(sample []byte) []NaluType {
+	return findNaluTypes(sample, 4, true)
+}
+
+// FindNaluTypesUpToFirstVideoNALUWithLengthSize - find list of NAL unit types in sample up to first video NAL unit
+func FindNaluTypesUpToFirstVideoNALUWithLengthSize(sample []byte, lengthSize int) []NaluType {
+	return findNaluTypes(sample, lengthSize, true)
+}
+
+func findNaluTypes(sample []byte, lengthSize int, upToFirstVideo bool) []NaluType {
 	naluList := make([]NaluType, 0)
 	length := len(sample)
-	if length < 4 {
+	if length < lengthSize || !IsValidLengthSize(lengthSize) {
 		return naluList
 	}
-	var pos uint32 = 0
-	for pos < uint32(length-4) {
-		naluLength := binary.BigEndian.Uint32(sample[pos : pos+4])
-		pos += 4
+	var pos uint64 = 0
+	for pos < uint64(length-lengthSize) {
+		naluLength := ReadNaluLength(sample[pos:], lengthSize)
+		pos += uint64(lengthSize)
 		naluType := GetNaluType(sample[pos])
 		naluList = append(naluList, naluType)
-		pos += naluLength
-		if naluType <= highestVideoNaluType {
+		pos += uint64(naluLength)
+		if upToFirstVideo && naluType <= highestVideoNaluType {
 			break // first video nalu
 		}
 	}
@@ -110,25 +110,43 @@ func IsIDRSample(sample []byte) bool {
 	return ContainsNaluType(sample, NALU_IDR)
 }
 
+// IsIDRSampleWithLengthSize - does sample with lengthSize (1, 2, or 4) bytes length fields contain IDR NALU
+func IsIDRSampleWithLengthSize(sample []byte, lengthSize int) bool {
+	return ContainsNaluTypeWithLengthSize(sample, lengthSize, NALU_IDR)
+}
+
 // ContainsNaluType - is specific NaluType present in sample
 func ContainsNaluType(sample []byte, specificNalType NaluType) bool {
-	var pos uint32 = 0
+	return ContainsNaluTypeWithLengthSize(sample, 4, specificNalType)
+}
+
+// ContainsNaluTypeWithLengthSize - is specific NaluType present in sample with lengthSize (1, 2, or 4) bytes length fields
+func ContainsNaluTypeWithLengthSize(sample []byte, lengthSize int, specificNalType NaluType) bool {
+	if !IsValidLengthSize(lengthSize) {
+		return false
+	}
+	var pos uint64 = 0
 	length := len(sample)
-	for pos < uint32(length-4) {
-		naluLength := binary.BigEndian.Uint32(sample[pos : pos+4])
-		pos += 4
+	for int(pos) < length-lengthSize {
+		naluLength := ReadNaluLength(sample[pos:], lengthSize)
+		pos += uint64(lengthSize)
 		naluType := GetNaluType(sample[pos])
 		if naluType == specificNalType {
 			return true
 		}
-		pos += naluLength
+		pos += uint64(naluLength)
 	}
 	return false
 }
 
 // HasParameterSets - Check if H.264 SPS and PPS are present
 func HasParameterSets(b []byte) bool {
-	naluTypeList := FindNaluTypesUpToFirstVideoNALU(b)
+	return HasParameterSetsWithLengthSize(b, 4)
+}
+
+// HasParameterSetsWithLengthSize - Check if H.264 SPS and PPS are present in sample with lengthSize (1, 2, or 4) bytes length fields
+func HasParameterSetsWithLengthSize(b []byte, lengthSize int) bool {
+	naluTypeList := FindNaluTypesUpToFirstVideoNALUWithLengthSize(b, lengthSize)
 	hasSPS := false
 	hasPPS := false
 	for _, naluType := range naluTypeList {
@@ -147,15 +165,23 @@ func HasParameterSets(b []byte) bool {
 
 // GetParameterSets - get (multiple) SPS and PPS from a sample
 func GetParameterSets(sample []byte) (sps [][]byte, pps [][]byte) {
-	sampleLength := uint32(len(sample))
-	var pos uint32 = 0
+	return GetParameterSetsWithLengthSize(sample, 4)
+}
+
+// GetParameterSetsWithLengthSize - get (multiple) SPS and PPS from a sample with lengthSize (1, 2, or 4) bytes length fields
+func GetParameterSetsWithLengthSize(sample []byte, lengthSize int) (sps [][]byte, pps [][]byte) {
+	if !IsValidLengthSize(lengthSize) {
+		return nil, nil
+	}
+	sampleLength := uint64(len(sample))
+	var pos uint64 = 0
 naluLoop:
 	for {
 		if pos >= sampleLength {
 			break
 		}
-		naluLength := binary.BigEndian.Uint32(sample[pos : pos+4])
-		pos += 4
+		naluLength := uint64(ReadNaluLength(sample[pos:], lengthSize))
+		pos += uint64(lengthSize)
 		naluHdr := sample[pos]
 		switch naluType := GetNaluType(naluHdr); {
 		case naluType == NALU_SPS:
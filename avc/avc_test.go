@@ -96,3 +96,54 @@ func TestGetParameterSets(t *testing.T) {
 		}
 	}
 }
+
+func TestNaluLengthSizes(t *testing.T) {
+	sample4 := []byte{0, 0, 0, 2, 9, 2,
+		0, 0, 0, 3, 7, 5, 4,
+		0, 0, 0, 3, 8, 1, 2,
+		0, 0, 0, 2, 5, 0}
+	samples := map[int][]byte{
+		1: {2, 9, 2, 3, 7, 5, 4, 3, 8, 1, 2, 2, 5, 0},
+		2: {0, 2, 9, 2, 0, 3, 7, 5, 4, 0, 3, 8, 1, 2, 0, 2, 5, 0},
+		4: sample4,
+	}
+	wantedTypes := []NaluType{NALU_AUD, NALU_SPS, NALU_PPS, NALU_IDR}
+	for lengthSize, sample := range samples {
+		if diff := deep.Equal(FindNaluTypesWithLengthSize(sample, lengthSize), wantedTypes); diff != nil {
+			t.Errorf("length size %d: %v", lengthSize, diff)
+		}
+		if !IsIDRSampleWithLengthSize(sample, lengthSize) || !HasParameterSetsWithLengthSize(sample, lengthSize) {
+			t.Errorf("length size %d: IDR or parameter sets not found", lengthSize)
+		}
+		sps, pps := GetParameterSetsWithLengthSize(sample, lengthSize)
+		if diff := deep.Equal([][][]byte{sps, pps}, [][][]byte{{{7, 5, 4}}, {{8, 1, 2}}}); diff != nil {
+			t.Errorf("length size %d: %v", lengthSize, diff)
+		}
+		for toSize, wanted := range samples {
+			got, err := ConvertNaluLengthSize(sample, lengthSize, toSize)
+			if err != nil {
+				t.Error(err)
+			}
+			if diff := deep.Equal(got, wanted); diff != nil {
+				t.Errorf("length size %d to %d: %v", lengthSize, toSize, diff)
+			}
+		}
+		byteStream, err := ConvertSampleToByteStreamWithLengthSize(append([]byte{}, sample...), lengthSize)
+		if err != nil {
+			t.Error(err)
+		}
+		if diff := deep.Equal(ExtractNalusFromByteStream(byteStream), [][]byte{{9, 2}, {7, 5, 4}, {8, 1, 2}, {5, 0}}); diff != nil {
+			t.Errorf("length size %d: %v", lengthSize, diff)
+		}
+	}
+	longNalu := append([]byte{0, 0, 1, 0, 5}, make([]byte, 255)...)
+	if _, err := ConvertNaluLengthSize(longNalu, 4, 2); err != nil {
+		t.Error(err)
+	}
+	if _, err := ConvertNaluLengthSize(longNalu, 4, 1); err == nil {
+		t.Error("no error for too long NALU")
+	}
+	if _, err := GetNalusFromSampleWithLengthSize(sample4, 3); err != ErrLengthSize {
+		t.Errorf("got error %v for length size 3", err)
+	}
+}
@@ -1,6 +1,7 @@
 package avc
 
 import (
+	"bytes"
 	"encoding/hex"
 	"testing"
 
@@ -20,6 +21,7 @@ func TestAvcDecoderConfigRecord(t *testing.T) {
 		AVCProfileIndication: 100,
 		ProfileCompatibility: 0,
 		AVCLevelIndication:   30,
+		NaluLengthSize:       4,
 		SPSnalus:             [][]byte{spsBytes},
 		PPSnalus:             [][]byte{ppsBytes},
 		ChromaFormat:         1,
@@ -36,3 +38,56 @@ func TestAvcDecoderConfigRecord(t *testing.T) {
 		t.Error(diff)
 	}
 }
+
+func TestAvcDecoderConfigRecordLengthSize(t *testing.T) {
+	byteData, _ := hex.DecodeString(avcDecoderConfigRecord)
+	byteData[4] = 0xfd // 2-byte NALU length
+	got, err := DecodeAVCDecConfRec(byteData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.NaluLengthSize != 2 {
+		t.Errorf("got NaluLengthSize %d instead of 2", got.NaluLengthSize)
+	}
+	buf := bytes.Buffer{}
+	if err := got.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), byteData) {
+		t.Errorf("encoded record differs from input")
+	}
+	byteData[4] = 0xfe // 3-byte NALU length is not allowed
+	if _, err := DecodeAVCDecConfRec(byteData); err != ErrLengthSize {
+		t.Errorf("got error %v instead of ErrLengthSize", err)
+	}
+}
+
+// TestAvcDecoderConfigRecordZeroLengthSize - a record without NaluLengthSize has 4-byte NAL unit lengths
+func TestAvcDecoderConfigRecordZeroLengthSize(t *testing.T) {
+	spsBytes, _ := hex.DecodeString(sps)
+	ppsBytes, _ := hex.DecodeString(pps)
+	dcr := DecConfRec{
+		AVCProfileIndication: 66,
+		AVCLevelIndication:   30,
+		SPSnalus:             [][]byte{spsBytes},
+		PPSnalus:             [][]byte{ppsBytes},
+	}
+	buf := bytes.Buffer{}
+	if err := dcr.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if b := buf.Bytes()[4]; b != 0xff {
+		t.Errorf("got length size byte %02x instead of ff", b)
+	}
+	got, err := DecodeAVCDecConfRec(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.LengthSize() != 4 {
+		t.Errorf("got length size %d instead of 4", got.LengthSize())
+	}
+	dcr.NaluLengthSize = 3
+	if err := dcr.Encode(&buf); err != ErrLengthSize {
+		t.Errorf("got error %v instead of ErrLengthSize for 3-byte lengths", err)
+	}
+}
@@ -12,7 +12,7 @@ import (
 // AVC parsing errors
 var (
 	ErrCannotParseAVCExtension = errors.New("Cannot parse SPS extensions")
-	ErrLengthSize              = errors.New("Can only handle 4byte NAL length size")
+	ErrLengthSize              = errors.New("Can only handle 1, 2, or 4 byte NAL length size")
 )
 
 // DecConfRec - AVCDecoderConfigurationRecord
@@ -20,6 +20,7 @@ type DecConfRec struct {
 	AVCProfileIndication byte
 	ProfileCompatibility byte
 	AVCLevelIndication   byte
+	NaluLengthSize       byte // NAL unit length size in samples (1, 2, or 4). 0 means 4
 	SPSnalus             [][]byte
 	PPSnalus             [][]byte
 	ChromaFormat         byte
@@ -44,6 +45,7 @@ func CreateAVCDecConfRec(spsNalus [][]byte, ppsNalus [][]byte, includePS bool) (
 		AVCProfileIndication: byte(sps.Profile),
 		ProfileCompatibility: byte(sps.ProfileCompatibility),
 		AVCLevelIndication:   byte(sps.Level),
+		NaluLengthSize:       4,
 		SPSnalus:             nil,
 		PPSnalus:             nil,
 		ChromaFormat:         1,
@@ -70,7 +72,7 @@ func DecodeAVCDecConfRec(data []byte) (DecConfRec, error) {
 	ProfileCompatibility := data[2]
 	AVCLevelIndication := data[3]
 	LengthSizeMinus1 := data[4] & 0x03 // The first 5 bits are 1
-	if LengthSizeMinus1 != 0x3 {
+	if !IsValidLengthSize(int(LengthSizeMinus1) + 1) {
 		return DecConfRec{}, ErrLengthSize
 	}
 	numSPS := data[5] & 0x1f // 5 bits following 3 reserved bits
@@ -95,6 +97,7 @@ func DecodeAVCDecConfRec(data []byte) (DecConfRec, error) {
 		AVCProfileIndication: AVCProfileIndication,
 		ProfileCompatibility: ProfileCompatibility,
 		AVCLevelIndication:   AVCLevelIndication,
+		NaluLengthSize:       LengthSizeMinus1 + 1,
 		SPSnalus:             spsNALUs,
 		PPSnalus:             ppsNALUs,
 	}
@@ -126,6 +129,14 @@ func DecodeAVCDecConfRec(data []byte) (DecConfRec, error) {
 	return adcr, nil
 }
 
+// LengthSize - NAL unit length size in samples, 4 if NaluLengthSize is not set
+func (a *DecConfRec) LengthSize() int {
+	if a.NaluLengthSize == 0 {
+		return 4
+	}
+	return int(a.NaluLengthSize)
+}
+
 // Size - total size in bytes
 func (a *DecConfRec) Size() uint64 {
 	totalSize := 7
@@ -159,13 +170,16 @@ func (a *DecConfRec) Encode(w io.Writer) error {
 
 // Encode - write an AVCDecConfRec to w
 func (a *DecConfRec) EncodeSW(sw bits.SliceWriter) error {
-
+	lengthSize := a.LengthSize()
+	if !IsValidLengthSize(lengthSize) {
+		return ErrLengthSize
+	}
 	var configurationVersion byte = 1
 	sw.WriteUint8(configurationVersion)
 	sw.WriteUint8(a.AVCProfileIndication)
 	sw.WriteUint8(a.ProfileCompatibility)
 	sw.WriteUint8(a.AVCLevelIndication)
-	sw.WriteUint8(0xff) // Set length to 4
+	sw.WriteUint8(0xfc | byte(lengthSize-1)) // 6 reserved bits set to 1
 
 	var nrSPS byte = byte(len(a.SPSnalus)) | 0xe0 // Added reserved 3 bits
 	sw.WriteUint8(nrSPS)
@@ -1,4 +1,5 @@
 /*
 Package avc -  parse AVC(H.264) NAL unit headers, slice headers and complete SPS and PPS.
+SPS and PPS can also be encoded to rewrite parameter sets.
 */
 package avc
@@ -0,0 +1,256 @@
+package avc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/jaypadia-frame/mp4ff/bits"
+)
+
+// SEIFillerPayloadType - SEI payload type of filler payload messages
+const SEIFillerPayloadType = 3
+
+// NaluFilter - transform the NAL units of a sample.
+// NAL units may be removed, changed or inserted, and the returned slice may share memory with nalus.
+// Filters are applied to samples in decode order.
+type NaluFilter func(nalus [][]byte) ([][]byte, error)
+
+// ChainNaluFilters - NaluFilter applying filters in order
+func ChainNaluFilters(filters ...NaluFilter) NaluFilter {
+	return func(nalus [][]byte) ([][]byte, error) {
+		var err error
+		for _, filter := range filters {
+			nalus, err = filter(nalus)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return nalus, nil
+	}
+}
+
+// FilterSample - apply filter to the NAL units of a sample with NAL unit length fields of lengthSize bytes.
+// The new sample has the same length size.
+func FilterSample(sample []byte, lengthSize int, filter NaluFilter) ([]byte, error) {
+	nalus, err := GetNalusFromSampleWithLengthSize(sample, lengthSize)
+	if err != nil {
+		return nil, err
+	}
+	nalus, err = filter(nalus)
+	if err != nil {
+		return nil, err
+	}
+	size := 0
+	for _, nalu := range nalus {
+		if uint64(len(nalu)) >= 1<<(8*uint(lengthSize)) {
+			return nil, fmt.Errorf("NAL unit size %d too big for %d-byte length", len(nalu), lengthSize)
+		}
+		size += lengthSize + len(nalu)
+	}
+	out := make([]byte, size)
+	pos := 0
+	for _, nalu := range nalus {
+		switch lengthSize {
+		case 1:
+			out[pos] = byte(len(nalu))
+		case 2:
+			binary.BigEndian.PutUint16(out[pos:], uint16(len(nalu)))
+		case 4:
+			binary.BigEndian.PutUint32(out[pos:], uint32(len(nalu)))
+		}
+		pos += lengthSize
+		pos += copy(out[pos:], nalu)
+	}
+	return out, nil
+}
+
+// RemoveNaluTypes - NaluFilter removing NAL units of any of naluTypes
+func RemoveNaluTypes(naluTypes ...NaluType) NaluFilter {
+	return func(nalus [][]byte) ([][]byte, error) {
+		out := nalus[:0:0]
+		for _, nalu := range nalus {
+			if len(nalu) > 0 && containsNaluType(naluTypes, GetNaluType(nalu[0])) {
+				continue
+			}
+			out = append(out, nalu)
+		}
+		return out, nil
+	}
+}
+
+func containsNaluType(naluTypes []NaluType, naluType NaluType) bool {
+	for _, t := range naluTypes {
+		if t == naluType {
+			return true
+		}
+	}
+	return false
+}
+
+// RemoveAUD - NaluFilter removing access unit delimiters
+func RemoveAUD() NaluFilter {
+	return RemoveNaluTypes(NALU_AUD)
+}
+
+// InsertAUD - NaluFilter inserting an access unit delimiter first in samples without one.
+// primary_pic_type is set from the slice types of the sample.
+func InsertAUD() NaluFilter {
+	return func(nalus [][]byte) ([][]byte, error) {
+		if len(nalus) > 0 && len(nalus[0]) > 0 && GetNaluType(nalus[0][0]) == NALU_AUD {
+			return nalus, nil
+		}
+		primaryPicType := byte(0) // I
+		for _, nalu := range nalus {
+			if len(nalu) == 0 {
+				continue
+			}
+			switch GetNaluType(nalu[0]) {
+			case NALU_NON_IDR, NALU_IDR:
+			default:
+				continue
+			}
+			sliceType, err := GetSliceTypeFromNALU(nalu)
+			if err != nil {
+				return nil, err
+			}
+			switch sliceType {
+			case SLICE_P:
+				if primaryPicType < 1 {
+					primaryPicType = 1 // I, P
+				}
+			case SLICE_B:
+				primaryPicType = 2 // I, P, B
+			case SLICE_SI, SLICE_SP:
+				primaryPicType = 7 // All slice types
+			}
+			if primaryPicType == 7 {
+				break
+			}
+		}
+		aud := []byte{byte(NALU_AUD), primaryPicType<<5 | 0x10}
+		return append([][]byte{aud}, nalus...), nil
+	}
+}
+
+// RemoveFillerData - NaluFilter removing filler data NAL units and filler payload SEI messages
+func RemoveFillerData() NaluFilter {
+	return ChainNaluFilters(RemoveNaluTypes(NALU_FILL), RemoveSEITypes(SEIFillerPayloadType))
+}
+
+// RemoveUnregisteredSEI - NaluFilter removing user data unregistered SEI messages
+func RemoveUnregisteredSEI() NaluFilter {
+	return RemoveSEITypes(SEIUnregisteredType)
+}
+
+// RemoveSEITypes - NaluFilter removing SEI messages of any of payloadTypes.
+// SEI NAL units without messages left are removed.
+func RemoveSEITypes(payloadTypes ...uint) NaluFilter {
+	return func(nalus [][]byte) ([][]byte, error) {
+		out := nalus[:0:0]
+		for _, nalu := range nalus {
+			if len(nalu) > 0 && GetNaluType(nalu[0]) == NALU_SEI {
+				var err error
+				nalu, err = RemoveSEIMessages(nalu, 1, payloadTypes)
+				if err != nil {
+					return nil, err
+				}
+				if nalu == nil {
+					continue
+				}
+			}
+			out = append(out, nalu)
+		}
+		return out, nil
+	}
+}
+
+// RemoveSEIMessages - remove SEI messages of any of payloadTypes from an SEI NAL unit with a header of
+// headerLength bytes (1 for AVC and 2 for HEVC). The NAL unit is returned unchanged if no message is removed,
+// and nil is returned if no message is left.
+func RemoveSEIMessages(nalu []byte, headerLength int, payloadTypes []uint) ([]byte, error) {
+	if len(nalu) <= headerLength {
+		return nil, fmt.Errorf("SEI NAL unit too short")
+	}
+	seiData, err := ExtractSEIData(bytes.NewReader(nalu[headerLength:]))
+	if err != nil {
+		return nil, err
+	}
+	kept := seiData[:0:0]
+	for _, sd := range seiData {
+		if !containsPayloadType(payloadTypes, sd.Type()) {
+			kept = append(kept, sd)
+		}
+	}
+	switch len(kept) {
+	case len(seiData):
+		return nalu, nil
+	case 0:
+		return nil, nil
+	}
+	buf := bytes.Buffer{}
+	w := bits.NewEBSPWriter(&buf)
+	for _, b := range nalu[:headerLength] {
+		w.Write(uint(b), 8)
+	}
+	for _, sd := range kept {
+		writeSEIValue(w, sd.Type())
+		writeSEIValue(w, sd.Size())
+		for _, b := range sd.Payload() {
+			w.Write(uint(b), 8)
+		}
+	}
+	w.WriteRbspTrailingBits()
+	if w.AccError() != nil {
+		return nil, w.AccError()
+	}
+	return buf.Bytes(), nil
+}
+
+func containsPayloadType(payloadTypes []uint, payloadType uint) bool {
+	for _, t := range payloadTypes {
+		if t == payloadType {
+			return true
+		}
+	}
+	return false
+}
+
+// writeSEIValue - write SEI payload type or size as a sequence of 0xff bytes and a last byte
+func writeSEIValue(w *bits.EBSPWriter, value uint) {
+	for value >= 0xff {
+		w.Write(0xff, 8)
+		value -= 0xff
+	}
+	w.Write(value, 8)
+}
+
+// RemoveRedundantParameterSets - NaluFilter removing SPS and PPS NAL units which are identical to
+// one of outOfBand, e.g. the parameter sets of the avcC box, or to an earlier NAL unit in the same sample.
+func RemoveRedundantParameterSets(outOfBand [][]byte) NaluFilter {
+	return func(nalus [][]byte) ([][]byte, error) {
+		out := nalus[:0:0]
+		for _, nalu := range nalus {
+			if len(nalu) > 0 {
+				switch GetNaluType(nalu[0]) {
+				case NALU_SPS, NALU_PPS:
+					if ContainsNalu(outOfBand, nalu) || ContainsNalu(out, nalu) {
+						continue
+					}
+				}
+			}
+			out = append(out, nalu)
+		}
+		return out, nil
+	}
+}
+
+// ContainsNalu - true if nalus has a NAL unit identical to nalu
+func ContainsNalu(nalus [][]byte, nalu []byte) bool {
+	for _, n := range nalus {
+		if bytes.Equal(n, nalu) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package avc
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestNaluFilters(t *testing.T) {
+	hexNalus := func(hexStrs ...string) [][]byte {
+		nalus := make([][]byte, len(hexStrs))
+		for i, h := range hexStrs {
+			nalus[i], _ = hex.DecodeString(h)
+		}
+		return nalus
+	}
+	const (
+		audHex  = "0950"
+		spsHex  = "6764001eacd940a02ff9610000030001000003003c8f162d96"
+		ppsHex  = "68ebecb22c"
+		fillHex = "0cffffff80"
+		// Messages of type 0, 5 (unregistered), and 3 (filler payload)
+		seiHex     = "060007810f1c00507440" + "0511" + "00112233445566778899aabbccddeeff" + "01" + "0302ffff" + "80"
+		seiType0   = "060007810f1c0050744080"
+		seiNoFill  = "060007810f1c00507440" + "0511" + "00112233445566778899aabbccddeeff" + "01" + "80"
+		pSliceHex  = "41c0"
+		bSliceHex  = "01a0"
+		fillerOnly = "060302ffff80"
+	)
+	sample := hexNalus(spsHex, ppsHex, spsHex, seiHex, fillerOnly, pSliceHex, bSliceHex, fillHex)
+
+	testCases := []struct {
+		desc   string
+		filter NaluFilter
+		want   [][]byte
+	}{
+		{"insert AUD", InsertAUD(),
+			hexNalus(audHex, spsHex, ppsHex, spsHex, seiHex, fillerOnly, pSliceHex, bSliceHex, fillHex)},
+		{"remove filler", RemoveFillerData(),
+			hexNalus(spsHex, ppsHex, spsHex, seiNoFill, pSliceHex, bSliceHex)},
+		{"remove SEI types 3 and 5", RemoveSEITypes(3, 5),
+			hexNalus(spsHex, ppsHex, spsHex, seiType0, pSliceHex, bSliceHex, fillHex)},
+		{"remove redundant parameter sets", RemoveRedundantParameterSets(hexNalus(ppsHex)),
+			hexNalus(spsHex, seiHex, fillerOnly, pSliceHex, bSliceHex, fillHex)},
+		{"chain", ChainNaluFilters(RemoveNaluTypes(NALU_SPS, NALU_PPS), RemoveUnregisteredSEI(), RemoveFillerData(),
+			InsertAUD(), RemoveAUD()),
+			hexNalus(seiType0, pSliceHex, bSliceHex)},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			in := make([][]byte, len(sample))
+			copy(in, sample)
+			got, err := tc.filter(in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("got %d NAL units instead of %d", len(got), len(tc.want))
+			}
+			for i := range got {
+				if !bytes.Equal(got[i], tc.want[i]) {
+					t.Errorf("NAL unit %d: got %x instead of %x", i, got[i], tc.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestFilterSample(t *testing.T) {
+	sample := []byte{0, 2, 0x09, 0xf0, 0, 3, 0x41, 0xc0, 0x80}
+	got, err := FilterSample(sample, 2, RemoveAUD())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := sample[4:]; !bytes.Equal(got, want) {
+		t.Errorf("got %x instead of %x", got, want)
+	}
+	got, err = FilterSample(got, 2, InsertAUD())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []byte{0, 2, 0x09, 0x30, 0, 3, 0x41, 0xc0, 0x80}; !bytes.Equal(got, want) {
+		t.Errorf("got %x instead of %x", got, want)
+	}
+}
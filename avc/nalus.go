@@ -7,20 +7,83 @@ import (
 
 // GetNalusFromSample - get nalus by following 4 byte length fields
 func GetNalusFromSample(sample []byte) ([][]byte, error) {
+	return GetNalusFromSampleWithLengthSize(sample, 4)
+}
+
+// GetNalusFromSampleWithLengthSize - get nalus by following length fields of lengthSize (1, 2, or 4) bytes
+func GetNalusFromSampleWithLengthSize(sample []byte, lengthSize int) ([][]byte, error) {
+	if !IsValidLengthSize(lengthSize) {
+		return nil, ErrLengthSize
+	}
 	naluList := make([][]byte, 0)
 	length := len(sample)
-	if length < 4 {
-		return naluList, fmt.Errorf("Less than 4 bytes, No NALUs")
-	}
-	var pos uint32 = 0
-	for pos < uint32(length-4) {
-		naluLength := binary.BigEndian.Uint32(sample[pos : pos+4])
-		pos += 4
-		if int(pos+naluLength) > len(sample) {
+	if length < lengthSize {
+		return naluList, fmt.Errorf("Less than %d bytes, No NALUs", lengthSize)
+	}
+	pos := 0
+	for pos < length-lengthSize {
+		naluLength := ReadNaluLength(sample[pos:], lengthSize)
+		pos += lengthSize
+		if uint64(pos)+uint64(naluLength) > uint64(length) {
 			return nil, fmt.Errorf("NAL length fields are bad. Not video?")
 		}
-		naluList = append(naluList, sample[pos:pos+naluLength])
-		pos += naluLength
+		naluList = append(naluList, sample[pos:pos+int(naluLength)])
+		pos += int(naluLength)
 	}
 	return naluList, nil
 }
+
+// IsValidLengthSize - is lengthSize an allowed NAL unit length size (1, 2, or 4 bytes)
+func IsValidLengthSize(lengthSize int) bool {
+	return lengthSize == 1 || lengthSize == 2 || lengthSize == 4
+}
+
+// ReadNaluLength - read NAL unit length field of lengthSize (1, 2, or 4) bytes at start of b
+func ReadNaluLength(b []byte, lengthSize int) uint32 {
+	switch lengthSize {
+	case 1:
+		return uint32(b[0])
+	case 2:
+		return uint32(binary.BigEndian.Uint16(b))
+	default:
+		return binary.BigEndian.Uint32(b)
+	}
+}
+
+// ConvertNaluLengthSize - convert sample with NAL unit length fields of fromSize bytes to toSize bytes.
+// The sample is returned unchanged if the sizes are equal. An error is returned if a NAL unit
+// is too long for the new length size.
+func ConvertNaluLengthSize(sample []byte, fromSize, toSize int) ([]byte, error) {
+	if !IsValidLengthSize(fromSize) || !IsValidLengthSize(toSize) {
+		return nil, ErrLengthSize
+	}
+	if fromSize == toSize {
+		return sample, nil
+	}
+	nalus, err := GetNalusFromSampleWithLengthSize(sample, fromSize)
+	if err != nil {
+		return nil, err
+	}
+	maxLength := uint64(1)<<(8*uint(toSize)) - 1
+	outSize := 0
+	for _, nalu := range nalus {
+		if uint64(len(nalu)) > maxLength {
+			return nil, fmt.Errorf("NALU length %d too big for %d-byte length field", len(nalu), toSize)
+		}
+		outSize += toSize + len(nalu)
+	}
+	out := make([]byte, 0, outSize)
+	for _, nalu := range nalus {
+		naluLength := uint32(len(nalu))
+		switch toSize {
+		case 1:
+			out = append(out, byte(naluLength))
+		case 2:
+			out = append(out, byte(naluLength>>8), byte(naluLength))
+		default:
+			out = append(out, byte(naluLength>>24), byte(naluLength>>16), byte(naluLength>>8), byte(naluLength))
+		}
+		out = append(out, nalu...)
+	}
+	return out, nil
+}
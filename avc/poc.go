@@ -0,0 +1,158 @@
+package avc
+
+import (
+	"fmt"
+)
+
+// POCCalculator - calculate picture order count of AVC pictures according to 14496-10 Sec. 8.2.1.
+// Pictures must be given in decode order, since the calculation depends on previous pictures.
+type POCCalculator struct {
+	prevPicOrderCntMsb int
+	prevPicOrderCntLsb int
+	prevFrameNumOffset int
+	prevFrameNum       uint
+	prevHadMMCO5       bool
+}
+
+// NewPOCCalculator - create a calculator starting with an empty state
+func NewPOCCalculator() *POCCalculator {
+	return &POCCalculator{}
+}
+
+// PicOrderCnt - picture order count of picture with NAL header naluHdr and slice header sh.
+// The result is the minimum of the top and bottom field order counts for frames,
+// and the field order count of the field for field pictures.
+// Only call this once per picture, e.g. for the slice with first_mb_in_slice equal to 0.
+func (c *POCCalculator) PicOrderCnt(naluHdr byte, sh *SliceHeader, sps *SPS) (int, error) {
+	isIDR := GetNaluType(naluHdr) == NALU_IDR
+	isRef := GetNalRefIDC(naluHdr) != 0
+	hasMMCO5 := false
+	if sh.DecRefPicMarking != nil {
+		for _, p := range sh.DecRefPicMarking.AdaptiveRefPicMarkingParams {
+			if p.MemoryManagementControlOperation == 5 {
+				hasMMCO5 = true
+			}
+		}
+	}
+	var top, bottom int
+	var frameNumOffset int
+	switch sps.PicOrderCntType {
+	case 0:
+		if isIDR {
+			c.prevPicOrderCntMsb, c.prevPicOrderCntLsb = 0, 0
+		}
+		maxPicOrderCntLsb := 1 << (sps.Log2MaxPicOrderCntLsbMinus4 + 4)
+		lsb := int(sh.PicOrderCntLSB)
+		msb := c.prevPicOrderCntMsb
+		switch {
+		case lsb < c.prevPicOrderCntLsb && c.prevPicOrderCntLsb-lsb >= maxPicOrderCntLsb/2:
+			msb += maxPicOrderCntLsb
+		case lsb > c.prevPicOrderCntLsb && lsb-c.prevPicOrderCntLsb > maxPicOrderCntLsb/2:
+			msb -= maxPicOrderCntLsb
+		}
+		top = msb + lsb
+		bottom = top + sh.DeltaPicOrderCntBottom
+		if sh.FieldPicFlag {
+			bottom = top
+		}
+		if isRef {
+			switch {
+			case hasMMCO5 && sh.BottomFieldFlag:
+				c.prevPicOrderCntMsb, c.prevPicOrderCntLsb = 0, 0
+			case hasMMCO5:
+				c.prevPicOrderCntMsb, c.prevPicOrderCntLsb = 0, top-minInt(top, bottom)
+			default:
+				c.prevPicOrderCntMsb, c.prevPicOrderCntLsb = msb, lsb
+			}
+		}
+	case 1, 2:
+		maxFrameNum := 1 << (sps.Log2MaxFrameNumMinus4 + 4)
+		prevFrameNumOffset := c.prevFrameNumOffset
+		if c.prevHadMMCO5 {
+			prevFrameNumOffset = 0
+		}
+		switch {
+		case isIDR:
+			frameNumOffset = 0
+		case c.prevFrameNum > sh.FrameNum:
+			frameNumOffset = prevFrameNumOffset + maxFrameNum
+		default:
+			frameNumOffset = prevFrameNumOffset
+		}
+		if sps.PicOrderCntType == 1 {
+			top, bottom = picOrderCntType1(sh, sps, frameNumOffset, isRef)
+			break
+		}
+		tempPicOrderCnt := 2 * (frameNumOffset + int(sh.FrameNum))
+		switch {
+		case isIDR:
+			tempPicOrderCnt = 0
+		case !isRef:
+			tempPicOrderCnt--
+		}
+		top, bottom = tempPicOrderCnt, tempPicOrderCnt
+	default:
+		return 0, fmt.Errorf("pic_order_cnt_type %d not supported", sps.PicOrderCntType)
+	}
+	c.prevFrameNumOffset = frameNumOffset
+	c.prevFrameNum = sh.FrameNum
+	c.prevHadMMCO5 = hasMMCO5
+	if hasMMCO5 {
+		c.prevFrameNum = 0
+	}
+	switch {
+	case !sh.FieldPicFlag:
+		return minInt(top, bottom), nil
+	case sh.BottomFieldFlag:
+		return bottom, nil
+	default:
+		return top, nil
+	}
+}
+
+// picOrderCntType1 - top and bottom field order counts for pic_order_cnt_type 1 (8.2.1.2)
+func picOrderCntType1(sh *SliceHeader, sps *SPS, frameNumOffset int, isRef bool) (top, bottom int) {
+	numRefFramesInPicOrderCntCycle := len(sps.RefFramesInPicOrderCntCycle)
+	absFrameNum := 0
+	if numRefFramesInPicOrderCntCycle != 0 {
+		absFrameNum = frameNumOffset + int(sh.FrameNum)
+	}
+	if !isRef && absFrameNum > 0 {
+		absFrameNum--
+	}
+	expectedPicOrderCnt := 0
+	if absFrameNum > 0 {
+		picOrderCntCycleCnt := (absFrameNum - 1) / numRefFramesInPicOrderCntCycle
+		frameNumInPicOrderCntCycle := (absFrameNum - 1) % numRefFramesInPicOrderCntCycle
+		expectedDeltaPerPicOrderCntCycle := 0
+		for _, offset := range sps.RefFramesInPicOrderCntCycle {
+			expectedDeltaPerPicOrderCntCycle += offset
+		}
+		expectedPicOrderCnt = picOrderCntCycleCnt * expectedDeltaPerPicOrderCntCycle
+		for i := 0; i <= frameNumInPicOrderCntCycle; i++ {
+			expectedPicOrderCnt += sps.RefFramesInPicOrderCntCycle[i]
+		}
+	}
+	if !isRef {
+		expectedPicOrderCnt += sps.OffsetForNonRefPic
+	}
+	switch {
+	case !sh.FieldPicFlag:
+		top = expectedPicOrderCnt + sh.DeltaPicOrderCnt[0]
+		bottom = top + sps.OffsetForTopToBottomField + sh.DeltaPicOrderCnt[1]
+	case !sh.BottomFieldFlag:
+		top = expectedPicOrderCnt + sh.DeltaPicOrderCnt[0]
+		bottom = top
+	default:
+		bottom = expectedPicOrderCnt + sps.OffsetForTopToBottomField + sh.DeltaPicOrderCnt[0]
+		top = bottom
+	}
+	return top, bottom
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
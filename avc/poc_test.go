@@ -0,0 +1,68 @@
+package avc
+
+import (
+	"testing"
+)
+
+func TestPicOrderCnt(t *testing.T) {
+	type pic struct {
+		naluHdr  byte
+		frameNum uint
+		lsb      uint
+		delta    [2]int
+		poc      int
+	}
+	cases := []struct {
+		desc string
+		sps  *SPS
+		pics []pic
+	}{
+		{
+			desc: "type 0 with wrap-around",
+			sps:  &SPS{PicOrderCntType: 0, Log2MaxPicOrderCntLsbMinus4: 0, FrameMbsOnlyFlag: true},
+			pics: []pic{
+				{naluHdr: 0x65, lsb: 0, poc: 0},
+				{naluHdr: 0x41, frameNum: 1, lsb: 6, poc: 6},
+				{naluHdr: 0x01, frameNum: 2, lsb: 2, poc: 2},
+				{naluHdr: 0x41, frameNum: 2, lsb: 12, poc: 12},
+				{naluHdr: 0x41, frameNum: 3, lsb: 2, poc: 18},
+				{naluHdr: 0x65, lsb: 4, poc: 4},
+			},
+		},
+		{
+			desc: "type 1",
+			sps: &SPS{PicOrderCntType: 1, FrameMbsOnlyFlag: true, OffsetForNonRefPic: -4,
+				RefFramesInPicOrderCntCycle: []int{6, 2}},
+			pics: []pic{
+				{naluHdr: 0x65, poc: 0},
+				{naluHdr: 0x41, frameNum: 1, poc: 6},
+				{naluHdr: 0x01, frameNum: 2, poc: 2},
+				{naluHdr: 0x41, frameNum: 2, delta: [2]int{1, 0}, poc: 9},
+				{naluHdr: 0x41, frameNum: 3, poc: 14},
+			},
+		},
+		{
+			desc: "type 2 with frame_num wrap-around",
+			sps:  &SPS{PicOrderCntType: 2, Log2MaxFrameNumMinus4: 0, FrameMbsOnlyFlag: true},
+			pics: []pic{
+				{naluHdr: 0x65, poc: 0},
+				{naluHdr: 0x41, frameNum: 15, poc: 30},
+				{naluHdr: 0x01, frameNum: 0, poc: 31},
+				{naluHdr: 0x41, frameNum: 0, poc: 32},
+			},
+		},
+	}
+	for _, tc := range cases {
+		c := NewPOCCalculator()
+		for i, p := range tc.pics {
+			sh := &SliceHeader{FrameNum: p.frameNum, PicOrderCntLSB: p.lsb, DeltaPicOrderCnt: p.delta}
+			poc, err := c.PicOrderCnt(p.naluHdr, sh, tc.sps)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if poc != p.poc {
+				t.Errorf("%s: picture %d got poc %d instead of %d", tc.desc, i, poc, p.poc)
+			}
+		}
+	}
+}
@@ -11,6 +11,7 @@ import (
 
 // PPS - Picture Parameter Set
 type PPS struct {
+	NalRefIdc                             uint // nal_ref_idc of the NAL unit header. 0 is encoded as 3
 	PicParameterSetID                     uint
 	SeqParameterSetID                     uint
 	EntropyCodingModeFlag                 bool
@@ -61,6 +62,7 @@ func ParsePPSNALUnit(data []byte, sps *SPS) (*PPS, error) {
 	if naluType != NALU_PPS {
 		return nil, ErrNotPPS
 	}
+	pps.NalRefIdc = GetNalRefIDC(byte(naluHdr))
 
 	pps.PicParameterSetID = reader.ReadExpGolomb()
 	pps.SeqParameterSetID = reader.ReadExpGolomb()
@@ -89,8 +91,8 @@ func ParsePPSNALUnit(data []byte, sps *SPS) (*PPS, error) {
 		case 6:
 			// slice_group_id[i] has Ceil(Log2(num_slice_groups_minus1 +1) bits)
 			nrBits := ceilLog2(pps.NumSliceGroupsMinus1 + 1)
-
-			for iGroup := uint(0); iGroup <= pps.NumSliceGroupsMinus1; iGroup++ {
+			pps.PicSizeInMapUnitsMinus1 = reader.ReadExpGolomb()
+			for i := uint(0); i <= pps.PicSizeInMapUnitsMinus1; i++ {
 				sgi := reader.Read(nrBits)
 				pps.SliceGroupID = append(pps.SliceGroupID, sgi)
 			}
@@ -117,6 +119,7 @@ func ParsePPSNALUnit(data []byte, sps *SPS) (*PPS, error) {
 		}
 		return nil, err
 	}
+	pps.SecondChromaQpIndexOffset = pps.ChromaQpIndexOffset // Inferred if not present
 	if moreRbsp {
 		pps.Transform8x8ModeFlag = reader.ReadFlag()
 		pps.PicScalingMatrixPresentFlag = reader.ReadFlag()
@@ -131,23 +134,16 @@ func ParsePPSNALUnit(data []byte, sps *SPS) (*PPS, error) {
 				} else {
 					nrScalingLists += 6
 				}
-				pps.PicScalingLists = make([]ScalingList, nrScalingLists)
-
-				for i := 0; i < nrScalingLists; i++ {
-					picScalingPresent := reader.ReadFlag()
-					if !picScalingPresent {
-						pps.PicScalingLists[i] = nil
-						continue
-					}
-					sizeOfScalingList := 16 // 4x4 for i < 6
-					if i >= 6 {
-						sizeOfScalingList = 64 // 8x8 for i >= 6
-					}
-					pps.PicScalingLists[i] = readScalingList(reader, sizeOfScalingList)
-					if err != nil {
-						return nil, err
-					}
+			}
+			pps.PicScalingLists = make([]ScalingList, nrScalingLists)
+
+			for i := 0; i < nrScalingLists; i++ {
+				picScalingPresent := reader.ReadFlag()
+				if !picScalingPresent {
+					pps.PicScalingLists[i] = nil
+					continue
 				}
+				pps.PicScalingLists[i] = readScalingList(reader, i)
 			}
 		}
 		pps.SecondChromaQpIndexOffset = reader.ReadSignedGolomb()
@@ -170,6 +166,79 @@ func ParsePPSNALUnit(data []byte, sps *SPS) (*PPS, error) {
 	return pps, nil
 }
 
+// Encode - write PPS as NAL unit with NalRefIdc in the header byte and emulation prevention bytes.
+// The fields after redundant_pic_cnt_present_flag are only written if they differ from their inferred values.
+func (p *PPS) Encode(w io.Writer) error {
+	ew := bits.NewEBSPWriter(w)
+	ew.Write(nalHeader(p.NalRefIdc, NALU_PPS), 8)
+	ew.WriteExpGolomb(p.PicParameterSetID)
+	ew.WriteExpGolomb(p.SeqParameterSetID)
+	writeFlag(ew, p.EntropyCodingModeFlag)
+	writeFlag(ew, p.BottomFieldPicOrderInFramePresentFlag)
+	ew.WriteExpGolomb(p.NumSliceGroupsMinus1)
+
+	if p.NumSliceGroupsMinus1 > 0 {
+		nrSliceGroups := int(p.NumSliceGroupsMinus1) + 1
+		ew.WriteExpGolomb(p.SliceGroupMapType)
+		switch p.SliceGroupMapType {
+		case 0:
+			if len(p.RunLengthMinus1) != nrSliceGroups {
+				return fmt.Errorf("%d run lengths for %d slice groups", len(p.RunLengthMinus1), nrSliceGroups)
+			}
+			for _, rl := range p.RunLengthMinus1 {
+				ew.WriteExpGolomb(rl)
+			}
+		case 2:
+			if len(p.TopLeft) != nrSliceGroups || len(p.BottomRight) != nrSliceGroups {
+				return fmt.Errorf("%d top left and %d bottom right for %d slice groups",
+					len(p.TopLeft), len(p.BottomRight), nrSliceGroups)
+			}
+			for iGroup := 0; iGroup < nrSliceGroups; iGroup++ {
+				ew.WriteExpGolomb(p.TopLeft[iGroup])
+				ew.WriteExpGolomb(p.BottomRight[iGroup])
+			}
+		case 3, 4, 5:
+			writeFlag(ew, p.SliceGroupChangeDirectionFlag)
+			ew.WriteExpGolomb(p.SliceGroupChangeRateMinus1)
+		case 6:
+			if len(p.SliceGroupID) != int(p.PicSizeInMapUnitsMinus1)+1 {
+				return fmt.Errorf("%d slice group ids for %d map units", len(p.SliceGroupID), p.PicSizeInMapUnitsMinus1+1)
+			}
+			nrBits := ceilLog2(p.NumSliceGroupsMinus1 + 1)
+			ew.WriteExpGolomb(p.PicSizeInMapUnitsMinus1)
+			for _, sgi := range p.SliceGroupID {
+				ew.Write(sgi, nrBits)
+			}
+		}
+	}
+	ew.WriteExpGolomb(p.NumRefIdxI0DefaultActiveMinus1)
+	ew.WriteExpGolomb(p.NumRefIdxI1DefaultActiveMinus1)
+	writeFlag(ew, p.WeightedPredFlag)
+	ew.Write(p.WeightedBipredIDC, 2)
+	ew.WriteSignedGolomb(p.PicInitQpMinus26)
+	ew.WriteSignedGolomb(p.PicInitQsMinus26)
+	ew.WriteSignedGolomb(p.ChromaQpIndexOffset)
+	writeFlag(ew, p.DeblockingFilterControlPresentFlag)
+	writeFlag(ew, p.ConstrainedIntraPredFlag)
+	writeFlag(ew, p.RedundantPicCntPresentFlag)
+	if p.Transform8x8ModeFlag || p.PicScalingMatrixPresentFlag ||
+		p.SecondChromaQpIndexOffset != p.ChromaQpIndexOffset {
+		writeFlag(ew, p.Transform8x8ModeFlag)
+		writeFlag(ew, p.PicScalingMatrixPresentFlag)
+		if p.PicScalingMatrixPresentFlag {
+			for i, list := range p.PicScalingLists {
+				writeFlag(ew, list != nil)
+				if list != nil {
+					writeScalingList(ew, list, i)
+				}
+			}
+		}
+		ew.WriteSignedGolomb(p.SecondChromaQpIndexOffset)
+	}
+	ew.WriteRbspTrailingBits()
+	return ew.AccError()
+}
+
 // ceilLog2 - nr bits needed to represent numbers 0 - n-1 values
 func ceilLog2(n uint) int {
 	for i := 0; i < 32; i++ {
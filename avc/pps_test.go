@@ -1,6 +1,7 @@
 package avc
 
 import (
+	"bytes"
 	"encoding/hex"
 	"testing"
 
@@ -13,6 +14,7 @@ func TestPPSParser(t *testing.T) {
 	byteData, _ := hex.DecodeString(pps1)
 
 	wanted := &PPS{
+		NalRefIdc:                             3,
 		PicParameterSetID:                     0,
 		SeqParameterSetID:                     0,
 		EntropyCodingModeFlag:                 true,
@@ -42,3 +44,73 @@ func TestPPSParser(t *testing.T) {
 		t.Error(diff)
 	}
 }
+
+// TestPPSSliceGroupMapType6 - explicit slice group ids are preceded by pic_size_in_map_units_minus1
+func TestPPSSliceGroupMapType6(t *testing.T) {
+	// 2 slice groups, pic_size_in_map_units_minus1 = 3, and 1-bit slice_group_id 1, 0, 1, 1
+	byteData, _ := hex.DecodeString("68c4725e3c80")
+	got, err := ParsePPSNALUnit(byteData, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.NumSliceGroupsMinus1 != 1 || got.SliceGroupMapType != 6 || got.PicSizeInMapUnitsMinus1 != 3 {
+		t.Errorf("got %d slice groups of map type %d and %d map units", got.NumSliceGroupsMinus1+1,
+			got.SliceGroupMapType, got.PicSizeInMapUnitsMinus1+1)
+	}
+	if diff := deep.Equal(got.SliceGroupID, []uint{1, 0, 1, 1}); diff != nil {
+		t.Error(diff)
+	}
+	if !got.DeblockingFilterControlPresentFlag || got.Transform8x8ModeFlag {
+		t.Errorf("fields after slice_group_id not parsed correctly")
+	}
+}
+
+func TestPPSEncode(t *testing.T) {
+	for _, ppsHex := range []string{pps1, pps} {
+		byteData, _ := hex.DecodeString(ppsHex)
+		p, err := ParsePPSNALUnit(byteData, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err := p.Encode(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf.Bytes(), byteData) {
+			t.Errorf("encoded PPS %x differs from %s", buf.Bytes(), ppsHex)
+		}
+	}
+
+	sps := &SPS{ChromaFormatIDC: 1}
+	custom := make(ScalingList, 64)
+	for i := range custom {
+		custom[i] = 20 + i/8
+	}
+	p := &PPS{
+		NalRefIdc:                   1,
+		PicParameterSetID:           1,
+		NumSliceGroupsMinus1:        2,
+		SliceGroupMapType:           6,
+		PicSizeInMapUnitsMinus1:     3,
+		SliceGroupID:                []uint{0, 2, 1, 1},
+		ChromaQpIndexOffset:         -1,
+		Transform8x8ModeFlag:        true,
+		PicScalingMatrixPresentFlag: true,
+		PicScalingLists:             []ScalingList{nil, nil, nil, default4x4Inter, nil, nil, custom, nil},
+		SecondChromaQpIndexOffset:   2,
+	}
+	var buf bytes.Buffer
+	if err := p.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Bytes()[0] != 0x28 {
+		t.Errorf("got NAL header byte %02x instead of 28 for nal_ref_idc 1", buf.Bytes()[0])
+	}
+	got, err := ParsePPSNALUnit(buf.Bytes(), sps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := deep.Equal(got, p); diff != nil {
+		t.Error(diff)
+	}
+}
@@ -30,7 +30,11 @@ type SEIMessage interface {
 	Payload() []byte
 }
 
-// DecodeSEIMessage decodes an SEIMessage
+// DecodeSEIMessage decodes an SEIMessage.
+// Only registered and unregistered messages are decoded here.
+//
+// Deprecated: Use sei.DecodeAVCSEIMessage, which also decodes buffering_period, pic_timing,
+// recovery_point and other typed messages.
 func DecodeSEIMessage(sd *SEIData) (SEIMessage, error) {
 	switch sd.Type() {
 	case 4:
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io"
 
 	"github.com/jaypadia-frame/mp4ff/bits"
 )
@@ -18,6 +19,7 @@ var (
 
 // SPS - AVC SPS parameters
 type SPS struct {
+	NalRefIdc                       uint // nal_ref_idc of the NAL unit header. 0 is encoded as 3
 	Profile                         uint
 	ProfileCompatibility            uint
 	Level                           uint
@@ -33,9 +35,9 @@ type SPS struct {
 	PicOrderCntType                 uint
 	Log2MaxPicOrderCntLsbMinus4     uint
 	DeltaPicOrderAlwaysZeroFlag     bool
-	OffsetForNonRefPic              uint
-	OffsetForTopToBottomField       uint
-	RefFramesInPicOrderCntCycle     []uint
+	OffsetForNonRefPic              int
+	OffsetForTopToBottomField       int
+	RefFramesInPicOrderCntCycle     []int
 	NumRefFrames                    uint
 	GapsInFrameNumValueAllowedFlag  bool
 	FrameMbsOnlyFlag                bool
@@ -90,6 +92,7 @@ type VUIParameters struct {
 	Log2MaxMvLengthVertical            uint
 	MaxNumReorderFrames                uint
 	MaxDecFrameBuffering               uint
+	aspectRatioOnly                    bool // Parsing stopped after aspect ratio
 }
 
 // HrdParameters inside VUI
@@ -111,6 +114,16 @@ type CpbEntry struct {
 	CbrFlag            bool
 }
 
+// BitRate - bit rate in bits/s for a CPB specification
+func (h *HrdParameters) BitRate(cpb CpbEntry) uint64 {
+	return uint64(cpb.BitRateValueMinus1+1) << (6 + h.BitRateScale)
+}
+
+// CpbSize - CPB size in bits for a CPB specification
+func (h *HrdParameters) CpbSize(cpb CpbEntry) uint64 {
+	return uint64(cpb.CpbSizeValueMinus1+1) << (4 + h.CpbSizeScale)
+}
+
 // ParseSPSNALUnit - Parse AVC SPS NAL unit starting with NAL header
 func ParseSPSNALUnit(data []byte, parseVUIBeyondAspectRatio bool) (*SPS, error) {
 
@@ -125,6 +138,7 @@ func ParseSPSNALUnit(data []byte, parseVUIBeyondAspectRatio bool) (*SPS, error)
 	if nalType != NALU_SPS {
 		return nil, ErrNotSPS
 	}
+	sps.NalRefIdc = GetNalRefIDC(byte(nalHdr))
 
 	sps.Profile = reader.Read(8)
 	sps.ProfileCompatibility = reader.Read(8)
@@ -160,11 +174,7 @@ func ParseSPSNALUnit(data []byte, parseVUIBeyondAspectRatio bool) (*SPS, error)
 					sps.SeqScalingLists[i] = nil
 					continue
 				}
-				sizeOfScalingList := 16 // 4x4 for i < 6
-				if i >= 6 {
-					sizeOfScalingList = 64 // 8x8 for i >= 6
-				}
-				sps.SeqScalingLists[i] = readScalingList(reader, sizeOfScalingList)
+				sps.SeqScalingLists[i] = readScalingList(reader, i)
 			}
 		}
 	default:
@@ -177,12 +187,12 @@ func ParseSPSNALUnit(data []byte, parseVUIBeyondAspectRatio bool) (*SPS, error)
 		sps.Log2MaxPicOrderCntLsbMinus4 = reader.ReadExpGolomb()
 	} else if sps.PicOrderCntType == 1 {
 		sps.DeltaPicOrderAlwaysZeroFlag = reader.ReadFlag()
-		sps.OffsetForNonRefPic = reader.ReadExpGolomb()
-		sps.OffsetForTopToBottomField = reader.ReadExpGolomb()
+		sps.OffsetForNonRefPic = reader.ReadSignedGolomb()
+		sps.OffsetForTopToBottomField = reader.ReadSignedGolomb()
 		numRefFramesInPicOrderCntCycle := reader.ReadExpGolomb()
-		sps.RefFramesInPicOrderCntCycle = make([]uint, numRefFramesInPicOrderCntCycle)
+		sps.RefFramesInPicOrderCntCycle = make([]int, numRefFramesInPicOrderCntCycle)
 		for i := 0; i < int(numRefFramesInPicOrderCntCycle); i++ {
-			sps.RefFramesInPicOrderCntCycle[i] = reader.ReadExpGolomb()
+			sps.RefFramesInPicOrderCntCycle[i] = reader.ReadSignedGolomb()
 		}
 	}
 
@@ -192,32 +202,20 @@ func ParseSPSNALUnit(data []byte, parseVUIBeyondAspectRatio bool) (*SPS, error)
 	picWidthInMbsUnitsMinus1 := reader.ReadExpGolomb()
 	picHeightInMbsUnitsMinus1 := reader.ReadExpGolomb()
 
-	sps.Width = (picWidthInMbsUnitsMinus1 + 1) * 16
-	sps.Height = (picHeightInMbsUnitsMinus1 + 1) * 16
-
 	sps.FrameMbsOnlyFlag = reader.ReadFlag()
 	if !sps.FrameMbsOnlyFlag {
 		sps.MbAdaptiveFrameFieldFlag = reader.ReadFlag()
 	}
+
+	sps.Width = (picWidthInMbsUnitsMinus1 + 1) * 16
+	sps.Height = (picHeightInMbsUnitsMinus1 + 1) * 16 * sps.fieldsPerMapUnit()
+
 	sps.Direct8x8InferenceFlag = reader.ReadFlag()
 	sps.FrameCroppingFlag = reader.ReadFlag()
-	var cropUnitX, cropUnitY uint
-	var frameMbsOnly uint = 0
-	if sps.FrameMbsOnlyFlag {
-		frameMbsOnly = 1
-	}
 	if sps.FrameCroppingFlag {
-		switch sps.ChromaFormatIDC {
-		case 0:
-			cropUnitX, cropUnitY = 1, 2-frameMbsOnly
-		case 1:
-			cropUnitX, cropUnitY = 2, 2*(2-frameMbsOnly)
-		case 2:
-			cropUnitX, cropUnitY = 2, 1*(2-frameMbsOnly)
-		case 3: //This lacks one extra check?
-			cropUnitX, cropUnitY = 1, 1*(2-frameMbsOnly)
-		default:
-			return nil, fmt.Errorf("Non-vaild chroma_format_idc value: %d", sps.ChromaFormatIDC)
+		cropUnitX, cropUnitY, err := sps.cropUnits()
+		if err != nil {
+			return nil, err
 		}
 
 		sps.FrameCropLeftOffset = reader.ReadExpGolomb()
@@ -242,6 +240,125 @@ func ParseSPSNALUnit(data []byte, parseVUIBeyondAspectRatio bool) (*SPS, error)
 	return sps, reader.AccError()
 }
 
+// Encode - write SPS as NAL unit with NalRefIdc in the header byte and emulation prevention bytes.
+// Width and Height must be multiples of the macroblock size minus the cropping offsets,
+// and the VUI must have been parsed beyond the aspect ratio.
+// The SAR is written with its aspect_ratio_idc if there is one, and otherwise as Extended_SAR.
+func (s *SPS) Encode(w io.Writer) error {
+	ew := bits.NewEBSPWriter(w)
+	ew.Write(nalHeader(s.NalRefIdc, NALU_SPS), 8)
+	ew.Write(s.Profile, 8)
+	ew.Write(s.ProfileCompatibility, 8)
+	ew.Write(s.Level, 8)
+	ew.WriteExpGolomb(s.ParameterID)
+
+	switch s.Profile {
+	case 100, 110, 122, 244, 44, 83, 86, 118, 128, 138, 139, 134, 135:
+		ew.WriteExpGolomb(s.ChromaFormatIDC)
+		if s.ChromaFormatIDC == 3 {
+			writeFlag(ew, s.SeparateColourPlaneFlag)
+		}
+		ew.WriteExpGolomb(s.BitDepthLumaMinus8)
+		ew.WriteExpGolomb(s.BitDepthChromaMinus8)
+		writeFlag(ew, s.QPPrimeYZeroTransformBypassFlag)
+		writeFlag(ew, s.SeqScalingMatrixPresentFlag)
+		if s.SeqScalingMatrixPresentFlag {
+			nrScalingLists := 12
+			if s.ChromaFormatIDC != 3 {
+				nrScalingLists = 8
+			}
+			if len(s.SeqScalingLists) != nrScalingLists {
+				return fmt.Errorf("%d scaling lists instead of %d", len(s.SeqScalingLists), nrScalingLists)
+			}
+			for i, list := range s.SeqScalingLists {
+				writeFlag(ew, list != nil)
+				if list != nil {
+					writeScalingList(ew, list, i)
+				}
+			}
+		}
+	}
+
+	ew.WriteExpGolomb(s.Log2MaxFrameNumMinus4)
+	ew.WriteExpGolomb(s.PicOrderCntType)
+	if s.PicOrderCntType == 0 {
+		ew.WriteExpGolomb(s.Log2MaxPicOrderCntLsbMinus4)
+	} else if s.PicOrderCntType == 1 {
+		writeFlag(ew, s.DeltaPicOrderAlwaysZeroFlag)
+		ew.WriteSignedGolomb(s.OffsetForNonRefPic)
+		ew.WriteSignedGolomb(s.OffsetForTopToBottomField)
+		ew.WriteExpGolomb(uint(len(s.RefFramesInPicOrderCntCycle)))
+		for _, offset := range s.RefFramesInPicOrderCntCycle {
+			ew.WriteSignedGolomb(offset)
+		}
+	}
+
+	ew.WriteExpGolomb(s.NumRefFrames)
+	writeFlag(ew, s.GapsInFrameNumValueAllowedFlag)
+
+	codedWidth, codedHeight := s.Width, s.Height
+	if s.FrameCroppingFlag {
+		cropUnitX, cropUnitY, err := s.cropUnits()
+		if err != nil {
+			return err
+		}
+		codedWidth += (s.FrameCropLeftOffset + s.FrameCropRightOffset) * cropUnitX
+		codedHeight += (s.FrameCropTopOffset + s.FrameCropBottomOffset) * cropUnitY
+	}
+	mapUnitHeight := 16 * s.fieldsPerMapUnit()
+	if codedWidth == 0 || codedWidth%16 != 0 || codedHeight == 0 || codedHeight%mapUnitHeight != 0 {
+		return fmt.Errorf("coded size %dx%d is not a multiple of macroblock size", codedWidth, codedHeight)
+	}
+	ew.WriteExpGolomb(codedWidth/16 - 1)
+	ew.WriteExpGolomb(codedHeight/mapUnitHeight - 1)
+
+	writeFlag(ew, s.FrameMbsOnlyFlag)
+	if !s.FrameMbsOnlyFlag {
+		writeFlag(ew, s.MbAdaptiveFrameFieldFlag)
+	}
+	writeFlag(ew, s.Direct8x8InferenceFlag)
+	writeFlag(ew, s.FrameCroppingFlag)
+	if s.FrameCroppingFlag {
+		ew.WriteExpGolomb(s.FrameCropLeftOffset)
+		ew.WriteExpGolomb(s.FrameCropRightOffset)
+		ew.WriteExpGolomb(s.FrameCropTopOffset)
+		ew.WriteExpGolomb(s.FrameCropBottomOffset)
+	}
+
+	writeFlag(ew, s.VUI != nil)
+	if s.VUI != nil {
+		if err := writeVUI(ew, s.VUI); err != nil {
+			return err
+		}
+	}
+	ew.WriteRbspTrailingBits()
+	return ew.AccError()
+}
+
+// fieldsPerMapUnit - 2 if map units are field macroblock pairs, otherwise 1
+func (s *SPS) fieldsPerMapUnit() uint {
+	if s.FrameMbsOnlyFlag {
+		return 1
+	}
+	return 2
+}
+
+// cropUnits - horizontal and vertical unit of frame cropping offsets
+func (s *SPS) cropUnits() (cropUnitX, cropUnitY uint, err error) {
+	switch s.ChromaFormatIDC {
+	case 0:
+		return 1, s.fieldsPerMapUnit(), nil
+	case 1:
+		return 2, 2 * s.fieldsPerMapUnit(), nil
+	case 2:
+		return 2, 1 * s.fieldsPerMapUnit(), nil
+	case 3: // Same as for separate colour planes
+		return 1, 1 * s.fieldsPerMapUnit(), nil
+	default:
+		return 0, 0, fmt.Errorf("Non-vaild chroma_format_idc value: %d", s.ChromaFormatIDC)
+	}
+}
+
 // CpbDbpDelaysPresent signals if Cpb and Dbp can be found in Picture Timing SEI
 func (s *SPS) CpbDpbDelaysPresent() bool {
 	if s.VUI == nil {
@@ -278,6 +395,7 @@ func parseVUI(reader *bits.AccErrEBSPReader, parseVUIBeyondAspectRatio bool) *VU
 		}
 	}
 	if !parseVUIBeyondAspectRatio {
+		vui.aspectRatioOnly = true
 		return vui
 	}
 	vui.OverscanInfoPresentFlag = reader.ReadFlag()
@@ -332,6 +450,115 @@ func parseVUI(reader *bits.AccErrEBSPReader, parseVUIBeyondAspectRatio bool) *VU
 	return vui
 }
 
+// writeVUI - write VUI (Visual Usability Information)
+func writeVUI(w *bits.EBSPWriter, vui *VUIParameters) error {
+	if vui.aspectRatioOnly {
+		return fmt.Errorf("VUI only parsed up to aspect ratio")
+	}
+	aspectRatioInfoPresentFlag := vui.SampleAspectRatioWidth != 0 || vui.SampleAspectRatioHeight != 0
+	writeFlag(w, aspectRatioInfoPresentFlag)
+	if aspectRatioInfoPresentFlag {
+		aspectRatioIDC := GetIDCfromSAR(vui.SampleAspectRatioWidth, vui.SampleAspectRatioHeight)
+		w.Write(aspectRatioIDC, 8)
+		if aspectRatioIDC == ExtendedSAR {
+			w.Write(vui.SampleAspectRatioWidth, 16)
+			w.Write(vui.SampleAspectRatioHeight, 16)
+		}
+	}
+	writeFlag(w, vui.OverscanInfoPresentFlag)
+	if vui.OverscanInfoPresentFlag {
+		writeFlag(w, vui.OverscanAppropriateFlag)
+	}
+	writeFlag(w, vui.VideoSignalTypePresentFlag)
+	if vui.VideoSignalTypePresentFlag {
+		w.Write(vui.VideoFormat, 3)
+		writeFlag(w, vui.VideoFullRangeFlag)
+		writeFlag(w, vui.ColourDescriptionFlag)
+		if vui.ColourDescriptionFlag {
+			w.Write(vui.ColourPrimaries, 8)
+			w.Write(vui.TransferCharacteristics, 8)
+			w.Write(vui.MatrixCoefficients, 8)
+		}
+	}
+	writeFlag(w, vui.ChromaLocInfoPresentFlag)
+	if vui.ChromaLocInfoPresentFlag {
+		w.WriteExpGolomb(vui.ChromaSampleLocTypeTopField)
+		w.WriteExpGolomb(vui.ChromaSampleLocTypeBottomField)
+	}
+	writeFlag(w, vui.TimingInfoPresentFlag)
+	if vui.TimingInfoPresentFlag {
+		w.Write(vui.NumUnitsInTick, 32)
+		w.Write(vui.TimeScale, 32)
+		writeFlag(w, vui.FixedFrameRateFlag)
+	}
+	writeFlag(w, vui.NalHrdParametersPresentFlag)
+	if vui.NalHrdParametersPresentFlag {
+		if err := writeHrdParameters(w, vui.NalHrdParameters); err != nil {
+			return fmt.Errorf("NAL HRD: %w", err)
+		}
+	}
+	writeFlag(w, vui.VclHrdParametersPresentFlag)
+	if vui.VclHrdParametersPresentFlag {
+		if err := writeHrdParameters(w, vui.VclHrdParameters); err != nil {
+			return fmt.Errorf("VCL HRD: %w", err)
+		}
+	}
+	if vui.NalHrdParametersPresentFlag || vui.VclHrdParametersPresentFlag {
+		writeFlag(w, vui.LowDelayHrdFlag)
+	}
+	writeFlag(w, vui.PicStructPresentFlag)
+	writeFlag(w, vui.BitstreamRestrictionFlag)
+	if vui.BitstreamRestrictionFlag {
+		writeFlag(w, vui.MotionVectorsOverPicBoundariesFlag)
+		w.WriteExpGolomb(vui.MaxBytesPerPicDenom)
+		w.WriteExpGolomb(vui.MaxBitsPerMbDenom)
+		w.WriteExpGolomb(vui.Log2MaxMvLengthHorizontal)
+		w.WriteExpGolomb(vui.Log2MaxMvLengthVertical)
+		w.WriteExpGolomb(vui.MaxNumReorderFrames)
+		w.WriteExpGolomb(vui.MaxDecFrameBuffering)
+	}
+	return nil
+}
+
+func writeHrdParameters(w *bits.EBSPWriter, hp *HrdParameters) error {
+	if hp == nil {
+		return fmt.Errorf("parameters missing")
+	}
+	if len(hp.CpbEntries) != int(hp.CpbCountMinus1)+1 {
+		return fmt.Errorf("%d cpb entries but cpb_cnt_minus1 is %d", len(hp.CpbEntries), hp.CpbCountMinus1)
+	}
+	w.WriteExpGolomb(hp.CpbCountMinus1)
+	w.Write(hp.BitRateScale, 4)
+	w.Write(hp.CpbSizeScale, 4)
+	for _, ce := range hp.CpbEntries {
+		w.WriteExpGolomb(ce.BitRateValueMinus1)
+		w.WriteExpGolomb(ce.CpbSizeValueMinus1)
+		writeFlag(w, ce.CbrFlag)
+	}
+	w.Write(hp.InitialCpbRemovalDelayLengthMinus1, 5)
+	w.Write(hp.CpbRemovalDelayLengthMinus1, 5)
+	w.Write(hp.DpbOutpuDelayLengthMinus1, 5)
+	w.Write(hp.TimeOffsetLength, 5)
+	return nil
+}
+
+// writeFlag - write flag as one bit
+// nalHeader - NAL unit header byte with nalRefIdc, where 0 means 3, since parameter sets must have nal_ref_idc > 0
+func nalHeader(nalRefIdc uint, naluType NaluType) uint {
+	if nalRefIdc == 0 {
+		nalRefIdc = 3
+	}
+	return (nalRefIdc&0x03)<<5 | uint(naluType)
+}
+
+func writeFlag(w *bits.EBSPWriter, flag bool) {
+	if flag {
+		w.Write(1, 1)
+	} else {
+		w.Write(0, 1)
+	}
+}
+
 func parseHrdParameters(r *bits.AccErrEBSPReader) *HrdParameters {
 	hp := &HrdParameters{}
 	hp.CpbCountMinus1 = r.ReadExpGolomb()
@@ -370,7 +597,53 @@ func GetSARfromIDC(index uint) (uint, uint, error) {
 	return aspectRatioTable[index-1][0], aspectRatioTable[index-1][1], nil
 }
 
-func readScalingList(reader *bits.AccErrEBSPReader, sizeOfScalingList int) ScalingList {
+// GetIDCfromSAR - get aspect_ratio_idc for Sample Aspect Ratio. ExtendedSAR if not in table
+func GetIDCfromSAR(width, height uint) uint {
+	for index := uint(1); index <= 16; index++ {
+		w, h, _ := GetSARfromIDC(index)
+		if w == width && h == height {
+			return index
+		}
+	}
+	return ExtendedSAR
+}
+
+// Default scaling lists in zig-zag order according to 14496-10 Table 7-3 and 7-4
+var (
+	default4x4Intra = ScalingList{6, 13, 13, 20, 20, 20, 28, 28, 28, 28, 32, 32, 32, 37, 37, 42}
+	default4x4Inter = ScalingList{10, 14, 14, 20, 20, 20, 24, 24, 24, 24, 27, 27, 27, 30, 30, 34}
+	default8x8Intra = ScalingList{
+		6, 10, 10, 13, 11, 13, 16, 16, 16, 16, 18, 18, 18, 18, 18, 23,
+		23, 23, 23, 23, 23, 25, 25, 25, 25, 25, 25, 25, 27, 27, 27, 27,
+		27, 27, 27, 27, 29, 29, 29, 29, 29, 29, 29, 31, 31, 31, 31, 31,
+		31, 33, 33, 33, 33, 33, 36, 36, 36, 36, 38, 38, 38, 40, 40, 42}
+	default8x8Inter = ScalingList{
+		9, 13, 13, 15, 13, 15, 17, 17, 17, 17, 19, 19, 19, 19, 19, 21,
+		21, 21, 21, 21, 21, 22, 22, 22, 22, 22, 22, 22, 24, 24, 24, 24,
+		24, 24, 24, 24, 25, 25, 25, 25, 25, 25, 25, 27, 27, 27, 27, 27,
+		27, 28, 28, 28, 28, 28, 30, 30, 30, 30, 32, 32, 32, 33, 33, 35}
+)
+
+// defaultScalingList - default list for scaling list index i.
+// Index 0-5 are 4x4 lists and index 6-11 are 8x8 lists, first intra then inter for each colour component.
+func defaultScalingList(i int) ScalingList {
+	switch {
+	case i < 3:
+		return default4x4Intra
+	case i < 6:
+		return default4x4Inter
+	case i%2 == 0:
+		return default8x8Intra
+	default:
+		return default8x8Inter
+	}
+}
+
+// readScalingList - read scaling list with index i.
+// If useDefaultScalingMatrixFlag is inferred, the default list is returned.
+func readScalingList(reader *bits.AccErrEBSPReader, i int) ScalingList {
+	defaultList := defaultScalingList(i)
+	sizeOfScalingList := len(defaultList)
 	scalingList := make([]int, sizeOfScalingList)
 	lastScale := 8
 	nextScale := 8
@@ -378,6 +651,10 @@ func readScalingList(reader *bits.AccErrEBSPReader, sizeOfScalingList int) Scali
 		if nextScale != 0 {
 			deltaScale := reader.ReadSignedGolomb()
 			nextScale = (lastScale + deltaScale + 256) % 256
+			if j == 0 && nextScale == 0 {
+				copy(scalingList, defaultList)
+				return scalingList
+			}
 		}
 		if nextScale == 0 {
 			scalingList[j] = lastScale
@@ -388,3 +665,42 @@ func readScalingList(reader *bits.AccErrEBSPReader, sizeOfScalingList int) Scali
 	}
 	return scalingList
 }
+
+// writeScalingList - write scaling list with index i.
+// The default list is signalled with useDefaultScalingMatrixFlag, and repeated values at the end are left out.
+func writeScalingList(w *bits.EBSPWriter, list ScalingList, i int) {
+	defaultList := defaultScalingList(i)
+	if scalingListsEqual(list, defaultList) {
+		w.WriteSignedGolomb(-8)
+		return
+	}
+	n := len(list)
+	for n > 1 && list[n-1] == list[n-2] {
+		n--
+	}
+	lastScale := 8
+	for j := 0; j < n; j++ {
+		w.WriteSignedGolomb(scaleDelta(lastScale, list[j]))
+		lastScale = list[j]
+	}
+	if n < len(list) {
+		w.WriteSignedGolomb(scaleDelta(lastScale, 0))
+	}
+}
+
+// scaleDelta - delta_scale in range -128 to 127 from lastScale to nextScale
+func scaleDelta(lastScale, nextScale int) int {
+	return (nextScale-lastScale+128+256)%256 - 128
+}
+
+func scalingListsEqual(a, b ScalingList) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
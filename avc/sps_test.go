@@ -1,6 +1,7 @@
 package avc
 
 import (
+	"bytes"
 	"encoding/hex"
 	"testing"
 
@@ -17,6 +18,7 @@ func TestSPSParser1(t *testing.T) {
 	byteData, _ := hex.DecodeString(sps1nalu)
 
 	wanted := SPS{
+		NalRefIdc:                       3,
 		Profile:                         100,
 		ProfileCompatibility:            0,
 		Level:                           32,
@@ -94,6 +96,7 @@ func TestSPSParser2(t *testing.T) {
 	byteData, _ := hex.DecodeString(sps2nalu)
 
 	wanted := SPS{
+		NalRefIdc:                       3,
 		Profile:                         100,
 		ProfileCompatibility:            0,
 		Level:                           13,
@@ -151,6 +154,7 @@ func TestSPSParser3(t *testing.T) {
 	byteData, _ := hex.DecodeString(sps3nalu)
 
 	wanted := SPS{
+		NalRefIdc:                       1,
 		Profile:                         100,
 		ProfileCompatibility:            0,
 		Level:                           32,
@@ -220,3 +224,135 @@ func TestSPSParser3(t *testing.T) {
 		t.Error(diff)
 	}
 }
+
+func TestSPSEncode(t *testing.T) {
+	for _, spsHex := range []string{sps1nalu, sps2nalu, sps3nalu, sps} {
+		byteData, _ := hex.DecodeString(spsHex)
+		s, err := ParseSPSNALUnit(byteData, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err := s.Encode(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf.Bytes(), byteData) {
+			t.Errorf("encoded SPS %x differs from %s", buf.Bytes(), spsHex)
+		}
+	}
+
+	s, err := ParseSPSNALUnit(mustDecodeHex(sps2nalu), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Encode(&bytes.Buffer{}); err == nil {
+		t.Errorf("no error for VUI only parsed up to aspect ratio")
+	}
+}
+
+func TestSPSEncodePatchedVUI(t *testing.T) {
+	s, err := ParseSPSNALUnit(mustDecodeHex(sps2nalu), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Level = 31
+	s.VUI.SampleAspectRatioWidth, s.VUI.SampleAspectRatioHeight = 4, 3
+	s.VUI.VideoSignalTypePresentFlag = true
+	s.VUI.VideoFormat = 5
+	s.VUI.ColourDescriptionFlag = true
+	s.VUI.ColourPrimaries, s.VUI.TransferCharacteristics, s.VUI.MatrixCoefficients = 1, 1, 1
+	s.VUI.NumUnitsInTick, s.VUI.TimeScale = 1001, 60000
+	s.VUI.FixedFrameRateFlag = true
+	var buf bytes.Buffer
+	if err := s.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ParseSPSNALUnit(buf.Bytes(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got.NrBytesBeforeVUI, got.NrBytesRead = s.NrBytesBeforeVUI, s.NrBytesRead
+	if diff := deep.Equal(got, s); diff != nil {
+		t.Error(diff)
+	}
+
+	// Extended SAR
+	s.VUI.SampleAspectRatioWidth, s.VUI.SampleAspectRatioHeight = 5, 7
+	buf.Reset()
+	if err := s.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	got, err = ParseSPSNALUnit(buf.Bytes(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.VUI.SampleAspectRatioWidth != 5 || got.VUI.SampleAspectRatioHeight != 7 {
+		t.Errorf("got SAR %d:%d instead of 5:7", got.VUI.SampleAspectRatioWidth, got.VUI.SampleAspectRatioHeight)
+	}
+
+	drc, err := CreateAVCDecConfRec([][]byte{buf.Bytes()}, [][]byte{mustDecodeHex(pps)}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if drc.AVCLevelIndication != 31 || !bytes.Equal(drc.SPSnalus[0], buf.Bytes()) {
+		t.Errorf("patched SPS not in decoder configuration record")
+	}
+}
+
+func TestSPSEncodeScalingListsAndInterlace(t *testing.T) {
+	flat := make(ScalingList, 64)
+	for i := range flat {
+		flat[i] = 16
+	}
+	custom := make(ScalingList, 16)
+	for i := range custom {
+		custom[i] = 4 + 3*i
+	}
+	s := &SPS{
+		Profile:                     100,
+		Level:                       40,
+		ChromaFormatIDC:             1,
+		SeqScalingMatrixPresentFlag: true,
+		SeqScalingLists: []ScalingList{
+			default4x4Intra, nil, custom, default4x4Inter, nil, nil, flat, default8x8Inter},
+		PicOrderCntType:             1,
+		OffsetForNonRefPic:          -3,
+		RefFramesInPicOrderCntCycle: []int{2, -4},
+		NumRefFrames:                4,
+		MbAdaptiveFrameFieldFlag:    true,
+		Direct8x8InferenceFlag:      true,
+		FrameCroppingFlag:           true,
+		FrameCropBottomOffset:       2,
+		Width:                       1920,
+		Height:                      1080,
+	}
+	var buf bytes.Buffer
+	if err := s.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Bytes()[0] != 0x67 {
+		t.Errorf("got NAL header byte %02x instead of 67 for zero NalRefIdc", buf.Bytes()[0])
+	}
+	got, err := ParseSPSNALUnit(buf.Bytes(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got.NrBytesBeforeVUI, got.NrBytesRead = 0, 0
+	s.NalRefIdc = 3
+	if diff := deep.Equal(got, s); diff != nil {
+		t.Error(diff)
+	}
+
+	s.Height = 1070
+	if err := s.Encode(&bytes.Buffer{}); err == nil {
+		t.Errorf("no error for height not matching macroblocks")
+	}
+}
+
+func mustDecodeHex(str string) []byte {
+	b, err := hex.DecodeString(str)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
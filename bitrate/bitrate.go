@@ -0,0 +1,436 @@
+package bitrate
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/jaypadia-frame/mp4ff/mp4"
+)
+
+// DefaultSegmentDuration - target segment duration in seconds for progressive files
+const DefaultSegmentDuration = 2.0
+
+// Config - options for TrackStats
+type Config struct {
+	SegmentDuration float64 // Target segment duration in seconds for progressive files. DefaultSegmentDuration if 0
+}
+
+// Peak - highest bit rate over a sliding window
+type Peak struct {
+	Window  float64 // Window length in seconds
+	Bitrate uint64  // Bits/s
+	Start   float64 // Start of the window in seconds after the first decode time
+}
+
+// Segment - samples of a track in a media segment, or in a part of a progressive file
+type Segment struct {
+	StartNr   int // First sample number starting at 1
+	NrSamples int
+	Size      uint64  // Bytes
+	Duration  float64 // Seconds
+}
+
+// Bitrate - average bit rate of the segment in bits/s
+func (s Segment) Bitrate() uint64 {
+	if s.Duration <= 0 {
+		return 0
+	}
+	return uint64(math.Round(float64(s.Size*8) / s.Duration))
+}
+
+// Distribution - min, max, mean, and percentiles of a set of values
+type Distribution struct {
+	Min    uint64
+	Median uint64
+	P90    uint64
+	Max    uint64
+	Mean   float64
+}
+
+// Stats - bit rate, segment, and GOP statistics of a track
+type Stats struct {
+	TrackID       uint32
+	NrSamples     int
+	Duration      float64 // Seconds from first decode time to end of last sample
+	TotalSize     uint64  // Bytes
+	MaxSampleSize uint32  // Bytes
+	AvgBitrate    uint64  // Bits/s
+	Peaks         []Peak  // For 1s window, and for the average segment duration if it differs
+	Segments      []Segment
+	GOPLengths    map[int]int // Number of GOPs by length in samples. A GOP starts at a sync sample
+}
+
+// sampleInfo - decode time, size, and sync flag of a sample, and the number of its segment (fragmented files)
+type sampleInfo struct {
+	decodeTime uint64
+	size       uint32
+	isSync     bool
+	segment    int
+}
+
+// TrackStats - compute statistics for a track in a progressive or fragmented file.
+// The sample sizes and times are taken from stsz, stts, and stss, or from the trun boxes of the fragments.
+// A fragmented file gives one segment per media segment with samples of the track, or per fragment if all
+// fragments are in one media segment (no styp or sidx boxes). A progressive file is split at the first sync
+// sample at or after every multiple of cfg.SegmentDuration.
+func TrackStats(f *mp4.File, trackID uint32, cfg Config) (*Stats, error) {
+	if f.Moov == nil {
+		return nil, fmt.Errorf("no moov box")
+	}
+	var trak *mp4.TrakBox
+	for _, t := range f.Moov.Traks {
+		if t.Tkhd.TrackID == trackID {
+			trak = t
+			break
+		}
+	}
+	if trak == nil {
+		return nil, fmt.Errorf("no track with ID %d", trackID)
+	}
+	timescale := float64(trak.Mdia.Mdhd.Timescale)
+	if timescale == 0 {
+		return nil, fmt.Errorf("track %d has timescale 0", trackID)
+	}
+	var samples []sampleInfo
+	var endTime uint64
+	var err error
+	if f.IsFragmented() {
+		samples, endTime, err = fragmentedSamples(f, trackID)
+	} else {
+		samples, endTime, err = progressiveSamples(trak.Mdia.Minf.Stbl)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("track %d has no samples", trackID)
+	}
+
+	st := &Stats{TrackID: trackID, NrSamples: len(samples), GOPLengths: make(map[int]int)}
+	startTime := samples[0].decodeTime
+	st.Duration = float64(endTime-startTime) / timescale
+	for _, s := range samples {
+		st.TotalSize += uint64(s.size)
+		if s.size > st.MaxSampleSize {
+			st.MaxSampleSize = s.size
+		}
+	}
+	if st.Duration > 0 {
+		st.AvgBitrate = uint64(math.Round(float64(st.TotalSize*8) / st.Duration))
+	}
+
+	segDur := cfg.SegmentDuration
+	if segDur <= 0 {
+		segDur = DefaultSegmentDuration
+	}
+	segmentEnd := func(i int) uint64 {
+		if i+1 < len(samples) {
+			return samples[i+1].decodeTime
+		}
+		return endTime
+	}
+	for i, s := range samples {
+		var newSegment bool
+		switch {
+		case i == 0:
+			newSegment = true
+		case f.IsFragmented():
+			newSegment = s.segment != samples[i-1].segment
+		default:
+			nextStart := float64(len(st.Segments)) * segDur
+			newSegment = s.isSync && float64(s.decodeTime-startTime)/timescale >= nextStart-1e-9
+		}
+		if newSegment {
+			st.Segments = append(st.Segments, Segment{StartNr: i + 1})
+		}
+		seg := &st.Segments[len(st.Segments)-1]
+		seg.NrSamples++
+		seg.Size += uint64(s.size)
+		seg.Duration = float64(segmentEnd(i)-samples[seg.StartNr-1].decodeTime) / timescale
+	}
+
+	gopLength := 0
+	for _, s := range samples {
+		if s.isSync && gopLength > 0 {
+			st.GOPLengths[gopLength]++
+			gopLength = 0
+		}
+		gopLength++
+	}
+	st.GOPLengths[gopLength]++
+
+	st.Peaks = append(st.Peaks, peakBitrate(samples, endTime, timescale, 1.0))
+	avgSegDur := st.Duration / float64(len(st.Segments))
+	if math.Abs(avgSegDur-1.0) > 1e-3 {
+		st.Peaks = append(st.Peaks, peakBitrate(samples, endTime, timescale, avgSegDur))
+	}
+	return st, nil
+}
+
+// progressiveSamples - samples from stsz, stts, and stss, and the end time of the last sample
+func progressiveSamples(stbl *mp4.StblBox) ([]sampleInfo, uint64, error) {
+	if stbl.Stsz == nil || stbl.Stts == nil {
+		return nil, 0, fmt.Errorf("no stsz or stts box")
+	}
+	nrSamples := int(stbl.Stsz.SampleNumber)
+	samples := make([]sampleInfo, 0, nrSamples)
+	var decodeTime uint64
+	for i, count := range stbl.Stts.SampleCount {
+		for j := uint32(0); j < count && len(samples) < nrSamples; j++ {
+			nr := uint32(len(samples) + 1)
+			samples = append(samples, sampleInfo{
+				decodeTime: decodeTime,
+				size:       stbl.Stsz.GetSampleSize(int(nr)),
+				isSync:     stbl.Stss == nil || stbl.Stss.IsSyncSample(nr),
+			})
+			decodeTime += uint64(stbl.Stts.SampleTimeDelta[i])
+		}
+	}
+	if len(samples) != nrSamples {
+		return nil, 0, fmt.Errorf("stts has %d samples and stsz %d", len(samples), nrSamples)
+	}
+	return samples, decodeTime, nil
+}
+
+// fragmentedSamples - samples from the trun boxes of all fragments, and the end time of the last sample
+func fragmentedSamples(f *mp4.File, trackID uint32) ([]sampleInfo, uint64, error) {
+	var trex *mp4.TrexBox
+	if f.Moov.Mvex != nil {
+		trex, _ = f.Moov.Mvex.GetTrex(trackID)
+	}
+	if trex == nil {
+		return nil, 0, fmt.Errorf("no trex box for track %d", trackID)
+	}
+	var samples []sampleInfo
+	var decodeTime uint64
+	segNr := 0
+	for _, seg := range f.Segments {
+		for _, frag := range seg.Fragments {
+			if len(f.Segments) == 1 {
+				segNr++
+			}
+			for _, traf := range frag.Moof.Trafs {
+				if traf.Tfhd.TrackID != trackID {
+					continue
+				}
+				if traf.Tfdt != nil {
+					decodeTime = traf.Tfdt.BaseMediaDecodeTime
+				}
+				for _, trun := range traf.Truns {
+					trun.AddSampleDefaultValues(traf.Tfhd, trex)
+					for _, s := range trun.GetSamples() {
+						samples = append(samples, sampleInfo{
+							decodeTime: decodeTime,
+							size:       s.Size,
+							isSync:     s.IsSync(),
+							segment:    segNr,
+						})
+						decodeTime += uint64(s.Dur)
+					}
+				}
+			}
+		}
+		segNr++
+	}
+	return samples, decodeTime, nil
+}
+
+// peakBitrate - highest bit rate over windows of length window seconds starting at the sample decode times.
+// The window is shortened to the track duration if that is shorter.
+func peakBitrate(samples []sampleInfo, endTime uint64, timescale, window float64) Peak {
+	startTime := samples[0].decodeTime
+	duration := float64(endTime-startTime) / timescale
+	length := window
+	if duration < length {
+		length = duration
+	}
+	peak := Peak{Window: window}
+	if length <= 0 {
+		return peak
+	}
+	windowTicks := uint64(math.Round(length * timescale))
+	var size uint64
+	j := 0
+	for i := range samples {
+		for j < len(samples) && samples[j].decodeTime < samples[i].decodeTime+windowTicks {
+			size += uint64(samples[j].size)
+			j++
+		}
+		if rate := uint64(math.Round(float64(size*8) / length)); rate > peak.Bitrate {
+			peak.Bitrate = rate
+			peak.Start = float64(samples[i].decodeTime-startTime) / timescale
+		}
+		size -= uint64(samples[i].size)
+	}
+	return peak
+}
+
+// SegmentSizes - distribution of the segment sizes in bytes
+func (s *Stats) SegmentSizes() Distribution {
+	values := make([]uint64, len(s.Segments))
+	for i, seg := range s.Segments {
+		values[i] = seg.Size
+	}
+	return distribution(values)
+}
+
+// SegmentBitrates - distribution of the segment bit rates in bits/s
+func (s *Stats) SegmentBitrates() Distribution {
+	values := make([]uint64, len(s.Segments))
+	for i, seg := range s.Segments {
+		values[i] = seg.Bitrate()
+	}
+	return distribution(values)
+}
+
+// distribution - min, max, mean, and nearest-rank percentiles of values
+func distribution(values []uint64) Distribution {
+	if len(values) == 0 {
+		return Distribution{}
+	}
+	sorted := make([]uint64, len(values))
+	copy(sorted, values)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	percentile := func(p float64) uint64 {
+		rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+		if rank < 1 {
+			rank = 1
+		}
+		return sorted[rank-1]
+	}
+	var sum float64
+	for _, v := range sorted {
+		sum += float64(v)
+	}
+	return Distribution{
+		Min:    sorted[0],
+		Median: percentile(50),
+		P90:    percentile(90),
+		Max:    sorted[len(sorted)-1],
+		Mean:   sum / float64(len(sorted)),
+	}
+}
+
+// Btrt - btrt box values. bufferSizeDB is the largest sample size, maxBitrate the peak bit rate over 1s,
+// and avgBitrate the average bit rate of the track.
+func (s *Stats) Btrt() mp4.BtrtBox {
+	btrt := mp4.BtrtBox{
+		BufferSizeDB: s.MaxSampleSize,
+		AvgBitrate:   clampUint32(s.AvgBitrate),
+	}
+	for _, p := range s.Peaks {
+		if p.Window == 1.0 {
+			btrt.MaxBitrate = clampUint32(p.Bitrate)
+		}
+	}
+	return btrt
+}
+
+func clampUint32(v uint64) uint32 {
+	if v > math.MaxUint32 {
+		return math.MaxUint32
+	}
+	return uint32(v)
+}
+
+// SetBtrt - set btrt in all sample entries of a track, adding btrt boxes where needed.
+// Adding boxes makes the moov box bigger, so the chunk offsets of a progressive file pointing after
+// the moov box are moved by the size difference. The sample data is not changed.
+// If an error is returned, the file is not changed.
+func SetBtrt(f *mp4.File, trackID uint32, btrt mp4.BtrtBox) error {
+	if f.Moov == nil {
+		return fmt.Errorf("no moov box")
+	}
+	var trak *mp4.TrakBox
+	for _, t := range f.Moov.Traks {
+		if t.Tkhd.TrackID == trackID {
+			trak = t
+			break
+		}
+	}
+	if trak == nil {
+		return fmt.Errorf("no track with ID %d", trackID)
+	}
+	entries := trak.Mdia.Minf.Stbl.Stsd.Children
+	var delta uint64 // Growth of the moov box
+	for _, entry := range entries {
+		old, err := mp4.GetSampleEntryBtrt(entry)
+		if err != nil {
+			return err
+		}
+		if old == nil {
+			delta += btrt.Size()
+		}
+	}
+	shift := delta > 0 && !f.IsFragmented()
+	var moovEnd uint64
+	if shift {
+		moovEnd = moovEndPos(f)
+		if err := checkChunkOffsets(f, moovEnd, delta); err != nil {
+			return err
+		}
+	}
+	for _, entry := range entries {
+		if err := mp4.SetSampleEntryBtrt(entry, btrt); err != nil {
+			return err
+		}
+	}
+	if shift {
+		shiftChunkOffsets(f, moovEnd, delta)
+	}
+	return nil
+}
+
+// moovEndPos - position after the moov box in a progressive file
+func moovEndPos(f *mp4.File) uint64 {
+	var pos uint64
+	for _, b := range f.Children {
+		pos += b.Size()
+		if b == f.Moov {
+			break
+		}
+	}
+	return pos
+}
+
+// checkChunkOffsets - check that stco chunk offsets at or after moovEnd still fit in 32 bits when moved by delta
+func checkChunkOffsets(f *mp4.File, moovEnd, delta uint64) error {
+	for _, t := range f.Moov.Traks {
+		stco := t.Mdia.Minf.Stbl.Stco
+		if stco == nil {
+			continue
+		}
+		for _, o := range stco.ChunkOffset {
+			if uint64(o) >= moovEnd && uint64(o)+delta > math.MaxUint32 {
+				return fmt.Errorf("track %d: chunk offset %d too big for stco", t.Tkhd.TrackID, uint64(o)+delta)
+			}
+		}
+	}
+	return nil
+}
+
+// shiftChunkOffsets - add delta to the chunk offsets of all tracks at or after moovEnd,
+// and to the position of an mdat box after the moov box
+func shiftChunkOffsets(f *mp4.File, moovEnd, delta uint64) {
+	for _, t := range f.Moov.Traks {
+		stbl := t.Mdia.Minf.Stbl
+		if stbl.Stco != nil {
+			for i, o := range stbl.Stco.ChunkOffset {
+				if uint64(o) >= moovEnd {
+					stbl.Stco.ChunkOffset[i] = o + uint32(delta)
+				}
+			}
+		}
+		if stbl.Co64 != nil {
+			for i, o := range stbl.Co64.ChunkOffset {
+				if o >= moovEnd {
+					stbl.Co64.ChunkOffset[i] = o + delta
+				}
+			}
+		}
+	}
+	if f.Mdat != nil && f.Mdat.StartPos >= moovEnd {
+		f.Mdat.StartPos += delta
+	}
+}
@@ -0,0 +1,185 @@
+package bitrate
+
+import (
+	"bytes"
+	"crypto/md5"
+	"math"
+	"testing"
+
+	"github.com/jaypadia-frame/mp4ff/internal/mp4test"
+	"github.com/jaypadia-frame/mp4ff/mp4"
+)
+
+// sampleChecksums - md5 sums of the samples of a track
+func sampleChecksums(t *testing.T, f *mp4.File, trackID uint32) [][md5.Size]byte {
+	t.Helper()
+	var sums [][md5.Size]byte
+	err := f.VisitTrackSamples(trackID, nil, func(nr, sdi uint32, s *mp4.FullSample) error {
+		sums = append(sums, md5.Sum(s.Data))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sums
+}
+
+func TestProgressiveTrackStats(t *testing.T) {
+	f, err := mp4.ReadMP4File("../mp4/testdata/prog_8s.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err := TrackStats(f, 2, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.NrSamples != 240 || st.Duration != 8 || st.AvgBitrate != st.TotalSize*8/8 {
+		t.Errorf("got %d samples, duration %f, average bit rate %d", st.NrSamples, st.Duration, st.AvgBitrate)
+	}
+	if len(st.GOPLengths) != 1 || st.GOPLengths[30] != 8 {
+		t.Errorf("got GOP lengths %v", st.GOPLengths)
+	}
+	if len(st.Segments) != 4 {
+		t.Fatalf("got %d segments", len(st.Segments))
+	}
+	var totSize uint64
+	for i, seg := range st.Segments {
+		if seg.StartNr != 60*i+1 || seg.NrSamples != 60 || seg.Duration != 2 {
+			t.Errorf("got segment %+v", seg)
+		}
+		totSize += seg.Size
+	}
+	sizes := st.SegmentSizes()
+	if totSize != st.TotalSize || sizes.Mean != float64(totSize)/4 || sizes.Min > sizes.Median || sizes.P90 > sizes.Max {
+		t.Errorf("got segment sizes %+v", sizes)
+	}
+	if len(st.Peaks) != 2 || st.Peaks[0].Window != 1 || st.Peaks[1].Window != 2 ||
+		st.Peaks[0].Bitrate < st.Peaks[1].Bitrate || st.Peaks[1].Bitrate < st.AvgBitrate {
+		t.Errorf("got peaks %+v", st.Peaks)
+	}
+
+	st, err = TrackStats(f, 1, Config{SegmentDuration: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(st.Segments) != 3 || len(st.GOPLengths) != 1 || st.GOPLengths[1] != st.NrSamples {
+		t.Errorf("got %d audio segments and GOP lengths %v", len(st.Segments), st.GOPLengths)
+	}
+}
+
+func TestFragmentedTrackStats(t *testing.T) {
+	seg, err := mp4.ReadMP4File("../mp4/testdata/1.m4s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	samples, err := seg.Segments[0].Fragments[0].GetFullSamples(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	init := mp4.CreateEmptyInit()
+	init.AddEmptyTrack(90000, "video", "und")
+	f := mp4.NewFile()
+	f.AddChild(init.Ftyp, 0)
+	f.AddChild(init.Moov, init.Ftyp.Size())
+	for i := 0; i < 3; i++ {
+		frag, err := mp4.CreateFragment(uint32(i+1), 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range samples[20*i : 20*(i+1)] {
+			frag.AddFullSample(s)
+		}
+		seg := mp4.NewMediaSegmentWithoutStyp()
+		seg.AddFragment(frag)
+		f.AddMediaSegment(seg)
+	}
+	f = mp4test.EncodeDecode(t, f)
+	st, err := TrackStats(f, 1, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.NrSamples != 60 || len(st.Segments) != 3 {
+		t.Fatalf("got %d samples in %d segments", st.NrSamples, len(st.Segments))
+	}
+	var totSize uint64
+	for i, seg := range st.Segments {
+		var size uint64
+		for _, s := range samples[20*i : 20*(i+1)] {
+			size += uint64(s.Size)
+		}
+		if seg.StartNr != 20*i+1 || seg.NrSamples != 20 || seg.Size != size {
+			t.Errorf("got segment %+v", seg)
+		}
+		totSize += size
+	}
+	if st.TotalSize != totSize {
+		t.Errorf("got total size %d instead of %d", st.TotalSize, totSize)
+	}
+}
+
+func TestSetBtrt(t *testing.T) {
+	f, err := mp4.ReadMP4File("../mp4/testdata/prog_8s.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mdatData := f.Mdat.Data
+	sums := map[uint32][][md5.Size]byte{1: sampleChecksums(t, f, 1), 2: sampleChecksums(t, f, 2)}
+	btrts := make(map[uint32]mp4.BtrtBox)
+	for _, trackID := range []uint32{1, 2} {
+		st, err := TrackStats(f, trackID, Config{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		btrts[trackID] = st.Btrt()
+		if err := SetBtrt(f, trackID, st.Btrt()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for trackID, trackSums := range sums {
+		if len(sampleChecksums(t, f, trackID)) != len(trackSums) {
+			t.Errorf("track %d: samples not found after SetBtrt", trackID)
+		}
+	}
+	f = mp4test.EncodeDecode(t, f)
+	if !bytes.Equal(f.Mdat.Data, mdatData) {
+		t.Error("mdat changed by SetBtrt")
+	}
+	stsd := f.Moov.Traks[0].Mdia.Minf.Stbl.Stsd
+	if btrt := stsd.Mp4a.Btrt; btrt == nil || *btrt != btrts[1] {
+		t.Errorf("got audio btrt %v instead of %v", btrt, btrts[1])
+	}
+	stsd = f.Moov.Traks[1].Mdia.Minf.Stbl.Stsd
+	if btrt := stsd.AvcX.Btrt; btrt == nil || *btrt != btrts[2] {
+		t.Errorf("got video btrt %v instead of %v", btrt, btrts[2])
+	}
+	for trackID, trackSums := range sums {
+		for i, sum := range sampleChecksums(t, f, trackID) {
+			if sum != trackSums[i] {
+				t.Errorf("track %d sample %d changed", trackID, i+1)
+			}
+		}
+	}
+}
+
+func TestSetBtrtOffsetOverflow(t *testing.T) {
+	f, err := mp4.ReadMP4File("../mp4/testdata/prog_8s.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stco := f.Moov.Traks[1].Mdia.Minf.Stbl.Stco
+	stco.ChunkOffset[len(stco.ChunkOffset)-1] = math.MaxUint32 - 10
+	var before bytes.Buffer
+	if err := f.Encode(&before); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetBtrt(f, 1, mp4.BtrtBox{AvgBitrate: 128000}); err == nil {
+		t.Fatal("no error for chunk offset overflow")
+	}
+	var after bytes.Buffer
+	if err := f.Encode(&after); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(before.Bytes(), after.Bytes()) {
+		t.Error("file changed by failed SetBtrt")
+	}
+}
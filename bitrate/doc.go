@@ -0,0 +1,11 @@
+/*
+Package bitrate - compute bit rate, segment size, and GOP length statistics for tracks in mp4 files.
+
+TrackStats gives the average bit rate, the peak bit rate over sliding windows of 1s and of the average
+segment duration, the sizes and bit rates of the segments, and a histogram of GOP lengths. Sample sizes and
+times are taken from stsz and stts in progressive files, and from trun in fragmented files, so no sample data
+is read.
+
+The statistics give the values of the btrt box, which SetBtrt sets in the sample entries of a track.
+*/
+package bitrate
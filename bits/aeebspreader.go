@@ -243,7 +243,7 @@ func (r *AccErrEBSPReader) ReadRbspTrailingBits() error {
 
 // SetError - set an error if not already set.
 func (r *AccErrEBSPReader) SetError(err error) {
-	if r.err != nil {
+	if r.err == nil {
 		r.err = err
 	}
 }
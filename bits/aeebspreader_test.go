@@ -0,0 +1,20 @@
+package bits
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestAccErrEBSPReaderSetError(t *testing.T) {
+	r := NewAccErrEBSPReader(bytes.NewReader([]byte{0xff, 0xff}))
+	errFirst := errors.New("first")
+	r.SetError(errFirst)
+	r.SetError(errors.New("second"))
+	if r.AccError() != errFirst {
+		t.Errorf("got error %v instead of %v", r.AccError(), errFirst)
+	}
+	if v := r.Read(8); v != 0 {
+		t.Errorf("read %d after error", v)
+	}
+}
@@ -10,10 +10,11 @@ import (
 // AccErrReader - bit reader that accumulates error
 // First error can be fetched as reader.AccError()
 type AccErrReader struct {
-	rd     io.Reader
-	err    error
-	nrBits int  // current number of bits
-	value  uint // current accumulated value
+	rd          io.Reader
+	err         error
+	nrBits      int  // current number of bits
+	value       uint // current accumulated value
+	nrBytesRead int  // number of bytes read from rd
 }
 
 // AccError - accumulated error is first error that occurred
@@ -28,6 +29,16 @@ func NewAccErrReader(rd io.Reader) *AccErrReader {
 	}
 }
 
+// NrBytesRead - how many bytes read into parser
+func (r *AccErrReader) NrBytesRead() int {
+	return r.nrBytesRead
+}
+
+// NrBitsReadInCurrentByte - how many bits have been read
+func (r *AccErrReader) NrBitsReadInCurrentByte() int {
+	return 8 - r.nrBits
+}
+
 // Read - read n bits. Return 0, if error now or previously
 func (r *AccErrReader) Read(n int) uint {
 	if r.err != nil {
@@ -42,6 +53,7 @@ func (r *AccErrReader) Read(n int) uint {
 			r.err = err
 			return 0
 		}
+		r.nrBytesRead++
 		r.value |= uint(newByte)
 
 		r.nrBits += 8
@@ -54,6 +66,21 @@ func (r *AccErrReader) Read(n int) uint {
 	return value
 }
 
+// ReadBytes - read n bytes and return nil if (previous) error or if n bytes not available
+func (r *AccErrReader) ReadBytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	payload := make([]byte, n)
+	for i := 0; i < n; i++ {
+		payload[i] = byte(r.Read(8))
+	}
+	if r.err != nil {
+		return nil
+	}
+	return payload
+}
+
 // ReadFlag - read 1 bit into flag. Return false if error now or previously
 func (r *AccErrReader) ReadFlag() bool {
 	bit := r.Read(1)
@@ -63,15 +90,49 @@ func (r *AccErrReader) ReadFlag() bool {
 	return bit == 1
 }
 
-// ReadFlag - Read i(v) which is 2-complement of n bits
+// ReadExpGolomb - Read one unsigned exponential golomb code. Return 0 if error
+func (r *AccErrReader) ReadExpGolomb() uint {
+	if r.err != nil {
+		return 0
+	}
+	leadingZeroBits := 0
+	for {
+		b := r.Read(1)
+		if r.err != nil {
+			return 0
+		}
+		if b == 1 {
+			break
+		}
+		leadingZeroBits++
+	}
+	var res uint = (1 << leadingZeroBits) - 1
+	endBits := r.Read(leadingZeroBits)
+	if r.err != nil {
+		return 0
+	}
+	return res + endBits
+}
+
+// ReadSignedGolomb - Read one signed exponential golomb code. Return 0 if error
+func (r *AccErrReader) ReadSignedGolomb() int {
+	unsignedGolomb := r.ReadExpGolomb()
+	if r.err != nil {
+		return 0
+	}
+	if unsignedGolomb%2 == 1 {
+		return int((unsignedGolomb + 1) / 2)
+	}
+	return -int(unsignedGolomb / 2)
+}
+
+// ReadVInt - Read i(v) which is 2-complement of n bits
 func (r *AccErrReader) ReadVInt(n int) int {
 	uval := r.Read(n)
-	var ival int
-
-	if uval >= 1<<(n/2) {
-		ival = int(uval) - (1 << n)
+	if uval >= 1<<(n-1) {
+		return int(uval) - (1 << n)
 	}
-	return ival
+	return int(uval)
 }
 
 // ReadRemainingBytes - read remaining bytes if byte-aligned
@@ -88,5 +149,6 @@ func (r *AccErrReader) ReadRemainingBytes() []byte {
 		r.err = err
 		return nil
 	}
+	r.nrBytesRead += len(rest)
 	return rest
 }
@@ -65,3 +65,52 @@ func TestBadAccErrReader(t *testing.T) {
 		t.Errorf("Wanted io.EOF but got %v", err)
 	}
 }
+
+func TestAccErrReaderPosition(t *testing.T) {
+	input := []byte{0xff, 0x0f, 0x01, 0x02}
+	reader := NewAccErrReader(bytes.NewReader(input))
+	_ = reader.Read(3)
+	if reader.NrBytesRead() != 1 || reader.NrBitsReadInCurrentByte() != 3 {
+		t.Errorf("got %d bytes and %d bits instead of 1 and 3", reader.NrBytesRead(), reader.NrBitsReadInCurrentByte())
+	}
+	_ = reader.Read(5)
+	got := reader.ReadBytes(2)
+	if !bytes.Equal(got, []byte{0x0f, 0x01}) {
+		t.Errorf("got %x instead of 0f01", got)
+	}
+	rest := reader.ReadRemainingBytes()
+	if len(rest) != 1 || reader.NrBytesRead() != 4 {
+		t.Errorf("got %d remaining bytes and %d bytes read", len(rest), reader.NrBytesRead())
+	}
+	if reader.AccError() != nil {
+		t.Error(reader.AccError())
+	}
+}
+
+func TestAccErrReaderVInt(t *testing.T) {
+	input := []byte{0xf3, 0x7f}
+	reader := NewAccErrReader(bytes.NewReader(input))
+	got := []int{reader.ReadVInt(4), reader.ReadVInt(4), reader.ReadVInt(8)}
+	wanted := []int{-1, 3, 127}
+	for i := range wanted {
+		if got[i] != wanted[i] {
+			t.Errorf("value %d: got %d instead of %d", i, got[i], wanted[i])
+		}
+	}
+}
+
+func TestAccErrReaderExpGolomb(t *testing.T) {
+	input := []byte{0xa6, 0x42, 0x80} // 1 010 011 00100 00101
+	reader := NewAccErrReader(bytes.NewReader(input))
+	got := []int{int(reader.ReadExpGolomb()), int(reader.ReadExpGolomb()), int(reader.ReadExpGolomb()),
+		reader.ReadSignedGolomb(), reader.ReadSignedGolomb()}
+	wanted := []int{0, 1, 2, 2, -2}
+	for i := range wanted {
+		if got[i] != wanted[i] {
+			t.Errorf("value %d: got %d instead of %d", i, got[i], wanted[i])
+		}
+	}
+	if reader.AccError() != nil {
+		t.Error(reader.AccError())
+	}
+}
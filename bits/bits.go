@@ -59,6 +59,11 @@ func (w *Writer) Flush() {
 	}
 }
 
+// NrBitsInBuffer - number bits written in buffer byte
+func (w *Writer) NrBitsInBuffer() uint {
+	return uint(w.n)
+}
+
 // Error - error that has occurred and stopped writing
 func (w *Writer) Error() error {
 	return w.err
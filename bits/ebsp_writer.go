@@ -53,6 +53,8 @@ func (w *EBSPWriter) Write(bits uint, n int) {
 		}
 		if b == 0 {
 			w.nr0++
+		} else {
+			w.nr0 = 0
 		}
 		w.n -= 8
 	}
@@ -80,6 +82,15 @@ func (w *EBSPWriter) WriteExpGolomb(nr uint) {
 	}
 }
 
+// WriteSignedGolomb - write a signed exponential Golomb code
+func (w *EBSPWriter) WriteSignedGolomb(nr int) {
+	if nr > 0 {
+		w.WriteExpGolomb(uint(2*nr - 1))
+	} else {
+		w.WriteExpGolomb(uint(-2 * nr))
+	}
+}
+
 // WriteRbspTrailingBits - write rbsp trailing bits (a 1 followed by zeros to a byte boundary)
 func (w *EBSPWriter) WriteRbspTrailingBits() {
 	w.Write(1, 1)
@@ -31,6 +31,30 @@ func TestExpGolomb(t *testing.T) {
 	}
 }
 
+func TestSignedGolomb(t *testing.T) {
+	cases := []struct {
+		bits string
+		n    int
+	}{
+		{"1", 0},
+		{"010", 1},
+		{"011", -1},
+		{"00100", 2},
+		{"00101", -2},
+		{"0001111", -7},
+	}
+
+	for _, tc := range cases {
+		b := bytes.Buffer{}
+		w := NewEBSPWriter(&b)
+		w.WriteSignedGolomb(tc.n)
+		gotBits := getBitsWritten(w, &b)
+		if gotBits != tc.bits {
+			t.Errorf("wanted %s but got %s for %d", tc.bits, gotBits, tc.n)
+		}
+	}
+}
+
 func getBitsWritten(w *EBSPWriter, b *bytes.Buffer) string {
 	bits := ""
 	for _, c := range b.Bytes() {
@@ -0,0 +1,375 @@
+package captions
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CEA-608 screen size
+const (
+	cea608Rows = 15
+	cea608Cols = 32
+)
+
+// cea608Mode - caption mode of a CEA-608 channel
+type cea608Mode int
+
+const (
+	cea608ModeNone cea608Mode = iota
+	cea608ModePopOn
+	cea608ModeRollUp
+	cea608ModePaintOn
+	cea608ModeText
+)
+
+// emptyCell - empty character cell, also used for transparent space
+const emptyCell = rune(0)
+
+// cea608BasicChars - characters 0x20-0x7f that differ from ASCII
+var cea608BasicChars = map[byte]rune{
+	0x2a: 'á',
+	0x5c: 'é',
+	0x5e: 'í',
+	0x5f: 'ó',
+	0x60: 'ú',
+	0x7b: 'ç',
+	0x7c: '÷',
+	0x7d: 'Ñ',
+	0x7e: 'ñ',
+	0x7f: '█',
+}
+
+// cea608SpecialChars - characters for second byte 0x30-0x3f after 0x11
+var cea608SpecialChars = [16]rune{
+	'®', '°', '½', '¿', '™', '¢', '£', '♪', 'à', emptyCell, 'è', 'â', 'ê', 'î', 'ô', 'û',
+}
+
+// cea608ExtendedChars1 - Spanish, miscellaneous, and French characters for second byte 0x20-0x3f after 0x12
+var cea608ExtendedChars1 = [32]rune{
+	'Á', 'É', 'Ó', 'Ú', 'Ü', 'ü', '‘', '¡', '*', '\'', '—', '©', '℠', '•', '“', '”',
+	'À', 'Â', 'Ç', 'È', 'Ê', 'Ë', 'ë', 'Î', 'Ï', 'ï', 'Ô', 'Ù', 'ù', 'Û', '«', '»',
+}
+
+// cea608ExtendedChars2 - Portuguese, German, and Danish characters for second byte 0x20-0x3f after 0x13
+var cea608ExtendedChars2 = [32]rune{
+	'Ã', 'ã', 'Í', 'Ì', 'ì', 'Ò', 'ò', 'Õ', 'õ', '{', '}', '\\', '^', '_', '|', '~',
+	'Ä', 'ä', 'Ö', 'ö', 'ß', '¥', '¤', '│', 'Å', 'å', 'Ø', 'ø', '┌', '┐', '└', '┘',
+}
+
+// cea608PACRows - row (1-15) given by first byte & 0x07 and bit 0x20 of the second byte of a PAC
+var cea608PACRows = [8][2]int{
+	{11, 11}, {1, 2}, {3, 4}, {12, 13}, {14, 15}, {5, 6}, {7, 8}, {9, 10},
+}
+
+// CEA608Char - translate a CEA-608 basic character (without parity) to a rune
+func CEA608Char(c byte) rune {
+	if r, ok := cea608BasicChars[c]; ok {
+		return r
+	}
+	return rune(c)
+}
+
+// cea608Screen - character cells of a CEA-608 caption memory
+type cea608Screen [cea608Rows][cea608Cols]rune
+
+func (s *cea608Screen) clear() {
+	*s = cea608Screen{}
+}
+
+func (s *cea608Screen) clearRow(row int) {
+	s[row] = [cea608Cols]rune{}
+}
+
+func (s *cea608Screen) isEmpty() bool {
+	for r := range s {
+		for _, c := range s[r] {
+			if c != emptyCell && c != ' ' {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// rows - non-empty rows with position and text without leading and trailing space
+func (s *cea608Screen) rows() []CueRow {
+	var rows []CueRow
+	for r := range s {
+		first := -1
+		var sb strings.Builder
+		for col, c := range s[r] {
+			if first < 0 {
+				if c == emptyCell || c == ' ' {
+					continue
+				}
+				first = col
+			}
+			if c == emptyCell {
+				c = ' '
+			}
+			sb.WriteRune(c)
+		}
+		if first < 0 {
+			continue
+		}
+		rows = append(rows, CueRow{Row: r + 1, Column: first, Text: strings.TrimRight(sb.String(), " ")})
+	}
+	return rows
+}
+
+// cea608Channel - decoder state for one of the caption channels CC1-CC4
+type cea608Channel struct {
+	name         string
+	mode         cea608Mode
+	displayed    cea608Screen
+	nonDisplayed cea608Screen
+	row          int // 0-based cursor row
+	col          int // 0-based cursor column
+	rollUpRows   int
+	cueOpen      bool
+	cueStart     uint64
+	cues         []Cue
+}
+
+func newCEA608Channel(name string) *cea608Channel {
+	return &cea608Channel{name: name, row: cea608Rows - 1}
+}
+
+// memory - caption memory where characters are written in current mode
+func (c *cea608Channel) memory() *cea608Screen {
+	if c.mode == cea608ModePopOn {
+		return &c.nonDisplayed
+	}
+	return &c.displayed
+}
+
+// startCue - start a new cue at t if something is displayed
+func (c *cea608Channel) startCue(t uint64) {
+	if !c.cueOpen && !c.displayed.isEmpty() {
+		c.cueOpen = true
+		c.cueStart = t
+	}
+}
+
+// endCue - end an open cue at t with the currently displayed content
+func (c *cea608Channel) endCue(t uint64) {
+	if !c.cueOpen {
+		return
+	}
+	c.cueOpen = false
+	rows := c.displayed.rows()
+	if len(rows) == 0 || t <= c.cueStart {
+		return
+	}
+	c.cues = append(c.cues, Cue{Channel: c.name, StartTime: c.cueStart, EndTime: t, Rows: rows})
+}
+
+// writeChar - write a character at the cursor position and advance the cursor
+func (c *cea608Channel) writeChar(t uint64, r rune) {
+	if c.mode == cea608ModeNone || c.mode == cea608ModeText {
+		return
+	}
+	if c.col >= cea608Cols {
+		c.col = cea608Cols - 1
+	}
+	c.memory()[c.row][c.col] = r
+	c.col++
+	if c.mode != cea608ModePopOn {
+		c.startCue(t)
+	}
+}
+
+// backspace - move cursor one step left and clear that cell
+func (c *cea608Channel) backspace() {
+	if c.col > 0 {
+		c.col--
+		c.memory()[c.row][c.col] = emptyCell
+	}
+}
+
+// rollUp - move the roll-up window rows up one step and clear the base row
+func (c *cea608Channel) rollUp() {
+	top := c.row - c.rollUpRows + 1
+	for r := 0; r < c.row; r++ {
+		if r >= top {
+			c.displayed[r] = c.displayed[r+1]
+		} else {
+			c.displayed.clearRow(r)
+		}
+	}
+	c.displayed.clearRow(c.row)
+}
+
+// setRollUpBaseRow - move the roll-up window content to a new base row
+func (c *cea608Channel) setRollUpBaseRow(row int) {
+	if row == c.row {
+		return
+	}
+	var moved cea608Screen
+	for i := 0; i < c.rollUpRows; i++ {
+		from, to := c.row-i, row-i
+		if from >= 0 && to >= 0 {
+			moved[to] = c.displayed[from]
+		}
+	}
+	c.displayed = moved
+	c.row = row
+}
+
+// miscControl - handle miscellaneous control codes (second byte 0x20-0x2f)
+func (c *cea608Channel) miscControl(t uint64, code byte) {
+	switch code {
+	case 0x20: // RCL Resume Caption Loading
+		c.mode = cea608ModePopOn
+	case 0x21: // BS Backspace
+		c.backspace()
+	case 0x24: // DER Delete to End of Row
+		mem := c.memory()
+		for col := c.col; col < cea608Cols; col++ {
+			mem[c.row][col] = emptyCell
+		}
+	case 0x25, 0x26, 0x27: // RU2, RU3, RU4 Roll-Up Captions
+		if c.mode != cea608ModeRollUp {
+			c.endCue(t)
+			c.displayed.clear()
+			c.nonDisplayed.clear()
+			c.row = cea608Rows - 1
+		}
+		c.mode = cea608ModeRollUp
+		c.rollUpRows = int(code-0x25) + 2
+		if c.row < c.rollUpRows-1 {
+			c.row = c.rollUpRows - 1
+		}
+		c.col = 0
+	case 0x29: // RDC Resume Direct Captioning
+		c.mode = cea608ModePaintOn
+	case 0x2a, 0x2b: // TR Text Restart, RTD Resume Text Display
+		c.mode = cea608ModeText
+	case 0x2c: // EDM Erase Displayed Memory
+		c.endCue(t)
+		c.displayed.clear()
+	case 0x2d: // CR Carriage Return
+		if c.mode == cea608ModeRollUp {
+			c.endCue(t)
+			c.rollUp()
+			c.col = 0
+			c.startCue(t)
+		}
+	case 0x2e: // ENM Erase Non-Displayed Memory
+		c.nonDisplayed.clear()
+	case 0x2f: // EOC End Of Caption
+		c.endCue(t)
+		c.displayed, c.nonDisplayed = c.nonDisplayed, c.displayed
+		c.mode = cea608ModePopOn
+		c.startCue(t)
+	}
+}
+
+// pac - handle Preamble Address Code which sets row and indentation
+func (c *cea608Channel) pac(b1, b2 byte) {
+	row := cea608PACRows[b1&0x07][(b2>>5)&0x01] - 1
+	if c.mode == cea608ModeRollUp {
+		if row < c.rollUpRows-1 {
+			row = c.rollUpRows - 1
+		}
+		c.setRollUpBaseRow(row)
+	} else {
+		c.row = row
+	}
+	c.col = 0
+	attr := (b2 & 0x1e) >> 1
+	if attr >= 8 {
+		c.col = int(attr-8) * 4
+	}
+}
+
+// flush - end open cue at time t
+func (c *cea608Channel) flush(t uint64) {
+	c.endCue(t)
+}
+
+// cea608Field - decoder for the two channels in one field (CC1/CC2 or CC3/CC4)
+type cea608Field struct {
+	channels    [2]*cea608Channel
+	current     int
+	lastControl [2]byte
+	inXDS       bool
+}
+
+func newCEA608Field(fieldNr int) *cea608Field {
+	first := 2*fieldNr - 1
+	return &cea608Field{
+		channels: [2]*cea608Channel{
+			newCEA608Channel(fmt.Sprintf("CC%d", first)),
+			newCEA608Channel(fmt.Sprintf("CC%d", first+1)),
+		},
+	}
+}
+
+// addPair - decode one CEA-608 byte pair (parity bits are removed)
+func (f *cea608Field) addPair(t uint64, b1, b2 byte) {
+	b1, b2 = b1&0x7f, b2&0x7f
+	if b1 == 0 && b2 == 0 {
+		return
+	}
+	if b1 >= 0x10 && b1 <= 0x1f {
+		f.inXDS = false
+		if f.lastControl == [2]byte{b1, b2} {
+			f.lastControl = [2]byte{} // Control codes are often sent twice
+			return
+		}
+		f.lastControl = [2]byte{b1, b2}
+		f.current = int(b1&0x08) >> 3
+		f.control(t, b1&^0x08, b2)
+		return
+	}
+	f.lastControl = [2]byte{}
+	switch {
+	case b1 == 0:
+		return
+	case b1 < 0x10: // XDS start or end
+		f.inXDS = b1 != 0x0f
+		return
+	case f.inXDS:
+		return
+	}
+	ch := f.channels[f.current]
+	ch.writeChar(t, CEA608Char(b1))
+	if b2 >= 0x20 {
+		ch.writeChar(t, CEA608Char(b2))
+	}
+}
+
+// control - handle control code with first byte normalized to channel 1 (0x10-0x17)
+func (f *cea608Field) control(t uint64, b1, b2 byte) {
+	ch := f.channels[f.current]
+	switch {
+	case b2 >= 0x40 && b2 <= 0x7f:
+		ch.pac(b1, b2)
+	case (b1 == 0x14 || b1 == 0x15) && b2 >= 0x20 && b2 <= 0x2f:
+		ch.miscControl(t, b2)
+	case b1 == 0x17 && b2 >= 0x21 && b2 <= 0x23: // Tab offsets
+		ch.col += int(b2 - 0x20)
+		if ch.col >= cea608Cols {
+			ch.col = cea608Cols - 1
+		}
+	case b1 == 0x11 && b2 >= 0x20 && b2 <= 0x2f: // Mid-row code, displayed as space
+		ch.writeChar(t, ' ')
+	case b1 == 0x11 && b2 >= 0x30 && b2 <= 0x3f:
+		ch.writeChar(t, cea608SpecialChars[b2-0x30])
+	case b1 == 0x12 && b2 >= 0x20 && b2 <= 0x3f:
+		ch.backspace() // Extended characters replace the preceding basic character
+		ch.writeChar(t, cea608ExtendedChars1[b2-0x20])
+	case b1 == 0x13 && b2 >= 0x20 && b2 <= 0x3f:
+		ch.backspace()
+		ch.writeChar(t, cea608ExtendedChars2[b2-0x20])
+	}
+	// Background attributes (0x10 and 0x17 with 0x20-0x2f) do not change the text
+}
+
+// flush - end open cues in both channels at time t
+func (f *cea608Field) flush(t uint64) {
+	for _, ch := range f.channels {
+		ch.flush(t)
+	}
+}
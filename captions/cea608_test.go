@@ -0,0 +1,77 @@
+package captions
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+// addHexPairs - add CEA-608 byte pairs given as hex string, one pair per time unit
+func addHexPairs(t *testing.T, d *Decoder, startTime uint64, fieldNr int, hexPairs string) uint64 {
+	t.Helper()
+	data, err := hex.DecodeString(hexPairs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < len(data); i += 2 {
+		d.fields[fieldNr-1].addPair(startTime, data[i], data[i+1])
+		startTime++
+	}
+	return startTime
+}
+
+func TestCEA608PopOn(t *testing.T) {
+	d := NewDecoder(1000)
+	// RCL (twice), PAC row 15 indent 12, TO2, "Bip!", EDM, EOC
+	end := addHexPairs(t, d, 100, 1, "942094209476"+"97a2"+"c2e970a1"+"942c942f")
+	// RCL, ENM, PAC row 1, "Hi", EOC (replaces displayed caption)
+	end = addHexPairs(t, d, 200, 1, "9420"+"94ae"+"9140"+"c8e9"+"942f")
+	d.Flush(300)
+	wanted := []Cue{
+		{Channel: "CC1", StartTime: 107, EndTime: 204, Rows: []CueRow{{Row: 15, Column: 14, Text: "Bip!"}}},
+		{Channel: "CC1", StartTime: 204, EndTime: 300, Rows: []CueRow{{Row: 1, Column: 0, Text: "Hi"}}},
+	}
+	if end != 205 {
+		t.Errorf("got end %d", end)
+	}
+	if diff := deep.Equal(d.Cues(), wanted); diff != nil {
+		t.Error(diff)
+	}
+}
+
+func TestCEA608RollUp(t *testing.T) {
+	d := NewDecoder(1000)
+	// RU2, CR, PAC row 15, "AB", CR, "CD", CR
+	addHexPairs(t, d, 0, 1, "9425"+"94ad"+"94e0"+"c1c2")
+	addHexPairs(t, d, 10, 1, "94ad"+"4344")
+	addHexPairs(t, d, 20, 1, "94ad")
+	d.Flush(30)
+	wanted := []Cue{
+		{Channel: "CC1", StartTime: 3, EndTime: 10, Rows: []CueRow{{Row: 15, Column: 0, Text: "AB"}}},
+		{Channel: "CC1", StartTime: 10, EndTime: 20,
+			Rows: []CueRow{{Row: 14, Column: 0, Text: "AB"}, {Row: 15, Column: 0, Text: "CD"}}},
+		{Channel: "CC1", StartTime: 20, EndTime: 30, Rows: []CueRow{{Row: 14, Column: 0, Text: "CD"}}},
+	}
+	if diff := deep.Equal(d.Cues(), wanted); diff != nil {
+		t.Error(diff)
+	}
+}
+
+func TestCEA608PaintOnAndCharacters(t *testing.T) {
+	d := NewDecoder(1000)
+	// CC2 in field 1: RDC, PAC row 5, "e", extended É replacing it, special ♪, mid-row code, "*" (á)
+	addHexPairs(t, d, 0, 1, "1c29"+"1d40"+"6580"+"1a21"+"1937"+"1920"+"2a80")
+	// CC3 in field 2: RDC, PAC row 1 indent 4, "A", BS, "B"
+	addHexPairs(t, d, 0, 2, "1529"+"1152"+"4180"+"1521"+"4280")
+	// XDS packet in field 2 is ignored
+	addHexPairs(t, d, 10, 2, "0101"+"4142"+"0f00")
+	d.Flush(50)
+	wanted := []Cue{
+		{Channel: "CC2", StartTime: 2, EndTime: 50, Rows: []CueRow{{Row: 5, Column: 0, Text: "É♪ á"}}},
+		{Channel: "CC3", StartTime: 2, EndTime: 50, Rows: []CueRow{{Row: 1, Column: 4, Text: "B"}}},
+	}
+	if diff := deep.Equal(d.Cues(), wanted); diff != nil {
+		t.Error(diff)
+	}
+}
@@ -0,0 +1,414 @@
+package captions
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Limits for CEA-708 windows
+const (
+	cea708NrWindows = 8
+	cea708MaxRows   = 15
+	cea708MaxCols   = 42
+)
+
+// cea708G2Chars - characters in the G2 table that are supported
+var cea708G2Chars = map[byte]rune{
+	0x20: emptyCell, // Transparent space
+	0x21: ' ',       // Non-breaking transparent space
+	0x25: '…',
+	0x2a: 'Š',
+	0x2c: 'Œ',
+	0x30: '█',
+	0x31: '‘',
+	0x32: '’',
+	0x33: '“',
+	0x34: '”',
+	0x35: '•',
+	0x39: '™',
+	0x3a: 'š',
+	0x3c: 'œ',
+	0x3d: '℠',
+	0x3f: 'Ÿ',
+	0x76: '⅛',
+	0x77: '⅜',
+	0x78: '⅝',
+	0x79: '⅞',
+	0x7a: '│',
+	0x7b: '┐',
+	0x7c: '└',
+	0x7d: '─',
+	0x7e: '┘',
+	0x7f: '┌',
+}
+
+// cea708C1ParamLengths - number of parameter bytes for C1 commands 0x80-0x9f
+var cea708C1ParamLengths = [32]int{
+	0, 0, 0, 0, 0, 0, 0, 0, // CW0-CW7
+	1, 1, 1, 1, 1, 1, 0, 0, // CLW, DSW, HDW, TGW, DLW, DLY, DLC, RST
+	2, 3, 2, 0, 0, 0, 0, 4, // SPA, SPC, SPL, reserved, SWA
+	6, 6, 6, 6, 6, 6, 6, 6, // DF0-DF7
+}
+
+// cea708Window - a CEA-708 caption window with its text content
+type cea708Window struct {
+	id       int
+	visible  bool
+	position WindowPosition
+	nrRows   int
+	nrCols   int
+	cells    [][]rune
+	penRow   int
+	penCol   int
+	cueOpen  bool
+	cueStart uint64
+}
+
+func newCEA708Window(id, nrRows, nrCols int) *cea708Window {
+	w := &cea708Window{id: id, nrRows: nrRows, nrCols: nrCols}
+	w.cells = make([][]rune, nrRows)
+	for r := range w.cells {
+		w.cells[r] = make([]rune, nrCols)
+	}
+	return w
+}
+
+func (w *cea708Window) clear() {
+	for r := range w.cells {
+		w.clearRow(r)
+	}
+}
+
+func (w *cea708Window) clearRow(row int) {
+	for c := range w.cells[row] {
+		w.cells[row][c] = emptyCell
+	}
+}
+
+// rows - non-empty rows with 1-based row number within the window
+func (w *cea708Window) rows() []CueRow {
+	var rows []CueRow
+	for r, cells := range w.cells {
+		text := strings.TrimRight(strings.Map(func(c rune) rune {
+			if c == emptyCell {
+				return ' '
+			}
+			return c
+		}, string(cells)), " ")
+		trimmed := strings.TrimLeft(text, " ")
+		if trimmed == "" {
+			continue
+		}
+		rows = append(rows, CueRow{Row: r + 1, Column: len([]rune(text)) - len([]rune(trimmed)), Text: trimmed})
+	}
+	return rows
+}
+
+// cea708Service - decoder state for one CEA-708 caption service
+type cea708Service struct {
+	name    string
+	windows [cea708NrWindows]*cea708Window
+	current int
+	cues    []Cue
+}
+
+func newCEA708Service(serviceNr int) *cea708Service {
+	return &cea708Service{name: fmt.Sprintf("SERVICE%d", serviceNr)}
+}
+
+// startCue - start a cue for window at t if it is visible and not empty
+func (s *cea708Service) startCue(t uint64, w *cea708Window) {
+	if w.cueOpen || !w.visible || len(w.rows()) == 0 {
+		return
+	}
+	w.cueOpen = true
+	w.cueStart = t
+}
+
+// endCue - end an open cue for window at t with the current window content
+func (s *cea708Service) endCue(t uint64, w *cea708Window) {
+	if !w.cueOpen {
+		return
+	}
+	w.cueOpen = false
+	rows := w.rows()
+	if len(rows) == 0 || t <= w.cueStart {
+		return
+	}
+	pos := w.position
+	s.cues = append(s.cues, Cue{Channel: s.name, StartTime: w.cueStart, EndTime: t, Rows: rows, Window: &pos})
+}
+
+// windowsInBitmap - defined windows with bit set in bitmap
+func (s *cea708Service) windowsInBitmap(bitmap byte) []*cea708Window {
+	var windows []*cea708Window
+	for i, w := range s.windows {
+		if w != nil && bitmap&(1<<uint(i)) != 0 {
+			windows = append(windows, w)
+		}
+	}
+	return windows
+}
+
+// decode - decode the data of one service block
+func (s *cea708Service) decode(t uint64, data []byte) {
+	for pos := 0; pos < len(data); {
+		b := data[pos]
+		pos++
+		switch {
+		case b <= 0x1f: // C0 codes
+			switch {
+			case b == 0x10: // EXT1
+				if pos >= len(data) {
+					return
+				}
+				pos += s.extended(t, data[pos:])
+			case b >= 0x18: // P16 and other codes with two-byte parameter
+				pos += 2
+			case b >= 0x11:
+				pos++
+			default:
+				s.c0Control(t, b)
+			}
+		case b <= 0x7f: // G0
+			if b == 0x7f {
+				s.writeChar(t, '♪')
+			} else {
+				s.writeChar(t, rune(b))
+			}
+		case b <= 0x9f: // C1
+			nrParams := cea708C1ParamLengths[b-0x80]
+			if pos+nrParams > len(data) {
+				return
+			}
+			s.c1Command(t, b, data[pos:pos+nrParams])
+			pos += nrParams
+		default: // G1 (ISO 8859-1)
+			s.writeChar(t, rune(b))
+		}
+	}
+}
+
+// extended - handle code after EXT1. Return number of bytes consumed
+func (s *cea708Service) extended(t uint64, data []byte) int {
+	b := data[0]
+	switch {
+	case b <= 0x07: // C2
+		return 1
+	case b <= 0x0f:
+		return 2
+	case b <= 0x17:
+		return 3
+	case b <= 0x1f:
+		return 4
+	case b <= 0x7f: // G2
+		if r, ok := cea708G2Chars[b]; ok {
+			s.writeChar(t, r)
+		}
+		return 1
+	case b <= 0x87: // C3
+		return 5
+	case b <= 0x8f:
+		return 6
+	case b <= 0x9f: // Variable length C3 command
+		if len(data) < 2 {
+			return len(data)
+		}
+		return 2 + int(data[1]&0x3f)
+	default: // G3
+		if b == 0xa0 {
+			s.writeChar(t, '㏄')
+		}
+		return 1
+	}
+}
+
+// c0Control - handle C0 control codes without parameters
+func (s *cea708Service) c0Control(t uint64, b byte) {
+	w := s.windows[s.current]
+	if w == nil {
+		return
+	}
+	switch b {
+	case 0x08: // BS
+		if w.penCol > 0 {
+			w.penCol--
+			w.cells[w.penRow][w.penCol] = emptyCell
+		}
+	case 0x0c: // FF
+		s.endCue(t, w)
+		w.clear()
+		w.penRow, w.penCol = 0, 0
+	case 0x0d: // CR
+		s.endCue(t, w)
+		w.penRow++
+		if w.penRow >= w.nrRows {
+			copy(w.cells, w.cells[1:])
+			w.cells[w.nrRows-1] = make([]rune, w.nrCols)
+			w.penRow = w.nrRows - 1
+		}
+		w.penCol = 0
+		s.startCue(t, w)
+	case 0x0e: // HCR
+		w.clearRow(w.penRow)
+		w.penCol = 0
+	}
+}
+
+// c1Command - handle C1 window and pen commands
+func (s *cea708Service) c1Command(t uint64, b byte, params []byte) {
+	switch {
+	case b <= 0x87: // CWx
+		s.current = int(b - 0x80)
+	case b == 0x88: // CLW
+		for _, w := range s.windowsInBitmap(params[0]) {
+			s.endCue(t, w)
+			w.clear()
+		}
+	case b == 0x89: // DSW
+		for _, w := range s.windowsInBitmap(params[0]) {
+			w.visible = true
+			s.startCue(t, w)
+		}
+	case b == 0x8a: // HDW
+		for _, w := range s.windowsInBitmap(params[0]) {
+			s.endCue(t, w)
+			w.visible = false
+		}
+	case b == 0x8b: // TGW
+		for _, w := range s.windowsInBitmap(params[0]) {
+			s.endCue(t, w)
+			w.visible = !w.visible
+			s.startCue(t, w)
+		}
+	case b == 0x8c: // DLW
+		for _, w := range s.windowsInBitmap(params[0]) {
+			s.endCue(t, w)
+			s.windows[w.id] = nil
+		}
+	case b == 0x8f: // RST
+		s.flush(t)
+		s.windows = [cea708NrWindows]*cea708Window{}
+	case b == 0x92: // SPL
+		w := s.windows[s.current]
+		if w == nil {
+			return
+		}
+		w.penRow = clampInt(int(params[0]&0x0f), w.nrRows-1)
+		w.penCol = clampInt(int(params[1]&0x3f), w.nrCols-1)
+	case b >= 0x98: // DFx
+		s.defineWindow(t, int(b-0x98), params)
+	}
+	// SPA, SPC, SWA, DLY, and DLC only affect presentation style and timing
+}
+
+// defineWindow - create or update window from the 6 DefineWindow parameters
+func (s *cea708Service) defineWindow(t uint64, id int, p []byte) {
+	nrRows := clampInt(int(p[3]&0x0f)+1, cea708MaxRows)
+	nrCols := clampInt(int(p[4]&0x3f)+1, cea708MaxCols)
+	w := s.windows[id]
+	if w != nil {
+		s.endCue(t, w)
+	}
+	if w == nil || w.nrRows != nrRows || w.nrCols != nrCols {
+		w = newCEA708Window(id, nrRows, nrCols)
+		s.windows[id] = w
+	}
+	w.visible = p[0]&0x20 != 0
+	w.position = WindowPosition{
+		ID:                  id,
+		RelativePositioning: p[1]&0x80 != 0,
+		AnchorVertical:      int(p[1] & 0x7f),
+		AnchorHorizontal:    int(p[2]),
+		AnchorPoint:         int(p[3] >> 4),
+	}
+	s.current = id
+	s.startCue(t, w)
+}
+
+// writeChar - write character at pen position in current window
+func (s *cea708Service) writeChar(t uint64, r rune) {
+	w := s.windows[s.current]
+	if w == nil {
+		return
+	}
+	if w.penCol >= w.nrCols {
+		w.penCol = w.nrCols - 1
+	}
+	w.cells[w.penRow][w.penCol] = r
+	w.penCol++
+	s.startCue(t, w)
+}
+
+// flush - end open cues of all windows at time t
+func (s *cea708Service) flush(t uint64) {
+	for _, w := range s.windows {
+		if w != nil {
+			s.endCue(t, w)
+		}
+	}
+}
+
+// dtvccDecoder - assembles DTVCC packets from cc_data and decodes their service blocks
+type dtvccDecoder struct {
+	packet   []byte
+	services map[int]*cea708Service
+}
+
+func newDTVCCDecoder() *dtvccDecoder {
+	return &dtvccDecoder{services: make(map[int]*cea708Service)}
+}
+
+// addCCData - add DTVCC cc_data bytes. Packet start (ccType 3) begins a new packet
+func (d *dtvccDecoder) addCCData(t uint64, ccType byte, b1, b2 byte) {
+	if ccType == 3 {
+		d.packet = d.packet[:0]
+	} else if len(d.packet) == 0 {
+		return // Data without packet start
+	}
+	d.packet = append(d.packet, b1, b2)
+	packetSize := int(d.packet[0]&0x3f) * 2
+	if packetSize == 0 {
+		packetSize = 128
+	}
+	if len(d.packet) >= packetSize {
+		d.decodePacket(t, d.packet[1:packetSize])
+		d.packet = d.packet[:0]
+	}
+}
+
+// decodePacket - decode service blocks in a DTVCC packet (without header)
+func (d *dtvccDecoder) decodePacket(t uint64, p []byte) {
+	for pos := 0; pos < len(p); {
+		serviceNr := int(p[pos] >> 5)
+		blockSize := int(p[pos] & 0x1f)
+		pos++
+		if serviceNr == 0 || blockSize == 0 {
+			return // Null service block header signals end of data
+		}
+		if serviceNr == 7 {
+			if pos >= len(p) {
+				return
+			}
+			serviceNr = int(p[pos] & 0x3f)
+			pos++
+		}
+		if pos+blockSize > len(p) {
+			return
+		}
+		s, ok := d.services[serviceNr]
+		if !ok {
+			s = newCEA708Service(serviceNr)
+			d.services[serviceNr] = s
+		}
+		s.decode(t, p[pos:pos+blockSize])
+		pos += blockSize
+	}
+}
+
+func clampInt(v, maxVal int) int {
+	if v > maxVal {
+		return maxVal
+	}
+	return v
+}
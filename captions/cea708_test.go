@@ -0,0 +1,47 @@
+package captions
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/jaypadia-frame/mp4ff/sei"
+)
+
+// dtvccCCData - split DTVCC packet into cc_data triplets
+func dtvccCCData(t *testing.T, packetHex string) []sei.CCData {
+	t.Helper()
+	packet, err := hex.DecodeString(packetHex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ccData []sei.CCData
+	for i := 0; i < len(packet); i += 2 {
+		ccType := byte(2)
+		if i == 0 {
+			ccType = 3
+		}
+		ccData = append(ccData, sei.CCData{Valid: true, Type: ccType, Data1: packet[i], Data2: packet[i+1]})
+	}
+	return ccData
+}
+
+func TestCEA708Service(t *testing.T) {
+	d := NewDecoder(1000)
+	// Service 1: DefineWindow 0 (visible, 2 rows, 32 columns), "Hi"
+	d.AddCCData(0, dtvccCCData(t, "06299820"+"0a14011f00"+"486900"))
+	// Service 1: CR, "Yo"
+	d.AddCCData(10, dtvccCCData(t, "43230d596f00"))
+	// Service 1: HideWindows 0
+	d.AddCCData(20, dtvccCCData(t, "82228a01"))
+	d.Flush(30)
+	pos := &WindowPosition{ID: 0, AnchorVertical: 10, AnchorHorizontal: 20}
+	wanted := []Cue{
+		{Channel: "SERVICE1", StartTime: 0, EndTime: 10, Rows: []CueRow{{Row: 1, Column: 0, Text: "Hi"}}, Window: pos},
+		{Channel: "SERVICE1", StartTime: 10, EndTime: 20,
+			Rows: []CueRow{{Row: 1, Column: 0, Text: "Hi"}, {Row: 2, Column: 0, Text: "Yo"}}, Window: pos},
+	}
+	if diff := deep.Equal(d.Cues(), wanted); diff != nil {
+		t.Error(diff)
+	}
+}
@@ -0,0 +1,225 @@
+package captions
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+
+	"github.com/jaypadia-frame/mp4ff/avc"
+	"github.com/jaypadia-frame/mp4ff/hevc"
+	"github.com/jaypadia-frame/mp4ff/mp4"
+	"github.com/jaypadia-frame/mp4ff/sei"
+)
+
+// videoSampleCCData - cc_data in registered SEI NAL units of an AVC or HEVC sample with length fields of
+// lengthSize bytes
+func videoSampleCCData(sample []byte, codec string, lengthSize int) ([]sei.CCData, error) {
+	nalus, err := avc.GetNalusFromSampleWithLengthSize(sample, lengthSize)
+	if err != nil {
+		return nil, err
+	}
+	var ccData []sei.CCData
+	for _, nalu := range nalus {
+		var hdrLen int
+		switch codec {
+		case "avc":
+			if avc.GetNaluType(nalu[0]) != avc.NALU_SEI {
+				continue
+			}
+			hdrLen = 1
+		case "hevc":
+			naluType := hevc.GetNaluType(nalu[0])
+			if naluType != hevc.NALU_SEI_PREFIX && naluType != hevc.NALU_SEI_SUFFIX {
+				continue
+			}
+			hdrLen = 2
+		}
+		seiDatas, err := sei.ExtractSEIData(bytes.NewReader(nalu[hdrLen:]))
+		if err != nil {
+			return nil, err
+		}
+		for i := range seiDatas {
+			if seiDatas[i].Type() != sei.SEIRegisteredType {
+				continue
+			}
+			msg, err := sei.DecodeUserDataRegisteredSEI(&seiDatas[i])
+			if err != nil {
+				return nil, err
+			}
+			if cea, ok := msg.(*sei.CEA708SEI); ok {
+				ccData = append(ccData, cea.CCData...)
+			}
+		}
+	}
+	return ccData, nil
+}
+
+// parseClcpSample - CEA-608 byte pairs of the cdat (field 1) and cdt2 (field 2) boxes in a clcp sample
+func parseClcpSample(sample []byte) (field1, field2 []byte, err error) {
+	for pos := 0; pos < len(sample); {
+		if pos+8 > len(sample) {
+			return nil, nil, fmt.Errorf("clcp sample: %d bytes left for box header", len(sample)-pos)
+		}
+		size := int(binary.BigEndian.Uint32(sample[pos:]))
+		boxType := string(sample[pos+4 : pos+8])
+		if size < 8 || pos+size > len(sample) {
+			return nil, nil, fmt.Errorf("clcp sample: bad %s box size %d", boxType, size)
+		}
+		payload := sample[pos+8 : pos+size]
+		switch boxType {
+		case "cdat":
+			field1 = append(field1, payload...)
+		case "cdt2":
+			field2 = append(field2, payload...)
+		}
+		pos += size
+	}
+	if len(field1)%2 != 0 || len(field2)%2 != 0 {
+		return nil, nil, fmt.Errorf("clcp sample: odd number of CEA-608 bytes")
+	}
+	return field1, field2, nil
+}
+
+// isCEA608Padding - is byte pair a null pair (with or without parity)
+func isCEA608Padding(b1, b2 byte) bool {
+	return b1&0x7f == 0 && b2&0x7f == 0
+}
+
+// VideoCCData - cc_data from registered SEI messages in AVC or HEVC samples at their presentation times.
+// The samples have 4-byte NAL unit lengths. The result is sorted in presentation order.
+func VideoCCData(samples []mp4.FullSample, codec string) ([]TimedCCData, error) {
+	return VideoCCDataWithLengthSize(samples, codec, 4)
+}
+
+// VideoCCDataWithLengthSize - VideoCCData for samples with NAL unit length fields of lengthSize bytes
+func VideoCCDataWithLengthSize(samples []mp4.FullSample, codec string, lengthSize int) ([]TimedCCData, error) {
+	var ccData []TimedCCData
+	for _, s := range samples {
+		sampleCC, err := videoSampleCCData(s.Data, codec, lengthSize)
+		if err != nil {
+			return nil, fmt.Errorf("sample at %d: %w", s.DecodeTime, err)
+		}
+		if len(sampleCC) > 0 {
+			ccData = append(ccData, TimedCCData{Time: s.PresentationTime(), CCData: sampleCC})
+		}
+	}
+	sort.SliceStable(ccData, func(i, j int) bool {
+		return ccData[i].Time < ccData[j].Time
+	})
+	return ccData, nil
+}
+
+// ClcpCCData - CEA-608 cc_data from clcp samples with cdat and cdt2 boxes.
+// The byte pairs of a sample are spaced by one 29.97 Hz frame starting at the sample presentation time.
+func ClcpCCData(samples []mp4.FullSample, timescale uint32) ([]TimedCCData, error) {
+	var ccData []TimedCCData
+	for _, s := range samples {
+		field1, field2, err := parseClcpSample(s.Data)
+		if err != nil {
+			return nil, err
+		}
+		for fieldIdx, pairs := range [2][]byte{field1, field2} {
+			for i := 0; i < len(pairs); i += 2 {
+				ccData = append(ccData, TimedCCData{
+					Time: s.PresentationTime() + uint64(i/2)*uint64(timescale)*1001/30000,
+					CCData: []sei.CCData{
+						{Valid: true, Type: byte(fieldIdx), Data1: pairs[i], Data2: pairs[i+1]},
+					},
+				})
+			}
+		}
+	}
+	sort.SliceStable(ccData, func(i, j int) bool {
+		return ccData[i].Time < ccData[j].Time
+	})
+	return ccData, nil
+}
+
+// CreateClcpSample - create clcp sample with a cdat box for field 1 and a cdt2 box for field 2 byte pairs.
+// Boxes are only written for fields with data.
+func CreateClcpSample(field1, field2 []byte) ([]byte, error) {
+	if len(field1)%2 != 0 || len(field2)%2 != 0 {
+		return nil, fmt.Errorf("odd number of CEA-608 bytes")
+	}
+	buf := bytes.Buffer{}
+	if len(field1) > 0 {
+		if err := (&mp4.CdatBox{Data: field1}).Encode(&buf); err != nil {
+			return nil, err
+		}
+	}
+	if len(field2) > 0 {
+		if err := (&mp4.Cdt2Box{Data: field2}).Encode(&buf); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// CreateClcpSamples - create clcp samples for the valid non-padding CEA-608 data in ccData.
+// There is one sample for each time with data, which lasts until the next sample.
+// The first sample starts at startTime, and only contains padding if there is no data at that time.
+// Data before startTime is put in the first sample, and the last sample ends at endTime.
+// CEA-708 DTVCC data cannot be carried in c608 clcp samples and is dropped.
+func CreateClcpSamples(ccData []TimedCCData, startTime, endTime uint64) ([]mp4.FullSample, error) {
+	type clcpData struct {
+		time   uint64
+		fields [2][]byte
+	}
+	sorted := make([]TimedCCData, len(ccData))
+	copy(sorted, ccData)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Time < sorted[j].Time
+	})
+	datas := []clcpData{{time: startTime}}
+	for _, tc := range sorted {
+		t := tc.Time
+		if t < startTime {
+			t = startTime
+		}
+		for _, cc := range tc.CCData {
+			if !cc.Valid || cc.Type > 1 || isCEA608Padding(cc.Data1, cc.Data2) {
+				continue
+			}
+			last := &datas[len(datas)-1]
+			if t != last.time {
+				datas = append(datas, clcpData{time: t})
+				last = &datas[len(datas)-1]
+			}
+			last.fields[cc.Type] = append(last.fields[cc.Type], cc.Data1, cc.Data2)
+		}
+	}
+	if last := datas[len(datas)-1]; endTime <= last.time {
+		return nil, fmt.Errorf("endTime %d not after last caption data at %d", endTime, last.time)
+	}
+	samples := make([]mp4.FullSample, 0, len(datas))
+	for i, d := range datas {
+		field1 := d.fields[0]
+		if len(field1) == 0 && len(d.fields[1]) == 0 {
+			field1 = []byte{0x80, 0x80}
+		}
+		data, err := CreateClcpSample(field1, d.fields[1])
+		if err != nil {
+			return nil, err
+		}
+		nextTime := endTime
+		if i+1 < len(datas) {
+			nextTime = datas[i+1].time
+		}
+		samples = append(samples, mp4.FullSample{
+			Sample:     mp4.NewSample(mp4.SyncSampleFlags, uint32(nextTime-d.time), uint32(len(data)), 0),
+			DecodeTime: d.time,
+			Data:       data,
+		})
+	}
+	return samples, nil
+}
+
+// RescaleCCData - change time of cc_data from one timescale to another
+func RescaleCCData(ccData []TimedCCData, fromTimescale, toTimescale uint32) []TimedCCData {
+	out := make([]TimedCCData, len(ccData))
+	for i, tc := range ccData {
+		out[i] = TimedCCData{Time: tc.Time * uint64(toTimescale) / uint64(fromTimescale), CCData: tc.CCData}
+	}
+	return out
+}
@@ -0,0 +1,108 @@
+package captions
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Cue - caption text displayed from StartTime until EndTime
+type Cue struct {
+	Channel   string // CC1-CC4 for CEA-608 and SERVICE1-SERVICE63 for CEA-708
+	StartTime uint64
+	EndTime   uint64
+	Rows      []CueRow
+	Window    *WindowPosition // Only set for CEA-708
+}
+
+// CueRow - one row of text in a cue.
+// For CEA-608, Row is the screen row 1-15 and Column 0-31.
+// For CEA-708, Row is the 1-based row and Column the 0-based column in the window.
+type CueRow struct {
+	Row    int
+	Column int
+	Text   string
+}
+
+// WindowPosition - CEA-708 window id and anchor
+type WindowPosition struct {
+	ID                  int
+	RelativePositioning bool // Anchor in percent instead of absolute grid position
+	AnchorVertical      int
+	AnchorHorizontal    int
+	AnchorPoint         int // 0-8 for top-left to bottom-right
+}
+
+// Text - text of all rows separated by newlines
+func (c Cue) Text() string {
+	lines := make([]string, len(c.Rows))
+	for i, r := range c.Rows {
+		lines[i] = r.Text
+	}
+	return strings.Join(lines, "\n")
+}
+
+// String - channel, time interval and text on one line
+func (c Cue) String() string {
+	return fmt.Sprintf("%s %d-%d %q", c.Channel, c.StartTime, c.EndTime, c.Text())
+}
+
+// SortCues - sort cues by start time and channel
+func SortCues(cues []Cue) {
+	sort.SliceStable(cues, func(i, j int) bool {
+		if cues[i].StartTime != cues[j].StartTime {
+			return cues[i].StartTime < cues[j].StartTime
+		}
+		return cues[i].Channel < cues[j].Channel
+	})
+}
+
+// WriteWebVTT - write cues as WebVTT with CEA-608 rows and columns mapped to line and position
+func WriteWebVTT(w io.Writer, cues []Cue, timescale uint32) error {
+	_, err := fmt.Fprintf(w, "WEBVTT\n")
+	if err != nil {
+		return err
+	}
+	for _, c := range cues {
+		settings := ""
+		if c.Window == nil && len(c.Rows) > 0 {
+			// Map to 80% title-safe area as recommended for CEA-608 to WebVTT conversion
+			line := 10 + (c.Rows[0].Row-1)*80/cea608Rows
+			position := 10 + c.Rows[0].Column*80/cea608Cols
+			settings = fmt.Sprintf(" line:%d%% position:%d%% align:start", line, position)
+		}
+		_, err = fmt.Fprintf(w, "\n%s --> %s%s\n%s\n", timeString(c.StartTime, timescale, "."),
+			timeString(c.EndTime, timescale, "."), settings, c.Text())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteSRT - write cues as SubRip text
+func WriteSRT(w io.Writer, cues []Cue, timescale uint32) error {
+	for i, c := range cues {
+		if i > 0 {
+			if _, err := fmt.Fprintf(w, "\n"); err != nil {
+				return err
+			}
+		}
+		_, err := fmt.Fprintf(w, "%d\n%s --> %s\n%s\n", i+1, timeString(c.StartTime, timescale, ","),
+			timeString(c.EndTime, timescale, ","), c.Text())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// timeString - hh:mm:ss.mmm with specified separator before milliseconds
+func timeString(t uint64, timescale uint32, msSep string) string {
+	ms := t * 1000 / uint64(timescale)
+	hours := ms / 3600000
+	minutes := (ms / 60000) % 60
+	seconds := (ms / 1000) % 60
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", hours, minutes, seconds, msSep, ms%1000)
+}
@@ -0,0 +1,155 @@
+package captions
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/jaypadia-frame/mp4ff/mp4"
+	"github.com/jaypadia-frame/mp4ff/sei"
+)
+
+// Decoder - CEA-608 and CEA-708 caption decoder producing timed cues
+type Decoder struct {
+	LengthSize int // NAL unit length size of video samples. NewDecoder sets 4
+	timescale  uint32
+	fields     [2]*cea608Field
+	dtvcc      *dtvccDecoder
+}
+
+// NewDecoder - create caption decoder for times in timescale
+func NewDecoder(timescale uint32) *Decoder {
+	return &Decoder{
+		LengthSize: 4,
+		timescale:  timescale,
+		fields:     [2]*cea608Field{newCEA608Field(1), newCEA608Field(2)},
+		dtvcc:      newDTVCCDecoder(),
+	}
+}
+
+// AddCCData - add cc_data triplets with presentation time t
+func (d *Decoder) AddCCData(t uint64, ccData []sei.CCData) {
+	for _, cc := range ccData {
+		if !cc.Valid {
+			continue
+		}
+		switch cc.Type {
+		case 0, 1:
+			d.fields[cc.Type].addPair(t, cc.Data1, cc.Data2)
+		default:
+			d.dtvcc.addCCData(t, cc.Type, cc.Data1, cc.Data2)
+		}
+	}
+}
+
+// AddCEA608Pairs - add CEA-608 byte pairs for field 1 or 2 starting at time t.
+// Consecutive pairs are spaced by one 29.97 Hz frame as in a line 21 signal.
+func (d *Decoder) AddCEA608Pairs(t uint64, fieldNr int, pairs []byte) error {
+	if fieldNr != 1 && fieldNr != 2 {
+		return fmt.Errorf("bad CEA-608 field number %d", fieldNr)
+	}
+	if len(pairs)%2 != 0 {
+		return fmt.Errorf("odd number of CEA-608 bytes: %d", len(pairs))
+	}
+	for i := 0; i < len(pairs); i += 2 {
+		pairTime := t + uint64(i/2)*uint64(d.timescale)*1001/30000
+		d.fields[fieldNr-1].addPair(pairTime, pairs[i], pairs[i+1])
+	}
+	return nil
+}
+
+// AddVideoSample - add captions from registered SEI NAL units in an AVC or HEVC sample with
+// length fields of LengthSize bytes
+func (d *Decoder) AddVideoSample(pts uint64, sample []byte, codec string) error {
+	ccData, err := videoSampleCCData(sample, codec, d.LengthSize)
+	if err != nil {
+		return err
+	}
+	d.AddCCData(pts, ccData)
+	return nil
+}
+
+// AddClcpSample - add CEA-608 byte pairs from cdat (field 1) and cdt2 (field 2) boxes in a clcp sample
+func (d *Decoder) AddClcpSample(pts uint64, sample []byte) error {
+	field1, field2, err := parseClcpSample(sample)
+	if err != nil {
+		return err
+	}
+	err = d.AddCEA608Pairs(pts, 1, field1)
+	if err != nil {
+		return err
+	}
+	return d.AddCEA608Pairs(pts, 2, field2)
+}
+
+// Flush - end all cues that are still displayed at time t
+func (d *Decoder) Flush(t uint64) {
+	for _, f := range d.fields {
+		f.flush(t)
+	}
+	for _, s := range d.dtvcc.services {
+		s.flush(t)
+	}
+}
+
+// Cues - all finished cues sorted by start time and channel
+func (d *Decoder) Cues() []Cue {
+	var cues []Cue
+	for _, f := range d.fields {
+		for _, ch := range f.channels {
+			cues = append(cues, ch.cues...)
+		}
+	}
+	for _, s := range d.dtvcc.services {
+		cues = append(cues, s.cues...)
+	}
+	SortCues(cues)
+	return cues
+}
+
+// DecodeVideoSamples - decode captions in AVC or HEVC samples with 4-byte NAL unit lengths from one track.
+// The samples are sorted in presentation order and open cues end at the end of the last sample.
+func DecodeVideoSamples(samples []mp4.FullSample, timescale uint32, codec string) ([]Cue, error) {
+	return DecodeVideoSamplesWithLengthSize(samples, timescale, codec, 4)
+}
+
+// DecodeVideoSamplesWithLengthSize - DecodeVideoSamples for samples with NAL unit length fields of lengthSize bytes
+func DecodeVideoSamplesWithLengthSize(samples []mp4.FullSample, timescale uint32, codec string,
+	lengthSize int) ([]Cue, error) {
+	d := NewDecoder(timescale)
+	d.LengthSize = lengthSize
+	sorted := make([]mp4.FullSample, len(samples))
+	copy(sorted, samples)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].PresentationTime() < sorted[j].PresentationTime()
+	})
+	var endTime uint64
+	for _, s := range sorted {
+		err := d.AddVideoSample(s.PresentationTime(), s.Data, codec)
+		if err != nil {
+			return nil, err
+		}
+		if end := s.PresentationTime() + uint64(s.Dur); end > endTime {
+			endTime = end
+		}
+	}
+	d.Flush(endTime)
+	return d.Cues(), nil
+}
+
+// DecodeClcpSamples - decode captions in samples of a clcp track.
+// Open cues end at the end of the last sample.
+func DecodeClcpSamples(samples []mp4.FullSample, timescale uint32) ([]Cue, error) {
+	d := NewDecoder(timescale)
+	var endTime uint64
+	for _, s := range samples {
+		err := d.AddClcpSample(s.PresentationTime(), s.Data)
+		if err != nil {
+			return nil, err
+		}
+		if end := s.PresentationTime() + uint64(s.Dur); end > endTime {
+			endTime = end
+		}
+	}
+	d.Flush(endTime)
+	return d.Cues(), nil
+}
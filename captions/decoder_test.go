@@ -0,0 +1,95 @@
+package captions
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/jaypadia-frame/mp4ff/avc"
+)
+
+const avcSEICaptionHex = "660434b500314741393403cefffc9420fc94aefc9162fce56efc67bafc91b9fcb0b0fcbab0fcb0bafcb031fcbab0fcb080fc942cfc942f80"
+
+func TestAddVideoSample(t *testing.T) {
+	nalu, _ := hex.DecodeString(avcSEICaptionHex)
+	sample := make([]byte, 4, 4+len(nalu))
+	binary.BigEndian.PutUint32(sample, uint32(len(nalu)))
+	sample = append(sample, nalu...)
+
+	d := NewDecoder(90000)
+	err := d.AddVideoSample(1000, sample, "avc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Flush(5000)
+	wanted := []Cue{
+		{Channel: "CC1", StartTime: 1000, EndTime: 5000, Rows: []CueRow{{Row: 2, Column: 0, Text: "eng: 00:00:01:00"}}},
+	}
+	if diff := deep.Equal(d.Cues(), wanted); diff != nil {
+		t.Error(diff)
+	}
+
+	sample2, err := avc.ConvertNaluLengthSize(sample, 4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d = NewDecoder(90000)
+	d.LengthSize = 2
+	if err := d.AddVideoSample(1000, sample2, "avc"); err != nil {
+		t.Fatal(err)
+	}
+	d.Flush(5000)
+	if diff := deep.Equal(d.Cues(), wanted); diff != nil {
+		t.Errorf("2-byte lengths: %v", diff)
+	}
+}
+
+func TestAddClcpSample(t *testing.T) {
+	// cdat box with RCL, PAC row 15, "Hi", EOC followed by a cdat box with EDM
+	cdat1, _ := hex.DecodeString("000000106364617494209470c8e9942f")
+	cdat2, _ := hex.DecodeString("0000000a63646174942c")
+	d := NewDecoder(30000)
+	if err := d.AddClcpSample(0, cdat1); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.AddClcpSample(30000, cdat2); err != nil {
+		t.Fatal(err)
+	}
+	wanted := []Cue{
+		{Channel: "CC1", StartTime: 3003, EndTime: 30000, Rows: []CueRow{{Row: 15, Column: 0, Text: "Hi"}}},
+	}
+	if diff := deep.Equal(d.Cues(), wanted); diff != nil {
+		t.Error(diff)
+	}
+	if err := d.AddClcpSample(0, cdat1[:10]); err == nil {
+		t.Error("no error for truncated cdat box")
+	}
+}
+
+func TestWriteSidecar(t *testing.T) {
+	cues := []Cue{
+		{Channel: "CC1", StartTime: 3003, EndTime: 30000, Rows: []CueRow{{Row: 15, Column: 4, Text: "Hi"}}},
+		{Channel: "CC1", StartTime: 3630000, EndTime: 3660000,
+			Rows: []CueRow{{Row: 14, Column: 0, Text: "Two"}, {Row: 15, Column: 0, Text: "rows"}}},
+	}
+	var buf bytes.Buffer
+	if err := WriteWebVTT(&buf, cues, 30000); err != nil {
+		t.Fatal(err)
+	}
+	wantedVTT := "WEBVTT\n\n" +
+		"00:00:00.100 --> 00:00:01.000 line:84% position:20% align:start\nHi\n\n" +
+		"00:02:01.000 --> 00:02:02.000 line:79% position:10% align:start\nTwo\nrows\n"
+	if buf.String() != wantedVTT {
+		t.Errorf("got WebVTT %q instead of %q", buf.String(), wantedVTT)
+	}
+	buf.Reset()
+	if err := WriteSRT(&buf, cues, 30000); err != nil {
+		t.Fatal(err)
+	}
+	wantedSRT := "1\n00:00:00,100 --> 00:00:01,000\nHi\n\n2\n00:02:01,000 --> 00:02:02,000\nTwo\nrows\n"
+	if buf.String() != wantedSRT {
+		t.Errorf("got SRT %q instead of %q", buf.String(), wantedSRT)
+	}
+}
@@ -0,0 +1,21 @@
+/*
+Package captions - decode CEA-608 and CEA-708 closed captions into timed cues, and insert captions in video.
+
+The captions can be read from ATSC A/53 cc_data in registered SEI messages of AVC and HEVC samples,
+or from the CEA-608 byte pairs in cdat and cdt2 boxes of QuickTime clcp track samples.
+
+CEA-608 is decoded in all four channels CC1-CC4 with pop-on, roll-up and paint-on modes.
+CEA-708 DTVCC packets are decoded into caption services with windows.
+The resulting cues can be written as WebVTT or SRT sidecar files.
+
+In the other direction, cues for CC1-CC4 can be encoded as pop-on CEA-608 cc_data with EncodeCEA608Cues.
+InsertCCData and InsertCCDataInFragment put timed cc_data into AVC or HEVC samples as
+registered SEI NAL units, and update sample sizes.
+VideoCCData and ClcpCCData extract the cc_data of video and clcp samples, and CreateClcpSamples
+creates c608 clcp samples, so captions can be moved between in-band SEI and clcp tracks.
+ParseSCC and WriteSCC read and write Scenarist SCC files with drop-frame or non-drop-frame timecodes.
+
+Times are given in the timescale of the track the data comes from.
+Samples must be added in presentation order since that is the order of the caption data.
+*/
+package captions
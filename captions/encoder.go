@@ -0,0 +1,246 @@
+package captions
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/jaypadia-frame/mp4ff/sei"
+)
+
+// TimedCCData - cc_data triplets to be presented at Time
+type TimedCCData struct {
+	Time   uint64
+	CCData []sei.CCData
+}
+
+// cea608Code - a character or control code as a CEA-608 byte pair for channel 1
+type cea608Code [2]byte
+
+// Reverse tables from rune to the codes that represent it
+var (
+	cea608BasicCodes    map[rune]byte
+	cea608SpecialCodes  map[rune]cea608Code
+	cea608ExtendedCodes map[rune]cea608Code
+)
+
+func init() {
+	cea608BasicCodes = make(map[rune]byte)
+	for c := byte(0x20); c <= 0x7f; c++ {
+		cea608BasicCodes[CEA608Char(c)] = c
+	}
+	cea608SpecialCodes = make(map[rune]cea608Code)
+	for i, r := range cea608SpecialChars {
+		if r != emptyCell {
+			cea608SpecialCodes[r] = cea608Code{0x11, 0x30 + byte(i)}
+		}
+	}
+	cea608ExtendedCodes = make(map[rune]cea608Code)
+	for i := len(cea608ExtendedChars2) - 1; i >= 0; i-- {
+		cea608ExtendedCodes[cea608ExtendedChars2[i]] = cea608Code{0x13, 0x20 + byte(i)}
+	}
+	for i := len(cea608ExtendedChars1) - 1; i >= 0; i-- {
+		cea608ExtendedCodes[cea608ExtendedChars1[i]] = cea608Code{0x12, 0x20 + byte(i)}
+	}
+}
+
+// cea608Parity - set bit 7 to get odd parity
+func cea608Parity(b byte) byte {
+	b &= 0x7f
+	ones := 0
+	for v := b; v != 0; v >>= 1 {
+		ones += int(v & 1)
+	}
+	if ones%2 == 0 {
+		b |= 0x80
+	}
+	return b
+}
+
+// cea608ChannelIndex - field index (0 or 1) and channel index within field for CC1-CC4
+func cea608ChannelIndex(channel string) (fieldIdx, chIdx int, err error) {
+	var nr int
+	if _, err := fmt.Sscanf(channel, "CC%d", &nr); err != nil || nr < 1 || nr > 4 {
+		return 0, 0, fmt.Errorf("channel %q is not CC1-CC4", channel)
+	}
+	return (nr - 1) / 2, (nr - 1) % 2, nil
+}
+
+// cea608PopOnEncoder - encodes the byte pairs of pop-on captions for one channel
+type cea608PopOnEncoder struct {
+	fieldIdx int
+	chIdx    int
+	pairs    []cea608Code
+	pending  byte // Basic character waiting for a second character in the pair
+}
+
+// control - add a control code twice, as is common practice for robustness
+func (e *cea608PopOnEncoder) control(c cea608Code) {
+	e.flushChar()
+	c[0] |= byte(e.chIdx) << 3
+	e.pairs = append(e.pairs, c, c)
+}
+
+// miscControl - add miscellaneous control code (second byte 0x20-0x2f)
+func (e *cea608PopOnEncoder) miscControl(code byte) {
+	e.control(cea608Code{0x14 + byte(e.fieldIdx), code})
+}
+
+// basicChar - add basic character, two characters per byte pair
+func (e *cea608PopOnEncoder) basicChar(c byte) {
+	if e.pending == 0 {
+		e.pending = c
+		return
+	}
+	e.pairs = append(e.pairs, cea608Code{e.pending, c})
+	e.pending = 0
+}
+
+// flushChar - send a pending basic character padded with a null byte
+func (e *cea608PopOnEncoder) flushChar() {
+	if e.pending != 0 {
+		e.pairs = append(e.pairs, cea608Code{e.pending, 0})
+		e.pending = 0
+	}
+}
+
+// pac - add Preamble Address Code for row 1-15 and column 0-31, including tab offset
+func (e *cea608PopOnEncoder) pac(row, col int) {
+	for i, rows := range cea608PACRows {
+		for bit, r := range rows {
+			if r != row {
+				continue
+			}
+			indent := byte(8 + col/4)
+			e.control(cea608Code{0x10 | byte(i), 0x40 | byte(bit)<<5 | indent<<1})
+			if col%4 != 0 {
+				e.control(cea608Code{0x17, 0x20 + byte(col%4)})
+			}
+			return
+		}
+	}
+}
+
+// text - add the characters of a row
+func (e *cea608PopOnEncoder) text(text string) error {
+	for _, r := range text {
+		if c, ok := cea608BasicCodes[r]; ok {
+			e.basicChar(c)
+			continue
+		}
+		if c, ok := cea608SpecialCodes[r]; ok {
+			e.control(c)
+			continue
+		}
+		if c, ok := cea608ExtendedCodes[r]; ok {
+			e.basicChar(' ') // Replaced by the extended character in decoders supporting it
+			e.control(c)
+			continue
+		}
+		return fmt.Errorf("character %q cannot be encoded in CEA-608", r)
+	}
+	e.flushChar()
+	return nil
+}
+
+// cueRowPositions - row and column for each cue row.
+// Rows without position (Row == 0) are placed at the bottom of the screen.
+func cueRowPositions(cue Cue) ([][2]int, error) {
+	positions := make([][2]int, len(cue.Rows))
+	for i, r := range cue.Rows {
+		row := r.Row
+		if row == 0 {
+			row = cea608Rows - len(cue.Rows) + 1 + i
+		}
+		if row < 1 || row > cea608Rows {
+			return nil, fmt.Errorf("row %d outside screen", row)
+		}
+		if r.Column < 0 || r.Column+len([]rune(r.Text)) > cea608Cols {
+			return nil, fmt.Errorf("row %d: text %q at column %d does not fit in %d columns",
+				row, r.Text, r.Column, cea608Cols)
+		}
+		positions[i] = [2]int{row, r.Column}
+	}
+	return positions, nil
+}
+
+// cea608Burst - consecutive byte pairs in one field, starting at time
+type cea608Burst struct {
+	time  uint64
+	pairs []cea608Code
+}
+
+// EncodeCEA608Cues - encode cues for channels CC1-CC4 as pop-on CEA-608 captions.
+// Each cue is loaded into non-displayed memory, shown with EOC at StartTime, and erased with EDM at EndTime
+// unless the next cue of the channel starts by then. Byte pairs are spaced by one 29.97 Hz frame in each field,
+// and the loading starts early enough for EOC to be sent at StartTime if the timeline allows.
+// The result has one cc_data triplet (type 0 for field 1, and 1 for field 2) per entry, sorted in time.
+func EncodeCEA608Cues(cues []Cue, timescale uint32) ([]TimedCCData, error) {
+	pairDur := uint64(timescale) * 1001 / 30000
+	sorted := make([]Cue, len(cues))
+	copy(sorted, cues)
+	SortCues(sorted)
+	var bursts [2][]cea608Burst
+	for i, cue := range sorted {
+		fieldIdx, chIdx, err := cea608ChannelIndex(cue.Channel)
+		if err != nil {
+			return nil, err
+		}
+		positions, err := cueRowPositions(cue)
+		if err != nil {
+			return nil, fmt.Errorf("cue %s: %w", cue, err)
+		}
+		e := &cea608PopOnEncoder{fieldIdx: fieldIdx, chIdx: chIdx}
+		e.miscControl(0x20) // RCL
+		e.miscControl(0x2e) // ENM
+		for j, r := range cue.Rows {
+			e.pac(positions[j][0], positions[j][1])
+			if err := e.text(r.Text); err != nil {
+				return nil, fmt.Errorf("cue %s: %w", cue, err)
+			}
+		}
+		e.miscControl(0x2f) // EOC
+		var start uint64
+		if lead := uint64(len(e.pairs)-2) * pairDur; cue.StartTime > lead {
+			start = cue.StartTime - lead
+		}
+		bursts[fieldIdx] = append(bursts[fieldIdx], cea608Burst{start, e.pairs})
+		if !nextCueStartsBy(sorted[i+1:], cue.Channel, cue.EndTime) {
+			e.pairs = nil
+			e.miscControl(0x2c) // EDM
+			bursts[fieldIdx] = append(bursts[fieldIdx], cea608Burst{cue.EndTime, e.pairs})
+		}
+	}
+	var ccData []TimedCCData
+	for fieldIdx, fieldBursts := range bursts {
+		sort.SliceStable(fieldBursts, func(i, j int) bool {
+			return fieldBursts[i].time < fieldBursts[j].time
+		})
+		var nextTime uint64
+		for _, b := range fieldBursts {
+			t := b.time
+			if t < nextTime {
+				t = nextTime
+			}
+			for _, p := range b.pairs {
+				cc := sei.CCData{Valid: true, Type: byte(fieldIdx), Data1: cea608Parity(p[0]), Data2: cea608Parity(p[1])}
+				ccData = append(ccData, TimedCCData{Time: t, CCData: []sei.CCData{cc}})
+				t += pairDur
+			}
+			nextTime = t
+		}
+	}
+	sort.SliceStable(ccData, func(i, j int) bool {
+		return ccData[i].Time < ccData[j].Time
+	})
+	return ccData, nil
+}
+
+// nextCueStartsBy - does the next cue of channel start at or before t
+func nextCueStartsBy(cues []Cue, channel string, t uint64) bool {
+	for _, c := range cues {
+		if c.Channel == channel {
+			return c.StartTime <= t
+		}
+	}
+	return false
+}
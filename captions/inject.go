@@ -0,0 +1,127 @@
+package captions
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+
+	"github.com/jaypadia-frame/mp4ff/avc"
+	"github.com/jaypadia-frame/mp4ff/hevc"
+	"github.com/jaypadia-frame/mp4ff/mp4"
+	"github.com/jaypadia-frame/mp4ff/sei"
+)
+
+// maxCCCountPerSample - the 5-bit cc_count limits the triplets in one cc_data
+const maxCCCountPerSample = 31
+
+// timedCC - one cc_data triplet with its presentation time
+type timedCC struct {
+	time uint64
+	cc   sei.CCData
+}
+
+// InsertCCData - insert cc_data as registered SEI NAL units in AVC or HEVC samples with 4-byte length fields.
+// The samples of one track are given in decode order, and Data and Size of the samples that get cc_data are updated.
+// The triplets are put in the first sample presented at or after their time, with at most 31 triplets per sample.
+// Excess triplets are moved to following samples. Triplets that do not fit in the samples are returned.
+func InsertCCData(samples []mp4.FullSample, codec string, ccData []TimedCCData) ([]TimedCCData, error) {
+	var queue []timedCC
+	for _, tc := range ccData {
+		for _, cc := range tc.CCData {
+			queue = append(queue, timedCC{tc.Time, cc})
+		}
+	}
+	sort.SliceStable(queue, func(i, j int) bool {
+		return queue[i].time < queue[j].time
+	})
+	order := make([]int, len(samples))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return samples[order[i]].PresentationTime() < samples[order[j]].PresentationTime()
+	})
+	for _, idx := range order {
+		s := &samples[idx]
+		var sampleCC []sei.CCData
+		for len(queue) > 0 && queue[0].time <= s.PresentationTime() && len(sampleCC) < maxCCCountPerSample {
+			sampleCC = append(sampleCC, queue[0].cc)
+			queue = queue[1:]
+		}
+		if len(sampleCC) == 0 {
+			continue
+		}
+		msg, err := sei.CreateCEA708SEI(sampleCC)
+		if err != nil {
+			return nil, err
+		}
+		nalu, err := sei.CreateSEINALU(codec, []sei.SEIMessage{msg})
+		if err != nil {
+			return nil, err
+		}
+		data, err := InsertSEINALU(s.Data, nalu, codec)
+		if err != nil {
+			return nil, fmt.Errorf("sample at %d: %w", s.DecodeTime, err)
+		}
+		s.Data = data
+		s.Size = uint32(len(data))
+	}
+	var remaining []TimedCCData
+	for _, q := range queue {
+		remaining = append(remaining, TimedCCData{Time: q.time, CCData: []sei.CCData{q.cc}})
+	}
+	return remaining, nil
+}
+
+// InsertSEINALU - insert SEI NAL unit before the first video NAL unit of a sample with 4-byte length fields.
+// Access unit delimiter, parameter sets, and other SEI NAL units thus stay before the new NAL unit.
+func InsertSEINALU(sample, nalu []byte, codec string) ([]byte, error) {
+	pos := 0
+	for {
+		if pos+4 >= len(sample) {
+			return nil, fmt.Errorf("no video NAL unit in sample")
+		}
+		naluLength := int(binary.BigEndian.Uint32(sample[pos : pos+4]))
+		if pos+4+naluLength > len(sample) {
+			return nil, fmt.Errorf("NAL unit length %d beyond end of sample", naluLength)
+		}
+		var isVideo bool
+		switch codec {
+		case "avc":
+			naluType := avc.GetNaluType(sample[pos+4])
+			isVideo = naluType >= avc.NALU_NON_IDR && naluType <= avc.NALU_IDR
+		case "hevc":
+			isVideo = hevc.GetNaluType(sample[pos+4]) < hevc.NALU_VPS
+		}
+		if isVideo {
+			break
+		}
+		pos += 4 + naluLength
+	}
+	out := make([]byte, 0, len(sample)+4+len(nalu))
+	out = append(out, sample[:pos]...)
+	out = append(out, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(out[pos:], uint32(len(nalu)))
+	out = append(out, nalu...)
+	out = append(out, sample[pos:]...)
+	return out, nil
+}
+
+// InsertCCDataInFragment - insert cc_data in the AVC or HEVC samples of a fragment with one track.
+// The trun sample sizes and the mdat data are updated. Triplets after the last sample are returned.
+func InsertCCDataInFragment(frag *mp4.Fragment, trex *mp4.TrexBox, codec string,
+	ccData []TimedCCData) ([]TimedCCData, error) {
+	samples, err := frag.GetFullSamples(trex)
+	if err != nil {
+		return nil, err
+	}
+	remaining, err := InsertCCData(samples, codec, ccData)
+	if err != nil {
+		return nil, err
+	}
+	data := make([][]byte, len(samples))
+	for i := range samples {
+		data[i] = samples[i].Data
+	}
+	return remaining, frag.SetSampleData(data)
+}
@@ -0,0 +1,208 @@
+package captions
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/jaypadia-frame/mp4ff/avc"
+	"github.com/jaypadia-frame/mp4ff/mp4"
+	"github.com/jaypadia-frame/mp4ff/sei"
+)
+
+// lengthPrefixed - sample with 4-byte length fields from NAL units
+func lengthPrefixed(nalus ...[]byte) []byte {
+	var sample []byte
+	for _, nalu := range nalus {
+		sample = append(sample, 0, 0, 0, 0)
+		binary.BigEndian.PutUint32(sample[len(sample)-4:], uint32(len(nalu)))
+		sample = append(sample, nalu...)
+	}
+	return sample
+}
+
+// encodeDecodeFragment - encode and decode fragment to get it as read from a file
+func encodeDecodeFragment(t *testing.T, frag *mp4.Fragment) *mp4.Fragment {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := frag.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	f, err := mp4.DecodeFile(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f.Segments[0].Fragments[0]
+}
+
+func TestInsertCCDataInFragment(t *testing.T) {
+	const timescale = 90000
+	const frameDur = 3600
+	const nrFrames = 100
+	cues := []Cue{
+		{Channel: "CC1", StartTime: 180000, EndTime: 270000, Rows: []CueRow{
+			{Row: 14, Column: 2, Text: "Hello ♪"},
+			{Row: 15, Column: 5, Text: "Año Über"},
+		}},
+		{Channel: "CC3", StartTime: 216000, EndTime: 288000, Rows: []CueRow{{Text: "Field two"}}},
+	}
+	ccData, err := EncodeCEA608Cues(cues, timescale)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	frag, err := mp4.CreateFragment(1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < nrFrames; i++ {
+		// Presentation order 0, 2, 1, 4, 3, ... as with B-frames
+		frameNr := i
+		if i > 0 {
+			frameNr = i + 1 - 2*((i+1)%2)
+		}
+		aud := []byte{0x09, 0xf0}
+		slice := []byte{0x01, byte(i), 0x80}
+		if i == 0 {
+			slice[0] = 0x65
+		}
+		data := lengthPrefixed(aud, slice)
+		frag.AddFullSample(mp4.FullSample{
+			Sample:     mp4.NewSample(0, frameDur, uint32(len(data)), int32((frameNr-i)*frameDur)),
+			DecodeTime: uint64(i * frameDur),
+			Data:       data,
+		})
+	}
+	frag = encodeDecodeFragment(t, frag)
+	trex := &mp4.TrexBox{TrackID: 1}
+	remaining, err := InsertCCDataInFragment(frag, trex, "avc", ccData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(remaining) != 0 {
+		t.Errorf("%d cc_data entries remaining", len(remaining))
+	}
+	frag = encodeDecodeFragment(t, frag)
+	samples, err := frag.GetFullSamples(trex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != nrFrames {
+		t.Fatalf("got %d samples instead of %d", len(samples), nrFrames)
+	}
+	for _, s := range samples {
+		naluTypes := avc.FindNaluTypes(s.Data)
+		if naluTypes[0] != avc.NALU_AUD || naluTypes[len(naluTypes)-1] > avc.NALU_IDR {
+			t.Errorf("sample at %d: bad NAL unit order %v", s.DecodeTime, naluTypes)
+		}
+	}
+	decoded, err := DecodeVideoSamples(samples, timescale, "avc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wanted := []Cue{
+		{Channel: "CC1", StartTime: 180000, EndTime: 270000, Rows: []CueRow{
+			{Row: 14, Column: 2, Text: "Hello ♪"},
+			{Row: 15, Column: 5, Text: "Año Über"},
+		}},
+		{Channel: "CC3", StartTime: 216000, EndTime: 288000, Rows: []CueRow{{Row: 15, Column: 0, Text: "Field two"}}},
+	}
+	if diff := deep.Equal(decoded, wanted); diff != nil {
+		t.Error(diff)
+	}
+
+	// cc_data after the last sample is returned
+	late := []TimedCCData{{Time: (nrFrames + 1) * frameDur, CCData: []sei.CCData{{Valid: true, Data1: 0x94, Data2: 0x2c}}}}
+	remaining, err = InsertCCData(samples, "avc", late)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := deep.Equal(remaining, late); diff != nil {
+		t.Error(diff)
+	}
+}
+
+func TestEncodeCEA608CuesErrors(t *testing.T) {
+	badCues := []Cue{
+		{Channel: "SERVICE1", Rows: []CueRow{{Row: 15, Text: "708"}}},
+		{Channel: "CC1", Rows: []CueRow{{Row: 16, Text: "Below screen"}}},
+		{Channel: "CC1", Rows: []CueRow{{Row: 15, Column: 30, Text: "Too long"}}},
+		{Channel: "CC1", Rows: []CueRow{{Row: 15, Text: "€"}}},
+	}
+	for _, c := range badCues {
+		if _, err := EncodeCEA608Cues([]Cue{c}, 90000); err == nil {
+			t.Errorf("no error for cue %s", c)
+		}
+	}
+}
+
+func TestInsertSEINALU(t *testing.T) {
+	sample := lengthPrefixed([]byte{0x09, 0xf0}, []byte{0x67, 0x42}, []byte{0x65, 0x88})
+	out, err := InsertSEINALU(sample, []byte{0x06, 0x04, 0x00, 0x80}, "avc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wanted := lengthPrefixed([]byte{0x09, 0xf0}, []byte{0x67, 0x42}, []byte{0x06, 0x04, 0x00, 0x80}, []byte{0x65, 0x88})
+	if !bytes.Equal(out, wanted) {
+		t.Errorf("got %x instead of %x", out, wanted)
+	}
+	_, err = InsertSEINALU(sample[:10], []byte{0x06, 0x04, 0x00, 0x80}, "avc")
+	if err == nil {
+		t.Errorf("no error for sample without video NAL unit")
+	}
+}
+
+func TestVideoToClcpAndBack(t *testing.T) {
+	const timescale = 90000
+	const frameDur = 3003
+	cues := []Cue{
+		{Channel: "CC1", StartTime: 60060, EndTime: 150150, Rows: []CueRow{{Row: 15, Column: 4, Text: "In band"}}},
+		{Channel: "CC3", StartTime: 90090, EndTime: 180180, Rows: []CueRow{{Row: 2, Text: "Second field"}}},
+	}
+	ccData, err := EncodeCEA608Cues(cues, timescale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var video []mp4.FullSample
+	for i := 0; i < 90; i++ {
+		data := lengthPrefixed([]byte{0x01, byte(i), 0x80})
+		video = append(video, mp4.FullSample{
+			Sample:     mp4.NewSample(0, frameDur, uint32(len(data)), 0),
+			DecodeTime: uint64(i * frameDur),
+			Data:       data,
+		})
+	}
+	if _, err := InsertCCData(video, "avc", ccData); err != nil {
+		t.Fatal(err)
+	}
+	videoCC, err := VideoCCData(video, "avc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clcp, err := CreateClcpSamples(videoCC, 0, 90*frameDur)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clcp[0].DecodeTime != 0 || clcp[len(clcp)-1].DecodeTime+uint64(clcp[len(clcp)-1].Dur) != 90*frameDur {
+		t.Errorf("clcp samples do not cover the video")
+	}
+	clcpCues, err := DecodeClcpSamples(clcp, timescale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := deep.Equal(clcpCues, cues); diff != nil {
+		t.Errorf("cues from clcp: %v", diff)
+	}
+	clcpCC, err := ClcpCCData(clcp, timescale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The first clcp sample only has padding since the captions start later
+	if !isCEA608Padding(clcpCC[0].CCData[0].Data1, clcpCC[0].CCData[0].Data2) {
+		t.Errorf("first clcp cc_data is not padding")
+	}
+	if diff := deep.Equal(clcpCC[1:], ccData); diff != nil {
+		t.Errorf("cc_data from clcp: %v", diff)
+	}
+}
@@ -0,0 +1,193 @@
+package captions
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jaypadia-frame/mp4ff/sei"
+)
+
+// sccHeader - first line of a Scenarist SCC file
+const sccHeader = "Scenarist_SCC V1.0"
+
+// Drop-frame timecode counts 30 frames per second but skips frame numbers 0 and 1
+// every minute except every tenth minute to follow the 29.97 Hz frame rate.
+const (
+	framesPerDFMinute    = 30*60 - 2
+	framesPerDF10Minutes = 10*framesPerDFMinute + 2
+)
+
+// Timecode - SMPTE timecode with 30 frames per second for a 29.97 Hz frame rate
+type Timecode struct {
+	Hours     int
+	Minutes   int
+	Seconds   int
+	Frames    int
+	DropFrame bool
+}
+
+// NewTimecode - timecode for 29.97 Hz frame number with drop-frame or non-drop-frame counting
+func NewTimecode(frameNr int, dropFrame bool) Timecode {
+	n := frameNr
+	if dropFrame {
+		tens, rest := frameNr/framesPerDF10Minutes, frameNr%framesPerDF10Minutes
+		n += 18 * tens
+		if rest >= 2 {
+			n += 2 * ((rest - 2) / framesPerDFMinute)
+		}
+	}
+	return Timecode{
+		Hours:     n / (30 * 3600),
+		Minutes:   n / (30 * 60) % 60,
+		Seconds:   n / 30 % 60,
+		Frames:    n % 30,
+		DropFrame: dropFrame,
+	}
+}
+
+// ParseTimecode - parse hh:mm:ss:ff (non-drop-frame) or hh:mm:ss;ff (drop-frame, also with . or , before ff)
+func ParseTimecode(s string) (Timecode, error) {
+	tc := Timecode{}
+	if len(s) != 11 || s[2] != ':' || s[5] != ':' {
+		return tc, fmt.Errorf("bad timecode %q", s)
+	}
+	switch s[8] {
+	case ':':
+	case ';', '.', ',':
+		tc.DropFrame = true
+	default:
+		return tc, fmt.Errorf("bad timecode %q", s)
+	}
+	_, err := fmt.Sscanf(s[:8]+":"+s[9:], "%02d:%02d:%02d:%02d", &tc.Hours, &tc.Minutes, &tc.Seconds, &tc.Frames)
+	if err != nil {
+		return tc, fmt.Errorf("bad timecode %q: %w", s, err)
+	}
+	if tc.Minutes >= 60 || tc.Seconds >= 60 || tc.Frames >= 30 {
+		return tc, fmt.Errorf("bad timecode %q", s)
+	}
+	if tc.DropFrame && tc.Seconds == 0 && tc.Frames < 2 && tc.Minutes%10 != 0 {
+		return tc, fmt.Errorf("dropped frame number in timecode %q", s)
+	}
+	return tc, nil
+}
+
+// FrameNr - 29.97 Hz frame number of timecode
+func (t Timecode) FrameNr() int {
+	n := ((t.Hours*60+t.Minutes)*60+t.Seconds)*30 + t.Frames
+	if t.DropFrame {
+		totalMinutes := 60*t.Hours + t.Minutes
+		n -= 2 * (totalMinutes - totalMinutes/10)
+	}
+	return n
+}
+
+// String - hh:mm:ss:ff or hh:mm:ss;ff for drop-frame timecode
+func (t Timecode) String() string {
+	sep := ':'
+	if t.DropFrame {
+		sep = ';'
+	}
+	return fmt.Sprintf("%02d:%02d:%02d%c%02d", t.Hours, t.Minutes, t.Seconds, sep, t.Frames)
+}
+
+// frameNrToTime - time in timescale for 29.97 Hz frame number
+func frameNrToTime(frameNr int, timescale uint32) uint64 {
+	return uint64(frameNr) * uint64(timescale) * 1001 / 30000
+}
+
+// timeToFrameNr - nearest 29.97 Hz frame number for time in timescale
+func timeToFrameNr(t uint64, timescale uint32) int {
+	frameDur := uint64(timescale) * 1001
+	return int((t*30000 + frameDur/2) / frameDur)
+}
+
+// ParseSCC - parse Scenarist SCC file into field 1 CEA-608 cc_data at times in timescale.
+// The byte pairs of a line are sent one per 29.97 Hz frame starting at the timecode of the line.
+func ParseSCC(r io.Reader, timescale uint32) ([]TimedCCData, error) {
+	scanner := bufio.NewScanner(r)
+	lineNr := 0
+	var ccData []TimedCCData
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		lineNr++
+		if lineNr == 1 {
+			if strings.TrimPrefix(line, "\ufeff") != sccHeader {
+				return nil, fmt.Errorf("scc: bad header %q", line)
+			}
+			continue
+		}
+		if line == "" {
+			continue
+		}
+		fields := strings.Fields(line)
+		tc, err := ParseTimecode(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("scc line %d: %w", lineNr, err)
+		}
+		frameNr := tc.FrameNr()
+		for i, word := range fields[1:] {
+			pair, err := hex.DecodeString(word)
+			if err != nil || len(pair) != 2 {
+				return nil, fmt.Errorf("scc line %d: bad byte pair %q", lineNr, word)
+			}
+			ccData = append(ccData, TimedCCData{
+				Time:   frameNrToTime(frameNr+i, timescale),
+				CCData: []sei.CCData{{Valid: true, Type: 0, Data1: pair[0], Data2: pair[1]}},
+			})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if lineNr == 0 {
+		return nil, fmt.Errorf("scc: empty file")
+	}
+	return ccData, nil
+}
+
+// WriteSCC - write field 1 CEA-608 data in ccData as Scenarist SCC with drop-frame or non-drop-frame timecodes.
+// ccData must be sorted in time. Pairs in consecutive 29.97 Hz frames are written on one line, and a new line
+// starts when there is a gap. Padding pairs do not start lines, and pairs later than the frame rate allows are delayed.
+func WriteSCC(w io.Writer, ccData []TimedCCData, timescale uint32, dropFrame bool) error {
+	_, err := fmt.Fprintf(w, "%s\n", sccHeader)
+	if err != nil {
+		return err
+	}
+	var line []string
+	lineStart := 0
+	flush := func() error {
+		for len(line) > 0 && (line[len(line)-1] == "8080" || line[len(line)-1] == "0000") {
+			line = line[:len(line)-1]
+		}
+		if len(line) == 0 {
+			return nil
+		}
+		_, err := fmt.Fprintf(w, "\n%s\t%s\n", NewTimecode(lineStart, dropFrame), strings.Join(line, " "))
+		line = line[:0]
+		return err
+	}
+	for _, tc := range ccData {
+		frameNr := timeToFrameNr(tc.Time, timescale)
+		for _, cc := range tc.CCData {
+			if !cc.Valid || cc.Type != 0 {
+				continue
+			}
+			if frameNr > lineStart+len(line) {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+			if len(line) == 0 {
+				if isCEA608Padding(cc.Data1, cc.Data2) {
+					continue
+				}
+				lineStart = frameNr
+			}
+			line = append(line, hex.EncodeToString([]byte{cc.Data1, cc.Data2}))
+		}
+	}
+	return flush()
+}
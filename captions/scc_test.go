@@ -0,0 +1,89 @@
+package captions
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestTimecode(t *testing.T) {
+	testCases := []struct {
+		frameNr   int
+		dropFrame bool
+		timecode  string
+	}{
+		{0, true, "00:00:00;00"},
+		{1799, true, "00:00:59;29"},
+		{1800, true, "00:01:00;02"},
+		{17981, true, "00:09:59;29"},
+		{17982, true, "00:10:00;00"},
+		{19782, true, "00:11:00;02"},
+		{107892, true, "01:00:00;00"},
+		{1800, false, "00:01:00:00"},
+		{108000, false, "01:00:00:00"},
+	}
+	for _, tc := range testCases {
+		timecode := NewTimecode(tc.frameNr, tc.dropFrame)
+		if timecode.String() != tc.timecode {
+			t.Errorf("frame %d: got %s instead of %s", tc.frameNr, timecode, tc.timecode)
+		}
+		parsed, err := ParseTimecode(tc.timecode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parsed.FrameNr() != tc.frameNr {
+			t.Errorf("%s: got frame %d instead of %d", tc.timecode, parsed.FrameNr(), tc.frameNr)
+		}
+	}
+	for _, bad := range []string{"00:01:00;00", "00:00:60:00", "00:00:00/00", "0:00:00:00"} {
+		if _, err := ParseTimecode(bad); err == nil {
+			t.Errorf("no error for %q", bad)
+		}
+	}
+}
+
+func TestSCCRoundTrip(t *testing.T) {
+	const timescale = 90000
+	cues := []Cue{
+		{Channel: "CC1", StartTime: 180180, EndTime: 270270, Rows: []CueRow{{Row: 15, Column: 4, Text: "Hello"}}},
+		{Channel: "CC1", StartTime: 5399394, EndTime: 5501496, Rows: []CueRow{{Row: 14, Text: "One minute"}}},
+	}
+	ccData, err := EncodeCEA608Cues(cues, timescale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	err = WriteSCC(&buf, ccData, timescale, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scc := buf.String()
+	if !strings.HasPrefix(scc, "Scenarist_SCC V1.0\n\n00:00:01;21\t9420 9420 94ae 94ae 94f2 94f2 c8e5 ecec ef80 942f 942f\n") {
+		t.Errorf("unexpected SCC start:\n%s", scc)
+	}
+	parsed, err := ParseSCC(strings.NewReader(scc), timescale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := deep.Equal(parsed, ccData); diff != nil {
+		t.Error(diff)
+	}
+	d := NewDecoder(timescale)
+	for _, tc := range parsed {
+		d.AddCCData(tc.Time, tc.CCData)
+	}
+	if diff := deep.Equal(d.Cues(), cues); diff != nil {
+		t.Error(diff)
+	}
+
+	_, err = ParseSCC(strings.NewReader("WEBVTT\n"), timescale)
+	if err == nil {
+		t.Error("no error for bad header")
+	}
+	_, err = ParseSCC(strings.NewReader("Scenarist_SCC V1.0\n\n00:00:00;00\t94\n"), timescale)
+	if err == nil {
+		t.Error("no error for bad byte pair")
+	}
+}
@@ -0,0 +1,140 @@
+// mp4ff-bitrate - print bit rate, segment size, and GOP length statistics for the tracks of an mp4 (ISOBMFF) file.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/jaypadia-frame/mp4ff/bitrate"
+	"github.com/jaypadia-frame/mp4ff/mp4"
+)
+
+var usg = `Usage of mp4ff-bitrate:
+
+mp4ff-bitrate computes the average bit rate, the peak bit rate over sliding windows of 1s and of the
+segment duration, the distribution of segment sizes and bit rates, and a histogram of GOP lengths
+for the tracks of a progressive or fragmented mp4 file.
+
+Fragmented files are split into their media segments, or into fragments if there is only one media segment.
+Progressive files are split at the first sync sample after every multiple of the segment duration (-s).
+
+With -o, the btrt boxes of the sample entries are set from the statistics (bufferSizeDB is the largest sample,
+maxBitrate the peak over 1s, and avgBitrate the average), and the file is written to the output path.
+`
+
+var usage = func() {
+	parts := strings.Split(os.Args[0], "/")
+	name := parts[len(parts)-1]
+	fmt.Fprintln(os.Stderr, usg)
+	fmt.Fprintf(os.Stderr, "%s [options] <mp4File>\n", name)
+	flag.PrintDefaults()
+}
+
+func main() {
+	trackID := flag.Uint("t", 0, "Track ID. All tracks if 0")
+	segDur := flag.Float64("s", bitrate.DefaultSegmentDuration, "Segment duration in seconds for progressive files")
+	outFilePath := flag.String("o", "", "Output file with btrt boxes set")
+	verbose := flag.Bool("v", false, "Print all segments")
+	version := flag.Bool("version", false, "Get mp4ff version")
+
+	flag.Parse()
+
+	if *version {
+		fmt.Printf("mp4ff-bitrate %s\n", mp4.GetVersion())
+		os.Exit(0)
+	}
+
+	var inFilePath = flag.Arg(0)
+	if inFilePath == "" {
+		usage()
+		os.Exit(1)
+	}
+
+	ifd, err := os.Open(inFilePath)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer ifd.Close()
+	parsedMp4, err := mp4.DecodeFile(ifd)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if parsedMp4.Moov == nil {
+		fmt.Printf("Error: no moov box\n")
+		os.Exit(1)
+	}
+	cfg := bitrate.Config{SegmentDuration: *segDur}
+	nrTracks := 0
+	for _, trak := range parsedMp4.Moov.Traks {
+		id := trak.Tkhd.TrackID
+		if *trackID != 0 && id != uint32(*trackID) {
+			continue
+		}
+		st, err := bitrate.TrackStats(parsedMp4, id, cfg)
+		if err != nil {
+			fmt.Printf("Error: track %d: %s\n", id, err)
+			os.Exit(1)
+		}
+		printStats(st, trak.Mdia.Hdlr.HandlerType, *verbose)
+		nrTracks++
+		if *outFilePath != "" {
+			if err := bitrate.SetBtrt(parsedMp4, id, st.Btrt()); err != nil {
+				fmt.Printf("Error: track %d: %s\n", id, err)
+				os.Exit(1)
+			}
+		}
+	}
+	if nrTracks == 0 {
+		fmt.Printf("Error: no track with ID %d\n", *trackID)
+		os.Exit(1)
+	}
+	if *outFilePath != "" {
+		ofd, err := os.Create(*outFilePath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer ofd.Close()
+		if err := parsedMp4.Encode(ofd); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+func printStats(st *bitrate.Stats, handlerType string, verbose bool) {
+	fmt.Printf("Track %d (%s): %d samples, %.3fs, %d bytes\n", st.TrackID, handlerType, st.NrSamples, st.Duration,
+		st.TotalSize)
+	fmt.Printf("  Average bit rate: %d bits/s\n", st.AvgBitrate)
+	for _, p := range st.Peaks {
+		fmt.Printf("  Peak bit rate over %.3fs: %d bits/s at %.3fs\n", p.Window, p.Bitrate, p.Start)
+	}
+	btrt := st.Btrt()
+	fmt.Printf("  btrt: bufferSizeDB=%d maxBitrate=%d avgBitrate=%d\n", btrt.BufferSizeDB, btrt.MaxBitrate,
+		btrt.AvgBitrate)
+	sizes := st.SegmentSizes()
+	rates := st.SegmentBitrates()
+	fmt.Printf("  %d segments\n", len(st.Segments))
+	fmt.Printf("    size (bytes):     min %d, median %d, p90 %d, max %d, mean %.0f\n",
+		sizes.Min, sizes.Median, sizes.P90, sizes.Max, sizes.Mean)
+	fmt.Printf("    bit rate (bits/s): min %d, median %d, p90 %d, max %d, mean %.0f\n",
+		rates.Min, rates.Median, rates.P90, rates.Max, rates.Mean)
+	if verbose {
+		for i, seg := range st.Segments {
+			fmt.Printf("    segment %d: sample %d, %d samples, %.3fs, %d bytes, %d bits/s\n",
+				i+1, seg.StartNr, seg.NrSamples, seg.Duration, seg.Size, seg.Bitrate())
+		}
+	}
+	lengths := make([]int, 0, len(st.GOPLengths))
+	for l := range st.GOPLengths {
+		lengths = append(lengths, l)
+	}
+	sort.Ints(lengths)
+	fmt.Printf("  GOP lengths (samples: count):")
+	for _, l := range lengths {
+		fmt.Printf(" %d: %d", l, st.GOPLengths[l])
+	}
+	fmt.Printf("\n")
+}
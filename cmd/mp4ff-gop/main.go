@@ -0,0 +1,201 @@
+// mp4ff-gop - analyze GOP structure and picture order of the first AVC or HEVC track of an mp4 (ISOBMFF) file.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/jaypadia-frame/mp4ff/gop"
+	"github.com/jaypadia-frame/mp4ff/hevc"
+	"github.com/jaypadia-frame/mp4ff/mp4"
+)
+
+var usg = `Usage of mp4ff-gop:
+
+mp4ff-gop analyzes the GOP structure of the first AVC or HEVC video track in a progressive mp4 file,
+or the first track in a fragmented file.
+
+The picture order count (POC) of every frame is computed from the slice headers, and is used to find
+GOP lengths, open and closed GOPs, B-frame pyramid depth and reorder delay. Frames where the POC order
+differs from the presentation order given by the ctts or trun composition time offsets are listed.
+`
+
+var usage = func() {
+	parts := strings.Split(os.Args[0], "/")
+	name := parts[len(parts)-1]
+	fmt.Fprintln(os.Stderr, usg)
+	fmt.Fprintf(os.Stderr, "%s [-v] [-c codec] <mp4File>\n", name)
+	flag.PrintDefaults()
+}
+
+func main() {
+	codec := flag.String("c", "avc", "Codec if not given by sample description (avc or hevc)")
+	verbose := flag.Bool("v", false, "Print all frames")
+	version := flag.Bool("version", false, "Get mp4ff version")
+
+	flag.Parse()
+
+	if *version {
+		fmt.Printf("mp4ff-gop %s\n", mp4.GetVersion())
+		os.Exit(0)
+	}
+
+	var inFilePath = flag.Arg(0)
+	if inFilePath == "" {
+		usage()
+		os.Exit(1)
+	}
+
+	ifd, err := os.Open(inFilePath)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer ifd.Close()
+	parsedMp4, err := mp4.DecodeFile(ifd)
+	if err != nil {
+		log.Fatal(err)
+	}
+	res, err := analyze(parsedMp4, *codec)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		os.Exit(1)
+	}
+	printResult(res, *verbose)
+}
+
+// analyze - run the GOP analyzer on the samples of the video track
+func analyze(f *mp4.File, codec string) (*gop.Result, error) {
+	var trak *mp4.TrakBox
+	var trex *mp4.TrexBox
+	var moov *mp4.MoovBox
+	if f.Init != nil {
+		moov = f.Init.Moov
+	} else {
+		moov = f.Moov
+	}
+	if moov != nil {
+		var ok bool
+		trak, ok = findFirstVideoTrak(moov)
+		if !ok {
+			return nil, fmt.Errorf("No video track found")
+		}
+		if moov.Mvex != nil {
+			trex, _ = moov.Mvex.GetTrex(trak.Tkhd.TrackID)
+		}
+	}
+	var a *gop.Analyzer
+	var psNalus [][]byte
+	switch {
+	case trak != nil && trak.Mdia.Minf.Stbl.Stsd.AvcX != nil:
+		a = gop.NewAnalyzer("avc")
+		if avcC := trak.Mdia.Minf.Stbl.Stsd.AvcX.AvcC; avcC != nil {
+			a.LengthSize = avcC.LengthSize()
+			psNalus = append(psNalus, avcC.SPSnalus...)
+			psNalus = append(psNalus, avcC.PPSnalus...)
+		}
+	case trak != nil && trak.Mdia.Minf.Stbl.Stsd.HvcX != nil:
+		a = gop.NewAnalyzer("hevc")
+		if hvcC := trak.Mdia.Minf.Stbl.Stsd.HvcX.HvcC; hvcC != nil {
+			a.LengthSize = int(hvcC.LengthSizeMinusOne) + 1
+			psNalus = append(psNalus, hvcC.GetNalusForType(hevc.NALU_SPS)...)
+			psNalus = append(psNalus, hvcC.GetNalusForType(hevc.NALU_PPS)...)
+		}
+	case codec == "avc":
+		a = gop.NewAnalyzer("avc")
+	case codec == "hevc":
+		a = gop.NewAnalyzer("hevc")
+	default:
+		return nil, fmt.Errorf("Unknown codec: %s", codec)
+	}
+	if err := a.AddParameterSets(psNalus); err != nil {
+		return nil, err
+	}
+
+	if !f.IsFragmented() {
+		samples, err := f.GetTrackSamples(trak.Tkhd.TrackID, nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range samples {
+			if err := a.AddSample(s); err != nil {
+				return nil, err
+			}
+		}
+		return a.Result(), nil
+	}
+	for _, iSeg := range f.Segments {
+		for _, iFrag := range iSeg.Fragments {
+			fSamples, err := iFrag.GetFullSamples(trex)
+			if err != nil {
+				return nil, err
+			}
+			for _, s := range fSamples {
+				if err := a.AddSample(s); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return a.Result(), nil
+}
+
+func findFirstVideoTrak(moov *mp4.MoovBox) (*mp4.TrakBox, bool) {
+	for _, inTrak := range moov.Traks {
+		hdlrType := inTrak.Mdia.Hdlr.HandlerType
+		if hdlrType != "vide" {
+			continue
+		}
+		return inTrak, true
+	}
+	return nil, false
+}
+
+func printResult(res *gop.Result, verbose bool) {
+	if verbose {
+		for _, f := range res.Frames {
+			ref := "non-ref"
+			if f.IsRef {
+				ref = "ref"
+			}
+			leading := ""
+			if f.Leading {
+				leading = " leading"
+			}
+			fmt.Printf("Sample %d, dts=%d pts=%d: %s %s poc=%d level=%d%s\n",
+				f.Nr, f.DecodeTime, f.PresentationTime, f.Type, ref, f.POC, f.PyramidLevel, leading)
+		}
+	}
+	minLen, maxLen, totLen := 0, 0, 0
+	for i, g := range res.GOPs {
+		closed := "open"
+		if g.Closed {
+			closed = "closed"
+		}
+		fmt.Printf("GOP %d: sample %d %s %s, %d frames, %d B, %d leading, pyramid depth %d, reorder delay %d\n",
+			i+1, g.StartNr, g.StartType, closed, g.NrFrames, g.NrB, g.NrLeading, g.PyramidDepth, g.ReorderDelay)
+		if i == 0 || g.NrFrames < minLen {
+			minLen = g.NrFrames
+		}
+		if g.NrFrames > maxLen {
+			maxLen = g.NrFrames
+		}
+		totLen += g.NrFrames
+	}
+	if len(res.GOPs) > 0 {
+		fmt.Printf("GOP lengths: min %d, max %d, avg %.1f frames\n", minLen, maxLen, float64(totLen)/float64(len(res.GOPs)))
+	}
+	fmt.Printf("B-frame pyramid depth: %d\n", res.PyramidDepth)
+	fmt.Printf("Reorder delay: %d frames\n", res.ReorderDelay)
+	if len(res.POCMismatches) == 0 {
+		fmt.Printf("POC order matches composition times\n")
+		return
+	}
+	fmt.Printf("POC order differs from composition times for %d frames:\n", len(res.POCMismatches))
+	for _, nr := range res.POCMismatches {
+		f := res.Frames[nr-1]
+		fmt.Printf("  sample %d: %s poc=%d pts=%d\n", nr, f.Type, f.POC, f.PresentationTime)
+	}
+}
@@ -0,0 +1,134 @@
+// mp4ff-hrd - verify AVC and HEVC tracks of an mp4 (ISOBMFF) file against the HRD coded picture buffer model.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/jaypadia-frame/mp4ff/hrd"
+	"github.com/jaypadia-frame/mp4ff/mp4"
+)
+
+var usg = `Usage of mp4ff-hrd:
+
+mp4ff-hrd simulates the coded picture buffer (CPB) of the hypothetical reference decoder (HRD)
+for the AVC and HEVC tracks of a progressive or fragmented mp4 file, using the sample sizes and
+decode times, and the bit rate and CPB size signalled in the SPS. If there are no HRD parameters,
+the limits of the level are used. Buffer underflows and overflows are reported, as well as
+bit rates and CPB sizes above the MaxBR and MaxCPB limits of the level.
+
+The exit code is 2 if any track fails the verification.
+`
+
+var usage = func() {
+	parts := strings.Split(os.Args[0], "/")
+	name := parts[len(parts)-1]
+	fmt.Fprintln(os.Stderr, usg)
+	fmt.Fprintf(os.Stderr, "%s [options] <mp4File>\n", name)
+	flag.PrintDefaults()
+}
+
+func main() {
+	trackID := flag.Uint("t", 0, "Track ID to verify. All AVC and HEVC tracks if 0")
+	bitRate := flag.Uint64("bitrate", 0, "Bit rate in bits/s replacing the signalled one")
+	cpbSize := flag.Uint64("cpbsize", 0, "CPB size in bits replacing the signalled one")
+	initDelay := flag.Uint("initdelay", 0, "Initial CPB removal delay in 90 kHz ticks")
+	useVCL := flag.Bool("vcl", false, "Use VCL instead of NAL HRD parameters and level limits")
+	verbose := flag.Bool("v", false, "Print all violations, and not only the first 10")
+	version := flag.Bool("version", false, "Get mp4ff version")
+
+	flag.Parse()
+
+	if *version {
+		fmt.Printf("mp4ff-hrd %s\n", mp4.GetVersion())
+		os.Exit(0)
+	}
+
+	var inFilePath = flag.Arg(0)
+	if inFilePath == "" {
+		usage()
+		os.Exit(1)
+	}
+
+	ifd, err := os.Open(inFilePath)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer ifd.Close()
+	parsedMp4, err := mp4.DecodeFile(ifd)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if parsedMp4.Moov == nil {
+		fmt.Printf("Error: no moov box\n")
+		os.Exit(1)
+	}
+	cfg := hrd.Config{
+		BitRate:      *bitRate,
+		CpbSize:      *cpbSize,
+		InitialDelay: uint32(*initDelay),
+		UseVCL:       *useVCL,
+	}
+	failed := false
+	nrVerified := 0
+	for _, trak := range parsedMp4.Moov.Traks {
+		stsd := trak.Mdia.Minf.Stbl.Stsd
+		if stsd.AvcX == nil && stsd.HvcX == nil {
+			continue
+		}
+		if *trackID != 0 && trak.Tkhd.TrackID != uint32(*trackID) {
+			continue
+		}
+		rep, err := hrd.VerifyTrack(parsedMp4, trak.Tkhd.TrackID, cfg)
+		if err != nil {
+			fmt.Printf("Error: track %d: %s\n", trak.Tkhd.TrackID, err)
+			os.Exit(1)
+		}
+		nrVerified++
+		if !printReport(rep, *verbose) {
+			failed = true
+		}
+	}
+	if nrVerified == 0 {
+		fmt.Printf("Error: no AVC or HEVC track found\n")
+		os.Exit(1)
+	}
+	if failed {
+		os.Exit(2)
+	}
+}
+
+// printReport - print report and return true if there are no violations
+func printReport(rep *hrd.Report, verbose bool) bool {
+	p := rep.Params
+	rateMode := "VBR"
+	if p.CBR {
+		rateMode = "CBR"
+	}
+	fmt.Printf("Track %d (%s level %s): %d samples, %.3fs, average bit rate %d bits/s\n",
+		rep.TrackID, rep.Codec, rep.Limits.Level, rep.NrSamples, rep.Duration, rep.AvgBitRate)
+	fmt.Printf("  CPB from %s: %s %d bits/s, size %d bits, initial delay %d ticks (%.3fs)\n",
+		p.Source, rateMode, p.BitRate, p.CpbSize, p.InitialDelay, float64(p.InitialDelay)/90000)
+	fmt.Printf("  CPB fullness: min %d bits, max %d bits\n", rep.MinFullness, rep.MaxFullness)
+	for _, msg := range rep.LevelErrors {
+		fmt.Printf("  Level error: %s\n", msg)
+	}
+	for i, v := range rep.Violations {
+		if i == 10 && !verbose {
+			fmt.Printf("  ... %d more violations\n", len(rep.Violations)-i)
+			break
+		}
+		fmt.Printf("  CPB %s at sample %d, time %.3fs: fullness %d bits, sample %d bits\n",
+			v.Kind, v.SampleNr, v.Time, v.Fullness, v.Size)
+	}
+	ok := len(rep.LevelErrors) == 0 && len(rep.Violations) == 0
+	if ok {
+		fmt.Printf("  OK\n")
+	} else {
+		fmt.Printf("  FAILED: %d level errors, %d CPB violations\n", len(rep.LevelErrors), len(rep.Violations))
+	}
+	return ok
+}
@@ -25,13 +25,14 @@ var usage = func() {
 	parts := strings.Split(os.Args[0], "/")
 	name := parts[len(parts)-1]
 	fmt.Fprintln(os.Stderr, usg)
-	fmt.Fprintf(os.Stderr, "%s [-l string] <mp4File> \n", name)
+	fmt.Fprintf(os.Stderr, "%s [-l string] [-codecs] <mp4File> \n", name)
 	flag.PrintDefaults()
 }
 
 func main() {
 
 	specBoxLevels := flag.String("l", "", "level of details, e.g. all:1 or trun:1,subs:1")
+	codecs := flag.Bool("codecs", false, "Print RFC 6381 codec strings of all tracks instead of the box tree")
 	version := flag.Bool("version", false, "Get mp4ff version")
 
 	flag.Parse()
@@ -56,6 +57,14 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
+	if *codecs {
+		codecList, err := parsedMp4.Codecs()
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(strings.Join(codecList, ","))
+		return
+	}
 	err = parsedMp4.Info(os.Stdout, *specBoxLevels, "", "  ")
 	if err != nil {
 		log.Fatal(err)
@@ -14,6 +14,7 @@ import (
 	"github.com/jaypadia-frame/mp4ff/avc"
 	"github.com/jaypadia-frame/mp4ff/hevc"
 	"github.com/jaypadia-frame/mp4ff/mp4"
+	"github.com/jaypadia-frame/mp4ff/sei"
 )
 
 var usg = `Usage of mp4ff-nallister:
@@ -62,15 +63,11 @@ func main() {
 		if err != nil {
 			log.Fatal(err)
 		}
-		sampleData := avc.ConvertByteStreamToNaluSample(data)
-		nalus, err := avc.GetNalusFromSample(sampleData)
-		if err != nil {
-			log.Fatal(err)
-		}
+		nalus := avc.ExtractNalusFromByteStream(data)
 		if *codec == "avc" {
-			err = printAVCNalus(nalus, 0, 0, *seiLevel, *parameterSets, *printRaw)
+			err = printAVCNalus(nalus, 0, 0, *seiLevel, *parameterSets, *printRaw, &avcParamSets{})
 		} else {
-			err = printHEVCNalus(nalus, 0, 0, *seiLevel, *parameterSets, *printRaw)
+			err = printHEVCNalus(nalus, 0, 0, *seiLevel, *parameterSets, *printRaw, newHevcParamSets())
 		}
 		if err != nil {
 			log.Fatal(err)
@@ -117,6 +114,15 @@ func parseProgressiveMp4(f *mp4.File, maxNrSamples int, codec string, seiLevel i
 	} else if stbl.Stsd.HvcX != nil {
 		codec = "hevc"
 	}
+	avcPS := &avcParamSets{}
+	if stbl.Stsd.AvcX != nil && stbl.Stsd.AvcX.AvcC != nil {
+		avcPS.addFromDecConfRec(&stbl.Stsd.AvcX.AvcC.DecConfRec)
+	}
+	hevcPS := newHevcParamSets()
+	if stbl.Stsd.HvcX != nil && stbl.Stsd.HvcX.HvcC != nil {
+		hevcPS.addFromDecConfRec(&stbl.Stsd.HvcX.HvcC.DecConfRec)
+	}
+	lengthSize := naluLengthSize(videoTrak)
 	nrSamples := stbl.Stsz.SampleNumber
 	mdat := f.Mdat
 	mdatPayloadStart := mdat.PayloadAbsoluteOffset()
@@ -138,15 +144,15 @@ func parseProgressiveMp4(f *mp4.File, maxNrSamples int, codec string, seiLevel i
 		// Next find sample bytes as slice in mdat
 		offsetInMdatData := uint64(offset) - mdatPayloadStart
 		sample := mdat.Data[offsetInMdatData : offsetInMdatData+uint64(size)]
-		nalus, err := avc.GetNalusFromSample(sample)
+		nalus, err := avc.GetNalusFromSampleWithLengthSize(sample, lengthSize)
 		if err != nil {
 			return err
 		}
 		switch codec {
 		case "avc", "h.264", "h264":
-			err = printAVCNalus(nalus, sampleNr, decTime+uint64(cto), seiLevel, parameterSets, nrRaw)
+			err = printAVCNalus(nalus, sampleNr, decTime+uint64(cto), seiLevel, parameterSets, nrRaw, avcPS)
 		case "hevc", "h.265", "h265":
-			err = printHEVCNalus(nalus, sampleNr, decTime+uint64(cto), seiLevel, parameterSets, nrRaw)
+			err = printHEVCNalus(nalus, sampleNr, decTime+uint64(cto), seiLevel, parameterSets, nrRaw, hevcPS)
 		default:
 			return fmt.Errorf("Unknown codec: %s", codec)
 		}
@@ -171,6 +177,17 @@ func findFirstVideoTrak(moov *mp4.MoovBox) (*mp4.TrakBox, bool) {
 	return nil, false
 }
 
+// naluLengthSize - NAL unit length size from the first sample description of trak, 4 if not found
+func naluLengthSize(trak *mp4.TrakBox) int {
+	stsd := trak.Mdia.Minf.Stbl.Stsd
+	if len(stsd.Children) > 0 {
+		if lengthSize := mp4.NaluLengthSize(stsd.Children[0]); lengthSize > 0 {
+			return lengthSize
+		}
+	}
+	return 4
+}
+
 func getChunkOffset(stbl *mp4.StblBox, chunkNr int) int64 {
 	if stbl.Stco != nil {
 		return int64(stbl.Stco.ChunkOffset[chunkNr-1])
@@ -183,6 +200,9 @@ func getChunkOffset(stbl *mp4.StblBox, chunkNr int) int64 {
 
 func parseFragmentedMp4(f *mp4.File, maxNrSamples int, codec string, seiLevel int, parameterSets bool, nrRaw int) error {
 	var trex *mp4.TrexBox
+	lengthSize := 4
+	avcPS := &avcParamSets{}
+	hevcPS := newHevcParamSets()
 	if f.Init != nil { // Auto-detect codec if moov box is there
 		moov := f.Init.Moov
 		videoTrak, ok := findFirstVideoTrak(moov)
@@ -192,10 +212,17 @@ func parseFragmentedMp4(f *mp4.File, maxNrSamples int, codec string, seiLevel in
 		stbl := videoTrak.Mdia.Minf.Stbl
 		if stbl.Stsd.AvcX != nil {
 			codec = "avc"
+			if stbl.Stsd.AvcX.AvcC != nil {
+				avcPS.addFromDecConfRec(&stbl.Stsd.AvcX.AvcC.DecConfRec)
+			}
 		} else if stbl.Stsd.HvcX != nil {
 			codec = "hevc"
+			if stbl.Stsd.HvcX.HvcC != nil {
+				hevcPS.addFromDecConfRec(&stbl.Stsd.HvcX.HvcC.DecConfRec)
+			}
 		}
 		trex, _ = moov.Mvex.GetTrex(videoTrak.Tkhd.TrackID)
+		lengthSize = naluLengthSize(videoTrak)
 	}
 	iSamples := make([]mp4.FullSample, 0)
 	for _, iSeg := range f.Segments {
@@ -208,15 +235,15 @@ func parseFragmentedMp4(f *mp4.File, maxNrSamples int, codec string, seiLevel in
 		}
 	}
 	for i, s := range iSamples {
-		nalus, err := avc.GetNalusFromSample(s.Data)
+		nalus, err := avc.GetNalusFromSampleWithLengthSize(s.Data, lengthSize)
 		if err != nil {
 			return err
 		}
 		switch codec {
 		case "avc", "h.264", "h264":
-			err = printAVCNalus(nalus, i+1, s.PresentationTime(), seiLevel, parameterSets, nrRaw)
+			err = printAVCNalus(nalus, i+1, s.PresentationTime(), seiLevel, parameterSets, nrRaw, avcPS)
 		case "hevc", "h.265", "h265":
-			err = printHEVCNalus(nalus, i+1, s.PresentationTime(), seiLevel, parameterSets, nrRaw)
+			err = printHEVCNalus(nalus, i+1, s.PresentationTime(), seiLevel, parameterSets, nrRaw, hevcPS)
 		default:
 			return fmt.Errorf("Unknown codec: %s", codec)
 		}
@@ -231,7 +258,30 @@ func parseFragmentedMp4(f *mp4.File, maxNrSamples int, codec string, seiLevel in
 	return nil
 }
 
-func printAVCNalus(nalus [][]byte, nr int, pts uint64, seiLevel int, parameterSets bool, nrRaw int) error {
+// avcParamSets - latest SPS needed to parse AVC buffering_period and pic_timing SEI
+type avcParamSets struct {
+	sps *avc.SPS
+}
+
+// addFromDecConfRec - add SPS from avcC decoder configuration record
+func (p *avcParamSets) addFromDecConfRec(dcr *avc.DecConfRec) {
+	for _, sps := range dcr.SPSnalus {
+		p.add(sps)
+	}
+}
+
+// add - parse and store nalu if it is an SPS. Errors are ignored
+func (p *avcParamSets) add(nalu []byte) {
+	if avc.GetNaluType(nalu[0]) != avc.NALU_SPS {
+		return
+	}
+	sps, err := avc.ParseSPSNALUnit(nalu, true)
+	if err == nil {
+		p.sps = sps
+	}
+}
+
+func printAVCNalus(nalus [][]byte, nr int, pts uint64, seiLevel int, parameterSets bool, nrRaw int, ps *avcParamSets) error {
 	msg := ""
 	var seiNALUs [][]byte
 	totLen := 0
@@ -243,6 +293,8 @@ func printAVCNalus(nalus [][]byte, nr int, pts uint64, seiLevel int, parameterSe
 		naluType := avc.GetNaluType(nalu[0])
 		imgType := ""
 		switch naluType {
+		case avc.NALU_SPS:
+			ps.add(nalu)
 		case avc.NALU_NON_IDR, avc.NALU_IDR:
 			sliceType, err := avc.GetSliceTypeFromNALU(nalu)
 			if err == nil {
@@ -261,7 +313,7 @@ func printAVCNalus(nalus [][]byte, nr int, pts uint64, seiLevel int, parameterSe
 		}
 	}
 	fmt.Printf("Sample %d, pts=%d (%dB):%s\n", nr, pts, totLen, msg)
-	printSEINALus(seiNALUs, "avc", seiLevel)
+	printSEINALus(seiNALUs, "avc", seiLevel, ps.sps)
 	if parameterSets {
 		for _, nalu := range nalus {
 			naluType := avc.GetNaluType(nalu[0])
@@ -276,7 +328,46 @@ func printAVCNalus(nalus [][]byte, nr int, pts uint64, seiLevel int, parameterSe
 	return nil
 }
 
-func printHEVCNalus(nalus [][]byte, nr int, pts uint64, seiLevel int, parameterSets bool, nrRaw int) error {
+// hevcParamSets - SPS and PPS needed to parse HEVC slice headers
+type hevcParamSets struct {
+	spsMap map[uint32]*hevc.SPS
+	ppsMap map[uint32]*hevc.PPS
+}
+
+func newHevcParamSets() *hevcParamSets {
+	return &hevcParamSets{
+		spsMap: make(map[uint32]*hevc.SPS),
+		ppsMap: make(map[uint32]*hevc.PPS),
+	}
+}
+
+// addFromDecConfRec - add SPS and PPS from hvcC decoder configuration record
+func (p *hevcParamSets) addFromDecConfRec(dcr *hevc.DecConfRec) {
+	for _, sps := range dcr.GetNalusForType(hevc.NALU_SPS) {
+		p.add(sps)
+	}
+	for _, pps := range dcr.GetNalusForType(hevc.NALU_PPS) {
+		p.add(pps)
+	}
+}
+
+// add - parse and store nalu if it is an SPS or PPS. Errors are ignored
+func (p *hevcParamSets) add(nalu []byte) {
+	switch hevc.GetNaluType(nalu[0]) {
+	case hevc.NALU_SPS:
+		sps, err := hevc.ParseSPSNALUnit(nalu)
+		if err == nil {
+			p.spsMap[uint32(sps.SpsID)] = sps
+		}
+	case hevc.NALU_PPS:
+		pps, err := hevc.ParsePPSNALUnit(nalu)
+		if err == nil {
+			p.ppsMap[pps.PicParameterSetID] = pps
+		}
+	}
+}
+
+func printHEVCNalus(nalus [][]byte, nr int, pts uint64, seiLevel int, parameterSets bool, nrRaw int, ps *hevcParamSets) error {
 	msg := ""
 	var seiNALUs [][]byte
 	totLen := 0
@@ -286,18 +377,28 @@ func printHEVCNalus(nalus [][]byte, nr int, pts uint64, seiLevel int, parameterS
 			msg += ","
 		}
 		naluType := hevc.GetNaluType(nalu[0])
+		imgType := ""
+		switch {
+		case naluType == hevc.NALU_SPS || naluType == hevc.NALU_PPS:
+			ps.add(nalu)
+		case naluType < hevc.NALU_VPS:
+			sh, err := hevc.ParseSliceHeader(nalu, ps.spsMap, ps.ppsMap)
+			if err == nil {
+				imgType = fmt.Sprintf("[%s poc=%d] ", sh.SliceType, sh.PicOrderCntLsb)
+			}
+		}
 		if nrRaw > 0 {
-			msg += fmt.Sprintf("\n %s (%dB)", naluType, len(nalu))
+			msg += fmt.Sprintf("\n %s %s(%dB)", naluType, imgType, len(nalu))
 			msg += fmt.Sprintf(" raw: %s", bytesToStringN(nalu, nrRaw))
 		} else {
-			msg += fmt.Sprintf(" %s (%dB)", naluType, len(nalu))
+			msg += fmt.Sprintf(" %s %s(%dB)", naluType, imgType, len(nalu))
 		}
 		if seiLevel > 0 && (naluType == hevc.NALU_SEI_PREFIX || naluType == hevc.NALU_SEI_SUFFIX) {
 			seiNALUs = append(seiNALUs, nalu)
 		}
 	}
 	fmt.Printf("Sample %d, pts=%d (%dB):%s\n", nr, pts, totLen, msg)
-	printSEINALus(seiNALUs, "hevc", seiLevel)
+	printSEINALus(seiNALUs, "hevc", seiLevel, nil)
 	if parameterSets {
 		for _, nalu := range nalus {
 			naluType := hevc.GetNaluType(nalu[0])
@@ -315,7 +416,8 @@ func printHEVCNalus(nalus [][]byte, nr int, pts uint64, seiLevel int, parameterS
 }
 
 // printSEINALus - print interpreted information if seiLevel is >= 1. Add hex dump if seiLevel >= 2
-func printSEINALus(seiNALUs [][]byte, codec string, seiLevel int) {
+// avcSPS is needed to interpret AVC buffering_period and pic_timing messages
+func printSEINALus(seiNALUs [][]byte, codec string, seiLevel int, avcSPS *avc.SPS) {
 	if seiLevel < 1 {
 		return
 	}
@@ -334,18 +436,23 @@ func printSEINALus(seiNALUs [][]byte, codec string, seiLevel int) {
 				seiBytes = seiNALU[hdrLen:]
 			}
 			buf := bytes.NewReader(seiBytes)
-			seiDatas, err := avc.ExtractSEIData(buf)
+			seiDatas, err := sei.ExtractSEIData(buf)
 			if err != nil {
 				fmt.Printf("  SEI: Got error %q\n", err)
 				continue
 			}
 			for _, seiData := range seiDatas {
-				sei, err := avc.DecodeSEIMessage(&seiData)
+				var seiMsg sei.SEIMessage
+				if codec == "avc" {
+					seiMsg, err = sei.DecodeAVCSEIMessage(&seiData, avcSPS)
+				} else {
+					seiMsg, err = sei.DecodeSEIMessage(&seiData, codec)
+				}
 				if err != nil {
 					fmt.Printf("  SEI: Got error %q\n", err)
 					continue
 				}
-				fmt.Printf("  %s\n", sei)
+				fmt.Printf("  %s\n", seiMsg)
 			}
 		}
 	}
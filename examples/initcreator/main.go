@@ -30,6 +30,14 @@ func main() {
 	if err != nil {
 		log.Fatalln(err)
 	}
+	err = writeVideoHEVCHDR10InitSegment()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	err = writeVideoDolbyVisionInitSegment()
+	if err != nil {
+		log.Fatalln(err)
+	}
 	err = writeAudioAACInitSegment()
 	if err != nil {
 		log.Fatalln(err)
@@ -101,6 +109,64 @@ func writeVideoHEVCInitSegment() error {
 	return err
 }
 
+func writeVideoHEVCHDR10InitSegment() error {
+	vps, _ := hex.DecodeString(hevcVPSnalu)
+	vpsNALUs := [][]byte{vps}
+	sps, _ := hex.DecodeString(hevcSPSnalu)
+	spsNALUs := [][]byte{sps}
+	pps, _ := hex.DecodeString(hevcPPSnalu)
+	ppsNALUs := [][]byte{pps}
+
+	videoTimescale := uint32(180000)
+	init := mp4.CreateEmptyInit()
+	init.AddEmptyTrack(videoTimescale, "video", "und")
+	trak := init.Moov.Trak
+	err := trak.SetHEVCDescriptor("hvc1", vpsNALUs, spsNALUs, ppsNALUs, true)
+	if err != nil {
+		return err
+	}
+	hvcx := trak.Mdia.Minf.Stbl.Stsd.HvcX
+	// BT.2020 primaries, PQ transfer, BT.2020 non-constant luminance matrix
+	hvcx.AddChild(mp4.CreateNclxColrBox(9, 16, 9, false))
+	hvcx.AddChild(&mp4.MdcvBox{
+		DisplayPrimariesX:            [3]uint16{13250, 7500, 34000},
+		DisplayPrimariesY:            [3]uint16{34500, 3000, 16000},
+		WhitePointX:                  15635,
+		WhitePointY:                  16450,
+		MaxDisplayMasteringLuminance: 10000000,
+		MinDisplayMasteringLuminance: 50,
+	})
+	hvcx.AddChild(&mp4.ClliBox{MaxContentLightLevel: 1000, MaxPicAverageLightLevel: 400})
+	err = writeToFile(init, "video_hevc_hdr10_init.cmfv")
+	return err
+}
+
+func writeVideoDolbyVisionInitSegment() error {
+	vps, _ := hex.DecodeString(hevcVPSnalu)
+	vpsNALUs := [][]byte{vps}
+	sps, _ := hex.DecodeString(hevcSPSnalu)
+	spsNALUs := [][]byte{sps}
+	pps, _ := hex.DecodeString(hevcPPSnalu)
+	ppsNALUs := [][]byte{pps}
+
+	videoTimescale := uint32(180000)
+	init := mp4.CreateEmptyInit()
+	init.AddEmptyTrack(videoTimescale, "video", "und")
+	trak := init.Moov.Trak
+	// Profile 8.1 (HDR10 compatible base layer) with RPU, level 6
+	dvcC := mp4.CreateDvcCBox(8, 6, true, false, true, 1)
+	err := trak.SetDolbyVisionDescriptor("dvh1", vpsNALUs, spsNALUs, ppsNALUs, dvcC)
+	if err != nil {
+		return err
+	}
+	codec := dvcC.CodecString(trak.Mdia.Minf.Stbl.Stsd.DvX.Type())
+	if codec != "dvh1.08.06" {
+		return fmt.Errorf("Did get codec %s instead of dvh1.08.06", codec)
+	}
+	err = writeToFile(init, "video_dolbyvision_init.cmfv")
+	return err
+}
+
 func writeAudioAACInitSegment() error {
 	audioTimeScale := 48000
 	init := mp4.CreateEmptyInit()
@@ -4,12 +4,14 @@ package main
 import (
 	"bytes"
 	"encoding/hex"
+	"flag"
 	"fmt"
 	"io"
 	"log"
 	"os"
 
 	"github.com/jaypadia-frame/mp4ff/avc"
+	"github.com/jaypadia-frame/mp4ff/captions"
 	"github.com/jaypadia-frame/mp4ff/mp4"
 )
 
@@ -30,6 +32,9 @@ type Track struct {
 }
 
 func main() {
+	vttFilePath := flag.String("vtt", "", "Optional: Output WebVTT file with the decoded closed captions")
+	flag.Parse()
+
 	ifd, err := os.Open(filePath)
 	if err != nil {
 		log.Fatalln(err)
@@ -52,6 +57,12 @@ func main() {
 			if err != nil {
 				log.Fatalln(err)
 			}
+			if *vttFilePath != "" {
+				err = writeWebVTTFile(*vttFilePath, track)
+				if err != nil {
+					log.Fatalln(err)
+				}
+			}
 		}
 	}
 }
@@ -142,6 +153,20 @@ func writeScenaristFile(w io.Writer, clcpTrack *Track) error {
 	return nil
 }
 
+// writeWebVTTFile - decode the CEA-608 captions of a clcp track and write them as WebVTT to a file
+func writeWebVTTFile(path string, clcpTrack *Track) error {
+	cues, err := captions.DecodeClcpSamples(clcpTrack.samples, uint32(clcpTrack.timeScale))
+	if err != nil {
+		return err
+	}
+	ofd, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer ofd.Close()
+	return captions.WriteWebVTT(ofd, cues, uint32(clcpTrack.timeScale))
+}
+
 // timeFromMs - return time string hh:mm:ss:fr where fr is frame (~29.97Hz)
 func timeFromMs(tMs uint64) string {
 	frac := tMs % 1000
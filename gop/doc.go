@@ -0,0 +1,9 @@
+/*
+Package gop - analyze the GOP structure of AVC and HEVC video.
+
+Picture order counts (POC) are computed from the slice headers, and the frames are classified as
+IDR, CRA, BLA, I, P, or B, and as reference or non-reference frames. The result gives the GOPs with their
+lengths, B-frame pyramid depth and reorder delay, and the samples where the POC order does not match the
+presentation time order given by the composition time offsets in ctts or trun.
+*/
+package gop
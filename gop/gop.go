@@ -0,0 +1,499 @@
+package gop
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/jaypadia-frame/mp4ff/avc"
+	"github.com/jaypadia-frame/mp4ff/bits"
+	"github.com/jaypadia-frame/mp4ff/hevc"
+	"github.com/jaypadia-frame/mp4ff/mp4"
+)
+
+// maxDPBFrames - look-back window in frames for reordering and references, bounded by the max DPB size of 16
+const maxDPBFrames = 32
+
+// FrameType - type of coded frame
+type FrameType uint8
+
+// Frame types. IDR, CRA and BLA are random access frames, and B and P are set if any slice is B or P.
+const (
+	FrameI FrameType = iota
+	FrameP
+	FrameB
+	FrameIDR
+	FrameCRA
+	FrameBLA
+)
+
+func (f FrameType) String() string {
+	switch f {
+	case FrameI:
+		return "I"
+	case FrameP:
+		return "P"
+	case FrameB:
+		return "B"
+	case FrameIDR:
+		return "IDR"
+	case FrameCRA:
+		return "CRA"
+	case FrameBLA:
+		return "BLA"
+	default:
+		return ""
+	}
+}
+
+// IsIntra - is frame type coded without reference to other frames
+func (f FrameType) IsIntra() bool {
+	return f != FrameP && f != FrameB
+}
+
+// Frame - analysis of one video sample
+type Frame struct {
+	Nr               int // Sample number in decode order starting at 1
+	DecodeTime       uint64
+	PresentationTime uint64
+	Type             FrameType
+	IsRef            bool // Used as reference by other frames
+	POC              int  // Picture order count. Starts over at every IDR
+	Leading          bool // Decoded after, but presented before, the intra frame starting its GOP
+	PyramidLevel     int  // 0 for intra and P frames, 1 for B frames only referring to them, etc
+	decodable        bool // Leading frame not depending on frames before its GOP (RADL)
+	period           int  // Incremented when POC starts over
+}
+
+// GOP - group of frames starting with an intra frame
+type GOP struct {
+	StartNr      int // Sample number of the intra frame
+	StartType    FrameType
+	Closed       bool // No frame depends on frames of earlier GOPs
+	NrFrames     int
+	NrB          int
+	NrLeading    int
+	PyramidDepth int // Max B-frame pyramid level
+	ReorderDelay int // Max nr frames preceding a frame in decode order and following it in presentation order
+}
+
+// Result - analysis of a video track
+type Result struct {
+	Frames        []Frame
+	GOPs          []GOP
+	PyramidDepth  int
+	ReorderDelay  int
+	POCMismatches []int // Sample numbers of frames where POC order differs from presentation time order
+}
+
+// Analyzer - computes POC and classifies AVC or HEVC samples to find GOP structure.
+// Samples must be added in decode order. Parameter sets are taken from the samples,
+// or must be added before with AddParameterSets if they are only in the sample description.
+type Analyzer struct {
+	LengthSize int // NAL unit length size of samples. NewAnalyzer sets 4
+	codec      string
+	frames     []Frame
+	period     int
+	avcSPS     map[uint]*avc.SPS
+	avcPPS     map[uint]*avc.PPS
+	avcPOC     *avc.POCCalculator
+	hevcSPS    map[uint32]*hevc.SPS
+	hevcPPS    map[uint32]*hevc.PPS
+	hevcPOC    *hevc.POCCalculator
+}
+
+// NewAnalyzer - analyzer for "avc" or "hevc" samples with 4-byte NAL unit lengths. Set LengthSize for other sizes
+func NewAnalyzer(codec string) *Analyzer {
+	return &Analyzer{
+		LengthSize: 4,
+		codec:      codec,
+		avcSPS:     make(map[uint]*avc.SPS),
+		avcPPS:     make(map[uint]*avc.PPS),
+		avcPOC:     avc.NewPOCCalculator(),
+		hevcSPS:    make(map[uint32]*hevc.SPS),
+		hevcPPS:    make(map[uint32]*hevc.PPS),
+		hevcPOC:    hevc.NewPOCCalculator(),
+	}
+}
+
+// AddParameterSets - add SPS and PPS NAL units, e.g. from avcC or hvcC. Other NAL units are ignored
+func (a *Analyzer) AddParameterSets(nalus [][]byte) error {
+	for _, nalu := range nalus {
+		var err error
+		switch a.codec {
+		case "avc":
+			err = a.addAVCParameterSet(nalu)
+		case "hevc":
+			err = a.addHEVCParameterSet(nalu)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *Analyzer) addAVCParameterSet(nalu []byte) error {
+	switch avc.GetNaluType(nalu[0]) {
+	case avc.NALU_SPS:
+		sps, err := avc.ParseSPSNALUnit(nalu, false)
+		if err != nil {
+			return fmt.Errorf("parse SPS: %w", err)
+		}
+		a.avcSPS[sps.ParameterID] = sps
+	case avc.NALU_PPS:
+		// The SPS is only needed for PPS scaling lists, so the first one will do
+		var sps *avc.SPS
+		for _, s := range a.avcSPS {
+			sps = s
+			break
+		}
+		pps, err := avc.ParsePPSNALUnit(nalu, sps)
+		if err != nil {
+			return fmt.Errorf("parse PPS: %w", err)
+		}
+		a.avcPPS[pps.PicParameterSetID] = pps
+	}
+	return nil
+}
+
+func (a *Analyzer) addHEVCParameterSet(nalu []byte) error {
+	switch hevc.GetNaluType(nalu[0]) {
+	case hevc.NALU_SPS:
+		sps, err := hevc.ParseSPSNALUnit(nalu)
+		if err != nil {
+			return fmt.Errorf("parse SPS: %w", err)
+		}
+		a.hevcSPS[uint32(sps.SpsID)] = sps
+	case hevc.NALU_PPS:
+		pps, err := hevc.ParsePPSNALUnit(nalu)
+		if err != nil {
+			return fmt.Errorf("parse PPS: %w", err)
+		}
+		a.hevcPPS[pps.PicParameterSetID] = pps
+	}
+	return nil
+}
+
+// AddSample - parse slice headers of sample and compute its POC and frame type
+func (a *Analyzer) AddSample(s mp4.FullSample) error {
+	nalus, err := avc.GetNalusFromSampleWithLengthSize(s.Data, a.LengthSize)
+	if err != nil {
+		return err
+	}
+	f := Frame{
+		Nr:               len(a.frames) + 1,
+		DecodeTime:       s.DecodeTime,
+		PresentationTime: s.PresentationTime(),
+	}
+	switch a.codec {
+	case "avc":
+		err = a.analyzeAVC(nalus, &f)
+	case "hevc":
+		err = a.analyzeHEVC(nalus, &f)
+	default:
+		err = fmt.Errorf("unknown codec %q", a.codec)
+	}
+	if err != nil {
+		return fmt.Errorf("sample %d: %w", f.Nr, err)
+	}
+	a.frames = append(a.frames, f)
+	return nil
+}
+
+// setSliceType - raise frame type to B or P if slice is of that type
+func (f *Frame) setSliceType(isB, isP bool) {
+	switch {
+	case f.Type.IsIntra() && f.Type != FrameI:
+		// Random access frame
+	case isB:
+		f.Type = FrameB
+	case isP && f.Type != FrameB:
+		f.Type = FrameP
+	}
+}
+
+func (a *Analyzer) analyzeAVC(nalus [][]byte, f *Frame) error {
+	nrPictures := 0
+	for _, nalu := range nalus {
+		naluType := avc.GetNaluType(nalu[0])
+		switch naluType {
+		case avc.NALU_SPS, avc.NALU_PPS:
+			if err := a.addAVCParameterSet(nalu); err != nil {
+				return err
+			}
+			continue
+		case avc.NALU_NON_IDR, avc.NALU_IDR:
+		default:
+			continue
+		}
+		ppsID, err := avcPPSID(nalu)
+		if err != nil {
+			return err
+		}
+		pps, ok := a.avcPPS[ppsID]
+		if !ok {
+			return fmt.Errorf("PPS %d not found", ppsID)
+		}
+		sps, ok := a.avcSPS[pps.SeqParameterSetID]
+		if !ok {
+			return fmt.Errorf("SPS %d not found", pps.SeqParameterSetID)
+		}
+		sh, _, err := avc.ParseSliceHeader(nalu, sps, pps)
+		if err != nil {
+			return err
+		}
+		if naluType == avc.NALU_IDR {
+			f.Type = FrameIDR
+		}
+		f.setSliceType(sh.SliceType == avc.SLICE_B, sh.SliceType == avc.SLICE_P || sh.SliceType == avc.SLICE_SP)
+		if avc.GetNalRefIDC(nalu[0]) != 0 {
+			f.IsRef = true
+		}
+		if sh.FirstMbInSlice != 0 {
+			continue
+		}
+		// First slice of a frame or field
+		if naluType == avc.NALU_IDR && nrPictures == 0 {
+			a.period++
+		}
+		poc, err := a.avcPOC.PicOrderCnt(nalu[0], sh, sps)
+		if err != nil {
+			return err
+		}
+		if nrPictures == 0 || poc < f.POC {
+			f.POC = poc
+		}
+		nrPictures++
+	}
+	if nrPictures == 0 {
+		return fmt.Errorf("no slice")
+	}
+	f.period = a.period
+	return nil
+}
+
+// avcPPSID - pic_parameter_set_id of AVC slice header, needed to find the parameter sets to parse it
+func avcPPSID(nalu []byte) (uint, error) {
+	r := bits.NewEBSPReader(bytes.NewReader(nalu[1:]))
+	for i := 0; i < 2; i++ { // first_mb_in_slice and slice_type
+		if _, err := r.ReadExpGolomb(); err != nil {
+			return 0, err
+		}
+	}
+	return r.ReadExpGolomb()
+}
+
+func (a *Analyzer) analyzeHEVC(nalus [][]byte, f *Frame) error {
+	nrPictures := 0
+	for _, nalu := range nalus {
+		naluType := hevc.GetNaluType(nalu[0])
+		switch {
+		case naluType == hevc.NALU_SPS || naluType == hevc.NALU_PPS:
+			if err := a.addHEVCParameterSet(nalu); err != nil {
+				return err
+			}
+			continue
+		case naluType == hevc.NALU_EOS:
+			a.hevcPOC.EndOfSequence()
+			continue
+		case naluType > 23:
+			continue
+		}
+		sh, err := hevc.ParseSliceHeader(nalu, a.hevcSPS, a.hevcPPS)
+		if err != nil {
+			return err
+		}
+		switch {
+		case naluType == hevc.NALU_IDR_W_RADL || naluType == hevc.NALU_IDR_N_LP:
+			f.Type = FrameIDR
+		case naluType == hevc.NALU_CRA:
+			f.Type = FrameCRA
+		case naluType >= hevc.NALU_BLA_W_LP && naluType <= hevc.NALU_BLA_N_LP:
+			f.Type = FrameBLA
+		}
+		if !sh.DependentSliceSegmentFlag {
+			f.setSliceType(sh.SliceType == hevc.SLICE_B, sh.SliceType == hevc.SLICE_P)
+		}
+		if !hevc.IsSubLayerNonReference(naluType) {
+			f.IsRef = true
+		}
+		f.decodable = naluType == hevc.NALU_RADL_N || naluType == hevc.NALU_RADL_R
+		if !sh.FirstSliceSegmentInPicFlag {
+			continue
+		}
+		pps := a.hevcPPS[sh.PicParameterSetID]
+		sps := a.hevcSPS[pps.SeqParameterSetID]
+		poc, startsCVS := a.hevcPOC.PicOrderCnt(nalu[:2], sh, sps)
+		if startsCVS {
+			a.period++
+		}
+		f.POC = poc
+		nrPictures++
+	}
+	if nrPictures == 0 {
+		return fmt.Errorf("no slice")
+	}
+	f.period = a.period
+	return nil
+}
+
+// Frames - frames analyzed so far
+func (a *Analyzer) Frames() []Frame {
+	return a.frames
+}
+
+// OutputRanks - position in output order derived from POC for every frame added, starting at 0.
+// This gives the presentation order when composition times are not yet known, e.g. for raw streams.
+func (a *Analyzer) OutputRanks() []int {
+	ranks := make([]int, len(a.frames))
+	for rank, idx := range pocOrder(a.frames) {
+		ranks[idx] = rank
+	}
+	return ranks
+}
+
+// Result - GOP structure, reordering and POC mismatches of all frames added
+func (a *Analyzer) Result() *Result {
+	frames := make([]Frame, len(a.frames))
+	copy(frames, a.frames)
+	res := &Result{Frames: frames}
+	setPyramidLevels(frames)
+	reorderDelays := reorderDelays(frames)
+
+	var gop *GOP
+	var start *Frame
+	for i := range frames {
+		f := &frames[i]
+		if f.Type.IsIntra() || gop == nil {
+			res.GOPs = append(res.GOPs, GOP{StartNr: f.Nr, StartType: f.Type, Closed: true})
+			gop = &res.GOPs[len(res.GOPs)-1]
+			start = f
+		}
+		gop.NrFrames++
+		if f.Type == FrameB {
+			gop.NrB++
+		}
+		if f != start && f.period == start.period && f.POC < start.POC {
+			f.Leading = true
+			gop.NrLeading++
+			if !f.decodable {
+				gop.Closed = false
+			}
+		}
+		if f.PyramidLevel > gop.PyramidDepth {
+			gop.PyramidDepth = f.PyramidLevel
+		}
+		if reorderDelays[i] > gop.ReorderDelay {
+			gop.ReorderDelay = reorderDelays[i]
+		}
+	}
+	if len(res.GOPs) > 0 && !res.GOPs[0].StartType.IsIntra() {
+		res.GOPs[0].Closed = false
+	}
+	for _, g := range res.GOPs {
+		if g.PyramidDepth > res.PyramidDepth {
+			res.PyramidDepth = g.PyramidDepth
+		}
+		if g.ReorderDelay > res.ReorderDelay {
+			res.ReorderDelay = g.ReorderDelay
+		}
+	}
+	res.POCMismatches = pocMismatches(frames)
+	return res
+}
+
+// setPyramidLevels - set B-frame levels from the levels of the closest reference frames before and after in POC order
+func setPyramidLevels(frames []Frame) {
+	type ref struct {
+		poc, level int
+	}
+	var refs []ref
+	period := -1
+	for i := range frames {
+		f := &frames[i]
+		if f.period != period {
+			refs = refs[:0]
+			period = f.period
+		}
+		if f.Type == FrameB {
+			var before, after *ref
+			for j := range refs {
+				r := &refs[j]
+				if r.poc < f.POC && (before == nil || r.poc > before.poc) {
+					before = r
+				}
+				if r.poc > f.POC && (after == nil || r.poc < after.poc) {
+					after = r
+				}
+			}
+			level := 0
+			if before != nil {
+				level = before.level
+			}
+			if after != nil && after.level > level {
+				level = after.level
+			}
+			f.PyramidLevel = level + 1
+		}
+		if f.IsRef {
+			refs = append(refs, ref{f.POC, f.PyramidLevel})
+			if len(refs) > maxDPBFrames {
+				refs = refs[1:]
+			}
+		}
+	}
+}
+
+// reorderDelays - nr frames preceding each frame in decode order, and following it in presentation order
+func reorderDelays(frames []Frame) []int {
+	delays := make([]int, len(frames))
+	for i, f := range frames {
+		for j := i - 1; j >= 0 && j >= i-maxDPBFrames; j-- {
+			if frames[j].period != f.period {
+				break
+			}
+			if frames[j].POC > f.POC {
+				delays[i]++
+			}
+		}
+	}
+	return delays
+}
+
+// pocMismatches - sample numbers of frames with different rank in POC and presentation time order.
+// All frames before a POC reset at an IDR are output before it, so the POC order is by period and then POC.
+func pocMismatches(frames []Frame) []int {
+	byPOC := pocOrder(frames)
+	byTime := make([]int, len(frames))
+	for i := range byTime {
+		byTime[i] = i
+	}
+	sort.SliceStable(byTime, func(i, j int) bool {
+		return frames[byTime[i]].PresentationTime < frames[byTime[j]].PresentationTime
+	})
+	var mismatches []int
+	for k := range byPOC {
+		if byPOC[k] != byTime[k] {
+			mismatches = append(mismatches, frames[byPOC[k]].Nr)
+		}
+	}
+	sort.Ints(mismatches)
+	return mismatches
+}
+
+// pocOrder - frame indices in output order given by period and POC
+func pocOrder(frames []Frame) []int {
+	byPOC := make([]int, len(frames))
+	for i := range byPOC {
+		byPOC[i] = i
+	}
+	sort.SliceStable(byPOC, func(i, j int) bool {
+		fi, fj := &frames[byPOC[i]], &frames[byPOC[j]]
+		if fi.period != fj.period {
+			return fi.period < fj.period
+		}
+		return fi.POC < fj.POC
+	})
+	return byPOC
+}
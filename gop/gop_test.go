@@ -0,0 +1,114 @@
+package gop
+
+import (
+	"os"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/jaypadia-frame/mp4ff/mp4"
+)
+
+func readFragmentedSamples(t *testing.T, path string) []mp4.FullSample {
+	t.Helper()
+	fh, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fh.Close()
+	f, err := mp4.DecodeFile(fh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var samples []mp4.FullSample
+	for _, seg := range f.Segments {
+		for _, frag := range seg.Fragments {
+			fs, err := frag.GetFullSamples(nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			samples = append(samples, fs...)
+		}
+	}
+	return samples
+}
+
+func TestAnalyzeAVC(t *testing.T) {
+	samples := readFragmentedSamples(t, "../mp4/testdata/1.m4s")
+	a := NewAnalyzer("avc")
+	for _, s := range samples {
+		if err := a.AddSample(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	res := a.Result()
+	wantedGOPs := []GOP{
+		{StartNr: 1, StartType: FrameIDR, Closed: true, NrFrames: 30, NrB: 19, PyramidDepth: 2, ReorderDelay: 2},
+		{StartNr: 31, StartType: FrameIDR, Closed: true, NrFrames: 30, NrB: 21, PyramidDepth: 2, ReorderDelay: 2},
+	}
+	if diff := deep.Equal(res.GOPs, wantedGOPs); diff != nil {
+		t.Error(diff)
+	}
+	if res.PyramidDepth != 2 || res.ReorderDelay != 2 || len(res.POCMismatches) != 0 {
+		t.Errorf("got pyramid depth %d, reorder delay %d, mismatches %v",
+			res.PyramidDepth, res.ReorderDelay, res.POCMismatches)
+	}
+	// Sample 4 is P-frame with POC 12 followed by reference B-frame with POC 8 and non-reference B-frames
+	wantedFrames := []Frame{
+		{Nr: 4, DecodeTime: 9000, PresentationTime: 24000, Type: FrameP, IsRef: true, POC: 12, period: 1},
+		{Nr: 5, DecodeTime: 12000, PresentationTime: 18000, Type: FrameB, IsRef: true, POC: 8, PyramidLevel: 1, period: 1},
+		{Nr: 6, DecodeTime: 15000, PresentationTime: 15000, Type: FrameB, POC: 6, PyramidLevel: 2, period: 1},
+	}
+	if diff := deep.Equal(res.Frames[3:6], wantedFrames); diff != nil {
+		t.Error(diff)
+	}
+
+	// Swap composition times of two B-frames
+	samples[5].CompositionTimeOffset, samples[6].CompositionTimeOffset =
+		samples[6].CompositionTimeOffset+3000, samples[5].CompositionTimeOffset-3000
+	a = NewAnalyzer("avc")
+	for _, s := range samples {
+		if err := a.AddSample(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	res = a.Result()
+	if diff := deep.Equal(res.POCMismatches, []int{6, 7}); diff != nil {
+		t.Errorf("POC mismatches: %v", diff)
+	}
+}
+
+func TestOpenGOP(t *testing.T) {
+	// Decode order with POC: I0 P4 B2 b1 b3 | I8 (open GOP) B6 b5 b7 P12 | IDR0
+	frames := []Frame{
+		{Type: FrameI, IsRef: true, POC: 0},
+		{Type: FrameP, IsRef: true, POC: 4},
+		{Type: FrameB, IsRef: true, POC: 2},
+		{Type: FrameB, POC: 1},
+		{Type: FrameB, POC: 3},
+		{Type: FrameI, IsRef: true, POC: 8},
+		{Type: FrameB, IsRef: true, POC: 6},
+		{Type: FrameB, POC: 5},
+		{Type: FrameB, POC: 7},
+		{Type: FrameP, IsRef: true, POC: 12},
+		{Type: FrameIDR, IsRef: true, POC: 0, period: 1},
+	}
+	a := NewAnalyzer("avc")
+	for i := range frames {
+		frames[i].Nr = i + 1
+		frames[i].PresentationTime = uint64(frames[i].POC)
+	}
+	a.frames = frames
+	res := a.Result()
+	wantedGOPs := []GOP{
+		{StartNr: 1, StartType: FrameI, Closed: true, NrFrames: 5, NrB: 3, PyramidDepth: 2, ReorderDelay: 2},
+		{StartNr: 6, StartType: FrameI, Closed: false, NrFrames: 5, NrB: 3, NrLeading: 3, PyramidDepth: 2, ReorderDelay: 2},
+		{StartNr: 11, StartType: FrameIDR, Closed: true, NrFrames: 1},
+	}
+	if diff := deep.Equal(res.GOPs, wantedGOPs); diff != nil {
+		t.Error(diff)
+	}
+	// The IDR frame is presented before the frames of the previous period
+	if diff := deep.Equal(res.POCMismatches, []int{2, 3, 4, 5, 6, 7, 8, 9, 10, 11}); diff != nil {
+		t.Errorf("POC mismatches: %v", diff)
+	}
+}
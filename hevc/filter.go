@@ -0,0 +1,116 @@
+package hevc
+
+import (
+	"github.com/jaypadia-frame/mp4ff/avc"
+)
+
+// SEI payload types used by the filters
+const (
+	SEIFillerPayloadType = 3
+	SEIUnregisteredType  = 5
+)
+
+// The filters below are combined with avc.ChainNaluFilters and applied to samples with avc.FilterSample.
+
+// RemoveNaluTypes - NaluFilter removing NAL units of any of naluTypes
+func RemoveNaluTypes(naluTypes ...NaluType) avc.NaluFilter {
+	return func(nalus [][]byte) ([][]byte, error) {
+		out := nalus[:0:0]
+		for _, nalu := range nalus {
+			if len(nalu) > 0 && containsNaluType(naluTypes, GetNaluType(nalu[0])) {
+				continue
+			}
+			out = append(out, nalu)
+		}
+		return out, nil
+	}
+}
+
+func containsNaluType(naluTypes []NaluType, naluType NaluType) bool {
+	for _, t := range naluTypes {
+		if t == naluType {
+			return true
+		}
+	}
+	return false
+}
+
+// RemoveAUD - NaluFilter removing access unit delimiters
+func RemoveAUD() avc.NaluFilter {
+	return RemoveNaluTypes(NALU_AUD)
+}
+
+// InsertAUD - NaluFilter inserting an access unit delimiter first in samples without one.
+// pic_type is set to 2 (I, P and B slices), since slice types are not known without parsing
+// the parameter sets. The TemporalId is taken from the first VCL NAL unit.
+func InsertAUD() avc.NaluFilter {
+	return func(nalus [][]byte) ([][]byte, error) {
+		if len(nalus) > 0 && len(nalus[0]) > 0 && GetNaluType(nalus[0][0]) == NALU_AUD {
+			return nalus, nil
+		}
+		temporalIDPlus1 := byte(1)
+		for _, nalu := range nalus {
+			if len(nalu) >= 2 && GetNaluType(nalu[0]) <= highestVideoNaluType {
+				temporalIDPlus1 = nalu[1] & 0x07
+				break
+			}
+		}
+		aud := []byte{byte(NALU_AUD) << 1, temporalIDPlus1, 2<<5 | 0x10}
+		return append([][]byte{aud}, nalus...), nil
+	}
+}
+
+// RemoveFillerData - NaluFilter removing filler data NAL units and filler payload SEI messages
+func RemoveFillerData() avc.NaluFilter {
+	return avc.ChainNaluFilters(RemoveNaluTypes(NALU_FD), RemoveSEITypes(SEIFillerPayloadType))
+}
+
+// RemoveUnregisteredSEI - NaluFilter removing user data unregistered SEI messages
+func RemoveUnregisteredSEI() avc.NaluFilter {
+	return RemoveSEITypes(SEIUnregisteredType)
+}
+
+// RemoveSEITypes - NaluFilter removing SEI messages of any of payloadTypes from prefix and suffix SEI NAL units.
+// SEI NAL units without messages left are removed.
+func RemoveSEITypes(payloadTypes ...uint) avc.NaluFilter {
+	return func(nalus [][]byte) ([][]byte, error) {
+		out := nalus[:0:0]
+		for _, nalu := range nalus {
+			if len(nalu) > 0 {
+				switch GetNaluType(nalu[0]) {
+				case NALU_SEI_PREFIX, NALU_SEI_SUFFIX:
+					var err error
+					nalu, err = avc.RemoveSEIMessages(nalu, 2, payloadTypes)
+					if err != nil {
+						return nil, err
+					}
+					if nalu == nil {
+						continue
+					}
+				}
+			}
+			out = append(out, nalu)
+		}
+		return out, nil
+	}
+}
+
+// RemoveRedundantParameterSets - NaluFilter removing VPS, SPS and PPS NAL units which are identical to
+// one of outOfBand, e.g. the parameter sets of the hvcC box, or to an earlier NAL unit in the same sample.
+func RemoveRedundantParameterSets(outOfBand [][]byte) avc.NaluFilter {
+	return func(nalus [][]byte) ([][]byte, error) {
+		out := nalus[:0:0]
+		for _, nalu := range nalus {
+			if len(nalu) > 0 {
+				switch GetNaluType(nalu[0]) {
+				case NALU_VPS, NALU_SPS, NALU_PPS:
+					if avc.ContainsNalu(outOfBand, nalu) || avc.ContainsNalu(out, nalu) {
+						continue
+					}
+				}
+			}
+			out = append(out, nalu)
+		}
+		return out, nil
+	}
+}
@@ -0,0 +1,63 @@
+package hevc
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/jaypadia-frame/mp4ff/avc"
+)
+
+func TestNaluFilters(t *testing.T) {
+	hexNalus := func(hexStrs ...string) [][]byte {
+		nalus := make([][]byte, len(hexStrs))
+		for i, h := range hexStrs {
+			nalus[i], _ = hex.DecodeString(h)
+		}
+		return nalus
+	}
+	const (
+		vpsHex     = "40010c01ffff"
+		fdHex      = "4c01ffff80"
+		prefixSEI  = "4e01" + "0511" + "00112233445566778899aabbccddeeff" + "01" + "80"
+		suffixSEI  = "5001" + "840100" + "0302ffff" + "80"
+		suffixHash = "5001" + "84010080"
+		sliceHex   = "0203c0"
+	)
+	sample := hexNalus(vpsHex, vpsHex, prefixSEI, sliceHex, suffixSEI, fdHex)
+
+	testCases := []struct {
+		desc   string
+		filter avc.NaluFilter
+		want   [][]byte
+	}{
+		{"insert AUD", InsertAUD(),
+			hexNalus("460350", vpsHex, vpsHex, prefixSEI, sliceHex, suffixSEI, fdHex)},
+		{"remove filler", RemoveFillerData(),
+			hexNalus(vpsHex, vpsHex, prefixSEI, sliceHex, suffixHash)},
+		{"remove unregistered SEI", RemoveUnregisteredSEI(),
+			hexNalus(vpsHex, vpsHex, sliceHex, suffixSEI, fdHex)},
+		{"remove redundant parameter sets", RemoveRedundantParameterSets(nil),
+			hexNalus(vpsHex, prefixSEI, sliceHex, suffixSEI, fdHex)},
+		{"chain", avc.ChainNaluFilters(InsertAUD(), RemoveNaluTypes(NALU_VPS), RemoveSEITypes(3, 5, 132)),
+			hexNalus("460350", sliceHex, fdHex)},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			in := make([][]byte, len(sample))
+			copy(in, sample)
+			got, err := tc.filter(in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("got %d NAL units instead of %d", len(got), len(tc.want))
+			}
+			for i := range got {
+				if !bytes.Equal(got[i], tc.want[i]) {
+					t.Errorf("NAL unit %d: got %x instead of %x", i, got[i], tc.want[i])
+				}
+			}
+		})
+	}
+}
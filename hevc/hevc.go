@@ -1,8 +1,9 @@
 package hevc
 
 import (
-	"encoding/binary"
 	"fmt"
+
+	"github.com/jaypadia-frame/mp4ff/avc"
 )
 
 // NaluType - HEVC nal type according to ISO/IEC 23008-2 Table 7.1
@@ -89,37 +90,39 @@ func GetNaluType(naluHeaderStart byte) NaluType {
 
 // FindNaluTypes - find list of nalu types in sample
 func FindNaluTypes(sample []byte) []NaluType {
-	naluList := make([]NaluType, 0)
-	length := len(sample)
-	if length < 4 {
-		return naluList
-	}
-	var pos uint32 = 0
-	for pos < uint32(length-4) {
-		naluLength := binary.BigEndian.Uint32(sample[pos : pos+4])
-		pos += 4
-		naluType := GetNaluType(sample[pos])
-		naluList = append(naluList, naluType)
-		pos += naluLength
-	}
-	return naluList
+	return FindNaluTypesWithLengthSize(sample, 4)
+}
+
+// FindNaluTypesWithLengthSize - find list of nalu types in sample with lengthSize (1, 2, or 4) bytes length fields
+func FindNaluTypesWithLengthSize(sample []byte, lengthSize int) []NaluType {
+	return findNaluTypes(sample, lengthSize, false)
 }
 
 // FindNaluTypesUpToFirstVideoNalu - all nalu types up to first video nalu
 func FindNaluTypesUpToFirstVideoNalu(sample []byte) []NaluType {
+	return findNaluTypes(sample, 4, true)
+}
+
+// FindNaluTypesUpToFirstVideoNaluWithLengthSize - all nalu types up to first video nalu
+// in sample with lengthSize (1, 2, or 4) bytes length fields
+func FindNaluTypesUpToFirstVideoNaluWithLengthSize(sample []byte, lengthSize int) []NaluType {
+	return findNaluTypes(sample, lengthSize, true)
+}
+
+func findNaluTypes(sample []byte, lengthSize int, upToFirstVideo bool) []NaluType {
 	naluList := make([]NaluType, 0)
 	length := len(sample)
-	if length < 4 {
+	if length < lengthSize || !avc.IsValidLengthSize(lengthSize) {
 		return naluList
 	}
-	var pos uint32 = 0
-	for pos < uint32(length-4) {
-		naluLength := binary.BigEndian.Uint32(sample[pos : pos+4])
-		pos += 4
+	var pos uint64 = 0
+	for pos < uint64(length-lengthSize) {
+		naluLength := avc.ReadNaluLength(sample[pos:], lengthSize)
+		pos += uint64(lengthSize)
 		naluType := GetNaluType(sample[pos])
 		naluList = append(naluList, naluType)
-		pos += naluLength
-		if naluType <= highestVideoNaluType {
+		pos += uint64(naluLength)
+		if upToFirstVideo && naluType <= highestVideoNaluType {
 			break // Video has started
 		}
 	}
@@ -128,23 +131,27 @@ func FindNaluTypesUpToFirstVideoNalu(sample []byte) []NaluType {
 
 // ContainsNaluType - is specific NaluType present in sample
 func ContainsNaluType(sample []byte, specificNaluType NaluType) bool {
-	var pos uint32 = 0
-	length := len(sample)
-	for pos < uint32(length-4) {
-		naluLength := binary.BigEndian.Uint32(sample[pos : pos+4])
-		pos += 4
-		naluType := GetNaluType(sample[pos])
+	return ContainsNaluTypeWithLengthSize(sample, 4, specificNaluType)
+}
+
+// ContainsNaluTypeWithLengthSize - is specific NaluType present in sample with lengthSize (1, 2, or 4) bytes length fields
+func ContainsNaluTypeWithLengthSize(sample []byte, lengthSize int, specificNaluType NaluType) bool {
+	for _, naluType := range FindNaluTypesWithLengthSize(sample, lengthSize) {
 		if naluType == specificNaluType {
 			return true
 		}
-		pos += naluLength
 	}
 	return false
 }
 
 // IsRAPSample - is Random Access picture (NALU 16-23)
 func IsRAPSample(sample []byte) bool {
-	for _, naluType := range FindNaluTypes(sample) {
+	return IsRAPSampleWithLengthSize(sample, 4)
+}
+
+// IsRAPSampleWithLengthSize - is Random Access picture (NALU 16-23) in sample with lengthSize (1, 2, or 4) bytes length fields
+func IsRAPSampleWithLengthSize(sample []byte, lengthSize int) bool {
+	for _, naluType := range FindNaluTypesWithLengthSize(sample, lengthSize) {
 		if 16 <= naluType && naluType <= 23 {
 			return true
 		}
@@ -154,7 +161,12 @@ func IsRAPSample(sample []byte) bool {
 
 // IsIDRSample - is IDR picture (NALU 19-20)
 func IsIDRSample(sample []byte) bool {
-	for _, naluType := range FindNaluTypes(sample) {
+	return IsIDRSampleWithLengthSize(sample, 4)
+}
+
+// IsIDRSampleWithLengthSize - is IDR picture (NALU 19-20) in sample with lengthSize (1, 2, or 4) bytes length fields
+func IsIDRSampleWithLengthSize(sample []byte, lengthSize int) bool {
+	for _, naluType := range FindNaluTypesWithLengthSize(sample, lengthSize) {
 		if 19 <= naluType && naluType <= 20 {
 			return true
 		}
@@ -164,7 +176,13 @@ func IsIDRSample(sample []byte) bool {
 
 // HasParameterSets - Check if HEVC VPS, SPS and PPS are present
 func HasParameterSets(b []byte) bool {
-	naluTypeList := FindNaluTypesUpToFirstVideoNalu(b)
+	return HasParameterSetsWithLengthSize(b, 4)
+}
+
+// HasParameterSetsWithLengthSize - Check if HEVC VPS, SPS and PPS are present in sample
+// with lengthSize (1, 2, or 4) bytes length fields
+func HasParameterSetsWithLengthSize(b []byte, lengthSize int) bool {
+	naluTypeList := FindNaluTypesUpToFirstVideoNaluWithLengthSize(b, lengthSize)
 	var hasVPS, hasSPS, hasPPS bool
 	for _, naluType := range naluTypeList {
 		switch naluType {
@@ -184,15 +202,24 @@ func HasParameterSets(b []byte) bool {
 
 // GetParameterSets - get (multiple) VPS,  SPS, and PPS from a sample
 func GetParameterSets(sample []byte) (vps, sps, pps [][]byte) {
-	sampleLength := uint32(len(sample))
-	var pos uint32 = 0
+	return GetParameterSetsWithLengthSize(sample, 4)
+}
+
+// GetParameterSetsWithLengthSize - get (multiple) VPS,  SPS, and PPS from a sample
+// with lengthSize (1, 2, or 4) bytes length fields
+func GetParameterSetsWithLengthSize(sample []byte, lengthSize int) (vps, sps, pps [][]byte) {
+	if !avc.IsValidLengthSize(lengthSize) {
+		return nil, nil, nil
+	}
+	sampleLength := uint64(len(sample))
+	var pos uint64 = 0
 naluLoop:
 	for {
 		if pos >= sampleLength {
 			break
 		}
-		naluLength := binary.BigEndian.Uint32(sample[pos : pos+4])
-		pos += 4
+		naluLength := uint64(avc.ReadNaluLength(sample[pos:], lengthSize))
+		pos += uint64(lengthSize)
 		switch naluType := GetNaluType(sample[pos]); {
 		case naluType == NALU_VPS:
 			vps = append(vps, sample[pos:pos+naluLength])
@@ -10,7 +10,7 @@ import (
 
 // HEVC errors
 var (
-	ErrLengthSize = errors.New("can only handle 4byte NALU length size")
+	ErrLengthSize = errors.New("can only handle 1, 2, or 4 byte NALU length size")
 )
 
 // DecConfRec - HEVCDecoderConfigurationRecord
@@ -99,7 +99,7 @@ func CreateHEVCDecConfRec(vpsNalus, spsNalus, ppsNalus [][]byte,
 		ConstantFrameRate:                0,          // Set as default value
 		NumTemporalLayers:                0,          // Set as default value
 		TemporalIDNested:                 0,          // Set as default value
-		LengthSizeMinusOne:               3,          // only support 4-byte length
+		LengthSizeMinusOne:               3,          // 4-byte NALU length
 		NaluArrays:                       naluArrays, // VPS, SPS, PPS nalus with complete flag
 	}, nil
 }
@@ -131,7 +131,7 @@ func DecodeHEVCDecConfRec(data []byte) (DecConfRec, error) {
 	hdcr.NumTemporalLayers = (aByte >> 3) & 0x7
 	hdcr.TemporalIDNested = (aByte >> 2) & 0x1
 	hdcr.LengthSizeMinusOne = aByte & 0x3
-	if hdcr.LengthSizeMinusOne != 3 {
+	if hdcr.LengthSizeMinusOne == 2 {
 		return hdcr, ErrLengthSize
 	}
 	numArrays := sr.ReadUint8()
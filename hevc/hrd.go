@@ -0,0 +1,123 @@
+package hevc
+
+import (
+	"github.com/jaypadia-frame/mp4ff/bits"
+)
+
+// HrdParameters - Hypothetical Reference Decoder parameters as defined in Section E.2.2
+type HrdParameters struct {
+	NalHrdParametersPresentFlag            bool
+	VclHrdParametersPresentFlag            bool
+	SubPicHrdParamsPresentFlag             bool
+	TickDivisorMinus2                      byte
+	DuCpbRemovalDelayIncrementLengthMinus1 byte
+	SubPicCpbParamsInPicTimingSeiFlag      bool
+	DpbOutputDelayDuLengthMinus1           byte
+	BitRateScale                           byte
+	CpbSizeScale                           byte
+	CpbSizeDuScale                         byte
+	InitialCpbRemovalDelayLengthMinus1     byte
+	AuCpbRemovalDelayLengthMinus1          byte
+	DpbOutputDelayLengthMinus1             byte
+	SubLayers                              []SubLayerHrd
+}
+
+// SubLayerHrd - per sub-layer information in HrdParameters
+type SubLayerHrd struct {
+	FixedPicRateGeneralFlag     bool
+	FixedPicRateWithinCvsFlag   bool
+	ElementalDurationInTcMinus1 uint
+	LowDelayHrdFlag             bool
+	CpbCntMinus1                byte
+	NalCpbs                     []CpbParameters
+	VclCpbs                     []CpbParameters
+}
+
+// CpbParameters - sub_layer_hrd_parameters() values for one CPB specification (E.2.3)
+type CpbParameters struct {
+	BitRateValueMinus1   uint32
+	CpbSizeValueMinus1   uint32
+	CpbSizeDuValueMinus1 uint32
+	BitRateDuValueMinus1 uint32
+	CbrFlag              bool
+}
+
+// BitRate - bit rate in bits/s for a CPB specification
+func (h *HrdParameters) BitRate(cpb CpbParameters) uint64 {
+	return uint64(cpb.BitRateValueMinus1+1) << (6 + h.BitRateScale)
+}
+
+// CpbSize - CPB size in bits for a CPB specification
+func (h *HrdParameters) CpbSize(cpb CpbParameters) uint64 {
+	return uint64(cpb.CpbSizeValueMinus1+1) << (4 + h.CpbSizeScale)
+}
+
+// parseHrdParameters - parse hrd_parameters() according to E.2.2
+func parseHrdParameters(r *bits.AccErrEBSPReader, commonInfPresentFlag bool, maxNumSubLayersMinus1 byte) *HrdParameters {
+	hrd := &HrdParameters{}
+	if commonInfPresentFlag {
+		hrd.NalHrdParametersPresentFlag = r.ReadFlag()
+		hrd.VclHrdParametersPresentFlag = r.ReadFlag()
+		if hrd.NalHrdParametersPresentFlag || hrd.VclHrdParametersPresentFlag {
+			hrd.SubPicHrdParamsPresentFlag = r.ReadFlag()
+			if hrd.SubPicHrdParamsPresentFlag {
+				hrd.TickDivisorMinus2 = byte(r.Read(8))
+				hrd.DuCpbRemovalDelayIncrementLengthMinus1 = byte(r.Read(5))
+				hrd.SubPicCpbParamsInPicTimingSeiFlag = r.ReadFlag()
+				hrd.DpbOutputDelayDuLengthMinus1 = byte(r.Read(5))
+			}
+			hrd.BitRateScale = byte(r.Read(4))
+			hrd.CpbSizeScale = byte(r.Read(4))
+			if hrd.SubPicHrdParamsPresentFlag {
+				hrd.CpbSizeDuScale = byte(r.Read(4))
+			}
+			hrd.InitialCpbRemovalDelayLengthMinus1 = byte(r.Read(5))
+			hrd.AuCpbRemovalDelayLengthMinus1 = byte(r.Read(5))
+			hrd.DpbOutputDelayLengthMinus1 = byte(r.Read(5))
+		}
+	}
+	for i := byte(0); i <= maxNumSubLayersMinus1; i++ {
+		sl := SubLayerHrd{}
+		sl.FixedPicRateGeneralFlag = r.ReadFlag()
+		sl.FixedPicRateWithinCvsFlag = true
+		if !sl.FixedPicRateGeneralFlag {
+			sl.FixedPicRateWithinCvsFlag = r.ReadFlag()
+		}
+		if sl.FixedPicRateWithinCvsFlag {
+			sl.ElementalDurationInTcMinus1 = r.ReadExpGolomb()
+		} else {
+			sl.LowDelayHrdFlag = r.ReadFlag()
+		}
+		if !sl.LowDelayHrdFlag {
+			sl.CpbCntMinus1 = byte(r.ReadExpGolomb())
+		}
+		if hrd.NalHrdParametersPresentFlag {
+			sl.NalCpbs = parseSubLayerHrdParameters(r, sl.CpbCntMinus1, hrd.SubPicHrdParamsPresentFlag)
+		}
+		if hrd.VclHrdParametersPresentFlag {
+			sl.VclCpbs = parseSubLayerHrdParameters(r, sl.CpbCntMinus1, hrd.SubPicHrdParamsPresentFlag)
+		}
+		hrd.SubLayers = append(hrd.SubLayers, sl)
+		if r.AccError() != nil {
+			break
+		}
+	}
+	return hrd
+}
+
+// parseSubLayerHrdParameters - parse sub_layer_hrd_parameters() according to E.2.3
+func parseSubLayerHrdParameters(r *bits.AccErrEBSPReader, cpbCntMinus1 byte, subPicHrdParamsPresentFlag bool) []CpbParameters {
+	cpbs := make([]CpbParameters, 0, cpbCntMinus1+1)
+	for i := 0; i <= int(cpbCntMinus1); i++ {
+		c := CpbParameters{}
+		c.BitRateValueMinus1 = uint32(r.ReadExpGolomb())
+		c.CpbSizeValueMinus1 = uint32(r.ReadExpGolomb())
+		if subPicHrdParamsPresentFlag {
+			c.CpbSizeDuValueMinus1 = uint32(r.ReadExpGolomb())
+			c.BitRateDuValueMinus1 = uint32(r.ReadExpGolomb())
+		}
+		c.CbrFlag = r.ReadFlag()
+		cpbs = append(cpbs, c)
+	}
+	return cpbs
+}
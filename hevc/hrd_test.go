@@ -0,0 +1,47 @@
+package hevc
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestHrdParametersInVUI(t *testing.T) {
+	// SPS with NAL HRD parameters for 1Mbps and 2Mbit CPB
+	spsHex := "42010101600000030090000003000003003ca020810596e92930b80400000fa0000186a180732978003d090007a122"
+	byteData, _ := hex.DecodeString(spsHex)
+	sps, err := ParseSPSNALUnit(byteData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sps.VUI == nil || !sps.VUI.HrdParametersPresentFlag {
+		t.Fatal("no HRD parameters in VUI")
+	}
+	wanted := HrdParameters{
+		NalHrdParametersPresentFlag:        true,
+		CpbSizeScale:                       3,
+		InitialCpbRemovalDelayLengthMinus1: 19,
+		AuCpbRemovalDelayLengthMinus1:      5,
+		DpbOutputDelayLengthMinus1:         5,
+		SubLayers: []SubLayerHrd{
+			{
+				FixedPicRateGeneralFlag:   true,
+				FixedPicRateWithinCvsFlag: true,
+				NalCpbs: []CpbParameters{
+					{BitRateValueMinus1: 15624, CpbSizeValueMinus1: 15624},
+				},
+			},
+		},
+	}
+	hrd := sps.VUI.HrdParameters
+	if diff := deep.Equal(*hrd, wanted); diff != nil {
+		t.Errorf("Got HRD: %+v\n Diff is %v", hrd, diff)
+	}
+	if br := hrd.BitRate(hrd.SubLayers[0].NalCpbs[0]); br != 1000000 {
+		t.Errorf("got bitrate %d instead of 1000000", br)
+	}
+	if cs := hrd.CpbSize(hrd.SubLayers[0].NalCpbs[0]); cs != 2000000 {
+		t.Errorf("got cpb size %d instead of 2000000", cs)
+	}
+}
@@ -0,0 +1,55 @@
+package hevc
+
+// POCCalculator - calculate picture order count of HEVC pictures according to ISO/IEC 23008-2 Sec. 8.3.1.
+// Pictures must be given in decode order, since the calculation depends on previous pictures.
+type POCCalculator struct {
+	prevTid0PicOrderCntMsb int
+	prevTid0PicOrderCntLsb int
+	noRaslOutput           bool // Next IRAP picture starts a new coded video sequence
+}
+
+// NewPOCCalculator - create a calculator where the first IRAP picture starts a coded video sequence
+func NewPOCCalculator() *POCCalculator {
+	return &POCCalculator{noRaslOutput: true}
+}
+
+// EndOfSequence - signal an end of sequence NAL unit, so that the next IRAP picture starts a coded video sequence
+func (c *POCCalculator) EndOfSequence() {
+	c.noRaslOutput = true
+}
+
+// IsSubLayerNonReference - is NAL unit type a sub-layer non-reference picture (TRAIL_N, TSA_N, etc)
+func IsSubLayerNonReference(naluType NaluType) bool {
+	return naluType <= 14 && naluType%2 == 0
+}
+
+// PicOrderCnt - picture order count for slice segment header sh of NAL unit with header naluHdr (two bytes).
+// Only call this once per picture, e.g. for the slice segment with first_slice_segment_in_pic_flag set.
+// startsCVS is true if the picture is an IRAP picture with NoRaslOutputFlag equal to 1.
+func (c *POCCalculator) PicOrderCnt(naluHdr []byte, sh *SliceHeader, sps *SPS) (poc int, startsCVS bool) {
+	naluType := GetNaluType(naluHdr[0])
+	temporalID := 0
+	if len(naluHdr) > 1 {
+		temporalID = int(naluHdr[1]&0x07) - 1
+	}
+	isIRAP := naluType >= NALU_BLA_W_LP && naluType <= 23
+	maxPicOrderCntLsb := 1 << (sps.Log2MaxPicOrderCntLsbMinus4 + 4)
+	lsb := int(sh.PicOrderCntLsb)
+	msb := 0
+	switch {
+	case isIRAP && (c.noRaslOutput || naluType != NALU_CRA):
+		startsCVS = true
+		c.noRaslOutput = false
+	case lsb < c.prevTid0PicOrderCntLsb && c.prevTid0PicOrderCntLsb-lsb >= maxPicOrderCntLsb/2:
+		msb = c.prevTid0PicOrderCntMsb + maxPicOrderCntLsb
+	case lsb > c.prevTid0PicOrderCntLsb && lsb-c.prevTid0PicOrderCntLsb > maxPicOrderCntLsb/2:
+		msb = c.prevTid0PicOrderCntMsb - maxPicOrderCntLsb
+	default:
+		msb = c.prevTid0PicOrderCntMsb
+	}
+	isLeading := naluType >= NALU_RADL_N && naluType <= NALU_RASL_R
+	if temporalID == 0 && !isLeading && !IsSubLayerNonReference(naluType) {
+		c.prevTid0PicOrderCntMsb, c.prevTid0PicOrderCntLsb = msb, lsb
+	}
+	return msb + lsb, startsCVS
+}
@@ -0,0 +1,39 @@
+package hevc
+
+import (
+	"testing"
+)
+
+func TestPicOrderCnt(t *testing.T) {
+	sps := &SPS{Log2MaxPicOrderCntLsbMinus4: 0} // MaxPicOrderCntLsb = 16
+	cases := []struct {
+		naluType  NaluType
+		tid       byte
+		lsb       uint32
+		poc       int
+		startsCVS bool
+	}{
+		{NALU_IDR_W_RADL, 0, 0, 0, true},
+		{NALU_TRAIL_R, 0, 8, 8, false},
+		{NALU_TRAIL_R, 0, 14, 14, false},
+		{NALU_TRAIL_N, 1, 2, 18, false}, // Sub-layer non-reference does not update prevTid0Pic
+		{NALU_TRAIL_R, 0, 4, 20, false},
+		{NALU_CRA, 0, 12, 28, false}, // CRA in the middle of a coded video sequence
+		{NALU_RASL_N, 0, 10, 26, false},
+		{NALU_TRAIL_R, 0, 0, 32, false},
+	}
+	c := NewPOCCalculator()
+	for i, tc := range cases {
+		naluHdr := []byte{byte(tc.naluType) << 1, tc.tid + 1}
+		sh := &SliceHeader{NaluType: tc.naluType, PicOrderCntLsb: tc.lsb}
+		poc, startsCVS := c.PicOrderCnt(naluHdr, sh, sps)
+		if poc != tc.poc || startsCVS != tc.startsCVS {
+			t.Errorf("case %d: got poc %d, startsCVS %t instead of %d, %t", i, poc, startsCVS, tc.poc, tc.startsCVS)
+		}
+	}
+	c.EndOfSequence()
+	poc, startsCVS := c.PicOrderCnt([]byte{byte(NALU_CRA) << 1, 1}, &SliceHeader{PicOrderCntLsb: 6}, sps)
+	if poc != 6 || !startsCVS {
+		t.Errorf("CRA after end of sequence: got poc %d, startsCVS %t", poc, startsCVS)
+	}
+}
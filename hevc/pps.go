@@ -0,0 +1,193 @@
+package hevc
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/jaypadia-frame/mp4ff/bits"
+)
+
+// PPS - HEVC Picture Parameter Set
+// ISO/IEC 23008-2 Sec. 7.3.2.3
+type PPS struct {
+	PicParameterSetID                      uint32
+	SeqParameterSetID                      uint32
+	DependentSliceSegmentsEnabledFlag      bool
+	OutputFlagPresentFlag                  bool
+	NumExtraSliceHeaderBits                byte
+	SignDataHidingEnabledFlag              bool
+	CabacInitPresentFlag                   bool
+	NumRefIdxL0DefaultActiveMinus1         byte
+	NumRefIdxL1DefaultActiveMinus1         byte
+	InitQpMinus26                          int
+	ConstrainedIntraPredFlag               bool
+	TransformSkipEnabledFlag               bool
+	CuQpDeltaEnabledFlag                   bool
+	DiffCuQpDeltaDepth                     uint
+	CbQpOffset                             int
+	CrQpOffset                             int
+	SliceChromaQpOffsetsPresentFlag        bool
+	WeightedPredFlag                       bool
+	WeightedBipredFlag                     bool
+	TransquantBypassEnabledFlag            bool
+	TilesEnabledFlag                       bool
+	EntropyCodingSyncEnabledFlag           bool
+	NumTileColumnsMinus1                   uint
+	NumTileRowsMinus1                      uint
+	UniformSpacingFlag                     bool
+	ColumnWidthMinus1                      []uint
+	RowHeightMinus1                        []uint
+	LoopFilterAcrossTilesEnabledFlag       bool
+	LoopFilterAcrossSlicesEnabledFlag      bool
+	DeblockingFilterControlPresentFlag     bool
+	DeblockingFilterOverrideEnabledFlag    bool
+	DeblockingFilterDisabledFlag           bool
+	BetaOffsetDiv2                         int
+	TcOffsetDiv2                           int
+	ScalingListDataPresentFlag             bool
+	ScalingListData                        *ScalingListData
+	ListsModificationPresentFlag           bool
+	Log2ParallelMergeLevelMinus2           uint
+	SliceSegmentHeaderExtensionPresentFlag bool
+	ExtensionPresentFlag                   bool
+	RangeExtensionFlag                     bool
+	MultilayerExtensionFlag                bool
+	Extension3dFlag                        bool
+	SccExtensionFlag                       bool
+	Extension4bits                         byte
+	RangeExtension                         *PPSRangeExtension
+}
+
+// PPSRangeExtension - pps_range_extension() syntax from 7.3.2.3.2
+type PPSRangeExtension struct {
+	Log2MaxTransformSkipBlockSizeMinus2 uint
+	CrossComponentPredictionEnabledFlag bool
+	ChromaQpOffsetListEnabledFlag       bool
+	DiffCuChromaQpOffsetDepth           uint
+	ChromaQpOffsetListLenMinus1         uint
+	CbQpOffsetList                      []int
+	CrQpOffsetList                      []int
+	Log2SaoOffsetScaleLuma              uint
+	Log2SaoOffsetScaleChroma            uint
+}
+
+// ParsePPSNALUnit - Parse HEVC PPS NAL unit starting with NAL unit header
+func ParsePPSNALUnit(data []byte) (*PPS, error) {
+	pps := &PPS{}
+
+	rd := bytes.NewReader(data)
+	r := bits.NewAccErrEBSPReader(rd)
+	// Note! First two bytes are NALU Header
+
+	naluHdrBits := r.Read(16)
+	naluType := GetNaluType(byte(naluHdrBits >> 8))
+	if naluType != NALU_PPS {
+		return nil, fmt.Errorf("NALU type is %s not PPS", naluType)
+	}
+	pps.PicParameterSetID = uint32(r.ReadExpGolomb())
+	pps.SeqParameterSetID = uint32(r.ReadExpGolomb())
+	pps.DependentSliceSegmentsEnabledFlag = r.ReadFlag()
+	pps.OutputFlagPresentFlag = r.ReadFlag()
+	pps.NumExtraSliceHeaderBits = byte(r.Read(3))
+	pps.SignDataHidingEnabledFlag = r.ReadFlag()
+	pps.CabacInitPresentFlag = r.ReadFlag()
+	pps.NumRefIdxL0DefaultActiveMinus1 = byte(r.ReadExpGolomb())
+	pps.NumRefIdxL1DefaultActiveMinus1 = byte(r.ReadExpGolomb())
+	pps.InitQpMinus26 = r.ReadSignedGolomb()
+	pps.ConstrainedIntraPredFlag = r.ReadFlag()
+	pps.TransformSkipEnabledFlag = r.ReadFlag()
+	pps.CuQpDeltaEnabledFlag = r.ReadFlag()
+	if pps.CuQpDeltaEnabledFlag {
+		pps.DiffCuQpDeltaDepth = r.ReadExpGolomb()
+	}
+	pps.CbQpOffset = r.ReadSignedGolomb()
+	pps.CrQpOffset = r.ReadSignedGolomb()
+	pps.SliceChromaQpOffsetsPresentFlag = r.ReadFlag()
+	pps.WeightedPredFlag = r.ReadFlag()
+	pps.WeightedBipredFlag = r.ReadFlag()
+	pps.TransquantBypassEnabledFlag = r.ReadFlag()
+	pps.TilesEnabledFlag = r.ReadFlag()
+	pps.EntropyCodingSyncEnabledFlag = r.ReadFlag()
+	if pps.TilesEnabledFlag {
+		pps.NumTileColumnsMinus1 = r.ReadExpGolomb()
+		pps.NumTileRowsMinus1 = r.ReadExpGolomb()
+		if pps.NumTileColumnsMinus1 > 255 || pps.NumTileRowsMinus1 > 255 {
+			return nil, fmt.Errorf("too many tiles %dx%d", pps.NumTileColumnsMinus1+1, pps.NumTileRowsMinus1+1)
+		}
+		pps.UniformSpacingFlag = r.ReadFlag()
+		if !pps.UniformSpacingFlag {
+			for i := uint(0); i < pps.NumTileColumnsMinus1; i++ {
+				pps.ColumnWidthMinus1 = append(pps.ColumnWidthMinus1, r.ReadExpGolomb())
+			}
+			for i := uint(0); i < pps.NumTileRowsMinus1; i++ {
+				pps.RowHeightMinus1 = append(pps.RowHeightMinus1, r.ReadExpGolomb())
+			}
+		}
+		pps.LoopFilterAcrossTilesEnabledFlag = r.ReadFlag()
+	}
+	pps.LoopFilterAcrossSlicesEnabledFlag = r.ReadFlag()
+	pps.DeblockingFilterControlPresentFlag = r.ReadFlag()
+	if pps.DeblockingFilterControlPresentFlag {
+		pps.DeblockingFilterOverrideEnabledFlag = r.ReadFlag()
+		pps.DeblockingFilterDisabledFlag = r.ReadFlag()
+		if !pps.DeblockingFilterDisabledFlag {
+			pps.BetaOffsetDiv2 = r.ReadSignedGolomb()
+			pps.TcOffsetDiv2 = r.ReadSignedGolomb()
+		}
+	}
+	pps.ScalingListDataPresentFlag = r.ReadFlag()
+	if pps.ScalingListDataPresentFlag {
+		pps.ScalingListData = parseScalingListData(r)
+	}
+	pps.ListsModificationPresentFlag = r.ReadFlag()
+	pps.Log2ParallelMergeLevelMinus2 = r.ReadExpGolomb()
+	pps.SliceSegmentHeaderExtensionPresentFlag = r.ReadFlag()
+	pps.ExtensionPresentFlag = r.ReadFlag()
+	if pps.ExtensionPresentFlag {
+		pps.RangeExtensionFlag = r.ReadFlag()
+		pps.MultilayerExtensionFlag = r.ReadFlag()
+		pps.Extension3dFlag = r.ReadFlag()
+		pps.SccExtensionFlag = r.ReadFlag()
+		pps.Extension4bits = byte(r.Read(4))
+	}
+	if pps.RangeExtensionFlag {
+		pps.RangeExtension = parsePPSRangeExtension(r, pps.TransformSkipEnabledFlag)
+	}
+	if r.AccError() != nil {
+		return nil, r.AccError()
+	}
+	if pps.MultilayerExtensionFlag || pps.Extension3dFlag || pps.SccExtensionFlag || pps.Extension4bits != 0 {
+		// Other extensions are not parsed
+		return pps, nil
+	}
+	err := r.ReadRbspTrailingBits()
+	if err != nil {
+		return nil, err
+	}
+	return pps, nil
+}
+
+// parsePPSRangeExtension - parse pps_range_extension() according to 7.3.2.3.2
+func parsePPSRangeExtension(r *bits.AccErrEBSPReader, transformSkipEnabledFlag bool) *PPSRangeExtension {
+	ext := &PPSRangeExtension{}
+	if transformSkipEnabledFlag {
+		ext.Log2MaxTransformSkipBlockSizeMinus2 = r.ReadExpGolomb()
+	}
+	ext.CrossComponentPredictionEnabledFlag = r.ReadFlag()
+	ext.ChromaQpOffsetListEnabledFlag = r.ReadFlag()
+	if ext.ChromaQpOffsetListEnabledFlag {
+		ext.DiffCuChromaQpOffsetDepth = r.ReadExpGolomb()
+		ext.ChromaQpOffsetListLenMinus1 = r.ReadExpGolomb()
+		if ext.ChromaQpOffsetListLenMinus1 > 5 {
+			r.SetError(fmt.Errorf("chroma_qp_offset_list_len_minus1 %d > 5", ext.ChromaQpOffsetListLenMinus1))
+			return ext
+		}
+		for i := uint(0); i <= ext.ChromaQpOffsetListLenMinus1; i++ {
+			ext.CbQpOffsetList = append(ext.CbQpOffsetList, r.ReadSignedGolomb())
+			ext.CrQpOffsetList = append(ext.CrQpOffsetList, r.ReadSignedGolomb())
+		}
+	}
+	ext.Log2SaoOffsetScaleLuma = r.ReadExpGolomb()
+	ext.Log2SaoOffsetScaleChroma = r.ReadExpGolomb()
+	return ext
+}
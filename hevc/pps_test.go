@@ -0,0 +1,54 @@
+package hevc
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestPPSParser(t *testing.T) {
+	testCases := []struct {
+		name   string
+		ppsHex string
+		wanted PPS
+	}{
+		{
+			name:   "default",
+			ppsHex: "4401c073c089",
+			wanted: PPS{
+				CuQpDeltaEnabledFlag:              true,
+				LoopFilterAcrossSlicesEnabledFlag: true,
+			},
+		},
+		{
+			name:   "weighted prediction",
+			ppsHex: "4401c073d889",
+			wanted: PPS{
+				CuQpDeltaEnabledFlag:              true,
+				WeightedPredFlag:                  true,
+				WeightedBipredFlag:                true,
+				LoopFilterAcrossSlicesEnabledFlag: true,
+			},
+		},
+	}
+	for _, tc := range testCases {
+		byteData, _ := hex.DecodeString(tc.ppsHex)
+		got, err := ParsePPSNALUnit(byteData)
+		if err != nil {
+			t.Errorf("%s: %s", tc.name, err)
+			continue
+		}
+		if diff := deep.Equal(*got, tc.wanted); diff != nil {
+			t.Errorf("%s: got PPS %+v\n Diff is %v", tc.name, got, diff)
+		}
+	}
+}
+
+func TestPPSParserWrongType(t *testing.T) {
+	spsData, _ := hex.DecodeString(spsNalu)
+	_, err := ParsePPSNALUnit(spsData)
+	if err == nil {
+		t.Error("expected error when parsing SPS as PPS")
+	}
+}
@@ -0,0 +1,432 @@
+package hevc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/jaypadia-frame/mp4ff/bits"
+)
+
+// Errors for parsing HEVC slice headers
+var (
+	ErrNoSliceHeader = errors.New("No slice header")
+)
+
+// SliceType - HEVC slice type
+type SliceType uint
+
+// HEVC slice types
+const (
+	SLICE_B = SliceType(0)
+	SLICE_P = SliceType(1)
+	SLICE_I = SliceType(2)
+)
+
+func (s SliceType) String() string {
+	switch s {
+	case SLICE_I:
+		return "I"
+	case SLICE_P:
+		return "P"
+	case SLICE_B:
+		return "B"
+	default:
+		return ""
+	}
+}
+
+// SliceHeader - HEVC slice segment header
+// ISO/IEC 23008-2 Sec. 7.3.6.1
+type SliceHeader struct {
+	NaluType                          NaluType
+	FirstSliceSegmentInPicFlag        bool
+	NoOutputOfPriorPicsFlag           bool
+	PicParameterSetID                 uint32
+	DependentSliceSegmentFlag         bool
+	SegmentAddress                    uint
+	SliceType                         SliceType
+	PicOutputFlag                     bool
+	ColourPlaneID                     byte
+	PicOrderCntLsb                    uint32
+	ShortTermRefPicSetSpsFlag         bool
+	ShortTermRefPicSetIdx             byte
+	ShortTermRefPicSet                ShortTermRPS // The set used, either from SPS or from slice header
+	NumLongTermSps                    byte
+	NumLongTermPics                   byte
+	LongTermRefPics                   []LongTermRefPic
+	TemporalMvpEnabledFlag            bool
+	SaoLumaFlag                       bool
+	SaoChromaFlag                     bool
+	NumRefIdxActiveOverrideFlag       bool
+	NumRefIdxL0ActiveMinus1           byte
+	NumRefIdxL1ActiveMinus1           byte
+	RefPicListModification            *RefPicListModification
+	MvdL1ZeroFlag                     bool
+	CabacInitFlag                     bool
+	CollocatedFromL0Flag              bool
+	CollocatedRefIdx                  uint
+	PredWeightTable                   *PredWeightTable
+	FiveMinusMaxNumMergeCand          uint
+	QpDelta                           int
+	CbQpOffset                        int
+	CrQpOffset                        int
+	CuChromaQpOffsetEnabledFlag       bool
+	DeblockingFilterOverrideFlag      bool
+	DeblockingFilterDisabledFlag      bool
+	BetaOffsetDiv2                    int
+	TcOffsetDiv2                      int
+	LoopFilterAcrossSlicesEnabledFlag bool
+	NumEntryPointOffsets              uint
+	OffsetLenMinus1                   uint
+	EntryPointOffsetMinus1            []uint32
+	ExtensionLength                   uint
+	ExtensionDataByte                 []byte
+	Size                              uint32 // Size in bytes including NALU header and byte_alignment()
+}
+
+// LongTermRefPic - long-term reference picture info in slice header
+type LongTermRefPic struct {
+	LtIdxSps               byte
+	PocLsbLt               uint32
+	UsedByCurrPicLtFlag    bool
+	DeltaPocMsbPresentFlag bool
+	DeltaPocMsbCycleLt     uint
+}
+
+// RefPicListModification - ref_pic_lists_modification() in 7.3.6.2
+type RefPicListModification struct {
+	RefPicListModificationFlagL0 bool
+	ListEntryL0                  []uint8
+	RefPicListModificationFlagL1 bool
+	ListEntryL1                  []uint8
+}
+
+// PredWeightTable - pred_weight_table() in 7.3.6.3
+type PredWeightTable struct {
+	LumaLog2WeightDenom        uint
+	DeltaChromaLog2WeightDenom int
+	LumaWeightL0Flag           []bool
+	ChromaWeightL0Flag         []bool
+	DeltaLumaWeightL0          []int
+	LumaOffsetL0               []int
+	DeltaChromaWeightL0        [][2]int
+	DeltaChromaOffsetL0        [][2]int
+	LumaWeightL1Flag           []bool
+	ChromaWeightL1Flag         []bool
+	DeltaLumaWeightL1          []int
+	LumaOffsetL1               []int
+	DeltaChromaWeightL1        [][2]int
+	DeltaChromaOffsetL1        [][2]int
+}
+
+// NumPicTotalCurr - number of pictures used for inter prediction of the current picture (7-55)
+func (sh *SliceHeader) NumPicTotalCurr() int {
+	n := sh.ShortTermRefPicSet.NumPicsUsedByCurr()
+	for _, lt := range sh.LongTermRefPics {
+		if lt.UsedByCurrPicLtFlag {
+			n++
+		}
+	}
+	return n
+}
+
+// IsIDR - is the slice in an IDR picture
+func (sh *SliceHeader) IsIDR() bool {
+	return sh.NaluType == NALU_IDR_W_RADL || sh.NaluType == NALU_IDR_N_LP
+}
+
+// ParseSliceHeader - parse HEVC slice segment header starting with NALU header.
+// The SPS and PPS referred to must be available in spsMap and ppsMap.
+func ParseSliceHeader(nalu []byte, spsMap map[uint32]*SPS, ppsMap map[uint32]*PPS) (*SliceHeader, error) {
+	sh := &SliceHeader{}
+	rd := bytes.NewReader(nalu)
+	r := bits.NewAccErrEBSPReader(rd)
+	// Note! First two bytes are NALU Header
+	naluHdrBits := r.Read(16)
+	sh.NaluType = GetNaluType(byte(naluHdrBits >> 8))
+	if r.AccError() != nil {
+		return nil, r.AccError()
+	}
+	if sh.NaluType > highestVideoNaluType {
+		return nil, ErrNoSliceHeader
+	}
+	sh.FirstSliceSegmentInPicFlag = r.ReadFlag()
+	if sh.NaluType >= NALU_BLA_W_LP && sh.NaluType <= 23 {
+		sh.NoOutputOfPriorPicsFlag = r.ReadFlag()
+	}
+	sh.PicParameterSetID = uint32(r.ReadExpGolomb())
+	if r.AccError() != nil {
+		return nil, r.AccError()
+	}
+	pps, ok := ppsMap[sh.PicParameterSetID]
+	if !ok {
+		return nil, fmt.Errorf("pps ID %d not found", sh.PicParameterSetID)
+	}
+	sps, ok := spsMap[pps.SeqParameterSetID]
+	if !ok {
+		return nil, fmt.Errorf("sps ID %d not found", pps.SeqParameterSetID)
+	}
+	if !sh.FirstSliceSegmentInPicFlag {
+		if pps.DependentSliceSegmentsEnabledFlag {
+			sh.DependentSliceSegmentFlag = r.ReadFlag()
+		}
+		sh.SegmentAddress = r.Read(ceilLog2(uint(sps.PicSizeInCtbsY())))
+	}
+
+	// Default values for parameters not present
+	sh.PicOutputFlag = true
+	sh.CollocatedFromL0Flag = true
+	sh.NumRefIdxL0ActiveMinus1 = pps.NumRefIdxL0DefaultActiveMinus1
+	sh.NumRefIdxL1ActiveMinus1 = pps.NumRefIdxL1DefaultActiveMinus1
+	sh.DeblockingFilterDisabledFlag = pps.DeblockingFilterDisabledFlag
+	sh.BetaOffsetDiv2 = pps.BetaOffsetDiv2
+	sh.TcOffsetDiv2 = pps.TcOffsetDiv2
+	sh.LoopFilterAcrossSlicesEnabledFlag = pps.LoopFilterAcrossSlicesEnabledFlag
+
+	if !sh.DependentSliceSegmentFlag {
+		for i := byte(0); i < pps.NumExtraSliceHeaderBits; i++ {
+			_ = r.ReadFlag() // slice_reserved_flag
+		}
+		sh.SliceType = SliceType(r.ReadExpGolomb())
+		if sh.SliceType > SLICE_I {
+			if r.AccError() != nil {
+				return nil, r.AccError()
+			}
+			return nil, fmt.Errorf("invalid slice type %d", sh.SliceType)
+		}
+		if pps.OutputFlagPresentFlag {
+			sh.PicOutputFlag = r.ReadFlag()
+		}
+		if sps.SeparateColourPlaneFlag {
+			sh.ColourPlaneID = byte(r.Read(2))
+		}
+		if !sh.IsIDR() {
+			sh.PicOrderCntLsb = uint32(r.Read(int(sps.Log2MaxPicOrderCntLsbMinus4 + 4)))
+			sh.ShortTermRefPicSetSpsFlag = r.ReadFlag()
+			if !sh.ShortTermRefPicSetSpsFlag {
+				sh.ShortTermRefPicSet = parseShortTermRPS(r, sps.NumShortTermRefPicSets, sps.NumShortTermRefPicSets, sps)
+			} else {
+				if sps.NumShortTermRefPicSets == 0 {
+					return nil, fmt.Errorf("no short-term ref pic sets in sps")
+				}
+				if sps.NumShortTermRefPicSets > 1 {
+					sh.ShortTermRefPicSetIdx = byte(r.Read(ceilLog2(uint(sps.NumShortTermRefPicSets))))
+				}
+				if int(sh.ShortTermRefPicSetIdx) >= len(sps.ShortTermRefPicSets) {
+					return nil, fmt.Errorf("short_term_ref_pic_set_idx %d out of range", sh.ShortTermRefPicSetIdx)
+				}
+				sh.ShortTermRefPicSet = sps.ShortTermRefPicSets[sh.ShortTermRefPicSetIdx]
+			}
+			if sps.LongTermRefPicsPresentFlag {
+				if sps.NumLongTermRefPicsSps > 0 {
+					sh.NumLongTermSps = byte(r.ReadExpGolomb())
+				}
+				sh.NumLongTermPics = byte(r.ReadExpGolomb())
+				nrLongTerm := int(sh.NumLongTermSps) + int(sh.NumLongTermPics)
+				for i := 0; i < nrLongTerm; i++ {
+					lt := LongTermRefPic{}
+					if i < int(sh.NumLongTermSps) {
+						if sps.NumLongTermRefPicsSps > 1 {
+							lt.LtIdxSps = byte(r.Read(ceilLog2(uint(sps.NumLongTermRefPicsSps))))
+						}
+						if int(lt.LtIdxSps) < len(sps.LtRefPicPocLsbSps) {
+							lt.PocLsbLt = sps.LtRefPicPocLsbSps[lt.LtIdxSps]
+							lt.UsedByCurrPicLtFlag = sps.UsedByCurrPicLtSpsFlag[lt.LtIdxSps]
+						}
+					} else {
+						lt.PocLsbLt = uint32(r.Read(int(sps.Log2MaxPicOrderCntLsbMinus4 + 4)))
+						lt.UsedByCurrPicLtFlag = r.ReadFlag()
+					}
+					lt.DeltaPocMsbPresentFlag = r.ReadFlag()
+					if lt.DeltaPocMsbPresentFlag {
+						lt.DeltaPocMsbCycleLt = r.ReadExpGolomb()
+					}
+					sh.LongTermRefPics = append(sh.LongTermRefPics, lt)
+					if r.AccError() != nil {
+						return nil, r.AccError()
+					}
+				}
+			}
+			if sps.SpsTemporalMvpEnabledFlag {
+				sh.TemporalMvpEnabledFlag = r.ReadFlag()
+			}
+		}
+		if sps.SampleAdaptiveOffsetEnabledFlag {
+			sh.SaoLumaFlag = r.ReadFlag()
+			if sps.ChromaArrayType() != 0 {
+				sh.SaoChromaFlag = r.ReadFlag()
+			}
+		}
+		if sh.SliceType == SLICE_P || sh.SliceType == SLICE_B {
+			sh.NumRefIdxActiveOverrideFlag = r.ReadFlag()
+			if sh.NumRefIdxActiveOverrideFlag {
+				sh.NumRefIdxL0ActiveMinus1 = byte(r.ReadExpGolomb())
+				if sh.SliceType == SLICE_B {
+					sh.NumRefIdxL1ActiveMinus1 = byte(r.ReadExpGolomb())
+				}
+			}
+			numPicTotalCurr := sh.NumPicTotalCurr()
+			if pps.ListsModificationPresentFlag && numPicTotalCurr > 1 {
+				sh.RefPicListModification = parseRefPicListModification(r, sh, numPicTotalCurr)
+			}
+			if sh.SliceType == SLICE_B {
+				sh.MvdL1ZeroFlag = r.ReadFlag()
+			}
+			if pps.CabacInitPresentFlag {
+				sh.CabacInitFlag = r.ReadFlag()
+			}
+			if sh.TemporalMvpEnabledFlag {
+				if sh.SliceType == SLICE_B {
+					sh.CollocatedFromL0Flag = r.ReadFlag()
+				}
+				if (sh.CollocatedFromL0Flag && sh.NumRefIdxL0ActiveMinus1 > 0) ||
+					(!sh.CollocatedFromL0Flag && sh.NumRefIdxL1ActiveMinus1 > 0) {
+					sh.CollocatedRefIdx = r.ReadExpGolomb()
+				}
+			}
+			if (pps.WeightedPredFlag && sh.SliceType == SLICE_P) ||
+				(pps.WeightedBipredFlag && sh.SliceType == SLICE_B) {
+				sh.PredWeightTable = parsePredWeightTable(r, sh, sps.ChromaArrayType())
+			}
+			sh.FiveMinusMaxNumMergeCand = r.ReadExpGolomb()
+		}
+		sh.QpDelta = r.ReadSignedGolomb()
+		if pps.SliceChromaQpOffsetsPresentFlag {
+			sh.CbQpOffset = r.ReadSignedGolomb()
+			sh.CrQpOffset = r.ReadSignedGolomb()
+		}
+		if pps.RangeExtension != nil && pps.RangeExtension.ChromaQpOffsetListEnabledFlag {
+			sh.CuChromaQpOffsetEnabledFlag = r.ReadFlag()
+		}
+		if pps.DeblockingFilterOverrideEnabledFlag {
+			sh.DeblockingFilterOverrideFlag = r.ReadFlag()
+		}
+		if sh.DeblockingFilterOverrideFlag {
+			sh.DeblockingFilterDisabledFlag = r.ReadFlag()
+			if !sh.DeblockingFilterDisabledFlag {
+				sh.BetaOffsetDiv2 = r.ReadSignedGolomb()
+				sh.TcOffsetDiv2 = r.ReadSignedGolomb()
+			}
+		}
+		if pps.LoopFilterAcrossSlicesEnabledFlag &&
+			(sh.SaoLumaFlag || sh.SaoChromaFlag || !sh.DeblockingFilterDisabledFlag) {
+			sh.LoopFilterAcrossSlicesEnabledFlag = r.ReadFlag()
+		}
+	}
+	if pps.TilesEnabledFlag || pps.EntropyCodingSyncEnabledFlag {
+		sh.NumEntryPointOffsets = r.ReadExpGolomb()
+		if sh.NumEntryPointOffsets > 0 {
+			sh.OffsetLenMinus1 = r.ReadExpGolomb()
+			if sh.OffsetLenMinus1 > 31 {
+				return nil, fmt.Errorf("offset_len_minus1 %d > 31", sh.OffsetLenMinus1)
+			}
+			if sh.NumEntryPointOffsets > uint(sps.PicSizeInCtbsY()) {
+				return nil, fmt.Errorf("num_entry_point_offsets %d too big", sh.NumEntryPointOffsets)
+			}
+			sh.EntryPointOffsetMinus1 = make([]uint32, sh.NumEntryPointOffsets)
+			for i := range sh.EntryPointOffsetMinus1 {
+				sh.EntryPointOffsetMinus1[i] = uint32(r.Read(int(sh.OffsetLenMinus1 + 1)))
+			}
+		}
+	}
+	if pps.SliceSegmentHeaderExtensionPresentFlag {
+		sh.ExtensionLength = r.ReadExpGolomb()
+		if sh.ExtensionLength > 256 {
+			return nil, fmt.Errorf("slice_segment_header_extension_length %d > 256", sh.ExtensionLength)
+		}
+		sh.ExtensionDataByte = make([]byte, sh.ExtensionLength)
+		for i := range sh.ExtensionDataByte {
+			sh.ExtensionDataByte[i] = byte(r.Read(8))
+		}
+	}
+	// byte_alignment()
+	if r.Read(1) != 1 && r.AccError() == nil {
+		return nil, fmt.Errorf("alignment_bit_equal_to_one is not 1")
+	}
+	if n := r.NrBitsReadInCurrentByte(); n != 8 {
+		_ = r.Read(8 - n)
+	}
+	if r.AccError() != nil {
+		return nil, r.AccError()
+	}
+	sh.Size = uint32(r.NrBytesRead())
+	return sh, nil
+}
+
+func parseRefPicListModification(r *bits.AccErrEBSPReader, sh *SliceHeader, numPicTotalCurr int) *RefPicListModification {
+	rplm := &RefPicListModification{}
+	nrBits := ceilLog2(uint(numPicTotalCurr))
+	rplm.RefPicListModificationFlagL0 = r.ReadFlag()
+	if rplm.RefPicListModificationFlagL0 {
+		for i := byte(0); i <= sh.NumRefIdxL0ActiveMinus1; i++ {
+			rplm.ListEntryL0 = append(rplm.ListEntryL0, uint8(r.Read(nrBits)))
+		}
+	}
+	if sh.SliceType == SLICE_B {
+		rplm.RefPicListModificationFlagL1 = r.ReadFlag()
+		if rplm.RefPicListModificationFlagL1 {
+			for i := byte(0); i <= sh.NumRefIdxL1ActiveMinus1; i++ {
+				rplm.ListEntryL1 = append(rplm.ListEntryL1, uint8(r.Read(nrBits)))
+			}
+		}
+	}
+	return rplm
+}
+
+func parsePredWeightTable(r *bits.AccErrEBSPReader, sh *SliceHeader, chromaArrayType byte) *PredWeightTable {
+	pwt := &PredWeightTable{}
+	pwt.LumaLog2WeightDenom = r.ReadExpGolomb()
+	if chromaArrayType != 0 {
+		pwt.DeltaChromaLog2WeightDenom = r.ReadSignedGolomb()
+	}
+	parseList := func(numRefIdxActiveMinus1 byte) (lumaFlags, chromaFlags []bool, lumaWeights, lumaOffsets []int,
+		chromaWeights, chromaOffsets [][2]int) {
+		n := int(numRefIdxActiveMinus1) + 1
+		lumaFlags = make([]bool, n)
+		chromaFlags = make([]bool, n)
+		for i := 0; i < n; i++ {
+			lumaFlags[i] = r.ReadFlag()
+		}
+		if chromaArrayType != 0 {
+			for i := 0; i < n; i++ {
+				chromaFlags[i] = r.ReadFlag()
+			}
+		}
+		lumaWeights = make([]int, n)
+		lumaOffsets = make([]int, n)
+		chromaWeights = make([][2]int, n)
+		chromaOffsets = make([][2]int, n)
+		for i := 0; i < n; i++ {
+			if lumaFlags[i] {
+				lumaWeights[i] = r.ReadSignedGolomb()
+				lumaOffsets[i] = r.ReadSignedGolomb()
+			}
+			if chromaFlags[i] {
+				for j := 0; j < 2; j++ {
+					chromaWeights[i][j] = r.ReadSignedGolomb()
+					chromaOffsets[i][j] = r.ReadSignedGolomb()
+				}
+			}
+		}
+		return lumaFlags, chromaFlags, lumaWeights, lumaOffsets, chromaWeights, chromaOffsets
+	}
+	pwt.LumaWeightL0Flag, pwt.ChromaWeightL0Flag, pwt.DeltaLumaWeightL0, pwt.LumaOffsetL0,
+		pwt.DeltaChromaWeightL0, pwt.DeltaChromaOffsetL0 = parseList(sh.NumRefIdxL0ActiveMinus1)
+	if sh.SliceType == SLICE_B {
+		pwt.LumaWeightL1Flag, pwt.ChromaWeightL1Flag, pwt.DeltaLumaWeightL1, pwt.LumaOffsetL1,
+			pwt.DeltaChromaWeightL1, pwt.DeltaChromaOffsetL1 = parseList(sh.NumRefIdxL1ActiveMinus1)
+	}
+	return pwt
+}
+
+// ceilLog2 - nr bits needed to represent numbers 0 - n-1 values
+func ceilLog2(n uint) int {
+	for i := 0; i < 32; i++ {
+		maxNr := uint(1 << i)
+		if maxNr >= n {
+			return i
+		}
+	}
+	return 32
+}
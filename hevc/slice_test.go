@@ -0,0 +1,177 @@
+package hevc
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func parseParameterSets(t *testing.T, spsHex, ppsHex string) (map[uint32]*SPS, map[uint32]*PPS) {
+	t.Helper()
+	spsData, _ := hex.DecodeString(spsHex)
+	sps, err := ParseSPSNALUnit(spsData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ppsData, _ := hex.DecodeString(ppsHex)
+	pps, err := ParsePPSNALUnit(ppsData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[uint32]*SPS{uint32(sps.SpsID): sps}, map[uint32]*PPS{pps.PicParameterSetID: pps}
+}
+
+func TestParseSliceHeader(t *testing.T) {
+	testCases := []struct {
+		name      string
+		spsHex    string
+		ppsHex    string
+		naluHex   string
+		sliceType SliceType
+		pocLsb    uint32
+		size      uint32
+		weighted  bool
+	}{
+		{
+			name:      "IDR",
+			spsHex:    "42010101600000030090000003000003001ea0208105965644a4c2e01000003e8000061a8080",
+			ppsHex:    "4401c073c089",
+			naluHex:   "2801ac7600cf",
+			sliceType: SLICE_I,
+			size:      4,
+		},
+		{
+			name:      "CRA",
+			spsHex:    "42010101600000030090000003000003001ea0208105965644a4c2e01000003e8000061a8080",
+			ppsHex:    "4401c073c089",
+			naluHex:   "2a01ac184b491980",
+			sliceType: SLICE_I,
+			pocLsb:    6,
+			size:      8,
+		},
+		{
+			name:      "RASL",
+			spsHex:    "42010101600000030090000003000003001ea0208105965644a4c2e01000003e8000061a8080",
+			ppsHex:    "4401c073c089",
+			naluHex:   "1001e086fffa240a60",
+			sliceType: SLICE_B,
+			pocLsb:    4,
+			size:      9,
+		},
+		{
+			name:      "TRAIL_N",
+			spsHex:    "42010101600000030090000003000003001ea0208105965644a4c2e01000003e8000061a8080",
+			ppsHex:    "4401c073c089",
+			naluHex:   "0001e024ffe890298039c46b80",
+			sliceType: SLICE_B,
+			pocLsb:    1,
+			size:      9,
+		},
+		{
+			name:      "weighted P",
+			spsHex:    "42010101600000030090000003000003001ea0208105965654a4cae01000003e8000061a8080",
+			ppsHex:    "4401c073d889",
+			naluHex:   "0201d02149e10c20eced4417d5e538aec3c0",
+			sliceType: SLICE_P,
+			pocLsb:    4,
+			size:      9,
+			weighted:  true,
+		},
+		{
+			name:      "weighted B",
+			spsHex:    "42010101600000030090000003000003001ea0208105965654a4cae01000003e8000061a8080",
+			ppsHex:    "4401c073d889",
+			naluHex:   "0001e024fd7e8886010298303ed75fc925c0",
+			sliceType: SLICE_B,
+			pocLsb:    1,
+			size:      11,
+			weighted:  true,
+		},
+	}
+	for _, tc := range testCases {
+		spsMap, ppsMap := parseParameterSets(t, tc.spsHex, tc.ppsHex)
+		nalu, _ := hex.DecodeString(tc.naluHex)
+		sh, err := ParseSliceHeader(nalu, spsMap, ppsMap)
+		if err != nil {
+			t.Errorf("%s: %s", tc.name, err)
+			continue
+		}
+		if sh.SliceType != tc.sliceType {
+			t.Errorf("%s: got slice type %s instead of %s", tc.name, sh.SliceType, tc.sliceType)
+		}
+		if sh.PicOrderCntLsb != tc.pocLsb {
+			t.Errorf("%s: got POC LSB %d instead of %d", tc.name, sh.PicOrderCntLsb, tc.pocLsb)
+		}
+		if sh.Size != tc.size {
+			t.Errorf("%s: got size %d instead of %d", tc.name, sh.Size, tc.size)
+		}
+		if (sh.PredWeightTable != nil) != tc.weighted {
+			t.Errorf("%s: got pred weight table %v", tc.name, sh.PredWeightTable)
+		}
+	}
+}
+
+func TestParseSliceHeaderDetails(t *testing.T) {
+	spsMap, ppsMap := parseParameterSets(t,
+		"42010101600000030090000003000003001ea0208105965654a4cae01000003e8000061a8080", "4401c073d889")
+	nalu, _ := hex.DecodeString("0201d02149e10c20eced4417d5e538aec3c0")
+	got, err := ParseSliceHeader(nalu, spsMap, ppsMap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wanted := &SliceHeader{
+		NaluType:                   NALU_TRAIL_R,
+		FirstSliceSegmentInPicFlag: true,
+		SliceType:                  SLICE_P,
+		PicOutputFlag:              true,
+		PicOrderCntLsb:             4,
+		ShortTermRefPicSet: ShortTermRPS{
+			DeltaPocS0:      []uint32{4},
+			DeltaPocS1:      []uint32{},
+			UsedByCurrPicS0: []bool{true},
+			UsedByCurrPicS1: []bool{},
+			NumNegativePics: 1,
+			NumDeltaPocs:    1,
+		},
+		TemporalMvpEnabledFlag: true,
+		SaoLumaFlag:            true,
+		SaoChromaFlag:          true,
+		CollocatedFromL0Flag:   true,
+		PredWeightTable: &PredWeightTable{
+			LumaLog2WeightDenom:        7,
+			DeltaChromaLog2WeightDenom: -1,
+			LumaWeightL0Flag:           []bool{false},
+			ChromaWeightL0Flag:         []bool{false},
+			DeltaLumaWeightL0:          []int{0},
+			LumaOffsetL0:               []int{0},
+			DeltaChromaWeightL0:        [][2]int{{0, 0}},
+			DeltaChromaOffsetL0:        [][2]int{{0, 0}},
+		},
+		FiveMinusMaxNumMergeCand:          3,
+		QpDelta:                           7,
+		LoopFilterAcrossSlicesEnabledFlag: true,
+		Size:                              9,
+	}
+	if diff := deep.Equal(got, wanted); diff != nil {
+		t.Errorf("Got slice header: %+v\n Diff is %v", got, diff)
+	}
+	if got.NumPicTotalCurr() != 1 {
+		t.Errorf("got NumPicTotalCurr %d instead of 1", got.NumPicTotalCurr())
+	}
+}
+
+func TestParseSliceHeaderErrors(t *testing.T) {
+	spsMap, ppsMap := parseParameterSets(t,
+		"42010101600000030090000003000003001ea0208105965644a4c2e01000003e8000061a8080", "4401c073c089")
+	ppsData, _ := hex.DecodeString("4401c073c089")
+	_, err := ParseSliceHeader(ppsData, spsMap, ppsMap)
+	if err != ErrNoSliceHeader {
+		t.Errorf("got error %v instead of ErrNoSliceHeader", err)
+	}
+	nalu, _ := hex.DecodeString("2801ac7600cf")
+	_, err = ParseSliceHeader(nalu, spsMap, map[uint32]*PPS{})
+	if err == nil {
+		t.Error("expected error for missing PPS")
+	}
+}
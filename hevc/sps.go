@@ -35,6 +35,7 @@ type SPS struct {
 	MaxTransformHierarchyDepthIntra      byte
 	ScalingListEnabledFlag               bool
 	ScalingListDataPresentFlag           bool
+	ScalingListData                      *ScalingListData
 	AmpEnabledFlag                       bool
 	SampleAdaptiveOffsetEnabledFlag      bool
 	PCMEnabledFlag                       bool
@@ -46,6 +47,9 @@ type SPS struct {
 	NumShortTermRefPicSets               byte
 	ShortTermRefPicSets                  []ShortTermRPS
 	LongTermRefPicsPresentFlag           bool
+	NumLongTermRefPicsSps                byte
+	LtRefPicPocLsbSps                    []uint32
+	UsedByCurrPicLtSpsFlag               []bool
 	SpsTemporalMvpEnabledFlag            bool
 	StrongIntraSmoothingEnabledFlag      bool
 	VUIParametersPresentFlag             bool
@@ -65,8 +69,19 @@ type ProfileTierLevel struct {
 	GeneralFrameOnlyConstraintFlag   bool
 	// 43 + 1 bits of info
 	GeneralLevelIDC byte
-	// Sublayer stuff
+	SubLayers       []SubLayerProfileLevel
+}
 
+// SubLayerProfileLevel - optional sub-layer profile and level in ProfileTierLevel
+type SubLayerProfileLevel struct {
+	ProfilePresentFlag        bool
+	LevelPresentFlag          bool
+	ProfileSpace              byte
+	TierFlag                  bool
+	ProfileIDC                byte
+	ProfileCompatibilityFlags uint32
+	ConstraintIndicatorFlags  uint64 // 48 bits
+	LevelIDC                  byte
 }
 
 // ConformanceWindow according to ISO/IEC 23008-2
@@ -114,6 +129,7 @@ type VUIParameters struct {
 	PocProportionalToTimingFlag    bool
 	NumTicksPocDiffOneMinus1       uint
 	HrdParametersPresentFlag       bool
+	HrdParameters                  *HrdParameters
 	BitstreamRestrictionFlag       bool
 	BitstreamResctrictions         *BitstreamRestrictions
 }
@@ -147,15 +163,7 @@ func ParseSPSNALUnit(data []byte) (*SPS, error) {
 	sps.VpsID = byte(r.Read(4))
 	sps.MaxSubLayersMinus1 = byte(r.Read(3))
 	sps.TemporalIDNestingFlag = r.ReadFlag()
-	sps.ProfileTierLevel.GeneralProfileSpace = byte(r.Read(2))
-	sps.ProfileTierLevel.GeneralTierFlag = r.ReadFlag()
-	sps.ProfileTierLevel.GeneralProfileIDC = byte(r.Read(5))
-	sps.ProfileTierLevel.GeneralProfileCompatibilityFlags = uint32(r.Read(32))
-	sps.ProfileTierLevel.GeneralConstraintIndicatorFlags = uint64(r.Read(48))
-	sps.ProfileTierLevel.GeneralLevelIDC = byte(r.Read(8))
-	if sps.MaxSubLayersMinus1 != 0 {
-		return sps, nil // Cannot parse any further
-	}
+	sps.ProfileTierLevel = parseProfileTierLevel(r, true, sps.MaxSubLayersMinus1)
 	sps.SpsID = byte(r.ReadExpGolomb())
 	sps.ChromaFormatIDC = byte(r.ReadExpGolomb())
 	if sps.ChromaFormatIDC == 3 {
@@ -177,7 +185,7 @@ func ParseSPSNALUnit(data []byte) (*SPS, error) {
 	sps.Log2MaxPicOrderCntLsbMinus4 = byte(r.ReadExpGolomb())
 	sps.SubLayerOrderingInfoPresentFlag = r.ReadFlag()
 	startValue := byte(0)
-	if sps.SubLayerOrderingInfoPresentFlag {
+	if !sps.SubLayerOrderingInfoPresentFlag {
 		startValue = sps.MaxSubLayersMinus1
 	}
 	for i := startValue; i <= sps.MaxSubLayersMinus1; i++ {
@@ -197,7 +205,10 @@ func ParseSPSNALUnit(data []byte) (*SPS, error) {
 	sps.MaxTransformHierarchyDepthIntra = byte(r.ReadExpGolomb())
 	sps.ScalingListEnabledFlag = r.ReadFlag()
 	if sps.ScalingListEnabledFlag {
-		readPastScalingList(r)
+		sps.ScalingListDataPresentFlag = r.ReadFlag()
+		if sps.ScalingListDataPresentFlag {
+			sps.ScalingListData = parseScalingListData(r)
+		}
 	}
 	sps.AmpEnabledFlag = r.ReadFlag()
 	sps.SampleAdaptiveOffsetEnabledFlag = r.ReadFlag()
@@ -219,18 +230,17 @@ func ParseSPSNALUnit(data []byte) (*SPS, error) {
 
 	sps.LongTermRefPicsPresentFlag = r.ReadFlag()
 	if sps.LongTermRefPicsPresentFlag {
-		// Get passed this without storing the information
-		numLongTermRefPics := r.ReadExpGolomb()
-		for i := uint(0); i < numLongTermRefPics; i++ {
-			/* ltRefPicPocLsbSps[i] */ _ = r.Read(int(sps.Log2MaxPicOrderCntLsbMinus4 + 4))
-			/* usedByCurrPicLtSps = */ _ = r.ReadFlag()
+		sps.NumLongTermRefPicsSps = byte(r.ReadExpGolomb())
+		for i := byte(0); i < sps.NumLongTermRefPicsSps; i++ {
+			sps.LtRefPicPocLsbSps = append(sps.LtRefPicPocLsbSps, uint32(r.Read(int(sps.Log2MaxPicOrderCntLsbMinus4+4))))
+			sps.UsedByCurrPicLtSpsFlag = append(sps.UsedByCurrPicLtSpsFlag, r.ReadFlag())
 		}
 	}
 	sps.SpsTemporalMvpEnabledFlag = r.ReadFlag()
 	sps.StrongIntraSmoothingEnabledFlag = r.ReadFlag()
 	sps.VUIParametersPresentFlag = r.ReadFlag()
 	if sps.VUIParametersPresentFlag {
-		sps.VUI = parseVUI(r)
+		sps.VUI = parseVUI(r, sps.MaxSubLayersMinus1)
 	}
 
 	return sps, r.AccError()
@@ -251,9 +261,67 @@ func (s *SPS) ImageSize() (width, height uint32) {
 	return width, height
 }
 
+// ChromaArrayType - ChromaFormatIDC or 0 if separate colour planes
+func (s *SPS) ChromaArrayType() byte {
+	if s.SeparateColourPlaneFlag {
+		return 0
+	}
+	return s.ChromaFormatIDC
+}
+
+// CtbSizeY - size of coding tree block in luma samples
+func (s *SPS) CtbSizeY() uint32 {
+	return 1 << (s.Log2MinLumaCodingBlockSizeMinus3 + 3 + s.Log2DiffMaxMinLumaCodingBlockSize)
+}
+
+// PicSizeInCtbsY - number of coding tree blocks in picture
+func (s *SPS) PicSizeInCtbsY() uint32 {
+	ctbSize := s.CtbSizeY()
+	widthInCtbs := (s.PicWidthInLumaSamples + ctbSize - 1) / ctbSize
+	heightInCtbs := (s.PicHeightInLumaSamples + ctbSize - 1) / ctbSize
+	return widthInCtbs * heightInCtbs
+}
+
+// parseProfileTierLevel - profile_tier_level() according to ISO/IEC 23008-2 Section 7.3.3
+func parseProfileTierLevel(r *bits.AccErrEBSPReader, profilePresentFlag bool, maxNumSubLayersMinus1 byte) ProfileTierLevel {
+	ptl := ProfileTierLevel{}
+	if profilePresentFlag {
+		ptl.GeneralProfileSpace = byte(r.Read(2))
+		ptl.GeneralTierFlag = r.ReadFlag()
+		ptl.GeneralProfileIDC = byte(r.Read(5))
+		ptl.GeneralProfileCompatibilityFlags = uint32(r.Read(32))
+		ptl.GeneralConstraintIndicatorFlags = uint64(r.Read(48))
+	}
+	ptl.GeneralLevelIDC = byte(r.Read(8))
+	if maxNumSubLayersMinus1 == 0 {
+		return ptl
+	}
+	ptl.SubLayers = make([]SubLayerProfileLevel, maxNumSubLayersMinus1)
+	for i := range ptl.SubLayers {
+		ptl.SubLayers[i].ProfilePresentFlag = r.ReadFlag()
+		ptl.SubLayers[i].LevelPresentFlag = r.ReadFlag()
+	}
+	for i := maxNumSubLayersMinus1; i < 8; i++ {
+		_ = r.Read(2) // reserved_zero_2bits
+	}
+	for i := range ptl.SubLayers {
+		sl := &ptl.SubLayers[i]
+		if sl.ProfilePresentFlag {
+			sl.ProfileSpace = byte(r.Read(2))
+			sl.TierFlag = r.ReadFlag()
+			sl.ProfileIDC = byte(r.Read(5))
+			sl.ProfileCompatibilityFlags = uint32(r.Read(32))
+			sl.ConstraintIndicatorFlags = uint64(r.Read(48))
+		}
+		if sl.LevelPresentFlag {
+			sl.LevelIDC = byte(r.Read(8))
+		}
+	}
+	return ptl
+}
+
 // parseVUI - parse VUI (Visual Usability Information)
-// if parseVUIBeyondAspectRatio is false, stop after AspectRatio has been parsed
-func parseVUI(r *bits.AccErrEBSPReader) *VUIParameters {
+func parseVUI(r *bits.AccErrEBSPReader, maxSubLayersMinus1 byte) *VUIParameters {
 	vui := &VUIParameters{}
 	aspectRatioInfoPresentFlag := r.ReadFlag()
 	if aspectRatioInfoPresentFlag {
@@ -309,8 +377,7 @@ func parseVUI(r *bits.AccErrEBSPReader) *VUIParameters {
 		}
 		vui.HrdParametersPresentFlag = r.ReadFlag()
 		if vui.HrdParametersPresentFlag {
-			//TODO.  Add HrdParameters parsing according to E.3.2 if needed
-			return vui
+			vui.HrdParameters = parseHrdParameters(r, true, maxSubLayersMinus1)
 		}
 	}
 	vui.BitstreamRestrictionFlag = r.ReadFlag()
@@ -336,7 +403,8 @@ func parseBitstreamRestrictions(r *bits.AccErrEBSPReader) *BitstreamRestrictions
 
 // ShortTermRPS - Short term Reference Picture Set
 type ShortTermRPS struct {
-	// Delta Picture Order Count
+	// Delta Picture Order Count steps. Each value is the distance to the previous picture in the list,
+	// going backwards (S0) or forwards (S1) from the current picture
 	DeltaPocS0      []uint32
 	DeltaPocS1      []uint32
 	UsedByCurrPicS0 []bool
@@ -348,8 +416,41 @@ type ShortTermRPS struct {
 
 const maxSTRefPics = 16
 
-// parseShortTermRPS - short-term refrence pictures et syntax from 7.3.7.
-// Focus is on reading/parsing beyond this structure in SPS (and possibly in slice header)
+// DeltaPocs - signed POC differences relative to current picture as DeltaPocS0 and DeltaPocS1 in 7.4.8
+func (s ShortTermRPS) DeltaPocs() (deltaPocS0, deltaPocS1 []int32) {
+	deltaPocS0 = make([]int32, len(s.DeltaPocS0))
+	prev := int32(0)
+	for i, d := range s.DeltaPocS0 {
+		prev -= int32(d)
+		deltaPocS0[i] = prev
+	}
+	deltaPocS1 = make([]int32, len(s.DeltaPocS1))
+	prev = 0
+	for i, d := range s.DeltaPocS1 {
+		prev += int32(d)
+		deltaPocS1[i] = prev
+	}
+	return deltaPocS0, deltaPocS1
+}
+
+// NumPicsUsedByCurr - number of pictures in the set used for reference by the current picture
+func (s ShortTermRPS) NumPicsUsedByCurr() int {
+	n := 0
+	for _, used := range s.UsedByCurrPicS0 {
+		if used {
+			n++
+		}
+	}
+	for _, used := range s.UsedByCurrPicS1 {
+		if used {
+			n++
+		}
+	}
+	return n
+}
+
+// parseShortTermRPS - short-term reference picture set syntax from 7.3.7 and semantics in 7.4.8.
+// idx == numSTRefPicSets signals that the set is parsed in a slice header.
 func parseShortTermRPS(r *bits.AccErrEBSPReader, idx, numSTRefPicSets byte, sps *SPS) ShortTermRPS {
 	stps := ShortTermRPS{}
 
@@ -366,22 +467,87 @@ func parseShortTermRPS(r *bits.AccErrEBSPReader, idx, numSTRefPicSets byte, sps
 		}
 		if deltaIdx > idx {
 			r.SetError(fmt.Errorf("deltaIdx > idx in parseShortTermRPS"))
+			return stps
 		}
-		/* deltaRpsSign */ _ = r.Read(1)
-		/* absDeltaRpsMinus1*/ _ = r.ReadExpGolomb()
-		//deltaRps := (1 - (deltaRpsSign << 1)) * (absDeltaRpsMinus1 + 1)
-		refIdx := idx - deltaIdx
-		numDeltaPocs := sps.ShortTermRefPicSets[refIdx].NumDeltaPocs
-		for j := byte(0); j < numDeltaPocs; j++ {
-			usedByCurrPicFlag := r.ReadFlag()
-			useDeltaFlag := false
-			if !usedByCurrPicFlag {
-				useDeltaFlag = r.ReadFlag()
+		deltaRpsSign := r.Read(1)
+		absDeltaRpsMinus1 := r.ReadExpGolomb()
+		deltaRps := (1 - 2*int32(deltaRpsSign)) * int32(absDeltaRpsMinus1+1)
+		refRPS := sps.ShortTermRefPicSets[idx-deltaIdx]
+		numDeltaPocs := int(refRPS.NumDeltaPocs)
+		usedByCurrPicFlags := make([]bool, numDeltaPocs+1)
+		useDeltaFlags := make([]bool, numDeltaPocs+1)
+		for j := 0; j <= numDeltaPocs; j++ {
+			usedByCurrPicFlags[j] = r.ReadFlag()
+			useDeltaFlags[j] = true
+			if !usedByCurrPicFlags[j] {
+				useDeltaFlags[j] = r.ReadFlag()
 			}
-			if usedByCurrPicFlag || useDeltaFlag {
-				stps.NumDeltaPocs++
+		}
+		refS0, refS1 := refRPS.DeltaPocs()
+		nNeg := len(refS0)
+		var s0, s1 []int32
+		var used0, used1 []bool
+		// Equation 7-61
+		for j := len(refS1) - 1; j >= 0; j-- {
+			dPoc := refS1[j] + deltaRps
+			if dPoc < 0 && useDeltaFlags[nNeg+j] {
+				s0 = append(s0, dPoc)
+				used0 = append(used0, usedByCurrPicFlags[nNeg+j])
+			}
+		}
+		if deltaRps < 0 && useDeltaFlags[numDeltaPocs] {
+			s0 = append(s0, deltaRps)
+			used0 = append(used0, usedByCurrPicFlags[numDeltaPocs])
+		}
+		for j := 0; j < nNeg; j++ {
+			dPoc := refS0[j] + deltaRps
+			if dPoc < 0 && useDeltaFlags[j] {
+				s0 = append(s0, dPoc)
+				used0 = append(used0, usedByCurrPicFlags[j])
+			}
+		}
+		// Equation 7-62
+		for j := nNeg - 1; j >= 0; j-- {
+			dPoc := refS0[j] + deltaRps
+			if dPoc > 0 && useDeltaFlags[j] {
+				s1 = append(s1, dPoc)
+				used1 = append(used1, usedByCurrPicFlags[j])
+			}
+		}
+		if deltaRps > 0 && useDeltaFlags[numDeltaPocs] {
+			s1 = append(s1, deltaRps)
+			used1 = append(used1, usedByCurrPicFlags[numDeltaPocs])
+		}
+		for j := 0; j < len(refS1); j++ {
+			dPoc := refS1[j] + deltaRps
+			if dPoc > 0 && useDeltaFlags[nNeg+j] {
+				s1 = append(s1, dPoc)
+				used1 = append(used1, usedByCurrPicFlags[nNeg+j])
 			}
 		}
+		stps.NumNegativePics = byte(len(s0))
+		stps.NumPositivePics = byte(len(s1))
+		stps.NumDeltaPocs = stps.NumNegativePics + stps.NumPositivePics
+		stps.DeltaPocS0 = make([]uint32, len(s0))
+		stps.UsedByCurrPicS0 = used0
+		if stps.UsedByCurrPicS0 == nil {
+			stps.UsedByCurrPicS0 = []bool{}
+		}
+		prev := int32(0)
+		for i, d := range s0 {
+			stps.DeltaPocS0[i] = uint32(prev - d)
+			prev = d
+		}
+		stps.DeltaPocS1 = make([]uint32, len(s1))
+		stps.UsedByCurrPicS1 = used1
+		if stps.UsedByCurrPicS1 == nil {
+			stps.UsedByCurrPicS1 = []bool{}
+		}
+		prev = 0
+		for i, d := range s1 {
+			stps.DeltaPocS1[i] = uint32(d - prev)
+			prev = d
+		}
 	} else {
 		stps.NumNegativePics = byte(r.ReadExpGolomb())
 		stps.NumPositivePics = byte(r.ReadExpGolomb())
@@ -407,33 +573,47 @@ func parseShortTermRPS(r *bits.AccErrEBSPReader, idx, numSTRefPicSets byte, sps
 	return stps
 }
 
-// readPastScalingList - read and parse all bits of scaling list, without storing values
-func readPastScalingList(r *bits.AccErrEBSPReader) {
-	for sizeId := 0; sizeId < 4; sizeId++ {
-		nrMatrixIds := 6
-		if sizeId == 3 {
-			nrMatrixIds = 2
+// ScalingListData - scaling_list_data() syntax from 7.3.4.
+// Entries are indexed by sizeID and matrixID. For sizeID 3, only matrixID 0 and 3 are used.
+type ScalingListData struct {
+	Entries [4][6]ScalingListEntry
+}
+
+// ScalingListEntry - one scaling list in ScalingListData
+type ScalingListEntry struct {
+	PredModeFlag      bool
+	PredMatrixIDDelta uint
+	DCCoefMinus8      int   // Only for sizeID > 1
+	DeltaCoefs        []int // scaling_list_delta_coef values
+}
+
+// parseScalingListData - parse scaling_list_data() according to 7.3.4
+func parseScalingListData(r *bits.AccErrEBSPReader) *ScalingListData {
+	sld := &ScalingListData{}
+	for sizeID := 0; sizeID < 4; sizeID++ {
+		matrixIDStep := 1
+		if sizeID == 3 {
+			matrixIDStep = 3
 		}
-		for matrixId := 0; matrixId < nrMatrixIds; matrixId++ {
-			flag := r.ReadFlag() // scaling_list_pred_mode_flag[sizeId][matrixId]
-			if !flag {
-				_ = r.ReadExpGolomb() // scaling_list_pred_matrix_id_delta[sizeId][matrixId]
-			} else {
-				// nextCoef = 8;
-				coefNum := (1 << (4 + (sizeId << 1)))
-				if coefNum > 64 {
-					coefNum = 64
-				}
-				if sizeId > 1 {
-					_ = r.ReadExpGolomb // scaling_list_dc_coef_minus8[sizeId − 2][matrixId]
-					// nextCoef = scaling_list_dc_coef_minus8[sizeId − 2][matrixId] + 8
-				}
-				for i := 0; i < coefNum; i++ {
-					_ = r.ReadExpGolomb() // scaling_list_delta_coef
-					// nextCoef = ( nextCoef + scaling_list_delta_coef + 256 ) % 256
-					// ScalingList[sizeId][matrixId][i] = nextCoef
-				}
+		for matrixID := 0; matrixID < 6; matrixID += matrixIDStep {
+			e := &sld.Entries[sizeID][matrixID]
+			e.PredModeFlag = r.ReadFlag()
+			if !e.PredModeFlag {
+				e.PredMatrixIDDelta = r.ReadExpGolomb()
+				continue
+			}
+			coefNum := 1 << (4 + (sizeID << 1))
+			if coefNum > 64 {
+				coefNum = 64
+			}
+			if sizeID > 1 {
+				e.DCCoefMinus8 = r.ReadSignedGolomb()
+			}
+			e.DeltaCoefs = make([]int, coefNum)
+			for i := 0; i < coefNum; i++ {
+				e.DeltaCoefs[i] = r.ReadSignedGolomb()
 			}
 		}
 	}
+	return sld
 }
@@ -0,0 +1,117 @@
+package hevc
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/jaypadia-frame/mp4ff/bits"
+)
+
+// VPS - HEVC Video Parameter Set
+// ISO/IEC 23008-2 Sec. 7.3.2.1
+type VPS struct {
+	VpsID                           byte
+	BaseLayerInternalFlag           bool
+	BaseLayerAvailableFlag          bool
+	MaxLayersMinus1                 byte
+	MaxSubLayersMinus1              byte
+	TemporalIDNestingFlag           bool
+	ProfileTierLevel                ProfileTierLevel
+	SubLayerOrderingInfoPresentFlag bool
+	SubLayeringOrderingInfos        []SubLayerOrderingInfo
+	MaxLayerID                      byte
+	NumLayerSetsMinus1              uint
+	LayerIDIncludedFlags            [][]bool // Layer sets 1 and up
+	TimingInfoPresentFlag           bool
+	NumUnitsInTick                  uint32
+	TimeScale                       uint32
+	PocProportionalToTimingFlag     bool
+	NumTicksPocDiffOneMinus1        uint
+	HrdLayerSets                    []VPSHrd
+	ExtensionFlag                   bool
+}
+
+// VPSHrd - HRD parameters for a layer set in VPS
+type VPSHrd struct {
+	HrdLayerSetIdx   uint
+	CprmsPresentFlag bool
+	HrdParameters    *HrdParameters
+}
+
+// ParseVPSNALUnit - Parse HEVC VPS NAL unit starting with NAL unit header
+func ParseVPSNALUnit(data []byte) (*VPS, error) {
+	vps := &VPS{}
+
+	rd := bytes.NewReader(data)
+	r := bits.NewAccErrEBSPReader(rd)
+	// Note! First two bytes are NALU Header
+
+	naluHdrBits := r.Read(16)
+	naluType := GetNaluType(byte(naluHdrBits >> 8))
+	if naluType != NALU_VPS {
+		return nil, fmt.Errorf("NALU type is %s not VPS", naluType)
+	}
+	vps.VpsID = byte(r.Read(4))
+	vps.BaseLayerInternalFlag = r.ReadFlag()
+	vps.BaseLayerAvailableFlag = r.ReadFlag()
+	vps.MaxLayersMinus1 = byte(r.Read(6))
+	vps.MaxSubLayersMinus1 = byte(r.Read(3))
+	vps.TemporalIDNestingFlag = r.ReadFlag()
+	reserved := r.Read(16)
+	if reserved != 0xffff && r.AccError() == nil {
+		return nil, fmt.Errorf("vps_reserved_0xffff_16bits is %04x", reserved)
+	}
+	vps.ProfileTierLevel = parseProfileTierLevel(r, true, vps.MaxSubLayersMinus1)
+	vps.SubLayerOrderingInfoPresentFlag = r.ReadFlag()
+	startValue := byte(0)
+	if !vps.SubLayerOrderingInfoPresentFlag {
+		startValue = vps.MaxSubLayersMinus1
+	}
+	for i := startValue; i <= vps.MaxSubLayersMinus1; i++ {
+		vps.SubLayeringOrderingInfos = append(
+			vps.SubLayeringOrderingInfos,
+			SubLayerOrderingInfo{
+				MaxDecPicBufferingMinus1: byte(r.ReadExpGolomb()),
+				MaxNumReorderPics:        byte(r.ReadExpGolomb()),
+				MaxLatencyIncreasePlus1:  byte(r.ReadExpGolomb()),
+			})
+	}
+	vps.MaxLayerID = byte(r.Read(6))
+	vps.NumLayerSetsMinus1 = r.ReadExpGolomb()
+	if vps.NumLayerSetsMinus1 > 1023 {
+		return nil, fmt.Errorf("vps_num_layer_sets_minus1 %d too big", vps.NumLayerSetsMinus1)
+	}
+	for i := uint(1); i <= vps.NumLayerSetsMinus1; i++ {
+		flags := make([]bool, vps.MaxLayerID+1)
+		for j := range flags {
+			flags[j] = r.ReadFlag()
+		}
+		vps.LayerIDIncludedFlags = append(vps.LayerIDIncludedFlags, flags)
+	}
+	vps.TimingInfoPresentFlag = r.ReadFlag()
+	if vps.TimingInfoPresentFlag {
+		vps.NumUnitsInTick = uint32(r.Read(32))
+		vps.TimeScale = uint32(r.Read(32))
+		vps.PocProportionalToTimingFlag = r.ReadFlag()
+		if vps.PocProportionalToTimingFlag {
+			vps.NumTicksPocDiffOneMinus1 = r.ReadExpGolomb()
+		}
+		numHrdParameters := r.ReadExpGolomb()
+		if numHrdParameters > vps.NumLayerSetsMinus1+1 {
+			return nil, fmt.Errorf("vps_num_hrd_parameters %d too big", numHrdParameters)
+		}
+		for i := uint(0); i < numHrdParameters; i++ {
+			vh := VPSHrd{}
+			vh.HrdLayerSetIdx = r.ReadExpGolomb()
+			vh.CprmsPresentFlag = true
+			if i > 0 {
+				vh.CprmsPresentFlag = r.ReadFlag()
+			}
+			vh.HrdParameters = parseHrdParameters(r, vh.CprmsPresentFlag, vps.MaxSubLayersMinus1)
+			vps.HrdLayerSets = append(vps.HrdLayerSets, vh)
+		}
+	}
+	vps.ExtensionFlag = r.ReadFlag()
+	// vps_extension data is not parsed
+	return vps, r.AccError()
+}
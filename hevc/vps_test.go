@@ -0,0 +1,46 @@
+package hevc
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestVPSParser(t *testing.T) {
+	vpsHex := "40010c01ffff01600000030090000003000003001e959409"
+	byteData, _ := hex.DecodeString(vpsHex)
+	wanted := VPS{
+		VpsID:                  0,
+		BaseLayerInternalFlag:  true,
+		BaseLayerAvailableFlag: true,
+		MaxLayersMinus1:        0,
+		MaxSubLayersMinus1:     0,
+		TemporalIDNestingFlag:  true,
+		ProfileTierLevel: ProfileTierLevel{
+			GeneralProfileIDC:                1,
+			GeneralProfileCompatibilityFlags: 0x60000000,
+			GeneralConstraintIndicatorFlags:  0x900000000000,
+			GeneralLevelIDC:                  30,
+		},
+		SubLayerOrderingInfoPresentFlag: true,
+		SubLayeringOrderingInfos: []SubLayerOrderingInfo{
+			{
+				MaxDecPicBufferingMinus1: 4,
+				MaxNumReorderPics:        2,
+				MaxLatencyIncreasePlus1:  4,
+			},
+		},
+	}
+	got, err := ParseVPSNALUnit(byteData)
+	if err != nil {
+		t.Fatal("Error parsing VPS")
+	}
+	if diff := deep.Equal(*got, wanted); diff != nil {
+		t.Errorf("Got VPS: %+v\n Diff is %v", got, diff)
+	}
+	_, err = ParseVPSNALUnit(byteData[:10])
+	if err == nil {
+		t.Error("expected error for truncated VPS")
+	}
+}
@@ -0,0 +1,11 @@
+/*
+Package hrd - verify AVC and HEVC tracks against the hypothetical reference decoder (HRD) buffer model.
+
+The coded picture buffer (CPB) is simulated with the sample sizes and decode times from stts or trun,
+and the bit rate and CPB size signalled in the HRD parameters of the SPS VUI. Underflows, where a sample
+has not fully arrived at its decode time, and overflows for constant bit rate streams are reported.
+
+The signalled bit rate and CPB size are also checked against the MaxBR and MaxCPB limits of the level.
+If the SPS has no HRD parameters, the CPB model uses the level limits instead.
+*/
+package hrd
@@ -0,0 +1,431 @@
+package hrd
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+
+	"github.com/jaypadia-frame/mp4ff/avc"
+	"github.com/jaypadia-frame/mp4ff/hevc"
+	"github.com/jaypadia-frame/mp4ff/mp4"
+	"github.com/jaypadia-frame/mp4ff/sei"
+)
+
+// Sources of CPB parameters
+const (
+	SourceNAL    = "NAL HRD"
+	SourceVCL    = "VCL HRD"
+	SourceLevel  = "level limits"
+	SourceConfig = "configuration"
+)
+
+// Params - parameters of the CPB model
+type Params struct {
+	Source       string // SourceNAL, SourceVCL, SourceLevel, or SourceConfig
+	BitRate      uint64 // Input bit rate in bits/s
+	CpbSize      uint64 // CPB size in bits
+	CBR          bool   // Constant bit rate. Otherwise, the input stops while the CPB is full
+	InitialDelay uint32 // Time from start of input to removal of the first access unit in 90 kHz ticks
+}
+
+// AccessUnit - size and decode time of a sample
+type AccessUnit struct {
+	Size       uint32 // Bytes
+	DecodeTime uint64 // In track timescale
+}
+
+// ViolationKind - CPB underflow or overflow
+type ViolationKind int
+
+// Kinds of violations
+const (
+	Underflow ViolationKind = iota
+	Overflow
+)
+
+func (k ViolationKind) String() string {
+	switch k {
+	case Underflow:
+		return "underflow"
+	case Overflow:
+		return "overflow"
+	}
+	return fmt.Sprintf("ViolationKind(%d)", int(k))
+}
+
+// Violation - CPB underflow or overflow at the removal time of an access unit
+type Violation struct {
+	Kind     ViolationKind
+	SampleNr int     // Starting at 1 in decode order
+	Time     float64 // Removal time in seconds
+	Fullness uint64  // Bits in the CPB just before removal
+	Size     uint64  // Bits of the access unit
+}
+
+// Simulation - result of running the CPB model
+type Simulation struct {
+	Violations  []Violation
+	MinFullness uint64 // Lowest CPB fullness in bits just after a removal
+	MaxFullness uint64 // Highest CPB fullness in bits just before a removal
+}
+
+// Simulate - run the CPB model for access units in decode order.
+//
+// Bits enter the CPB at Params.BitRate from time 0, and every access unit is removed instantaneously at its
+// removal time, which is InitialDelay for the first one, followed by the decode time differences.
+// An underflow occurs if an access unit has not fully arrived at its removal time, and the CPB is then emptied.
+// For CBR, an overflow occurs if the CPB is more than full. For VBR, the input is paused while the CPB is full.
+func Simulate(aus []AccessUnit, timescale uint32, p Params) (*Simulation, error) {
+	if timescale == 0 {
+		return nil, fmt.Errorf("timescale is 0")
+	}
+	if p.BitRate == 0 || p.CpbSize == 0 {
+		return nil, fmt.Errorf("bit rate %d and CPB size %d must be positive", p.BitRate, p.CpbSize)
+	}
+	sim := &Simulation{}
+	if len(aus) == 0 {
+		return sim, nil
+	}
+	bitRate := float64(p.BitRate)
+	cpbSize := float64(p.CpbSize)
+	fullness, now := 0.0, 0.0
+	firstDecodeTime := aus[0].DecodeTime
+	for i, au := range aus {
+		if au.DecodeTime < firstDecodeTime || (i > 0 && au.DecodeTime < aus[i-1].DecodeTime) {
+			return nil, fmt.Errorf("sample %d: decreasing decode time %d", i+1, au.DecodeTime)
+		}
+		removalTime := float64(p.InitialDelay)/90000 + float64(au.DecodeTime-firstDecodeTime)/float64(timescale)
+		fullness += bitRate * (removalTime - now)
+		now = removalTime
+		if fullness > cpbSize+0.5 && p.CBR {
+			sim.Violations = append(sim.Violations, Violation{Overflow, i + 1, now, roundBits(fullness), uint64(au.Size) * 8})
+		}
+		if fullness > cpbSize {
+			fullness = cpbSize
+		}
+		if i == 0 || roundBits(fullness) > sim.MaxFullness {
+			sim.MaxFullness = roundBits(fullness)
+		}
+		bits := float64(au.Size) * 8
+		if fullness+0.5 < bits {
+			sim.Violations = append(sim.Violations, Violation{Underflow, i + 1, now, roundBits(fullness), uint64(bits)})
+			fullness = 0
+		} else {
+			fullness -= bits
+			if fullness < 0 {
+				fullness = 0
+			}
+		}
+		if i == 0 || roundBits(fullness) < sim.MinFullness {
+			sim.MinFullness = roundBits(fullness)
+		}
+	}
+	return sim, nil
+}
+
+// roundBits - CPB fullness rounded to whole bits
+func roundBits(fullness float64) uint64 {
+	return uint64(math.Round(fullness))
+}
+
+// Config - options for VerifyTrack. Zero values give the parameters signalled in the SPS.
+type Config struct {
+	BitRate      uint64 // Bit rate in bits/s replacing the signalled one
+	CpbSize      uint64 // CPB size in bits replacing the signalled one
+	InitialDelay uint32 // Initial CPB removal delay in 90 kHz ticks
+	UseVCL       bool   // Use VCL HRD parameters and level limits instead of NAL
+}
+
+// Report - result of verifying a track
+type Report struct {
+	TrackID     uint32
+	Codec       string // avc or hevc
+	NrSamples   int
+	Duration    float64 // Seconds from first decode time to end of last sample
+	AvgBitRate  uint64  // Bits/s
+	Params      Params
+	Limits      LevelLimits
+	LevelErrors []string // Parameters or average bit rate above the limits of the level
+	Simulation
+}
+
+// VerifyTrack - check the samples of an AVC or HEVC track against the CPB model and level limits.
+//
+// The bit rate, CPB size, and CBR flag are taken from the NAL HRD parameters of the first SPS (VCL HRD with
+// cfg.UseVCL or if there is no NAL HRD), and from the level limits if the SPS has no HRD parameters.
+// The SPS comes from the avcC or hvcC box, or from the first sample.
+// The initial removal delay is taken from an AVC or HEVC buffering period SEI message in the first sample if present.
+// Otherwise, the CPB is full at the first removal.
+// Sample sizes include the NAL unit length fields, which approximates the start codes counted by the NAL HRD.
+// The samples are read with mp4.File.VisitTrackSamples, so the file is not changed. If the mdat data was
+// lazily decoded, the first sample is not available for parameter sets and SEI messages.
+func VerifyTrack(f *mp4.File, trackID uint32, cfg Config) (*Report, error) {
+	if f.Moov == nil {
+		return nil, fmt.Errorf("no moov box")
+	}
+	var trak *mp4.TrakBox
+	for _, t := range f.Moov.Traks {
+		if t.Tkhd.TrackID == trackID {
+			trak = t
+			break
+		}
+	}
+	if trak == nil {
+		return nil, fmt.Errorf("no track with ID %d", trackID)
+	}
+	stsd := trak.Mdia.Minf.Stbl.Stsd
+	rep := &Report{TrackID: trackID}
+	var lengthSize int
+	switch {
+	case stsd.AvcX != nil:
+		rep.Codec = "avc"
+		lengthSize = mp4.NaluLengthSize(stsd.AvcX)
+	case stsd.HvcX != nil:
+		rep.Codec = "hevc"
+		lengthSize = mp4.NaluLengthSize(stsd.HvcX)
+	default:
+		return nil, fmt.Errorf("track %d is not AVC or HEVC", trackID)
+	}
+	if lengthSize == 0 {
+		return nil, fmt.Errorf("track %d has no avcC or hvcC box", trackID)
+	}
+
+	var aus []AccessUnit
+	var firstSample []byte
+	var endTime uint64
+	var totalBytes uint64
+	err := f.VisitTrackSamples(trackID, nil, func(nr, sdi uint32, s *mp4.FullSample) error {
+		if nr == 1 {
+			firstSample = s.Data
+		}
+		aus = append(aus, AccessUnit{Size: s.Size, DecodeTime: s.DecodeTime})
+		endTime = s.DecodeTime + uint64(s.Dur)
+		totalBytes += uint64(s.Size)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(aus) == 0 {
+		return nil, fmt.Errorf("track %d has no samples", trackID)
+	}
+	timescale := trak.Mdia.Mdhd.Timescale
+	rep.NrSamples = len(aus)
+	rep.Duration = float64(endTime-aus[0].DecodeTime) / float64(timescale)
+	if rep.Duration > 0 {
+		rep.AvgBitRate = uint64(float64(totalBytes*8) / rep.Duration)
+	}
+
+	switch rep.Codec {
+	case "avc":
+		err = rep.setAVCParams(stsd.AvcX, firstSample, lengthSize, cfg)
+	case "hevc":
+		err = rep.setHEVCParams(stsd.HvcX, firstSample, lengthSize, cfg)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if cfg.BitRate != 0 || cfg.CpbSize != 0 {
+		rep.Params.Source = SourceConfig
+		if cfg.BitRate != 0 {
+			rep.Params.BitRate = cfg.BitRate
+		}
+		if cfg.CpbSize != 0 {
+			rep.Params.CpbSize = cfg.CpbSize
+		}
+	}
+	if cfg.InitialDelay != 0 {
+		rep.Params.InitialDelay = cfg.InitialDelay
+	}
+	if rep.Params.InitialDelay == 0 && rep.Params.BitRate > 0 {
+		rep.Params.InitialDelay = uint32(rep.Params.CpbSize * 90000 / rep.Params.BitRate)
+	}
+	rep.checkLevel(cfg.UseVCL)
+
+	sim, err := Simulate(aus, timescale, rep.Params)
+	if err != nil {
+		return nil, err
+	}
+	rep.Simulation = *sim
+	return rep, nil
+}
+
+// setAVCParams - set level limits and CPB parameters from the first AVC SPS
+func (r *Report) setAVCParams(entry *mp4.VisualSampleEntryBox, firstSample []byte, lengthSize int, cfg Config) error {
+	spsNalus := entry.AvcC.SPSnalus
+	if len(spsNalus) == 0 {
+		spsNalus, _ = avc.GetParameterSetsWithLengthSize(firstSample, lengthSize)
+	}
+	if len(spsNalus) == 0 {
+		return fmt.Errorf("no SPS found")
+	}
+	sps, err := avc.ParseSPSNALUnit(spsNalus[0], true)
+	if err != nil {
+		return err
+	}
+	if r.Limits, err = AVCLevelLimits(sps); err != nil {
+		return err
+	}
+	var hrd *avc.HrdParameters
+	if vui := sps.VUI; vui != nil {
+		switch {
+		case vui.NalHrdParametersPresentFlag && !(cfg.UseVCL && vui.VclHrdParametersPresentFlag):
+			hrd, r.Params.Source = vui.NalHrdParameters, SourceNAL
+		case vui.VclHrdParametersPresentFlag:
+			hrd, r.Params.Source = vui.VclHrdParameters, SourceVCL
+		}
+	}
+	if hrd == nil || len(hrd.CpbEntries) == 0 {
+		r.setLevelParams(cfg.UseVCL)
+		return nil
+	}
+	cpb := hrd.CpbEntries[0]
+	r.Params.BitRate = hrd.BitRate(cpb)
+	r.Params.CpbSize = hrd.CpbSize(cpb)
+	r.Params.CBR = cpb.CbrFlag
+	r.Params.InitialDelay = avcInitialDelay(firstSample, lengthSize, sps, r.Params.Source == SourceVCL)
+	return nil
+}
+
+// avcInitialDelay - initial_cpb_removal_delay of the first CPB in a buffering period SEI message, or 0
+func avcInitialDelay(sample []byte, lengthSize int, sps *avc.SPS, useVCL bool) uint32 {
+	nalus, err := avc.GetNalusFromSampleWithLengthSize(sample, lengthSize)
+	if err != nil {
+		return 0
+	}
+	for _, nalu := range nalus {
+		if len(nalu) < 2 || avc.GetNaluType(nalu[0]) != avc.NALU_SEI {
+			continue
+		}
+		seiData, err := sei.ExtractSEIData(bytes.NewReader(nalu[1:]))
+		if err != nil {
+			continue
+		}
+		for i := range seiData {
+			if seiData[i].Type() != sei.SEIBufferingPeriodType {
+				continue
+			}
+			msg, err := sei.DecodeAVCSEIMessage(&seiData[i], sps)
+			if err != nil {
+				continue
+			}
+			bp, ok := msg.(*sei.BufferingPeriodAVCSEI)
+			if !ok {
+				continue
+			}
+			cpbs := bp.NalInitialCpb
+			if useVCL {
+				cpbs = bp.VclInitialCpb
+			}
+			if len(cpbs) > 0 {
+				return cpbs[0].Delay
+			}
+		}
+	}
+	return 0
+}
+
+// setHEVCParams - set level limits and CPB parameters from the first HEVC SPS
+func (r *Report) setHEVCParams(entry *mp4.VisualSampleEntryBox, firstSample []byte, lengthSize int, cfg Config) error {
+	spsNalus := entry.HvcC.GetNalusForType(hevc.NALU_SPS)
+	if len(spsNalus) == 0 {
+		_, spsNalus, _ = hevc.GetParameterSetsWithLengthSize(firstSample, lengthSize)
+	}
+	if len(spsNalus) == 0 {
+		return fmt.Errorf("no SPS found")
+	}
+	sps, err := hevc.ParseSPSNALUnit(spsNalus[0])
+	if err != nil {
+		return err
+	}
+	if r.Limits, err = HEVCLevelLimits(sps); err != nil {
+		return err
+	}
+	var cpbs []hevc.CpbParameters
+	var hrd *hevc.HrdParameters
+	if sps.VUI != nil && sps.VUI.HrdParametersPresentFlag && len(sps.VUI.HrdParameters.SubLayers) > 0 {
+		hrd = sps.VUI.HrdParameters
+		subLayer := hrd.SubLayers[len(hrd.SubLayers)-1]
+		switch {
+		case hrd.NalHrdParametersPresentFlag && !(cfg.UseVCL && hrd.VclHrdParametersPresentFlag):
+			cpbs, r.Params.Source = subLayer.NalCpbs, SourceNAL
+		case hrd.VclHrdParametersPresentFlag:
+			cpbs, r.Params.Source = subLayer.VclCpbs, SourceVCL
+		}
+	}
+	if len(cpbs) == 0 {
+		r.setLevelParams(cfg.UseVCL)
+		return nil
+	}
+	r.Params.BitRate = hrd.BitRate(cpbs[0])
+	r.Params.CpbSize = hrd.CpbSize(cpbs[0])
+	r.Params.CBR = cpbs[0].CbrFlag
+	r.Params.InitialDelay = hevcInitialDelay(firstSample, lengthSize, sps, r.Params.Source == SourceVCL)
+	return nil
+}
+
+// hevcInitialDelay - initial_cpb_removal_delay of the first CPB in a buffering period SEI message, or 0
+func hevcInitialDelay(sample []byte, lengthSize int, sps *hevc.SPS, useVCL bool) uint32 {
+	nalus, err := avc.GetNalusFromSampleWithLengthSize(sample, lengthSize)
+	if err != nil {
+		return 0
+	}
+	for _, nalu := range nalus {
+		if len(nalu) < 3 || hevc.GetNaluType(nalu[0]) != hevc.NALU_SEI_PREFIX {
+			continue
+		}
+		seiData, err := sei.ExtractSEIData(bytes.NewReader(nalu[2:]))
+		if err != nil {
+			continue
+		}
+		for i := range seiData {
+			if seiData[i].Type() != sei.SEIBufferingPeriodType {
+				continue
+			}
+			msg, err := sei.DecodeHEVCSEIMessage(&seiData[i], sps)
+			if err != nil {
+				continue
+			}
+			bp, ok := msg.(*sei.BufferingPeriodHEVCSEI)
+			if !ok {
+				continue
+			}
+			cpbs := bp.NalInitialCpb
+			if useVCL {
+				cpbs = bp.VclInitialCpb
+			}
+			if len(cpbs) > 0 {
+				return cpbs[0].Delay
+			}
+		}
+	}
+	return 0
+}
+
+// setLevelParams - VBR CPB parameters from the level limits
+func (r *Report) setLevelParams(useVCL bool) {
+	r.Params = Params{Source: SourceLevel, BitRate: r.Limits.MaxBitRateNAL, CpbSize: r.Limits.MaxCpbSizeNAL}
+	if useVCL {
+		r.Params.BitRate, r.Params.CpbSize = r.Limits.MaxBitRateVCL, r.Limits.MaxCpbSizeVCL
+	}
+}
+
+// checkLevel - add level errors for bit rate and CPB size above the level limits
+func (r *Report) checkLevel(useVCL bool) {
+	maxBitRate, maxCpbSize, kind := r.Limits.MaxBitRateNAL, r.Limits.MaxCpbSizeNAL, "NAL"
+	if useVCL || r.Params.Source == SourceVCL {
+		maxBitRate, maxCpbSize, kind = r.Limits.MaxBitRateVCL, r.Limits.MaxCpbSizeVCL, "VCL"
+	}
+	if r.Params.BitRate > maxBitRate {
+		r.LevelErrors = append(r.LevelErrors, fmt.Sprintf("bit rate %d bits/s above %s MaxBR %d bits/s of level %s",
+			r.Params.BitRate, kind, maxBitRate, r.Limits.Level))
+	}
+	if r.Params.CpbSize > maxCpbSize {
+		r.LevelErrors = append(r.LevelErrors, fmt.Sprintf("CPB size %d bits above %s MaxCPB %d bits of level %s",
+			r.Params.CpbSize, kind, maxCpbSize, r.Limits.Level))
+	}
+	if r.AvgBitRate > maxBitRate {
+		r.LevelErrors = append(r.LevelErrors, fmt.Sprintf("average bit rate %d bits/s above %s MaxBR %d bits/s of level %s",
+			r.AvgBitRate, kind, maxBitRate, r.Limits.Level))
+	}
+}
@@ -0,0 +1,172 @@
+package hrd
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/jaypadia-frame/mp4ff/avc"
+	"github.com/jaypadia-frame/mp4ff/bits"
+	"github.com/jaypadia-frame/mp4ff/hevc"
+	"github.com/jaypadia-frame/mp4ff/mp4"
+	"github.com/jaypadia-frame/mp4ff/sei"
+)
+
+func TestSimulate(t *testing.T) {
+	// 25 frames/s at 1 Mbit/s is 40000 bits per frame
+	accessUnits := func(sizes ...uint32) []AccessUnit {
+		aus := make([]AccessUnit, len(sizes))
+		for i, size := range sizes {
+			aus[i] = AccessUnit{Size: size, DecodeTime: uint64(i) * 3600}
+		}
+		return aus
+	}
+	testCases := []struct {
+		desc   string
+		aus    []AccessUnit
+		params Params
+		want   []Violation
+	}{
+		{"constant size", accessUnits(5000, 5000, 5000, 5000),
+			Params{BitRate: 1000000, CpbSize: 500000, CBR: true, InitialDelay: 45000}, nil},
+		{"too big frame", accessUnits(5000, 70000, 5000),
+			Params{BitRate: 1000000, CpbSize: 500000, InitialDelay: 45000},
+			[]Violation{{Underflow, 2, 0.54, 500000, 560000}}},
+		{"CBR with too small frames", accessUnits(5000, 10, 10, 10),
+			Params{BitRate: 1000000, CpbSize: 500000, CBR: true, InitialDelay: 45000},
+			[]Violation{{Overflow, 3, 0.58, 539920, 80}, {Overflow, 4, 0.62, 539920, 80}}},
+		{"VBR with too small frames", accessUnits(5000, 10, 10, 10),
+			Params{BitRate: 1000000, CpbSize: 500000, InitialDelay: 45000}, nil},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			sim, err := Simulate(tc.aus, 90000, tc.params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(sim.Violations) != len(tc.want) {
+				t.Fatalf("got violations %v instead of %v", sim.Violations, tc.want)
+			}
+			for i, v := range sim.Violations {
+				w := tc.want[i]
+				if v.Kind != w.Kind || v.SampleNr != w.SampleNr || v.Time < w.Time-1e-9 || v.Time > w.Time+1e-9 ||
+					v.Fullness != w.Fullness || v.Size != w.Size {
+					t.Errorf("got violation %+v instead of %+v", v, w)
+				}
+			}
+		})
+	}
+	if _, err := Simulate(accessUnits(10), 90000, Params{CpbSize: 1000}); err == nil {
+		t.Error("no error for bit rate 0")
+	}
+}
+
+func TestLevelLimits(t *testing.T) {
+	limits, err := AVCLevelLimits(&avc.SPS{Profile: 66, ProfileCompatibility: 0x10, Level: 11})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limits.Level != "1b" || limits.MaxBitRateVCL != 128000 || limits.MaxCpbSizeNAL != 420000 {
+		t.Errorf("got %+v for level 1b", limits)
+	}
+	limits, err = AVCLevelLimits(&avc.SPS{Profile: 100, Level: 40})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limits.Level != "4" || limits.MaxBitRateNAL != 30000000 || limits.MaxCpbSizeVCL != 31250000 {
+		t.Errorf("got %+v for High profile level 4", limits)
+	}
+	sps := &hevc.SPS{ProfileTierLevel: hevc.ProfileTierLevel{GeneralTierFlag: true, GeneralLevelIDC: 153}}
+	limits, err = HEVCLevelLimits(sps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limits.Level != "5.1 High tier" || limits.MaxBitRateVCL != 160000000 || limits.MaxCpbSizeNAL != 176000000 {
+		t.Errorf("got %+v for level 5.1 High tier", limits)
+	}
+	if _, err := AVCLevelLimits(&avc.SPS{Level: 7}); err == nil {
+		t.Error("no error for unknown level")
+	}
+}
+
+func TestVerifyTrack(t *testing.T) {
+	fh, err := os.Open("../mp4/testdata/prog_8s.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fh.Close()
+	f, err := mp4.DecodeFile(fh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var before bytes.Buffer
+	if err := f.Encode(&before); err != nil {
+		t.Fatal(err)
+	}
+	rep, err := VerifyTrack(f, 2, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var after bytes.Buffer
+	if err := f.Encode(&after); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(before.Bytes(), after.Bytes()) {
+		t.Error("file changed by VerifyTrack")
+	}
+	if rep.Codec != "avc" || rep.NrSamples != 240 || rep.Duration != 8 || rep.Params.Source != SourceLevel ||
+		rep.Limits.Level != "3" || len(rep.LevelErrors) != 0 || len(rep.Violations) != 0 {
+		t.Errorf("got report %+v", rep)
+	}
+
+	rep, err = VerifyTrack(f, 2, Config{BitRate: rep.AvgBitRate / 2, CpbSize: 100000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Params.Source != SourceConfig || rep.Params.InitialDelay != uint32(100000*90000/rep.Params.BitRate) {
+		t.Errorf("got params %+v", rep.Params)
+	}
+	if len(rep.Violations) == 0 || rep.Violations[len(rep.Violations)-1].Kind != Underflow {
+		t.Errorf("no underflow for half the bit rate")
+	}
+	if _, err := VerifyTrack(f, 1, Config{}); err == nil {
+		t.Error("no error for audio track")
+	}
+}
+
+func TestHEVCInitialDelay(t *testing.T) {
+	sps := &hevc.SPS{
+		VUI: &hevc.VUIParameters{
+			HrdParametersPresentFlag: true,
+			HrdParameters: &hevc.HrdParameters{
+				NalHrdParametersPresentFlag:        true,
+				VclHrdParametersPresentFlag:        true,
+				InitialCpbRemovalDelayLengthMinus1: 23,
+				AuCpbRemovalDelayLengthMinus1:      15,
+				SubLayers:                          []hevc.SubLayerHrd{{CpbCntMinus1: 0}},
+			},
+		},
+	}
+	buf := bytes.Buffer{}
+	w := bits.NewWriter(&buf)
+	w.Write(1, 1)      // bp_seq_parameter_set_id ue(v) 0
+	w.Write(0, 1)      // irap_cpb_params_present_flag
+	w.Write(0, 1)      // concatenation_flag
+	w.Write(0, 16)     // au_cpb_removal_delay_delta_minus1
+	w.Write(45000, 24) // nal_initial_cpb_removal_delay
+	w.Write(0, 24)     // nal_initial_cpb_removal_offset
+	w.Write(30000, 24) // vcl_initial_cpb_removal_delay
+	w.Write(0, 24)     // vcl_initial_cpb_removal_offset
+	w.Flush()
+	nalu, err := sei.CreateSEINALU("hevc", []sei.SEIMessage{sei.NewSEIData(sei.SEIBufferingPeriodType, buf.Bytes())})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sample := append([]byte{0, 0, 0, byte(len(nalu))}, nalu...)
+	if got := hevcInitialDelay(sample, 4, sps, false); got != 45000 {
+		t.Errorf("got NAL initial delay %d instead of 45000", got)
+	}
+	if got := hevcInitialDelay(sample, 4, sps, true); got != 30000 {
+		t.Errorf("got VCL initial delay %d instead of 30000", got)
+	}
+}
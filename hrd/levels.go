@@ -0,0 +1,127 @@
+package hrd
+
+import (
+	"fmt"
+
+	"github.com/jaypadia-frame/mp4ff/avc"
+	"github.com/jaypadia-frame/mp4ff/hevc"
+)
+
+// LevelLimits - max bit rate and CPB size of a level for NAL HRD and VCL HRD
+type LevelLimits struct {
+	Level         string
+	MaxBitRateNAL uint64 // bits/s
+	MaxCpbSizeNAL uint64 // bits
+	MaxBitRateVCL uint64 // bits/s
+	MaxCpbSizeVCL uint64 // bits
+}
+
+// avcLevel - MaxBR in 1000 bits/s and MaxCPB in 1000 bits for an AVC level (ISO/IEC 14496-10 Table A-1)
+type avcLevel struct {
+	name          string
+	maxBR, maxCPB uint64
+}
+
+var avcLevels = map[uint]avcLevel{
+	9:  {"1b", 128, 350},
+	10: {"1", 64, 175},
+	11: {"1.1", 192, 500},
+	12: {"1.2", 384, 1000},
+	13: {"1.3", 768, 2000},
+	20: {"2", 2000, 2000},
+	21: {"2.1", 4000, 4000},
+	22: {"2.2", 4000, 4000},
+	30: {"3", 10000, 10000},
+	31: {"3.1", 14000, 14000},
+	32: {"3.2", 20000, 20000},
+	40: {"4", 20000, 25000},
+	41: {"4.1", 50000, 62500},
+	42: {"4.2", 50000, 62500},
+	50: {"5", 135000, 135000},
+	51: {"5.1", 240000, 240000},
+	52: {"5.2", 240000, 240000},
+	60: {"6", 240000, 240000},
+	61: {"6.1", 480000, 240000},
+	62: {"6.2", 800000, 240000},
+}
+
+// avcFactors - cpbBrVclFactor and cpbBrNalFactor for AVC profile (ISO/IEC 14496-10 Table A-2)
+func avcFactors(profile uint) (vcl, nal uint64) {
+	switch profile {
+	case 100:
+		return 1250, 1500
+	case 110:
+		return 3000, 3600
+	case 122, 244, 44:
+		return 4000, 4800
+	}
+	return 1000, 1200
+}
+
+// AVCLevelLimits - limits for the profile and level of an AVC SPS.
+// Level 1b is signalled as level_idc 11 with constraint_set3_flag for Baseline, Main, and Extended profile.
+func AVCLevelLimits(sps *avc.SPS) (LevelLimits, error) {
+	levelIDC := sps.Level
+	constraintSet3 := sps.ProfileCompatibility&0x10 != 0
+	if levelIDC == 11 && constraintSet3 && (sps.Profile == 66 || sps.Profile == 77 || sps.Profile == 88) {
+		levelIDC = 9
+	}
+	level, ok := avcLevels[levelIDC]
+	if !ok {
+		return LevelLimits{}, fmt.Errorf("unknown AVC level_idc %d", sps.Level)
+	}
+	vcl, nal := avcFactors(sps.Profile)
+	return LevelLimits{
+		Level:         level.name,
+		MaxBitRateNAL: level.maxBR * nal,
+		MaxCpbSizeNAL: level.maxCPB * nal,
+		MaxBitRateVCL: level.maxBR * vcl,
+		MaxCpbSizeVCL: level.maxCPB * vcl,
+	}, nil
+}
+
+// hevcLevel - MaxCPB and MaxBR for Main and High tier in 1000 bits or 1000 bits/s (ISO/IEC 23008-2 Table A.8).
+// High tier is not defined below level 4, and gets the Main tier values.
+type hevcLevel struct {
+	name                                         string
+	maxCPBMain, maxCPBHigh, maxBRMain, maxBRHigh uint64
+}
+
+var hevcLevels = map[byte]hevcLevel{
+	30:  {"1", 350, 350, 128, 128},
+	60:  {"2", 1500, 1500, 1500, 1500},
+	63:  {"2.1", 3000, 3000, 3000, 3000},
+	90:  {"3", 6000, 6000, 6000, 6000},
+	93:  {"3.1", 10000, 10000, 10000, 10000},
+	120: {"4", 12000, 30000, 12000, 30000},
+	123: {"4.1", 20000, 50000, 20000, 50000},
+	150: {"5", 25000, 100000, 25000, 100000},
+	153: {"5.1", 40000, 160000, 40000, 160000},
+	156: {"5.2", 60000, 240000, 60000, 240000},
+	180: {"6", 60000, 240000, 60000, 240000},
+	183: {"6.1", 120000, 480000, 120000, 480000},
+	186: {"6.2", 240000, 800000, 240000, 800000},
+}
+
+// HEVCLevelLimits - limits for the tier and level of an HEVC SPS.
+// The CpbVclFactor 1000 and CpbNalFactor 1100 of the Main, Main 10, and Main Still Picture profiles are used
+// for all profiles.
+func HEVCLevelLimits(sps *hevc.SPS) (LevelLimits, error) {
+	ptl := sps.ProfileTierLevel
+	level, ok := hevcLevels[ptl.GeneralLevelIDC]
+	if !ok {
+		return LevelLimits{}, fmt.Errorf("unknown HEVC general_level_idc %d", ptl.GeneralLevelIDC)
+	}
+	maxBR, maxCPB, tier := level.maxBRMain, level.maxCPBMain, "Main"
+	if ptl.GeneralTierFlag {
+		maxBR, maxCPB, tier = level.maxBRHigh, level.maxCPBHigh, "High"
+	}
+	const vcl, nal = 1000, 1100
+	return LevelLimits{
+		Level:         fmt.Sprintf("%s %s tier", level.name, tier),
+		MaxBitRateNAL: maxBR * nal,
+		MaxCpbSizeNAL: maxCPB * nal,
+		MaxBitRateVCL: maxBR * vcl,
+		MaxCpbSizeVCL: maxCPB * vcl,
+	}, nil
+}
@@ -0,0 +1,23 @@
+// Package mp4test - helpers for tests of packages that work on mp4 files.
+package mp4test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/jaypadia-frame/mp4ff/mp4"
+)
+
+// EncodeDecode - encode f and decode the result, as when writing and reading a file
+func EncodeDecode(t *testing.T, f *mp4.File) *mp4.File {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := f.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out, err := mp4.DecodeFile(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
@@ -0,0 +1,12 @@
+/*
+Package mp3 - import MPEG-1/2 layer 1-3 audio (like MP3 files) into MP4 tracks.
+
+DecodeFrameHeader parses the 4-byte frame header with version, layer, bitrate, sampling frequency and channel mode.
+Xing/Info headers, including the LAME extension with encoder delay and padding, and VBRI headers
+in the first frame are also parsed.
+
+Import splits a stream into one sample per frame. The track is signalled as mp4a with object type indication
+0x6B (MPEG-1) or 0x69 (MPEG-2) in the esds box, or optionally as MPEG-4 audio object type 32-34.
+The LAME encoder delay and padding are translated into an edit list.
+*/
+package mp3
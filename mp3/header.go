@@ -0,0 +1,297 @@
+package mp3
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// MPEG audio versions as signalled in the frame header
+const (
+	MPEG25 = 0 // Unofficial MPEG-2.5 extension for low sampling frequencies
+	MPEG2  = 2 // ISO/IEC 13818-3 low sampling frequencies
+	MPEG1  = 3 // ISO/IEC 11172-3
+)
+
+// Channel modes
+const (
+	ChannelModeStereo      = 0
+	ChannelModeJointStereo = 1
+	ChannelModeDualChannel = 2
+	ChannelModeMono        = 3
+)
+
+// HeaderSize - size of frame header excluding CRC
+const HeaderSize = 4
+
+// bitrates in kbps for bitrate_index 1-14 of MPEG-1 layer 1, 2, 3 and MPEG-2/2.5 layer 1, and 2 and 3
+var bitrates = [5][15]int{
+	{0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448},
+	{0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384},
+	{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320},
+	{0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256},
+	{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+}
+
+// sampleRates for version and sampling_frequency index
+var sampleRates = map[byte][3]int{
+	MPEG1:  {44100, 48000, 32000},
+	MPEG2:  {22050, 24000, 16000},
+	MPEG25: {11025, 12000, 8000},
+}
+
+// FrameHeader - MPEG-1/2 audio frame header (ISO/IEC 11172-3 2.4.1.3)
+type FrameHeader struct {
+	Version                byte // MPEG1, MPEG2, or MPEG25
+	Layer                  byte // 1, 2, or 3
+	ProtectionAbsent       bool // No CRC after header
+	BitrateIndex           byte
+	SamplingFrequencyIndex byte
+	Padding                bool
+	Private                bool
+	ChannelMode            byte
+	ModeExtension          byte
+	Copyright              bool
+	Original               bool
+	Emphasis               byte
+}
+
+// DecodeFrameHeader - decode the frame header at the start of data.
+// Free format (bitrate index 0) is not supported.
+func DecodeFrameHeader(data []byte) (*FrameHeader, error) {
+	if len(data) < HeaderSize {
+		return nil, fmt.Errorf("frame header: need %d bytes", HeaderSize)
+	}
+	v := binary.BigEndian.Uint32(data)
+	if v>>21 != 0x7ff {
+		return nil, fmt.Errorf("no MPEG audio syncword")
+	}
+	h := &FrameHeader{
+		Version:                byte(v>>19) & 0x03,
+		Layer:                  4 - byte(v>>17)&0x03,
+		ProtectionAbsent:       v>>16&0x01 == 1,
+		BitrateIndex:           byte(v>>12) & 0x0f,
+		SamplingFrequencyIndex: byte(v>>10) & 0x03,
+		Padding:                v>>9&0x01 == 1,
+		Private:                v>>8&0x01 == 1,
+		ChannelMode:            byte(v>>6) & 0x03,
+		ModeExtension:          byte(v>>4) & 0x03,
+		Copyright:              v>>3&0x01 == 1,
+		Original:               v>>2&0x01 == 1,
+		Emphasis:               byte(v) & 0x03,
+	}
+	switch {
+	case h.Version == 1:
+		return nil, fmt.Errorf("reserved version")
+	case h.Layer == 4:
+		return nil, fmt.Errorf("reserved layer")
+	case h.BitrateIndex == 0:
+		return nil, fmt.Errorf("free format not supported")
+	case h.BitrateIndex == 15:
+		return nil, fmt.Errorf("bad bitrate index 15")
+	case h.SamplingFrequencyIndex == 3:
+		return nil, fmt.Errorf("reserved sampling frequency index")
+	}
+	return h, nil
+}
+
+// Encode - frame header as 4 bytes
+func (h *FrameHeader) Encode() []byte {
+	v := uint32(0x7ff)<<21 | uint32(h.Version)<<19 | uint32(4-h.Layer)<<17 | flag(h.ProtectionAbsent)<<16 |
+		uint32(h.BitrateIndex)<<12 | uint32(h.SamplingFrequencyIndex)<<10 | flag(h.Padding)<<9 |
+		flag(h.Private)<<8 | uint32(h.ChannelMode)<<6 | uint32(h.ModeExtension)<<4 |
+		flag(h.Copyright)<<3 | flag(h.Original)<<2 | uint32(h.Emphasis)
+	data := make([]byte, HeaderSize)
+	binary.BigEndian.PutUint32(data, v)
+	return data
+}
+
+func flag(f bool) uint32 {
+	if f {
+		return 1
+	}
+	return 0
+}
+
+// Bitrate - bitrate in bits per second
+func (h *FrameHeader) Bitrate() int {
+	table := int(h.Layer) - 1
+	if h.Version != MPEG1 {
+		table = 3
+		if h.Layer > 1 {
+			table = 4
+		}
+	}
+	return 1000 * bitrates[table][h.BitrateIndex]
+}
+
+// SampleRate - sampling frequency in Hz
+func (h *FrameHeader) SampleRate() int {
+	return sampleRates[h.Version][h.SamplingFrequencyIndex]
+}
+
+// SamplesPerFrame - number of audio samples per channel in the frame
+func (h *FrameHeader) SamplesPerFrame() int {
+	switch {
+	case h.Layer == 1:
+		return 384
+	case h.Layer == 3 && h.Version != MPEG1:
+		return 576
+	default:
+		return 1152
+	}
+}
+
+// FrameSize - size of frame in bytes including header
+func (h *FrameHeader) FrameSize() int {
+	if h.Layer == 1 {
+		size := 12 * h.Bitrate() / h.SampleRate()
+		if h.Padding {
+			size++
+		}
+		return 4 * size
+	}
+	size := h.SamplesPerFrame() / 8 * h.Bitrate() / h.SampleRate()
+	if h.Padding {
+		size++
+	}
+	return size
+}
+
+// NrChannels - 1 for mono and 2 otherwise
+func (h *FrameHeader) NrChannels() int {
+	if h.ChannelMode == ChannelModeMono {
+		return 1
+	}
+	return 2
+}
+
+// sideInfoSize - size of layer 3 side information
+func (h *FrameHeader) sideInfoSize() int {
+	switch {
+	case h.Version == MPEG1 && h.ChannelMode == ChannelModeMono:
+		return 17
+	case h.Version == MPEG1:
+		return 32
+	case h.ChannelMode == ChannelModeMono:
+		return 9
+	default:
+		return 17
+	}
+}
+
+// Xing header flags
+const (
+	XingFramesFlag  = 0x01
+	XingBytesFlag   = 0x02
+	XingTOCFlag     = 0x04
+	XingQualityFlag = 0x08
+)
+
+// decoderDelay - delay of the MDCT synthesis of layer 3 decoders added to the LAME encoder delay
+const decoderDelay = 529
+
+// XingHeader - Xing (VBR) or Info (CBR) header in the first frame of a layer 3 stream
+type XingHeader struct {
+	Info     bool // "Info" instead of "Xing" tag
+	Flags    uint32
+	NrFrames uint32 // Number of frames excluding the header frame if XingFramesFlag
+	NrBytes  uint32 // File size if XingBytesFlag
+	TOC      []byte // 100 seek points if XingTOCFlag
+	Quality  uint32 // If XingQualityFlag
+	LAME     *LAMETag
+}
+
+// LAMETag - the parts of the LAME extension of a Xing header used for gapless playback
+type LAMETag struct {
+	Encoder      string // 9-byte encoder version like LAME3.100
+	EncoderDelay int    // Samples added at start by encoder
+	Padding      int    // Samples added at end
+}
+
+// DecodeXingHeader - decode Xing or Info header in frame with header h. Return nil if not present
+func DecodeXingHeader(frame []byte, h *FrameHeader) *XingHeader {
+	if h.Layer != 3 {
+		return nil
+	}
+	pos := HeaderSize + h.sideInfoSize()
+	if !h.ProtectionAbsent {
+		pos += 2
+	}
+	if len(frame) < pos+8 {
+		return nil
+	}
+	tag := string(frame[pos : pos+4])
+	if tag != "Xing" && tag != "Info" {
+		return nil
+	}
+	x := &XingHeader{Info: tag == "Info", Flags: binary.BigEndian.Uint32(frame[pos+4:])}
+	pos += 8
+	need := pos
+	for _, f := range []struct {
+		flag uint32
+		size int
+	}{{XingFramesFlag, 4}, {XingBytesFlag, 4}, {XingTOCFlag, 100}, {XingQualityFlag, 4}} {
+		if x.Flags&f.flag != 0 {
+			need += f.size
+		}
+	}
+	if len(frame) < need {
+		return nil
+	}
+	if x.Flags&XingFramesFlag != 0 {
+		x.NrFrames = binary.BigEndian.Uint32(frame[pos:])
+		pos += 4
+	}
+	if x.Flags&XingBytesFlag != 0 {
+		x.NrBytes = binary.BigEndian.Uint32(frame[pos:])
+		pos += 4
+	}
+	if x.Flags&XingTOCFlag != 0 {
+		x.TOC = frame[pos : pos+100]
+		pos += 100
+	}
+	if x.Flags&XingQualityFlag != 0 {
+		x.Quality = binary.BigEndian.Uint32(frame[pos:])
+		pos += 4
+	}
+	// LAME extension with encoder delay and padding 21 bytes after the encoder string
+	if len(frame) >= pos+24 {
+		encoder := string(frame[pos : pos+9])
+		if len(encoder) >= 4 && (encoder[:4] == "LAME" || encoder[:4] == "Lavc" || encoder[:4] == "Lavf") {
+			d := frame[pos+21 : pos+24]
+			x.LAME = &LAMETag{
+				Encoder:      encoder,
+				EncoderDelay: int(d[0])<<4 | int(d[1])>>4,
+				Padding:      int(d[1]&0x0f)<<8 | int(d[2]),
+			}
+		}
+	}
+	return x
+}
+
+// VBRIHeader - Fraunhofer VBRI header in the first frame of a layer 3 stream
+type VBRIHeader struct {
+	Version  uint16
+	Delay    uint16
+	Quality  uint16
+	NrBytes  uint32
+	NrFrames uint32
+}
+
+// vbriOffset - position of VBRI tag in frame
+const vbriOffset = HeaderSize + 32
+
+// DecodeVBRIHeader - decode VBRI header in frame with header h. Return nil if not present
+func DecodeVBRIHeader(frame []byte, h *FrameHeader) *VBRIHeader {
+	if h.Layer != 3 || len(frame) < vbriOffset+18 || string(frame[vbriOffset:vbriOffset+4]) != "VBRI" {
+		return nil
+	}
+	d := frame[vbriOffset+4:]
+	return &VBRIHeader{
+		Version:  binary.BigEndian.Uint16(d[0:]),
+		Delay:    binary.BigEndian.Uint16(d[2:]),
+		Quality:  binary.BigEndian.Uint16(d[4:]),
+		NrBytes:  binary.BigEndian.Uint32(d[6:]),
+		NrFrames: binary.BigEndian.Uint32(d[10:]),
+	}
+}
@@ -0,0 +1,255 @@
+package mp3
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+
+	"github.com/jaypadia-frame/mp4ff/aac"
+	"github.com/jaypadia-frame/mp4ff/mp4"
+)
+
+// Config - configuration for importing an MPEG audio stream
+type Config struct {
+	Language string // Default is und
+	// MPEG4 signals the track as MPEG-4 audio (mp4a.40.34 for layer 3) with an AudioSpecificConfig
+	// instead of object type indication 0x6B (MPEG-1) or 0x69 (MPEG-2)
+	MPEG4 bool
+}
+
+// Track - MPEG-1/2 layer 1-3 audio track imported from an .mp3 (or .mp2) stream
+type Track struct {
+	Header     *FrameHeader // Header of the first audio frame
+	Xing       *XingHeader  // Xing or Info header frame, not included in samples
+	VBRI       *VBRIHeader  // VBRI header frame, not included in samples
+	MPEG4      bool
+	Language   string
+	SkipStart  int // Samples to skip at start (LAME encoder delay plus decoder delay)
+	SkipEnd    int // Samples to skip at end (LAME padding minus decoder delay)
+	MaxBitrate int
+	AvgBitrate int
+	Samples    []mp4.FullSample // One frame including header per sample with durations in sampling frequency timescale
+}
+
+// Import - split an MPEG audio stream into frames. ID3v2 tags at the start and an ID3v1 tag at the end are skipped.
+// All frames must have the same version, layer, sampling frequency and number of channels.
+// Encoder delay and padding in a LAME tag are stored in SkipStart and SkipEnd, and result in an edit list.
+// The sample data refers to the stream.
+func Import(stream []byte, cfg Config) (*Track, error) {
+	t := &Track{Language: cfg.Language, MPEG4: cfg.MPEG4}
+	if t.Language == "" {
+		t.Language = "und"
+	}
+	pos := skipID3v2(stream)
+	end := len(stream)
+	if end-pos >= 128 && string(stream[end-128:end-125]) == "TAG" {
+		end -= 128 // ID3v1
+	}
+	var decodeTime uint64
+	var nrBytes int
+	for pos < end {
+		h, err := DecodeFrameHeader(stream[pos:end])
+		if err != nil {
+			return nil, fmt.Errorf("frame at byte %d: %w", pos, err)
+		}
+		size := h.FrameSize()
+		if pos+size > end {
+			return nil, fmt.Errorf("frame at byte %d: truncated", pos)
+		}
+		frame := stream[pos : pos+size]
+		if t.Header == nil && t.Xing == nil && t.VBRI == nil {
+			t.Xing = DecodeXingHeader(frame, h)
+			if t.Xing == nil {
+				t.VBRI = DecodeVBRIHeader(frame, h)
+			}
+			if t.Xing != nil || t.VBRI != nil {
+				pos += size
+				continue
+			}
+		}
+		if t.Header == nil {
+			t.Header = h
+		} else if h.Version != t.Header.Version || h.Layer != t.Header.Layer ||
+			h.SamplingFrequencyIndex != t.Header.SamplingFrequencyIndex || h.NrChannels() != t.Header.NrChannels() {
+			return nil, fmt.Errorf("frame at byte %d: audio configuration changed", pos)
+		}
+		if br := h.Bitrate(); br > t.MaxBitrate {
+			t.MaxBitrate = br
+		}
+		dur := uint32(h.SamplesPerFrame())
+		t.Samples = append(t.Samples, mp4.FullSample{
+			Sample:     mp4.NewSample(mp4.SyncSampleFlags, dur, uint32(size), 0),
+			DecodeTime: decodeTime,
+			Data:       frame,
+		})
+		decodeTime += uint64(dur)
+		nrBytes += size
+		pos += size
+	}
+	if t.Header == nil {
+		return nil, fmt.Errorf("no audio frames found")
+	}
+	t.AvgBitrate = int(uint64(nrBytes) * 8 * uint64(t.Header.SampleRate()) / decodeTime)
+	if t.Xing != nil && t.Xing.LAME != nil {
+		t.SkipStart = t.Xing.LAME.EncoderDelay + decoderDelay
+		if t.Xing.LAME.Padding > decoderDelay {
+			t.SkipEnd = t.Xing.LAME.Padding - decoderDelay
+		}
+		if uint64(t.SkipStart+t.SkipEnd) >= decodeTime {
+			t.SkipStart, t.SkipEnd = 0, 0
+		}
+	}
+	return t, nil
+}
+
+// skipID3v2 - size of ID3v2 tags at start of stream
+func skipID3v2(stream []byte) int {
+	pos := 0
+	for len(stream)-pos >= 10 && string(stream[pos:pos+3]) == "ID3" {
+		h := stream[pos:]
+		size := 10 + (int(h[6]&0x7f)<<21 | int(h[7]&0x7f)<<14 | int(h[8]&0x7f)<<7 | int(h[9]&0x7f)) // syncsafe
+		if h[5]&0x10 != 0 {
+			size += 10 // footer
+		}
+		pos += size
+	}
+	if pos > len(stream) {
+		return len(stream)
+	}
+	return pos
+}
+
+// ObjectType - object type indication of the DecoderConfigDescriptor
+func (t *Track) ObjectType() byte {
+	switch {
+	case t.MPEG4:
+		return mp4.ObjectTypeMPEG4Audio
+	case t.Header.Version == MPEG1:
+		return mp4.ObjectTypeMPEG1Audio
+	default:
+		return mp4.ObjectTypeMPEG2Audio
+	}
+}
+
+// AudioSpecificConfig - MPEG-4 configuration with audio object type 32, 33, or 34 for layer 1, 2, or 3
+func (t *Track) AudioSpecificConfig() *aac.AudioSpecificConfig {
+	return &aac.AudioSpecificConfig{
+		ObjectType:           aac.MPEGLayer1 - 1 + t.Header.Layer,
+		ChannelConfiguration: byte(t.Header.NrChannels()),
+		SamplingFrequency:    t.Header.SampleRate(),
+	}
+}
+
+// CodecString - RFC 6381 codec string, like mp4a.6B or mp4a.40.34
+func (t *Track) CodecString() string {
+	if t.MPEG4 {
+		return fmt.Sprintf("mp4a.40.%d", aac.MPEGLayer1-1+t.Header.Layer)
+	}
+	return fmt.Sprintf("mp4a.%02X", t.ObjectType())
+}
+
+// esds - EsdsBox with object type, buffer size and bitrates
+func (t *Track) esds() (*mp4.EsdsBox, error) {
+	var esds *mp4.EsdsBox
+	if t.MPEG4 {
+		buf := &bytes.Buffer{}
+		if err := t.AudioSpecificConfig().Encode(buf); err != nil {
+			return nil, err
+		}
+		esds = mp4.CreateEsdsBox(buf.Bytes())
+	} else {
+		esds = mp4.CreateEsdsBox(nil)
+		esds.DecConfigDescriptor.ObjectType = t.ObjectType()
+		esds.DecConfigDescriptor.DecSpecificInfo.DecConfig = nil // No DecSpecificInfo
+	}
+	var maxSize uint32
+	for _, s := range t.Samples {
+		if s.Size > maxSize {
+			maxSize = s.Size
+		}
+	}
+	esds.DecConfigDescriptor.BufferSizeDB = maxSize
+	esds.DecConfigDescriptor.MaxBitrate = uint32(t.MaxBitrate)
+	esds.DecConfigDescriptor.AvgBitrate = uint32(t.AvgBitrate)
+	return esds, nil
+}
+
+// CreateInit - create an init segment with the audio track
+func (t *Track) CreateInit() (*mp4.InitSegment, error) {
+	init := mp4.CreateEmptyInit()
+	sampleRate := t.Header.SampleRate()
+	init.AddEmptyTrack(uint32(sampleRate), "audio", t.Language)
+	esds, err := t.esds()
+	if err != nil {
+		return nil, err
+	}
+	mp4a := mp4.CreateAudioSampleEntryBox("mp4a", uint16(t.Header.NrChannels()), 16, uint16(sampleRate), esds)
+	init.Moov.Trak.Mdia.Minf.Stbl.Stsd.AddChild(mp4a)
+	return init, nil
+}
+
+// CreateProgressiveFile - create a progressive file with all samples in one chunk.
+// An edit list removes SkipStart and SkipEnd samples, which must be shorter than the track.
+func (t *Track) CreateProgressiveFile() (*mp4.File, error) {
+	init, err := t.CreateInit()
+	if err != nil {
+		return nil, err
+	}
+	var mediaDur uint64
+	for _, s := range t.Samples {
+		mediaDur += uint64(s.Dur)
+	}
+	if t.SkipStart < 0 || t.SkipEnd < 0 || uint64(t.SkipStart+t.SkipEnd) >= mediaDur {
+		return nil, fmt.Errorf("skip start %d and end %d do not fit in duration %d", t.SkipStart, t.SkipEnd, mediaDur)
+	}
+	var segmentDur uint64
+	if t.SkipStart > 0 || t.SkipEnd > 0 {
+		segmentDur = (mediaDur - uint64(t.SkipStart+t.SkipEnd)) * uint64(init.Moov.Mvhd.Timescale) /
+			uint64(t.Header.SampleRate())
+		addEditList(init.Moov.Trak, segmentDur, int64(t.SkipStart))
+	}
+	f, err := mp4.CreateProgressiveFile(init, [][]mp4.FullSample{t.Samples})
+	if err != nil {
+		return nil, err
+	}
+	if segmentDur > 0 {
+		// The presentation duration is given by the edit list. It is shorter, so the box sizes do not change
+		f.Moov.Trak.Tkhd.Duration = segmentDur
+		f.Moov.Mvhd.Duration = segmentDur
+	}
+	return f, nil
+}
+
+// CreateFragmentedFile - create a fragmented file with one segment per fragment of fragmentDur
+// (in track timescale) as described for mp4.CreateFragmentedFile.
+// If SkipStart is set, an edit list with zero duration skips that part.
+func (t *Track) CreateFragmentedFile(fragmentDur uint64) (*mp4.File, error) {
+	init, err := t.CreateInit()
+	if err != nil {
+		return nil, err
+	}
+	if t.SkipStart > 0 {
+		addEditList(init.Moov.Trak, 0, int64(t.SkipStart))
+	}
+	return mp4.CreateFragmentedFile(init, t.Samples, fragmentDur)
+}
+
+// addEditList - add an edts box with one edit directly after the tkhd box
+func addEditList(trak *mp4.TrakBox, segmentDur uint64, mediaTime int64) {
+	elst := &mp4.ElstBox{Entries: []mp4.ElstEntry{
+		{SegmentDuration: segmentDur, MediaTime: mediaTime, MediaRateInteger: 1}}}
+	if segmentDur > math.MaxUint32 {
+		elst.Version = 1
+	}
+	edts := &mp4.EdtsBox{}
+	edts.AddChild(elst)
+	trak.Edts = edts
+	children := make([]mp4.Box, 0, len(trak.Children)+1)
+	for _, c := range trak.Children {
+		children = append(children, c)
+		if c.Type() == "tkhd" {
+			children = append(children, edts)
+		}
+	}
+	trak.Children = children
+}
@@ -0,0 +1,206 @@
+package mp3
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/jaypadia-frame/mp4ff/aac"
+	"github.com/jaypadia-frame/mp4ff/mp4"
+)
+
+func TestDecodeFrameHeader(t *testing.T) {
+	testCases := []struct {
+		desc            string
+		hdr             FrameHeader
+		bitrate         int
+		sampleRate      int
+		samplesPerFrame int
+		frameSize       int
+	}{
+		{"MPEG-1 layer 3 with padding", FrameHeader{Version: MPEG1, Layer: 3, ProtectionAbsent: true, BitrateIndex: 9,
+			Padding: true, ChannelMode: ChannelModeJointStereo, ModeExtension: 2}, 128000, 44100, 1152, 418},
+		{"MPEG-2 layer 3 mono", FrameHeader{Version: MPEG2, Layer: 3, BitrateIndex: 8, ChannelMode: ChannelModeMono,
+			Original: true}, 64000, 22050, 576, 208},
+		{"MPEG-1 layer 2", FrameHeader{Version: MPEG1, Layer: 2, ProtectionAbsent: true, BitrateIndex: 10,
+			SamplingFrequencyIndex: 1, Copyright: true, Emphasis: 1}, 192000, 48000, 1152, 576},
+		{"MPEG-1 layer 1", FrameHeader{Version: MPEG1, Layer: 1, ProtectionAbsent: true, BitrateIndex: 1,
+			SamplingFrequencyIndex: 1, ChannelMode: ChannelModeDualChannel}, 32000, 48000, 384, 32},
+		{"MPEG-2.5 layer 3", FrameHeader{Version: MPEG25, Layer: 3, ProtectionAbsent: true, BitrateIndex: 1,
+			SamplingFrequencyIndex: 2, Private: true}, 8000, 8000, 576, 72},
+	}
+	for _, tc := range testCases {
+		h, err := DecodeFrameHeader(tc.hdr.Encode())
+		if err != nil {
+			t.Errorf("%s: %s", tc.desc, err)
+			continue
+		}
+		if diff := deep.Equal(*h, tc.hdr); diff != nil {
+			t.Errorf("%s: %v", tc.desc, diff)
+		}
+		if h.Bitrate() != tc.bitrate || h.SampleRate() != tc.sampleRate || h.SamplesPerFrame() != tc.samplesPerFrame ||
+			h.FrameSize() != tc.frameSize {
+			t.Errorf("%s: got bitrate %d, sample rate %d, %d samples, and size %d", tc.desc, h.Bitrate(),
+				h.SampleRate(), h.SamplesPerFrame(), h.FrameSize())
+		}
+	}
+	for _, data := range [][]byte{{0xff, 0xfb, 0x00, 0x00}, {0xff, 0xfb, 0xfc, 0x00}, {0xff, 0xe9, 0x90, 0x00}, {0xfe}} {
+		if _, err := DecodeFrameHeader(data); err == nil {
+			t.Errorf("no error for % x", data)
+		}
+	}
+}
+
+var stereo128k = FrameHeader{Version: MPEG1, Layer: 3, ProtectionAbsent: true, BitrateIndex: 9,
+	ChannelMode: ChannelModeJointStereo}
+
+// createFrame - frame with header h and payload with the frame number
+func createFrame(h *FrameHeader, nr byte) []byte {
+	frame := make([]byte, h.FrameSize())
+	copy(frame, h.Encode())
+	for i := HeaderSize; i < len(frame); i++ {
+		frame[i] = nr
+	}
+	return frame
+}
+
+// createInfoFrame - frame with Info header and LAME tag
+func createInfoFrame(h *FrameHeader, nrFrames int, delay, padding int) []byte {
+	frame := make([]byte, h.FrameSize())
+	copy(frame, h.Encode())
+	pos := HeaderSize + h.sideInfoSize()
+	copy(frame[pos:], "Info")
+	binary.BigEndian.PutUint32(frame[pos+4:], XingFramesFlag)
+	binary.BigEndian.PutUint32(frame[pos+8:], uint32(nrFrames))
+	pos += 12
+	copy(frame[pos:], "LAME3.100")
+	frame[pos+21] = byte(delay >> 4)
+	frame[pos+22] = byte(delay<<4) | byte(padding>>8)
+	frame[pos+23] = byte(padding)
+	return frame
+}
+
+func TestImport(t *testing.T) {
+	nrFrames := 20
+	id3v2 := append([]byte("ID3\x04\x00\x00\x00\x00\x00\x14"), make([]byte, 20)...)
+	stream := append([]byte{}, id3v2...)
+	stream = append(stream, createInfoFrame(&stereo128k, nrFrames, 576, 1000)...)
+	for i := 0; i < nrFrames; i++ {
+		stream = append(stream, createFrame(&stereo128k, byte(i))...)
+	}
+	stream = append(stream, append([]byte("TAG"), make([]byte, 125)...)...)
+
+	for _, mpeg4 := range []bool{false, true} {
+		track, err := Import(stream, Config{MPEG4: mpeg4})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if track.Xing == nil || !track.Xing.Info || track.Xing.NrFrames != uint32(nrFrames) || track.Xing.LAME == nil ||
+			track.Xing.LAME.Encoder != "LAME3.100" {
+			t.Fatalf("got Xing header %+v", track.Xing)
+		}
+		if track.SkipStart != 576+529 || track.SkipEnd != 1000-529 {
+			t.Errorf("got skip start %d and end %d", track.SkipStart, track.SkipEnd)
+		}
+		if len(track.Samples) != nrFrames || track.MaxBitrate != 128000 || track.AvgBitrate != 127706 {
+			t.Fatalf("got %d samples, max bitrate %d, avg bitrate %d", len(track.Samples), track.MaxBitrate,
+				track.AvgBitrate)
+		}
+		for i, s := range track.Samples {
+			if s.Dur != 1152 || s.DecodeTime != uint64(i*1152) || len(s.Data) != 417 || s.Data[4] != byte(i) {
+				t.Errorf("sample %d: got dur %d time %d size %d", i+1, s.Dur, s.DecodeTime, len(s.Data))
+			}
+		}
+		wantCodec := "mp4a.6B"
+		if mpeg4 {
+			wantCodec = "mp4a.40.34"
+		}
+		if track.CodecString() != wantCodec {
+			t.Errorf("got codec %s instead of %s", track.CodecString(), wantCodec)
+		}
+
+		f, err := track.CreateProgressiveFile()
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf := &bytes.Buffer{}
+		if err := f.Encode(buf); err != nil {
+			t.Fatal(err)
+		}
+		decFile, err := mp4.DecodeFile(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		trak := decFile.Moov.Trak
+		esds := trak.Mdia.Minf.Stbl.Stsd.Mp4a.Esds
+		if esds.CodecString() != wantCodec || esds.DecConfigDescriptor.BufferSizeDB != 417 {
+			t.Errorf("got esds %+v", esds.DecConfigDescriptor)
+		}
+		if mpeg4 {
+			asc, err := aac.DecodeAudioSpecificConfig(bytes.NewReader(esds.DecConfigDescriptor.DecSpecificInfo.DecConfig))
+			if err != nil || asc.ObjectType != aac.MPEGLayer3 || asc.SamplingFrequency != 44100 ||
+				asc.ChannelConfiguration != 2 {
+				t.Errorf("got AudioSpecificConfig %+v, err %v", asc, err)
+			}
+		}
+		wantSegmentDur := uint64(nrFrames*1152-576-1000) * 90000 / 44100
+		if trak.Edts == nil || len(trak.Edts.Elst) != 1 || trak.Children[1].Type() != "edts" {
+			t.Fatalf("no edit list after tkhd")
+		}
+		wantEntry := mp4.ElstEntry{SegmentDuration: wantSegmentDur, MediaTime: 1105, MediaRateInteger: 1}
+		if diff := deep.Equal(trak.Edts.Elst[0].Entries, []mp4.ElstEntry{wantEntry}); diff != nil {
+			t.Errorf("elst diff %v", diff)
+		}
+		if trak.Tkhd.Duration != wantSegmentDur || decFile.Moov.Mvhd.Duration != wantSegmentDur {
+			t.Errorf("got durations %d and %d", trak.Tkhd.Duration, decFile.Moov.Mvhd.Duration)
+		}
+		var frames []byte
+		for _, s := range track.Samples {
+			frames = append(frames, s.Data...)
+		}
+		if !bytes.Equal(decFile.Mdat.Data, frames) {
+			t.Errorf("mdat does not contain the audio frames")
+		}
+	}
+}
+
+func TestImportVBRI(t *testing.T) {
+	h := FrameHeader{Version: MPEG2, Layer: 3, ProtectionAbsent: true, BitrateIndex: 8, SamplingFrequencyIndex: 1,
+		ChannelMode: ChannelModeMono}
+	vbri := make([]byte, h.FrameSize())
+	copy(vbri, h.Encode())
+	copy(vbri[vbriOffset:], "VBRI\x00\x01\x04\x80\x00\x4b\x00\x00\x10\x00\x00\x00\x00\x03")
+	stream := append([]byte{}, vbri...)
+	for i := 0; i < 3; i++ {
+		stream = append(stream, createFrame(&h, byte(i))...)
+	}
+	track, err := Import(stream, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantVBRI := &VBRIHeader{Version: 1, Delay: 0x480, Quality: 75, NrBytes: 4096, NrFrames: 3}
+	if diff := deep.Equal(track.VBRI, wantVBRI); diff != nil {
+		t.Error(diff)
+	}
+	if len(track.Samples) != 3 || track.SkipStart != 0 || track.CodecString() != "mp4a.69" {
+		t.Errorf("got %d samples, skip start %d, codec %s", len(track.Samples), track.SkipStart, track.CodecString())
+	}
+	init, err := track.CreateInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mp4a := init.Moov.Trak.Mdia.Minf.Stbl.Stsd.Mp4a
+	if mp4a.ChannelCount != 1 || mp4a.SampleRate != 24000 || init.Moov.Trak.Edts != nil {
+		t.Errorf("got %d channels and sample rate %d", mp4a.ChannelCount, mp4a.SampleRate)
+	}
+
+	track.SkipStart, track.SkipEnd = 2000, 2000
+	if _, err := track.CreateProgressiveFile(); err == nil {
+		t.Error("no error for skip start and end longer than the track")
+	}
+
+	if _, err := Import(append(stream, 0xff, 0xfb), Config{}); err == nil {
+		t.Error("no error for truncated frame")
+	}
+}
@@ -18,6 +18,9 @@ type AudioSampleEntryBox struct {
 	Esds               *EsdsBox
 	Dac3               *Dac3Box
 	Dec3               *Dec3Box
+	Dac4               *Dac4Box
+	MhaC               *MhaCBox
+	Btrt               *BtrtBox
 	Sinf               *SinfBox
 	Children           []Box
 }
@@ -60,6 +63,12 @@ func (a *AudioSampleEntryBox) AddChild(child Box) {
 		a.Dac3 = child.(*Dac3Box)
 	case "dec3":
 		a.Dec3 = child.(*Dec3Box)
+	case "dac4":
+		a.Dac4 = child.(*Dac4Box)
+	case "mhaC":
+		a.MhaC = child.(*MhaCBox)
+	case "btrt":
+		a.Btrt = child.(*BtrtBox)
 	case "sinf":
 		a.Sinf = child.(*SinfBox)
 	}
@@ -20,23 +20,37 @@ var decoders map[string]BoxDecoder
 func init() {
 	decoders = map[string]BoxDecoder{
 		"ac-3":    DecodeAudioSampleEntry,
+		"ac-4":    DecodeAudioSampleEntry,
 		"avc1":    DecodeVisualSampleEntry,
 		"avc3":    DecodeVisualSampleEntry,
 		"avcC":    DecodeAvcC,
 		"btrt":    DecodeBtrt,
+		"c608":    DecodeClosedCaptionSampleEntry,
+		"c708":    DecodeClosedCaptionSampleEntry,
 		"cdat":    DecodeCdat,
+		"cdt2":    DecodeCdt2,
 		"cdsc":    DecodeTrefType,
 		"clap":    DecodeClap,
+		"clli":    DecodeClli,
+		"colr":    DecodeColr,
 		"cslg":    DecodeCslg,
 		"co64":    DecodeCo64,
 		"ctim":    DecodeCtim,
 		"ctts":    DecodeCtts,
 		"dac3":    DecodeDac3,
+		"dac4":    DecodeDac4,
 		"data":    DecodeData,
 		"dec3":    DecodeDec3,
 		"dinf":    DecodeDinf,
 		"dpnd":    DecodeTrefType,
 		"dref":    DecodeDref,
+		"dva1":    DecodeVisualSampleEntry,
+		"dvav":    DecodeVisualSampleEntry,
+		"dvcC":    DecodeDvcC,
+		"dvh1":    DecodeVisualSampleEntry,
+		"dvhe":    DecodeVisualSampleEntry,
+		"dvvC":    DecodeDvcC,
+		"dvwC":    DecodeDvcC,
 		"ec-3":    DecodeAudioSampleEntry,
 		"elng":    DecodeElng,
 		"esds":    DecodeEsds,
@@ -61,6 +75,7 @@ func init() {
 		"ipir":    DecodeTrefType,
 		"kind":    DecodeKind,
 		"mdat":    DecodeMdat,
+		"mdcv":    DecodeMdcv,
 		"mehd":    DecodeMehd,
 		"mdhd":    DecodeMdhd,
 		"mdia":    DecodeMdia,
@@ -68,6 +83,11 @@ func init() {
 		"mfhd":    DecodeMfhd,
 		"mfra":    DecodeMfra,
 		"mfro":    DecodeMfro,
+		"mha1":    DecodeAudioSampleEntry,
+		"mha2":    DecodeAudioSampleEntry,
+		"mhaC":    DecodeMhaC,
+		"mhm1":    DecodeAudioSampleEntry,
+		"mhm2":    DecodeAudioSampleEntry,
 		"mime":    DecodeMime,
 		"minf":    DecodeMinf,
 		"moof":    DecodeMoof,
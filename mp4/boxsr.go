@@ -11,23 +11,37 @@ var decodersSR map[string]BoxDecoderSR
 func init() {
 	decodersSR = map[string]BoxDecoderSR{
 		"ac-3":    DecodeAudioSampleEntrySR,
+		"ac-4":    DecodeAudioSampleEntrySR,
 		"avc1":    DecodeVisualSampleEntrySR,
 		"avc3":    DecodeVisualSampleEntrySR,
 		"avcC":    DecodeAvcCSR,
 		"btrt":    DecodeBtrtSR,
+		"c608":    DecodeClosedCaptionSampleEntrySR,
+		"c708":    DecodeClosedCaptionSampleEntrySR,
 		"cdat":    DecodeCdatSR,
+		"cdt2":    DecodeCdt2SR,
 		"cdsc":    DecodeTrefTypeSR,
 		"clap":    DecodeClapSR,
+		"clli":    DecodeClliSR,
+		"colr":    DecodeColrSR,
 		"cslg":    DecodeCslgSR,
 		"co64":    DecodeCo64SR,
 		"ctim":    DecodeCtimSR,
 		"ctts":    DecodeCttsSR,
 		"dac3":    DecodeDac3SR,
+		"dac4":    DecodeDac4SR,
 		"data":    DecodeDataSR,
 		"dec3":    DecodeDec3SR,
 		"dinf":    DecodeDinfSR,
 		"dpnd":    DecodeTrefTypeSR,
 		"dref":    DecodeDrefSR,
+		"dva1":    DecodeVisualSampleEntrySR,
+		"dvav":    DecodeVisualSampleEntrySR,
+		"dvcC":    DecodeDvcCSR,
+		"dvh1":    DecodeVisualSampleEntrySR,
+		"dvhe":    DecodeVisualSampleEntrySR,
+		"dvvC":    DecodeDvcCSR,
+		"dvwC":    DecodeDvcCSR,
 		"ec-3":    DecodeAudioSampleEntrySR,
 		"elng":    DecodeElngSR,
 		"esds":    DecodeEsdsSR,
@@ -52,6 +66,7 @@ func init() {
 		"ipir":    DecodeTrefTypeSR,
 		"kind":    DecodeKindSR,
 		"mdat":    DecodeMdatSR,
+		"mdcv":    DecodeMdcvSR,
 		"mehd":    DecodeMehdSR,
 		"mdhd":    DecodeMdhdSR,
 		"mdia":    DecodeMdiaSR,
@@ -59,6 +74,11 @@ func init() {
 		"mfhd":    DecodeMfhdSR,
 		"mfra":    DecodeMfraSR,
 		"mfro":    DecodeMfroSR,
+		"mha1":    DecodeAudioSampleEntrySR,
+		"mha2":    DecodeAudioSampleEntrySR,
+		"mhaC":    DecodeMhaCSR,
+		"mhm1":    DecodeAudioSampleEntrySR,
+		"mhm2":    DecodeAudioSampleEntrySR,
 		"mime":    DecodeMimeSR,
 		"minf":    DecodeMinfSR,
 		"moof":    DecodeMoofSR,
@@ -1,6 +1,7 @@
 package mp4
 
 import (
+	"fmt"
 	"io"
 
 	"github.com/edgeware/mp4ff/bits"
@@ -74,3 +75,57 @@ func (b *BtrtBox) Info(w io.Writer, specificBoxLevels, indent, indentStep string
 	bd.write(" - AvgBitrate: %d", b.AvgBitrate)
 	return bd.err
 }
+
+// GetSampleEntryBtrt - the btrt box of a sample entry, or nil if there is none.
+// An error is returned for sample entries not supported by SetSampleEntryBtrt.
+func GetSampleEntryBtrt(entry Box) (*BtrtBox, error) {
+	field, _, err := sampleEntryBtrtField(entry)
+	if err != nil {
+		return nil, err
+	}
+	return *field, nil
+}
+
+// SetSampleEntryBtrt - set the values of the btrt box of a sample entry, and add a btrt box if there is none.
+// A new box is put before any sinf box. Visual, audio, wvtt, stpp, and c608/c708 sample entries are supported.
+func SetSampleEntryBtrt(entry Box, btrt BtrtBox) error {
+	field, children, err := sampleEntryBtrtField(entry)
+	if err != nil {
+		return err
+	}
+	if *field != nil {
+		**field = btrt
+		return nil
+	}
+	box := &btrt
+	pos := len(*children)
+	for i, c := range *children {
+		if c.Type() == "sinf" {
+			pos = i
+			break
+		}
+	}
+	*children = append(*children, nil)
+	copy((*children)[pos+1:], (*children)[pos:])
+	(*children)[pos] = box
+	*field = box
+	return nil
+}
+
+// sampleEntryBtrtField - the Btrt field and the children of a sample entry
+func sampleEntryBtrtField(entry Box) (field **BtrtBox, children *[]Box, err error) {
+	switch e := entry.(type) {
+	case *VisualSampleEntryBox:
+		return &e.Btrt, &e.Children, nil
+	case *AudioSampleEntryBox:
+		return &e.Btrt, &e.Children, nil
+	case *WvttBox:
+		return &e.Btrt, &e.Children, nil
+	case *StppBox:
+		return &e.Btrt, &e.Children, nil
+	case *ClosedCaptionSampleEntryBox:
+		return &e.Btrt, &e.Children, nil
+	default:
+		return nil, nil, fmt.Errorf("cannot set btrt box of %s sample entry", entry.Type())
+	}
+}
@@ -18,3 +18,35 @@ func TestBtrt(t *testing.T) {
 		boxDiffAfterEncodeAndDecode(t, inBox)
 	}
 }
+
+func TestSetSampleEntryBtrt(t *testing.T) {
+	enca := NewAudioSampleEntryBox("enca")
+	enca.AddChild(&EsdsBox{})
+	enca.AddChild(&SinfBox{})
+	btrt := BtrtBox{BufferSizeDB: 1024, MaxBitrate: 130000, AvgBitrate: 128000}
+	if old, err := GetSampleEntryBtrt(enca); err != nil || old != nil {
+		t.Errorf("got btrt %v and error %v before adding", old, err)
+	}
+	if err := SetSampleEntryBtrt(enca, btrt); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := GetSampleEntryBtrt(enca); err != nil || got != enca.Btrt {
+		t.Errorf("got btrt %v and error %v", got, err)
+	}
+	if enca.Btrt == nil || *enca.Btrt != btrt || len(enca.Children) != 3 || enca.Children[1] != enca.Btrt {
+		t.Errorf("btrt box not inserted before sinf")
+	}
+	btrt.MaxBitrate = 140000
+	if err := SetSampleEntryBtrt(enca, btrt); err != nil {
+		t.Fatal(err)
+	}
+	if *enca.Btrt != btrt || len(enca.Children) != 3 {
+		t.Errorf("btrt box not updated")
+	}
+	if _, err := GetSampleEntryBtrt(&FreeBox{}); err == nil {
+		t.Error("no error for free box")
+	}
+	if err := SetSampleEntryBtrt(&FreeBox{}, btrt); err == nil {
+		t.Error("no error for free box")
+	}
+}
@@ -0,0 +1,72 @@
+package mp4
+
+import (
+	"encoding/hex"
+	"io"
+
+	"github.com/jaypadia-frame/mp4ff/bits"
+)
+
+// Cdt2Box - Closed Captioning Sample Data for the second field according to QuickTime spec:
+// https://developer.apple.com/library/archive/documentation/QuickTime/QTFF/QTFFChap3/qtff3.html#//apple_ref/doc/uid/TP40000939-CH205-SW87
+type Cdt2Box struct {
+	Data []byte
+}
+
+// DecodeCdt2 - box-specific decode
+func DecodeCdt2(hdr BoxHeader, startPos uint64, r io.Reader) (Box, error) {
+	data, err := readBoxBody(r, hdr)
+	if err != nil {
+		return nil, err
+	}
+	b := &Cdt2Box{
+		Data: data,
+	}
+	return b, nil
+}
+
+// DecodeCdt2 - box-specific decode
+func DecodeCdt2SR(hdr BoxHeader, startPos uint64, sr bits.SliceReader) (Box, error) {
+	b := &Cdt2Box{
+		Data: sr.ReadBytes(hdr.payloadLen()),
+	}
+	return b, sr.AccError()
+}
+
+// Type - box type
+func (b *Cdt2Box) Type() string {
+	return "cdt2"
+}
+
+// Size - calculated size of box
+func (b *Cdt2Box) Size() uint64 {
+	return uint64(boxHeaderSize + len(b.Data))
+}
+
+// Encode - write box to w
+func (b *Cdt2Box) Encode(w io.Writer) error {
+	sw := bits.NewFixedSliceWriter(int(b.Size()))
+	err := b.EncodeSW(sw)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(sw.Bytes())
+	return err
+}
+
+// EncodeSW - box-specific encode to slicewriter
+func (b *Cdt2Box) EncodeSW(sw bits.SliceWriter) error {
+	err := EncodeHeaderSW(b, sw)
+	if err != nil {
+		return err
+	}
+	sw.WriteBytes(b.Data)
+	return sw.AccError()
+}
+
+// Info - write specific box information
+func (b *Cdt2Box) Info(w io.Writer, specificBoxLevels, indent, indentStep string) error {
+	bd := newInfoDumper(w, indent, b, -1, 0)
+	bd.write(" - data: %s", hex.EncodeToString(b.Data))
+	return bd.err
+}
@@ -0,0 +1,71 @@
+package mp4
+
+import (
+	"io"
+
+	"github.com/jaypadia-frame/mp4ff/bits"
+)
+
+// ClliBox - ContentLightLevelBox ISO/IEC 14496-12 2022 Sec. 12.1.7
+type ClliBox struct {
+	MaxContentLightLevel    uint16
+	MaxPicAverageLightLevel uint16
+}
+
+// DecodeClli - box-specific decode
+func DecodeClli(hdr BoxHeader, startPos uint64, r io.Reader) (Box, error) {
+	data, err := readBoxBody(r, hdr)
+	if err != nil {
+		return nil, err
+	}
+	sr := bits.NewFixedSliceReader(data)
+	return DecodeClliSR(hdr, startPos, sr)
+}
+
+// DecodeClliSR - box-specific decode
+func DecodeClliSR(hdr BoxHeader, startPos uint64, sr bits.SliceReader) (Box, error) {
+	b := &ClliBox{}
+	b.MaxContentLightLevel = sr.ReadUint16()
+	b.MaxPicAverageLightLevel = sr.ReadUint16()
+	return b, sr.AccError()
+}
+
+// Type - box type
+func (b *ClliBox) Type() string {
+	return "clli"
+}
+
+// Size - calculated size of box
+func (b *ClliBox) Size() uint64 {
+	return uint64(boxHeaderSize + 4)
+}
+
+// Encode - write box to w
+func (b *ClliBox) Encode(w io.Writer) error {
+	sw := bits.NewFixedSliceWriter(int(b.Size()))
+	err := b.EncodeSW(sw)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(sw.Bytes())
+	return err
+}
+
+// EncodeSW - box-specific encode to slicewriter
+func (b *ClliBox) EncodeSW(sw bits.SliceWriter) error {
+	err := EncodeHeaderSW(b, sw)
+	if err != nil {
+		return err
+	}
+	sw.WriteUint16(b.MaxContentLightLevel)
+	sw.WriteUint16(b.MaxPicAverageLightLevel)
+	return sw.AccError()
+}
+
+// Info - write box-specific information
+func (b *ClliBox) Info(w io.Writer, specificBoxLevels, indent, indentStep string) error {
+	bd := newInfoDumper(w, indent, b, -1, 0)
+	bd.write(" - maxContentLightLevel: %d", b.MaxContentLightLevel)
+	bd.write(" - maxPicAverageLightLevel: %d", b.MaxPicAverageLightLevel)
+	return bd.err
+}
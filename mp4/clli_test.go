@@ -0,0 +1,10 @@
+package mp4
+
+import (
+	"testing"
+)
+
+func TestEncDecClli(t *testing.T) {
+	b := &ClliBox{MaxContentLightLevel: 1000, MaxPicAverageLightLevel: 400}
+	boxDiffAfterEncodeAndDecode(t, b)
+}
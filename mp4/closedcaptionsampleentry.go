@@ -0,0 +1,123 @@
+package mp4
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/jaypadia-frame/mp4ff/bits"
+)
+
+// ClosedCaptionSampleEntryBox - QuickTime closed caption sample entry (c608 or c708)
+// https://developer.apple.com/library/archive/documentation/QuickTime/QTFF/QTFFChap3/qtff3.html#//apple_ref/doc/uid/TP40000939-CH205-SW87
+// The samples consist of cdat and cdt2 boxes with CEA-608 byte pairs for c608.
+type ClosedCaptionSampleEntryBox struct {
+	name               string
+	DataReferenceIndex uint16
+	Btrt               *BtrtBox
+	Children           []Box
+}
+
+// NewClosedCaptionSampleEntryBox - create new empty c608 or c708 sample entry
+func NewClosedCaptionSampleEntryBox(name string) *ClosedCaptionSampleEntryBox {
+	return &ClosedCaptionSampleEntryBox{name: name, DataReferenceIndex: 1}
+}
+
+// AddChild - add a child box
+func (b *ClosedCaptionSampleEntryBox) AddChild(child Box) {
+	if btrt, ok := child.(*BtrtBox); ok {
+		b.Btrt = btrt
+	}
+	b.Children = append(b.Children, child)
+}
+
+const nrClosedCaptionBytesBeforeChildren = 16
+
+// DecodeClosedCaptionSampleEntry - decode c608 or c708 sample entry
+func DecodeClosedCaptionSampleEntry(hdr BoxHeader, startPos uint64, r io.Reader) (Box, error) {
+	data, err := readBoxBody(r, hdr)
+	if err != nil {
+		return nil, err
+	}
+	sr := bits.NewFixedSliceReader(data)
+	return DecodeClosedCaptionSampleEntrySR(hdr, startPos, sr)
+}
+
+// DecodeClosedCaptionSampleEntrySR - decode c608 or c708 sample entry
+func DecodeClosedCaptionSampleEntrySR(hdr BoxHeader, startPos uint64, sr bits.SliceReader) (Box, error) {
+	b := NewClosedCaptionSampleEntryBox(hdr.Name)
+	// 14496-12 8.5.2.2 Sample entry (8 bytes)
+	sr.SkipBytes(6) // Skip 6 reserved bytes
+	b.DataReferenceIndex = sr.ReadUint16()
+	pos := startPos + nrClosedCaptionBytesBeforeChildren
+	endPos := startPos + uint64(hdr.Hdrlen+hdr.payloadLen())
+	for pos < endPos {
+		box, err := DecodeBoxSR(pos, sr)
+		if err != nil {
+			return nil, err
+		}
+		if box == nil {
+			return nil, fmt.Errorf("no child of %s", hdr.Name)
+		}
+		b.AddChild(box)
+		pos += box.Size()
+	}
+	return b, sr.AccError()
+}
+
+// Type - return box type
+func (b *ClosedCaptionSampleEntryBox) Type() string {
+	return b.name
+}
+
+// Size - return calculated size
+func (b *ClosedCaptionSampleEntryBox) Size() uint64 {
+	totalSize := uint64(nrClosedCaptionBytesBeforeChildren)
+	for _, child := range b.Children {
+		totalSize += child.Size()
+	}
+	return totalSize
+}
+
+// Encode - write box to w
+func (b *ClosedCaptionSampleEntryBox) Encode(w io.Writer) error {
+	sw := bits.NewFixedSliceWriter(int(b.Size()))
+	err := b.EncodeSW(sw)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(sw.Bytes())
+	return err
+}
+
+// EncodeSW - write box to sw
+func (b *ClosedCaptionSampleEntryBox) EncodeSW(sw bits.SliceWriter) error {
+	err := EncodeHeaderSW(b, sw)
+	if err != nil {
+		return err
+	}
+	sw.WriteZeroBytes(6)
+	sw.WriteUint16(b.DataReferenceIndex)
+	for _, child := range b.Children {
+		err = child.EncodeSW(sw)
+		if err != nil {
+			return err
+		}
+	}
+	return sw.AccError()
+}
+
+// Info - write box-specific information
+func (b *ClosedCaptionSampleEntryBox) Info(w io.Writer, specificBoxLevels, indent, indentStep string) error {
+	bd := newInfoDumper(w, indent, b, -1, 0)
+	bd.write(" - dataReferenceIndex: %d", b.DataReferenceIndex)
+	if bd.err != nil {
+		return bd.err
+	}
+	for _, child := range b.Children {
+		err := child.Info(w, specificBoxLevels, indent+indentStep, indent)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package mp4
+
+import (
+	"testing"
+)
+
+func TestEncDecClosedCaptionSampleEntry(t *testing.T) {
+	for _, name := range []string{"c608", "c708"} {
+		b := NewClosedCaptionSampleEntryBox(name)
+		boxDiffAfterEncodeAndDecode(t, b)
+		b.AddChild(&BtrtBox{BufferSizeDB: 2, MaxBitrate: 960, AvgBitrate: 960})
+		boxDiffAfterEncodeAndDecode(t, b)
+	}
+	boxDiffAfterEncodeAndDecode(t, &Cdt2Box{Data: []byte{0x15, 0x2c}})
+}
+
+func TestClosedCaptionInitSegment(t *testing.T) {
+	init := CreateEmptyInit()
+	init.AddEmptyTrack(30000, "clcp", "eng")
+	trak := init.Moov.Trak
+	if err := trak.SetClosedCaptionDescriptor("wvtt"); err == nil {
+		t.Error("no error for wvtt")
+	}
+	if err := trak.SetClosedCaptionDescriptor("c608"); err != nil {
+		t.Fatal(err)
+	}
+	if trak.Mdia.Hdlr.HandlerType != "clcp" {
+		t.Errorf("got handler type %s instead of clcp", trak.Mdia.Hdlr.HandlerType)
+	}
+	stsd := trak.Mdia.Minf.Stbl.Stsd
+	if stsd.Clcp == nil || stsd.Clcp.Type() != "c608" {
+		t.Errorf("no c608 sample entry in stsd")
+	}
+}
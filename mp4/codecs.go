@@ -0,0 +1,149 @@
+package mp4
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jaypadia-frame/mp4ff/avc"
+	"github.com/jaypadia-frame/mp4ff/hevc"
+)
+
+// TTML profile designators (W3C IMSC) and the corresponding codecs parameter profiles (ISO/IEC 14496-30)
+var ttmlProfiles = []struct {
+	designator string
+	profile    string
+}{
+	{"http://www.w3.org/ns/ttml/profile/imsc1.1/text", "im2t"},
+	{"http://www.w3.org/ns/ttml/profile/imsc1.1/image", "im2i"},
+	{"http://www.w3.org/ns/ttml/profile/imsc1/text", "im1t"},
+	{"http://www.w3.org/ns/ttml/profile/imsc1/image", "im1i"},
+}
+
+// CodecString - RFC 6381 codec string of the first sample description of the track, like avc1.64001E or mp4a.40.2.
+// Encrypted sample entries (encv, enca) are resolved to the original format given by the frma box.
+func (t *TrakBox) CodecString() (string, error) {
+	stsd := t.Mdia.Minf.Stbl.Stsd
+	if stsd == nil || len(stsd.Children) == 0 {
+		return "", fmt.Errorf("track %d has no sample description", t.Tkhd.TrackID)
+	}
+	return SampleEntryCodecString(stsd.Children[0])
+}
+
+// SampleEntryCodecString - RFC 6381 codec string for a sample entry.
+// For AVC and HEVC, the profile and level come from avcC or hvcC, for mp4a from esds,
+// and for AC-4 and MPEG-H from dac4 and mhaC. stpp gets an IMSC profile like stpp.ttml.im1t
+// if it is signalled in the namespace or schemaLocation, and is plain stpp otherwise.
+// Other sample entries without a codecs parameter definition return the sample entry type.
+func SampleEntryCodecString(entry Box) (string, error) {
+	entryType := entry.Type()
+	switch e := entry.(type) {
+	case *VisualSampleEntryBox:
+		if entryType == "encv" {
+			if e.Sinf == nil || e.Sinf.Frma == nil {
+				return "", fmt.Errorf("encv without frma box")
+			}
+			entryType = e.Sinf.Frma.DataFormat
+		}
+		return visualCodecString(entryType, e)
+	case *AudioSampleEntryBox:
+		if entryType == "enca" {
+			if e.Sinf == nil || e.Sinf.Frma == nil {
+				return "", fmt.Errorf("enca without frma box")
+			}
+			entryType = e.Sinf.Frma.DataFormat
+		}
+		return audioCodecString(entryType, e), nil
+	case *StppBox:
+		return stppCodecString(e), nil
+	}
+	return entryType, nil
+}
+
+// visualCodecString - codec string for visual sample entry of type entryType
+func visualCodecString(entryType string, e *VisualSampleEntryBox) (string, error) {
+	switch entryType {
+	case "avc1", "avc3":
+		if e.AvcC == nil {
+			return "", fmt.Errorf("%s without avcC box", entryType)
+		}
+		sps := avc.SPS{
+			Profile:              uint(e.AvcC.AVCProfileIndication),
+			ProfileCompatibility: uint(e.AvcC.ProfileCompatibility),
+			Level:                uint(e.AvcC.AVCLevelIndication),
+		}
+		return avc.CodecString(entryType, &sps), nil
+	case "hvc1", "hev1":
+		if e.HvcC == nil {
+			return "", fmt.Errorf("%s without hvcC box", entryType)
+		}
+		sps := hevc.SPS{
+			ProfileTierLevel: hevc.ProfileTierLevel{
+				GeneralProfileSpace:              e.HvcC.GeneralProfileSpace,
+				GeneralTierFlag:                  e.HvcC.GeneralTierFlag,
+				GeneralProfileIDC:                e.HvcC.GeneralProfileIDC,
+				GeneralProfileCompatibilityFlags: e.HvcC.GeneralProfileCompatibilityFlags,
+				GeneralConstraintIndicatorFlags:  e.HvcC.GeneralConstraintIndicatorFlags,
+				GeneralLevelIDC:                  e.HvcC.GeneralLevelIDC,
+			},
+		}
+		return hevc.CodecString(entryType, &sps), nil
+	case "dvh1", "dvhe", "dva1", "dvav":
+		if e.DvcC == nil {
+			return "", fmt.Errorf("%s without Dolby Vision configuration box", entryType)
+		}
+		return e.DvcC.CodecString(entryType), nil
+	}
+	return entryType, nil
+}
+
+// audioCodecString - codec string for audio sample entry of type entryType
+func audioCodecString(entryType string, e *AudioSampleEntryBox) string {
+	switch entryType {
+	case "mp4a":
+		if e.Esds != nil {
+			return e.Esds.CodecString()
+		}
+	case "ac-4":
+		if e.Dac4 != nil {
+			return e.Dac4.CodecString()
+		}
+	case "mha1", "mha2", "mhm1", "mhm2":
+		if e.MhaC != nil {
+			return e.MhaC.CodecString(entryType)
+		}
+	}
+	return entryType
+}
+
+// stppCodecString - stpp.ttml.<profile> if an IMSC profile designator is in stpp, and stpp otherwise
+func stppCodecString(b *StppBox) string {
+	fields := strings.Fields(b.Namespace + " " + b.SchemaLocation)
+	for _, p := range ttmlProfiles {
+		for _, field := range fields {
+			if field == p.designator {
+				return "stpp.ttml." + p.profile
+			}
+		}
+	}
+	return "stpp"
+}
+
+// Codecs - RFC 6381 codec strings of all tracks in track order
+func (f *File) Codecs() ([]string, error) {
+	moov := f.Moov
+	if moov == nil && f.Init != nil {
+		moov = f.Init.Moov
+	}
+	if moov == nil {
+		return nil, fmt.Errorf("no moov box")
+	}
+	codecs := make([]string, 0, len(moov.Traks))
+	for _, trak := range moov.Traks {
+		codec, err := trak.CodecString()
+		if err != nil {
+			return nil, err
+		}
+		codecs = append(codecs, codec)
+	}
+	return codecs, nil
+}
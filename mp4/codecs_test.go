@@ -0,0 +1,94 @@
+package mp4
+
+import (
+	"encoding/hex"
+	"os"
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestFileCodecs(t *testing.T) {
+	testCases := []struct {
+		file   string
+		wanted []string
+	}{
+		{"prog_8s.mp4", []string{"mp4a.40.2", "avc1.64001E"}},
+		{"init1.cmfv", []string{"avc3.4D001F"}},
+		{"prog_8s_enc_dashinit.mp4", []string{"avc1.64001E", "mp4a.40.2"}}, // encv and enca
+	}
+	for _, tc := range testCases {
+		fd, err := os.Open("testdata/" + tc.file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f, err := DecodeFile(fd)
+		fd.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := f.Codecs()
+		if err != nil {
+			t.Error(err)
+		}
+		if diff := deep.Equal(got, tc.wanted); diff != nil {
+			t.Errorf("%s: %v", tc.file, diff)
+		}
+	}
+}
+
+func TestTrakCodecString(t *testing.T) {
+	vps, _ := hex.DecodeString(vpsHex)
+	sps, _ := hex.DecodeString(spsHex)
+	pps, _ := hex.DecodeString(ppsHex)
+	testCases := []struct {
+		name      string
+		mediaType string
+		set       func(trak *TrakBox) error
+		wanted    string
+	}{
+		{"hev1", "video", func(trak *TrakBox) error {
+			return trak.SetHEVCDescriptor("hev1", [][]byte{vps}, [][]byte{sps}, [][]byte{pps}, true)
+		}, "hev1.2.4.L123.B0"},
+		{"aac-he", "audio", func(trak *TrakBox) error {
+			return trak.SetAACDescriptor(5, 48000)
+		}, "mp4a.40.5"},
+		{"ac-3", "audio", func(trak *TrakBox) error {
+			return trak.SetAC3Descriptor(&Dac3Box{ACMod: 7})
+		}, "ac-3"},
+		{"ec-3", "audio", func(trak *TrakBox) error {
+			return trak.SetEC3Descriptor(&Dec3Box{EC3Subs: []EC3Sub{{ACMod: 7}}})
+		}, "ec-3"},
+		{"wvtt", "text", func(trak *TrakBox) error {
+			return trak.SetWvttDescriptor("WEBVTT")
+		}, "wvtt"},
+		{"stpp plain TTML", "subtitle", func(trak *TrakBox) error {
+			return trak.SetStppDescriptor("http://www.w3.org/ns/ttml", "", "")
+		}, "stpp"},
+		{"stpp text profile in schema location", "subtitle", func(trak *TrakBox) error {
+			return trak.SetStppDescriptor("http://www.w3.org/ns/ttml",
+				"http://www.w3.org/ns/ttml/profile/imsc1/text imsc1.xsd", "")
+		}, "stpp.ttml.im1t"},
+		{"stpp image profile", "subtitle", func(trak *TrakBox) error {
+			return trak.SetStppDescriptor("http://www.w3.org/ns/ttml http://www.w3.org/ns/ttml/profile/imsc1/image",
+				"", "image/png")
+		}, "stpp.ttml.im1i"},
+		{"stpp other", "subtitle", func(trak *TrakBox) error {
+			return trak.SetStppDescriptor("urn:example", "", "")
+		}, "stpp"},
+	}
+	for _, tc := range testCases {
+		init := CreateEmptyInit()
+		init.AddEmptyTrack(48000, tc.mediaType, "und")
+		if err := tc.set(init.Moov.Trak); err != nil {
+			t.Fatalf("%s: %s", tc.name, err)
+		}
+		got, err := init.Moov.Trak.CodecString()
+		if err != nil {
+			t.Errorf("%s: %s", tc.name, err)
+		}
+		if got != tc.wanted {
+			t.Errorf("%s: got %s instead of %s", tc.name, got, tc.wanted)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package mp4
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/jaypadia-frame/mp4ff/bits"
+)
+
+// Colour types in colr box
+const (
+	ColorTypeNCLX            = "nclx" // On-screen colours as defined in ISO/IEC 23091-2
+	ColorTypeNCLC            = "nclc" // QuickTime variant without full_range_flag
+	ColorTypeRestrictedICC   = "rICC"
+	ColorTypeUnrestrictedICC = "prof"
+)
+
+// ColrBox - Colour Information Box, ISO/IEC 14496-12 2020 Sec. 12.1.5
+type ColrBox struct {
+	ColorType               string
+	ColorPrimaries          uint16
+	TransferCharacteristics uint16
+	MatrixCoefficients      uint16
+	FullRangeFlag           bool
+	ICCProfile              []byte // For rICC and prof
+	UnknownPayload          []byte // For unknown colour types
+}
+
+// CreateNclxColrBox - create an nclx colr box
+func CreateNclxColrBox(colorPrimaries, transferCharacteristics, matrixCoefficients uint16, fullRange bool) *ColrBox {
+	return &ColrBox{
+		ColorType:               ColorTypeNCLX,
+		ColorPrimaries:          colorPrimaries,
+		TransferCharacteristics: transferCharacteristics,
+		MatrixCoefficients:      matrixCoefficients,
+		FullRangeFlag:           fullRange,
+	}
+}
+
+// DecodeColr - box-specific decode
+func DecodeColr(hdr BoxHeader, startPos uint64, r io.Reader) (Box, error) {
+	data, err := readBoxBody(r, hdr)
+	if err != nil {
+		return nil, err
+	}
+	sr := bits.NewFixedSliceReader(data)
+	return DecodeColrSR(hdr, startPos, sr)
+}
+
+// DecodeColrSR - box-specific decode
+func DecodeColrSR(hdr BoxHeader, startPos uint64, sr bits.SliceReader) (Box, error) {
+	b := &ColrBox{}
+	b.ColorType = sr.ReadFixedLengthString(4)
+	remaining := hdr.payloadLen() - 4
+	switch b.ColorType {
+	case ColorTypeNCLX, ColorTypeNCLC:
+		minSize := 7
+		if b.ColorType == ColorTypeNCLC {
+			minSize = 6
+		}
+		if remaining < minSize {
+			return nil, fmt.Errorf("colr %s: payload too short: %d", b.ColorType, remaining)
+		}
+		b.ColorPrimaries = sr.ReadUint16()
+		b.TransferCharacteristics = sr.ReadUint16()
+		b.MatrixCoefficients = sr.ReadUint16()
+		if b.ColorType == ColorTypeNCLX {
+			b.FullRangeFlag = sr.ReadUint8()&0x80 != 0
+		}
+		if remaining > minSize {
+			return nil, fmt.Errorf("colr %s: %d extra bytes", b.ColorType, remaining-minSize)
+		}
+	case ColorTypeRestrictedICC, ColorTypeUnrestrictedICC:
+		b.ICCProfile = sr.ReadBytes(remaining)
+	default:
+		b.UnknownPayload = sr.ReadBytes(remaining)
+	}
+	return b, sr.AccError()
+}
+
+// Type - box type
+func (b *ColrBox) Type() string {
+	return "colr"
+}
+
+// Size - calculated size of box
+func (b *ColrBox) Size() uint64 {
+	switch b.ColorType {
+	case ColorTypeNCLX:
+		return uint64(boxHeaderSize + 11)
+	case ColorTypeNCLC:
+		return uint64(boxHeaderSize + 10)
+	case ColorTypeRestrictedICC, ColorTypeUnrestrictedICC:
+		return uint64(boxHeaderSize + 4 + len(b.ICCProfile))
+	default:
+		return uint64(boxHeaderSize + 4 + len(b.UnknownPayload))
+	}
+}
+
+// Encode - write box to w
+func (b *ColrBox) Encode(w io.Writer) error {
+	sw := bits.NewFixedSliceWriter(int(b.Size()))
+	err := b.EncodeSW(sw)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(sw.Bytes())
+	return err
+}
+
+// EncodeSW - box-specific encode to slicewriter
+func (b *ColrBox) EncodeSW(sw bits.SliceWriter) error {
+	err := EncodeHeaderSW(b, sw)
+	if err != nil {
+		return err
+	}
+	sw.WriteString(b.ColorType, false)
+	switch b.ColorType {
+	case ColorTypeNCLX, ColorTypeNCLC:
+		sw.WriteUint16(b.ColorPrimaries)
+		sw.WriteUint16(b.TransferCharacteristics)
+		sw.WriteUint16(b.MatrixCoefficients)
+		if b.ColorType == ColorTypeNCLX {
+			sw.WriteUint8(byte(bool2uint(b.FullRangeFlag) << 7))
+		}
+	case ColorTypeRestrictedICC, ColorTypeUnrestrictedICC:
+		sw.WriteBytes(b.ICCProfile)
+	default:
+		sw.WriteBytes(b.UnknownPayload)
+	}
+	return sw.AccError()
+}
+
+// Info - write box-specific information
+func (b *ColrBox) Info(w io.Writer, specificBoxLevels, indent, indentStep string) error {
+	bd := newInfoDumper(w, indent, b, -1, 0)
+	bd.write(" - colorType: %s", b.ColorType)
+	switch b.ColorType {
+	case ColorTypeNCLX:
+		bd.write(" - ColorPrimaries: %d, TransferCharacteristics: %d, MatrixCoefficients: %d, FullRange: %t",
+			b.ColorPrimaries, b.TransferCharacteristics, b.MatrixCoefficients, b.FullRangeFlag)
+	case ColorTypeNCLC:
+		bd.write(" - ColorPrimaries: %d, TransferCharacteristics: %d, MatrixCoefficients: %d",
+			b.ColorPrimaries, b.TransferCharacteristics, b.MatrixCoefficients)
+	case ColorTypeRestrictedICC, ColorTypeUnrestrictedICC:
+		bd.write(" - ICC profile size: %d", len(b.ICCProfile))
+	default:
+		bd.write(" - payload: %x", b.UnknownPayload)
+	}
+	return bd.err
+}
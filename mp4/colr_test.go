@@ -0,0 +1,17 @@
+package mp4
+
+import (
+	"testing"
+)
+
+func TestEncDecColr(t *testing.T) {
+	boxes := []*ColrBox{
+		CreateNclxColrBox(9, 16, 9, true),
+		{ColorType: ColorTypeNCLC, ColorPrimaries: 1, TransferCharacteristics: 1, MatrixCoefficients: 1},
+		{ColorType: ColorTypeUnrestrictedICC, ICCProfile: []byte{0, 1, 2, 3, 4, 5}},
+		{ColorType: "abcd", UnknownPayload: []byte{7, 8}},
+	}
+	for _, b := range boxes {
+		boxDiffAfterEncodeAndDecode(t, b)
+	}
+}
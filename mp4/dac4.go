@@ -0,0 +1,765 @@
+package mp4
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/jaypadia-frame/mp4ff/bits"
+)
+
+// AC4SampleRates - Base sample rates signaled by fs_index in ETSI TS 103 190-2 V1.2.1 (2018)
+var AC4SampleRates = []int{44100, 48000}
+
+// AC4FrameRates - Frame rates signaled by frame_rate_index in ETSI TS 103 190-2 V1.2.1 (2018)
+var AC4FrameRates = []string{
+	"23.976",
+	"24",
+	"25",
+	"29.97",
+	"30",
+	"47.95",
+	"48",
+	"50",
+	"59.94",
+	"60",
+	"100",
+	"119.88",
+	"120",
+	"23.44",
+}
+
+// AC4ChannelModes - channel configurations signaled by presentation_channel_mode
+// ETSI TS 103 190-2 V1.2.1 (2018)
+var AC4ChannelModes = []string{
+	"Mono",
+	"Stereo",
+	"3.0",
+	"5.0",
+	"5.1",
+	"7.0 (3/4/0)",
+	"7.1 (3/4/0.1)",
+	"7.0 (5/2/0)",
+	"7.1 (5/2/0.1)",
+	"7.0 (3/2/2)",
+	"7.1 (3/2/2.1)",
+	"7.0.4",
+	"7.1.4",
+	"9.0.4",
+	"9.1.4",
+	"22.2",
+}
+
+// ac4ChannelModeNrChannels - nominal number of channels per presentation_channel_mode
+var ac4ChannelModeNrChannels = []int{1, 2, 3, 5, 6, 7, 8, 7, 8, 7, 8, 11, 12, 13, 14, 24}
+
+// Dac4Box - AC4SpecificBox from ETSI TS 103 190-2 V1.2.1 Annex E.4 (2018)
+// Presentations with presentation_version 1 and 2 are fully parsed,
+// while presentation_version 0 (legacy) and unknown versions are kept as raw bytes.
+type Dac4Box struct {
+	DSIVersion       byte
+	BitstreamVersion byte
+	FSIndex          byte
+	FrameRateIndex   byte
+	HasProgramID     bool
+	ShortProgramID   uint16
+	ProgramUUID      []byte // 16 bytes if present
+	BitrateInfo      AC4BitrateInfo
+	Presentations    []AC4Presentation
+	Reserved         []byte
+}
+
+// AC4BitrateInfo - ac4_bitrate_dsi() E.7
+type AC4BitrateInfo struct {
+	Mode      byte
+	Bitrate   uint32
+	Precision uint32
+}
+
+// AC4Presentation - ac4_presentation_v1_dsi() E.10 or raw presentation data
+type AC4Presentation struct {
+	Version               byte
+	Raw                   []byte // Full payload if Version is not 1 or 2
+	Config                byte
+	MDCompat              byte
+	HasPresentationID     bool
+	PresentationID        byte
+	FrameRateMultiplyInfo byte
+	FrameRateFractionInfo byte
+	EMDFVersion           byte
+	KeyID                 uint16
+	ChannelCoded          bool
+	ChannelMode           byte
+	BackChannelsPresent   bool // pres_b_4_back_channels_present
+	TopChannelPairs       byte
+	ChannelMask           uint32
+	CoreDiffers           bool
+	CoreChannelCoded      bool
+	CoreChannelMode       byte
+	HasFilter             bool
+	EnablePresentation    bool
+	FilterData            []byte
+	MultiPID              bool
+	SkipData              []byte
+	SubstreamGroups       []AC4SubstreamGroup
+	PreVirtualized        bool
+	AddEMDFSubstreams     bool
+	EMDFSubstreams        []AC4EMDFSubstream
+	BitrateInfo           *AC4BitrateInfo
+	Alternative           *AC4AlternativeInfo
+	HasExtension          bool
+	DEIndicator           bool
+	AtmosIndicator        bool
+	HasExtendedID         bool
+	ExtendedID            uint16
+	Trailing              []byte // skip_area bytes
+}
+
+// AC4SubstreamGroup - ac4_substream_group_dsi() E.11
+type AC4SubstreamGroup struct {
+	SubstreamsPresent bool
+	HSFExt            bool
+	ChannelCoded      bool
+	Substreams        []AC4Substream
+	HasContentType    bool
+	ContentClassifier byte
+	LanguageTag       []byte
+}
+
+// AC4Substream - substream entry in ac4_substream_group_dsi()
+type AC4Substream struct {
+	SFMultiplier           byte
+	HasBitrateIndicator    bool
+	BitrateIndicator       byte
+	ChannelMask            uint32 // if group is channel coded
+	AJOC                   bool
+	StaticDmx              bool
+	NrDmxObjectsMinus1     byte
+	NrUmxObjectsMinus1     byte
+	ContainsBedObjects     bool
+	ContainsDynamicObjects bool
+	ContainsISFObjects     bool
+}
+
+// AC4EMDFSubstream - additional EMDF substream info
+type AC4EMDFSubstream struct {
+	EMDFVersion byte
+	KeyID       uint16
+}
+
+// AC4AlternativeInfo - alternative_info() E.12
+type AC4AlternativeInfo struct {
+	Name    string
+	Targets []AC4Target
+}
+
+// AC4Target - target device for alternative presentation
+type AC4Target struct {
+	MDCompat       byte
+	DeviceCategory byte
+}
+
+// DecodeDac4 - box-specific decode
+func DecodeDac4(hdr BoxHeader, startPos uint64, r io.Reader) (Box, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return decodeDac4FromData(data)
+}
+
+// DecodeDac4SR - box-specific decode
+func DecodeDac4SR(hdr BoxHeader, startPos uint64, sr bits.SliceReader) (Box, error) {
+	data := sr.ReadBytes(hdr.payloadLen())
+	if sr.AccError() != nil {
+		return nil, sr.AccError()
+	}
+	return decodeDac4FromData(data)
+}
+
+func decodeDac4FromData(data []byte) (Box, error) {
+	br := bits.NewAccErrReader(bytes.NewBuffer(data))
+	b := Dac4Box{}
+	b.DSIVersion = byte(br.Read(3))
+	b.BitstreamVersion = byte(br.Read(7))
+	b.FSIndex = byte(br.Read(1))
+	b.FrameRateIndex = byte(br.Read(4))
+	nrPresentations := int(br.Read(9))
+	if b.BitstreamVersion > 1 {
+		b.HasProgramID = br.ReadFlag()
+		if b.HasProgramID {
+			b.ShortProgramID = uint16(br.Read(16))
+			if br.ReadFlag() {
+				b.ProgramUUID = br.ReadBytes(16)
+			}
+		}
+	}
+	b.BitrateInfo = readAC4BitrateInfo(br)
+	byteAlignAC4(br)
+	for i := 0; i < nrPresentations; i++ {
+		p := AC4Presentation{}
+		p.Version = byte(br.Read(8))
+		presBytes := int(br.Read(8))
+		if presBytes == 255 {
+			presBytes += int(br.Read(16))
+		}
+		payload := br.ReadBytes(presBytes)
+		if br.AccError() != nil {
+			return nil, br.AccError()
+		}
+		switch p.Version {
+		case 1, 2:
+			err := p.decodeV1(payload)
+			if err != nil {
+				return nil, fmt.Errorf("dac4 presentation %d: %w", i, err)
+			}
+		default:
+			p.Raw = payload
+		}
+		b.Presentations = append(b.Presentations, p)
+	}
+	b.Reserved = br.ReadRemainingBytes()
+	if len(b.Reserved) == 0 {
+		b.Reserved = nil
+	}
+	return &b, br.AccError()
+}
+
+func readAC4BitrateInfo(br *bits.AccErrReader) AC4BitrateInfo {
+	return AC4BitrateInfo{
+		Mode:      byte(br.Read(2)),
+		Bitrate:   uint32(br.Read(32)),
+		Precision: uint32(br.Read(32)),
+	}
+}
+
+func writeAC4BitrateInfo(bw *bits.Writer, bi AC4BitrateInfo) {
+	bw.Write(uint(bi.Mode), 2)
+	bw.Write(uint(bi.Bitrate), 32)
+	bw.Write(uint(bi.Precision), 32)
+}
+
+// byteAlignAC4 - skip bits until next byte boundary
+func byteAlignAC4(br *bits.AccErrReader) {
+	if n := br.NrBitsReadInCurrentByte(); n != 8 {
+		_ = br.Read(8 - n)
+	}
+}
+
+// byteAlignAC4Writer - write zero bits until next byte boundary
+func byteAlignAC4Writer(bw *bits.Writer) {
+	if n := bw.NrBitsInBuffer(); n != 0 {
+		bw.Write(0, int(8-n))
+	}
+}
+
+func (p *AC4Presentation) decodeV1(payload []byte) error {
+	br := bits.NewAccErrReader(bytes.NewBuffer(payload))
+	p.Config = byte(br.Read(5))
+	if p.Config == 0x06 {
+		p.AddEMDFSubstreams = true
+	} else {
+		p.MDCompat = byte(br.Read(3))
+		p.HasPresentationID = br.ReadFlag()
+		if p.HasPresentationID {
+			p.PresentationID = byte(br.Read(5))
+		}
+		p.FrameRateMultiplyInfo = byte(br.Read(2))
+		p.FrameRateFractionInfo = byte(br.Read(2))
+		p.EMDFVersion = byte(br.Read(5))
+		p.KeyID = uint16(br.Read(10))
+		p.ChannelCoded = br.ReadFlag()
+		if p.ChannelCoded {
+			p.ChannelMode = byte(br.Read(5))
+			if p.ChannelMode >= 11 && p.ChannelMode <= 14 {
+				p.BackChannelsPresent = br.ReadFlag()
+				p.TopChannelPairs = byte(br.Read(2))
+			}
+			p.ChannelMask = uint32(br.Read(24))
+		}
+		p.CoreDiffers = br.ReadFlag()
+		if p.CoreDiffers {
+			p.CoreChannelCoded = br.ReadFlag()
+			if p.CoreChannelCoded {
+				p.CoreChannelMode = byte(br.Read(2))
+			}
+		}
+		p.HasFilter = br.ReadFlag()
+		if p.HasFilter {
+			p.EnablePresentation = br.ReadFlag()
+			nrFilterBytes := int(br.Read(8))
+			p.FilterData = br.ReadBytes(nrFilterBytes)
+		}
+		if p.Config == 0x1f {
+			p.SubstreamGroups = append(p.SubstreamGroups, readAC4SubstreamGroup(br))
+		} else {
+			p.MultiPID = br.ReadFlag()
+			nrGroups := 0
+			switch p.Config {
+			case 0, 1, 2:
+				nrGroups = 2
+			case 3, 4:
+				nrGroups = 3
+			case 5:
+				nrGroups = int(br.Read(3)) + 2
+			default:
+				nrSkipBytes := int(br.Read(7))
+				p.SkipData = br.ReadBytes(nrSkipBytes)
+			}
+			for i := 0; i < nrGroups; i++ {
+				p.SubstreamGroups = append(p.SubstreamGroups, readAC4SubstreamGroup(br))
+			}
+		}
+		p.PreVirtualized = br.ReadFlag()
+		p.AddEMDFSubstreams = br.ReadFlag()
+	}
+	if p.AddEMDFSubstreams {
+		nrAddEMDFSubstreams := int(br.Read(7))
+		for i := 0; i < nrAddEMDFSubstreams; i++ {
+			p.EMDFSubstreams = append(p.EMDFSubstreams, AC4EMDFSubstream{
+				EMDFVersion: byte(br.Read(5)),
+				KeyID:       uint16(br.Read(10)),
+			})
+		}
+	}
+	if br.ReadFlag() {
+		bi := readAC4BitrateInfo(br)
+		p.BitrateInfo = &bi
+	}
+	if br.ReadFlag() {
+		byteAlignAC4(br)
+		ai := AC4AlternativeInfo{}
+		nameLen := int(br.Read(16))
+		ai.Name = string(br.ReadBytes(nameLen))
+		nrTargets := int(br.Read(5))
+		for i := 0; i < nrTargets; i++ {
+			ai.Targets = append(ai.Targets, AC4Target{
+				MDCompat:       byte(br.Read(3)),
+				DeviceCategory: byte(br.Read(8)),
+			})
+		}
+		p.Alternative = &ai
+	}
+	byteAlignAC4(br)
+	if br.AccError() != nil {
+		return br.AccError()
+	}
+	if br.NrBytesRead() <= len(payload)-1 {
+		p.HasExtension = true
+		p.DEIndicator = br.ReadFlag()
+		p.AtmosIndicator = br.ReadFlag()
+		_ = br.Read(4) // reserved
+		p.HasExtendedID = br.ReadFlag()
+		if p.HasExtendedID {
+			p.ExtendedID = uint16(br.Read(9))
+		} else {
+			_ = br.Read(1) // reserved
+		}
+	}
+	p.Trailing = br.ReadRemainingBytes()
+	if len(p.Trailing) == 0 {
+		p.Trailing = nil
+	}
+	return br.AccError()
+}
+
+func readAC4SubstreamGroup(br *bits.AccErrReader) AC4SubstreamGroup {
+	g := AC4SubstreamGroup{}
+	g.SubstreamsPresent = br.ReadFlag()
+	g.HSFExt = br.ReadFlag()
+	g.ChannelCoded = br.ReadFlag()
+	nrSubstreams := int(br.Read(8))
+	for i := 0; i < nrSubstreams; i++ {
+		s := AC4Substream{}
+		s.SFMultiplier = byte(br.Read(2))
+		s.HasBitrateIndicator = br.ReadFlag()
+		if s.HasBitrateIndicator {
+			s.BitrateIndicator = byte(br.Read(5))
+		}
+		if g.ChannelCoded {
+			s.ChannelMask = uint32(br.Read(24))
+		} else {
+			s.AJOC = br.ReadFlag()
+			if s.AJOC {
+				s.StaticDmx = br.ReadFlag()
+				if !s.StaticDmx {
+					s.NrDmxObjectsMinus1 = byte(br.Read(4))
+				}
+				s.NrUmxObjectsMinus1 = byte(br.Read(6))
+			}
+			s.ContainsBedObjects = br.ReadFlag()
+			s.ContainsDynamicObjects = br.ReadFlag()
+			s.ContainsISFObjects = br.ReadFlag()
+			_ = br.Read(1) // reserved
+		}
+		g.Substreams = append(g.Substreams, s)
+	}
+	g.HasContentType = br.ReadFlag()
+	if g.HasContentType {
+		g.ContentClassifier = byte(br.Read(3))
+		if br.ReadFlag() {
+			nrLanguageTagBytes := int(br.Read(6))
+			g.LanguageTag = br.ReadBytes(nrLanguageTagBytes)
+		}
+	}
+	return g
+}
+
+func writeAC4SubstreamGroup(bw *bits.Writer, g AC4SubstreamGroup) {
+	bw.Write(bool2uint(g.SubstreamsPresent), 1)
+	bw.Write(bool2uint(g.HSFExt), 1)
+	bw.Write(bool2uint(g.ChannelCoded), 1)
+	bw.Write(uint(len(g.Substreams)), 8)
+	for _, s := range g.Substreams {
+		bw.Write(uint(s.SFMultiplier), 2)
+		bw.Write(bool2uint(s.HasBitrateIndicator), 1)
+		if s.HasBitrateIndicator {
+			bw.Write(uint(s.BitrateIndicator), 5)
+		}
+		if g.ChannelCoded {
+			bw.Write(uint(s.ChannelMask), 24)
+		} else {
+			bw.Write(bool2uint(s.AJOC), 1)
+			if s.AJOC {
+				bw.Write(bool2uint(s.StaticDmx), 1)
+				if !s.StaticDmx {
+					bw.Write(uint(s.NrDmxObjectsMinus1), 4)
+				}
+				bw.Write(uint(s.NrUmxObjectsMinus1), 6)
+			}
+			bw.Write(bool2uint(s.ContainsBedObjects), 1)
+			bw.Write(bool2uint(s.ContainsDynamicObjects), 1)
+			bw.Write(bool2uint(s.ContainsISFObjects), 1)
+			bw.Write(0, 1) // reserved
+		}
+	}
+	bw.Write(bool2uint(g.HasContentType), 1)
+	if g.HasContentType {
+		bw.Write(uint(g.ContentClassifier), 3)
+		bw.Write(bool2uint(g.LanguageTag != nil), 1)
+		if g.LanguageTag != nil {
+			bw.Write(uint(len(g.LanguageTag)), 6)
+			for _, c := range g.LanguageTag {
+				bw.Write(uint(c), 8)
+			}
+		}
+	}
+}
+
+// payload - presentation bytes after presentation_version and pres_bytes
+func (p *AC4Presentation) payload() ([]byte, error) {
+	if p.Version != 1 && p.Version != 2 {
+		return p.Raw, nil
+	}
+	buf := bytes.Buffer{}
+	bw := bits.NewWriter(&buf)
+	bw.Write(uint(p.Config), 5)
+	if p.Config != 0x06 {
+		bw.Write(uint(p.MDCompat), 3)
+		bw.Write(bool2uint(p.HasPresentationID), 1)
+		if p.HasPresentationID {
+			bw.Write(uint(p.PresentationID), 5)
+		}
+		bw.Write(uint(p.FrameRateMultiplyInfo), 2)
+		bw.Write(uint(p.FrameRateFractionInfo), 2)
+		bw.Write(uint(p.EMDFVersion), 5)
+		bw.Write(uint(p.KeyID), 10)
+		bw.Write(bool2uint(p.ChannelCoded), 1)
+		if p.ChannelCoded {
+			bw.Write(uint(p.ChannelMode), 5)
+			if p.ChannelMode >= 11 && p.ChannelMode <= 14 {
+				bw.Write(bool2uint(p.BackChannelsPresent), 1)
+				bw.Write(uint(p.TopChannelPairs), 2)
+			}
+			bw.Write(uint(p.ChannelMask), 24)
+		}
+		bw.Write(bool2uint(p.CoreDiffers), 1)
+		if p.CoreDiffers {
+			bw.Write(bool2uint(p.CoreChannelCoded), 1)
+			if p.CoreChannelCoded {
+				bw.Write(uint(p.CoreChannelMode), 2)
+			}
+		}
+		bw.Write(bool2uint(p.HasFilter), 1)
+		if p.HasFilter {
+			bw.Write(bool2uint(p.EnablePresentation), 1)
+			bw.Write(uint(len(p.FilterData)), 8)
+			for _, c := range p.FilterData {
+				bw.Write(uint(c), 8)
+			}
+		}
+		if p.Config == 0x1f {
+			if len(p.SubstreamGroups) != 1 {
+				return nil, fmt.Errorf("presentation config 0x1f needs 1 substream group, not %d", len(p.SubstreamGroups))
+			}
+			writeAC4SubstreamGroup(bw, p.SubstreamGroups[0])
+		} else {
+			bw.Write(bool2uint(p.MultiPID), 1)
+			switch p.Config {
+			case 0, 1, 2, 3, 4:
+				nrGroups := 2
+				if p.Config >= 3 {
+					nrGroups = 3
+				}
+				if len(p.SubstreamGroups) != nrGroups {
+					return nil, fmt.Errorf("presentation config %d needs %d substream groups, not %d",
+						p.Config, nrGroups, len(p.SubstreamGroups))
+				}
+			case 5:
+				bw.Write(uint(len(p.SubstreamGroups)-2), 3)
+			default:
+				bw.Write(uint(len(p.SkipData)), 7)
+				for _, c := range p.SkipData {
+					bw.Write(uint(c), 8)
+				}
+			}
+			for _, g := range p.SubstreamGroups {
+				writeAC4SubstreamGroup(bw, g)
+			}
+		}
+		bw.Write(bool2uint(p.PreVirtualized), 1)
+		bw.Write(bool2uint(p.AddEMDFSubstreams), 1)
+	}
+	if p.AddEMDFSubstreams {
+		bw.Write(uint(len(p.EMDFSubstreams)), 7)
+		for _, e := range p.EMDFSubstreams {
+			bw.Write(uint(e.EMDFVersion), 5)
+			bw.Write(uint(e.KeyID), 10)
+		}
+	}
+	bw.Write(bool2uint(p.BitrateInfo != nil), 1)
+	if p.BitrateInfo != nil {
+		writeAC4BitrateInfo(bw, *p.BitrateInfo)
+	}
+	bw.Write(bool2uint(p.Alternative != nil), 1)
+	if p.Alternative != nil {
+		byteAlignAC4Writer(bw)
+		bw.Write(uint(len(p.Alternative.Name)), 16)
+		for _, c := range []byte(p.Alternative.Name) {
+			bw.Write(uint(c), 8)
+		}
+		bw.Write(uint(len(p.Alternative.Targets)), 5)
+		for _, t := range p.Alternative.Targets {
+			bw.Write(uint(t.MDCompat), 3)
+			bw.Write(uint(t.DeviceCategory), 8)
+		}
+	}
+	byteAlignAC4Writer(bw)
+	if p.HasExtension {
+		bw.Write(bool2uint(p.DEIndicator), 1)
+		bw.Write(bool2uint(p.AtmosIndicator), 1)
+		bw.Write(0, 4) // reserved
+		bw.Write(bool2uint(p.HasExtendedID), 1)
+		if p.HasExtendedID {
+			bw.Write(uint(p.ExtendedID), 9)
+		} else {
+			bw.Write(0, 1) // reserved
+		}
+	}
+	for _, c := range p.Trailing {
+		bw.Write(uint(c), 8)
+	}
+	if bw.Error() != nil {
+		return nil, bw.Error()
+	}
+	return buf.Bytes(), nil
+}
+
+// NrChannels - number of channels for a channel-coded presentation, 0 if not known
+func (p *AC4Presentation) NrChannels() int {
+	if !p.ChannelCoded || int(p.ChannelMode) >= len(ac4ChannelModeNrChannels) {
+		return 0
+	}
+	nrChannels := ac4ChannelModeNrChannels[p.ChannelMode]
+	if p.ChannelMode >= 11 && p.ChannelMode <= 14 {
+		if !p.BackChannelsPresent {
+			nrChannels -= 2
+		}
+		nrChannels -= 2 * (2 - int(p.TopChannelPairs))
+	}
+	return nrChannels
+}
+
+// IsIMS - presentation uses Immersive Stereo (presentation_version 2)
+func (p *AC4Presentation) IsIMS() bool {
+	return p.Version == 2
+}
+
+// Type - box type
+func (b *Dac4Box) Type() string {
+	return "dac4"
+}
+
+// headerBytes - ac4_dsi_v1 bytes before the presentations
+func (b *Dac4Box) headerBytes() int {
+	nrBits := 3 + 7 + 1 + 4 + 9
+	if b.BitstreamVersion > 1 {
+		nrBits++
+		if b.HasProgramID {
+			nrBits += 16 + 1
+			if b.ProgramUUID != nil {
+				nrBits += 128
+			}
+		}
+	}
+	nrBits += 66 // ac4_bitrate_dsi
+	return (nrBits + 7) / 8
+}
+
+// Size - calculated size of box
+func (b *Dac4Box) Size() uint64 {
+	size := boxHeaderSize + b.headerBytes()
+	for i := range b.Presentations {
+		payload, err := b.Presentations[i].payload()
+		if err != nil {
+			return 0
+		}
+		size += 2 + len(payload)
+		if len(payload) >= 255 {
+			size += 2
+		}
+	}
+	size += len(b.Reserved)
+	return uint64(size)
+}
+
+// Encode - write box to w
+func (b *Dac4Box) Encode(w io.Writer) error {
+	sw := bits.NewFixedSliceWriter(int(b.Size()))
+	err := b.EncodeSW(sw)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(sw.Bytes())
+	return err
+}
+
+// EncodeSW - write box to sw
+func (b *Dac4Box) EncodeSW(sw bits.SliceWriter) error {
+	err := EncodeHeaderSW(b, sw)
+	if err != nil {
+		return err
+	}
+	sw.WriteBits(uint(b.DSIVersion), 3)
+	sw.WriteBits(uint(b.BitstreamVersion), 7)
+	sw.WriteBits(uint(b.FSIndex), 1)
+	sw.WriteBits(uint(b.FrameRateIndex), 4)
+	sw.WriteBits(uint(len(b.Presentations)), 9)
+	if b.BitstreamVersion > 1 {
+		sw.WriteBits(bool2uint(b.HasProgramID), 1)
+		if b.HasProgramID {
+			sw.WriteBits(uint(b.ShortProgramID), 16)
+			sw.WriteBits(bool2uint(b.ProgramUUID != nil), 1)
+			if b.ProgramUUID != nil {
+				if len(b.ProgramUUID) != 16 {
+					return fmt.Errorf("dac4 program_uuid must be 16 bytes, not %d", len(b.ProgramUUID))
+				}
+				for _, c := range b.ProgramUUID {
+					sw.WriteBits(uint(c), 8)
+				}
+			}
+		}
+	}
+	sw.WriteBits(uint(b.BitrateInfo.Mode), 2)
+	sw.WriteBits(uint(b.BitrateInfo.Bitrate), 32)
+	sw.WriteBits(uint(b.BitrateInfo.Precision), 32)
+	sw.FlushBits() // byte_align
+	for i := range b.Presentations {
+		p := &b.Presentations[i]
+		payload, err := p.payload()
+		if err != nil {
+			return err
+		}
+		sw.WriteUint8(p.Version)
+		if len(payload) < 255 {
+			sw.WriteUint8(byte(len(payload)))
+		} else {
+			sw.WriteUint8(255)
+			sw.WriteUint16(uint16(len(payload) - 255))
+		}
+		sw.WriteBytes(payload)
+	}
+	if len(b.Reserved) > 0 {
+		sw.WriteBytes(b.Reserved)
+	}
+	return sw.AccError()
+}
+
+// SamplingFrequency - base sampling frequency
+func (b *Dac4Box) SamplingFrequency() int {
+	return AC4SampleRates[b.FSIndex]
+}
+
+// CodecString - codecs parameter ac-4.XX.YY.ZZ for first presentation.
+// XX is bitstream_version, YY is presentation_version and ZZ is mdcompat.
+// Defined in ETSI TS 103 190-2 V1.2.1 (2018) Annex E.13.
+func (b *Dac4Box) CodecString() string {
+	var presVersion, mdcompat byte
+	if len(b.Presentations) > 0 {
+		presVersion = b.Presentations[0].Version
+		mdcompat = b.Presentations[0].MDCompat
+	}
+	return fmt.Sprintf("ac-4.%02X.%02X.%02X", b.BitstreamVersion, presVersion, mdcompat)
+}
+
+// Info - write box-specific information
+func (b *Dac4Box) Info(w io.Writer, specificBoxLevels, indent, indentStep string) error {
+	bd := newInfoDumper(w, indent, b, -1, 0)
+	bd.write(" - ac4DSIVersion=%d bitstreamVersion=%d", b.DSIVersion, b.BitstreamVersion)
+	bd.write(" - sampleRateIndex=%d => sampleRate=%d", b.FSIndex, AC4SampleRates[b.FSIndex])
+	frameRate := "unknown"
+	if int(b.FrameRateIndex) < len(AC4FrameRates) {
+		frameRate = AC4FrameRates[b.FrameRateIndex]
+	}
+	bd.write(" - frameRateIndex=%d => frameRate=%s", b.FrameRateIndex, frameRate)
+	if b.HasProgramID {
+		bd.write(" - shortProgramID=%d", b.ShortProgramID)
+		if b.ProgramUUID != nil {
+			bd.write(" - programUUID=%x", b.ProgramUUID)
+		}
+	}
+	bd.write(" - bitrateMode=%d bitrate=%d precision=%d", b.BitrateInfo.Mode, b.BitrateInfo.Bitrate,
+		b.BitrateInfo.Precision)
+	bd.write(" - codecString=%s", b.CodecString())
+	bd.write(" - nrPresentations=%d", len(b.Presentations))
+	for i, p := range b.Presentations {
+		if p.Version != 1 && p.Version != 2 {
+			bd.write("   - %d presentationVersion=%d nrBytes=%d", i+1, p.Version, len(p.Raw))
+			continue
+		}
+		bd.write("   - %d presentationVersion=%d config=%d mdcompat=%d ims=%t", i+1, p.Version, p.Config,
+			p.MDCompat, p.IsIMS())
+		if p.HasPresentationID {
+			bd.write("     presentationID=%d", p.PresentationID)
+		}
+		if p.ChannelCoded {
+			channelMode := "unknown"
+			if int(p.ChannelMode) < len(AC4ChannelModes) {
+				channelMode = AC4ChannelModes[p.ChannelMode]
+			}
+			bd.write("     channelMode=%d (%s) nrChannels=%d channelMask=%06x", p.ChannelMode, channelMode,
+				p.NrChannels(), p.ChannelMask)
+		}
+		if p.HasExtension {
+			bd.write("     dialogEnhancement=%t dolbyAtmos=%t", p.DEIndicator, p.AtmosIndicator)
+		}
+		for j, g := range p.SubstreamGroups {
+			bd.write("     - substreamGroup %d channelCoded=%t nrSubstreams=%d", j+1, g.ChannelCoded, len(g.Substreams))
+			if g.HasContentType {
+				bd.write("       contentClassifier=%d language=%q", g.ContentClassifier, string(g.LanguageTag))
+			}
+		}
+		if p.Alternative != nil {
+			bd.write("     alternativeName=%q", p.Alternative.Name)
+		}
+	}
+	return bd.err
+}
+
+func bool2uint(flag bool) uint {
+	if flag {
+		return 1
+	}
+	return 0
+}
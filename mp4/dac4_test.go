@@ -0,0 +1,148 @@
+package mp4
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/jaypadia-frame/mp4ff/bits"
+)
+
+func TestEncodeDecodeDac4(t *testing.T) {
+	dac4 := &Dac4Box{
+		DSIVersion:       1,
+		BitstreamVersion: 2,
+		FSIndex:          1,
+		FrameRateIndex:   2,
+		HasProgramID:     true,
+		ShortProgramID:   1234,
+		BitrateInfo:      AC4BitrateInfo{Mode: 1, Bitrate: 256000, Precision: 0xffffffff},
+		Presentations: []AC4Presentation{
+			{
+				Version:           1,
+				Config:            0x1f,
+				MDCompat:          3,
+				HasPresentationID: true,
+				PresentationID:    1,
+				KeyID:             5,
+				ChannelCoded:      true,
+				ChannelMode:       12,
+				TopChannelPairs:   2,
+				ChannelMask:       0x0000c7,
+				SubstreamGroups: []AC4SubstreamGroup{
+					{
+						SubstreamsPresent: true,
+						Substreams: []AC4Substream{
+							{AJOC: true, NrUmxObjectsMinus1: 15, ContainsDynamicObjects: true},
+						},
+						HasContentType:    true,
+						ContentClassifier: 0,
+						LanguageTag:       []byte("en"),
+					},
+				},
+				BitrateInfo:    &AC4BitrateInfo{Mode: 2, Bitrate: 128000},
+				Alternative:    &AC4AlternativeInfo{Name: "main", Targets: []AC4Target{{MDCompat: 1, DeviceCategory: 3}}},
+				HasExtension:   true,
+				AtmosIndicator: true,
+				HasExtendedID:  true,
+				ExtendedID:     300,
+			},
+			{
+				Version:        2,
+				Config:         1,
+				ChannelCoded:   true,
+				ChannelMode:    1,
+				ChannelMask:    0x000001,
+				PreVirtualized: true,
+				SubstreamGroups: []AC4SubstreamGroup{
+					{ChannelCoded: true, Substreams: []AC4Substream{{ChannelMask: 1}}},
+					{ChannelCoded: true, Substreams: []AC4Substream{{HasBitrateIndicator: true, BitrateIndicator: 7, ChannelMask: 2}}},
+				},
+			},
+			{
+				Version: 0,
+				Raw:     []byte{0x01, 0x02, 0x03},
+			},
+		},
+	}
+	boxDiffAfterEncodeAndDecode(t, dac4)
+}
+
+func TestDac4CodecStringAndChannels(t *testing.T) {
+	dac4 := &Dac4Box{
+		BitstreamVersion: 2,
+		FSIndex:          1,
+		Presentations: []AC4Presentation{
+			{Version: 1, Config: 0x1f, MDCompat: 3, ChannelCoded: true, ChannelMode: 12,
+				BackChannelsPresent: true, TopChannelPairs: 2,
+				SubstreamGroups: []AC4SubstreamGroup{{ChannelCoded: true}}},
+			{Version: 2, Config: 0x06, AddEMDFSubstreams: true,
+				EMDFSubstreams: []AC4EMDFSubstream{{EMDFVersion: 1, KeyID: 2}}},
+		},
+	}
+	if got := dac4.CodecString(); got != "ac-4.02.01.03" {
+		t.Errorf("got codec string %q instead of ac-4.02.01.03", got)
+	}
+	if got := dac4.Presentations[0].NrChannels(); got != 12 {
+		t.Errorf("got %d channels instead of 12", got)
+	}
+	if dac4.Presentations[0].IsIMS() || !dac4.Presentations[1].IsIMS() {
+		t.Errorf("wrong IMS signaling")
+	}
+	if got := dac4.SamplingFrequency(); got != 48000 {
+		t.Errorf("got sampling frequency %d instead of 48000", got)
+	}
+	buf := bytes.Buffer{}
+	err := dac4.Info(&buf, "", "", "  ")
+	if err != nil {
+		t.Error(err)
+	}
+	boxDiffAfterEncodeAndDecode(t, dac4)
+
+	ase := CreateAudioSampleEntryBox("ac-4", 2, 16, 48000, dac4)
+	aseDec := boxAfterEncodeAndDecode(t, ase).(*AudioSampleEntryBox)
+	if aseDec.Dac4 == nil || aseDec.Dac4.CodecString() != "ac-4.02.01.03" {
+		t.Errorf("ac-4 sample entry did not get dac4 child")
+	}
+}
+
+// TestDecodeDac4 - dac4 box of a 5.1 AC-4 stream with one presentation at 48kHz and 25fps
+func TestDecodeDac4(t *testing.T) {
+	dac4Hex := "000000246461633420a601400000001fffffffe0010ef9000009000011ca02000011c080"
+	dac4Bytes, err := hex.DecodeString(dac4Hex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	box, err := DecodeBoxSR(0, bits.NewFixedSliceReader(dac4Bytes))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dac4 := box.(*Dac4Box)
+	if dac4.DSIVersion != 1 || dac4.BitstreamVersion != 2 || dac4.FrameRateIndex != 3 || dac4.HasProgramID {
+		t.Errorf("got dsi version %d, bitstream version %d, frame rate index %d", dac4.DSIVersion,
+			dac4.BitstreamVersion, dac4.FrameRateIndex)
+	}
+	if got := dac4.SamplingFrequency(); got != 48000 {
+		t.Errorf("got sampling frequency %d instead of 48000", got)
+	}
+	if len(dac4.Presentations) != 1 {
+		t.Fatalf("got %d presentations instead of 1", len(dac4.Presentations))
+	}
+	p := dac4.Presentations[0]
+	if p.Version != 1 || p.MDCompat != 1 || !p.ChannelCoded || p.ChannelMask != 0x47 || len(p.SubstreamGroups) != 1 {
+		t.Errorf("got presentation version %d, mdcompat %d, channel mask %06x", p.Version, p.MDCompat, p.ChannelMask)
+	}
+	if got := p.NrChannels(); got != 6 {
+		t.Errorf("got %d channels instead of 6", got)
+	}
+	if got := dac4.CodecString(); got != "ac-4.02.01.01" {
+		t.Errorf("got codec string %q instead of ac-4.02.01.01", got)
+	}
+	buf := bytes.Buffer{}
+	if err := dac4.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), dac4Bytes) {
+		t.Errorf("encoded dac4 %x differs from %s", buf.Bytes(), dac4Hex)
+	}
+}
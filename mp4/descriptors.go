@@ -19,7 +19,14 @@ const (
 	DecSpecificInfoTag    = 5
 	SLConfigDescrTag      = 6
 
-	minimalEsDescrSize = 25
+	minimalEsDescrSize = 23 // Without DecSpecificInfo
+)
+
+// Object type indications for audio in DecoderConfigDescriptor (ISO/IEC 14496-1 Table 5)
+const (
+	ObjectTypeMPEG4Audio = 0x40 // ISO/IEC 14496-3 with AudioSpecificConfig
+	ObjectTypeMPEG2Audio = 0x69 // ISO/IEC 13818-3 (MPEG-2 layer 1-3) without DecSpecificInfo
+	ObjectTypeMPEG1Audio = 0x6B // ISO/IEC 11172-3 (MPEG-1 layer 1-3) without DecSpecificInfo
 )
 
 type Descriptor interface {
@@ -200,6 +207,8 @@ func (e *ESDescriptor) EncodeSW(sw bits.SliceWriter) error {
 	return sw.AccError()
 }
 
+// DecoderConfigDescriptor - DecoderConfigDescriptor in ISO/IEC 14496-1 7.2.6.6.
+// DecSpecificInfo is not present if its DecConfig is nil.
 type DecoderConfigDescriptor struct {
 	ObjectType          byte
 	StreamType          byte
@@ -229,9 +238,11 @@ func DecodeDecoderConfigDescriptor(sr bits.SliceReader) (DecoderConfigDescriptor
 	dd.BufferSizeDB = streamTypeAndBufferSizeDB & 0xffffff
 	dd.MaxBitrate = sr.ReadUint32()
 	dd.AvgBitrate = sr.ReadUint32()
-	dd.DecSpecificInfo, err = DecodeDecSpecificInfoDescriptor(sr)
-	if err != nil {
-		return dd, err
+	if size > 13 {
+		dd.DecSpecificInfo, err = DecodeDecSpecificInfoDescriptor(sr)
+		if err != nil {
+			return dd, err
+		}
 	}
 	if size != dd.Size() {
 		return dd, fmt.Errorf("read size %d differs from calculated size %d", size, dd.Size())
@@ -244,6 +255,9 @@ func (d *DecoderConfigDescriptor) Tag() byte {
 }
 
 func (d *DecoderConfigDescriptor) Size() uint32 {
+	if d.DecSpecificInfo.DecConfig == nil {
+		return 13
+	}
 	return 13 + d.DecSpecificInfo.SizeSize()
 }
 
@@ -259,6 +273,9 @@ func (d *DecoderConfigDescriptor) EncodeSW(sw bits.SliceWriter) error {
 	sw.WriteUint32(streamTypeAndBufferSizeDB)
 	sw.WriteUint32(d.MaxBitrate)
 	sw.WriteUint32(d.AvgBitrate)
+	if d.DecSpecificInfo.DecConfig == nil {
+		return sw.AccError()
+	}
 	err := d.DecSpecificInfo.EncodeSW(sw)
 	if err != nil {
 		return err
@@ -400,11 +417,16 @@ func (d *RawDescriptor) EncodeSW(sw bits.SliceWriter) error {
 	return sw.AccError()
 }
 
+// CreateESDescriptor - ESDescriptor for MPEG-4 audio with decConfig in DecSpecificInfo.
+// A nil decConfig gives an empty DecSpecificInfo. Set DecConfig to nil afterwards to leave it out.
 func CreateESDescriptor(decConfig []byte) ESDescriptor {
+	if decConfig == nil {
+		decConfig = []byte{}
+	}
 	e := ESDescriptor{
 		EsID: 0x01,
 		DecConfigDescriptor: DecoderConfigDescriptor{
-			ObjectType: 0x40, // Audio ISO/IEC 14496-3,
+			ObjectType: ObjectTypeMPEG4Audio,
 			StreamType: 0x15, // 0x5 << 2 + 0x01 (audioType + upstreamFlag + reserved)
 			DecSpecificInfo: DecSpecificInfoDescriptor{
 				DecConfig: decConfig,
@@ -0,0 +1,139 @@
+package mp4
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/jaypadia-frame/mp4ff/bits"
+)
+
+// DvcCBox - Dolby Vision configuration box (dvcC, dvvC or dvwC) with DOVIDecoderConfigurationRecord
+// Dolby Vision Streams within the ISO Base Media File Format v2.x.
+// dvcC is used for profiles up to 7, dvvC for profiles 8-10, and dvwC for higher profiles.
+type DvcCBox struct {
+	name                      string
+	DVVersionMajor            byte
+	DVVersionMinor            byte
+	DVProfile                 byte
+	DVLevel                   byte
+	RPUPresentFlag            bool
+	ELPresentFlag             bool
+	BLPresentFlag             bool
+	DVBLSignalCompatibilityID byte
+}
+
+// CreateDvcCBox - create Dolby Vision config box version 1.0 with box type given by profile
+func CreateDvcCBox(profile, level byte, rpuPresent, elPresent, blPresent bool, blSignalCompatibilityID byte) *DvcCBox {
+	name := "dvcC"
+	switch {
+	case profile > 10:
+		name = "dvwC"
+	case profile > 7:
+		name = "dvvC"
+	}
+	return &DvcCBox{
+		name:                      name,
+		DVVersionMajor:            1,
+		DVVersionMinor:            0,
+		DVProfile:                 profile,
+		DVLevel:                   level,
+		RPUPresentFlag:            rpuPresent,
+		ELPresentFlag:             elPresent,
+		BLPresentFlag:             blPresent,
+		DVBLSignalCompatibilityID: blSignalCompatibilityID,
+	}
+}
+
+// DecodeDvcC - box-specific decode of dvcC, dvvC, and dvwC
+func DecodeDvcC(hdr BoxHeader, startPos uint64, r io.Reader) (Box, error) {
+	data, err := readBoxBody(r, hdr)
+	if err != nil {
+		return nil, err
+	}
+	sr := bits.NewFixedSliceReader(data)
+	return DecodeDvcCSR(hdr, startPos, sr)
+}
+
+// DecodeDvcCSR - box-specific decode of dvcC, dvvC, and dvwC
+func DecodeDvcCSR(hdr BoxHeader, startPos uint64, sr bits.SliceReader) (Box, error) {
+	if hdr.payloadLen() != 24 {
+		return nil, fmt.Errorf("%s: payload size %d instead of 24", hdr.Name, hdr.payloadLen())
+	}
+	b := &DvcCBox{name: hdr.Name}
+	b.DVVersionMajor = sr.ReadUint8()
+	b.DVVersionMinor = sr.ReadUint8()
+	v := sr.ReadUint16()
+	b.DVProfile = byte(v >> 9)
+	b.DVLevel = byte((v >> 3) & 0x3f)
+	b.RPUPresentFlag = (v>>2)&1 == 1
+	b.ELPresentFlag = (v>>1)&1 == 1
+	b.BLPresentFlag = v&1 == 1
+	b.DVBLSignalCompatibilityID = sr.ReadUint8() >> 4
+	sr.SkipBytes(19) // reserved
+	return b, sr.AccError()
+}
+
+// Type - box type
+func (b *DvcCBox) Type() string {
+	return b.name
+}
+
+// Size - calculated size of box
+func (b *DvcCBox) Size() uint64 {
+	return uint64(boxHeaderSize + 24)
+}
+
+// Encode - write box to w
+func (b *DvcCBox) Encode(w io.Writer) error {
+	sw := bits.NewFixedSliceWriter(int(b.Size()))
+	err := b.EncodeSW(sw)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(sw.Bytes())
+	return err
+}
+
+// EncodeSW - box-specific encode to slicewriter
+func (b *DvcCBox) EncodeSW(sw bits.SliceWriter) error {
+	err := EncodeHeaderSW(b, sw)
+	if err != nil {
+		return err
+	}
+	sw.WriteUint8(b.DVVersionMajor)
+	sw.WriteUint8(b.DVVersionMinor)
+	v := uint16(b.DVProfile)<<9 | uint16(b.DVLevel&0x3f)<<3 |
+		uint16(bool2uint(b.RPUPresentFlag))<<2 | uint16(bool2uint(b.ELPresentFlag))<<1 | uint16(bool2uint(b.BLPresentFlag))
+	sw.WriteUint16(v)
+	sw.WriteUint8(b.DVBLSignalCompatibilityID << 4)
+	sw.WriteZeroBytes(19)
+	return sw.AccError()
+}
+
+// CodecString - codecs parameter like dvh1.08.06 for sampleEntry dvh1.
+// For backwards-compatible sample entries (hvc1, hev1, avc1, avc3),
+// the corresponding Dolby Vision sample entry type is used.
+func (b *DvcCBox) CodecString(sampleEntry string) string {
+	switch sampleEntry {
+	case "hvc1":
+		sampleEntry = "dvh1"
+	case "hev1":
+		sampleEntry = "dvhe"
+	case "avc1":
+		sampleEntry = "dva1"
+	case "avc3":
+		sampleEntry = "dvav"
+	}
+	return fmt.Sprintf("%s.%02d.%02d", sampleEntry, b.DVProfile, b.DVLevel)
+}
+
+// Info - write box-specific information
+func (b *DvcCBox) Info(w io.Writer, specificBoxLevels, indent, indentStep string) error {
+	bd := newInfoDumper(w, indent, b, -1, 0)
+	bd.write(" - dvVersion: %d.%d", b.DVVersionMajor, b.DVVersionMinor)
+	bd.write(" - dvProfile: %d", b.DVProfile)
+	bd.write(" - dvLevel: %d", b.DVLevel)
+	bd.write(" - rpuPresent: %t, elPresent: %t, blPresent: %t", b.RPUPresentFlag, b.ELPresentFlag, b.BLPresentFlag)
+	bd.write(" - dvBLSignalCompatibilityID: %d", b.DVBLSignalCompatibilityID)
+	return bd.err
+}
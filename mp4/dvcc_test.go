@@ -0,0 +1,59 @@
+package mp4
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestEncDecDvcC(t *testing.T) {
+	testCases := []struct {
+		profile     byte
+		level       byte
+		compatID    byte
+		boxType     string
+		sampleEntry string
+		codec       string
+	}{
+		{profile: 5, level: 6, compatID: 0, boxType: "dvcC", sampleEntry: "dvh1", codec: "dvh1.05.06"},
+		{profile: 8, level: 6, compatID: 1, boxType: "dvvC", sampleEntry: "hvc1", codec: "dvh1.08.06"},
+		{profile: 9, level: 5, compatID: 2, boxType: "dvvC", sampleEntry: "avc3", codec: "dvav.09.05"},
+		{profile: 20, level: 13, compatID: 0, boxType: "dvwC", sampleEntry: "dvhe", codec: "dvhe.20.13"},
+	}
+	for _, tc := range testCases {
+		b := CreateDvcCBox(tc.profile, tc.level, true, false, true, tc.compatID)
+		if b.Type() != tc.boxType {
+			t.Errorf("got box type %s instead of %s", b.Type(), tc.boxType)
+		}
+		if codec := b.CodecString(tc.sampleEntry); codec != tc.codec {
+			t.Errorf("got codec %s instead of %s", codec, tc.codec)
+		}
+		boxDiffAfterEncodeAndDecode(t, b)
+	}
+}
+
+func TestDolbyVisionInitSegment(t *testing.T) {
+	vps, _ := hex.DecodeString(vpsHex)
+	sps, _ := hex.DecodeString(spsHex)
+	pps, _ := hex.DecodeString(ppsHex)
+
+	init := CreateEmptyInit()
+	init.AddEmptyTrack(90000, "video", "und")
+	trak := init.Moov.Trak
+	dvcC := CreateDvcCBox(8, 6, true, false, true, 1)
+	err := trak.SetDolbyVisionDescriptor("avc1", [][]byte{vps}, [][]byte{sps}, [][]byte{pps}, dvcC)
+	if err == nil {
+		t.Error("expected error for avc1 sample entry")
+	}
+	err = trak.SetDolbyVisionDescriptor("dvh1", [][]byte{vps}, [][]byte{sps}, [][]byte{pps}, dvcC)
+	if err != nil {
+		t.Error(err)
+	}
+	stsd := trak.Mdia.Minf.Stbl.Stsd
+	if stsd.DvX == nil || stsd.HvcX != nil {
+		t.Fatalf("dvh1 sample entry not set correctly in stsd")
+	}
+	if stsd.DvX.HvcC == nil || stsd.DvX.DvcC != dvcC {
+		t.Errorf("dvh1 sample entry lacks hvcC or dvvC")
+	}
+	boxDiffAfterEncodeAndDecode(t, stsd.DvX)
+}
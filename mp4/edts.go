@@ -55,8 +55,11 @@ func DecodeEdtsSR(hdr BoxHeader, startPos uint64, sr bits.SliceReader) (Box, err
 	return e, sr.AccError()
 }
 
-// AddChild - Add a child box and update EntryCount
+// AddChild - Add a child box and update Elst
 func (e *EdtsBox) AddChild(child Box) {
+	if elst, ok := child.(*ElstBox); ok {
+		e.Elst = append(e.Elst, elst)
+	}
 	e.Children = append(e.Children, child)
 }
 
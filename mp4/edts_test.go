@@ -0,0 +1,15 @@
+package mp4
+
+import (
+	"testing"
+)
+
+func TestEdtsAddChild(t *testing.T) {
+	elst := &ElstBox{Entries: []ElstEntry{{1000, 1234, 1, 0}}}
+	edts := &EdtsBox{}
+	edts.AddChild(elst)
+	if len(edts.Elst) != 1 || edts.Elst[0] != elst {
+		t.Errorf("got Elst %v after AddChild", edts.Elst)
+	}
+	boxDiffAfterEncodeAndDecode(t, edts)
+}
@@ -1,11 +1,13 @@
 package mp4
 
 import (
+	"bytes"
 	"encoding/hex"
 	"fmt"
 	"io"
 
 	"github.com/edgeware/mp4ff/bits"
+	"github.com/jaypadia-frame/mp4ff/aac"
 )
 
 // EsdsBox as used for MPEG-audio, see ISO 14496-1 Section 7.2.6.6  for DecoderConfigDescriptor
@@ -99,3 +101,17 @@ func (e *EsdsBox) Info(w io.Writer, specificBoxLevels, indent, indentStep string
 	bd.write(" - decConfig: %s", hex.EncodeToString(e.DecConfigDescriptor.DecSpecificInfo.DecConfig))
 	return bd.err
 }
+
+// CodecString - RFC 6381 codec string for mp4a sample entry, like mp4a.40.2 for AAC-LC or mp4a.6B for MPEG-1 audio.
+// For MPEG-4 audio, the audio object type is taken from the AudioSpecificConfig.
+func (e *EsdsBox) CodecString() string {
+	dcd := e.DecConfigDescriptor
+	if dcd.ObjectType != ObjectTypeMPEG4Audio {
+		return fmt.Sprintf("mp4a.%02X", dcd.ObjectType)
+	}
+	asc, err := aac.DecodeAudioSpecificConfig(bytes.NewReader(dcd.DecSpecificInfo.DecConfig))
+	if err != nil {
+		return "mp4a.40"
+	}
+	return fmt.Sprintf("mp4a.40.%d", asc.ObjectType)
+}
@@ -12,6 +12,8 @@ const (
 	esdsProgIn   = `00000036657364730000000003808080250002000480808017401500000000010d88000003f80580808005128856e500068080800102`
 	esdsMp4Box   = `0000002a6573647300000000031c0000000414401500000000010d88000003f80505128856e500060102`
 	esdsEncAudio = `0000003365736473000000000380808022000000048080801440150018000003eb100002710005808080021190068080800102`
+	esdsMP3      = `0000002365736473000000000315000000040d6b150000000001f4000001f400060102`
+	esdsEmptyDSI = `0000002565736473000000000317000000040f6b150000000001f4000001f4000500060102`
 )
 
 func TestEsdsEncodeAndDecode(t *testing.T) {
@@ -38,8 +40,26 @@ func TestEsdsEncodeAndDecode(t *testing.T) {
 			hex.EncodeToString(decCfgOut), hex.EncodeToString(decCfg))
 	}
 }
+
+func TestEsdsWithoutDecSpecificInfo(t *testing.T) {
+	esds := CreateEsdsBox(nil)
+	if esds.Size() != 37 {
+		t.Errorf("got size %d with empty DecSpecificInfo instead of 37", esds.Size())
+	}
+	esds.DecConfigDescriptor.ObjectType = 0x6b
+	esds.DecConfigDescriptor.DecSpecificInfo.DecConfig = nil
+	var buf bytes.Buffer
+	if err := esds.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if got := hex.EncodeToString(buf.Bytes()); got != "0000002365736473000000000315000100040d6b150000000000000000000000060102" {
+		t.Errorf("got esds %s", got)
+	}
+	boxDiffAfterEncodeAndDecode(t, esds)
+}
+
 func TestDecodeEncodeEsds(t *testing.T) {
-	inputs := []string{esdsProgIn, esdsMp4Box, esdsEncAudio}
+	inputs := []string{esdsProgIn, esdsMp4Box, esdsEncAudio, esdsMP3, esdsEmptyDSI}
 	for i, inp := range inputs {
 		data, err := hex.DecodeString(inp)
 		if err != nil {
@@ -59,6 +79,32 @@ func TestDecodeEncodeEsds(t *testing.T) {
 		if !bytes.Equal(sw.Bytes(), data) {
 			t.Errorf("case %d does not reproduce esds", i)
 		}
+		if esds.Size() != uint64(len(data)) {
+			t.Errorf("case %d: got size %d instead of %d", i, esds.Size(), len(data))
+		}
 
 	}
 }
+
+func TestEsdsCodecString(t *testing.T) {
+	testCases := []struct {
+		hexData string
+		want    string
+	}{
+		{esdsMp4Box, "mp4a.40.2"},
+		{esdsMP3, "mp4a.6B"},
+	}
+	for _, tc := range testCases {
+		data, err := hex.DecodeString(tc.hexData)
+		if err != nil {
+			t.Fatal(err)
+		}
+		box, err := DecodeBoxSR(0, bits.NewFixedSliceReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := box.(*EsdsBox).CodecString(); got != tc.want {
+			t.Errorf("got %s instead of %s", got, tc.want)
+		}
+	}
+}
@@ -277,7 +277,15 @@ func (f *File) DumpWithSampleData(w io.Writer, specificBoxLevels string) error {
 				if err != nil {
 					return err
 				}
-				err = frag.DumpSampleData(w, f.Init.Moov.Mvex.Trex)
+				lengthSize := 4
+				for _, trak := range f.Init.Moov.Traks {
+					if trak.Tkhd.TrackID == frag.Moof.Traf.Tfhd.TrackID && len(trak.Mdia.Minf.Stbl.Stsd.Children) > 0 {
+						if size := NaluLengthSize(trak.Mdia.Minf.Stbl.Stsd.Children[0]); size > 0 {
+							lengthSize = size
+						}
+					}
+				}
+				err = frag.DumpSampleDataWithLengthSize(w, f.Init.Moov.Mvex.Trex, lengthSize)
 				if err != nil {
 					w.Close()
 					return err
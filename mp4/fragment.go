@@ -6,6 +6,7 @@ import (
 	"io"
 	"sort"
 
+	"github.com/edgeware/mp4ff/avc"
 	"github.com/edgeware/mp4ff/bits"
 )
 
@@ -234,6 +235,11 @@ func (f *Fragment) AddSampleToTrack(s Sample, trackID uint32, baseMediaDecodeTim
 
 // DumpSampleData - Get Sample data and print out
 func (f *Fragment) DumpSampleData(w io.Writer, trex *TrexBox) error {
+	return f.DumpSampleDataWithLengthSize(w, trex, 4)
+}
+
+// DumpSampleDataWithLengthSize - Get Sample data with NAL unit length fields of lengthSize bytes and print out
+func (f *Fragment) DumpSampleDataWithLengthSize(w io.Writer, trex *TrexBox, lengthSize int) error {
 	samples, err := f.GetFullSamples(trex)
 	if err != nil {
 		return err
@@ -243,9 +249,12 @@ func (f *Fragment) DumpSampleData(w io.Writer, trex *TrexBox) error {
 			fmt.Printf("%4d %8d %8d %6x %d %d\n", i, s.DecodeTime, s.PresentationTime(),
 				s.Flags, s.Size, len(s.Data))
 		}
-		toAnnexB(s.Data)
+		data, err := avc.ConvertSampleToByteStreamWithLengthSize(s.Data, lengthSize)
+		if err != nil {
+			return err
+		}
 		if w != nil {
-			_, err := w.Write(s.Data)
+			_, err := w.Write(data)
 			if err != nil {
 				return err
 			}
@@ -397,6 +406,50 @@ func (f *Fragment) GetSampleInterval(trex *TrexBox, startSampleNr, endSampleNr u
 	return trun.GetSampleInterval(startSampleNr, endSampleNr, traf.Tfdt.BaseMediaDecodeTime, f.Mdat, offsetInMdat)
 }
 
+// SetSampleData - replace the media data of all samples in a fragment with only one track.
+// data must have one entry per sample in trun order. The sample sizes and data offsets
+// of the trun boxes are updated, and the mdat data is replaced by the concatenated samples.
+func (f *Fragment) SetSampleData(data [][]byte) error {
+	moof := f.Moof
+	if len(moof.Trafs) != 1 {
+		return fmt.Errorf("Not exactly one track in fragment")
+	}
+	traf := moof.Traf
+	if traf.Tfhd.HasBaseDataOffset() {
+		return fmt.Errorf("tfhd base data offset not supported")
+	}
+	if f.Mdat == nil || f.Mdat.IsLazy() {
+		return fmt.Errorf("mdat data not available")
+	}
+	var nrSamples, totalSize int
+	for _, trun := range traf.Truns {
+		nrSamples += len(trun.Samples)
+	}
+	if nrSamples != len(data) {
+		return fmt.Errorf("%d sample data for %d samples", len(data), nrSamples)
+	}
+	for _, d := range data {
+		totalSize += len(d)
+	}
+	mdatData := make([]byte, 0, totalSize)
+	i := 0
+	for _, trun := range traf.Truns {
+		trun.Flags |= TrunDataOffsetPresentFlag | TrunSampleSizePresentFlag
+		for j := range trun.Samples {
+			trun.Samples[j].Size = uint32(len(data[i]))
+			mdatData = append(mdatData, data[i]...)
+			i++
+		}
+	}
+	f.Mdat.SetData(mdatData)
+	dataOffset := moof.Size() + f.Mdat.HeaderSize()
+	for _, trun := range traf.Truns {
+		trun.DataOffset = int32(dataOffset)
+		dataOffset += trun.SizeOfData()
+	}
+	return nil
+}
+
 // AddSampleInterval - add SampleInterval for a fragment with only one track
 func (f *Fragment) AddSampleInterval(sItvl SampleInterval) error {
 	moof := f.Moof
@@ -41,7 +41,7 @@ func CreateHdlr(mediaOrHdlrType string) (*HdlrBox, error) {
 		hdlr.HandlerType = "text"
 		hdlr.Name = "mp4ff text handler"
 	case "clcp":
-		hdlr.HandlerType = "subt"
+		hdlr.HandlerType = "clcp"
 		hdlr.Name = "mp4ff closed captions handler"
 	default:
 		if len(mediaOrHdlrType) != 4 {
@@ -39,3 +39,13 @@ func TestHdlrDecodeMissingNullTermination(t *testing.T) {
 		t.Errorf("Expected empty name, but got %s", hdlr.Name)
 	}
 }
+
+// TestCreateHdlrClosedCaptions - clcp tracks have handler type clcp as in QuickTime, not subt
+func TestCreateHdlrClosedCaptions(t *testing.T) {
+	hdlr, err := CreateHdlr("clcp")
+	assertNoError(t, err)
+	if hdlr.HandlerType != "clcp" {
+		t.Errorf("got handler type %s instead of clcp", hdlr.HandlerType)
+	}
+	boxDiffAfterEncodeAndDecode(t, hdlr)
+}
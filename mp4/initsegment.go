@@ -208,29 +208,61 @@ func (t *TrakBox) SetHEVCDescriptor(sampleDescriptorType string, vpsNALUs, spsNA
 	if sampleDescriptorType != "hvc1" && sampleDescriptorType != "hev1" {
 		return fmt.Errorf("sampleDescriptorType %s not allowed", sampleDescriptorType)
 	}
+	hvcx, err := t.createHEVCSampleEntry(sampleDescriptorType, vpsNALUs, spsNALUs, ppsNALUs, includePS)
+	if err != nil {
+		return err
+	}
+	t.Mdia.Minf.Stbl.Stsd.AddChild(hvcx)
+	return nil
+}
+
+// SetDolbyVisionDescriptor - Set Dolby Vision HEVC SampleDescriptor with hvcC and a Dolby Vision config box.
+// sampleDescriptorType is dvh1 or dvhe for streams without backwards-compatible base layer (e.g. profile 5),
+// and hvc1 or hev1 for streams where the base layer is backwards-compatible (e.g. profile 8.1).
+func (t *TrakBox) SetDolbyVisionDescriptor(sampleDescriptorType string, vpsNALUs, spsNALUs, ppsNALUs [][]byte,
+	dvcC *DvcCBox) error {
+	var hevcType string
+	switch sampleDescriptorType {
+	case "dvh1", "hvc1":
+		hevcType = "hvc1"
+	case "dvhe", "hev1":
+		hevcType = "hev1"
+	default:
+		return fmt.Errorf("sampleDescriptorType %s not allowed", sampleDescriptorType)
+	}
+	sampleEntry, err := t.createHEVCSampleEntry(hevcType, vpsNALUs, spsNALUs, ppsNALUs, true)
+	if err != nil {
+		return err
+	}
+	sampleEntry.SetType(sampleDescriptorType)
+	sampleEntry.AddChild(dvcC)
+	t.Mdia.Minf.Stbl.Stsd.AddChild(sampleEntry)
+	return nil
+}
+
+// createHEVCSampleEntry - create HEVC sample entry and set tkhd width and height
+func (t *TrakBox) createHEVCSampleEntry(sampleDescriptorType string, vpsNALUs, spsNALUs, ppsNALUs [][]byte,
+	includePS bool) (*VisualSampleEntryBox, error) {
 	hevcSPS, err := hevc.ParseSPSNALUnit(spsNALUs[0])
 	if err != nil {
-		return fmt.Errorf("Could not parse SPS NALU: %w", err)
+		return nil, fmt.Errorf("Could not parse SPS NALU: %w", err)
 	}
 	width, height := hevcSPS.ImageSize()
 	t.Tkhd.Width = Fixed32(width << 16)   // This is display width
 	t.Tkhd.Height = Fixed32(height << 16) // This is display height
-	stsd := t.Mdia.Minf.Stbl.Stsd
 
 	// hvc1 must include parameter sets (PS) and they must be complete
 	// hev1 may include PS and they may not be complete
 	// here we choose to include PS in both cases
 	completePS := sampleDescriptorType == "hvc1"
 	if sampleDescriptorType == "hvc1" && !includePS {
-		return fmt.Errorf("must include parameter sets for hvc1")
+		return nil, fmt.Errorf("must include parameter sets for hvc1")
 	}
 	hvcC, err := CreateHvcC(vpsNALUs, spsNALUs, ppsNALUs, completePS, completePS, completePS, includePS)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	avcx := CreateVisualSampleEntryBox(sampleDescriptorType, uint16(width), uint16(height), hvcC)
-	stsd.AddChild(avcx)
-	return nil
+	return CreateVisualSampleEntryBox(sampleDescriptorType, uint16(width), uint16(height), hvcC), nil
 }
 
 // GetMediaType - should return video or audio (at present)
@@ -249,7 +281,6 @@ func (s *InitSegment) GetMediaType() string {
 // objType is one of AAClc, HEAACv1, HEAACv2
 // For HEAAC, the samplingFrequency is the base frequency (normally 24000)
 func (t *TrakBox) SetAACDescriptor(objType byte, samplingFrequency int) error {
-	stsd := t.Mdia.Minf.Stbl.Stsd
 	asc := &aac.AudioSpecificConfig{
 		ObjectType:           objType,
 		ChannelConfiguration: 2,
@@ -268,7 +299,13 @@ func (t *TrakBox) SetAACDescriptor(objType byte, samplingFrequency int) error {
 		asc.ChannelConfiguration = 1
 		asc.PSPresentFlag = true
 	}
+	return t.SetAACDescriptorFromConfig(asc)
+}
 
+// SetAACDescriptorFromConfig - Modify a TrakBox by adding an AAC SampleDescriptor with the given AudioSpecificConfig
+// The sample rate in the sample entry is the base sampling frequency of the config
+func (t *TrakBox) SetAACDescriptorFromConfig(asc *aac.AudioSpecificConfig) error {
+	stsd := t.Mdia.Minf.Stbl.Stsd
 	buf := &bytes.Buffer{}
 	err := asc.Encode(buf)
 	if err != nil {
@@ -276,9 +313,10 @@ func (t *TrakBox) SetAACDescriptor(objType byte, samplingFrequency int) error {
 	}
 	ascBytes := buf.Bytes()
 	esds := CreateEsdsBox(ascBytes)
+	nrChannels := uint16(asc.ChannelCount())
 	mp4a := CreateAudioSampleEntryBox("mp4a",
-		uint16(asc.ChannelConfiguration),
-		16, uint16(samplingFrequency), esds)
+		nrChannels,
+		16, uint16(asc.SamplingFrequency), esds)
 	stsd.AddChild(mp4a)
 	return nil
 }
@@ -310,6 +348,18 @@ func (t *TrakBox) SetEC3Descriptor(dec3 *Dec3Box) error {
 	return nil
 }
 
+// SetAC4Descriptor  - Modify a TrakBox by adding AC-4 SampleDescriptor
+func (t *TrakBox) SetAC4Descriptor(dac4 *Dac4Box) error {
+	stsd := t.Mdia.Minf.Stbl.Stsd
+	samplingFrequency := dac4.SamplingFrequency()
+
+	ac4 := CreateAudioSampleEntryBox("ac-4",
+		2, //  Fixed value according to ETSI TS 103 190-2 E.4
+		16, uint16(samplingFrequency), dac4)
+	stsd.AddChild(ac4)
+	return nil
+}
+
 // SetWvttDescriptor - Set wvtt descriptor with a vttC box. config should start with WEBVTT or be empty.
 func (t *TrakBox) SetWvttDescriptor(config string) error {
 	if config == "" {
@@ -322,6 +372,15 @@ func (t *TrakBox) SetWvttDescriptor(config string) error {
 	return nil
 }
 
+// SetClosedCaptionDescriptor - Set c608 or c708 closed caption sample entry for a clcp track
+func (t *TrakBox) SetClosedCaptionDescriptor(sampleDescriptorType string) error {
+	if sampleDescriptorType != "c608" && sampleDescriptorType != "c708" {
+		return fmt.Errorf("sampleDescriptorType %s not c608 or c708", sampleDescriptorType)
+	}
+	t.Mdia.Minf.Stbl.Stsd.AddChild(NewClosedCaptionSampleEntryBox(sampleDescriptorType))
+	return nil
+}
+
 // SetStppDescriptor - add stpp box with utf8-lists namespace, schemaLocation and auxiliaryMimeType
 // The utf8-lists have space-separated items, but no zero-termination
 func (t *TrakBox) SetStppDescriptor(namespace, schemaLocation, auxiliaryMimeTypes string) error {
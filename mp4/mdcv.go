@@ -0,0 +1,93 @@
+package mp4
+
+import (
+	"io"
+
+	"github.com/jaypadia-frame/mp4ff/bits"
+)
+
+// MdcvBox - MasteringDisplayColourVolumeBox (SMPTE ST 2086) ISO/IEC 14496-12 2022 Sec. 12.1.8
+// Same syntax as the corresponding SEI message.
+// Primaries and white point are in units of 0.00002, luminances in units of 0.0001 cd/m2.
+type MdcvBox struct {
+	DisplayPrimariesX            [3]uint16
+	DisplayPrimariesY            [3]uint16
+	WhitePointX                  uint16
+	WhitePointY                  uint16
+	MaxDisplayMasteringLuminance uint32
+	MinDisplayMasteringLuminance uint32
+}
+
+// DecodeMdcv - box-specific decode
+func DecodeMdcv(hdr BoxHeader, startPos uint64, r io.Reader) (Box, error) {
+	data, err := readBoxBody(r, hdr)
+	if err != nil {
+		return nil, err
+	}
+	sr := bits.NewFixedSliceReader(data)
+	return DecodeMdcvSR(hdr, startPos, sr)
+}
+
+// DecodeMdcvSR - box-specific decode
+func DecodeMdcvSR(hdr BoxHeader, startPos uint64, sr bits.SliceReader) (Box, error) {
+	b := &MdcvBox{}
+	for i := 0; i < 3; i++ {
+		b.DisplayPrimariesX[i] = sr.ReadUint16()
+		b.DisplayPrimariesY[i] = sr.ReadUint16()
+	}
+	b.WhitePointX = sr.ReadUint16()
+	b.WhitePointY = sr.ReadUint16()
+	b.MaxDisplayMasteringLuminance = sr.ReadUint32()
+	b.MinDisplayMasteringLuminance = sr.ReadUint32()
+	return b, sr.AccError()
+}
+
+// Type - box type
+func (b *MdcvBox) Type() string {
+	return "mdcv"
+}
+
+// Size - calculated size of box
+func (b *MdcvBox) Size() uint64 {
+	return uint64(boxHeaderSize + 24)
+}
+
+// Encode - write box to w
+func (b *MdcvBox) Encode(w io.Writer) error {
+	sw := bits.NewFixedSliceWriter(int(b.Size()))
+	err := b.EncodeSW(sw)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(sw.Bytes())
+	return err
+}
+
+// EncodeSW - box-specific encode to slicewriter
+func (b *MdcvBox) EncodeSW(sw bits.SliceWriter) error {
+	err := EncodeHeaderSW(b, sw)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < 3; i++ {
+		sw.WriteUint16(b.DisplayPrimariesX[i])
+		sw.WriteUint16(b.DisplayPrimariesY[i])
+	}
+	sw.WriteUint16(b.WhitePointX)
+	sw.WriteUint16(b.WhitePointY)
+	sw.WriteUint32(b.MaxDisplayMasteringLuminance)
+	sw.WriteUint32(b.MinDisplayMasteringLuminance)
+	return sw.AccError()
+}
+
+// Info - write box-specific information
+func (b *MdcvBox) Info(w io.Writer, specificBoxLevels, indent, indentStep string) error {
+	bd := newInfoDumper(w, indent, b, -1, 0)
+	bd.write(" - displayPrimaries (x,y): (%d,%d) (%d,%d) (%d,%d)",
+		b.DisplayPrimariesX[0], b.DisplayPrimariesY[0], b.DisplayPrimariesX[1], b.DisplayPrimariesY[1],
+		b.DisplayPrimariesX[2], b.DisplayPrimariesY[2])
+	bd.write(" - whitePoint (x,y): (%d,%d)", b.WhitePointX, b.WhitePointY)
+	bd.write(" - maxDisplayMasteringLuminance: %d", b.MaxDisplayMasteringLuminance)
+	bd.write(" - minDisplayMasteringLuminance: %d", b.MinDisplayMasteringLuminance)
+	return bd.err
+}
@@ -0,0 +1,17 @@
+package mp4
+
+import (
+	"testing"
+)
+
+func TestEncDecMdcv(t *testing.T) {
+	b := &MdcvBox{
+		DisplayPrimariesX:            [3]uint16{13250, 7500, 34000},
+		DisplayPrimariesY:            [3]uint16{34500, 3000, 16000},
+		WhitePointX:                  15635,
+		WhitePointY:                  16450,
+		MaxDisplayMasteringLuminance: 10000000,
+		MinDisplayMasteringLuminance: 50,
+	}
+	boxDiffAfterEncodeAndDecode(t, b)
+}
@@ -218,3 +218,57 @@ func TestDecodeEncodeCencFragmentedFile(t *testing.T) {
 		t.Errorf("generated bytes differ from input")
 	}
 }
+
+func TestFragmentSetSampleData(t *testing.T) {
+	fd, err := os.Open("testdata/1.m4s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+	f, err := DecodeFile(fd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frag := f.Segments[0].Fragments[0]
+	trex := &TrexBox{TrackID: frag.Moof.Traf.Tfhd.TrackID}
+	samples, err := frag.GetFullSamples(trex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([][]byte, len(samples))
+	for i, s := range samples {
+		data[i] = append([]byte{0, 0, 0, 2, 0x09, 0xf0}, s.Data...)
+	}
+	err = frag.SetSampleData(data[1:])
+	if err == nil {
+		t.Errorf("no error for wrong number of samples")
+	}
+	err = frag.SetSampleData(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	err = frag.Encode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decFile, err := DecodeFile(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decSamples, err := decFile.Segments[0].Fragments[0].GetFullSamples(trex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decSamples) != len(samples) {
+		t.Fatalf("got %d samples instead of %d", len(decSamples), len(samples))
+	}
+	for i, s := range decSamples {
+		if !bytes.Equal(s.Data, data[i]) || s.Size != uint32(len(data[i])) {
+			t.Errorf("sample %d: data or size differs", i+1)
+		}
+		if s.DecodeTime != samples[i].DecodeTime || s.CompositionTimeOffset != samples[i].CompositionTimeOffset {
+			t.Errorf("sample %d: timing differs", i+1)
+		}
+	}
+}
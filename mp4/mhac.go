@@ -0,0 +1,125 @@
+package mp4
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/jaypadia-frame/mp4ff/bits"
+)
+
+// MPEGHProfileLevels - names of mpegh3daProfileLevelIndication values
+// ISO/IEC 23008-3 (2019)
+var MPEGHProfileLevels = map[byte]string{
+	0x0B: "LC Profile Level 1",
+	0x0C: "LC Profile Level 2",
+	0x0D: "LC Profile Level 3",
+	0x0E: "LC Profile Level 4",
+	0x0F: "LC Profile Level 5",
+	0x10: "Baseline Profile Level 1",
+	0x11: "Baseline Profile Level 2",
+	0x12: "Baseline Profile Level 3",
+	0x13: "Baseline Profile Level 4",
+	0x14: "Baseline Profile Level 5",
+}
+
+// MhaCBox - MHAConfigurationBox with MHADecoderConfigurationRecord
+// ISO/IEC 23008-3 (2019) Section 20.5
+type MhaCBox struct {
+	ConfigurationVersion   byte
+	ProfileLevelIndication byte
+	ReferenceChannelLayout byte
+	Config                 []byte // mpegh3daConfig
+}
+
+// CreateMhaCBox - create box with version 1 and config
+func CreateMhaCBox(profileLevelIndication, referenceChannelLayout byte, config []byte) *MhaCBox {
+	return &MhaCBox{
+		ConfigurationVersion:   1,
+		ProfileLevelIndication: profileLevelIndication,
+		ReferenceChannelLayout: referenceChannelLayout,
+		Config:                 config,
+	}
+}
+
+// DecodeMhaC - box-specific decode
+func DecodeMhaC(hdr BoxHeader, startPos uint64, r io.Reader) (Box, error) {
+	data, err := readBoxBody(r, hdr)
+	if err != nil {
+		return nil, err
+	}
+	sr := bits.NewFixedSliceReader(data)
+	return DecodeMhaCSR(hdr, startPos, sr)
+}
+
+// DecodeMhaCSR - box-specific decode
+func DecodeMhaCSR(hdr BoxHeader, startPos uint64, sr bits.SliceReader) (Box, error) {
+	b := MhaCBox{}
+	b.ConfigurationVersion = sr.ReadUint8()
+	b.ProfileLevelIndication = sr.ReadUint8()
+	b.ReferenceChannelLayout = sr.ReadUint8()
+	configLength := int(sr.ReadUint16())
+	if configLength > hdr.payloadLen()-5 {
+		return nil, fmt.Errorf("mhaC config length %d beyond box size", configLength)
+	}
+	b.Config = sr.ReadBytes(configLength)
+	return &b, sr.AccError()
+}
+
+// Type - return box type
+func (b *MhaCBox) Type() string {
+	return "mhaC"
+}
+
+// Size - return calculated size
+func (b *MhaCBox) Size() uint64 {
+	return uint64(boxHeaderSize + 5 + len(b.Config))
+}
+
+// Encode - write box to w
+func (b *MhaCBox) Encode(w io.Writer) error {
+	sw := bits.NewFixedSliceWriter(int(b.Size()))
+	err := b.EncodeSW(sw)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(sw.Bytes())
+	return err
+}
+
+// EncodeSW - box-specific encode to slicewriter
+func (b *MhaCBox) EncodeSW(sw bits.SliceWriter) error {
+	err := EncodeHeaderSW(b, sw)
+	if err != nil {
+		return err
+	}
+	sw.WriteUint8(b.ConfigurationVersion)
+	sw.WriteUint8(b.ProfileLevelIndication)
+	sw.WriteUint8(b.ReferenceChannelLayout)
+	sw.WriteUint16(uint16(len(b.Config)))
+	sw.WriteBytes(b.Config)
+	return sw.AccError()
+}
+
+// CodecString - codecs parameter like mhm1.0x0D where mhm1 is sampleEntry
+// Defined in ISO/IEC 23008-3 (2019) Annex F
+func (b *MhaCBox) CodecString(sampleEntry string) string {
+	return fmt.Sprintf("%s.0x%02X", sampleEntry, b.ProfileLevelIndication)
+}
+
+// Info - write box-specific information
+func (b *MhaCBox) Info(w io.Writer, specificBoxLevels, indent, indentStep string) error {
+	bd := newInfoDumper(w, indent, b, -1, 0)
+	bd.write(" - configurationVersion=%d", b.ConfigurationVersion)
+	profileLevel, ok := MPEGHProfileLevels[b.ProfileLevelIndication]
+	if !ok {
+		profileLevel = "unknown"
+	}
+	bd.write(" - profileLevelIndication=0x%02X (%s)", b.ProfileLevelIndication, profileLevel)
+	bd.write(" - referenceChannelLayout=%d", b.ReferenceChannelLayout)
+	bd.write(" - configLength=%d", len(b.Config))
+	level := getInfoLevel(b, specificBoxLevels)
+	if level > 0 {
+		bd.write(" - config=%x", b.Config)
+	}
+	return bd.err
+}
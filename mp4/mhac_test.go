@@ -0,0 +1,16 @@
+package mp4
+
+import "testing"
+
+func TestEncodeDecodeMhaC(t *testing.T) {
+	mhaC := CreateMhaCBox(0x0D, 6, []byte{0x0d, 0x80, 0x88, 0x1e, 0x00})
+	boxDiffAfterEncodeAndDecode(t, mhaC)
+	if got := mhaC.CodecString("mhm1"); got != "mhm1.0x0D" {
+		t.Errorf("got codec string %q instead of mhm1.0x0D", got)
+	}
+	mha1 := CreateAudioSampleEntryBox("mha1", 6, 16, 48000, mhaC)
+	mha1Dec := boxAfterEncodeAndDecode(t, mha1).(*AudioSampleEntryBox)
+	if mha1Dec.MhaC == nil || mha1Dec.MhaC.ProfileLevelIndication != 0x0D {
+		t.Errorf("mha1 sample entry did not get mhaC child")
+	}
+}
@@ -0,0 +1,116 @@
+package mp4
+
+import (
+	"fmt"
+
+	"github.com/jaypadia-frame/mp4ff/avc"
+)
+
+// NaluLengthSize - NAL unit length size in bytes given by the avcC or hvcC box of a sample entry.
+// 0 is returned for sample entries without avcC or hvcC box.
+func NaluLengthSize(sampleEntry Box) int {
+	vse, ok := sampleEntry.(*VisualSampleEntryBox)
+	if !ok {
+		return 0
+	}
+	switch {
+	case vse.AvcC != nil:
+		return vse.AvcC.LengthSize()
+	case vse.HvcC != nil:
+		return int(vse.HvcC.LengthSizeMinusOne) + 1
+	default:
+		return 0
+	}
+}
+
+// ConvertNaluLengthSize - rewrite the samples of an AVC or HEVC track to have NAL unit length fields
+// of lengthSize (1, 2, or 4) bytes, and set the length size in the avcC or hvcC box of all sample entries.
+// The samples are rewritten with RewriteTrackSamples. Encrypted tracks are not supported.
+func (f *File) ConvertNaluLengthSize(trackID uint32, lengthSize int) error {
+	if !avc.IsValidLengthSize(lengthSize) {
+		return avc.ErrLengthSize
+	}
+	if f.Moov == nil {
+		return fmt.Errorf("no moov box")
+	}
+	var trak *TrakBox
+	for _, t := range f.Moov.Traks {
+		if t.Tkhd.TrackID == trackID {
+			trak = t
+			break
+		}
+	}
+	if trak == nil {
+		return fmt.Errorf("no track with ID %d", trackID)
+	}
+	stsd := trak.Mdia.Minf.Stbl.Stsd
+	oldSizes := make([]int, len(stsd.Children))
+	for i, entry := range stsd.Children {
+		if entry.Type() == "encv" {
+			return fmt.Errorf("cannot convert NALU length size of encrypted track %d", trackID)
+		}
+		oldSizes[i] = NaluLengthSize(entry)
+		if oldSizes[i] == 0 {
+			return fmt.Errorf("sample entry %s has no avcC or hvcC box", entry.Type())
+		}
+	}
+	err := f.RewriteTrackSamples(trackID, func(nr, sdi uint32, s *FullSample) ([]byte, uint32, error) {
+		if sdi == 0 || int(sdi) > len(oldSizes) {
+			return nil, 0, fmt.Errorf("sample description index %d out of range", sdi)
+		}
+		data, err := avc.ConvertNaluLengthSize(s.Data, oldSizes[sdi-1], lengthSize)
+		return data, sdi, err
+	})
+	if err != nil {
+		return err
+	}
+	for _, entry := range stsd.Children {
+		vse := entry.(*VisualSampleEntryBox)
+		if vse.AvcC != nil {
+			vse.AvcC.NaluLengthSize = byte(lengthSize)
+		} else {
+			vse.HvcC.LengthSizeMinusOne = byte(lengthSize - 1)
+		}
+	}
+	return nil
+}
+
+// FilterTrackNalus - apply filter to the NAL units of all samples of an AVC or HEVC track in decode order.
+// The samples are rewritten with RewriteTrackSamples, so stsz or trun sample sizes and chunk offsets are updated.
+// Encrypted tracks are not supported.
+func (f *File) FilterTrackNalus(trackID uint32, filter avc.NaluFilter) error {
+	if f.Moov == nil {
+		return fmt.Errorf("no moov box")
+	}
+	var trak *TrakBox
+	for _, t := range f.Moov.Traks {
+		if t.Tkhd.TrackID == trackID {
+			trak = t
+			break
+		}
+	}
+	if trak == nil {
+		return fmt.Errorf("no track with ID %d", trackID)
+	}
+	stsd := trak.Mdia.Minf.Stbl.Stsd
+	lengthSizes := make([]int, len(stsd.Children))
+	for i, entry := range stsd.Children {
+		if entry.Type() == "encv" {
+			return fmt.Errorf("cannot filter NAL units of encrypted track %d", trackID)
+		}
+		lengthSizes[i] = NaluLengthSize(entry)
+		if lengthSizes[i] == 0 {
+			return fmt.Errorf("sample entry %s has no avcC or hvcC box", entry.Type())
+		}
+	}
+	return f.RewriteTrackSamples(trackID, func(nr, sdi uint32, s *FullSample) ([]byte, uint32, error) {
+		if sdi == 0 || int(sdi) > len(lengthSizes) {
+			return nil, 0, fmt.Errorf("sample description index %d out of range", sdi)
+		}
+		data, err := avc.FilterSample(s.Data, lengthSizes[sdi-1], filter)
+		if err != nil {
+			return nil, 0, fmt.Errorf("sample %d: %w", nr, err)
+		}
+		return data, sdi, nil
+	})
+}
@@ -0,0 +1,88 @@
+package mp4
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/jaypadia-frame/mp4ff/avc"
+)
+
+func TestConvertNaluLengthSize(t *testing.T) {
+	fd, err := os.Open("./testdata/prog_8s.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+	f, err := DecodeFile(fd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	video := collectSamples(t, f, 2)
+	if err := f.ConvertNaluLengthSize(2, 2); err != nil {
+		t.Fatal(err)
+	}
+	f = encodeAndDecodeFile(t, f)
+	if size := NaluLengthSize(f.Moov.Traks[1].Mdia.Minf.Stbl.Stsd.AvcX); size != 2 {
+		t.Errorf("got NALU length size %d instead of 2", size)
+	}
+	for i, s := range collectSamples(t, f, 2) {
+		want, err := avc.ConvertNaluLengthSize(video[i].data, 4, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(s.data, want) {
+			t.Errorf("sample %d not converted", i+1)
+		}
+	}
+	if err := f.ConvertNaluLengthSize(2, 1); err == nil {
+		t.Error("no error for NALUs too long for 1-byte length")
+	}
+	if err := f.ConvertNaluLengthSize(1, 4); err == nil {
+		t.Error("no error for audio track")
+	}
+	if err := f.ConvertNaluLengthSize(2, 4); err != nil {
+		t.Fatal(err)
+	}
+	for i, s := range collectSamples(t, f, 2) {
+		if !bytes.Equal(s.data, video[i].data) {
+			t.Errorf("sample %d differs after converting back", i+1)
+		}
+	}
+}
+
+func TestFilterTrackNalus(t *testing.T) {
+	fd, err := os.Open("./testdata/prog_8s.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+	f, err := DecodeFile(fd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	video := collectSamples(t, f, 2)
+	if err := f.FilterTrackNalus(2, avc.ChainNaluFilters(avc.RemoveNaluTypes(avc.NALU_SPS, avc.NALU_PPS),
+		avc.InsertAUD())); err != nil {
+		t.Fatal(err)
+	}
+	f = encodeAndDecodeFile(t, f)
+	for i, s := range collectSamples(t, f, 2) {
+		types := avc.FindNaluTypes(s.data)
+		if types[0] != avc.NALU_AUD || avc.HasParameterSets(s.data) {
+			t.Errorf("sample %d: got NAL unit types %v", i+1, types)
+		}
+		nrWanted := 1
+		for _, nt := range avc.FindNaluTypes(video[i].data) {
+			if nt != avc.NALU_SPS && nt != avc.NALU_PPS && nt != avc.NALU_AUD {
+				nrWanted++
+			}
+		}
+		if len(types) != nrWanted {
+			t.Errorf("sample %d: got %d NAL units instead of %d", i+1, len(types), nrWanted)
+		}
+	}
+	if err := f.FilterTrackNalus(1, avc.RemoveAUD()); err == nil {
+		t.Error("no error for audio track")
+	}
+}
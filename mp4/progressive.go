@@ -0,0 +1,207 @@
+package mp4
+
+import (
+	"fmt"
+	"math"
+)
+
+// CreateProgressiveFile - create a progressive file from an init segment and the samples of its tracks.
+//
+// trackSamples[i] are the samples of init.Moov.Traks[i] in decode order with durations in the mdhd timescale.
+// The sample tables (stts, ctts, stss, stsz, stsc and stco/co64) and the durations of the tracks are set,
+// the mvex box is removed, and all samples of a track are written as one chunk in a single mdat box after moov.
+// The init segment is modified and becomes part of the file.
+func CreateProgressiveFile(init *InitSegment, trackSamples [][]FullSample) (*File, error) {
+	moov := init.Moov
+	if len(trackSamples) != len(moov.Traks) {
+		return nil, fmt.Errorf("got samples for %d tracks, but there are %d traks", len(trackSamples), len(moov.Traks))
+	}
+	for i, c := range moov.Children {
+		if c.Type() == "mvex" {
+			moov.Children = append(moov.Children[:i], moov.Children[i+1:]...)
+			break
+		}
+	}
+	moov.Mvex = nil
+
+	mdat := &MdatBox{}
+	chunkStarts := make([]uint64, len(moov.Traks)) // relative to mdat payload
+	var movieDur uint64
+	for i, trak := range moov.Traks {
+		samples := trackSamples[i]
+		if len(samples) == 0 {
+			return nil, fmt.Errorf("no samples for track %d", trak.Tkhd.TrackID)
+		}
+		chunkStarts[i] = mdat.DataLength()
+		for _, s := range samples {
+			mdat.AddSampleData(s.Data)
+		}
+		setSampleTables(trak.Mdia.Minf.Stbl, samples)
+		var trakDur uint64
+		for _, s := range samples {
+			trakDur += uint64(s.Dur)
+		}
+		trak.Mdia.Mdhd.Duration = trakDur
+		if trakDur > math.MaxUint32 {
+			trak.Mdia.Mdhd.Version = 1
+		}
+		tkhdDur := trakDur * uint64(moov.Mvhd.Timescale) / uint64(trak.Mdia.Mdhd.Timescale)
+		trak.Tkhd.Duration = tkhdDur
+		if tkhdDur > math.MaxUint32 {
+			trak.Tkhd.Version = 1
+		}
+		if tkhdDur > movieDur {
+			movieDur = tkhdDur
+		}
+	}
+	moov.Mvhd.Duration = movieDur
+	if movieDur > math.MaxUint32 {
+		moov.Mvhd.Version = 1
+	}
+
+	ftyp := NewFtyp("isom", 0x200, []string{"isom", "iso2", "mp41"})
+	// Switch to co64 if the last chunk does not fit in 32 bits. The moov size then changes, so check again.
+	for {
+		mdatPayloadStart := ftyp.Size() + moov.Size() + mdat.Size() - mdat.DataLength()
+		changed := false
+		for i, trak := range moov.Traks {
+			stbl := trak.Mdia.Minf.Stbl
+			offset := mdatPayloadStart + chunkStarts[i]
+			if stbl.Stco != nil && offset > math.MaxUint32 {
+				replaceStcoWithCo64(stbl)
+				changed = true
+			}
+			if stbl.Co64 != nil {
+				stbl.Co64.ChunkOffset = []uint64{offset}
+			} else {
+				stbl.Stco.ChunkOffset = []uint32{uint32(offset)}
+			}
+		}
+		if !changed {
+			break
+		}
+	}
+
+	f := NewFile()
+	f.AddChild(ftyp, 0)
+	f.AddChild(moov, ftyp.Size())
+	mdat.StartPos = ftyp.Size() + moov.Size()
+	f.AddChild(mdat, mdat.StartPos)
+	return f, nil
+}
+
+// CreateFragmentedFile - create a fragmented file from an init segment with one track and its samples.
+//
+// samples are in decode order with durations in the mdhd timescale. Every fragment is a media segment
+// without styp. A new fragment starts at the first sync sample after fragmentDur (in track timescale)
+// has passed since the start of the previous one. If fragmentDur is 0, all samples are in one fragment.
+// The init segment becomes part of the file.
+func CreateFragmentedFile(init *InitSegment, samples []FullSample, fragmentDur uint64) (*File, error) {
+	if len(init.Moov.Traks) != 1 {
+		return nil, fmt.Errorf("init segment has %d traks instead of 1", len(init.Moov.Traks))
+	}
+	f := NewFile()
+	f.AddChild(init.Ftyp, 0)
+	f.AddChild(init.Moov, init.Ftyp.Size())
+	trackID := init.Moov.Trak.Tkhd.TrackID
+	var frag *Fragment
+	var fragStart uint64
+	seqNr := uint32(1)
+	for _, s := range samples {
+		if frag == nil || (fragmentDur > 0 && s.IsSync() && s.DecodeTime-fragStart >= fragmentDur) {
+			var err error
+			frag, err = CreateFragment(seqNr, trackID)
+			if err != nil {
+				return nil, err
+			}
+			seqNr++
+			fragStart = s.DecodeTime
+			seg := NewMediaSegmentWithoutStyp()
+			seg.AddFragment(frag)
+			f.AddMediaSegment(seg)
+		}
+		frag.AddFullSample(s)
+	}
+	return f, nil
+}
+
+// setSampleTables - fill the sample tables of stbl for samples stored in one chunk
+func setSampleTables(stbl *StblBox, samples []FullSample) {
+	stts := &SttsBox{}
+	ctts := &CttsBox{}
+	stss := &StssBox{}
+	stsz := &StszBox{SampleNumber: uint32(len(samples))}
+	hasCto, hasNegativeCto, allSync := false, false, true
+	for i, s := range samples {
+		nr := len(stts.SampleCount)
+		if nr > 0 && stts.SampleTimeDelta[nr-1] == s.Dur {
+			stts.SampleCount[nr-1]++
+		} else {
+			stts.SampleCount = append(stts.SampleCount, 1)
+			stts.SampleTimeDelta = append(stts.SampleTimeDelta, s.Dur)
+		}
+		nr = len(ctts.SampleCount)
+		if nr > 0 && ctts.SampleOffset[nr-1] == s.CompositionTimeOffset {
+			ctts.SampleCount[nr-1]++
+		} else {
+			ctts.SampleCount = append(ctts.SampleCount, 1)
+			ctts.SampleOffset = append(ctts.SampleOffset, s.CompositionTimeOffset)
+		}
+		if s.CompositionTimeOffset != 0 {
+			hasCto = true
+		}
+		if s.CompositionTimeOffset < 0 {
+			hasNegativeCto = true
+		}
+		if s.IsSync() {
+			stss.SampleNumber = append(stss.SampleNumber, uint32(i+1))
+		} else {
+			allSync = false
+		}
+		stsz.SampleSize = append(stsz.SampleSize, s.Size)
+	}
+	if hasNegativeCto {
+		ctts.Version = 1
+	}
+	stsc := &StscBox{FirstChunk: []uint32{1}, SamplesPerChunk: []uint32{uint32(len(samples))}}
+	stsc.SetSingleSampleDescriptionID(1)
+
+	children := make([]Box, 0, len(stbl.Children)+2)
+	for _, c := range stbl.Children {
+		switch c.Type() {
+		case "stts", "ctts", "stss", "stsz", "stsc", "sdtp", "stco", "co64":
+			continue
+		}
+		children = append(children, c)
+	}
+	// One chunk offset, so that the moov size does not change when the offset is set
+	var chunkOffsets Box = &StcoBox{ChunkOffset: []uint32{0}}
+	if stbl.Co64 != nil {
+		stbl.Co64.ChunkOffset = []uint64{0}
+		chunkOffsets = stbl.Co64
+	}
+	stbl.Children = children
+	stbl.Ctts, stbl.Stss, stbl.Sdtp, stbl.Stco, stbl.Co64 = nil, nil, nil, nil, nil
+	stbl.AddChild(stts)
+	if hasCto {
+		stbl.AddChild(ctts)
+	}
+	if !allSync {
+		stbl.AddChild(stss)
+	}
+	stbl.AddChild(stsz)
+	stbl.AddChild(stsc)
+	stbl.AddChild(chunkOffsets)
+}
+
+// replaceStcoWithCo64 - replace the stco box of stbl with a co64 box with one chunk
+func replaceStcoWithCo64(stbl *StblBox) {
+	co64 := &Co64Box{ChunkOffset: []uint64{0}}
+	for i, c := range stbl.Children {
+		if c.Type() == "stco" {
+			stbl.Children[i] = co64
+		}
+	}
+	stbl.Stco = nil
+	stbl.Co64 = co64
+}
@@ -0,0 +1,270 @@
+package mp4
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// SampleRemover - return true if sample nr (starting at 1) of a track should be removed
+type SampleRemover func(nr uint32, s *FullSample) bool
+
+// RemoveTrackSamples - remove the samples of a track for which remove returns true.
+// The samples are given to remove in decode order.
+//
+// The remaining samples keep their presentation times. Their decode times follow the sorted presentation
+// times, starting at the decode time of the first remaining sample, and the durations and composition time
+// offsets are recomputed from them. The last sample lasts until the original end of the track.
+//
+// A progressive file gets new stts, ctts, stss and sdtp boxes, and stsz, stsc and the chunk offsets are
+// updated as in RewriteTrackSamples. In a fragmented file, the samples of every fragment are put in a single
+// trun box and tfdt is updated. Every fragment must keep at least one sample, and sidx boxes are not updated.
+// Tracks with encryption information or sample groups are not supported.
+func (f *File) RemoveTrackSamples(trackID uint32, remove SampleRemover) error {
+	if f.Moov == nil {
+		return fmt.Errorf("no moov box")
+	}
+	var trak *TrakBox
+	for _, t := range f.Moov.Traks {
+		if t.Tkhd.TrackID == trackID {
+			trak = t
+			break
+		}
+	}
+	if trak == nil {
+		return fmt.Errorf("no track with ID %d", trackID)
+	}
+	if err := f.checkSampleRemoval(trak); err != nil {
+		return err
+	}
+
+	var samples []FullSample
+	var removed []bool
+	nrKept := 0
+	err := f.VisitTrackSamples(trackID, nil, func(nr, sdi uint32, s *FullSample) error {
+		samples = append(samples, *s)
+		r := remove(nr, s)
+		removed = append(removed, r)
+		if !r {
+			nrKept++
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if nrKept == 0 {
+		return fmt.Errorf("all samples of track %d removed", trackID)
+	}
+	kept := retimeSamples(samples, removed, nrKept)
+
+	if !f.isFragmented {
+		identity := func(nr, sdi uint32, s *FullSample) ([]byte, uint32, error) {
+			return s.Data, sdi, nil
+		}
+		trakChunks, err := f.rewriteProgressiveSamples(trak, identity, removed)
+		if err != nil {
+			return err
+		}
+		stbl := trak.Mdia.Minf.Stbl
+		if stbl.Sdtp != nil && len(stbl.Sdtp.Entries) == len(removed) {
+			entries := make([]SdtpEntry, 0, nrKept)
+			for i, e := range stbl.Sdtp.Entries {
+				if !removed[i] {
+					entries = append(entries, e)
+				}
+			}
+			stbl.Sdtp.Entries = entries
+		}
+		setTimeTables(stbl, kept)
+		f.setChunkOffsets(trakChunks)
+		return nil
+	}
+	return f.removeFragmentedSamples(trackID, removed, kept)
+}
+
+// checkSampleRemoval - return error if trak has information which would not be updated when removing samples
+func (f *File) checkSampleRemoval(trak *TrakBox) error {
+	trackID := trak.Tkhd.TrackID
+	stbl := trak.Mdia.Minf.Stbl
+	if stbl.Sbgp != nil || stbl.Saiz != nil || stbl.Subs != nil {
+		return fmt.Errorf("track %d: sample groups, auxiliary information or sub-samples not supported", trackID)
+	}
+	for _, seg := range f.Segments {
+		for _, frag := range seg.Fragments {
+			for _, traf := range frag.Moof.Trafs {
+				if traf.Tfhd.TrackID != trackID {
+					continue
+				}
+				if len(frag.Moof.Trafs) != 1 {
+					return fmt.Errorf("fragment %d has %d tracks", frag.Moof.Mfhd.SequenceNumber, len(frag.Moof.Trafs))
+				}
+				if traf.Senc != nil || traf.Saiz != nil || traf.Sbgp != nil {
+					return fmt.Errorf("track %d: encryption information or sample groups not supported", trackID)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// retimeSamples - the samples which are not removed, with decode times following their sorted presentation times
+func retimeSamples(samples []FullSample, removed []bool, nrKept int) []FullSample {
+	kept := make([]FullSample, 0, nrKept)
+	pts := make([]int64, 0, nrKept)
+	for i, s := range samples {
+		if !removed[i] {
+			kept = append(kept, s)
+			pts = append(pts, int64(s.DecodeTime)+int64(s.CompositionTimeOffset))
+		}
+	}
+	sortedPts := make([]int64, len(pts))
+	copy(sortedPts, pts)
+	sort.Slice(sortedPts, func(i, j int) bool { return sortedPts[i] < sortedPts[j] })
+
+	last := samples[len(samples)-1]
+	endTime := last.DecodeTime + uint64(last.Dur)
+	startTime := kept[0].DecodeTime
+	for i := range kept {
+		kept[i].DecodeTime = startTime + uint64(sortedPts[i]-sortedPts[0])
+		kept[i].CompositionTimeOffset = int32(pts[i] - int64(kept[i].DecodeTime))
+	}
+	for i := range kept {
+		if i+1 < len(kept) {
+			kept[i].Dur = uint32(kept[i+1].DecodeTime - kept[i].DecodeTime)
+		} else if endTime > kept[i].DecodeTime {
+			kept[i].Dur = uint32(endTime - kept[i].DecodeTime)
+		}
+	}
+	return kept
+}
+
+// setTimeTables - replace stts, ctts and stss of stbl with tables for samples
+func setTimeTables(stbl *StblBox, samples []FullSample) {
+	stts := &SttsBox{}
+	ctts := &CttsBox{}
+	stss := &StssBox{}
+	hasCto, hasNegativeCto, allSync := false, false, true
+	for i, s := range samples {
+		nr := len(stts.SampleCount)
+		if nr > 0 && stts.SampleTimeDelta[nr-1] == s.Dur {
+			stts.SampleCount[nr-1]++
+		} else {
+			stts.SampleCount = append(stts.SampleCount, 1)
+			stts.SampleTimeDelta = append(stts.SampleTimeDelta, s.Dur)
+		}
+		nr = len(ctts.SampleCount)
+		if nr > 0 && ctts.SampleOffset[nr-1] == s.CompositionTimeOffset {
+			ctts.SampleCount[nr-1]++
+		} else {
+			ctts.SampleCount = append(ctts.SampleCount, 1)
+			ctts.SampleOffset = append(ctts.SampleOffset, s.CompositionTimeOffset)
+		}
+		if s.CompositionTimeOffset != 0 {
+			hasCto = true
+		}
+		if s.CompositionTimeOffset < 0 {
+			hasNegativeCto = true
+		}
+		if s.IsSync() {
+			stss.SampleNumber = append(stss.SampleNumber, uint32(i+1))
+		} else {
+			allSync = false
+		}
+	}
+	if hasNegativeCto {
+		ctts.Version = 1
+	}
+
+	children := make([]Box, 0, len(stbl.Children)+2)
+	for _, c := range stbl.Children {
+		switch c.Type() {
+		case "stts":
+			children = append(children, stts)
+			if hasCto {
+				children = append(children, ctts)
+			}
+		case "ctts", "stss":
+			continue
+		case "stsz":
+			if !allSync {
+				children = append(children, stss)
+			}
+			children = append(children, c)
+		default:
+			children = append(children, c)
+		}
+	}
+	stbl.Children = children
+	stbl.Stts, stbl.Ctts, stbl.Stss = stts, nil, nil
+	if hasCto {
+		stbl.Ctts = ctts
+	}
+	if !allSync {
+		stbl.Stss = stss
+	}
+}
+
+// removeFragmentedSamples - write the kept samples to the fragments with the track
+func (f *File) removeFragmentedSamples(trackID uint32, removed []bool, kept []FullSample) error {
+	trex, _ := f.Moov.Mvex.GetTrex(trackID)
+	type fragSamples struct {
+		frag    *Fragment
+		samples []FullSample
+	}
+	var frags []fragSamples
+	nr, keptNr := 0, 0
+	for _, seg := range f.Segments {
+		for _, frag := range seg.Fragments {
+			samples, err := frag.GetFullSamples(trex)
+			if err != nil {
+				return err
+			}
+			if len(samples) == 0 {
+				continue
+			}
+			fs := fragSamples{frag: frag}
+			for range samples {
+				if !removed[nr] {
+					fs.samples = append(fs.samples, kept[keptNr])
+					keptNr++
+				}
+				nr++
+			}
+			if len(fs.samples) == 0 {
+				return fmt.Errorf("no samples left in fragment %d", frag.Moof.Mfhd.SequenceNumber)
+			}
+			frags = append(frags, fs)
+		}
+	}
+	for _, fs := range frags {
+		traf := fs.frag.Moof.Traf
+		trun := CreateTrun(0)
+		data := make([][]byte, len(fs.samples))
+		for i := range fs.samples {
+			trun.AddFullSample(&fs.samples[i])
+			data[i] = fs.samples[i].Data
+		}
+		children := make([]Box, 0, len(traf.Children))
+		for _, c := range traf.Children {
+			if c.Type() == "trun" {
+				if c == traf.Trun {
+					children = append(children, trun)
+				}
+				continue
+			}
+			children = append(children, c)
+		}
+		traf.Children = children
+		traf.Trun, traf.Truns = trun, []*TrunBox{trun}
+		traf.Tfdt.BaseMediaDecodeTime = fs.samples[0].DecodeTime
+		if traf.Tfdt.BaseMediaDecodeTime > math.MaxUint32 {
+			traf.Tfdt.Version = 1
+		}
+		if err := fs.frag.SetSampleData(data); err != nil {
+			return err
+		}
+		fs.frag.Mdat.StartPos = fs.frag.Moof.StartPos + fs.frag.Moof.Size()
+	}
+	return nil
+}
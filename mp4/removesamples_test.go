@@ -0,0 +1,137 @@
+package mp4
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+// trackFullSamples - copies of all samples of a track in decode order
+func trackFullSamples(t *testing.T, f *File, trackID uint32) []FullSample {
+	t.Helper()
+	var samples []FullSample
+	err := f.VisitTrackSamples(trackID, nil, func(nr, sdi uint32, s *FullSample) error {
+		c := *s
+		c.Data = append([]byte{}, s.Data...)
+		samples = append(samples, c)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return samples
+}
+
+// checkRemovedSamples - check that the kept samples have the same data and presentation times,
+// increasing decode times, and the same end time as the original samples
+func checkRemovedSamples(t *testing.T, orig, got []FullSample, remove SampleRemover) {
+	t.Helper()
+	var kept []FullSample
+	for i := range orig {
+		if !remove(uint32(i+1), &orig[i]) {
+			kept = append(kept, orig[i])
+		}
+	}
+	if len(got) != len(kept) {
+		t.Fatalf("got %d samples instead of %d", len(got), len(kept))
+	}
+	for i, s := range got {
+		if !bytes.Equal(s.Data, kept[i].Data) || s.PresentationTime() != kept[i].PresentationTime() ||
+			s.IsSync() != kept[i].IsSync() {
+			t.Errorf("sample %d differs", i+1)
+		}
+		if i > 0 && s.DecodeTime != got[i-1].DecodeTime+uint64(got[i-1].Dur) {
+			t.Errorf("sample %d: decode time %d does not follow previous sample", i+1, s.DecodeTime)
+		}
+	}
+	lastOrig, lastGot := orig[len(orig)-1], got[len(got)-1]
+	if lastGot.DecodeTime+uint64(lastGot.Dur) != lastOrig.DecodeTime+uint64(lastOrig.Dur) {
+		t.Errorf("end time changed")
+	}
+}
+
+func TestRemoveProgressiveTrackSamples(t *testing.T) {
+	fd, err := os.Open("./testdata/prog_8s.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+	f, err := DecodeFile(fd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	audio := trackFullSamples(t, f, 1)
+	video := trackFullSamples(t, f, 2)
+	removeOdd := func(nr uint32, s *FullSample) bool { return nr%2 == 0 }
+	if err := f.RemoveTrackSamples(2, removeOdd); err != nil {
+		t.Fatal(err)
+	}
+	f = encodeAndDecodeFile(t, f)
+	checkRemovedSamples(t, video, trackFullSamples(t, f, 2), removeOdd)
+	for i, s := range trackFullSamples(t, f, 1) {
+		if !bytes.Equal(s.Data, audio[i].Data) || s.DecodeTime != audio[i].DecodeTime {
+			t.Errorf("audio sample %d changed", i+1)
+		}
+	}
+	stbl := f.Moov.Traks[1].Mdia.Minf.Stbl
+	if stbl.Stsz.SampleNumber != 120 || len(stbl.Stss.SampleNumber) != 8 || stbl.Stss.SampleNumber[1] != 16 {
+		t.Errorf("got %d samples and sync samples %v", stbl.Stsz.SampleNumber, stbl.Stss.SampleNumber)
+	}
+	nrSttsSamples := uint32(0)
+	for _, c := range stbl.Stts.SampleCount {
+		nrSttsSamples += c
+	}
+	if nrSttsSamples != 120 || stbl.Ctts == nil {
+		t.Errorf("got %d samples in stts and ctts %v", nrSttsSamples, stbl.Ctts)
+	}
+}
+
+func TestRemoveFragmentedTrackSamples(t *testing.T) {
+	fd, err := os.Open("./testdata/1.m4s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+	seg, err := DecodeFile(fd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	samples, err := seg.Segments[0].Fragments[0].GetFullSamples(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	createFile := func() *File {
+		init := CreateEmptyInit()
+		init.AddEmptyTrack(90000, "video", "und")
+		f := NewFile()
+		f.AddChild(init.Ftyp, 0)
+		f.AddChild(init.Moov, init.Ftyp.Size())
+		for i := 0; i < 2; i++ {
+			frag, err := CreateFragment(uint32(i+1), 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, s := range samples[30*i : 30*(i+1)] {
+				frag.AddFullSample(s)
+			}
+			seg := NewMediaSegmentWithoutStyp()
+			seg.AddFragment(frag)
+			f.AddMediaSegment(seg)
+		}
+		return encodeAndDecodeFile(t, f)
+	}
+
+	f := createFile()
+	orig := trackFullSamples(t, f, 1)
+	removeNonSync := func(nr uint32, s *FullSample) bool { return !s.IsSync() }
+	if err := f.RemoveTrackSamples(1, removeNonSync); err != nil {
+		t.Fatal(err)
+	}
+	f = encodeAndDecodeFile(t, f)
+	checkRemovedSamples(t, orig, trackFullSamples(t, f, 1), removeNonSync)
+
+	removeSecondFragment := func(nr uint32, s *FullSample) bool { return nr > 30 }
+	if err := createFile().RemoveTrackSamples(1, removeSecondFragment); err == nil {
+		t.Error("no error for fragment without samples")
+	}
+}
@@ -0,0 +1,310 @@
+package mp4
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// SampleRewriter - return the new data and sample description index for a sample of a track.
+// nr is the sample number starting at 1 and sdi the current sample description index.
+type SampleRewriter func(nr, sdi uint32, s *FullSample) (data []byte, newSdi uint32, err error)
+
+// RewriteTrackSamples - replace the data and sample description index of all samples of a track.
+// The samples are given to rewrite in decode order.
+//
+// For a progressive file, the mdat box is rebuilt with the chunks of all tracks in their original order,
+// and stsz, stsc and the chunk offsets are updated. Chunks of the track are split where the sample description
+// index changes. Data in mdat outside the chunks is dropped, and the mdat data must not be lazily decoded.
+//
+// For a fragmented file, the trun sample sizes and data offsets are updated using Fragment.SetSampleData,
+// so the fragments with the track must have no other track. All samples of a fragment must have the same
+// sample description index. It is signalled in tfhd if it differs from the trex default.
+// sidx boxes are not updated.
+func (f *File) RewriteTrackSamples(trackID uint32, rewrite SampleRewriter) error {
+	if f.Moov == nil {
+		return fmt.Errorf("no moov box")
+	}
+	for _, trak := range f.Moov.Traks {
+		if trak.Tkhd.TrackID == trackID {
+			if f.isFragmented {
+				return f.rewriteFragmentedSamples(trak, rewrite)
+			}
+			trakChunks, err := f.rewriteProgressiveSamples(trak, rewrite, nil)
+			if err != nil {
+				return err
+			}
+			f.setChunkOffsets(trakChunks)
+			return nil
+		}
+	}
+	return fmt.Errorf("no track with ID %d", trackID)
+}
+
+// rewriteFragmentedSamples - rewrite samples of trak fragment by fragment
+func (f *File) rewriteFragmentedSamples(trak *TrakBox, rewrite SampleRewriter) error {
+	trackID := trak.Tkhd.TrackID
+	var trex *TrexBox
+	if f.Moov.Mvex != nil {
+		trex, _ = f.Moov.Mvex.GetTrex(trackID)
+	}
+	if trex == nil {
+		return fmt.Errorf("no trex box for track %d", trackID)
+	}
+	nr := uint32(1)
+	for _, seg := range f.Segments {
+		for _, frag := range seg.Fragments {
+			samples, err := frag.GetFullSamples(trex)
+			if err != nil {
+				return err
+			}
+			if len(samples) == 0 {
+				continue
+			}
+			if len(frag.Moof.Trafs) != 1 {
+				return fmt.Errorf("fragment %d has %d tracks", frag.Moof.Mfhd.SequenceNumber, len(frag.Moof.Trafs))
+			}
+			tfhd := frag.Moof.Traf.Tfhd
+			sdi := trex.DefaultSampleDescriptionIndex
+			if tfhd.HasSampleDescriptionIndex() {
+				sdi = tfhd.SampleDescriptionIndex
+			}
+			data := make([][]byte, len(samples))
+			var fragSdi uint32
+			for i := range samples {
+				d, newSdi, err := rewrite(nr, sdi, &samples[i])
+				if err != nil {
+					return fmt.Errorf("sample %d: %w", nr, err)
+				}
+				if i == 0 {
+					fragSdi = newSdi
+				} else if newSdi != fragSdi {
+					return fmt.Errorf("sample %d: sample description index changes within fragment", nr)
+				}
+				data[i] = d
+				nr++
+			}
+			// Set tfhd before the data, since the trun data offsets depend on the moof size
+			if fragSdi == trex.DefaultSampleDescriptionIndex {
+				tfhd.Flags &^= sampleDescriptionIndexPresent
+			} else {
+				tfhd.Flags |= sampleDescriptionIndexPresent
+			}
+			tfhd.SampleDescriptionIndex = fragSdi
+			if err := frag.SetSampleData(data); err != nil {
+				return err
+			}
+			frag.Mdat.StartPos = frag.Moof.StartPos + frag.Moof.Size()
+		}
+	}
+	return nil
+}
+
+// chunkPart - a rewritten chunk, or part of a chunk split at a change of sample description
+type chunkPart struct {
+	offset    uint64 // relative to mdat payload
+	nrSamples uint32
+	sdi       uint32
+}
+
+// progressiveChunk - chunk of a track in a progressive file
+type progressiveChunk struct {
+	trak          *TrakBox
+	offset        uint64 // absolute position in original file
+	firstSampleNr uint32
+	nrSamples     uint32
+	sdi           uint32
+	parts         []chunkPart
+}
+
+// getChunks - all chunks of trak in chunk number order
+func getChunks(trak *TrakBox) ([]*progressiveChunk, error) {
+	stbl := trak.Mdia.Minf.Stbl
+	var offsets []uint64
+	switch {
+	case stbl.Stco != nil:
+		for _, o := range stbl.Stco.ChunkOffset {
+			offsets = append(offsets, uint64(o))
+		}
+	case stbl.Co64 != nil:
+		offsets = stbl.Co64.ChunkOffset
+	default:
+		return nil, fmt.Errorf("track %d: neither stco nor co64 present", trak.Tkhd.TrackID)
+	}
+	stsc := stbl.Stsc
+	chunks := make([]*progressiveChunk, 0, len(offsets))
+	sampleNr := uint32(1)
+	for i := range stsc.FirstChunk {
+		lastChunk := uint32(len(offsets))
+		if i+1 < len(stsc.FirstChunk) {
+			lastChunk = stsc.FirstChunk[i+1] - 1
+		}
+		for chunkNr := stsc.FirstChunk[i]; chunkNr <= lastChunk; chunkNr++ {
+			if chunkNr == 0 || chunkNr > uint32(len(offsets)) {
+				return nil, fmt.Errorf("track %d: bad chunk number %d", trak.Tkhd.TrackID, chunkNr)
+			}
+			chunks = append(chunks, &progressiveChunk{
+				trak:          trak,
+				offset:        offsets[chunkNr-1],
+				firstSampleNr: sampleNr,
+				nrSamples:     stsc.SamplesPerChunk[i],
+				sdi:           stsc.GetSampleDescriptionID(i + 1),
+			})
+			sampleNr += stsc.SamplesPerChunk[i]
+		}
+	}
+	if sampleNr-1 != stbl.Stsz.SampleNumber {
+		return nil, fmt.Errorf("track %d: %d samples in chunks, but %d in stsz", trak.Tkhd.TrackID,
+			sampleNr-1, stbl.Stsz.SampleNumber)
+	}
+	return chunks, nil
+}
+
+// rewriteProgressiveSamples - rewrite samples of trak and rebuild the mdat box. Samples with removed[nr-1] set
+// are left out, but the time tables of trak are not changed. The chunks of all tracks are returned,
+// and their offsets must be set with setChunkOffsets when the moov box is complete.
+func (f *File) rewriteProgressiveSamples(trak *TrakBox, rewrite SampleRewriter,
+	removed []bool) (map[*TrakBox][]*progressiveChunk, error) {
+	mdat := f.Mdat
+	if mdat == nil {
+		return nil, fmt.Errorf("no mdat box")
+	}
+	if mdat.IsLazy() || len(mdat.DataParts) > 0 {
+		return nil, fmt.Errorf("mdat data not available")
+	}
+	trakChunks := make(map[*TrakBox][]*progressiveChunk)
+	var chunks []*progressiveChunk
+	for _, t := range f.Moov.Traks {
+		tc, err := getChunks(t)
+		if err != nil {
+			return nil, err
+		}
+		trakChunks[t] = tc
+		chunks = append(chunks, tc...)
+	}
+	// Rewrite the samples of trak in decode order
+	stbl := trak.Mdia.Minf.Stbl
+	nrSamples := stbl.Stsz.SampleNumber
+	sampleData := make([][]byte, nrSamples)
+	sampleSdis := make([]uint32, nrSamples)
+	getData := func(offset, size uint64) ([]byte, error) {
+		return readMdatData(mdat, offset, size, nil)
+	}
+	err := visitProgressiveSamples(trak, trakChunks[trak], getData, func(nr, sdi uint32, s *FullSample) error {
+		newData, newSdi, err := rewrite(nr, sdi, s)
+		if err != nil {
+			return fmt.Errorf("sample %d: %w", nr, err)
+		}
+		sampleData[nr-1] = newData
+		sampleSdis[nr-1] = newSdi
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Write all chunks in file order
+	sort.SliceStable(chunks, func(i, j int) bool { return chunks[i].offset < chunks[j].offset })
+	var newMdatData []byte
+	for _, c := range chunks {
+		if c.trak != trak {
+			size, err := c.trak.Mdia.Minf.Stbl.Stsz.GetTotalSampleSize(c.firstSampleNr, c.firstSampleNr+c.nrSamples-1)
+			if err != nil {
+				return nil, err
+			}
+			data, err := getData(c.offset, size)
+			if err != nil {
+				return nil, fmt.Errorf("track %d: %w", c.trak.Tkhd.TrackID, err)
+			}
+			c.parts = []chunkPart{{offset: uint64(len(newMdatData)), nrSamples: c.nrSamples, sdi: c.sdi}}
+			newMdatData = append(newMdatData, data...)
+			continue
+		}
+		for nr := c.firstSampleNr; nr < c.firstSampleNr+c.nrSamples; nr++ {
+			if removed != nil && removed[nr-1] {
+				continue
+			}
+			sdi := sampleSdis[nr-1]
+			if len(c.parts) == 0 || c.parts[len(c.parts)-1].sdi != sdi {
+				c.parts = append(c.parts, chunkPart{offset: uint64(len(newMdatData)), sdi: sdi})
+			}
+			c.parts[len(c.parts)-1].nrSamples++
+			newMdatData = append(newMdatData, sampleData[nr-1]...)
+		}
+	}
+	mdat.SetData(newMdatData)
+
+	stsz := stbl.Stsz
+	stsz.SampleUniformSize = 0
+	stsz.SampleSize = make([]uint32, 0, nrSamples)
+	for i, d := range sampleData {
+		if removed == nil || !removed[i] {
+			stsz.SampleSize = append(stsz.SampleSize, uint32(len(d)))
+		}
+	}
+	stsz.SampleNumber = uint32(len(stsz.SampleSize))
+	stsc := &StscBox{Version: stbl.Stsc.Version, Flags: stbl.Stsc.Flags}
+	chunkNr := uint32(1)
+	for _, c := range trakChunks[trak] {
+		for _, p := range c.parts {
+			nrEntries := len(stsc.FirstChunk)
+			if nrEntries == 0 || stsc.SamplesPerChunk[nrEntries-1] != p.nrSamples ||
+				stsc.GetSampleDescriptionID(nrEntries) != p.sdi {
+				stsc.AddEntry(chunkNr, p.nrSamples, p.sdi)
+			}
+			chunkNr++
+		}
+	}
+	*stbl.Stsc = *stsc
+	return trakChunks, nil
+}
+
+// setChunkOffsets - set the chunk offsets of all tracks after the mdat box has been rebuilt.
+// The number of chunks and a switch to co64 change the moov size, so repeat until the mdat position is stable.
+func (f *File) setChunkOffsets(trakChunks map[*TrakBox][]*progressiveChunk) {
+	mdat := f.Mdat
+	prevMdatStart := uint64(math.MaxUint64)
+	for {
+		var mdatStart uint64
+		for _, b := range f.Children {
+			if b == mdat {
+				break
+			}
+			mdatStart += b.Size()
+		}
+		mdatSize := mdat.Size() // Sets LargeSize if needed
+		mdatPayloadStart := mdatStart + mdatSize - mdat.DataLength()
+		changed := false
+		for _, t := range f.Moov.Traks {
+			var offsets []uint64
+			var maxOffset uint64
+			for _, c := range trakChunks[t] {
+				for _, p := range c.parts {
+					offset := mdatPayloadStart + p.offset
+					offsets = append(offsets, offset)
+					if offset > maxOffset {
+						maxOffset = offset
+					}
+				}
+			}
+			tStbl := t.Mdia.Minf.Stbl
+			if tStbl.Stco != nil && maxOffset > math.MaxUint32 {
+				replaceStcoWithCo64(tStbl)
+				changed = true
+			}
+			if tStbl.Co64 != nil {
+				tStbl.Co64.ChunkOffset = offsets
+			} else {
+				tStbl.Stco.ChunkOffset = make([]uint32, len(offsets))
+				for i, o := range offsets {
+					tStbl.Stco.ChunkOffset[i] = uint32(o)
+				}
+			}
+		}
+		mdat.StartPos = mdatStart
+		if !changed && mdatStart == prevMdatStart {
+			break
+		}
+		prevMdatStart = mdatStart
+	}
+}
@@ -0,0 +1,154 @@
+package mp4
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+type rewrittenSample struct {
+	data []byte
+	sdi  uint32
+}
+
+// collectSamples - data and sample description index of all samples of track
+func collectSamples(t *testing.T, f *File, trackID uint32) []rewrittenSample {
+	t.Helper()
+	var samples []rewrittenSample
+	err := f.RewriteTrackSamples(trackID, func(nr, sdi uint32, s *FullSample) ([]byte, uint32, error) {
+		samples = append(samples, rewrittenSample{append([]byte{}, s.Data...), sdi})
+		return s.Data, sdi, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return samples
+}
+
+func encodeAndDecodeFile(t *testing.T, f *File) *File {
+	t.Helper()
+	buf := bytes.Buffer{}
+	if err := f.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	decFile, err := DecodeFile(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return decFile
+}
+
+// extendSample - add two bytes to each sample, and use sample description 2 from sample changeNr
+func extendSample(changeNr uint32) SampleRewriter {
+	return func(nr, sdi uint32, s *FullSample) ([]byte, uint32, error) {
+		if nr >= changeNr {
+			sdi = 2
+		}
+		return append(append([]byte{}, s.Data...), 0xfe, 0xff), sdi, nil
+	}
+}
+
+func TestRewriteProgressiveTrackSamples(t *testing.T) {
+	fd, err := os.Open("./testdata/prog_8s.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+	f, err := DecodeFile(fd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	audio := collectSamples(t, f, 1)
+	video := collectSamples(t, f, 2)
+	nrChunks := len(f.Moov.Traks[1].Mdia.Minf.Stbl.Stco.ChunkOffset)
+	if err := f.RewriteTrackSamples(2, extendSample(100)); err != nil {
+		t.Fatal(err)
+	}
+	f = encodeAndDecodeFile(t, f)
+	stbl := f.Moov.Traks[1].Mdia.Minf.Stbl
+	if len(stbl.Stco.ChunkOffset) != nrChunks+1 {
+		t.Errorf("got %d chunks instead of %d", len(stbl.Stco.ChunkOffset), nrChunks+1)
+	}
+	if stbl.Stsz.GetSampleSize(1) != uint32(len(video[0].data)+2) {
+		t.Errorf("stsz not updated")
+	}
+	for i, s := range collectSamples(t, f, 2) {
+		wantSdi := uint32(1)
+		if i+1 >= 100 {
+			wantSdi = 2
+		}
+		if !bytes.Equal(s.data[:len(s.data)-2], video[i].data) || s.sdi != wantSdi {
+			t.Errorf("video sample %d: got sample description %d and %d bytes", i+1, s.sdi, len(s.data))
+		}
+	}
+	for i, s := range collectSamples(t, f, 1) {
+		if !bytes.Equal(s.data, audio[i].data) || s.sdi != 1 {
+			t.Errorf("audio sample %d changed", i+1)
+		}
+	}
+}
+
+func TestRewriteFragmentedTrackSamples(t *testing.T) {
+	fd, err := os.Open("./testdata/1.m4s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+	seg, err := DecodeFile(fd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	samples, err := seg.Segments[0].Fragments[0].GetFullSamples(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	createFile := func() *File {
+		init := CreateEmptyInit()
+		init.AddEmptyTrack(90000, "video", "und")
+		f := NewFile()
+		f.AddChild(init.Ftyp, 0)
+		f.AddChild(init.Moov, init.Ftyp.Size())
+		for i := 0; i < 2; i++ {
+			frag, err := CreateFragment(uint32(i+1), 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, s := range samples[30*i : 30*(i+1)] {
+				frag.AddFullSample(s)
+			}
+			seg := NewMediaSegmentWithoutStyp()
+			seg.AddFragment(frag)
+			f.AddMediaSegment(seg)
+		}
+		return encodeAndDecodeFile(t, f)
+	}
+
+	f := createFile()
+	if err := f.RewriteTrackSamples(1, extendSample(31)); err != nil {
+		t.Fatal(err)
+	}
+	f = encodeAndDecodeFile(t, f)
+	var frags []*Fragment
+	for _, seg := range f.Segments {
+		frags = append(frags, seg.Fragments...)
+	}
+	if len(frags) != 2 {
+		t.Fatalf("got %d fragments", len(frags))
+	}
+	for i, frag := range frags {
+		tfhd := frag.Moof.Traf.Tfhd
+		if tfhd.HasSampleDescriptionIndex() != (i == 1) || (i == 1 && tfhd.SampleDescriptionIndex != 2) {
+			t.Errorf("fragment %d: got tfhd flags %06x and sample description index %d", i+1, tfhd.Flags,
+				tfhd.SampleDescriptionIndex)
+		}
+	}
+	for i, s := range collectSamples(t, f, 1) {
+		if !bytes.Equal(s.data[:len(s.data)-2], samples[i].Data) {
+			t.Errorf("sample %d not rewritten", i+1)
+		}
+	}
+
+	if err := createFile().RewriteTrackSamples(1, extendSample(20)); err == nil {
+		t.Error("no error for sample description change within fragment")
+	}
+}
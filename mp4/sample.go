@@ -1,7 +1,5 @@
 package mp4
 
-import "encoding/binary"
-
 // Sample - sample as used in trun box (mdhd timescale)
 type Sample struct {
 	Flags                 uint32 // interpreted as SampleFlags
@@ -41,17 +39,3 @@ func (s *FullSample) PresentationTime() uint64 {
 	}
 	return uint64(p)
 }
-
-func toAnnexB(videoSample []byte) {
-	length := uint64(len(videoSample))
-	var pos uint64 = 0
-	for pos < length-4 {
-		lenSlice := videoSample[pos : pos+4]
-		nalLen := binary.BigEndian.Uint32(lenSlice)
-		videoSample[pos] = 0
-		videoSample[pos+1] = 0
-		videoSample[pos+2] = 0
-		videoSample[pos+3] = 1
-		pos += uint64(nalLen + 4)
-	}
-}
@@ -42,28 +42,14 @@ func DecodeStscSR(hdr BoxHeader, startPos uint64, sr bits.SliceReader) (Box, err
 	b := StscBox{
 		Version:         byte(versionAndFlags >> 24),
 		Flags:           versionAndFlags & flagsMask,
-		FirstChunk:      make([]uint32, entryCount),
-		SamplesPerChunk: make([]uint32, entryCount),
+		FirstChunk:      make([]uint32, 0, entryCount),
+		SamplesPerChunk: make([]uint32, 0, entryCount),
 	}
 
 	for i := 0; i < int(entryCount); i++ {
-		b.FirstChunk[i] = sr.ReadUint32()
-		b.SamplesPerChunk[i] = sr.ReadUint32()
-		sdi := sr.ReadUint32()
-		if i == 0 {
-			b.singleSampleDescriptionID = sdi
-		} else {
-			if sdi != b.singleSampleDescriptionID {
-				if b.singleSampleDescriptionID != 0 {
-					b.SampleDescriptionID = make([]uint32, entryCount)
-					for j := 0; j < i; j++ {
-						b.SampleDescriptionID[i] = sdi
-					}
-					b.singleSampleDescriptionID = 0
-				}
-				b.SampleDescriptionID[i] = sdi
-			}
-		}
+		firstChunk := sr.ReadUint32()
+		samplesPerChunk := sr.ReadUint32()
+		b.AddEntry(firstChunk, samplesPerChunk, sr.ReadUint32())
 	}
 	return &b, nil
 }
@@ -137,6 +123,28 @@ func (b *StscBox) SetSingleSampleDescriptionID(sampleDescriptionID uint32) {
 	b.SampleDescriptionID = nil
 }
 
+// AddEntry - add an entry for the chunks starting at firstChunk.
+// The sample description ID is stored as a single value as long as all entries have the same.
+func (b *StscBox) AddEntry(firstChunk, samplesPerChunk, sampleDescriptionID uint32) {
+	nrEntries := len(b.FirstChunk)
+	switch {
+	case nrEntries == 0:
+		b.singleSampleDescriptionID = sampleDescriptionID
+		b.SampleDescriptionID = nil
+	case b.singleSampleDescriptionID != 0 && sampleDescriptionID != b.singleSampleDescriptionID:
+		b.SampleDescriptionID = make([]uint32, nrEntries, nrEntries+1)
+		for i := range b.SampleDescriptionID {
+			b.SampleDescriptionID[i] = b.singleSampleDescriptionID
+		}
+		b.singleSampleDescriptionID = 0
+	}
+	if b.singleSampleDescriptionID == 0 {
+		b.SampleDescriptionID = append(b.SampleDescriptionID, sampleDescriptionID)
+	}
+	b.FirstChunk = append(b.FirstChunk, firstChunk)
+	b.SamplesPerChunk = append(b.SamplesPerChunk, samplesPerChunk)
+}
+
 // ChunkNrFromSampleNr - get chunk number from sampleNr (one-based)
 func (b *StscBox) ChunkNrFromSampleNr(sampleNr int) (chunkNr, firstSampleInChunk int, err error) {
 	nrEntries := len(b.FirstChunk) // Nr entries in stsc box
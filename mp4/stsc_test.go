@@ -1,6 +1,9 @@
 package mp4
 
 import (
+	"bytes"
+	"encoding/hex"
+	"io/ioutil"
 	"testing"
 
 	"github.com/go-test/deep"
@@ -78,6 +81,72 @@ func TestStsc(t *testing.T) {
 		stsc.SetSingleSampleDescriptionID(1)
 		boxDiffAfterEncodeAndDecode(t, stsc)
 	})
+
+	t.Run("multiple sample descriptions", func(t *testing.T) {
+		stsc := &StscBox{}
+		stsc.AddEntry(1, 256, 1)
+		stsc.AddEntry(3, 1000, 1)
+		stsc.AddEntry(5, 1000, 2)
+		if diff := deep.Equal(stsc.SampleDescriptionID, []uint32{1, 1, 2}); diff != nil {
+			t.Error(diff)
+		}
+		for i, want := range []uint32{1, 1, 2} {
+			if got := stsc.GetSampleDescriptionID(i + 1); got != want {
+				t.Errorf("entry %d: got sample description ID %d instead of %d", i+1, got, want)
+			}
+		}
+		boxDiffAfterEncodeAndDecode(t, stsc)
+	})
+
+	t.Run("decode multiple sample descriptions", func(t *testing.T) {
+		// Entries for chunks 1, 3, and 5 with sample description IDs 1, 1, and 2
+		data, _ := hex.DecodeString("00000034737473630000000000000003" +
+			"000000010000010000000001" + "00000003000003e800000001" + "00000005000003e800000002")
+		box, err := DecodeBox(0, bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		stsc := box.(*StscBox)
+		if diff := deep.Equal(stsc.SampleDescriptionID, []uint32{1, 1, 2}); diff != nil {
+			t.Error(diff)
+		}
+		var buf bytes.Buffer
+		if err := stsc.Encode(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf.Bytes(), data) {
+			t.Errorf("got %s after encode", hex.EncodeToString(buf.Bytes()))
+		}
+	})
+}
+
+// TestStscFromFiles - stsc boxes of the test files are encoded as read
+func TestStscFromFiles(t *testing.T) {
+	for _, name := range []string{"prog_8s.mp4", "init_prog.mp4", "cbcs.mp4"} {
+		raw, err := ioutil.ReadFile("testdata/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f, err := DecodeFile(bytes.NewReader(raw))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, trak := range f.Moov.Traks {
+			stsc := trak.Mdia.Minf.Stbl.Stsc
+			var buf bytes.Buffer
+			if err := stsc.Encode(&buf); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Contains(raw, buf.Bytes()) {
+				t.Errorf("%s track %d: encoded stsc not in file", name, trak.Tkhd.TrackID)
+			}
+			for i := range stsc.FirstChunk {
+				if sdi := stsc.GetSampleDescriptionID(i + 1); sdi != 1 {
+					t.Errorf("%s track %d: entry %d has sample description ID %d", name, trak.Tkhd.TrackID, i+1, sdi)
+				}
+			}
+		}
+	}
 }
 
 func TestStscContainingChunks(t *testing.T) {
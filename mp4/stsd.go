@@ -18,10 +18,14 @@ type StsdBox struct {
 	SampleCount uint32
 	AvcX        *VisualSampleEntryBox
 	HvcX        *VisualSampleEntryBox
+	DvX         *VisualSampleEntryBox
 	Mp4a        *AudioSampleEntryBox
 	AC3         *AudioSampleEntryBox
 	EC3         *AudioSampleEntryBox
+	AC4         *AudioSampleEntryBox
+	MhaX        *AudioSampleEntryBox
 	Wvtt        *WvttBox
+	Clcp        *ClosedCaptionSampleEntryBox
 	Children    []Box
 }
 
@@ -37,14 +41,22 @@ func (s *StsdBox) AddChild(box Box) {
 		s.AvcX = box.(*VisualSampleEntryBox)
 	case "hvc1", "hev1":
 		s.HvcX = box.(*VisualSampleEntryBox)
+	case "dvh1", "dvhe", "dva1", "dvav":
+		s.DvX = box.(*VisualSampleEntryBox)
 	case "mp4a":
 		s.Mp4a = box.(*AudioSampleEntryBox)
 	case "ac-3":
 		s.AC3 = box.(*AudioSampleEntryBox)
 	case "ec-3":
 		s.EC3 = box.(*AudioSampleEntryBox)
+	case "ac-4":
+		s.AC4 = box.(*AudioSampleEntryBox)
+	case "mha1", "mha2", "mhm1", "mhm2":
+		s.MhaX = box.(*AudioSampleEntryBox)
 	case "wvtt":
 		s.Wvtt = box.(*WvttBox)
+	case "c608", "c708":
+		s.Clcp = box.(*ClosedCaptionSampleEntryBox)
 	}
 	s.Children = append(s.Children, box)
 	s.SampleCount++
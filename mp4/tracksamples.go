@@ -0,0 +1,199 @@
+package mp4
+
+import (
+	"fmt"
+	"io"
+)
+
+// SampleVisitor - called for a sample of a track in decode order.
+// nr is the sample number starting at 1, and sdi the sample description index.
+// The sample data refers to the mdat box and must not be modified.
+type SampleVisitor func(nr, sdi uint32, s *FullSample) error
+
+// VisitTrackSamples - call visit for all samples of a track in decode order without changing the file.
+//
+// The samples of a progressive file are given by stts, ctts, stss, stsz, stsc and stco/co64, and those of a
+// fragmented file by the trun boxes of the track in all fragments. If the mdat data was lazily decoded,
+// the sample data is read from rs. If rs is nil, the sample data is then nil, which is enough for sample
+// sizes and times.
+func (f *File) VisitTrackSamples(trackID uint32, rs io.ReadSeeker, visit SampleVisitor) error {
+	if f.Moov == nil {
+		return fmt.Errorf("no moov box")
+	}
+	for _, trak := range f.Moov.Traks {
+		if trak.Tkhd.TrackID != trackID {
+			continue
+		}
+		if f.isFragmented {
+			return f.visitFragmentedSamples(trak, rs, visit)
+		}
+		chunks, err := getChunks(trak)
+		if err != nil {
+			return err
+		}
+		mdat := f.Mdat
+		if mdat == nil {
+			return fmt.Errorf("no mdat box")
+		}
+		getData := func(offset, size uint64) ([]byte, error) {
+			return readMdatData(mdat, offset, size, rs)
+		}
+		return visitProgressiveSamples(trak, chunks, getData, visit)
+	}
+	return fmt.Errorf("no track with ID %d", trackID)
+}
+
+// GetTrackSamples - all samples of a track in decode order, as given by VisitTrackSamples
+func (f *File) GetTrackSamples(trackID uint32, rs io.ReadSeeker) ([]FullSample, error) {
+	var samples []FullSample
+	err := f.VisitTrackSamples(trackID, rs, func(nr, sdi uint32, s *FullSample) error {
+		samples = append(samples, *s)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return samples, nil
+}
+
+// readMdatData - read size bytes at the absolute file position offset from mdat, or from rs if mdat is lazy.
+// A lazy mdat without rs gives nil data.
+func readMdatData(mdat *MdatBox, offset, size uint64, rs io.ReadSeeker) ([]byte, error) {
+	if mdat.IsLazy() {
+		if rs == nil {
+			return nil, nil
+		}
+		payloadStart := mdat.PayloadAbsoluteOffset()
+		if offset < payloadStart || offset+size > payloadStart+mdat.GetLazyDataSize() {
+			return nil, fmt.Errorf("sample data at %d outside mdat", offset)
+		}
+		if _, err := rs.Seek(int64(offset), io.SeekStart); err != nil {
+			return nil, err
+		}
+		data := make([]byte, size)
+		if _, err := io.ReadFull(rs, data); err != nil {
+			return nil, err
+		}
+		return data, nil
+	}
+	if len(mdat.DataParts) > 0 {
+		return nil, fmt.Errorf("mdat data parts not supported")
+	}
+	payloadStart := mdat.PayloadAbsoluteOffset()
+	if offset < payloadStart || offset+size > payloadStart+uint64(len(mdat.Data)) {
+		return nil, fmt.Errorf("sample data at %d outside mdat", offset)
+	}
+	start := offset - payloadStart
+	return mdat.Data[start : start+size], nil
+}
+
+// visitProgressiveSamples - call visit for the samples in the chunks of trak with data from getData
+func visitProgressiveSamples(trak *TrakBox, chunks []*progressiveChunk,
+	getData func(offset, size uint64) ([]byte, error), visit SampleVisitor) error {
+	stbl := trak.Mdia.Minf.Stbl
+	stts, ctts, stss := stbl.Stts, stbl.Ctts, stbl.Stss
+	var decodeTime uint64
+	var sttsIdx, sttsNr, cttsIdx, cttsNr uint32
+	for _, c := range chunks {
+		offset := c.offset
+		for nr := c.firstSampleNr; nr < c.firstSampleNr+c.nrSamples; nr++ {
+			size := stbl.Stsz.GetSampleSize(int(nr))
+			data, err := getData(offset, uint64(size))
+			if err != nil {
+				return fmt.Errorf("track %d: sample %d: %w", trak.Tkhd.TrackID, nr, err)
+			}
+			offset += uint64(size)
+			if int(sttsIdx) >= len(stts.SampleCount) {
+				return fmt.Errorf("sample %d: not in stts", nr)
+			}
+			dur := stts.SampleTimeDelta[sttsIdx]
+			if sttsNr++; sttsNr == stts.SampleCount[sttsIdx] {
+				sttsIdx, sttsNr = sttsIdx+1, 0
+			}
+			var cto int32
+			if ctts != nil && int(cttsIdx) < len(ctts.SampleCount) {
+				cto = ctts.SampleOffset[cttsIdx]
+				if cttsNr++; cttsNr == ctts.SampleCount[cttsIdx] {
+					cttsIdx, cttsNr = cttsIdx+1, 0
+				}
+			}
+			flags := SyncSampleFlags
+			if stss != nil && !stss.IsSyncSample(nr) {
+				flags = NonSyncSampleFlags
+			}
+			s := FullSample{
+				Sample:     NewSample(flags, dur, size, cto),
+				DecodeTime: decodeTime,
+				Data:       data,
+			}
+			decodeTime += uint64(dur)
+			if err := visit(nr, c.sdi, &s); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// visitFragmentedSamples - call visit for the samples of trak in all fragments
+func (f *File) visitFragmentedSamples(trak *TrakBox, rs io.ReadSeeker, visit SampleVisitor) error {
+	trackID := trak.Tkhd.TrackID
+	var trex *TrexBox
+	if f.Moov.Mvex != nil {
+		trex, _ = f.Moov.Mvex.GetTrex(trackID)
+	}
+	if trex == nil {
+		return fmt.Errorf("no trex box for track %d", trackID)
+	}
+	nr := uint32(1)
+	var decodeTime uint64
+	for _, seg := range f.Segments {
+		for _, frag := range seg.Fragments {
+			for _, traf := range frag.Moof.Trafs {
+				tfhd := traf.Tfhd
+				if tfhd.TrackID != trackID {
+					continue
+				}
+				if traf.Tfdt != nil {
+					decodeTime = traf.Tfdt.BaseMediaDecodeTime
+				}
+				sdi := trex.DefaultSampleDescriptionIndex
+				if tfhd.HasSampleDescriptionIndex() {
+					sdi = tfhd.SampleDescriptionIndex
+				}
+				// The moof start is the base unless signalled otherwise (or for a traf following another one
+				// without default-base-is-moof, which is not supported)
+				baseOffset := frag.Moof.StartPos
+				if tfhd.HasBaseDataOffset() {
+					baseOffset = tfhd.BaseDataOffset
+				}
+				// A trun without data offset follows the data of the previous one
+				offset := baseOffset
+				for _, trun := range traf.Truns {
+					trun.AddSampleDefaultValues(tfhd, trex)
+					if trun.HasDataOffset() {
+						offset = uint64(int64(baseOffset) + int64(trun.DataOffset))
+					}
+					for _, sample := range trun.Samples {
+						var data []byte
+						if frag.Mdat != nil {
+							var err error
+							data, err = readMdatData(frag.Mdat, offset, uint64(sample.Size), rs)
+							if err != nil {
+								return fmt.Errorf("track %d: sample %d: %w", trackID, nr, err)
+							}
+						}
+						s := FullSample{Sample: sample, DecodeTime: decodeTime, Data: data}
+						if err := visit(nr, sdi, &s); err != nil {
+							return err
+						}
+						offset += uint64(sample.Size)
+						decodeTime += uint64(sample.Dur)
+						nr++
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
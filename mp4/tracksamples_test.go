@@ -0,0 +1,119 @@
+package mp4
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+// visitSamples - copies of all samples of a track given by VisitTrackSamples
+func visitSamples(t *testing.T, f *File, trackID uint32, rs io.ReadSeeker) []FullSample {
+	t.Helper()
+	var samples []FullSample
+	err := f.VisitTrackSamples(trackID, rs, func(nr, sdi uint32, s *FullSample) error {
+		if nr != uint32(len(samples)+1) || sdi != 1 {
+			t.Errorf("got sample nr %d and sdi %d", nr, sdi)
+		}
+		samples = append(samples, *s)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return samples
+}
+
+func TestVisitProgressiveTrackSamples(t *testing.T) {
+	raw, err := ioutil.ReadFile("./testdata/prog_8s.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := DecodeFile(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var before bytes.Buffer
+	if err := f.Encode(&before); err != nil {
+		t.Fatal(err)
+	}
+	samples := visitSamples(t, f, 2, nil)
+	var after bytes.Buffer
+	if err := f.Encode(&after); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(before.Bytes(), after.Bytes()) {
+		t.Error("file changed by VisitTrackSamples")
+	}
+
+	want := collectSamples(t, f, 2)
+	if len(samples) != len(want) {
+		t.Fatalf("got %d samples instead of %d", len(samples), len(want))
+	}
+	got, err := f.GetTrackSamples(2, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(samples) || got[len(got)-1].DecodeTime != samples[len(samples)-1].DecodeTime {
+		t.Errorf("GetTrackSamples gave %d samples", len(got))
+	}
+	stts := f.Moov.Traks[1].Mdia.Minf.Stbl.Stts
+	for i, s := range samples {
+		decTime, dur := stts.GetDecodeTime(uint32(i + 1))
+		if !bytes.Equal(s.Data, want[i].data) || s.DecodeTime != decTime || s.Dur != dur {
+			t.Errorf("sample %d: got time %d dur %d size %d", i+1, s.DecodeTime, s.Dur, len(s.Data))
+		}
+	}
+
+	lazy, err := DecodeFile(bytes.NewReader(raw), WithDecodeMode(DecModeLazyMdat))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, s := range visitSamples(t, lazy, 2, bytes.NewReader(raw)) {
+		if !bytes.Equal(s.Data, want[i].data) {
+			t.Errorf("lazy sample %d: data differs", i+1)
+		}
+	}
+	for i, s := range visitSamples(t, lazy, 2, nil) {
+		if s.Data != nil || s.Size != uint32(len(want[i].data)) {
+			t.Errorf("lazy sample %d without reader: got size %d and %d bytes", i+1, s.Size, len(s.Data))
+		}
+	}
+	if err := f.VisitTrackSamples(3, nil, func(nr, sdi uint32, s *FullSample) error { return nil }); err == nil {
+		t.Error("no error for missing track")
+	}
+}
+
+func TestVisitFragmentedTrackSamples(t *testing.T) {
+	raw, err := ioutil.ReadFile("./testdata/prog_8s_dec_dashinit.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := DecodeFile(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, trex := range f.Moov.Mvex.Trexs {
+		var want []FullSample
+		for _, seg := range f.Segments {
+			for _, frag := range seg.Fragments {
+				fs, err := frag.GetFullSamples(trex)
+				if err != nil {
+					t.Fatal(err)
+				}
+				want = append(want, fs...)
+			}
+		}
+		samples := visitSamples(t, f, trex.TrackID, nil)
+		if len(samples) == 0 || len(samples) != len(want) {
+			t.Fatalf("track %d: got %d samples instead of %d", trex.TrackID, len(samples), len(want))
+		}
+		for i, s := range samples {
+			w := want[i]
+			if !bytes.Equal(s.Data, w.Data) || s.DecodeTime != w.DecodeTime || s.Sample != w.Sample {
+				t.Errorf("track %d sample %d: got time %d dur %d size %d", trex.TrackID, i+1, s.DecodeTime,
+					s.Dur, len(s.Data))
+			}
+		}
+	}
+}
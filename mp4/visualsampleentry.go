@@ -23,6 +23,10 @@ type VisualSampleEntryBox struct {
 	Btrt               *BtrtBox
 	Clap               *ClapBox
 	Pasp               *PaspBox
+	Colr               *ColrBox
+	Mdcv               *MdcvBox
+	Clli               *ClliBox
+	DvcC               *DvcCBox // dvcC, dvvC, or dvwC
 	Sinf               *SinfBox
 	Children           []Box
 }
@@ -34,7 +38,7 @@ func NewVisualSampleEntryBox(name string) *VisualSampleEntryBox {
 	return b
 }
 
-// CreateVisualSampleEntryBox - Create new VisualSampleEntry such as avc1, avc3, hev1, hvc1
+// CreateVisualSampleEntryBox - Create new VisualSampleEntry such as avc1, avc3, hev1, hvc1, dvh1
 func CreateVisualSampleEntryBox(name string, width, height uint16, sampleEntry Box) *VisualSampleEntryBox {
 	b := &VisualSampleEntryBox{
 		name:               name,
@@ -66,6 +70,14 @@ func (b *VisualSampleEntryBox) AddChild(child Box) {
 		b.Clap = child.(*ClapBox)
 	case "pasp":
 		b.Pasp = child.(*PaspBox)
+	case "colr":
+		b.Colr = child.(*ColrBox)
+	case "mdcv":
+		b.Mdcv = child.(*MdcvBox)
+	case "clli":
+		b.Clli = child.(*ClliBox)
+	case "dvcC", "dvvC", "dvwC":
+		b.DvcC = child.(*DvcCBox)
 	case "sinf":
 		b.Sinf = child.(*SinfBox)
 	}
@@ -0,0 +1,6 @@
+/*
+Package mpegh - parse MPEG-H 3D Audio meta data including MHAS packet headers.
+
+MPEG-H 3D Audio is defined in ISO/IEC 23008-3.
+*/
+package mpegh
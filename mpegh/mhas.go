@@ -0,0 +1,160 @@
+package mpegh
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/jaypadia-frame/mp4ff/bits"
+)
+
+// MHASPacketType - MHAS packet type as defined in ISO/IEC 23008-3 (2019) Section 14.4
+type MHASPacketType uint32
+
+// MHAS packet types
+const (
+	PacTypFillData        MHASPacketType = 0
+	PacTypMpegh3daCfg     MHASPacketType = 1
+	PacTypMpegh3daFrame   MHASPacketType = 2
+	PacTypAudioSceneInfo  MHASPacketType = 3
+	PacTypSync            MHASPacketType = 6
+	PacTypSyncGap         MHASPacketType = 7
+	PacTypMarker          MHASPacketType = 8
+	PacTypCRC16           MHASPacketType = 9
+	PacTypCRC32           MHASPacketType = 10
+	PacTypDescriptor      MHASPacketType = 11
+	PacTypUserInteraction MHASPacketType = 12
+	PacTypLoudnessDRC     MHASPacketType = 13
+	PacTypBufferInfo      MHASPacketType = 14
+	PacTypGlobalCRC16     MHASPacketType = 15
+	PacTypGlobalCRC32     MHASPacketType = 16
+	PacTypAudioTruncation MHASPacketType = 17
+	PacTypGenData         MHASPacketType = 18
+	PacTypEarcon          MHASPacketType = 19
+	PacTypPCMConfig       MHASPacketType = 20
+	PacTypPCMData         MHASPacketType = 21
+	PacTypLoudness        MHASPacketType = 22
+)
+
+var mhasPacketTypeNames = map[MHASPacketType]string{
+	PacTypFillData:        "FILLDATA",
+	PacTypMpegh3daCfg:     "MPEGH3DACFG",
+	PacTypMpegh3daFrame:   "MPEGH3DAFRAME",
+	PacTypAudioSceneInfo:  "AUDIOSCENEINFO",
+	PacTypSync:            "SYNC",
+	PacTypSyncGap:         "SYNCGAP",
+	PacTypMarker:          "MARKER",
+	PacTypCRC16:           "CRC16",
+	PacTypCRC32:           "CRC32",
+	PacTypDescriptor:      "DESCRIPTOR",
+	PacTypUserInteraction: "USERINTERACTION",
+	PacTypLoudnessDRC:     "LOUDNESS_DRC",
+	PacTypBufferInfo:      "BUFFERINFO",
+	PacTypGlobalCRC16:     "GLOBAL_CRC16",
+	PacTypGlobalCRC32:     "GLOBAL_CRC32",
+	PacTypAudioTruncation: "AUDIOTRUNCATION",
+	PacTypGenData:         "GENDATA",
+	PacTypEarcon:          "EARCON",
+	PacTypPCMConfig:       "PCMCONFIG",
+	PacTypPCMData:         "PCMDATA",
+	PacTypLoudness:        "LOUDNESS",
+}
+
+func (t MHASPacketType) String() string {
+	name, ok := mhasPacketTypeNames[t]
+	if !ok {
+		return fmt.Sprintf("RESERVED_%d", uint32(t))
+	}
+	return name
+}
+
+// MHASPacketHeader - MHAS packet header with type, label and payload length
+type MHASPacketHeader struct {
+	Type   MHASPacketType
+	Label  uint64
+	Length uint64
+}
+
+// MHASPacket - MHAS packet with header and payload
+type MHASPacket struct {
+	Header  MHASPacketHeader
+	Payload []byte
+}
+
+// readEscapedValue - escapedValue(nBits1, nBits2, nBits3) ISO/IEC 23003-3 Section 5.2
+func readEscapedValue(r *bits.AccErrReader, nBits1, nBits2, nBits3 int) uint64 {
+	value := uint64(r.Read(nBits1))
+	if value == (1<<nBits1)-1 {
+		valueAdd := uint64(r.Read(nBits2))
+		value += valueAdd
+		if valueAdd == (1<<nBits2)-1 {
+			value += uint64(r.Read(nBits3))
+		}
+	}
+	return value
+}
+
+// writeEscapedValue - write value as escapedValue(nBits1, nBits2, nBits3)
+func writeEscapedValue(w *bits.Writer, value uint64, nBits1, nBits2, nBits3 int) {
+	max1 := uint64(1<<nBits1) - 1
+	if value < max1 {
+		w.Write(uint(value), nBits1)
+		return
+	}
+	w.Write(uint(max1), nBits1)
+	value -= max1
+	max2 := uint64(1<<nBits2) - 1
+	if value < max2 {
+		w.Write(uint(value), nBits2)
+		return
+	}
+	w.Write(uint(max2), nBits2)
+	w.Write(uint(value-max2), nBits3)
+}
+
+// DecodeMHASPacketHeader - decode header and return it together with its size in bytes.
+// The header is byte-aligned since the packet payload starts at a byte boundary.
+func DecodeMHASPacketHeader(data []byte) (hdr MHASPacketHeader, size int, err error) {
+	r := bits.NewAccErrReader(bytes.NewBuffer(data))
+	hdr.Type = MHASPacketType(readEscapedValue(r, 3, 8, 8))
+	hdr.Label = readEscapedValue(r, 2, 8, 32)
+	hdr.Length = readEscapedValue(r, 11, 24, 24)
+	if r.AccError() != nil {
+		return hdr, 0, fmt.Errorf("decode MHAS packet header: %w", r.AccError())
+	}
+	size = r.NrBytesRead()
+	if r.NrBitsReadInCurrentByte() != 8 {
+		return hdr, 0, fmt.Errorf("MHAS packet header not byte aligned")
+	}
+	return hdr, size, nil
+}
+
+// Encode - encode MHAS packet header to bytes
+func (h MHASPacketHeader) Encode() []byte {
+	buf := bytes.Buffer{}
+	w := bits.NewWriter(&buf)
+	writeEscapedValue(w, uint64(h.Type), 3, 8, 8)
+	writeEscapedValue(w, h.Label, 2, 8, 32)
+	writeEscapedValue(w, h.Length, 11, 24, 24)
+	w.Flush()
+	return buf.Bytes()
+}
+
+// DecodeMHASPackets - decode a sequence of MHAS packets as in an mhm1 or mhm2 sample
+func DecodeMHASPackets(data []byte) ([]MHASPacket, error) {
+	var packets []MHASPacket
+	pos := 0
+	for pos < len(data) {
+		hdr, hdrSize, err := DecodeMHASPacketHeader(data[pos:])
+		if err != nil {
+			return nil, err
+		}
+		pos += hdrSize
+		end := pos + int(hdr.Length)
+		if hdr.Length > uint64(len(data)) || end > len(data) {
+			return nil, fmt.Errorf("MHAS packet %s length %d beyond data size", hdr.Type, hdr.Length)
+		}
+		packets = append(packets, MHASPacket{Header: hdr, Payload: data[pos:end]})
+		pos = end
+	}
+	return packets, nil
+}
@@ -0,0 +1,58 @@
+package mpegh
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestMHASPacketHeader(t *testing.T) {
+	testCases := []MHASPacketHeader{
+		{Type: PacTypMpegh3daCfg, Label: 1, Length: 12},
+		{Type: PacTypMpegh3daFrame, Label: 3, Length: 2047},
+		{Type: PacTypLoudness, Label: 300, Length: 100000},
+		{Type: PacTypSync, Label: 0, Length: 1},
+	}
+	for _, hdr := range testCases {
+		data := hdr.Encode()
+		got, size, err := DecodeMHASPacketHeader(data)
+		if err != nil {
+			t.Error(err)
+		}
+		if size != len(data) {
+			t.Errorf("got header size %d instead of %d", size, len(data))
+		}
+		if diff := deep.Equal(got, hdr); diff != nil {
+			t.Error(diff)
+		}
+	}
+}
+
+func TestDecodeMHASPackets(t *testing.T) {
+	cfgPayload := []byte{0x01, 0x02, 0x03}
+	framePayload := bytes.Repeat([]byte{0xaa}, 300)
+	var data []byte
+	data = append(data, MHASPacketHeader{Type: PacTypMpegh3daCfg, Label: 1, Length: uint64(len(cfgPayload))}.Encode()...)
+	data = append(data, cfgPayload...)
+	data = append(data, MHASPacketHeader{Type: PacTypMpegh3daFrame, Label: 1, Length: uint64(len(framePayload))}.Encode()...)
+	data = append(data, framePayload...)
+
+	packets, err := DecodeMHASPackets(data)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(packets) != 2 {
+		t.Fatalf("got %d packets instead of 2", len(packets))
+	}
+	if packets[0].Header.Type.String() != "MPEGH3DACFG" || !bytes.Equal(packets[0].Payload, cfgPayload) {
+		t.Errorf("bad first packet %v", packets[0].Header)
+	}
+	if packets[1].Header.Type != PacTypMpegh3daFrame || len(packets[1].Payload) != 300 {
+		t.Errorf("bad second packet %v", packets[1].Header)
+	}
+	_, err = DecodeMHASPackets(data[:len(data)-1])
+	if err == nil {
+		t.Errorf("expected error for truncated packet")
+	}
+}
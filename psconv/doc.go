@@ -0,0 +1,15 @@
+/*
+Package psconv - convert the placement of AVC and HEVC parameter sets in progressive and fragmented files.
+
+ToOutOfBand moves in-band SPS, PPS and (for HEVC) VPS NAL units out of the samples and into the avcC or hvcC
+box of avc1 or hvc1 sample entries. If the parameter sets change, a new sample description is added
+for the samples that use them.
+
+ToInBand does the opposite, and inserts the active parameter sets at every sync sample.
+The sample entries become avc3 or hev1, and keep their parameter sets.
+
+Both functions rewrite the samples with mp4.File.RewriteTrackSamples, so sample sizes, chunk offsets
+and trun data offsets are updated. The NAL unit length size (1, 2, or 4 bytes) of each sample description
+is kept.
+*/
+package psconv
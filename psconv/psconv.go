@@ -0,0 +1,446 @@
+package psconv
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+
+	"github.com/jaypadia-frame/mp4ff/avc"
+	"github.com/jaypadia-frame/mp4ff/bits"
+	"github.com/jaypadia-frame/mp4ff/hevc"
+	"github.com/jaypadia-frame/mp4ff/mp4"
+)
+
+// Parameter set kinds in decoding order
+const (
+	kindVPS = iota
+	kindSPS
+	kindPPS
+	nrKinds
+)
+
+// paramSets - active parameter sets of a track by kind and id
+type paramSets struct {
+	isHEVC bool
+	sets   [nrKinds]map[uint32][]byte
+}
+
+func newParamSets(isHEVC bool) *paramSets {
+	p := &paramSets{isHEVC: isHEVC}
+	for i := range p.sets {
+		p.sets[i] = make(map[uint32][]byte)
+	}
+	return p
+}
+
+// kind - parameter set kind of nalu, or -1 if it is not a parameter set
+func (p *paramSets) kind(nalu []byte) int {
+	if len(nalu) == 0 {
+		return -1
+	}
+	if p.isHEVC {
+		switch hevc.GetNaluType(nalu[0]) {
+		case hevc.NALU_VPS:
+			return kindVPS
+		case hevc.NALU_SPS:
+			return kindSPS
+		case hevc.NALU_PPS:
+			return kindPPS
+		}
+		return -1
+	}
+	switch avc.GetNaluType(nalu[0]) {
+	case avc.NALU_SPS:
+		return kindSPS
+	case avc.NALU_PPS:
+		return kindPPS
+	}
+	return -1
+}
+
+// add - add a parameter set, replacing any previous one of the same kind and id
+func (p *paramSets) add(kind int, nalu []byte) error {
+	id, err := p.id(kind, nalu)
+	if err != nil {
+		return err
+	}
+	p.sets[kind][id] = nalu
+	return nil
+}
+
+// id - parameter set id of nalu
+func (p *paramSets) id(kind int, nalu []byte) (uint32, error) {
+	var start int // position of ue(v) id
+	switch {
+	case p.isHEVC && kind == kindVPS:
+		if len(nalu) < 3 {
+			return 0, fmt.Errorf("VPS too short")
+		}
+		return uint32(nalu[2] >> 4), nil
+	case p.isHEVC && kind == kindSPS:
+		sps, err := hevc.ParseSPSNALUnit(nalu)
+		if err != nil {
+			return 0, fmt.Errorf("SPS: %w", err)
+		}
+		return uint32(sps.SpsID), nil
+	case p.isHEVC:
+		start = 2
+	case kind == kindSPS:
+		start = 4 // after profile_idc, constraint flags and level_idc
+	default:
+		start = 1
+	}
+	if len(nalu) <= start {
+		return 0, fmt.Errorf("parameter set too short")
+	}
+	r := bits.NewEBSPReader(bytes.NewReader(nalu[start:]))
+	id, err := r.ReadExpGolomb()
+	return uint32(id), err
+}
+
+// nalus - parameter sets of kind ordered by id
+func (p *paramSets) nalus(kind int) [][]byte {
+	ids := make([]uint32, 0, len(p.sets[kind]))
+	for id := range p.sets[kind] {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	nalus := make([][]byte, 0, len(ids))
+	for _, id := range ids {
+		nalus = append(nalus, p.sets[kind][id])
+	}
+	return nalus
+}
+
+// all - all parameter sets in decoding order
+func (p *paramSets) all() [][]byte {
+	var nalus [][]byte
+	for kind := 0; kind < nrKinds; kind++ {
+		nalus = append(nalus, p.nalus(kind)...)
+	}
+	return nalus
+}
+
+// isComplete - are the parameter sets needed to start decoding present
+func (p *paramSets) isComplete() bool {
+	return (!p.isHEVC || len(p.sets[kindVPS]) > 0) && len(p.sets[kindSPS]) > 0 && len(p.sets[kindPPS]) > 0
+}
+
+// key - the parameter sets as one string for comparison
+func (p *paramSets) key() string {
+	return string(lengthPrefixed(p.all()))
+}
+
+// entryParamSets - parameter sets in avcC or hvcC of a sample entry
+func entryParamSets(entry *mp4.VisualSampleEntryBox, isHEVC bool) (*paramSets, error) {
+	p := newParamSets(isHEVC)
+	var nalus [][]byte
+	if isHEVC {
+		for _, naluType := range []hevc.NaluType{hevc.NALU_VPS, hevc.NALU_SPS, hevc.NALU_PPS} {
+			nalus = append(nalus, entry.HvcC.GetNalusForType(naluType)...)
+		}
+	} else {
+		nalus = append(append(nalus, entry.AvcC.SPSnalus...), entry.AvcC.PPSnalus...)
+	}
+	for _, nalu := range nalus {
+		if err := p.add(p.kind(nalu), nalu); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// lengthPrefixed - sample data with 4-byte NAL unit lengths
+func lengthPrefixed(nalus [][]byte) []byte {
+	size := 0
+	for _, nalu := range nalus {
+		size += 4 + len(nalu)
+	}
+	data := make([]byte, size)
+	pos := 0
+	for _, nalu := range nalus {
+		binary.BigEndian.PutUint32(data[pos:], uint32(len(nalu)))
+		pos += 4 + copy(data[pos+4:], nalu)
+	}
+	return data
+}
+
+// sampleData - sample data with NAL unit lengths of lengthSize bytes
+func sampleData(nalus [][]byte, lengthSize int) ([]byte, error) {
+	return avc.ConvertNaluLengthSize(lengthPrefixed(nalus), 4, lengthSize)
+}
+
+// videoTrack - trak with trackID and its sample entries, which must all be AVC or all be HEVC
+func videoTrack(f *mp4.File, trackID uint32) (*mp4.TrakBox, []*mp4.VisualSampleEntryBox, bool, error) {
+	if f.Moov == nil {
+		return nil, nil, false, fmt.Errorf("no moov box")
+	}
+	var trak *mp4.TrakBox
+	for _, t := range f.Moov.Traks {
+		if t.Tkhd.TrackID == trackID {
+			trak = t
+		}
+	}
+	if trak == nil {
+		return nil, nil, false, fmt.Errorf("no track with ID %d", trackID)
+	}
+	var entries []*mp4.VisualSampleEntryBox
+	var isHEVC bool
+	for i, c := range trak.Mdia.Minf.Stbl.Stsd.Children {
+		entry, ok := c.(*mp4.VisualSampleEntryBox)
+		if !ok {
+			return nil, nil, false, fmt.Errorf("sample entry %s is not AVC or HEVC", c.Type())
+		}
+		var entryIsHEVC bool
+		switch entry.Type() {
+		case "avc1", "avc3":
+			if entry.AvcC == nil {
+				return nil, nil, false, fmt.Errorf("no avcC box in %s", entry.Type())
+			}
+		case "hvc1", "hev1":
+			if entry.HvcC == nil {
+				return nil, nil, false, fmt.Errorf("no hvcC box in %s", entry.Type())
+			}
+			entryIsHEVC = true
+		default:
+			return nil, nil, false, fmt.Errorf("sample entry %s is not AVC or HEVC", entry.Type())
+		}
+		if !avc.IsValidLengthSize(mp4.NaluLengthSize(entry)) {
+			return nil, nil, false, fmt.Errorf("%s: %w", entry.Type(), avc.ErrLengthSize)
+		}
+		if i == 0 {
+			isHEVC = entryIsHEVC
+		} else if entryIsHEVC != isHEVC {
+			return nil, nil, false, fmt.Errorf("mixed AVC and HEVC sample entries")
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) == 0 {
+		return nil, nil, false, fmt.Errorf("no sample entries")
+	}
+	return trak, entries, isHEVC, nil
+}
+
+// sampleParamSets - split sample with NAL unit lengths of lengthSize bytes into parameter sets,
+// which are added to ps, and other NAL units
+func sampleParamSets(ps *paramSets, sample []byte, lengthSize int) (others [][]byte, nrParamSets int, err error) {
+	nalus, err := avc.GetNalusFromSampleWithLengthSize(sample, lengthSize)
+	if err != nil {
+		return nil, 0, err
+	}
+	others = make([][]byte, 0, len(nalus))
+	for _, nalu := range nalus {
+		kind := ps.kind(nalu)
+		if kind < 0 {
+			others = append(others, nalu)
+			continue
+		}
+		if err := ps.add(kind, nalu); err != nil {
+			return nil, 0, err
+		}
+		nrParamSets++
+	}
+	return others, nrParamSets, nil
+}
+
+// ToOutOfBand - move in-band parameter sets of an AVC or HEVC track to its sample descriptions.
+// The sample entries become avc1 or hvc1 with all active parameter sets in avcC or hvcC.
+// Each time the parameter sets change, a sample description is added, unless an identical one exists.
+// For a fragmented file, parameter sets may only change at the start of a fragment.
+// If an error is returned, the file may be partially converted.
+func ToOutOfBand(f *mp4.File, trackID uint32) error {
+	trak, entries, isHEVC, err := videoTrack(f, trackID)
+	if err != nil {
+		return err
+	}
+	// The new sample entries are added while rewriting, so that the moov size is known
+	// when the chunk offsets of a progressive file are set
+	stsd := trak.Mdia.Minf.Stbl.Stsd
+	stsd.Children = nil
+	stsd.SampleCount = 0
+	var newEntries []*mp4.VisualSampleEntryBox
+	newSdis := make(map[string]uint32) // sample description index for original index and parameter sets
+	var ps *paramSets
+	var prevSdi uint32
+	rewrite := func(nr, sdi uint32, s *mp4.FullSample) ([]byte, uint32, error) {
+		if sdi == 0 || int(sdi) > len(entries) {
+			return nil, 0, fmt.Errorf("bad sample description index %d", sdi)
+		}
+		entry := entries[sdi-1]
+		if sdi != prevSdi {
+			var err error
+			if ps, err = entryParamSets(entry, isHEVC); err != nil {
+				return nil, 0, err
+			}
+			prevSdi = sdi
+		}
+		others, nrParamSets, err := sampleParamSets(ps, s.Data, mp4.NaluLengthSize(entry))
+		if err != nil {
+			return nil, 0, err
+		}
+		key := fmt.Sprintf("%d:%s", sdi, ps.key())
+		newSdi, ok := newSdis[key]
+		if !ok {
+			newEntry, err := outOfBandEntry(entry, ps)
+			if err != nil {
+				return nil, 0, err
+			}
+			newEntries = append(newEntries, newEntry)
+			stsd.AddChild(newEntry)
+			newSdi = uint32(len(newEntries))
+			newSdis[key] = newSdi
+		}
+		if nrParamSets == 0 {
+			return s.Data, newSdi, nil
+		}
+		data, err := sampleData(others, mp4.NaluLengthSize(entry))
+		return data, newSdi, err
+	}
+	if err := f.RewriteTrackSamples(trackID, rewrite); err != nil {
+		return err
+	}
+	if len(newEntries) == 0 { // No samples, so keep the sample entries
+		newEntries = entries
+		for _, e := range entries {
+			stsd.AddChild(e)
+		}
+	}
+	// AddChild points to the last entry
+	if isHEVC {
+		stsd.HvcX = newEntries[0]
+	} else {
+		stsd.AvcX = newEntries[0]
+	}
+	return nil
+}
+
+// outOfBandEntry - avc1 or hvc1 version of entry with the parameter sets ps.
+// The entry is returned as is if it already is avc1 or hvc1 with the same parameter sets.
+func outOfBandEntry(entry *mp4.VisualSampleEntryBox, ps *paramSets) (*mp4.VisualSampleEntryBox, error) {
+	if !ps.isComplete() {
+		return nil, fmt.Errorf("parameter sets missing")
+	}
+	entryPS, err := entryParamSets(entry, ps.isHEVC)
+	if err != nil {
+		return nil, err
+	}
+	if (entry.Type() == "avc1" || entry.Type() == "hvc1") && entryPS.key() == ps.key() {
+		return entry, nil
+	}
+	newEntry := *entry
+	sps := ps.nalus(kindSPS)
+	var cfg mp4.Box
+	if ps.isHEVC {
+		hvcC, err := mp4.CreateHvcC(ps.nalus(kindVPS), sps, ps.nalus(kindPPS), true, true, true, true)
+		if err != nil {
+			return nil, err
+		}
+		hevcSPS, err := hevc.ParseSPSNALUnit(sps[0])
+		if err != nil {
+			return nil, err
+		}
+		width, height := hevcSPS.ImageSize()
+		hvcC.LengthSizeMinusOne = entry.HvcC.LengthSizeMinusOne
+		newEntry.SetType("hvc1")
+		newEntry.HvcC = hvcC
+		newEntry.Width, newEntry.Height = uint16(width), uint16(height)
+		cfg = hvcC
+	} else {
+		avcC, err := mp4.CreateAvcC(sps, ps.nalus(kindPPS), true)
+		if err != nil {
+			return nil, err
+		}
+		avcSPS, err := avc.ParseSPSNALUnit(sps[0], false)
+		if err != nil {
+			return nil, err
+		}
+		avcC.NaluLengthSize = entry.AvcC.NaluLengthSize
+		newEntry.SetType("avc1")
+		newEntry.AvcC = avcC
+		newEntry.Width, newEntry.Height = uint16(avcSPS.Width), uint16(avcSPS.Height)
+		cfg = avcC
+	}
+	newEntry.Children = make([]mp4.Box, 0, len(entry.Children))
+	for _, c := range entry.Children {
+		if c.Type() == "avcC" || c.Type() == "hvcC" {
+			c = cfg
+		}
+		newEntry.Children = append(newEntry.Children, c)
+	}
+	return &newEntry, nil
+}
+
+// ToInBand - insert the active parameter sets of an AVC or HEVC track at every sync sample.
+// They are put after any access unit delimiter, and replace parameter sets already in the sample.
+// The sample entries become avc3 or hev1 and keep their parameter sets, but
+// the hvcC arrays are no longer signalled as complete.
+// If an error is returned, the file may be partially converted.
+func ToInBand(f *mp4.File, trackID uint32) error {
+	_, entries, isHEVC, err := videoTrack(f, trackID)
+	if err != nil {
+		return err
+	}
+	var ps *paramSets
+	var prevSdi uint32
+	rewrite := func(nr, sdi uint32, s *mp4.FullSample) ([]byte, uint32, error) {
+		if sdi == 0 || int(sdi) > len(entries) {
+			return nil, 0, fmt.Errorf("bad sample description index %d", sdi)
+		}
+		if sdi != prevSdi {
+			var err error
+			if ps, err = entryParamSets(entries[sdi-1], isHEVC); err != nil {
+				return nil, 0, err
+			}
+			prevSdi = sdi
+		}
+		lengthSize := mp4.NaluLengthSize(entries[sdi-1])
+		others, _, err := sampleParamSets(ps, s.Data, lengthSize)
+		if err != nil {
+			return nil, 0, err
+		}
+		if !s.IsSync() {
+			return s.Data, sdi, nil
+		}
+		if !ps.isComplete() {
+			return nil, 0, fmt.Errorf("parameter sets missing")
+		}
+		paramSets := ps.all()
+		nalus := make([][]byte, 0, len(paramSets)+len(others))
+		if len(others) > 0 && isAUD(others[0], isHEVC) {
+			nalus = append(nalus, others[0])
+			others = others[1:]
+		}
+		nalus = append(append(nalus, paramSets...), others...)
+		data, err := sampleData(nalus, lengthSize)
+		return data, sdi, err
+	}
+	if err := f.RewriteTrackSamples(trackID, rewrite); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !isHEVC {
+			entry.SetType("avc3")
+			continue
+		}
+		entry.SetType("hev1")
+		for i, array := range entry.HvcC.NaluArrays {
+			switch naluType := array.NaluType(); naluType {
+			case hevc.NALU_VPS, hevc.NALU_SPS, hevc.NALU_PPS:
+				entry.HvcC.NaluArrays[i] = *hevc.NewNaluArray(false, naluType, array.Nalus)
+			}
+		}
+	}
+	return nil
+}
+
+// isAUD - is nalu an access unit delimiter
+func isAUD(nalu []byte, isHEVC bool) bool {
+	if len(nalu) == 0 {
+		return false
+	}
+	if isHEVC {
+		return hevc.GetNaluType(nalu[0]) == hevc.NALU_AUD
+	}
+	return avc.GetNaluType(nalu[0]) == avc.NALU_AUD
+}
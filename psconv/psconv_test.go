@@ -0,0 +1,234 @@
+package psconv
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/jaypadia-frame/mp4ff/avc"
+	"github.com/jaypadia-frame/mp4ff/internal/mp4test"
+	"github.com/jaypadia-frame/mp4ff/mp4"
+)
+
+// trackSamples - the samples of a track in decode order
+func trackSamples(t *testing.T, f *mp4.File, trackID uint32) []mp4.FullSample {
+	t.Helper()
+	var samples []mp4.FullSample
+	err := f.VisitTrackSamples(trackID, nil, func(nr, sdi uint32, s *mp4.FullSample) error {
+		samples = append(samples, *s)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return samples
+}
+
+// inBandFile - avc3 file with the samples of testdata 1.m4s. In-band parameter sets are in samples 1 and 31,
+// and the level of the SPS in sample 31 is changed, so that there are two different SPS.
+func inBandFile(t *testing.T, fragmented bool) (f *mp4.File, spss [][]byte) {
+	t.Helper()
+	seg, err := mp4.ReadMP4File("../mp4/testdata/1.m4s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	samples, err := seg.Segments[0].Fragments[0].GetFullSamples(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sps, pps := avc.GetParameterSets(samples[0].Data)
+	data := append([]byte{}, samples[30].Data...)
+	sps2 := data[4 : 4+len(sps[0])]
+	sps2[3]++ // level_idc
+	samples[30].Data = data
+	init := mp4.CreateEmptyInit()
+	init.AddEmptyTrack(90000, "video", "und")
+	if err := init.Moov.Trak.SetAVCDescriptor("avc3", sps, pps, false); err != nil {
+		t.Fatal(err)
+	}
+	if !fragmented {
+		f, err = mp4.CreateProgressiveFile(init, [][]mp4.FullSample{samples})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return mp4test.EncodeDecode(t, f), [][]byte{sps[0], sps2}
+	}
+	f = mp4.NewFile()
+	f.AddChild(init.Ftyp, 0)
+	f.AddChild(init.Moov, init.Ftyp.Size())
+	for i := 0; i < 2; i++ {
+		frag, err := mp4.CreateFragment(uint32(i+1), init.Moov.Trak.Tkhd.TrackID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range samples[30*i : 30*(i+1)] {
+			frag.AddFullSample(s)
+		}
+		seg := mp4.NewMediaSegmentWithoutStyp()
+		seg.AddFragment(frag)
+		f.AddMediaSegment(seg)
+	}
+	return mp4test.EncodeDecode(t, f), [][]byte{sps[0], sps2}
+}
+
+func TestToOutOfBand(t *testing.T) {
+	for _, fragmented := range []bool{false, true} {
+		f, spss := inBandFile(t, fragmented)
+		trackID := f.Moov.Trak.Tkhd.TrackID
+		inSamples := trackSamples(t, f, trackID)
+		if err := ToOutOfBand(f, trackID); err != nil {
+			t.Fatal(err)
+		}
+		f = mp4test.EncodeDecode(t, f)
+		stsd := f.Moov.Trak.Mdia.Minf.Stbl.Stsd
+		if len(stsd.Children) != 2 {
+			t.Fatalf("fragmented=%t: got %d sample descriptions", fragmented, len(stsd.Children))
+		}
+		for i, c := range stsd.Children {
+			entry := c.(*mp4.VisualSampleEntryBox)
+			if entry.Type() != "avc1" || len(entry.AvcC.SPSnalus) != 1 || !bytes.Equal(entry.AvcC.SPSnalus[0], spss[i]) ||
+				len(entry.AvcC.PPSnalus) != 1 || entry.Width != 640 {
+				t.Errorf("fragmented=%t: sample entry %d: got %s with %d SPS and width %d", fragmented, i+1,
+					entry.Type(), len(entry.AvcC.SPSnalus), entry.Width)
+			}
+		}
+		var sdis []uint32
+		outSamples := trackSamples(t, f, trackID)
+		err := f.VisitTrackSamples(trackID, nil, func(nr, sdi uint32, s *mp4.FullSample) error {
+			sdis = append(sdis, sdi)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(outSamples) != len(inSamples) {
+			t.Fatalf("fragmented=%t: got %d samples instead of %d", fragmented, len(outSamples), len(inSamples))
+		}
+		for i, s := range outSamples {
+			wantSdi := uint32(1)
+			if i >= 30 {
+				wantSdi = 2
+			}
+			if sdis[i] != wantSdi {
+				t.Errorf("fragmented=%t: sample %d: got sample description index %d", fragmented, i+1, sdis[i])
+			}
+			wantData := inSamples[i].Data
+			if i == 0 || i == 30 {
+				sps, pps := avc.GetParameterSets(wantData)
+				wantData = wantData[8+len(sps[0])+len(pps[0]):]
+			}
+			if !bytes.Equal(s.Data, wantData) || s.DecodeTime != inSamples[i].DecodeTime ||
+				s.IsSync() != inSamples[i].IsSync() {
+				t.Errorf("fragmented=%t: sample %d differs", fragmented, i+1)
+			}
+		}
+		if !fragmented {
+			stsc := f.Moov.Trak.Mdia.Minf.Stbl.Stsc
+			if len(stsc.FirstChunk) != 2 || stsc.FirstChunk[1] != 2 || stsc.SamplesPerChunk[0] != 30 {
+				t.Errorf("got stsc %+v", stsc)
+			}
+		}
+	}
+}
+
+// TestRoundTrip - the video track of prog_8s.mp4 is avc1 with SPS and PPS also at the start of each sync sample
+func TestRoundTrip(t *testing.T) {
+	f, err := mp4.ReadMP4File("../mp4/testdata/prog_8s.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	videoID, audioID := uint32(2), uint32(1)
+	videoSamples := trackSamples(t, f, videoID)
+	audioSamples := trackSamples(t, f, audioID)
+
+	if err := ToOutOfBand(f, videoID); err != nil {
+		t.Fatal(err)
+	}
+	f = mp4test.EncodeDecode(t, f)
+	stsd := f.Moov.Traks[1].Mdia.Minf.Stbl.Stsd
+	if len(stsd.Children) != 1 || stsd.AvcX.Type() != "avc1" {
+		t.Errorf("got %d sample entries of type %s", len(stsd.Children), stsd.AvcX.Type())
+	}
+	for i, s := range trackSamples(t, f, videoID) {
+		wantData := videoSamples[i].Data
+		if s.IsSync() {
+			sps, pps := avc.GetParameterSets(wantData)
+			wantData = wantData[8+len(sps[0])+len(pps[0]):]
+		}
+		if !bytes.Equal(s.Data, wantData) {
+			t.Errorf("video sample %d: parameter sets not removed", i+1)
+		}
+	}
+	for i, s := range trackSamples(t, f, audioID) {
+		if !bytes.Equal(s.Data, audioSamples[i].Data) {
+			t.Errorf("audio sample %d changed", i+1)
+		}
+	}
+
+	if err := ToInBand(f, videoID); err != nil {
+		t.Fatal(err)
+	}
+	f = mp4test.EncodeDecode(t, f)
+	stsd = f.Moov.Traks[1].Mdia.Minf.Stbl.Stsd
+	if stsd.AvcX.Type() != "avc3" || len(stsd.AvcX.AvcC.SPSnalus) != 1 {
+		t.Errorf("got sample entry %s", stsd.AvcX.Type())
+	}
+	for i, s := range trackSamples(t, f, videoID) {
+		if !bytes.Equal(s.Data, videoSamples[i].Data) {
+			t.Errorf("video sample %d differs after round trip", i+1)
+		}
+	}
+	for i, s := range trackSamples(t, f, audioID) {
+		if !bytes.Equal(s.Data, audioSamples[i].Data) {
+			t.Errorf("audio sample %d changed", i+1)
+		}
+	}
+}
+
+func TestLengthSize(t *testing.T) {
+	f, _ := inBandFile(t, false)
+	trackID := f.Moov.Trak.Tkhd.TrackID
+	if err := f.ConvertNaluLengthSize(trackID, 2); err != nil {
+		t.Fatal(err)
+	}
+	inSamples := trackSamples(t, f, trackID)
+	if err := ToOutOfBand(f, trackID); err != nil {
+		t.Fatal(err)
+	}
+	f = mp4test.EncodeDecode(t, f)
+	for _, c := range f.Moov.Trak.Mdia.Minf.Stbl.Stsd.Children {
+		if lengthSize := mp4.NaluLengthSize(c); lengthSize != 2 {
+			t.Errorf("got %d-byte NAL unit lengths in %s", lengthSize, c.Type())
+		}
+	}
+	for i, s := range trackSamples(t, f, trackID) {
+		if _, err := avc.GetNalusFromSampleWithLengthSize(s.Data, 2); err != nil {
+			t.Errorf("sample %d: %s", i+1, err)
+		}
+	}
+	if err := ToInBand(f, trackID); err != nil {
+		t.Fatal(err)
+	}
+	f = mp4test.EncodeDecode(t, f)
+	for i, s := range trackSamples(t, f, trackID) {
+		if (i == 0 || i == 30) && !bytes.Equal(s.Data, inSamples[i].Data) {
+			t.Errorf("sync sample %d differs after round trip", i+1)
+		}
+	}
+}
+
+func TestParamSetIDs(t *testing.T) {
+	ps := newParamSets(false)
+	sps := []byte{0x67, 0x4d, 0x40, 0x1f, 0x4e} // ue(v) 1 after level_idc
+	pps := []byte{0x68, 0x3c}                   // ue(v) 6
+	for _, nalu := range [][]byte{sps, pps} {
+		if err := ps.add(ps.kind(nalu), nalu); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if ps.sets[kindSPS][1] == nil || ps.sets[kindPPS][6] == nil || !ps.isComplete() {
+		t.Errorf("got parameter sets %v", ps.sets)
+	}
+	if ps.kind([]byte{0x65}) != -1 {
+		t.Error("IDR slice is a parameter set")
+	}
+}
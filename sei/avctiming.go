@@ -0,0 +1,192 @@
+package sei
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/jaypadia-frame/mp4ff/avc"
+	"github.com/jaypadia-frame/mp4ff/bits"
+)
+
+// InitialCpbRemoval - initial_cpb_removal_delay and offset for one SchedSelIdx
+type InitialCpbRemoval struct {
+	Delay  uint32
+	Offset uint32
+}
+
+// BufferingPeriodAVCSEI - AVC buffering_period SEI message (type 0) ISO/IEC 14496-10 Section D.1.2
+type BufferingPeriodAVCSEI struct {
+	payload           []byte
+	SeqParameterSetID uint
+	NalInitialCpb     []InitialCpbRemoval
+	VclInitialCpb     []InitialCpbRemoval
+}
+
+// DecodeBufferingPeriodAVCSEI - decode AVC buffering period SEI using HRD parameters from SPS
+func DecodeBufferingPeriodAVCSEI(sd *SEIData, sps *avc.SPS) (SEIMessage, error) {
+	r := bits.NewAccErrReader(bytes.NewBuffer(sd.payload))
+	bp := &BufferingPeriodAVCSEI{payload: sd.payload}
+	bp.SeqParameterSetID = r.ReadExpGolomb()
+	if r.AccError() != nil {
+		return nil, fmt.Errorf("buffering_period: %w", r.AccError())
+	}
+	if bp.SeqParameterSetID != sps.ParameterID {
+		return nil, fmt.Errorf("buffering_period: sps id %d does not match %d", bp.SeqParameterSetID, sps.ParameterID)
+	}
+	if sps.VUI != nil {
+		if sps.VUI.NalHrdParametersPresentFlag {
+			bp.NalInitialCpb = readInitialCpbRemovals(r, sps.VUI.NalHrdParameters)
+		}
+		if sps.VUI.VclHrdParametersPresentFlag {
+			bp.VclInitialCpb = readInitialCpbRemovals(r, sps.VUI.VclHrdParameters)
+		}
+	}
+	if r.AccError() != nil {
+		return nil, fmt.Errorf("buffering_period: %w", r.AccError())
+	}
+	return bp, nil
+}
+
+func readInitialCpbRemovals(r *bits.AccErrReader, hrd *avc.HrdParameters) []InitialCpbRemoval {
+	nrBits := int(hrd.InitialCpbRemovalDelayLengthMinus1 + 1)
+	icr := make([]InitialCpbRemoval, hrd.CpbCountMinus1+1)
+	for i := range icr {
+		icr[i].Delay = uint32(r.Read(nrBits))
+		icr[i].Offset = uint32(r.Read(nrBits))
+	}
+	return icr
+}
+
+// Type - SEI payload type
+func (s *BufferingPeriodAVCSEI) Type() uint {
+	return SEIBufferingPeriodType
+}
+
+// Size - size in bytes of raw SEI message rbsp payload
+func (s *BufferingPeriodAVCSEI) Size() uint {
+	return uint(len(s.payload))
+}
+
+// String - print sps id and initial cpb removal delays and offsets
+func (s *BufferingPeriodAVCSEI) String() string {
+	msg := fmt.Sprintf("SEI type %d BufferingPeriod: spsID=%d", s.Type(), s.SeqParameterSetID)
+	if len(s.NalInitialCpb) > 0 {
+		msg += fmt.Sprintf(", nal=%v", s.NalInitialCpb)
+	}
+	if len(s.VclInitialCpb) > 0 {
+		msg += fmt.Sprintf(", vcl=%v", s.VclInitialCpb)
+	}
+	return msg
+}
+
+// Payload - SEI raw rbsp payload
+func (s *BufferingPeriodAVCSEI) Payload() []byte {
+	return s.payload
+}
+
+// PicTimingAVCSEI - AVC pic_timing SEI message (type 1) ISO/IEC 14496-10 Section D.1.3
+// The presence of the fields is given by the SPS (CpbDpbDelaysPresent and PicStructPresent).
+type PicTimingAVCSEI struct {
+	payload             []byte
+	CpbDpbDelaysPresent bool
+	CpbRemovalDelay     uint32
+	DpbOutputDelay      uint32
+	PicStructPresent    bool
+	PicStruct           byte
+	ClockTimestamps     []ClockTimestamp
+}
+
+// numClockTS - number of clock timestamps for pic_struct values 0-8 (Table D-1)
+var numClockTS = [9]int{1, 1, 1, 2, 2, 3, 3, 2, 3}
+
+// DecodePicTimingAVCSEI - decode AVC pic_timing SEI using HRD and pic_struct information from SPS
+func DecodePicTimingAVCSEI(sd *SEIData, sps *avc.SPS) (SEIMessage, error) {
+	r := bits.NewAccErrReader(bytes.NewBuffer(sd.payload))
+	pt := &PicTimingAVCSEI{payload: sd.payload}
+	var hrd *avc.HrdParameters
+	if sps.VUI != nil {
+		hrd = sps.VUI.NalHrdParameters
+		if hrd == nil {
+			hrd = sps.VUI.VclHrdParameters
+		}
+	}
+	pt.CpbDpbDelaysPresent = sps.CpbDpbDelaysPresent()
+	if pt.CpbDpbDelaysPresent {
+		pt.CpbRemovalDelay = uint32(r.Read(int(hrd.CpbRemovalDelayLengthMinus1 + 1)))
+		pt.DpbOutputDelay = uint32(r.Read(int(hrd.DpbOutpuDelayLengthMinus1 + 1)))
+	}
+	pt.PicStructPresent = sps.PicStructPresent()
+	if pt.PicStructPresent {
+		pt.PicStruct = byte(r.Read(4))
+		if int(pt.PicStruct) >= len(numClockTS) {
+			return nil, fmt.Errorf("pic_timing: reserved pic_struct %d", pt.PicStruct)
+		}
+		timeOffsetLength := 24
+		if hrd != nil {
+			timeOffsetLength = int(hrd.TimeOffsetLength)
+		}
+		for i := 0; i < numClockTS[pt.PicStruct]; i++ {
+			c := ClockTimestamp{}
+			c.ClockTimestampFlag = r.ReadFlag()
+			if c.ClockTimestampFlag {
+				c.CtType = byte(r.Read(2))
+				c.UnitsFieldBasedFlag = r.ReadFlag()
+				c.CountingType = byte(r.Read(5))
+				c.FullTimestampFlag = r.ReadFlag()
+				c.DiscontinuityFlag = r.ReadFlag()
+				c.CntDroppedFlag = r.ReadFlag()
+				c.NFrames = uint16(r.Read(8))
+				readClockTimestampHMS(r, &c)
+				c.TimeOffsetLength = byte(timeOffsetLength)
+				if timeOffsetLength > 0 {
+					c.TimeOffsetValue = r.ReadVInt(timeOffsetLength)
+				}
+			}
+			pt.ClockTimestamps = append(pt.ClockTimestamps, c)
+		}
+	}
+	if r.AccError() != nil {
+		return nil, fmt.Errorf("pic_timing: %w", r.AccError())
+	}
+	return pt, nil
+}
+
+// Type - SEI payload type
+func (s *PicTimingAVCSEI) Type() uint {
+	return SEIPicTimingType
+}
+
+// Size - size in bytes of raw SEI message rbsp payload
+func (s *PicTimingAVCSEI) Size() uint {
+	return uint(len(s.payload))
+}
+
+// String - print delays, pic_struct and clock timestamps that are present
+func (s *PicTimingAVCSEI) String() string {
+	msg := fmt.Sprintf("SEI type %d PicTiming:", s.Type())
+	if s.CpbDpbDelaysPresent {
+		msg += fmt.Sprintf(" cpbRemovalDelay=%d, dpbOutputDelay=%d", s.CpbRemovalDelay, s.DpbOutputDelay)
+	}
+	if s.PicStructPresent {
+		if s.CpbDpbDelaysPresent {
+			msg += ","
+		}
+		msg += fmt.Sprintf(" picStruct=%d", s.PicStruct)
+		var times []string
+		for _, c := range s.ClockTimestamps {
+			if c.ClockTimestampFlag {
+				times = append(times, c.String())
+			}
+		}
+		if len(times) > 0 {
+			msg += fmt.Sprintf(", time=%s", strings.Join(times, ", "))
+		}
+	}
+	return msg
+}
+
+// Payload - SEI raw rbsp payload
+func (s *PicTimingAVCSEI) Payload() []byte {
+	return s.payload
+}
@@ -0,0 +1,7 @@
+/*
+Package sei - parse SEI (Supplementary Enhancement Information) messages for AVC (H.264) and HEVC (H.265).
+
+The SEI message syntax is shared between the two codecs, but some payload types are codec-specific,
+so DecodeSEIMessage takes the codec as a parameter.
+*/
+package sei
@@ -0,0 +1,128 @@
+package sei
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/jaypadia-frame/mp4ff/bits"
+)
+
+// FilmGrainCharacteristicsSEI - film_grain_characteristics SEI message (type 19)
+// ISO/IEC 14496-10 Section D.1.21 and ISO/IEC 23008-2 Section D.2.21
+type FilmGrainCharacteristicsSEI struct {
+	payload                              []byte
+	codec                                string
+	CancelFlag                           bool
+	ModelID                              byte
+	SeparateColourDescriptionPresentFlag bool
+	BitDepthLumaMinus8                   byte
+	BitDepthChromaMinus8                 byte
+	FullRangeFlag                        bool
+	ColourPrimaries                      byte
+	TransferCharacteristics              byte
+	MatrixCoefficients                   byte
+	BlendingModeID                       byte
+	Log2ScaleFactor                      byte
+	// CompModels has one entry per colour component. Nil if comp_model_present_flag is 0
+	CompModels       [3]*FilmGrainCompModel
+	RepetitionPeriod uint // AVC only
+	PersistenceFlag  bool // HEVC only
+}
+
+// FilmGrainCompModel - film grain model for one colour component
+type FilmGrainCompModel struct {
+	NumModelValuesMinus1 byte
+	Intervals            []FilmGrainIntensityInterval
+}
+
+// FilmGrainIntensityInterval - intensity interval with model values
+type FilmGrainIntensityInterval struct {
+	LowerBound  byte
+	UpperBound  byte
+	ModelValues []int
+}
+
+// DecodeFilmGrainCharacteristicsSEI - decode film grain characteristics SEI message
+func DecodeFilmGrainCharacteristicsSEI(sd *SEIData, codec string) (SEIMessage, error) {
+	r := bits.NewAccErrReader(bytes.NewBuffer(sd.payload))
+	fg := &FilmGrainCharacteristicsSEI{payload: sd.payload, codec: codec}
+	fg.CancelFlag = r.ReadFlag()
+	if !fg.CancelFlag {
+		fg.ModelID = byte(r.Read(2))
+		fg.SeparateColourDescriptionPresentFlag = r.ReadFlag()
+		if fg.SeparateColourDescriptionPresentFlag {
+			fg.BitDepthLumaMinus8 = byte(r.Read(3))
+			fg.BitDepthChromaMinus8 = byte(r.Read(3))
+			fg.FullRangeFlag = r.ReadFlag()
+			fg.ColourPrimaries = byte(r.Read(8))
+			fg.TransferCharacteristics = byte(r.Read(8))
+			fg.MatrixCoefficients = byte(r.Read(8))
+		}
+		fg.BlendingModeID = byte(r.Read(2))
+		fg.Log2ScaleFactor = byte(r.Read(4))
+		for c := 0; c < 3; c++ {
+			if r.ReadFlag() {
+				fg.CompModels[c] = &FilmGrainCompModel{}
+			}
+		}
+		for _, cm := range fg.CompModels {
+			if cm == nil {
+				continue
+			}
+			nrIntervals := int(r.Read(8)) + 1
+			cm.NumModelValuesMinus1 = byte(r.Read(3))
+			for i := 0; i < nrIntervals; i++ {
+				iv := FilmGrainIntensityInterval{
+					LowerBound: byte(r.Read(8)),
+					UpperBound: byte(r.Read(8)),
+				}
+				for j := 0; j <= int(cm.NumModelValuesMinus1); j++ {
+					iv.ModelValues = append(iv.ModelValues, r.ReadSignedGolomb())
+				}
+				if r.AccError() != nil {
+					return nil, fmt.Errorf("film_grain_characteristics: %w", r.AccError())
+				}
+				cm.Intervals = append(cm.Intervals, iv)
+			}
+		}
+		if codec == "avc" {
+			fg.RepetitionPeriod = r.ReadExpGolomb()
+		} else {
+			fg.PersistenceFlag = r.ReadFlag()
+		}
+	}
+	if r.AccError() != nil {
+		return nil, fmt.Errorf("film_grain_characteristics: %w", r.AccError())
+	}
+	return fg, nil
+}
+
+// Type - SEI payload type
+func (s *FilmGrainCharacteristicsSEI) Type() uint {
+	return SEIFilmGrainCharacteristicsType
+}
+
+// Size - size in bytes of raw SEI message rbsp payload
+func (s *FilmGrainCharacteristicsSEI) Size() uint {
+	return uint(len(s.payload))
+}
+
+// String - print model, blending mode and number of intervals per component
+func (s *FilmGrainCharacteristicsSEI) String() string {
+	if s.CancelFlag {
+		return fmt.Sprintf("SEI type %d FilmGrainCharacteristics: cancel", s.Type())
+	}
+	var nrIntervals [3]int
+	for c, cm := range s.CompModels {
+		if cm != nil {
+			nrIntervals[c] = len(cm.Intervals)
+		}
+	}
+	return fmt.Sprintf("SEI type %d FilmGrainCharacteristics: model=%d, blendingMode=%d, log2ScaleFactor=%d, intervals=%v",
+		s.Type(), s.ModelID, s.BlendingModeID, s.Log2ScaleFactor, nrIntervals)
+}
+
+// Payload - SEI raw rbsp payload
+func (s *FilmGrainCharacteristicsSEI) Payload() []byte {
+	return s.payload
+}
@@ -0,0 +1,99 @@
+package sei
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/jaypadia-frame/mp4ff/bits"
+)
+
+// FramePackingArrangementSEI - frame_packing_arrangement SEI message (type 45)
+// ISO/IEC 14496-10 Section D.1.26 and ISO/IEC 23008-2 Section D.2.16
+type FramePackingArrangementSEI struct {
+	payload                   []byte
+	codec                     string
+	ID                        uint
+	CancelFlag                bool
+	ArrangementType           byte
+	QuincunxSamplingFlag      bool
+	ContentInterpretationType byte
+	SpatialFlippingFlag       bool
+	Frame0FlippedFlag         bool
+	FieldViewsFlag            bool
+	CurrentFrameIsFrame0Flag  bool
+	Frame0SelfContainedFlag   bool
+	Frame1SelfContainedFlag   bool
+	Frame0GridPositionX       byte
+	Frame0GridPositionY       byte
+	Frame1GridPositionX       byte
+	Frame1GridPositionY       byte
+	ReservedByte              byte
+	RepetitionPeriod          uint // AVC only
+	PersistenceFlag           bool // HEVC only
+	ExtensionFlag             bool // AVC only
+	UpsampledAspectRatioFlag  bool // HEVC only
+}
+
+// DecodeFramePackingArrangementSEI - decode frame packing arrangement SEI message
+func DecodeFramePackingArrangementSEI(sd *SEIData, codec string) (SEIMessage, error) {
+	r := bits.NewAccErrReader(bytes.NewBuffer(sd.payload))
+	fp := &FramePackingArrangementSEI{payload: sd.payload, codec: codec}
+	fp.ID = r.ReadExpGolomb()
+	fp.CancelFlag = r.ReadFlag()
+	if !fp.CancelFlag {
+		fp.ArrangementType = byte(r.Read(7))
+		fp.QuincunxSamplingFlag = r.ReadFlag()
+		fp.ContentInterpretationType = byte(r.Read(6))
+		fp.SpatialFlippingFlag = r.ReadFlag()
+		fp.Frame0FlippedFlag = r.ReadFlag()
+		fp.FieldViewsFlag = r.ReadFlag()
+		fp.CurrentFrameIsFrame0Flag = r.ReadFlag()
+		fp.Frame0SelfContainedFlag = r.ReadFlag()
+		fp.Frame1SelfContainedFlag = r.ReadFlag()
+		if !fp.QuincunxSamplingFlag && fp.ArrangementType != 5 {
+			fp.Frame0GridPositionX = byte(r.Read(4))
+			fp.Frame0GridPositionY = byte(r.Read(4))
+			fp.Frame1GridPositionX = byte(r.Read(4))
+			fp.Frame1GridPositionY = byte(r.Read(4))
+		}
+		fp.ReservedByte = byte(r.Read(8))
+		if codec == "avc" {
+			fp.RepetitionPeriod = r.ReadExpGolomb()
+		} else {
+			fp.PersistenceFlag = r.ReadFlag()
+		}
+	}
+	if codec == "avc" {
+		fp.ExtensionFlag = r.ReadFlag()
+	} else {
+		fp.UpsampledAspectRatioFlag = r.ReadFlag()
+	}
+	if r.AccError() != nil {
+		return nil, fmt.Errorf("frame_packing_arrangement: %w", r.AccError())
+	}
+	return fp, nil
+}
+
+// Type - SEI payload type
+func (s *FramePackingArrangementSEI) Type() uint {
+	return SEIFramePackingArrangementType
+}
+
+// Size - size in bytes of raw SEI message rbsp payload
+func (s *FramePackingArrangementSEI) Size() uint {
+	return uint(len(s.payload))
+}
+
+// String - print id, arrangement type and content interpretation
+func (s *FramePackingArrangementSEI) String() string {
+	if s.CancelFlag {
+		return fmt.Sprintf("SEI type %d FramePackingArrangement: id=%d, cancel", s.Type(), s.ID)
+	}
+	return fmt.Sprintf("SEI type %d FramePackingArrangement: id=%d, type=%d, quincunx=%t, contentInterpretation=%d",
+		s.Type(), s.ID, s.ArrangementType, s.QuincunxSamplingFlag, s.ContentInterpretationType)
+}
+
+// Payload - SEI raw rbsp payload
+func (s *FramePackingArrangementSEI) Payload() []byte {
+	return s.payload
+}
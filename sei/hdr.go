@@ -0,0 +1,145 @@
+package sei
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// MasteringDisplayColourVolumeSEI - SMPTE ST 2086 mastering display colour volume (type 137)
+// Primaries and white point are in units of 0.00002, luminances in units of 0.0001 cd/m2.
+type MasteringDisplayColourVolumeSEI struct {
+	DisplayPrimariesX            [3]uint16
+	DisplayPrimariesY            [3]uint16
+	WhitePointX                  uint16
+	WhitePointY                  uint16
+	MaxDisplayMasteringLuminance uint32
+	MinDisplayMasteringLuminance uint32
+}
+
+// DecodeMasteringDisplayColourVolumeSEI - decode SEI message of type 137
+func DecodeMasteringDisplayColourVolumeSEI(sd *SEIData) (SEIMessage, error) {
+	if len(sd.payload) < 24 {
+		return nil, errTooShort(sd, 24)
+	}
+	p := sd.payload
+	m := &MasteringDisplayColourVolumeSEI{}
+	for i := 0; i < 3; i++ {
+		m.DisplayPrimariesX[i] = binary.BigEndian.Uint16(p[4*i:])
+		m.DisplayPrimariesY[i] = binary.BigEndian.Uint16(p[4*i+2:])
+	}
+	m.WhitePointX = binary.BigEndian.Uint16(p[12:])
+	m.WhitePointY = binary.BigEndian.Uint16(p[14:])
+	m.MaxDisplayMasteringLuminance = binary.BigEndian.Uint32(p[16:])
+	m.MinDisplayMasteringLuminance = binary.BigEndian.Uint32(p[20:])
+	return m, nil
+}
+
+// Type - SEI payload type
+func (m *MasteringDisplayColourVolumeSEI) Type() uint {
+	return SEIMasteringDisplayColourVolumeType
+}
+
+// Size - size in bytes of raw SEI message rbsp payload
+func (m *MasteringDisplayColourVolumeSEI) Size() uint {
+	return 24
+}
+
+// String - print values with primaries in x265 master-display order G, B, R
+func (m *MasteringDisplayColourVolumeSEI) String() string {
+	return fmt.Sprintf("SEI type %d MasteringDisplayColourVolume: G(%d,%d)B(%d,%d)R(%d,%d)WP(%d,%d)L(%d,%d)",
+		m.Type(), m.DisplayPrimariesX[0], m.DisplayPrimariesY[0], m.DisplayPrimariesX[1], m.DisplayPrimariesY[1],
+		m.DisplayPrimariesX[2], m.DisplayPrimariesY[2], m.WhitePointX, m.WhitePointY,
+		m.MaxDisplayMasteringLuminance, m.MinDisplayMasteringLuminance)
+}
+
+// Payload - SEI raw rbsp payload
+func (m *MasteringDisplayColourVolumeSEI) Payload() []byte {
+	p := make([]byte, 24)
+	for i := 0; i < 3; i++ {
+		binary.BigEndian.PutUint16(p[4*i:], m.DisplayPrimariesX[i])
+		binary.BigEndian.PutUint16(p[4*i+2:], m.DisplayPrimariesY[i])
+	}
+	binary.BigEndian.PutUint16(p[12:], m.WhitePointX)
+	binary.BigEndian.PutUint16(p[14:], m.WhitePointY)
+	binary.BigEndian.PutUint32(p[16:], m.MaxDisplayMasteringLuminance)
+	binary.BigEndian.PutUint32(p[20:], m.MinDisplayMasteringLuminance)
+	return p
+}
+
+// ContentLightLevelInformationSEI - content light level info (type 144)
+type ContentLightLevelInformationSEI struct {
+	MaxContentLightLevel    uint16
+	MaxPicAverageLightLevel uint16
+}
+
+// DecodeContentLightLevelInformationSEI - decode SEI message of type 144
+func DecodeContentLightLevelInformationSEI(sd *SEIData) (SEIMessage, error) {
+	if len(sd.payload) < 4 {
+		return nil, errTooShort(sd, 4)
+	}
+	return &ContentLightLevelInformationSEI{
+		MaxContentLightLevel:    binary.BigEndian.Uint16(sd.payload[0:2]),
+		MaxPicAverageLightLevel: binary.BigEndian.Uint16(sd.payload[2:4]),
+	}, nil
+}
+
+// Type - SEI payload type
+func (c *ContentLightLevelInformationSEI) Type() uint {
+	return SEIContentLightLevelInformationType
+}
+
+// Size - size in bytes of raw SEI message rbsp payload
+func (c *ContentLightLevelInformationSEI) Size() uint {
+	return 4
+}
+
+// String - print MaxCLL and MaxFALL
+func (c *ContentLightLevelInformationSEI) String() string {
+	return fmt.Sprintf("SEI type %d ContentLightLevelInformation: MaxCLL=%d, MaxFALL=%d",
+		c.Type(), c.MaxContentLightLevel, c.MaxPicAverageLightLevel)
+}
+
+// Payload - SEI raw rbsp payload
+func (c *ContentLightLevelInformationSEI) Payload() []byte {
+	p := make([]byte, 4)
+	binary.BigEndian.PutUint16(p[0:], c.MaxContentLightLevel)
+	binary.BigEndian.PutUint16(p[2:], c.MaxPicAverageLightLevel)
+	return p
+}
+
+// AlternativeTransferCharacteristicsSEI - alternative transfer characteristics (type 147)
+// Typically signals HLG (18) when VUI signals BT.2020 or BT.709 transfer.
+type AlternativeTransferCharacteristicsSEI struct {
+	PreferredTransferCharacteristics byte
+}
+
+// DecodeAlternativeTransferCharacteristicsSEI - decode SEI message of type 147
+func DecodeAlternativeTransferCharacteristicsSEI(sd *SEIData) (SEIMessage, error) {
+	if len(sd.payload) < 1 {
+		return nil, errTooShort(sd, 1)
+	}
+	return &AlternativeTransferCharacteristicsSEI{
+		PreferredTransferCharacteristics: sd.payload[0],
+	}, nil
+}
+
+// Type - SEI payload type
+func (a *AlternativeTransferCharacteristicsSEI) Type() uint {
+	return SEIAlternativeTransferCharacteristicsType
+}
+
+// Size - size in bytes of raw SEI message rbsp payload
+func (a *AlternativeTransferCharacteristicsSEI) Size() uint {
+	return 1
+}
+
+// String - print preferred transfer characteristics
+func (a *AlternativeTransferCharacteristicsSEI) String() string {
+	return fmt.Sprintf("SEI type %d AlternativeTransferCharacteristics: %d",
+		a.Type(), a.PreferredTransferCharacteristics)
+}
+
+// Payload - SEI raw rbsp payload
+func (a *AlternativeTransferCharacteristicsSEI) Payload() []byte {
+	return []byte{a.PreferredTransferCharacteristics}
+}
@@ -0,0 +1,181 @@
+package sei
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/jaypadia-frame/mp4ff/bits"
+)
+
+// HDR10PlusSEI - SMPTE ST 2094-40 dynamic metadata (HDR10+) in registered SEI.
+// Syntax according to ST 2094-40 Annex and CTA-861-G Annex S.
+type HDR10PlusSEI struct {
+	payload                                  []byte
+	ApplicationIdentifier                    byte
+	ApplicationVersion                       byte
+	NumWindows                               byte
+	Windows                                  []HDR10PlusWindow // Windows 1 and up (window 0 is the full picture)
+	TargetedSystemDisplayMaximumLuminance    uint32
+	TargetedSystemDisplayActualPeakLuminance *HDR10PlusLuminanceMatrix
+	ProcessingWindows                        []HDR10PlusProcessing // One per window
+	MasteringDisplayActualPeakLuminance      *HDR10PlusLuminanceMatrix
+}
+
+// HDR10PlusWindow - geometry of a processing window other than the first
+type HDR10PlusWindow struct {
+	UpperLeftCornerX             uint16
+	UpperLeftCornerY             uint16
+	LowerRightCornerX            uint16
+	LowerRightCornerY            uint16
+	CenterOfEllipseX             uint16
+	CenterOfEllipseY             uint16
+	RotationAngle                byte
+	SemimajorAxisInternalEllipse uint16
+	SemimajorAxisExternalEllipse uint16
+	SemiminorAxisExternalEllipse uint16
+	OverlapProcessOption         bool
+}
+
+// HDR10PlusLuminanceMatrix - actual peak luminance values in rows and columns
+type HDR10PlusLuminanceMatrix struct {
+	NumRows byte
+	NumCols byte
+	Values  [][]byte
+}
+
+// HDR10PlusProcessing - scene statistics and tone mapping for one processing window
+type HDR10PlusProcessing struct {
+	MaxScl                     [3]uint32
+	AverageMaxRGB              uint32
+	DistributionMaxRGB         []HDR10PlusPercentile
+	FractionBrightPixels       uint16
+	ToneMappingFlag            bool
+	KneePointX                 uint16
+	KneePointY                 uint16
+	BezierCurveAnchors         []uint16
+	ColorSaturationMappingFlag bool
+	ColorSaturationWeight      byte
+}
+
+// HDR10PlusPercentile - one point of the maxRGB distribution
+type HDR10PlusPercentile struct {
+	Percentage byte
+	Percentile uint32
+}
+
+// DecodeHDR10PlusSEI - decode HDR10+ metadata from a registered SEI message payload
+func DecodeHDR10PlusSEI(sd *SEIData) (SEIMessage, error) {
+	if len(sd.payload) < 7 {
+		return nil, errTooShort(sd, 7)
+	}
+	h := &HDR10PlusSEI{payload: sd.payload}
+	// Skip country code, terminal provider code and terminal provider oriented code
+	r := bits.NewAccErrReader(bytes.NewBuffer(sd.payload[5:]))
+	h.ApplicationIdentifier = byte(r.Read(8))
+	h.ApplicationVersion = byte(r.Read(8))
+	h.NumWindows = byte(r.Read(2))
+	if h.NumWindows == 0 {
+		return nil, fmt.Errorf("HDR10+: num_windows is 0")
+	}
+	for w := byte(1); w < h.NumWindows; w++ {
+		win := HDR10PlusWindow{}
+		win.UpperLeftCornerX = uint16(r.Read(16))
+		win.UpperLeftCornerY = uint16(r.Read(16))
+		win.LowerRightCornerX = uint16(r.Read(16))
+		win.LowerRightCornerY = uint16(r.Read(16))
+		win.CenterOfEllipseX = uint16(r.Read(16))
+		win.CenterOfEllipseY = uint16(r.Read(16))
+		win.RotationAngle = byte(r.Read(8))
+		win.SemimajorAxisInternalEllipse = uint16(r.Read(16))
+		win.SemimajorAxisExternalEllipse = uint16(r.Read(16))
+		win.SemiminorAxisExternalEllipse = uint16(r.Read(16))
+		win.OverlapProcessOption = r.ReadFlag()
+		h.Windows = append(h.Windows, win)
+	}
+	h.TargetedSystemDisplayMaximumLuminance = uint32(r.Read(27))
+	if r.ReadFlag() {
+		h.TargetedSystemDisplayActualPeakLuminance = readLuminanceMatrix(r)
+	}
+	h.ProcessingWindows = make([]HDR10PlusProcessing, h.NumWindows)
+	for w := range h.ProcessingWindows {
+		p := &h.ProcessingWindows[w]
+		for i := 0; i < 3; i++ {
+			p.MaxScl[i] = uint32(r.Read(17))
+		}
+		p.AverageMaxRGB = uint32(r.Read(17))
+		numPercentiles := int(r.Read(4))
+		for i := 0; i < numPercentiles; i++ {
+			p.DistributionMaxRGB = append(p.DistributionMaxRGB, HDR10PlusPercentile{
+				Percentage: byte(r.Read(7)),
+				Percentile: uint32(r.Read(17)),
+			})
+		}
+		p.FractionBrightPixels = uint16(r.Read(10))
+	}
+	if r.ReadFlag() {
+		h.MasteringDisplayActualPeakLuminance = readLuminanceMatrix(r)
+	}
+	for w := range h.ProcessingWindows {
+		p := &h.ProcessingWindows[w]
+		p.ToneMappingFlag = r.ReadFlag()
+		if p.ToneMappingFlag {
+			p.KneePointX = uint16(r.Read(12))
+			p.KneePointY = uint16(r.Read(12))
+			numAnchors := int(r.Read(4))
+			for i := 0; i < numAnchors; i++ {
+				p.BezierCurveAnchors = append(p.BezierCurveAnchors, uint16(r.Read(10)))
+			}
+		}
+		p.ColorSaturationMappingFlag = r.ReadFlag()
+		if p.ColorSaturationMappingFlag {
+			p.ColorSaturationWeight = byte(r.Read(6))
+		}
+	}
+	if r.AccError() != nil {
+		return nil, fmt.Errorf("HDR10+: %w", r.AccError())
+	}
+	return h, nil
+}
+
+func readLuminanceMatrix(r *bits.AccErrReader) *HDR10PlusLuminanceMatrix {
+	m := &HDR10PlusLuminanceMatrix{}
+	m.NumRows = byte(r.Read(5))
+	m.NumCols = byte(r.Read(5))
+	m.Values = make([][]byte, m.NumRows)
+	for i := range m.Values {
+		m.Values[i] = make([]byte, m.NumCols)
+		for j := range m.Values[i] {
+			m.Values[i][j] = byte(r.Read(4))
+		}
+	}
+	return m
+}
+
+// Type - SEI payload type
+func (h *HDR10PlusSEI) Type() uint {
+	return SEIRegisteredType
+}
+
+// Size - size in bytes of raw SEI message rbsp payload
+func (h *HDR10PlusSEI) Size() uint {
+	return uint(len(h.payload))
+}
+
+// String - print main HDR10+ values for first window
+func (h *HDR10PlusSEI) String() string {
+	msg := fmt.Sprintf("SEI type %d HDR10+, size=%d, version=%d, windows=%d, targetMaxLum=%d",
+		h.Type(), h.Size(), h.ApplicationVersion, h.NumWindows, h.TargetedSystemDisplayMaximumLuminance)
+	if len(h.ProcessingWindows) > 0 {
+		p := h.ProcessingWindows[0]
+		msg += fmt.Sprintf(", maxscl=%v, avgMaxRGB=%d", p.MaxScl, p.AverageMaxRGB)
+		if p.ToneMappingFlag {
+			msg += fmt.Sprintf(", knee=(%d,%d), anchors=%v", p.KneePointX, p.KneePointY, p.BezierCurveAnchors)
+		}
+	}
+	return msg
+}
+
+// Payload - SEI raw rbsp payload
+func (h *HDR10PlusSEI) Payload() []byte {
+	return h.payload
+}
@@ -0,0 +1,111 @@
+package sei
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/jaypadia-frame/mp4ff/bits"
+	"github.com/jaypadia-frame/mp4ff/hevc"
+)
+
+// BufferingPeriodHEVCSEI - HEVC buffering_period SEI message (type 0) ISO/IEC 23008-2 Section D.2.2
+// The CPB specifications are those of the highest sub-layer in the SPS HRD parameters.
+type BufferingPeriodHEVCSEI struct {
+	payload                      []byte
+	SeqParameterSetID            uint
+	IrapCpbParamsPresentFlag     bool
+	CpbDelayOffset               uint32
+	DpbDelayOffset               uint32
+	ConcatenationFlag            bool
+	AuCpbRemovalDelayDeltaMinus1 uint32
+	NalInitialCpb                []InitialCpbRemoval
+	NalInitialAltCpb             []InitialCpbRemoval
+	VclInitialCpb                []InitialCpbRemoval
+	VclInitialAltCpb             []InitialCpbRemoval
+}
+
+// DecodeBufferingPeriodHEVCSEI - decode HEVC buffering period SEI using HRD parameters from SPS
+func DecodeBufferingPeriodHEVCSEI(sd *SEIData, sps *hevc.SPS) (SEIMessage, error) {
+	r := bits.NewAccErrReader(bytes.NewBuffer(sd.payload))
+	bp := &BufferingPeriodHEVCSEI{payload: sd.payload}
+	bp.SeqParameterSetID = r.ReadExpGolomb()
+	if r.AccError() != nil {
+		return nil, fmt.Errorf("buffering_period: %w", r.AccError())
+	}
+	if bp.SeqParameterSetID != uint(sps.SpsID) {
+		return nil, fmt.Errorf("buffering_period: sps id %d does not match %d", bp.SeqParameterSetID, sps.SpsID)
+	}
+	if sps.VUI == nil || !sps.VUI.HrdParametersPresentFlag || len(sps.VUI.HrdParameters.SubLayers) == 0 {
+		return bp, nil
+	}
+	hrd := sps.VUI.HrdParameters
+	if !hrd.SubPicHrdParamsPresentFlag {
+		bp.IrapCpbParamsPresentFlag = r.ReadFlag()
+	}
+	if bp.IrapCpbParamsPresentFlag {
+		bp.CpbDelayOffset = uint32(r.Read(int(hrd.AuCpbRemovalDelayLengthMinus1 + 1)))
+		bp.DpbDelayOffset = uint32(r.Read(int(hrd.DpbOutputDelayLengthMinus1 + 1)))
+	}
+	bp.ConcatenationFlag = r.ReadFlag()
+	bp.AuCpbRemovalDelayDeltaMinus1 = uint32(r.Read(int(hrd.AuCpbRemovalDelayLengthMinus1 + 1)))
+	withAlt := hrd.SubPicHrdParamsPresentFlag || bp.IrapCpbParamsPresentFlag
+	if hrd.NalHrdParametersPresentFlag {
+		bp.NalInitialCpb, bp.NalInitialAltCpb = readHEVCInitialCpbRemovals(r, hrd, withAlt)
+	}
+	if hrd.VclHrdParametersPresentFlag {
+		bp.VclInitialCpb, bp.VclInitialAltCpb = readHEVCInitialCpbRemovals(r, hrd, withAlt)
+	}
+	if r.AccError() != nil {
+		return nil, fmt.Errorf("buffering_period: %w", r.AccError())
+	}
+	return bp, nil
+}
+
+func readHEVCInitialCpbRemovals(r *bits.AccErrReader, hrd *hevc.HrdParameters,
+	withAlt bool) (icr, alt []InitialCpbRemoval) {
+	nrBits := int(hrd.InitialCpbRemovalDelayLengthMinus1 + 1)
+	nrCpbs := int(hrd.SubLayers[len(hrd.SubLayers)-1].CpbCntMinus1) + 1
+	icr = make([]InitialCpbRemoval, nrCpbs)
+	if withAlt {
+		alt = make([]InitialCpbRemoval, nrCpbs)
+	}
+	for i := range icr {
+		icr[i].Delay = uint32(r.Read(nrBits))
+		icr[i].Offset = uint32(r.Read(nrBits))
+		if withAlt {
+			alt[i].Delay = uint32(r.Read(nrBits))
+			alt[i].Offset = uint32(r.Read(nrBits))
+		}
+	}
+	return icr, alt
+}
+
+// Type - SEI payload type
+func (s *BufferingPeriodHEVCSEI) Type() uint {
+	return SEIBufferingPeriodType
+}
+
+// Size - size in bytes of raw SEI message rbsp payload
+func (s *BufferingPeriodHEVCSEI) Size() uint {
+	return uint(len(s.payload))
+}
+
+// String - print sps id and initial cpb removal delays and offsets
+func (s *BufferingPeriodHEVCSEI) String() string {
+	msg := fmt.Sprintf("SEI type %d BufferingPeriod: spsID=%d", s.Type(), s.SeqParameterSetID)
+	if s.IrapCpbParamsPresentFlag {
+		msg += fmt.Sprintf(", cpbDelayOffset=%d, dpbDelayOffset=%d", s.CpbDelayOffset, s.DpbDelayOffset)
+	}
+	if len(s.NalInitialCpb) > 0 {
+		msg += fmt.Sprintf(", nal=%v", s.NalInitialCpb)
+	}
+	if len(s.VclInitialCpb) > 0 {
+		msg += fmt.Sprintf(", vcl=%v", s.VclInitialCpb)
+	}
+	return msg
+}
+
+// Payload - SEI raw rbsp payload
+func (s *BufferingPeriodHEVCSEI) Payload() []byte {
+	return s.payload
+}
@@ -0,0 +1,87 @@
+package sei
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Hash types in decoded picture hash SEI
+const (
+	HashTypeMD5      = 0
+	HashTypeCRC      = 1
+	HashTypeChecksum = 2
+)
+
+// DecodedPictureHashSEI - HEVC decoded picture hash (type 132) ISO/IEC 23008-2 Section D.2.19.
+// There is one hash per colour component. The number of components is derived from
+// the payload size since chroma_format_idc is not known here.
+type DecodedPictureHashSEI struct {
+	payload  []byte
+	HashType byte
+	Hashes   [][]byte
+}
+
+// DecodeDecodedPictureHashSEI - decode HEVC decoded picture hash SEI message
+func DecodeDecodedPictureHashSEI(sd *SEIData) (SEIMessage, error) {
+	if len(sd.payload) < 1 {
+		return nil, errTooShort(sd, 1)
+	}
+	h := &DecodedPictureHashSEI{payload: sd.payload, HashType: sd.payload[0]}
+	var hashSize int
+	switch h.HashType {
+	case HashTypeMD5:
+		hashSize = 16
+	case HashTypeCRC:
+		hashSize = 2
+	case HashTypeChecksum:
+		hashSize = 4
+	default:
+		return nil, fmt.Errorf("decoded picture hash: unknown hash_type %d", h.HashType)
+	}
+	data := sd.payload[1:]
+	nrComponents := len(data) / hashSize
+	if nrComponents != 1 && nrComponents != 3 {
+		return nil, fmt.Errorf("decoded picture hash: bad payload size %d for hash_type %d", len(sd.payload), h.HashType)
+	}
+	for i := 0; i < nrComponents; i++ {
+		h.Hashes = append(h.Hashes, data[i*hashSize:(i+1)*hashSize])
+	}
+	return h, nil
+}
+
+// HashTypeName - MD5, CRC or Checksum
+func (h *DecodedPictureHashSEI) HashTypeName() string {
+	switch h.HashType {
+	case HashTypeMD5:
+		return "MD5"
+	case HashTypeCRC:
+		return "CRC"
+	default:
+		return "Checksum"
+	}
+}
+
+// Type - SEI payload type
+func (h *DecodedPictureHashSEI) Type() uint {
+	return SEIDecodedPictureHashType
+}
+
+// Size - size in bytes of raw SEI message rbsp payload
+func (h *DecodedPictureHashSEI) Size() uint {
+	return uint(len(h.payload))
+}
+
+// String - print hash type and hashes in hex
+func (h *DecodedPictureHashSEI) String() string {
+	hashes := make([]string, 0, len(h.Hashes))
+	for _, hash := range h.Hashes {
+		hashes = append(hashes, hex.EncodeToString(hash))
+	}
+	return fmt.Sprintf("SEI type %d DecodedPictureHash %s: %s", h.Type(), h.HashTypeName(), strings.Join(hashes, " "))
+}
+
+// Payload - SEI raw rbsp payload
+func (h *DecodedPictureHashSEI) Payload() []byte {
+	return h.payload
+}
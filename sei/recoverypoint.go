@@ -0,0 +1,65 @@
+package sei
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/jaypadia-frame/mp4ff/bits"
+)
+
+// RecoveryPointSEI - recovery_point SEI message (type 6)
+// ISO/IEC 14496-10 Section D.1.8 and ISO/IEC 23008-2 Section D.2.8
+type RecoveryPointSEI struct {
+	payload []byte
+	codec   string
+	// RecoveryCnt is recovery_frame_cnt for AVC and recovery_poc_cnt for HEVC
+	RecoveryCnt           int
+	ExactMatchFlag        bool
+	BrokenLinkFlag        bool
+	ChangingSliceGroupIdc byte // AVC only
+}
+
+// DecodeRecoveryPointSEI - decode recovery point SEI message
+func DecodeRecoveryPointSEI(sd *SEIData, codec string) (SEIMessage, error) {
+	r := bits.NewAccErrReader(bytes.NewBuffer(sd.payload))
+	rp := &RecoveryPointSEI{payload: sd.payload, codec: codec}
+	if codec == "avc" {
+		rp.RecoveryCnt = int(r.ReadExpGolomb())
+	} else {
+		rp.RecoveryCnt = r.ReadSignedGolomb()
+	}
+	rp.ExactMatchFlag = r.ReadFlag()
+	rp.BrokenLinkFlag = r.ReadFlag()
+	if codec == "avc" {
+		rp.ChangingSliceGroupIdc = byte(r.Read(2))
+	}
+	if r.AccError() != nil {
+		return nil, fmt.Errorf("recovery_point: %w", r.AccError())
+	}
+	return rp, nil
+}
+
+// Type - SEI payload type
+func (s *RecoveryPointSEI) Type() uint {
+	return SEIRecoveryPointType
+}
+
+// Size - size in bytes of raw SEI message rbsp payload
+func (s *RecoveryPointSEI) Size() uint {
+	return uint(len(s.payload))
+}
+
+// String - print recovery count and flags
+func (s *RecoveryPointSEI) String() string {
+	cntName := "recoveryFrameCnt"
+	if s.codec == "hevc" {
+		cntName = "recoveryPocCnt"
+	}
+	return fmt.Sprintf("SEI type %d RecoveryPoint: %s=%d, exactMatch=%t, brokenLink=%t",
+		s.Type(), cntName, s.RecoveryCnt, s.ExactMatchFlag, s.BrokenLinkFlag)
+}
+
+// Payload - SEI raw rbsp payload
+func (s *RecoveryPointSEI) Payload() []byte {
+	return s.payload
+}
@@ -0,0 +1,219 @@
+package sei
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+)
+
+// ITU-T T.35 codes used to identify registered SEI payloads
+const (
+	T35CountryCodeUS        = 0xb5
+	T35ProviderCodeATSC     = 0x0031
+	T35ProviderCodeSamsung  = 0x003c
+	ATSCUserIdentifierGA94  = 0x47413934
+	ATSCUserDataTypeCCData  = 0x03
+	HDR10PlusProviderOrient = 0x0001
+	HDR10PlusApplicationID  = 4
+)
+
+// ITUData - first 8 bytes of payload for CEA-608 in type 4 (User data registered by ITU-T Rec T 35)
+type ITUData struct {
+	CountryCode      byte
+	UserDataTypeCode byte
+	ProviderCode     uint16
+	UserIdentifier   uint32
+}
+
+// IsCEA608 - check if ITU-T data corresponds to CEA-608/708 cc_data (ATSC A/53 GA94)
+func (i ITUData) IsCEA608() bool {
+	return (i.CountryCode == T35CountryCodeUS &&
+		i.ProviderCode == T35ProviderCodeATSC &&
+		i.UserIdentifier == ATSCUserIdentifierGA94 &&
+		i.UserDataTypeCode == ATSCUserDataTypeCCData)
+}
+
+// DecodeUserDataRegisteredSEI - decode a SEI message of type 4 (user_data_registered_itu_t_t35)
+func DecodeUserDataRegisteredSEI(sd *SEIData) (SEIMessage, error) {
+	p := sd.payload
+	if len(p) < 3 {
+		return nil, errTooShort(sd, 3)
+	}
+	ituData := ITUData{
+		CountryCode:  p[0],
+		ProviderCode: binary.BigEndian.Uint16(p[1:3]),
+	}
+	if ituData.CountryCode == 0xff { // itu_t_t35_country_code_extension_byte present
+		return NewRegisteredSEI(sd, ituData), nil
+	}
+	switch {
+	case ituData.CountryCode == T35CountryCodeUS && ituData.ProviderCode == T35ProviderCodeATSC && len(p) >= 8:
+		ituData.UserIdentifier = binary.BigEndian.Uint32(p[3:7])
+		ituData.UserDataTypeCode = p[7]
+		if ituData.IsCEA608() {
+			return NewCEA708SEI(sd)
+		}
+	case ituData.CountryCode == T35CountryCodeUS && ituData.ProviderCode == T35ProviderCodeSamsung && len(p) >= 6:
+		if binary.BigEndian.Uint16(p[3:5]) == HDR10PlusProviderOrient && p[5] == HDR10PlusApplicationID {
+			return DecodeHDR10PlusSEI(sd)
+		}
+	}
+	return NewRegisteredSEI(sd, ituData), nil
+}
+
+// RegisteredSEI - user_data_registered_itu_t_t35 SEI message without specific parsing
+type RegisteredSEI struct {
+	payload  []byte
+	ITUTData ITUData
+}
+
+// NewRegisteredSEI - create an ITU-T registered SEI message (type 4)
+func NewRegisteredSEI(sd *SEIData, ituData ITUData) *RegisteredSEI {
+	return &RegisteredSEI{
+		payload:  sd.payload,
+		ITUTData: ituData,
+	}
+}
+
+// Type - SEI payload type
+func (s *RegisteredSEI) Type() uint {
+	return SEIRegisteredType
+}
+
+// Size - size in bytes of raw SEI message rbsp payload
+func (s *RegisteredSEI) Size() uint {
+	return uint(len(s.payload))
+}
+
+func (s *RegisteredSEI) String() string {
+	return fmt.Sprintf("SEI type %d, size=%d, %+v", s.Type(), s.Size(), s.ITUTData)
+}
+
+// Payload - SEI raw rbsp payload
+func (s *RegisteredSEI) Payload() []byte {
+	return s.payload
+}
+
+// maxCCCount - maximum number of triplets given by the 5-bit cc_count
+const maxCCCount = 31
+
+// CCData - one cc_data triplet as defined in CEA-708 Section 4.4
+type CCData struct {
+	Valid bool
+	Type  byte // 0 and 1 are CEA-608 field 1 and 2, 2 and 3 are DTVCC data and start
+	Data1 byte
+	Data2 byte
+}
+
+// CEA708SEI - ATSC A/53 cc_data carried in registered SEI.
+// Field1 and Field2 contain the valid non-empty CEA-608 byte pairs (with parity bits),
+// and DTVCCData the CEA-708 DTVCC channel packet bytes.
+type CEA708SEI struct {
+	payload   []byte // full raw payload
+	CCData    []CCData
+	Field1    []byte
+	Field2    []byte
+	DTVCCData []byte
+}
+
+// NewCEA708SEI - new CEA-608/708 SEI message including parsing of cc_data
+func NewCEA708SEI(sd *SEIData) (*CEA708SEI, error) {
+	ccData, err := parseCCData(sd.payload[8:])
+	if err != nil {
+		return nil, err
+	}
+	s := &CEA708SEI{
+		payload: sd.payload,
+		CCData:  ccData,
+	}
+	for _, cc := range ccData {
+		if !cc.Valid {
+			continue
+		}
+		switch cc.Type {
+		case 0, 1:
+			if (cc.Data1&0x7f)+(cc.Data2&0x7f) == 0 {
+				continue // Empty padding
+			}
+			if cc.Type == 0 {
+				s.Field1 = append(s.Field1, cc.Data1, cc.Data2)
+			} else {
+				s.Field2 = append(s.Field2, cc.Data1, cc.Data2)
+			}
+		default:
+			s.DTVCCData = append(s.DTVCCData, cc.Data1, cc.Data2)
+		}
+	}
+	return s, nil
+}
+
+// CreateCEA708SEI - create ATSC A/53 cc_data SEI message with at most 31 cc_data triplets
+func CreateCEA708SEI(ccData []CCData) (*CEA708SEI, error) {
+	if len(ccData) > maxCCCount {
+		return nil, fmt.Errorf("%d cc_data triplets is more than %d", len(ccData), maxCCCount)
+	}
+	payload := make([]byte, 8, 8+2+3*len(ccData)+1)
+	payload[0] = T35CountryCodeUS
+	binary.BigEndian.PutUint16(payload[1:3], T35ProviderCodeATSC)
+	binary.BigEndian.PutUint32(payload[3:7], ATSCUserIdentifierGA94)
+	payload[7] = ATSCUserDataTypeCCData
+	// reserved=1, process_cc_data_flag=1, additional_data_flag=0, cc_count, and em_data=0xff
+	payload = append(payload, 0xc0|byte(len(ccData)), 0xff)
+	for _, cc := range ccData {
+		b := 0xf8 | cc.Type&0x03 // marker_bits=11111
+		if cc.Valid {
+			b |= 0x04
+		}
+		payload = append(payload, b, cc.Data1, cc.Data2)
+	}
+	payload = append(payload, 0xff) // marker_bits
+	return NewCEA708SEI(NewSEIData(SEIRegisteredType, payload))
+}
+
+// Type - SEI payload type
+func (s *CEA708SEI) Type() uint {
+	return SEIRegisteredType
+}
+
+// Size - size in bytes of raw SEI message rbsp payload
+func (s *CEA708SEI) Size() uint {
+	return uint(len(s.payload))
+}
+
+func (s *CEA708SEI) String() string {
+	msg := fmt.Sprintf("SEI type %d CEA-608, size=%d, field1: %q, field2: %q", s.Type(), s.Size(),
+		hex.EncodeToString(s.Field1), hex.EncodeToString(s.Field2))
+	if len(s.DTVCCData) > 0 {
+		msg += fmt.Sprintf(", dtvcc: %q", hex.EncodeToString(s.DTVCCData))
+	}
+	return msg
+}
+
+// Payload - SEI raw rbsp payload
+func (s *CEA708SEI) Payload() []byte {
+	return s.payload
+}
+
+// parseCCData - parse cc_data() starting after user_data_type_code
+func parseCCData(data []byte) ([]CCData, error) {
+	if len(data) < 2 {
+		return nil, fmt.Errorf("not enough data for cc_data header")
+	}
+	ccCount := int(data[0] & 0x1f)
+	pos := 2 // Advance 1 and skip em_data byte
+	if len(data) < pos+3*ccCount {
+		return nil, fmt.Errorf("not enough data for %d cc_data triplets", ccCount)
+	}
+	ccData := make([]CCData, 0, ccCount)
+	for i := 0; i < ccCount; i++ {
+		b := data[pos]
+		ccData = append(ccData, CCData{
+			Valid: b&0x04 != 0,
+			Type:  b & 0x03,
+			Data1: data[pos+1], // Keep parity bit
+			Data2: data[pos+2], // Keep parity bit
+		})
+		pos += 3
+	}
+	return ccData, nil
+}
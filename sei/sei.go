@@ -0,0 +1,209 @@
+package sei
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+
+	"github.com/jaypadia-frame/mp4ff/avc"
+	"github.com/jaypadia-frame/mp4ff/bits"
+	"github.com/jaypadia-frame/mp4ff/hevc"
+)
+
+// SEI payload types as defined in ISO/IEC 14496-10 Annex D and ISO/IEC 23008-2 Annex D
+const (
+	SEIBufferingPeriodType                    = 0
+	SEIPicTimingType                          = 1
+	SEIRegisteredType                         = 4
+	SEIUnregisteredType                       = 5
+	SEIRecoveryPointType                      = 6
+	SEIFilmGrainCharacteristicsType           = 19
+	SEIFramePackingArrangementType            = 45
+	SEIDecodedPictureHashType                 = 132
+	SEITimeCodeType                           = 136
+	SEIMasteringDisplayColourVolumeType       = 137
+	SEIContentLightLevelInformationType       = 144
+	SEIAlternativeTransferCharacteristicsType = 147
+)
+
+// SEIMessage is common part of any SEI message
+type SEIMessage interface {
+	Type() uint
+	Size() uint
+	String() string
+	Payload() []byte
+}
+
+// DecodeSEIMessage decodes an SEIMessage for a specific codec ("avc" or "hevc").
+// Payload types without a typed decoder are returned as SEIData.
+func DecodeSEIMessage(sd *SEIData, codec string) (SEIMessage, error) {
+	switch sd.Type() {
+	case SEIRegisteredType:
+		return DecodeUserDataRegisteredSEI(sd)
+	case SEIUnregisteredType:
+		return DecodeUserDataUnregisteredSEI(sd)
+	case SEIMasteringDisplayColourVolumeType:
+		return DecodeMasteringDisplayColourVolumeSEI(sd)
+	case SEIContentLightLevelInformationType:
+		return DecodeContentLightLevelInformationSEI(sd)
+	case SEIAlternativeTransferCharacteristicsType:
+		return DecodeAlternativeTransferCharacteristicsSEI(sd)
+	case SEIRecoveryPointType:
+		return DecodeRecoveryPointSEI(sd, codec)
+	case SEIFilmGrainCharacteristicsType:
+		return DecodeFilmGrainCharacteristicsSEI(sd, codec)
+	case SEIFramePackingArrangementType:
+		return DecodeFramePackingArrangementSEI(sd, codec)
+	}
+	if codec == "hevc" {
+		switch sd.Type() {
+		case SEIDecodedPictureHashType:
+			return DecodeDecodedPictureHashSEI(sd)
+		case SEITimeCodeType:
+			return DecodeTimeCodeSEI(sd)
+		}
+	}
+	return sd, nil
+}
+
+// DecodeAVCSEIMessage decodes an AVC SEIMessage using the active SPS.
+// The SPS is needed for buffering_period and pic_timing, which are returned as SEIData if sps is nil.
+func DecodeAVCSEIMessage(sd *SEIData, sps *avc.SPS) (SEIMessage, error) {
+	if sps != nil {
+		switch sd.Type() {
+		case SEIBufferingPeriodType:
+			return DecodeBufferingPeriodAVCSEI(sd, sps)
+		case SEIPicTimingType:
+			return DecodePicTimingAVCSEI(sd, sps)
+		}
+	}
+	return DecodeSEIMessage(sd, "avc")
+}
+
+// DecodeHEVCSEIMessage decodes an HEVC SEIMessage using the active SPS.
+// The SPS is needed for buffering_period, which is returned as SEIData if sps is nil.
+func DecodeHEVCSEIMessage(sd *SEIData, sps *hevc.SPS) (SEIMessage, error) {
+	if sps != nil && sd.Type() == SEIBufferingPeriodType {
+		return DecodeBufferingPeriodHEVCSEI(sd, sps)
+	}
+	return DecodeSEIMessage(sd, "hevc")
+}
+
+// SEIData - raw parsed SEI message with rbsp data
+type SEIData struct {
+	payloadType uint
+	payload     []byte
+}
+
+// NewSEIData - create raw SEI message from type and rbsp payload
+func NewSEIData(payloadType uint, payload []byte) *SEIData {
+	return &SEIData{payloadType, payload}
+}
+
+// Type - SEI payload type
+func (s *SEIData) Type() uint {
+	return s.payloadType
+}
+
+// Payload - SEI raw rbsp payload
+func (s *SEIData) Payload() []byte {
+	return s.payload
+}
+
+// String - print type, size and payload in hex
+func (s *SEIData) String() string {
+	return fmt.Sprintf("SEI type %d, size=%d, %q", s.Type(), s.Size(), hex.EncodeToString(s.payload))
+}
+
+// Size - size in bytes of raw SEI message rbsp payload
+func (s *SEIData) Size() uint {
+	return uint(len(s.payload))
+}
+
+// ExtractSEIData - parse ebsp after NAL unit header and return SEIData in rbsp format.
+// The NAL unit header is 1 byte for AVC and 2 bytes for HEVC.
+func ExtractSEIData(r io.ReadSeeker) (seiData []SEIData, err error) {
+	ar := bits.NewAccErrEBSPReader(r)
+	for {
+		payloadType := uint(0)
+		for {
+			nextByte := ar.Read(8)
+			payloadType += uint(nextByte)
+			if nextByte != 0xff {
+				break
+			}
+		}
+		payloadSize := uint32(0)
+		for {
+			nextByte := ar.Read(8)
+			payloadSize += uint32(nextByte)
+			if nextByte != 0xff {
+				break
+			}
+		}
+		payload := ar.ReadBytes(int(payloadSize))
+		if ar.AccError() != nil {
+			return nil, ar.AccError()
+		}
+
+		seiData = append(seiData, SEIData{payloadType, payload})
+		// Break loop if no more rbsp data (end of sei messages)
+		more, err := ar.MoreRbspData()
+		if err != nil {
+			return nil, err
+		}
+		if ar.AccError() != nil {
+			return nil, ar.AccError()
+		}
+		if !more {
+			break
+		}
+	}
+	return seiData, nil
+}
+
+// CreateSEINALU - create an SEI NAL unit with messages in ebsp format including the NAL unit header.
+// For HEVC, a prefix SEI NAL unit with nuh_layer_id 0 and nuh_temporal_id_plus1 1 is created.
+func CreateSEINALU(codec string, msgs []SEIMessage) ([]byte, error) {
+	if len(msgs) == 0 {
+		return nil, fmt.Errorf("no SEI messages")
+	}
+	buf := bytes.Buffer{}
+	w := bits.NewEBSPWriter(&buf)
+	switch codec {
+	case "avc":
+		w.Write(uint(avc.NALU_SEI), 8)
+	case "hevc":
+		w.Write(uint(hevc.NALU_SEI_PREFIX)<<9|1, 16)
+	default:
+		return nil, fmt.Errorf("unknown codec %q", codec)
+	}
+	for _, msg := range msgs {
+		writeSEIValue(w, msg.Type())
+		payload := msg.Payload()
+		writeSEIValue(w, uint(len(payload)))
+		for _, b := range payload {
+			w.Write(uint(b), 8)
+		}
+	}
+	w.WriteRbspTrailingBits()
+	if w.AccError() != nil {
+		return nil, w.AccError()
+	}
+	return buf.Bytes(), nil
+}
+
+// writeSEIValue - write payload type or size as a sequence of 0xff bytes and a last byte
+func writeSEIValue(w *bits.EBSPWriter, value uint) {
+	for value >= 0xff {
+		w.Write(0xff, 8)
+		value -= 0xff
+	}
+	w.Write(value, 8)
+}
+
+// errTooShort - error for payload too short for the given SEI type
+func errTooShort(sd *SEIData, minSize int) error {
+	return fmt.Errorf("SEI type %d: payload size %d less than %d", sd.Type(), sd.Size(), minSize)
+}
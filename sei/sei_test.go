@@ -0,0 +1,445 @@
+package sei
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/jaypadia-frame/mp4ff/avc"
+	"github.com/jaypadia-frame/mp4ff/bits"
+	"github.com/jaypadia-frame/mp4ff/hevc"
+)
+
+const (
+	avcSEI0Hex      = "060007810f1c0050744080"
+	avcSEI4Hex      = "660434b500314741393403cefffc9420fc94aefc9162fce56efc67bafc91b9fcb0b0fcbab0fcb0bafcb031fcbab0fcb080fc942cfc942f80"
+	hevcSEICLLHex   = "4e01900403e8019080"
+	hevcSEIMDCVHex  = "4e01891833c286c41d4c0bb884d03e803d13404200989680000003000180"
+	hevcSEIATCHex   = "4e0193011280"
+	hevcSEIHashHex  = "50018431009cf45826b3336eaa1f0339bb99ee3462b3b01379ba08916ef6b1b35f7d9ad51cb3b01379ba08916ef6b1b35f7d9ad51c80"
+	hevcSEI4Hex     = "4e010434b500314741393403cefffc9420fc94aefc9162fce56efc67bafc91b9fcb0b0fcbab0fcb0bafcb031fcbab0fcb080fc942cfc942f80"
+	hevcSEIUnregHex = "4e0105150102030405060708090a0b0c0d0e0f10414243444580"
+	avcSPS1Hex      = "67640020accac05005bb0169e0000003002000000c9c4c000432380008647c12401cb1c31380"
+	avcSPS3Hex      = "27640020ac2ec05005bb011000000300100000078e840016e300005b8d8bdef83b438627"
+	avcSEIRPHex     = "060601c480"
+	hevcSEIRPHex    = "4e0106012b80"
+	avcSEIFPAHex    = "062d07818100000300012080"
+	avcSEIFGCHex    = "06130801600200ff0a1ea080"
+)
+
+func TestParseSEI(t *testing.T) {
+	testCases := []struct {
+		name         string
+		codec        string
+		naluHex      string
+		wantedType   uint
+		wantedString string
+	}{
+		{"AVC type 0", "avc", avcSEI0Hex, 0, `SEI type 0, size=7, "810f1c00507440"`},
+		{"AVC type 6", "avc", avcSEIRPHex, 6,
+			`SEI type 6 RecoveryPoint: recoveryFrameCnt=0, exactMatch=true, brokenLink=false`},
+		{"HEVC type 6", "hevc", hevcSEIRPHex, 6,
+			`SEI type 6 RecoveryPoint: recoveryPocCnt=-2, exactMatch=false, brokenLink=true`},
+		{"AVC type 19", "avc", avcSEIFGCHex, 19,
+			`SEI type 19 FilmGrainCharacteristics: model=0, blendingMode=0, log2ScaleFactor=5, intervals=[1 0 0]`},
+		{"AVC type 45", "avc", avcSEIFPAHex, 45,
+			`SEI type 45 FramePackingArrangement: id=0, type=3, quincunx=false, contentInterpretation=1`},
+		{"AVC type 4", "avc", avcSEI4Hex, 4,
+			`SEI type 4 CEA-608, size=52, field1: "942094ae9162e56e67ba91b9b0b0bab0b0bab031bab0b080942c942f", field2: ""`},
+		{"HEVC type 4", "hevc", hevcSEI4Hex, 4,
+			`SEI type 4 CEA-608, size=52, field1: "942094ae9162e56e67ba91b9b0b0bab0b0bab031bab0b080942c942f", field2: ""`},
+		{"HEVC type 5", "hevc", hevcSEIUnregHex, 5,
+			`SEI type 5, size=21, uuid="0102030405060708090a0b0c0d0e0f10", payload="ABCDE"`},
+		{"HEVC type 132", "hevc", hevcSEIHashHex, 132,
+			`SEI type 132 DecodedPictureHash MD5: 9cf45826b3336eaa1f0339bb99ee3462 b3b01379ba08916ef6b1b35f7d9ad51c b3b01379ba08916ef6b1b35f7d9ad51c`},
+		{"HEVC type 137", "hevc", hevcSEIMDCVHex, 137,
+			`SEI type 137 MasteringDisplayColourVolume: G(13250,34500)B(7500,3000)R(34000,16000)WP(15635,16450)L(10000000,1)`},
+		{"HEVC type 144", "hevc", hevcSEICLLHex, 144, `SEI type 144 ContentLightLevelInformation: MaxCLL=1000, MaxFALL=400`},
+		{"HEVC type 147", "hevc", hevcSEIATCHex, 147, `SEI type 147 AlternativeTransferCharacteristics: 18`},
+	}
+
+	for _, tc := range testCases {
+		seiNALU, _ := hex.DecodeString(tc.naluHex)
+		hdrLen := 1
+		if tc.codec == "hevc" {
+			hdrLen = 2
+		}
+		rs := bytes.NewReader(seiNALU[hdrLen:])
+
+		seis, err := ExtractSEIData(rs)
+		if err != nil {
+			t.Error(err)
+		}
+		if len(seis) != 1 {
+			t.Errorf("%s: Not 1 but %d sei messages found", tc.name, len(seis))
+		}
+		seiMessage, err := DecodeSEIMessage(&seis[0], tc.codec)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if seiMessage.Type() != tc.wantedType {
+			t.Errorf("%s: got SEI type %d instead of %d", tc.name, seiMessage.Type(), tc.wantedType)
+		}
+		if seiMessage.String() != tc.wantedString {
+			t.Errorf("%s: got %q instead of %q", tc.name, seiMessage.String(), tc.wantedString)
+		}
+		if !bytes.Equal(seiMessage.Payload(), seis[0].Payload()) {
+			t.Errorf("%s: payload %x differs from %x", tc.name, seiMessage.Payload(), seis[0].Payload())
+		}
+	}
+}
+
+func TestCEA708SEI(t *testing.T) {
+	// 608 field 1 and field 2 pairs, one padding pair, and DTVCC packet start and data
+	payload, _ := hex.DecodeString("b500314741393403c5fffc9420fc8080fd9420ff0102fe0304ff")
+	msg, err := DecodeSEIMessage(NewSEIData(SEIRegisteredType, payload), "hevc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cea, ok := msg.(*CEA708SEI)
+	if !ok {
+		t.Fatalf("got %T instead of CEA708SEI", msg)
+	}
+	if len(cea.CCData) != 5 {
+		t.Errorf("got %d cc_data triplets instead of 5", len(cea.CCData))
+	}
+	if hex.EncodeToString(cea.Field1) != "9420" {
+		t.Errorf("got field1 %x", cea.Field1)
+	}
+	if hex.EncodeToString(cea.Field2) != "9420" {
+		t.Errorf("got field2 %x", cea.Field2)
+	}
+	if hex.EncodeToString(cea.DTVCCData) != "01020304" {
+		t.Errorf("got DTVCC data %x", cea.DTVCCData)
+	}
+}
+
+func TestCreateCEA708SEINALU(t *testing.T) {
+	payload, _ := hex.DecodeString("b500314741393403c5fffc9420fc8080fd9420ff0102fe0304ff")
+	ccData := []CCData{
+		{Valid: true, Type: 0, Data1: 0x94, Data2: 0x20},
+		{Valid: true, Type: 0, Data1: 0x80, Data2: 0x80},
+		{Valid: true, Type: 1, Data1: 0x94, Data2: 0x20},
+		{Valid: true, Type: 3, Data1: 0x01, Data2: 0x02},
+		{Valid: true, Type: 2, Data1: 0x03, Data2: 0x04},
+	}
+	cea, err := CreateCEA708SEI(ccData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(cea.Payload(), payload) {
+		t.Errorf("got payload %x instead of %x", cea.Payload(), payload)
+	}
+	if hex.EncodeToString(cea.Field1) != "9420" || hex.EncodeToString(cea.DTVCCData) != "01020304" {
+		t.Errorf("got field1 %x and DTVCC data %x", cea.Field1, cea.DTVCCData)
+	}
+	for _, codec := range []string{"avc", "hevc"} {
+		nalu, err := CreateSEINALU(codec, []SEIMessage{cea})
+		if err != nil {
+			t.Fatal(err)
+		}
+		hdrLen := 1
+		if codec == "hevc" {
+			hdrLen = 2
+			if hevc.GetNaluType(nalu[0]) != hevc.NALU_SEI_PREFIX || nalu[1] != 0x01 {
+				t.Errorf("bad HEVC NAL unit header %x", nalu[:2])
+			}
+		} else if avc.GetNaluType(nalu[0]) != avc.NALU_SEI {
+			t.Errorf("bad AVC NAL unit header %x", nalu[:1])
+		}
+		seis, err := ExtractSEIData(bytes.NewReader(nalu[hdrLen:]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(seis) != 1 || !bytes.Equal(seis[0].Payload(), payload) {
+			t.Errorf("codec %q: bad SEI data after round trip: %v", codec, seis)
+		}
+	}
+	_, err = CreateCEA708SEI(make([]CCData, 32))
+	if err == nil {
+		t.Errorf("no error for 32 cc_data triplets")
+	}
+}
+
+func TestCreateSEINALUEmulationPrevention(t *testing.T) {
+	sd := NewSEIData(SEIUnregisteredType, []byte{0, 0, 1, 0, 0, 0, 2})
+	nalu, err := CreateSEINALU("avc", []SEIMessage{sd})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(nalu) != "060507000003010000030002"+"80" {
+		t.Errorf("got NAL unit %x", nalu)
+	}
+	seis, err := ExtractSEIData(bytes.NewReader(nalu[1:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := deep.Equal(seis[0].Payload(), sd.Payload()); diff != nil {
+		t.Errorf("payload after ebsp round trip: %v", diff)
+	}
+}
+
+func TestHDR10PlusSEI(t *testing.T) {
+	buf := bytes.Buffer{}
+	w := bits.NewWriter(&buf)
+	w.Write(0xb5, 8)    // itu_t_t35_country_code
+	w.Write(0x003c, 16) // itu_t_t35_terminal_provider_code
+	w.Write(0x0001, 16) // itu_t_t35_terminal_provider_oriented_code
+	w.Write(4, 8)       // application_identifier
+	w.Write(1, 8)       // application_version
+	w.Write(1, 2)       // num_windows
+	w.Write(400, 27)    // targeted_system_display_maximum_luminance
+	w.Write(0, 1)       // targeted_system_display_actual_peak_luminance_flag
+	for i := 0; i < 3; i++ {
+		w.Write(uint(1000*(i+1)), 17) // maxscl
+	}
+	w.Write(500, 17) // average_maxrgb
+	w.Write(2, 4)    // num_distribution_maxrgb_percentiles
+	w.Write(1, 7)
+	w.Write(10, 17)
+	w.Write(99, 7)
+	w.Write(4000, 17)
+	w.Write(3, 10) // fraction_bright_pixels
+	w.Write(0, 1)  // mastering_display_actual_peak_luminance_flag
+	w.Write(1, 1)  // tone_mapping_flag
+	w.Write(100, 12)
+	w.Write(200, 12)
+	w.Write(2, 4) // num_bezier_curve_anchors
+	w.Write(256, 10)
+	w.Write(512, 10)
+	w.Write(0, 1) // color_saturation_mapping_flag
+	w.Flush()
+
+	msg, err := DecodeSEIMessage(NewSEIData(SEIRegisteredType, buf.Bytes()), "hevc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, ok := msg.(*HDR10PlusSEI)
+	if !ok {
+		t.Fatalf("got %T instead of HDR10PlusSEI", msg)
+	}
+	wantedProcessing := []HDR10PlusProcessing{
+		{
+			MaxScl:        [3]uint32{1000, 2000, 3000},
+			AverageMaxRGB: 500,
+			DistributionMaxRGB: []HDR10PlusPercentile{
+				{Percentage: 1, Percentile: 10},
+				{Percentage: 99, Percentile: 4000},
+			},
+			FractionBrightPixels: 3,
+			ToneMappingFlag:      true,
+			KneePointX:           100,
+			KneePointY:           200,
+			BezierCurveAnchors:   []uint16{256, 512},
+		},
+	}
+	if h.ApplicationVersion != 1 || h.NumWindows != 1 || h.TargetedSystemDisplayMaximumLuminance != 400 {
+		t.Errorf("bad HDR10+ header values: %s", h)
+	}
+	if diff := deep.Equal(h.ProcessingWindows, wantedProcessing); diff != nil {
+		t.Errorf("HDR10+ processing windows diff: %v", diff)
+	}
+}
+
+func TestTimeCodeSEI(t *testing.T) {
+	buf := bytes.Buffer{}
+	w := bits.NewWriter(&buf)
+	w.Write(1, 2)  // num_clock_ts
+	w.Write(1, 1)  // clock_timestamp_flag
+	w.Write(0, 1)  // units_field_based_flag
+	w.Write(4, 5)  // counting_type
+	w.Write(1, 1)  // full_timestamp_flag
+	w.Write(0, 1)  // discontinuity_flag
+	w.Write(1, 1)  // cnt_dropped_flag
+	w.Write(12, 9) // n_frames
+	w.Write(56, 6) // seconds_value
+	w.Write(34, 6) // minutes_value
+	w.Write(10, 5) // hours_value
+	w.Write(4, 5)  // time_offset_length
+	w.Write(0xf, 4)
+	w.Flush()
+
+	msg, err := DecodeSEIMessage(NewSEIData(SEITimeCodeType, buf.Bytes()), "hevc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wanted := `SEI type 136 TimeCode: 10:34:56;12`
+	if msg.String() != wanted {
+		t.Errorf("got %q instead of %q", msg.String(), wanted)
+	}
+	tc := msg.(*TimeCodeSEI)
+	if tc.ClockTimes[0].TimeOffsetValue != -1 {
+		t.Errorf("got time offset %d instead of -1", tc.ClockTimes[0].TimeOffsetValue)
+	}
+	// time_code is not an AVC SEI message
+	msg, err = DecodeSEIMessage(NewSEIData(SEITimeCodeType, buf.Bytes()), "avc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := msg.(*SEIData); !ok {
+		t.Errorf("got %T instead of SEIData for AVC", msg)
+	}
+}
+
+func TestSEIErrors(t *testing.T) {
+	testCases := []struct {
+		payloadType uint
+		payload     []byte
+	}{
+		{SEIRegisteredType, []byte{0xb5}},
+		{SEIUnregisteredType, []byte{0x01, 0x02}},
+		{SEIMasteringDisplayColourVolumeType, []byte{0x01, 0x02}},
+		{SEIContentLightLevelInformationType, []byte{0x01}},
+		{SEIDecodedPictureHashType, []byte{0x00, 0x01}},
+		{SEIDecodedPictureHashType, []byte{0x07, 0x01}},
+	}
+	for _, tc := range testCases {
+		_, err := DecodeSEIMessage(NewSEIData(tc.payloadType, tc.payload), "hevc")
+		if err == nil {
+			t.Errorf("type %d: expected error for payload %x", tc.payloadType, tc.payload)
+		}
+	}
+}
+
+func TestBufferingPeriodAVCSEI(t *testing.T) {
+	spsNALU, _ := hex.DecodeString(avcSPS3Hex)
+	sps, err := avc.ParseSPSNALUnit(spsNALU, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seiNALU, _ := hex.DecodeString(avcSEI0Hex)
+	seis, err := ExtractSEIData(bytes.NewReader(seiNALU[1:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := DecodeAVCSEIMessage(&seis[0], sps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wanted := `SEI type 0 BufferingPeriod: spsID=0, nal=[{138808 41192}]`
+	if msg.String() != wanted {
+		t.Errorf("got %q instead of %q", msg.String(), wanted)
+	}
+	msg, err = DecodeAVCSEIMessage(&seis[0], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := msg.(*SEIData); !ok {
+		t.Errorf("got %T instead of SEIData without SPS", msg)
+	}
+}
+
+func TestBufferingPeriodHEVCSEI(t *testing.T) {
+	sps := &hevc.SPS{
+		SpsID: 1,
+		VUI: &hevc.VUIParameters{
+			HrdParametersPresentFlag: true,
+			HrdParameters: &hevc.HrdParameters{
+				NalHrdParametersPresentFlag:        true,
+				InitialCpbRemovalDelayLengthMinus1: 23,
+				AuCpbRemovalDelayLengthMinus1:      15,
+				DpbOutputDelayLengthMinus1:         4,
+				SubLayers:                          []hevc.SubLayerHrd{{CpbCntMinus1: 0}},
+			},
+		},
+	}
+	buf := bytes.Buffer{}
+	w := bits.NewWriter(&buf)
+	w.Write(2, 3)      // bp_seq_parameter_set_id ue(v) 1
+	w.Write(1, 1)      // irap_cpb_params_present_flag
+	w.Write(3, 16)     // cpb_delay_offset
+	w.Write(2, 5)      // dpb_delay_offset
+	w.Write(0, 1)      // concatenation_flag
+	w.Write(0, 16)     // au_cpb_removal_delay_delta_minus1
+	w.Write(45000, 24) // nal_initial_cpb_removal_delay
+	w.Write(1000, 24)  // nal_initial_cpb_removal_offset
+	w.Write(40000, 24) // nal_initial_alt_cpb_removal_delay
+	w.Write(6000, 24)  // nal_initial_alt_cpb_removal_offset
+	w.Write(1, 1)      // rbsp_stop_one_bit
+	w.Flush()
+	sd := NewSEIData(SEIBufferingPeriodType, buf.Bytes())
+	msg, err := DecodeHEVCSEIMessage(sd, sps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bp := msg.(*BufferingPeriodHEVCSEI)
+	if diff := deep.Equal(bp.NalInitialAltCpb, []InitialCpbRemoval{{40000, 6000}}); diff != nil {
+		t.Error(diff)
+	}
+	wanted := `SEI type 0 BufferingPeriod: spsID=1, cpbDelayOffset=3, dpbDelayOffset=2, nal=[{45000 1000}]`
+	if msg.String() != wanted {
+		t.Errorf("got %q instead of %q", msg.String(), wanted)
+	}
+	msg, err = DecodeHEVCSEIMessage(sd, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := msg.(*SEIData); !ok {
+		t.Errorf("got %T instead of SEIData without SPS", msg)
+	}
+	sps.SpsID = 0
+	if _, err := DecodeHEVCSEIMessage(sd, sps); err == nil {
+		t.Error("no error for wrong sps id")
+	}
+}
+
+func TestPicTimingAVCSEI(t *testing.T) {
+	testCases := []struct {
+		name         string
+		spsHex       string
+		cpbDpbLen    []int
+		picStruct    uint
+		timeOffset   int
+		wantedString string
+	}{
+		{"no time offset", avcSPS1Hex, []int{10, 5}, 0, 0,
+			`SEI type 1 PicTiming: cpbRemovalDelay=4, dpbOutputDelay=2, picStruct=0, time=10:59:30;12`},
+		{"time offset and two clockTS", avcSPS3Hex, []int{24, 24}, 3, 24,
+			`SEI type 1 PicTiming: cpbRemovalDelay=4, dpbOutputDelay=2, picStruct=3, time=10:59:30;12`},
+	}
+	for _, tc := range testCases {
+		spsNALU, _ := hex.DecodeString(tc.spsHex)
+		sps, err := avc.ParseSPSNALUnit(spsNALU, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf := bytes.Buffer{}
+		w := bits.NewWriter(&buf)
+		w.Write(4, tc.cpbDpbLen[0]) // cpb_removal_delay
+		w.Write(2, tc.cpbDpbLen[1]) // dpb_output_delay
+		w.Write(tc.picStruct, 4)    // pic_struct
+		w.Write(1, 1)               // clock_timestamp_flag
+		w.Write(0, 2)               // ct_type
+		w.Write(0, 1)               // nuit_field_based_flag
+		w.Write(4, 5)               // counting_type
+		w.Write(1, 1)               // full_timestamp_flag
+		w.Write(0, 1)               // discontinuity_flag
+		w.Write(1, 1)               // cnt_dropped_flag
+		w.Write(12, 8)              // n_frames
+		w.Write(30, 6)              // seconds_value
+		w.Write(59, 6)              // minutes_value
+		w.Write(10, 5)              // hours_value
+		if tc.timeOffset > 0 {
+			w.Write(0xfffffe, tc.timeOffset) // time_offset
+		}
+		if tc.picStruct == 3 {
+			w.Write(0, 1) // clock_timestamp_flag of second clockTS
+		}
+		w.Write(1, 1) // stop bit
+		w.Flush()
+
+		msg, err := DecodeAVCSEIMessage(NewSEIData(SEIPicTimingType, buf.Bytes()), sps)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if msg.String() != tc.wantedString {
+			t.Errorf("%s: got %q instead of %q", tc.name, msg.String(), tc.wantedString)
+		}
+		pt := msg.(*PicTimingAVCSEI)
+		if tc.timeOffset > 0 && pt.ClockTimestamps[0].TimeOffsetValue != -2 {
+			t.Errorf("%s: got time offset %d instead of -2", tc.name, pt.ClockTimestamps[0].TimeOffsetValue)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package sei
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/jaypadia-frame/mp4ff/bits"
+)
+
+// TimeCodeSEI - HEVC time_code SEI message (type 136) ISO/IEC 23008-2 Section D.2.27
+type TimeCodeSEI struct {
+	payload    []byte
+	ClockTimes []ClockTimestamp
+}
+
+// ClockTimestamp - clock timestamp as used in HEVC time_code and AVC pic_timing SEI
+type ClockTimestamp struct {
+	ClockTimestampFlag  bool
+	CtType              byte // AVC only
+	UnitsFieldBasedFlag bool // nuit_field_based_flag in AVC
+	CountingType        byte
+	FullTimestampFlag   bool
+	DiscontinuityFlag   bool
+	CntDroppedFlag      bool
+	NFrames             uint16
+	SecondsFlag         bool
+	MinutesFlag         bool
+	HoursFlag           bool
+	Seconds             byte
+	Minutes             byte
+	Hours               byte
+	TimeOffsetLength    byte
+	TimeOffsetValue     int
+}
+
+// String - timecode as hh:mm:ss:ff, or hh:mm:ss;ff for dropped frames
+func (c ClockTimestamp) String() string {
+	sep := ":"
+	if c.CntDroppedFlag {
+		sep = ";"
+	}
+	return fmt.Sprintf("%02d:%02d:%02d%s%02d", c.Hours, c.Minutes, c.Seconds, sep, c.NFrames)
+}
+
+// DecodeTimeCodeSEI - decode HEVC time_code SEI message
+func DecodeTimeCodeSEI(sd *SEIData) (SEIMessage, error) {
+	r := bits.NewAccErrReader(bytes.NewBuffer(sd.payload))
+	tc := &TimeCodeSEI{payload: sd.payload}
+	numClockTS := int(r.Read(2))
+	for i := 0; i < numClockTS; i++ {
+		c := ClockTimestamp{}
+		c.ClockTimestampFlag = r.ReadFlag()
+		if c.ClockTimestampFlag {
+			c.UnitsFieldBasedFlag = r.ReadFlag()
+			c.CountingType = byte(r.Read(5))
+			c.FullTimestampFlag = r.ReadFlag()
+			c.DiscontinuityFlag = r.ReadFlag()
+			c.CntDroppedFlag = r.ReadFlag()
+			c.NFrames = uint16(r.Read(9))
+			readClockTimestampHMS(r, &c)
+			c.TimeOffsetLength = byte(r.Read(5))
+			if c.TimeOffsetLength > 0 {
+				c.TimeOffsetValue = r.ReadVInt(int(c.TimeOffsetLength))
+			}
+		}
+		tc.ClockTimes = append(tc.ClockTimes, c)
+	}
+	if r.AccError() != nil {
+		return nil, fmt.Errorf("time_code: %w", r.AccError())
+	}
+	return tc, nil
+}
+
+// readClockTimestampHMS - read seconds, minutes and hours, either full or with flags
+func readClockTimestampHMS(r *bits.AccErrReader, c *ClockTimestamp) {
+	if c.FullTimestampFlag {
+		c.SecondsFlag, c.MinutesFlag, c.HoursFlag = true, true, true
+		c.Seconds = byte(r.Read(6))
+		c.Minutes = byte(r.Read(6))
+		c.Hours = byte(r.Read(5))
+		return
+	}
+	c.SecondsFlag = r.ReadFlag()
+	if c.SecondsFlag {
+		c.Seconds = byte(r.Read(6))
+		c.MinutesFlag = r.ReadFlag()
+		if c.MinutesFlag {
+			c.Minutes = byte(r.Read(6))
+			c.HoursFlag = r.ReadFlag()
+			if c.HoursFlag {
+				c.Hours = byte(r.Read(5))
+			}
+		}
+	}
+}
+
+// Type - SEI payload type
+func (s *TimeCodeSEI) Type() uint {
+	return SEITimeCodeType
+}
+
+// Size - size in bytes of raw SEI message rbsp payload
+func (s *TimeCodeSEI) Size() uint {
+	return uint(len(s.payload))
+}
+
+// String - print all clock timestamps that are present
+func (s *TimeCodeSEI) String() string {
+	var times []string
+	for _, c := range s.ClockTimes {
+		if c.ClockTimestampFlag {
+			times = append(times, c.String())
+		}
+	}
+	return fmt.Sprintf("SEI type %d TimeCode: %s", s.Type(), strings.Join(times, ", "))
+}
+
+// Payload - SEI raw rbsp payload
+func (s *TimeCodeSEI) Payload() []byte {
+	return s.payload
+}
@@ -0,0 +1,50 @@
+package sei
+
+import (
+	"encoding/hex"
+	"fmt"
+)
+
+// UnregisteredSEI - SEI message of type 5 (user_data_unregistered)
+type UnregisteredSEI struct {
+	UUID    []byte
+	payload []byte
+}
+
+// DecodeUserDataUnregisteredSEI - Decode an unregistered SEI message (type 5)
+func DecodeUserDataUnregisteredSEI(sd *SEIData) (SEIMessage, error) {
+	if len(sd.payload) < 16 {
+		return nil, errTooShort(sd, 16)
+	}
+	uuid := sd.payload[:16]
+	return NewUnregisteredSEI(sd, uuid), nil
+}
+
+// NewUnregisteredSEI - Create an unregistered SEI message (type 5)
+func NewUnregisteredSEI(sd *SEIData, uuid []byte) *UnregisteredSEI {
+	return &UnregisteredSEI{
+		UUID:    uuid,
+		payload: sd.payload,
+	}
+}
+
+// Type - SEI payload type
+func (s *UnregisteredSEI) Type() uint {
+	return SEIUnregisteredType
+}
+
+// Size - size in bytes of raw SEI message rbsp payload
+func (s *UnregisteredSEI) Size() uint {
+	return uint(len(s.payload))
+}
+
+func (s *UnregisteredSEI) String() string {
+	payloadAfterUUID := string(s.payload[16:])
+	return fmt.Sprintf("SEI type %d, size=%d, uuid=%q, payload=%q",
+		s.Type(), s.Size(), hex.EncodeToString(s.UUID), payloadAfterUUID)
+}
+
+// Payload - SEI raw rbsp payload
+func (s *UnregisteredSEI) Payload() []byte {
+	return s.payload
+}
@@ -0,0 +1,18 @@
+/*
+Package temporal - extract temporal sub-layers from AVC and HEVC tracks without re-encoding.
+
+Every sample of a track is given a temporal layer by one of the methods:
+
+  - TemporalID uses the TemporalId in the NAL unit headers of HEVC samples
+  - RefIDC puts AVC samples with nal_ref_idc equal to 0 in layer 1 and other samples in layer 0
+  - Pyramid uses the B-frame pyramid level found by the gop package
+  - SyncSamples puts sync samples in layer 0 and other samples in layer 1, to make trick-play tracks
+
+Extract removes the samples above a layer with mp4.File.RemoveTrackSamples, which recomputes sample durations,
+ctts, and trun boxes. Dropping the highest layer of a dyadic hierarchy halves the frame rate, e.g. from 30 to 15 fps.
+
+The samples left must only refer to samples in the same or lower layers. This is guaranteed for TemporalID,
+RefIDC, and SyncSamples. For Pyramid, it holds for common encoder configurations, but is not guaranteed
+by the standards.
+*/
+package temporal
@@ -0,0 +1,224 @@
+package temporal
+
+import (
+	"fmt"
+
+	"github.com/jaypadia-frame/mp4ff/avc"
+	"github.com/jaypadia-frame/mp4ff/gop"
+	"github.com/jaypadia-frame/mp4ff/hevc"
+	"github.com/jaypadia-frame/mp4ff/mp4"
+)
+
+// Method - how the temporal layer of a sample is found
+type Method int
+
+const (
+	// TemporalID - TemporalId of the first VCL NAL unit of HEVC samples
+	TemporalID Method = iota
+	// RefIDC - layer 1 for AVC samples where all VCL NAL units have nal_ref_idc 0, and layer 0 for others
+	RefIDC
+	// Pyramid - 0 for intra and P frames, and the pyramid level for B frames, as found by gop.Analyzer
+	Pyramid
+	// SyncSamples - layer 0 for sync samples and layer 1 for others
+	SyncSamples
+)
+
+func (m Method) String() string {
+	switch m {
+	case TemporalID:
+		return "TemporalID"
+	case RefIDC:
+		return "RefIDC"
+	case Pyramid:
+		return "Pyramid"
+	case SyncSamples:
+		return "SyncSamples"
+	}
+	return fmt.Sprintf("Method(%d)", int(m))
+}
+
+// track - video track and its codec
+type track struct {
+	trak        *mp4.TrakBox
+	codec       string
+	lengthSizes []int // NAL unit length size per sample description
+}
+
+func getTrack(f *mp4.File, trackID uint32) (*track, error) {
+	if f.Moov == nil {
+		return nil, fmt.Errorf("no moov box")
+	}
+	for _, trak := range f.Moov.Traks {
+		if trak.Tkhd.TrackID != trackID {
+			continue
+		}
+		tr := &track{trak: trak}
+		stsd := trak.Mdia.Minf.Stbl.Stsd
+		switch {
+		case stsd.AvcX != nil:
+			tr.codec = "avc"
+		case stsd.HvcX != nil:
+			tr.codec = "hevc"
+		}
+		for _, entry := range stsd.Children {
+			tr.lengthSizes = append(tr.lengthSizes, mp4.NaluLengthSize(entry))
+		}
+		return tr, nil
+	}
+	return nil, fmt.Errorf("no track with ID %d", trackID)
+}
+
+// Layers - temporal layer of every sample of a track in decode order.
+// All methods except SyncSamples need an AVC or HEVC track, TemporalID an HEVC track, and RefIDC an AVC track.
+func Layers(f *mp4.File, trackID uint32, method Method) ([]int, error) {
+	tr, err := getTrack(f, trackID)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case method == TemporalID && tr.codec != "hevc":
+		return nil, fmt.Errorf("method %s needs an HEVC track", method)
+	case method == RefIDC && tr.codec != "avc":
+		return nil, fmt.Errorf("method %s needs an AVC track", method)
+	case method == Pyramid && tr.codec != "avc" && tr.codec != "hevc":
+		return nil, fmt.Errorf("method %s needs an AVC or HEVC track", method)
+	case method < TemporalID || method > SyncSamples:
+		return nil, fmt.Errorf("unknown method %s", method)
+	}
+
+	var analyzer *gop.Analyzer
+	if method == Pyramid {
+		if analyzer, err = newAnalyzer(tr); err != nil {
+			return nil, err
+		}
+	}
+	var layers []int
+	err = f.VisitTrackSamples(trackID, nil, func(nr, sdi uint32, s *mp4.FullSample) error {
+		lengthSize := 0
+		if sdi >= 1 && int(sdi) <= len(tr.lengthSizes) {
+			lengthSize = tr.lengthSizes[sdi-1]
+		}
+		if method != SyncSamples && lengthSize == 0 {
+			return fmt.Errorf("sample %d: no avcC or hvcC box for sample description index %d", nr, sdi)
+		}
+		layer := 0
+		var err error
+		switch method {
+		case TemporalID:
+			layer, err = temporalIDLayer(s.Data, lengthSize)
+		case RefIDC:
+			layer, err = refIDCLayer(s.Data, lengthSize)
+		case Pyramid:
+			analyzer.LengthSize = lengthSize
+			err = analyzer.AddSample(*s)
+		case SyncSamples:
+			if !s.IsSync() {
+				layer = 1
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("sample %d: %w", nr, err)
+		}
+		layers = append(layers, layer)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if method == Pyramid {
+		for i, frame := range analyzer.Result().Frames {
+			layers[i] = frame.PyramidLevel
+		}
+	}
+	return layers, nil
+}
+
+// newAnalyzer - GOP analyzer with the parameter sets of the first sample description
+func newAnalyzer(tr *track) (*gop.Analyzer, error) {
+	a := gop.NewAnalyzer(tr.codec)
+	var psNalus [][]byte
+	stsd := tr.trak.Mdia.Minf.Stbl.Stsd
+	switch {
+	case stsd.AvcX != nil && stsd.AvcX.AvcC != nil:
+		psNalus = append(psNalus, stsd.AvcX.AvcC.SPSnalus...)
+		psNalus = append(psNalus, stsd.AvcX.AvcC.PPSnalus...)
+	case stsd.HvcX != nil && stsd.HvcX.HvcC != nil:
+		psNalus = append(psNalus, stsd.HvcX.HvcC.GetNalusForType(hevc.NALU_SPS)...)
+		psNalus = append(psNalus, stsd.HvcX.HvcC.GetNalusForType(hevc.NALU_PPS)...)
+	}
+	if err := a.AddParameterSets(psNalus); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// temporalIDLayer - TemporalId of the first VCL NAL unit of an HEVC sample
+func temporalIDLayer(sample []byte, lengthSize int) (int, error) {
+	nalus, err := avc.GetNalusFromSampleWithLengthSize(sample, lengthSize)
+	if err != nil {
+		return 0, err
+	}
+	for _, nalu := range nalus {
+		if len(nalu) < 2 {
+			return 0, fmt.Errorf("NAL unit too short")
+		}
+		if hevc.GetNaluType(nalu[0]) < hevc.NALU_VPS {
+			return int(nalu[1]&0x07) - 1, nil
+		}
+	}
+	return 0, fmt.Errorf("no VCL NAL unit")
+}
+
+// refIDCLayer - 1 if all VCL NAL units of an AVC sample have nal_ref_idc 0, and 0 otherwise
+func refIDCLayer(sample []byte, lengthSize int) (int, error) {
+	nalus, err := avc.GetNalusFromSampleWithLengthSize(sample, lengthSize)
+	if err != nil {
+		return 0, err
+	}
+	nrVCL := 0
+	for _, nalu := range nalus {
+		if len(nalu) == 0 {
+			continue
+		}
+		switch avc.GetNaluType(nalu[0]) {
+		case avc.NALU_NON_IDR, avc.NALU_IDR:
+			nrVCL++
+			if avc.GetNalRefIDC(nalu[0]) != 0 {
+				return 0, nil
+			}
+		}
+	}
+	if nrVCL == 0 {
+		return 0, fmt.Errorf("no VCL NAL unit")
+	}
+	return 1, nil
+}
+
+// Extract - remove the samples of a track with layer above maxLayer.
+// The decode times, durations, and composition time offsets of the samples left are recomputed by
+// mp4.File.RemoveTrackSamples. For TemporalID, NumTemporalLayers in the hvcC boxes is lowered to maxLayer+1.
+func Extract(f *mp4.File, trackID uint32, method Method, maxLayer int) error {
+	if maxLayer < 0 {
+		return fmt.Errorf("negative max layer %d", maxLayer)
+	}
+	layers, err := Layers(f, trackID, method)
+	if err != nil {
+		return err
+	}
+	err = f.RemoveTrackSamples(trackID, func(nr uint32, s *mp4.FullSample) bool {
+		return layers[nr-1] > maxLayer
+	})
+	if err != nil {
+		return err
+	}
+	if method == TemporalID {
+		tr, _ := getTrack(f, trackID)
+		for _, entry := range tr.trak.Mdia.Minf.Stbl.Stsd.Children {
+			vse, ok := entry.(*mp4.VisualSampleEntryBox)
+			if ok && vse.HvcC != nil && int(vse.HvcC.NumTemporalLayers) > maxLayer+1 {
+				vse.HvcC.NumTemporalLayers = byte(maxLayer + 1)
+			}
+		}
+	}
+	return nil
+}
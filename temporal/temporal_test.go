@@ -0,0 +1,125 @@
+package temporal
+
+import (
+	"testing"
+
+	"github.com/jaypadia-frame/mp4ff/internal/mp4test"
+	"github.com/jaypadia-frame/mp4ff/mp4"
+)
+
+func TestLayers(t *testing.T) {
+	f, err := mp4.ReadMP4File("../mp4/testdata/prog_8s.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	refLayers, err := Layers(f, 2, RefIDC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pyramidLayers, err := Layers(f, 2, Pyramid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	syncLayers, err := Layers(f, 2, SyncSamples)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(refLayers) != 240 || len(pyramidLayers) != 240 || len(syncLayers) != 240 {
+		t.Fatalf("got %d, %d, and %d layers", len(refLayers), len(pyramidLayers), len(syncLayers))
+	}
+	nrNonRef := 0
+	for i := range refLayers {
+		if refLayers[i] == 1 {
+			nrNonRef++
+			if pyramidLayers[i] == 0 {
+				t.Errorf("sample %d: non-reference frame at pyramid level 0", i+1)
+			}
+		}
+		if (i%30 == 0) != (syncLayers[i] == 0) {
+			t.Errorf("sample %d: sync layer %d", i+1, syncLayers[i])
+		}
+	}
+	if nrNonRef == 0 {
+		t.Error("no non-reference frames")
+	}
+	if _, err := Layers(f, 2, TemporalID); err == nil {
+		t.Error("no error for TemporalID on AVC track")
+	}
+	if _, err := Layers(f, 1, RefIDC); err == nil {
+		t.Error("no error for RefIDC on audio track")
+	}
+}
+
+func TestExtract(t *testing.T) {
+	for _, method := range []Method{RefIDC, Pyramid, SyncSamples} {
+		t.Run(method.String(), func(t *testing.T) {
+			f, err := mp4.ReadMP4File("../mp4/testdata/prog_8s.mp4")
+			if err != nil {
+				t.Fatal(err)
+			}
+			layers, err := Layers(f, 2, method)
+			if err != nil {
+				t.Fatal(err)
+			}
+			nrKept := 0
+			for _, l := range layers {
+				if l == 0 {
+					nrKept++
+				}
+			}
+			stbl := f.Moov.Traks[1].Mdia.Minf.Stbl
+			endTime := uint64(0)
+			for i, c := range stbl.Stts.SampleCount {
+				endTime += uint64(c) * uint64(stbl.Stts.SampleTimeDelta[i])
+			}
+			if err := Extract(f, 2, method, 0); err != nil {
+				t.Fatal(err)
+			}
+			f = mp4test.EncodeDecode(t, f)
+			layers, err = Layers(f, 2, method)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(layers) != nrKept {
+				t.Errorf("got %d samples instead of %d", len(layers), nrKept)
+			}
+			for i, l := range layers {
+				if l != 0 {
+					t.Errorf("sample %d in layer %d", i+1, l)
+				}
+			}
+			stbl = f.Moov.Traks[1].Mdia.Minf.Stbl
+			gotEndTime := uint64(0)
+			for i, c := range stbl.Stts.SampleCount {
+				gotEndTime += uint64(c) * uint64(stbl.Stts.SampleTimeDelta[i])
+			}
+			if gotEndTime != endTime {
+				t.Errorf("got end time %d instead of %d", gotEndTime, endTime)
+			}
+		})
+	}
+}
+
+func TestLayerOfSample(t *testing.T) {
+	hevcSample := []byte{
+		0, 0, 0, 3, 0x4e, 0x01, 0x05, // prefix SEI, TId 0
+		0, 0, 0, 3, 0x02, 0x03, 0x80, // TRAIL_R, TId 2
+	}
+	layer, err := temporalIDLayer(hevcSample, 4)
+	if err != nil || layer != 2 {
+		t.Errorf("got TemporalId %d, err %v", layer, err)
+	}
+	avcSample := []byte{
+		0, 3, 0x06, 0x05, 0x00, // SEI
+		0, 2, 0x01, 0x88, // non-IDR slice with nal_ref_idc 0
+		0, 2, 0x21, 0x88, // non-IDR slice with nal_ref_idc 1
+	}
+	layer, err = refIDCLayer(avcSample[:9], 2)
+	if err != nil || layer != 1 {
+		t.Errorf("got layer %d, err %v", layer, err)
+	}
+	layer, err = refIDCLayer(avcSample, 2)
+	if err != nil || layer != 0 {
+		t.Errorf("got layer %d, err %v", layer, err)
+	}
+}
@@ -0,0 +1,593 @@
+package ts
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/jaypadia-frame/mp4ff/aac"
+	"github.com/jaypadia-frame/mp4ff/ac3"
+	"github.com/jaypadia-frame/mp4ff/adts"
+	"github.com/jaypadia-frame/mp4ff/avc"
+	"github.com/jaypadia-frame/mp4ff/hevc"
+	"github.com/jaypadia-frame/mp4ff/mp4"
+)
+
+// Timescale - timescale of all timestamps and samples
+const Timescale = 90000
+
+// Codec - codec of an elementary stream
+type Codec string
+
+// Supported codecs
+const (
+	CodecAVC  Codec = "avc"
+	CodecHEVC Codec = "hevc"
+	CodecAAC  Codec = "aac"
+	CodecAC3  Codec = "ac-3"
+	CodecEAC3 Codec = "ec-3"
+)
+
+// Stream - elementary stream with samples in 90 kHz timescale
+type Stream struct {
+	PID           uint16
+	ProgramNumber uint16
+	StreamType    byte
+	Codec         Codec
+	Samples       []mp4.FullSample // DecodeTime is the unwrapped DTS
+	VPSs          [][]byte         // HEVC
+	SPSs          [][]byte         // AVC and HEVC
+	PPSs          [][]byte         // AVC and HEVC
+	AudioConfig   *aac.AudioSpecificConfig
+	Dac3          *mp4.Dac3Box
+	Dec3          *mp4.Dec3Box
+
+	cc             int // Last continuity_counter, or -1
+	pes            []byte
+	pesOK          bool // pes started with payload_unit_start_indicator and has no gaps
+	started        bool // A DTS has been seen
+	lastDTS        int64
+	lastSampleTime int64
+	tsOffset       int64 // Added to unwrapped timestamps to continue after discontinuities
+	discontinuity  bool
+	nominalDur     uint32 // Sample duration used for the last sample
+	audioRest      []byte // Start of an audio frame that continues in the next PES packet
+	audioRestTime  int64  // Decode time of audioRest
+}
+
+// SCTE35Section - splice_info_section on an SCTE-35 PID
+type SCTE35Section struct {
+	PID           uint16
+	Data          []byte // Complete section
+	PTSAdjustment int64
+	CommandType   byte  // splice_command_type
+	SpliceTime    int64 // pts_time of a time_signal command, or -1
+}
+
+// Demuxer - demultiplex a transport stream into samples per elementary stream.
+// Feed it with packets by AddPacket or Demux, and call Flush at the end.
+type Demuxer struct {
+	Streams          []*Stream // In order of appearance in PMTs
+	SCTE35           []SCTE35Section
+	ContinuityErrors int // Number of continuity counter gaps
+	Warnings         []string
+	pmtPIDs          map[uint16]bool
+	streams          map[uint16]*Stream
+	scte35PIDs       map[uint16]bool
+	sections         map[uint16]*sectionBuffer
+	sectionCCs       map[uint16]int
+	nrPackets        int
+}
+
+// NewDemuxer - create a demuxer
+func NewDemuxer() *Demuxer {
+	return &Demuxer{
+		pmtPIDs:    make(map[uint16]bool),
+		streams:    make(map[uint16]*Stream),
+		scte35PIDs: make(map[uint16]bool),
+		sections:   make(map[uint16]*sectionBuffer),
+		sectionCCs: make(map[uint16]int),
+	}
+}
+
+// Demux - read and demultiplex all packets from r, and flush the streams
+func (d *Demuxer) Demux(r io.Reader) error {
+	buf := make([]byte, PacketSize)
+	for nr := 0; ; nr++ {
+		_, err := io.ReadFull(r, buf)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("packet %d: %w", nr, err)
+		}
+		if err := d.AddPacket(buf); err != nil {
+			return fmt.Errorf("packet %d: %w", nr, err)
+		}
+	}
+	return d.Flush()
+}
+
+// AddPacket - demultiplex a 188-byte packet.
+// An error is only returned for a wrong packet size. Broken packets and PES packets give Warnings.
+func (d *Demuxer) AddPacket(data []byte) error {
+	if len(data) != PacketSize {
+		return fmt.Errorf("packet size %d instead of %d", len(data), PacketSize)
+	}
+	d.nrPackets++
+	p, err := parsePacket(data)
+	if err != nil {
+		d.Warnings = append(d.Warnings, fmt.Sprintf("packet %d: %s", d.nrPackets-1, err))
+		return nil
+	}
+	switch {
+	case p.pid == PIDPAT, d.pmtPIDs[p.pid], d.scte35PIDs[p.pid]:
+		d.addSectionPacket(p)
+	case d.streams[p.pid] != nil:
+		d.addPESPacket(d.streams[p.pid], p)
+	}
+	return nil
+}
+
+// Flush - finish the last PES packet of every stream, and set the duration of the last samples
+func (d *Demuxer) Flush() error {
+	for _, s := range d.Streams {
+		if s.pesOK {
+			d.finishPES(s)
+		}
+		if len(s.audioRest) > 0 {
+			// The last frame may have been completed by PES packets without PTS
+			if err := s.addAudioSamples(nil, 0); err != nil {
+				d.Warnings = append(d.Warnings, fmt.Sprintf("PID %d: %s", s.PID, err))
+			}
+		}
+		if len(s.audioRest) > 0 {
+			d.Warnings = append(d.Warnings, fmt.Sprintf("PID %d: truncated audio frame at end dropped", s.PID))
+			s.audioRest = nil
+		}
+		if n := len(s.Samples); n > 0 {
+			s.Samples[n-1].Dur = s.nominalDur
+		}
+	}
+	return nil
+}
+
+// checkCC - check continuity counter. Returns false for duplicate packets, which should be dropped
+func checkCC(lastCC *int, p *packet) (ok, duplicate bool) {
+	if !p.hasPayload {
+		return true, false
+	}
+	last := *lastCC
+	*lastCC = int(p.cc)
+	switch {
+	case last < 0 || p.discontinuity:
+		return true, false
+	case int(p.cc) == last:
+		return true, true
+	case int(p.cc) == (last+1)&0x0f:
+		return true, false
+	}
+	return false, false
+}
+
+func (d *Demuxer) addSectionPacket(p *packet) {
+	sb := d.sections[p.pid]
+	if sb == nil {
+		sb = &sectionBuffer{}
+		d.sections[p.pid] = sb
+		d.sectionCCs[p.pid] = -1
+	}
+	cc := d.sectionCCs[p.pid]
+	ok, duplicate := checkCC(&cc, p)
+	d.sectionCCs[p.pid] = cc
+	if duplicate || !p.hasPayload {
+		return
+	}
+	if !ok {
+		d.ContinuityErrors++
+		sb.reset()
+	}
+	sections, err := sb.add(p.payload, p.pusi)
+	if err != nil {
+		d.Warnings = append(d.Warnings, fmt.Sprintf("PID %d: %s", p.pid, err))
+		sb.reset()
+		return
+	}
+	for _, section := range sections {
+		var err error
+		switch {
+		case p.pid == PIDPAT:
+			err = d.handlePAT(section)
+		case d.pmtPIDs[p.pid]:
+			err = d.handlePMT(section)
+		case d.scte35PIDs[p.pid]:
+			err = d.handleSCTE35(p.pid, section)
+		}
+		if err != nil {
+			d.Warnings = append(d.Warnings, fmt.Sprintf("PID %d: %s", p.pid, err))
+		}
+	}
+}
+
+func (d *Demuxer) handlePAT(section []byte) error {
+	programs, err := parsePAT(section)
+	if err != nil {
+		return err
+	}
+	for _, pid := range programs {
+		d.pmtPIDs[pid] = true
+	}
+	return nil
+}
+
+func (d *Demuxer) handlePMT(section []byte) error {
+	p, err := parsePMT(section)
+	if err != nil {
+		return err
+	}
+	for _, ps := range p.streams {
+		if ps.streamType == StreamTypeSCTE35 {
+			d.scte35PIDs[ps.pid] = true
+			continue
+		}
+		if d.streams[ps.pid] != nil {
+			continue
+		}
+		codec := streamCodec(ps)
+		if codec == "" {
+			continue
+		}
+		s := &Stream{PID: ps.pid, ProgramNumber: p.programNumber, StreamType: ps.streamType, Codec: codec, cc: -1}
+		d.streams[ps.pid] = s
+		d.Streams = append(d.Streams, s)
+	}
+	return nil
+}
+
+// streamCodec - codec of PMT stream entry, or "" if not supported
+func streamCodec(ps pmtStream) Codec {
+	switch ps.streamType {
+	case StreamTypeAVC:
+		return CodecAVC
+	case StreamTypeHEVC:
+		return CodecHEVC
+	case StreamTypeAAC:
+		return CodecAAC
+	case StreamTypeAC3:
+		return CodecAC3
+	case StreamTypeEAC3:
+		return CodecEAC3
+	case StreamTypePrivatePES:
+		for _, desc := range ps.descriptors {
+			switch desc.tag {
+			case 0x6a: // DVB AC-3_descriptor
+				return CodecAC3
+			case 0x7a: // DVB enhanced_AC-3_descriptor
+				return CodecEAC3
+			case 0x05: // registration_descriptor
+				switch string(desc.data) {
+				case "AC-3":
+					return CodecAC3
+				case "EAC3":
+					return CodecEAC3
+				}
+			}
+		}
+	}
+	return ""
+}
+
+func (d *Demuxer) handleSCTE35(pid uint16, section []byte) error {
+	if section[0] != tableIDSCTE35 {
+		return fmt.Errorf("table_id %d on SCTE-35 PID", section[0])
+	}
+	if len(section) < 18 {
+		return fmt.Errorf("splice_info_section too short")
+	}
+	if crc32MPEG2(section) != 0 {
+		return fmt.Errorf("CRC error in splice_info_section")
+	}
+	s := SCTE35Section{
+		PID:           pid,
+		Data:          section,
+		PTSAdjustment: int64(section[4]&0x01)<<32 | int64(section[5])<<24 | int64(section[6])<<16 | int64(section[7])<<8 | int64(section[8]),
+		CommandType:   section[13],
+		SpliceTime:    -1,
+	}
+	// time_signal has a splice_time with time_specified_flag and pts_time
+	if s.CommandType == 0x06 && len(section) >= 19 && section[14]&0x80 != 0 {
+		s.SpliceTime = int64(section[14]&0x01)<<32 | int64(section[15])<<24 | int64(section[16])<<16 |
+			int64(section[17])<<8 | int64(section[18])
+	}
+	d.SCTE35 = append(d.SCTE35, s)
+	return nil
+}
+
+func (d *Demuxer) addPESPacket(s *Stream, p *packet) {
+	ok, duplicate := checkCC(&s.cc, p)
+	if duplicate {
+		return
+	}
+	if p.discontinuity {
+		s.discontinuity = true
+	}
+	if !ok {
+		d.ContinuityErrors++
+		if s.pesOK {
+			d.Warnings = append(d.Warnings, fmt.Sprintf("PID %d: PES packet dropped after continuity error", s.PID))
+		}
+		s.pesOK = false
+		s.audioRest = nil
+	}
+	if !p.hasPayload {
+		return
+	}
+	if p.pusi {
+		if s.pesOK {
+			d.finishPES(s)
+		}
+		s.pes = append(s.pes[:0], p.payload...)
+		s.pesOK = true
+	} else if s.pesOK {
+		s.pes = append(s.pes, p.payload...)
+	}
+	if s.pesOK && pesComplete(s.pes) {
+		d.finishPES(s)
+	}
+}
+
+// pesComplete - true if the PES packet has a PES_packet_length and all its bytes are present
+func pesComplete(data []byte) bool {
+	if len(data) < 6 {
+		return false
+	}
+	pesPacketLength := int(data[4])<<8 | int(data[5])
+	return pesPacketLength > 0 && len(data) >= 6+pesPacketLength
+}
+
+// finishPES - parse the complete PES packet of s and add its samples. Errors are added to Warnings
+func (d *Demuxer) finishPES(s *Stream) {
+	s.pesOK = false
+	pes, err := parsePES(s.pes)
+	if err != nil {
+		d.Warnings = append(d.Warnings, fmt.Sprintf("PID %d: %s", s.PID, err))
+		return
+	}
+	if pes.pts < 0 {
+		if len(s.audioRest) > 0 {
+			// No audio frame starts in the packet, so it continues the truncated frame
+			s.audioRest = append(s.audioRest, pes.data...)
+			return
+		}
+		d.Warnings = append(d.Warnings, fmt.Sprintf("PID %d: PES packet without PTS dropped", s.PID))
+		return
+	}
+	dts := s.unwrapDTS(pes.dts)
+	cto := int32(unwrapTimestamp(pes.pts, pes.dts) - pes.dts)
+	// The data is copied since the PES buffer is reused
+	data := make([]byte, len(pes.data))
+	copy(data, pes.data)
+	switch s.Codec {
+	case CodecAVC, CodecHEVC:
+		err = s.addVideoSample(data, dts, cto)
+	case CodecAAC, CodecAC3, CodecEAC3:
+		err = s.addAudioSamples(data, dts)
+	}
+	if err != nil {
+		d.Warnings = append(d.Warnings, fmt.Sprintf("PID %d: %s", s.PID, err))
+	}
+}
+
+// unwrapDTS - DTS unwrapped from 33 bits, continuing after the last sample if there was a discontinuity
+func (s *Stream) unwrapDTS(dts int64) int64 {
+	if !s.started {
+		s.started = true
+		s.discontinuity = false
+		s.lastDTS = dts
+		return dts
+	}
+	unwrapped := unwrapTimestamp(dts, s.lastDTS-s.tsOffset) + s.tsOffset
+	if s.discontinuity {
+		s.discontinuity = false
+		expected := s.lastSampleTime + int64(s.nominalDur)
+		s.tsOffset += expected - unwrapped
+		unwrapped = expected
+	}
+	s.lastDTS = unwrapped
+	return unwrapped
+}
+
+// addSample - add sample and set the duration of the previous sample
+func (s *Stream) addSample(fs mp4.FullSample) {
+	if n := len(s.Samples); n > 0 {
+		prev := &s.Samples[n-1]
+		if fs.DecodeTime > prev.DecodeTime {
+			prev.Dur = uint32(fs.DecodeTime - prev.DecodeTime)
+			if s.Codec == CodecAVC || s.Codec == CodecHEVC {
+				s.nominalDur = prev.Dur
+			}
+		}
+	}
+	s.Samples = append(s.Samples, fs)
+	s.lastSampleTime = int64(fs.DecodeTime)
+}
+
+// addVideoSample - add a PES packet with one access unit as a sample
+func (s *Stream) addVideoSample(data []byte, dts int64, cto int32) error {
+	sample := avc.ConvertByteStreamToNaluSample(data)
+	isSync := false
+	switch s.Codec {
+	case CodecAVC:
+		isSync = avc.IsIDRSample(sample)
+		sps, pps := avc.GetParameterSets(sample)
+		s.SPSs = appendUnique(s.SPSs, sps...)
+		s.PPSs = appendUnique(s.PPSs, pps...)
+	case CodecHEVC:
+		for _, naluType := range hevc.FindNaluTypesUpToFirstVideoNalu(sample) {
+			if naluType >= hevc.NALU_BLA_W_LP && naluType <= hevc.NALU_CRA {
+				isSync = true
+			}
+		}
+		vps, sps, pps := hevc.GetParameterSets(sample)
+		s.VPSs = appendUnique(s.VPSs, vps...)
+		s.SPSs = appendUnique(s.SPSs, sps...)
+		s.PPSs = appendUnique(s.PPSs, pps...)
+	}
+	flags := mp4.NonSyncSampleFlags
+	if isSync {
+		flags = mp4.SyncSampleFlags
+	}
+	s.addSample(mp4.FullSample{
+		Sample:     mp4.NewSample(flags, s.nominalDur, uint32(len(sample)), cto),
+		DecodeTime: uint64(dts),
+		Data:       sample,
+	})
+	return nil
+}
+
+// addAudioSamples - add the complete audio frames of a PES packet as samples with interpolated timestamps.
+// Audio frames may continue in the next PES packet, so a truncated frame at the end is kept and completed
+// by the start of the next PES packet. The PES timestamp belongs to the first frame starting in the packet.
+func (s *Stream) addAudioSamples(data []byte, dts int64) error {
+	continued := len(s.audioRest) > 0
+	if continued {
+		data = append(s.audioRest, data...)
+	}
+	s.audioRest = nil
+	var n int
+	if s.Codec == CodecAAC {
+		n = completeADTSLength(data)
+	} else {
+		n = completeAC3Length(data)
+	}
+	if n == 0 {
+		if !continued {
+			s.audioRestTime = dts
+		}
+		s.audioRest = data
+		return nil
+	}
+	samples, freq, err := s.importAudio(data[:n])
+	if err != nil {
+		return err
+	}
+	// Round to closest 90 kHz tick
+	ticks := func(t uint64) int64 {
+		return (int64(t)*Timescale + freq/2) / freq
+	}
+	first := 0 // First sample starting in this PES packet
+	if continued {
+		first = 1
+	}
+	last := samples[len(samples)-1]
+	firstTime := last.DecodeTime + uint64(last.Dur)
+	if first < len(samples) {
+		firstTime = samples[first].DecodeTime
+	}
+	for i, fs := range samples {
+		if i < first {
+			fs.DecodeTime = uint64(s.audioRestTime)
+		} else {
+			fs.DecodeTime = uint64(dts + ticks(fs.DecodeTime-firstTime))
+		}
+		fs.Dur = uint32(ticks(uint64(fs.Dur)))
+		s.nominalDur = fs.Dur
+		s.addSample(fs)
+	}
+	if n < len(data) {
+		s.audioRest = data[n:]
+		s.audioRestTime = dts + ticks(last.DecodeTime+uint64(last.Dur)-firstTime)
+	}
+	return nil
+}
+
+// importAudio - split complete ADTS frames or AC-3 syncframes into samples in the sampling frequency timescale
+func (s *Stream) importAudio(data []byte) ([]mp4.FullSample, int64, error) {
+	if s.Codec == CodecAAC {
+		track, err := adts.Import(data, adts.Config{})
+		if err != nil {
+			return nil, 0, err
+		}
+		if s.AudioConfig == nil {
+			s.AudioConfig = track.Config
+		}
+		return track.Samples, int64(track.Config.SamplingFrequency), nil
+	}
+	track, err := ac3.Import(data, ac3.Config{})
+	if err != nil {
+		return nil, 0, err
+	}
+	if (track.Dec3 != nil) != (s.Codec == CodecEAC3) {
+		return nil, 0, fmt.Errorf("got %s syncframes", map[bool]string{true: "E-AC-3", false: "AC-3"}[track.Dec3 != nil])
+	}
+	if s.Dac3 == nil && s.Dec3 == nil {
+		s.Dac3, s.Dec3 = track.Dac3, track.Dec3
+	}
+	return track.Samples, int64(track.SampleRate), nil
+}
+
+// completeADTSLength - length of the complete ADTS frames at the start of data.
+// The rest is a truncated frame, or all of data if a header cannot be decoded.
+func completeADTSLength(data []byte) int {
+	const maxHeaderLength = 9
+	pos := 0
+	for pos < len(data) {
+		hdr, offset, err := aac.DecodeADTSHeader(bytes.NewReader(data[pos:]))
+		if err != nil {
+			if len(data)-pos < maxHeaderLength {
+				return pos
+			}
+			return len(data) // Let the import report the error
+		}
+		end := pos + offset + hdr.HeaderLength() + int(hdr.PayloadLength)
+		if end > len(data) {
+			return pos
+		}
+		pos = end
+	}
+	return pos
+}
+
+// completeAC3Length - length of the complete AC-3 or E-AC-3 samples at the start of data.
+// The rest is a sample with a truncated syncframe, or all of data if a syncframe cannot be decoded.
+func completeAC3Length(data []byte) int {
+	const minHeaderLength = 8
+	pos, sampleStart, sampleBlocks := 0, 0, 0
+	for pos < len(data) {
+		f, err := ac3.DecodeSyncFrame(data[pos:])
+		if err != nil {
+			if len(data)-pos < minHeaderLength {
+				return sampleStart
+			}
+			return len(data) // Let the import report the error
+		}
+		startsSample := f.IsIndependent() && f.SubstreamID == 0
+		if startsSample && sampleBlocks == 6 {
+			sampleStart, sampleBlocks = pos, 0
+		}
+		if pos+f.Size > len(data) {
+			return sampleStart
+		}
+		if startsSample {
+			sampleBlocks += f.NumBlocks
+		}
+		pos += f.Size
+	}
+	return pos
+}
+
+func appendUnique(nalus [][]byte, newNalus ...[]byte) [][]byte {
+	for _, nalu := range newNalus {
+		found := false
+		for _, n := range nalus {
+			if bytes.Equal(n, nalu) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			nalus = append(nalus, nalu)
+		}
+	}
+	return nalus
+}
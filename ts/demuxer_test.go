@@ -0,0 +1,388 @@
+package ts
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/jaypadia-frame/mp4ff/aac"
+	"github.com/jaypadia-frame/mp4ff/avc"
+	"github.com/jaypadia-frame/mp4ff/mp4"
+)
+
+const (
+	testPMTPID    = 0x1000
+	testVideoPID  = 0x100
+	testAACPID    = 0x101
+	testAC3PID    = 0x102
+	testSCTE35PID = 0x1ff
+)
+
+// testMuxer - packetizes sections and PES packets for tests
+type testMuxer struct {
+	ccs     map[uint16]byte
+	packets [][]byte
+}
+
+func newTestMuxer() *testMuxer {
+	return &testMuxer{ccs: make(map[uint16]byte)}
+}
+
+// write - split payload into packets, with adaptation field stuffing in the last packet
+func (m *testMuxer) write(pid uint16, payload []byte, discontinuity bool) {
+	for first := true; first || len(payload) > 0; first = false {
+		pkt := make([]byte, 4, PacketSize)
+		pkt[0] = SyncByte
+		pkt[1] = byte(pid >> 8)
+		if first {
+			pkt[1] |= 0x40
+		}
+		pkt[2] = byte(pid)
+		pkt[3] = 0x10 | m.ccs[pid]
+		m.ccs[pid] = (m.ccs[pid] + 1) & 0x0f
+		n := len(payload)
+		if n > PacketSize-4 {
+			n = PacketSize - 4
+		}
+		if n < PacketSize-4 || (first && discontinuity) {
+			if n > PacketSize-6 {
+				n = PacketSize - 6
+			}
+			pkt[3] |= 0x20
+			afLen := PacketSize - 5 - n
+			pkt = append(pkt, byte(afLen))
+			if afLen > 0 {
+				flags := byte(0)
+				if first && discontinuity {
+					flags = 0x80
+				}
+				pkt = append(pkt, flags)
+				for i := 1; i < afLen; i++ {
+					pkt = append(pkt, 0xff)
+				}
+			}
+		}
+		pkt = append(pkt, payload[:n]...)
+		payload = payload[n:]
+		m.packets = append(m.packets, pkt)
+	}
+}
+
+func (m *testMuxer) writeSection(pid uint16, section []byte) {
+	m.write(pid, append([]byte{0}, section...), false)
+}
+
+func (m *testMuxer) bytes() []byte {
+	var out []byte
+	for _, p := range m.packets {
+		out = append(out, p...)
+	}
+	return out
+}
+
+func pmtSection() []byte {
+	p := pmt{programNumber: 1, pcrPID: testVideoPID, streams: []pmtStream{
+		{streamType: StreamTypeAVC, pid: testVideoPID},
+		{streamType: StreamTypeAAC, pid: testAACPID},
+		{streamType: StreamTypePrivatePES, pid: testAC3PID, descriptors: []descriptor{{tag: 0x6a, data: []byte{0}}}},
+		{streamType: StreamTypeSCTE35, pid: testSCTE35PID},
+	}}
+	return p.encode()
+}
+
+// timeSignalSection - splice_info_section with a time_signal command
+func timeSignalSection(ptsTime int64) []byte {
+	s := []byte{tableIDSCTE35, 0x30, 0, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xf0, 5, 0x06,
+		0xfe | byte(ptsTime>>32), byte(ptsTime >> 24), byte(ptsTime >> 16), byte(ptsTime >> 8), byte(ptsTime), 0, 0}
+	sectionLength := len(s) - 3 + 4
+	s[1] |= byte(sectionLength >> 8)
+	s[2] = byte(sectionLength)
+	return appendCRC(s)
+}
+
+// createPES - PES packet with PTS, and DTS if different. Video packets have PES_packet_length 0
+func createPES(streamID byte, pts, dts int64, data []byte) []byte {
+	return append(createPESHeader(streamID, pts, dts, len(data), streamID < 0xe0), data...)
+}
+
+// readVideoSamples - AVC samples with in-band parameter sets from testdata
+func readVideoSamples(t *testing.T) []mp4.FullSample {
+	t.Helper()
+	fh, err := os.Open("../mp4/testdata/1.m4s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fh.Close()
+	f, err := mp4.DecodeFile(fh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var samples []mp4.FullSample
+	for _, seg := range f.Segments {
+		for _, frag := range seg.Fragments {
+			fs, err := frag.GetFullSamples(nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			samples = append(samples, fs...)
+		}
+	}
+	return samples
+}
+
+// ac3Frame48k - 512-byte AC-3 syncframe at 48 kHz, 128 kbit/s, 2/0 channels
+func ac3Frame48k() []byte {
+	frame := make([]byte, 512)
+	copy(frame, []byte{0x0b, 0x77, 0, 0, 0x10, 0x40, 0x40})
+	return frame
+}
+
+func adtsFrames(t *testing.T, nr, size int) []byte {
+	t.Helper()
+	var data []byte
+	for i := 0; i < nr; i++ {
+		hdr, err := aac.NewADTSHeader(48000, 2, aac.AAClc, uint16(size))
+		if err != nil {
+			t.Fatal(err)
+		}
+		data = append(data, hdr.Encode()...)
+		data = append(data, bytes.Repeat([]byte{byte(i)}, size)...)
+	}
+	return data
+}
+
+func TestDemux(t *testing.T) {
+	video := readVideoSamples(t)
+	// Start close to the 33-bit wrap, so that timestamps wrap within the stream
+	start := int64(ptsWrap - 3000*10)
+	m := newTestMuxer()
+	m.writeSection(PIDPAT, createPAT(1, 1, testPMTPID))
+	m.writeSection(testPMTPID, pmtSection())
+	m.writeSection(testSCTE35PID, timeSignalSection(start+90000))
+	for i, s := range video {
+		dts := start + int64(s.DecodeTime-video[0].DecodeTime)
+		pts := dts + int64(s.CompositionTimeOffset)
+		data := make([]byte, len(s.Data))
+		copy(data, s.Data) // ConvertSampleToByteStream overwrites the length fields
+		m.write(testVideoPID, createPES(0xe0, pts, dts, avc.ConvertSampleToByteStream(data)), false)
+		if i%4 == 0 {
+			// 4 AAC frames of 1920 ticks and 1 AC-3 frame of 2880 ticks per 12000 ticks
+			aacPTS := start + int64(i/4)*4*1920
+			m.write(testAACPID, createPES(0xc0, aacPTS, aacPTS, adtsFrames(t, 4, 100)), false)
+			ac3PTS := start + int64(i/4)*2880
+			m.write(testAC3PID, createPES(0xbd, ac3PTS, ac3PTS, ac3Frame48k()), false)
+		}
+	}
+
+	d := NewDemuxer()
+	if err := d.Demux(bytes.NewReader(m.bytes())); err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Warnings) > 0 || d.ContinuityErrors > 0 {
+		t.Errorf("got warnings %v and %d continuity errors", d.Warnings, d.ContinuityErrors)
+	}
+	if len(d.Streams) != 3 {
+		t.Fatalf("got %d streams instead of 3", len(d.Streams))
+	}
+	vs, as, ac3s := d.Streams[0], d.Streams[1], d.Streams[2]
+	if vs.Codec != CodecAVC || as.Codec != CodecAAC || ac3s.Codec != CodecAC3 {
+		t.Errorf("got codecs %s %s %s", vs.Codec, as.Codec, ac3s.Codec)
+	}
+	if len(vs.Samples) != len(video) {
+		t.Fatalf("got %d video samples instead of %d", len(vs.Samples), len(video))
+	}
+	for i, s := range vs.Samples {
+		o := video[i]
+		wantTime := uint64(start) + o.DecodeTime - video[0].DecodeTime
+		if s.DecodeTime != wantTime || s.Dur != o.Dur || s.CompositionTimeOffset != o.CompositionTimeOffset {
+			t.Errorf("video sample %d: got time %d dur %d cto %d instead of %d %d %d", i+1,
+				s.DecodeTime, s.Dur, s.CompositionTimeOffset, wantTime, o.Dur, o.CompositionTimeOffset)
+		}
+		if s.IsSync() != o.IsSync() {
+			t.Errorf("video sample %d: got sync %t", i+1, s.IsSync())
+		}
+		if !bytes.Equal(s.Data, o.Data) {
+			t.Errorf("video sample %d: data differs", i+1)
+		}
+	}
+	if len(vs.SPSs) != 1 || len(vs.PPSs) != 1 {
+		t.Errorf("got %d SPS and %d PPS", len(vs.SPSs), len(vs.PPSs))
+	}
+	nrAudioPES := (len(video) + 3) / 4
+	if len(as.Samples) != 4*nrAudioPES {
+		t.Fatalf("got %d AAC samples instead of %d", len(as.Samples), 4*nrAudioPES)
+	}
+	for i, s := range as.Samples {
+		if s.DecodeTime != uint64(start)+uint64(i)*1920 || s.Dur != 1920 || len(s.Data) != 100 || s.Data[0] != byte(i%4) {
+			t.Errorf("AAC sample %d: got time %d dur %d size %d", i+1, s.DecodeTime, s.Dur, len(s.Data))
+		}
+	}
+	if as.AudioConfig.SamplingFrequency != 48000 || as.AudioConfig.ChannelConfiguration != 2 {
+		t.Errorf("got AAC config %+v", as.AudioConfig)
+	}
+	if len(ac3s.Samples) != nrAudioPES || ac3s.Samples[1].DecodeTime != uint64(start)+2880 ||
+		ac3s.Samples[1].Dur != 2880 || len(ac3s.Samples[1].Data) != 512 {
+		t.Errorf("got %d AC-3 samples, second %+v", len(ac3s.Samples), ac3s.Samples[1].Sample)
+	}
+	if ac3s.Dac3 == nil || ac3s.Dac3.BitRateCode != 8 || ac3s.Dac3.ACMod != 2 || ac3s.Dac3.BSID != 8 {
+		t.Errorf("got dac3 %+v", ac3s.Dac3)
+	}
+	if len(d.SCTE35) != 1 {
+		t.Fatalf("got %d SCTE-35 sections instead of 1", len(d.SCTE35))
+	}
+	if sc := d.SCTE35[0]; sc.PID != testSCTE35PID || sc.CommandType != 0x06 || sc.SpliceTime != (start+90000)%ptsWrap {
+		t.Errorf("got SCTE-35 section %+v", sc)
+	}
+
+	init, err := d.CreateInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	seg, err := d.CreateMediaSegment(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := init.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := seg.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := mp4.DecodeFile(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Init.Moov.Traks) != 3 {
+		t.Errorf("got %d tracks instead of 3", len(decoded.Init.Moov.Traks))
+	}
+	frag := decoded.Segments[0].Fragments[0]
+	samples, err := frag.GetFullSamples(decoded.Init.Moov.Mvex.Trexs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != len(video) || samples[0].DecodeTime != uint64(start) {
+		t.Errorf("got %d video samples starting at %d", len(samples), samples[0].DecodeTime)
+	}
+}
+
+func TestContinuity(t *testing.T) {
+	const nrFrames = 10
+	create := func() *testMuxer {
+		m := newTestMuxer()
+		m.writeSection(PIDPAT, createPAT(1, 1, testPMTPID))
+		m.writeSection(testPMTPID, pmtSection())
+		for i := 0; i < nrFrames; i++ {
+			pts := int64(1000 + i*1920)
+			if i >= nrFrames/2 {
+				pts += 1 << 30 // New timebase
+			}
+			m.write(testAACPID, createPES(0xc0, pts, pts, adtsFrames(t, 1, 300)), i == nrFrames/2)
+		}
+		return m
+	}
+	testCases := []struct {
+		desc          string
+		modify        func(m *testMuxer)
+		wantSamples   int
+		wantCCErrors  int
+		wantLastStart uint64
+	}{
+		{"discontinuity", func(m *testMuxer) {}, nrFrames, 0, 1000 + (nrFrames-1)*1920},
+		{"duplicate packet", func(m *testMuxer) {
+			// First packet of third PES
+			dup := m.packets[6]
+			m.packets = append(m.packets[:7], append([][]byte{dup}, m.packets[7:]...)...)
+		}, nrFrames, 0, 1000 + (nrFrames-1)*1920},
+		{"lost packet", func(m *testMuxer) {
+			m.packets = append(m.packets[:6], m.packets[7:]...)
+		}, nrFrames - 1, 1, 1000 + (nrFrames-1)*1920},
+	}
+	for _, tc := range testCases {
+		m := create()
+		tc.modify(m)
+		d := NewDemuxer()
+		if err := d.Demux(bytes.NewReader(m.bytes())); err != nil {
+			t.Fatal(err)
+		}
+		s := d.Streams[1]
+		if len(s.Samples) != tc.wantSamples || d.ContinuityErrors != tc.wantCCErrors {
+			t.Errorf("%s: got %d samples and %d continuity errors", tc.desc, len(s.Samples), d.ContinuityErrors)
+			continue
+		}
+		if last := s.Samples[len(s.Samples)-1]; last.DecodeTime != tc.wantLastStart || last.Dur != 1920 {
+			t.Errorf("%s: got last sample at %d dur %d", tc.desc, last.DecodeTime, last.Dur)
+		}
+	}
+}
+
+func TestAudioFramesAcrossPES(t *testing.T) {
+	const start = 1000
+	aacData := adtsFrames(t, 6, 100)
+	frameSize := len(aacData) / 6
+	ac3Data := append(ac3Frame48k(), ac3Frame48k()...)
+	m := newTestMuxer()
+	m.writeSection(PIDPAT, createPAT(1, 1, testPMTPID))
+	m.writeSection(testPMTPID, pmtSection())
+	// PES packets of 1.5 ADTS frames. The PTS is that of the first frame starting in the packet
+	for i, firstFrame := range []int{0, 2, 3, 5} {
+		pts := int64(start + firstFrame*1920)
+		chunk := aacData[i*frameSize*3/2 : (i+1)*frameSize*3/2]
+		m.write(testAACPID, createPES(0xc0, pts, pts, chunk), false)
+	}
+	// AC-3 syncframes split at 100, 700 and 800 bytes. No syncframe starts in the last two PES packets,
+	// which have no PTS
+	m.write(testAC3PID, createPES(0xbd, start, start, ac3Data[:100]), false)
+	m.write(testAC3PID, createPES(0xbd, start+2880, start+2880, ac3Data[100:700]), false)
+	m.write(testAC3PID, append([]byte{0, 0, 1, 0xbd, 0, 103, 0x80, 0, 0}, ac3Data[700:800]...), false)
+	m.write(testAC3PID, append([]byte{0, 0, 1, 0xbd, 0, 227, 0x80, 0, 0}, ac3Data[800:]...), false)
+	badPacket := make([]byte, PacketSize)
+	m.packets = append(m.packets[:4], append([][]byte{badPacket}, m.packets[4:]...)...)
+
+	d := NewDemuxer()
+	if err := d.Demux(bytes.NewReader(m.bytes())); err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Warnings) != 1 || d.Warnings[0] != "packet 4: sync byte 0x00 instead of 0x47" {
+		t.Errorf("got warnings %v", d.Warnings)
+	}
+	as, ac3s := d.Streams[1], d.Streams[2]
+	if len(as.Samples) != 6 {
+		t.Fatalf("got %d AAC samples instead of 6", len(as.Samples))
+	}
+	for i, s := range as.Samples {
+		if s.DecodeTime != uint64(start+i*1920) || s.Dur != 1920 || len(s.Data) != 100 || s.Data[99] != byte(i) {
+			t.Errorf("AAC sample %d: got time %d dur %d size %d", i+1, s.DecodeTime, s.Dur, len(s.Data))
+		}
+	}
+	if len(ac3s.Samples) != 2 || ac3s.Samples[0].DecodeTime != start || ac3s.Samples[1].DecodeTime != start+2880 ||
+		len(ac3s.Samples[1].Data) != 512 {
+		t.Errorf("got %d AC-3 samples", len(ac3s.Samples))
+	}
+
+	// A truncated frame at the end is dropped with a warning
+	m.write(testAACPID, createPES(0xc0, start+6*1920, start+6*1920, aacData[:frameSize/2]), false)
+	d = NewDemuxer()
+	if err := d.Demux(bytes.NewReader(m.bytes())); err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Warnings) != 2 || len(d.Streams[1].Samples) != 6 {
+		t.Errorf("got warnings %v and %d AAC samples", d.Warnings, len(d.Streams[1].Samples))
+	}
+}
+
+func TestUnwrapTimestamp(t *testing.T) {
+	testCases := []struct {
+		ts, ref, want int64
+	}{
+		{100, 200, 100},
+		{100, ptsWrap - 100, ptsWrap + 100},
+		{ptsWrap - 100, 100, -100},
+		{100, 3*ptsWrap + 50, 3*ptsWrap + 100},
+	}
+	for _, tc := range testCases {
+		if got := unwrapTimestamp(tc.ts, tc.ref); got != tc.want {
+			t.Errorf("unwrapTimestamp(%d, %d) = %d instead of %d", tc.ts, tc.ref, got, tc.want)
+		}
+	}
+}
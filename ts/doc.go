@@ -0,0 +1,22 @@
+/*
+Package ts - demultiplex MPEG-2 Transport Streams into mp4 samples, and multiplex mp4 tracks into Transport Streams.
+
+The Demuxer parses PAT and PMT sections to find the elementary streams, and reassembles PES packets
+per PID with their PTS and DTS. Continuity counters are checked, and incomplete PES packets are dropped.
+The timestamps are unwrapped from 33 bits, and continue across discontinuities signalled in the adaptation field.
+
+H.264/AVC and H.265/HEVC PES packets become samples with 4-byte NAL unit lengths, and the parameter sets are
+collected for the sample descriptions. AAC in ADTS is split into one sample per frame, and AC-3/E-AC-3
+syncframes into samples of 1536 audio samples by the ac3 package. Audio frames may continue in the next PES packet.
+Broken packets and PES packets are dropped and reported in the Warnings of the Demuxer.
+All samples have 90 kHz timing, and can be used to create an init segment and media segments.
+
+SCTE-35 splice information sections on their own PIDs are collected as they are.
+
+The Muxer writes samples of mp4 tracks as PES packets in a single program, with PAT and PMT before the first sample
+and before every video sync sample. The PCR is carried on the video PID. AVC and HEVC samples are converted to
+Annex B byte streams with access unit delimiters, and the parameter sets of avc1 and hvc1 sample descriptions are
+inserted before sync samples. AAC samples get ADTS headers. WriteSegments splits a file into segments that start
+with PAT, PMT, and a video sync sample, as needed for HLS.
+*/
+package ts
@@ -0,0 +1,178 @@
+package ts
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/jaypadia-frame/mp4ff/mp4"
+)
+
+// MediaType - video or audio
+func (s *Stream) MediaType() string {
+	switch s.Codec {
+	case CodecAVC, CodecHEVC:
+		return "video"
+	}
+	return "audio"
+}
+
+// SetDescriptor - set the sample description of trak from the stream configuration
+func (s *Stream) SetDescriptor(trak *mp4.TrakBox) error {
+	switch s.Codec {
+	case CodecAVC:
+		if len(s.SPSs) == 0 || len(s.PPSs) == 0 {
+			return fmt.Errorf("PID %d: no AVC parameter sets", s.PID)
+		}
+		return trak.SetAVCDescriptor("avc1", s.SPSs, s.PPSs, true)
+	case CodecHEVC:
+		if len(s.VPSs) == 0 || len(s.SPSs) == 0 || len(s.PPSs) == 0 {
+			return fmt.Errorf("PID %d: no HEVC parameter sets", s.PID)
+		}
+		return trak.SetHEVCDescriptor("hvc1", s.VPSs, s.SPSs, s.PPSs, true)
+	case CodecAAC:
+		if s.AudioConfig == nil {
+			return fmt.Errorf("PID %d: no AAC configuration", s.PID)
+		}
+		return trak.SetAACDescriptorFromConfig(s.AudioConfig)
+	case CodecAC3:
+		if s.Dac3 == nil {
+			return fmt.Errorf("PID %d: no AC-3 configuration", s.PID)
+		}
+		return trak.SetAC3Descriptor(s.Dac3)
+	case CodecEAC3:
+		if s.Dec3 == nil {
+			return fmt.Errorf("PID %d: no E-AC-3 configuration", s.PID)
+		}
+		return trak.SetEC3Descriptor(s.Dec3)
+	}
+	return fmt.Errorf("PID %d: unknown codec %q", s.PID, s.Codec)
+}
+
+// TrackStreams - streams with samples. Track i+1 in CreateInit and CreateMediaSegment is stream i
+func (d *Demuxer) TrackStreams() []*Stream {
+	var streams []*Stream
+	for _, s := range d.Streams {
+		if len(s.Samples) > 0 {
+			streams = append(streams, s)
+		}
+	}
+	return streams
+}
+
+// CreateInit - create an init segment with one track per stream with samples, and 90 kHz timescale
+func (d *Demuxer) CreateInit() (*mp4.InitSegment, error) {
+	streams := d.TrackStreams()
+	if len(streams) == 0 {
+		return nil, fmt.Errorf("no streams with samples")
+	}
+	init := mp4.CreateEmptyInit()
+	for _, s := range streams {
+		init.AddEmptyTrack(Timescale, s.MediaType(), "und")
+		if err := s.SetDescriptor(init.Moov.Traks[len(init.Moov.Traks)-1]); err != nil {
+			return nil, err
+		}
+	}
+	return init, nil
+}
+
+// CreateMediaSegment - create a media segment with one fragment with the samples of all track streams
+func (d *Demuxer) CreateMediaSegment(seqNr uint32) (*mp4.MediaSegment, error) {
+	streams := d.TrackStreams()
+	trackIDs := make([]uint32, len(streams))
+	for i := range streams {
+		trackIDs[i] = uint32(i + 1)
+	}
+	frag, err := mp4.CreateMultiTrackFragment(seqNr, trackIDs)
+	if err != nil {
+		return nil, err
+	}
+	for i, s := range streams {
+		for _, fs := range s.Samples {
+			if err := frag.AddFullSampleToTrack(fs, trackIDs[i]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	seg := mp4.NewMediaSegment()
+	seg.AddFragment(frag)
+	return seg, nil
+}
+
+// ClearSamples - remove all samples but keep stream configurations and timing, so that the
+// next media segment can be demultiplexed into the same streams
+func (d *Demuxer) ClearSamples() {
+	for _, s := range d.Streams {
+		s.Samples = nil
+	}
+}
+
+// muxSample - sample with its track and 90 kHz decode time
+type muxSample struct {
+	trackID uint32
+	dts     int64
+	sample  mp4.FullSample
+}
+
+// WriteFile - multiplex all AVC, HEVC, AAC, AC-3, and E-AC-3 tracks of a progressive or fragmented file into w
+func WriteFile(w io.Writer, f *mp4.File) error {
+	return WriteSegments(f, 0, func(nr int) (io.Writer, error) { return w, nil })
+}
+
+// WriteSegments - multiplex all AVC, HEVC, AAC, AC-3, and E-AC-3 tracks of f into segments, as for HLS.
+// Every segment starts with PAT and PMT, and with a sync sample of the video track if there is one.
+// A new segment starts at the first video sync sample (or audio sample if there is no video) after
+// segmentDur (90 kHz) has passed since the start of the segment. If segmentDur is 0, there is one segment.
+// newSegment is called with segment numbers starting at 1.
+func WriteSegments(f *mp4.File, segmentDur int64, newSegment func(nr int) (io.Writer, error)) error {
+	moov := f.Moov
+	if f.Init != nil {
+		moov = f.Init.Moov
+	}
+	if moov == nil {
+		return fmt.Errorf("no moov box")
+	}
+	m := NewMuxer(nil)
+	var samples []muxSample
+	for _, trak := range moov.Traks {
+		if trackCodec(trak) == "" {
+			continue
+		}
+		if err := m.AddTrack(trak); err != nil {
+			return err
+		}
+		ms := m.streams[len(m.streams)-1]
+		trackSamples, err := f.GetTrackSamples(trak.Tkhd.TrackID, nil)
+		if err != nil {
+			return fmt.Errorf("track %d: %w", ms.trackID, err)
+		}
+		for _, s := range trackSamples {
+			samples = append(samples, muxSample{ms.trackID, ms.ticks(int64(s.DecodeTime)), s})
+		}
+	}
+	if len(m.streams) == 0 {
+		return fmt.Errorf("no supported tracks")
+	}
+	sort.SliceStable(samples, func(i, j int) bool { return samples[i].dts < samples[j].dts })
+
+	segNr := 0
+	var segStart int64
+	for _, s := range samples {
+		isSegmentStart := s.trackID == m.pcrStream.trackID && (!m.pcrStream.isVideo() || s.sample.IsSync())
+		if segNr == 0 || (segmentDur > 0 && isSegmentStart && s.dts-segStart >= segmentDur) {
+			segNr++
+			segStart = s.dts
+			w, err := newSegment(segNr)
+			if err != nil {
+				return err
+			}
+			if err := m.NewSegment(w); err != nil {
+				return err
+			}
+		}
+		if err := m.WriteSample(s.trackID, s.sample); err != nil {
+			return err
+		}
+	}
+	return nil
+}
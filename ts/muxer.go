@@ -0,0 +1,266 @@
+package ts
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/jaypadia-frame/mp4ff/aac"
+	"github.com/jaypadia-frame/mp4ff/adts"
+	"github.com/jaypadia-frame/mp4ff/avc"
+	"github.com/jaypadia-frame/mp4ff/hevc"
+	"github.com/jaypadia-frame/mp4ff/mp4"
+)
+
+const (
+	// PIDPMT - PID of the PMT written by the Muxer
+	PIDPMT = 0x1000
+	// PIDFirstStream - PID of the first elementary stream written by the Muxer
+	PIDFirstStream = 0x0100
+	// ProgramNumber - program_number of the program written by the Muxer
+	ProgramNumber = 1
+)
+
+var (
+	avcAUD  = []byte{0x09, 0xf0}       // primary_pic_type 7 (any slice type)
+	hevcAUD = []byte{0x46, 0x01, 0x50} // pic_type 2 (any slice type)
+)
+
+// muxStream - elementary stream created from an mp4 track
+type muxStream struct {
+	trackID    uint32
+	timescale  uint32
+	pid        uint16
+	streamType byte
+	streamID   byte
+	codec      Codec
+	cc         byte
+	psNalus    [][]byte // Parameter sets inserted before sync samples. Empty for avc3 and hev1
+	lengthSize int      // NAL unit length size of video samples
+	asc        *aac.AudioSpecificConfig
+}
+
+// Muxer - multiplex samples of mp4 tracks into a transport stream with a single program.
+// All tracks must be added before the first sample is written, and samples should be written
+// in decode time order across the tracks.
+type Muxer struct {
+	TimestampOffset int64 // Added to all 90 kHz timestamps. NewMuxer sets 126000 (1.4s)
+	PCRDelay        int64 // The PCR is DTS - PCRDelay (90 kHz). NewMuxer sets 63000 (0.7s)
+	w               io.Writer
+	streams         []*muxStream
+	pcrStream       *muxStream
+	patCC           byte
+	pmtCC           byte
+	started         bool
+	psiWritten      bool // PAT and PMT were written after the last PES packet
+	buf             []byte
+}
+
+// NewMuxer - create muxer writing to w
+func NewMuxer(w io.Writer) *Muxer {
+	return &Muxer{TimestampOffset: 126000, PCRDelay: 63000, w: w}
+}
+
+// trackCodec - codec of the sample description of trak, or "" if not supported
+func trackCodec(trak *mp4.TrakBox) Codec {
+	stsd := trak.Mdia.Minf.Stbl.Stsd
+	switch {
+	case stsd.AvcX != nil:
+		return CodecAVC
+	case stsd.HvcX != nil:
+		return CodecHEVC
+	case stsd.Mp4a != nil:
+		return CodecAAC
+	case stsd.AC3 != nil:
+		return CodecAC3
+	case stsd.EC3 != nil:
+		return CodecEAC3
+	}
+	return ""
+}
+
+// AddTrack - add an elementary stream for trak. The PCR is carried on the first video stream,
+// or on the first stream if there is no video.
+func (m *Muxer) AddTrack(trak *mp4.TrakBox) error {
+	if m.started {
+		return fmt.Errorf("tracks must be added before samples are written")
+	}
+	ms := &muxStream{
+		trackID:   trak.Tkhd.TrackID,
+		timescale: trak.Mdia.Mdhd.Timescale,
+		pid:       PIDFirstStream + uint16(len(m.streams)),
+		codec:     trackCodec(trak),
+	}
+	var nrVideo, nrAudio byte
+	for _, s := range m.streams {
+		switch s.codec {
+		case CodecAVC, CodecHEVC:
+			nrVideo++
+		case CodecAAC:
+			nrAudio++
+		}
+	}
+	stsd := trak.Mdia.Minf.Stbl.Stsd
+	switch ms.codec {
+	case CodecAVC:
+		ms.streamType, ms.streamID = StreamTypeAVC, 0xe0+nrVideo
+		ms.lengthSize = mp4.NaluLengthSize(stsd.AvcX)
+		if stsd.AvcX.Type() == "avc1" && stsd.AvcX.AvcC != nil {
+			ms.psNalus = append(ms.psNalus, stsd.AvcX.AvcC.SPSnalus...)
+			ms.psNalus = append(ms.psNalus, stsd.AvcX.AvcC.PPSnalus...)
+		}
+	case CodecHEVC:
+		ms.streamType, ms.streamID = StreamTypeHEVC, 0xe0+nrVideo
+		ms.lengthSize = mp4.NaluLengthSize(stsd.HvcX)
+		if stsd.HvcX.Type() == "hvc1" && stsd.HvcX.HvcC != nil {
+			for _, naluType := range []hevc.NaluType{hevc.NALU_VPS, hevc.NALU_SPS, hevc.NALU_PPS} {
+				ms.psNalus = append(ms.psNalus, stsd.HvcX.HvcC.GetNalusForType(naluType)...)
+			}
+		}
+	case CodecAAC:
+		ms.streamType, ms.streamID = StreamTypeAAC, 0xc0+nrAudio
+		asc, err := adts.GetAudioSpecificConfig(trak)
+		if err != nil {
+			return fmt.Errorf("track %d: %w", ms.trackID, err)
+		}
+		ms.asc = asc
+	case CodecAC3:
+		ms.streamType, ms.streamID = StreamTypeAC3, 0xbd
+	case CodecEAC3:
+		ms.streamType, ms.streamID = StreamTypeEAC3, 0xbd
+	default:
+		return fmt.Errorf("track %d: sample description not supported", ms.trackID)
+	}
+	if ms.timescale == 0 {
+		return fmt.Errorf("track %d: timescale 0", ms.trackID)
+	}
+	m.streams = append(m.streams, ms)
+	if m.pcrStream == nil || (ms.isVideo() && !m.pcrStream.isVideo()) {
+		m.pcrStream = ms
+	}
+	return nil
+}
+
+func (ms *muxStream) isVideo() bool {
+	return ms.codec == CodecAVC || ms.codec == CodecHEVC
+}
+
+// ticks - time in track timescale converted to 90 kHz
+func (ms *muxStream) ticks(t int64) int64 {
+	ts := int64(ms.timescale)
+	if t < 0 {
+		return -((-t*Timescale + ts/2) / ts)
+	}
+	return (t*Timescale + ts/2) / ts
+}
+
+// NewSegment - continue writing to w, starting with PAT and PMT so that the output can be decoded
+// from this point. Continuity counters continue across segments.
+func (m *Muxer) NewSegment(w io.Writer) error {
+	m.w = w
+	return m.WritePSI()
+}
+
+// WritePSI - write PAT and PMT
+func (m *Muxer) WritePSI() error {
+	if len(m.streams) == 0 {
+		return fmt.Errorf("no tracks added")
+	}
+	p := pmt{programNumber: ProgramNumber, pcrPID: m.pcrStream.pid}
+	for _, ms := range m.streams {
+		p.streams = append(p.streams, pmtStream{streamType: ms.streamType, pid: ms.pid})
+	}
+	m.buf = appendPackets(m.buf[:0], PIDPAT, &m.patCC, append([]byte{0}, createPAT(1, ProgramNumber, PIDPMT)...), -1, false)
+	m.buf = appendPackets(m.buf, PIDPMT, &m.pmtCC, append([]byte{0}, p.encode()...), -1, false)
+	m.started = true
+	m.psiWritten = true
+	_, err := m.w.Write(m.buf)
+	return err
+}
+
+// WriteSample - write sample of track as a PES packet. PAT and PMT are written before the first sample,
+// and before every video sync sample of the PCR stream.
+func (m *Muxer) WriteSample(trackID uint32, s mp4.FullSample) error {
+	var ms *muxStream
+	for _, st := range m.streams {
+		if st.trackID == trackID {
+			ms = st
+			break
+		}
+	}
+	if ms == nil {
+		return fmt.Errorf("track %d not added", trackID)
+	}
+	isSync := s.IsSync()
+	if !m.started || (ms == m.pcrStream && ms.isVideo() && isSync && !m.psiWritten) {
+		if err := m.WritePSI(); err != nil {
+			return err
+		}
+	}
+	data, err := ms.pesData(s, isSync)
+	if err != nil {
+		return fmt.Errorf("track %d: %w", trackID, err)
+	}
+	dts := m.TimestampOffset + ms.ticks(int64(s.DecodeTime))
+	pts := m.TimestampOffset + ms.ticks(int64(s.DecodeTime)+int64(s.CompositionTimeOffset))
+	pcr := int64(-1)
+	if ms == m.pcrStream {
+		pcr = (dts - m.PCRDelay) * 300
+		if pcr < 0 {
+			pcr = 0
+		}
+	}
+	pes := append(createPESHeader(ms.streamID, pts, dts, len(data), !ms.isVideo()), data...)
+	m.buf = appendPackets(m.buf[:0], ms.pid, &ms.cc, pes, pcr, isSync)
+	m.psiWritten = false
+	_, err = m.w.Write(m.buf)
+	return err
+}
+
+// pesData - sample data converted to the elementary stream format
+func (ms *muxStream) pesData(s mp4.FullSample, isSync bool) ([]byte, error) {
+	switch ms.codec {
+	case CodecAVC, CodecHEVC:
+		return ms.byteStream(s.Data, isSync)
+	case CodecAAC:
+		var buf bytes.Buffer
+		if err := adts.Export(&buf, ms.asc, []mp4.FullSample{s}); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	return s.Data, nil
+}
+
+// byteStream - Annex B byte stream starting with an access unit delimiter, and with the
+// parameter sets from the sample description before sync samples without parameter sets
+func (ms *muxStream) byteStream(sample []byte, isSync bool) ([]byte, error) {
+	nalus, err := avc.GetNalusFromSampleWithLengthSize(sample, ms.lengthSize)
+	if err != nil {
+		return nil, err
+	}
+	aud := avcAUD
+	hasPS := avc.HasParameterSetsWithLengthSize(sample, ms.lengthSize)
+	isAUD := func(nalu []byte) bool { return avc.GetNaluType(nalu[0]) == avc.NALU_AUD }
+	if ms.codec == CodecHEVC {
+		aud = hevcAUD
+		hasPS = hevc.HasParameterSetsWithLengthSize(sample, ms.lengthSize)
+		isAUD = func(nalu []byte) bool { return hevc.GetNaluType(nalu[0]) == hevc.NALU_AUD }
+	}
+	if len(nalus) > 0 && len(nalus[0]) > 0 && isAUD(nalus[0]) {
+		aud = nalus[0]
+		nalus = nalus[1:]
+	}
+	out := make([]byte, 0, len(sample)+64)
+	startCode := []byte{0, 0, 0, 1}
+	out = append(append(out, startCode...), aud...)
+	if isSync && !hasPS {
+		for _, ps := range ms.psNalus {
+			out = append(append(out, startCode...), ps...)
+		}
+	}
+	for _, nalu := range nalus {
+		out = append(append(out, startCode...), nalu...)
+	}
+	return out, nil
+}
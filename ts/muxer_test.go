@@ -0,0 +1,164 @@
+package ts
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/jaypadia-frame/mp4ff/avc"
+	"github.com/jaypadia-frame/mp4ff/mp4"
+)
+
+func TestAppendPackets(t *testing.T) {
+	payload := make([]byte, 500)
+	for i := range payload {
+		payload[i] = byte(i)
+	}
+	testCases := []struct {
+		size         int
+		pcr          int64
+		randomAccess bool
+		wantPackets  int
+	}{
+		{184, -1, false, 1},
+		{183, -1, false, 1},
+		{182, -1, false, 1},
+		{185, -1, false, 2},
+		{500, 1234567*300 + 299, true, 3},
+		{176, 0, false, 1},
+		{177, 0, false, 2},
+	}
+	for _, tc := range testCases {
+		cc := byte(14)
+		buf := appendPackets(nil, 0x123, &cc, payload[:tc.size], tc.pcr, tc.randomAccess)
+		if len(buf) != tc.wantPackets*PacketSize {
+			t.Errorf("size %d: got %d bytes instead of %d packets", tc.size, len(buf), tc.wantPackets)
+			continue
+		}
+		var got []byte
+		for i := 0; i < tc.wantPackets; i++ {
+			p, err := parsePacket(buf[i*PacketSize : (i+1)*PacketSize])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.pid != 0x123 || p.pusi != (i == 0) || p.cc != byte(14+i)&0x0f {
+				t.Errorf("size %d packet %d: got pid %d pusi %t cc %d", tc.size, i, p.pid, p.pusi, p.cc)
+			}
+			if i == 0 && (p.pcr != tc.pcr || p.randomAccess != tc.randomAccess) {
+				t.Errorf("size %d: got pcr %d random access %t", tc.size, p.pcr, p.randomAccess)
+			}
+			got = append(got, p.payload...)
+		}
+		if !bytes.Equal(got, payload[:tc.size]) {
+			t.Errorf("size %d: payload differs", tc.size)
+		}
+	}
+}
+
+func TestWriteSegments(t *testing.T) {
+	fh, err := os.Open("../mp4/testdata/prog_8s.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fh.Close()
+	f, err := mp4.DecodeFile(fh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var segments []*bytes.Buffer
+	err = WriteSegments(f, 2*Timescale, func(nr int) (io.Writer, error) {
+		segments = append(segments, &bytes.Buffer{})
+		return segments[nr-1], nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(segments) != 4 {
+		t.Fatalf("got %d segments instead of 4", len(segments))
+	}
+	var all []byte
+	for i, seg := range segments {
+		data := seg.Bytes()
+		p, err := parsePacket(data[:PacketSize])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.pid != PIDPAT {
+			t.Errorf("segment %d: starts with PID %d", i+1, p.pid)
+		}
+		d := NewDemuxer()
+		if err := d.Demux(bytes.NewReader(data)); err != nil {
+			t.Fatal(err)
+		}
+		if len(d.Streams) != 2 || len(d.Streams[1].Samples) == 0 || !d.Streams[1].Samples[0].IsSync() {
+			t.Errorf("segment %d: does not start with video sync sample", i+1)
+		}
+		all = append(all, data...)
+	}
+
+	d := NewDemuxer()
+	if err := d.Demux(bytes.NewReader(all)); err != nil {
+		t.Fatal(err)
+	}
+	if d.ContinuityErrors != 0 || len(d.Warnings) != 0 {
+		t.Errorf("got %d continuity errors and warnings %v", d.ContinuityErrors, d.Warnings)
+	}
+	if d.Streams[0].Codec != CodecAAC || d.Streams[1].Codec != CodecAVC {
+		t.Fatalf("got codecs %s %s", d.Streams[0].Codec, d.Streams[1].Codec)
+	}
+	for i, trak := range f.Moov.Traks {
+		orig, err := f.GetTrackSamples(trak.Tkhd.TrackID, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := d.Streams[i].Samples
+		if len(got) != len(orig) {
+			t.Errorf("track %d: got %d samples instead of %d", i+1, len(got), len(orig))
+			continue
+		}
+		timescale := int64(trak.Mdia.Mdhd.Timescale)
+		for j, s := range got {
+			o := orig[j]
+			wantTime := uint64(126000 + (int64(o.DecodeTime)*Timescale+timescale/2)/timescale)
+			if s.DecodeTime != wantTime {
+				t.Errorf("track %d sample %d: got time %d instead of %d", i+1, j+1, s.DecodeTime, wantTime)
+				break
+			}
+			if i == 0 && !bytes.Equal(s.Data, o.Data) {
+				t.Errorf("track %d sample %d: data differs", i+1, j+1)
+				break
+			}
+			if i == 1 {
+				// AUD, and parameter sets before sync samples, are added in front of the original NAL units
+				types := avc.FindNaluTypes(s.Data)
+				if !bytes.HasSuffix(s.Data, o.Data) || types[0] != avc.NALU_AUD ||
+					(o.IsSync() && types[1] != avc.NALU_SPS) || s.IsSync() != o.IsSync() {
+					t.Errorf("track %d sample %d: got NAL unit types %v", i+1, j+1, types)
+					break
+				}
+				if s.CompositionTimeOffset != o.CompositionTimeOffset {
+					t.Errorf("track %d sample %d: got cto %d", i+1, j+1, s.CompositionTimeOffset)
+					break
+				}
+			}
+		}
+	}
+
+	var nrPCRs int
+	for pos := 0; pos < len(all); pos += PacketSize {
+		p, err := parsePacket(all[pos : pos+PacketSize])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.pcr >= 0 {
+			if p.pid != PIDFirstStream+1 {
+				t.Errorf("PCR on PID %d", p.pid)
+			}
+			nrPCRs++
+		}
+	}
+	if nrPCRs != len(d.Streams[1].Samples) {
+		t.Errorf("got %d PCRs instead of one per video sample", nrPCRs)
+	}
+}
@@ -0,0 +1,135 @@
+package ts
+
+import (
+	"fmt"
+)
+
+const (
+	// PacketSize - size of a transport stream packet
+	PacketSize = 188
+	// SyncByte - first byte of every packet
+	SyncByte = 0x47
+	// PIDPAT - PID of the Program Association Table
+	PIDPAT = 0x0000
+	// PIDNull - PID of null packets
+	PIDNull = 0x1fff
+)
+
+// Stream types in PMT as defined in ISO/IEC 13818-1 Table 2-34, ATSC A/52 and SCTE 35
+const (
+	StreamTypePrivatePES = 0x06 // DVB AC-3 and E-AC-3 are signalled by descriptors
+	StreamTypeAAC        = 0x0f // ADTS
+	StreamTypeAVC        = 0x1b
+	StreamTypeHEVC       = 0x24
+	StreamTypeAC3        = 0x81 // ATSC
+	StreamTypeSCTE35     = 0x86
+	StreamTypeEAC3       = 0x87 // ATSC
+)
+
+// packet - parsed transport stream packet
+type packet struct {
+	pid           uint16
+	pusi          bool // payload_unit_start_indicator
+	cc            byte // continuity_counter
+	hasPayload    bool
+	discontinuity bool  // discontinuity_indicator
+	randomAccess  bool  // random_access_indicator
+	pcr           int64 // 27 MHz, or -1 if not present
+	payload       []byte
+}
+
+// parsePacket - parse header and adaptation field of a 188-byte packet
+func parsePacket(data []byte) (*packet, error) {
+	if len(data) != PacketSize {
+		return nil, fmt.Errorf("packet size %d instead of %d", len(data), PacketSize)
+	}
+	if data[0] != SyncByte {
+		return nil, fmt.Errorf("sync byte 0x%02x instead of 0x47", data[0])
+	}
+	if data[1]&0x80 != 0 {
+		return nil, fmt.Errorf("transport_error_indicator set")
+	}
+	p := &packet{
+		pid:  uint16(data[1]&0x1f)<<8 | uint16(data[2]),
+		pusi: data[1]&0x40 != 0,
+		cc:   data[3] & 0x0f,
+		pcr:  -1,
+	}
+	adaptationFieldControl := (data[3] >> 4) & 0x03
+	p.hasPayload = adaptationFieldControl&0x01 != 0
+	pos := 4
+	if adaptationFieldControl&0x02 != 0 {
+		afLen := int(data[4])
+		pos = 5 + afLen
+		if pos > PacketSize {
+			return nil, fmt.Errorf("adaptation_field_length %d too big", afLen)
+		}
+		if afLen > 0 {
+			flags := data[5]
+			p.discontinuity = flags&0x80 != 0
+			p.randomAccess = flags&0x40 != 0
+			if flags&0x10 != 0 && afLen >= 7 {
+				base := int64(data[6])<<25 | int64(data[7])<<17 | int64(data[8])<<9 | int64(data[9])<<1 | int64(data[10]>>7)
+				ext := int64(data[10]&0x01)<<8 | int64(data[11])
+				p.pcr = base*300 + ext
+			}
+		}
+	}
+	if p.hasPayload {
+		p.payload = data[pos:]
+	}
+	return p, nil
+}
+
+// appendPackets - packetize payload on pid and append the packets to buf.
+// The first packet has payload_unit_start_indicator set, and an adaptation field with a PCR if pcr >= 0,
+// and random_access_indicator set if randomAccess. The last packet is filled up with adaptation field stuffing.
+func appendPackets(buf []byte, pid uint16, cc *byte, payload []byte, pcr int64, randomAccess bool) []byte {
+	for first := true; first || len(payload) > 0; first = false {
+		hdr := [4]byte{SyncByte, byte(pid>>8) & 0x1f, byte(pid), 0x10 | *cc}
+		*cc = (*cc + 1) & 0x0f
+		if first {
+			hdr[1] |= 0x40
+		}
+		var af []byte // adaptation field after adaptation_field_length
+		if first && (pcr >= 0 || randomAccess) {
+			flags := byte(0)
+			if randomAccess {
+				flags |= 0x40
+			}
+			af = append(af, flags)
+			if pcr >= 0 {
+				af[0] |= 0x10
+				base, ext := pcr/300, pcr%300
+				af = append(af, byte(base>>25), byte(base>>17), byte(base>>9), byte(base>>1),
+					byte(base<<7)|0x7e|byte(ext>>8), byte(ext))
+			}
+		}
+		space := PacketSize - 4
+		if af != nil {
+			space -= 1 + len(af)
+		}
+		n := len(payload)
+		if n > space {
+			n = space
+		}
+		afTotal := PacketSize - 4 - n // Including adaptation_field_length
+		buf = append(buf, hdr[:]...)
+		if afTotal > 0 {
+			buf[len(buf)-1] |= 0x20
+			buf = append(buf, byte(afTotal-1))
+			if afTotal > 1 {
+				if af == nil {
+					af = []byte{0}
+				}
+				buf = append(buf, af...)
+				for i := 1 + len(af); i < afTotal; i++ {
+					buf = append(buf, 0xff)
+				}
+			}
+		}
+		buf = append(buf, payload[:n]...)
+		payload = payload[n:]
+	}
+	return buf
+}
@@ -0,0 +1,115 @@
+package ts
+
+import (
+	"fmt"
+)
+
+const (
+	ptsWrap = 1 << 33 // PTS and DTS have 33 bits
+	// pesStreamIDs without the optional PES header (ISO/IEC 13818-1 Table 2-22)
+	streamIDProgramStreamMap = 0xbc
+	streamIDPadding          = 0xbe
+	streamIDPrivate2         = 0xbf
+	streamIDECM              = 0xf0
+	streamIDEMM              = 0xf1
+	streamIDDirectory        = 0xff
+	streamIDDSMCC            = 0xf2
+	streamIDH2221E           = 0xf8
+)
+
+// pesPacket - parsed PES packet
+type pesPacket struct {
+	streamID byte
+	pts      int64 // -1 if not present
+	dts      int64 // Equal to pts if not present
+	data     []byte
+}
+
+// parsePES - parse a complete PES packet
+func parsePES(data []byte) (*pesPacket, error) {
+	if len(data) < 6 || data[0] != 0 || data[1] != 0 || data[2] != 1 {
+		return nil, fmt.Errorf("no PES start code")
+	}
+	p := &pesPacket{streamID: data[3], pts: -1, dts: -1}
+	pesPacketLength := int(data[4])<<8 | int(data[5])
+	end := len(data)
+	if pesPacketLength != 0 {
+		end = 6 + pesPacketLength
+		if end > len(data) {
+			return nil, fmt.Errorf("PES packet truncated: %d of %d bytes", len(data)-6, pesPacketLength)
+		}
+	}
+	switch p.streamID {
+	case streamIDProgramStreamMap, streamIDPadding, streamIDPrivate2, streamIDECM, streamIDEMM,
+		streamIDDirectory, streamIDDSMCC, streamIDH2221E:
+		p.data = data[6:end]
+		return p, nil
+	}
+	if end < 9 {
+		return nil, fmt.Errorf("PES header too short")
+	}
+	ptsDTSFlags := data[7] >> 6
+	headerEnd := 9 + int(data[8])
+	if headerEnd > end {
+		return nil, fmt.Errorf("PES_header_data_length %d too big", data[8])
+	}
+	switch ptsDTSFlags {
+	case 2:
+		if headerEnd < 14 {
+			return nil, fmt.Errorf("PES header too short for PTS")
+		}
+		p.pts = parseTimestamp(data[9:14])
+	case 3:
+		if headerEnd < 19 {
+			return nil, fmt.Errorf("PES header too short for PTS and DTS")
+		}
+		p.pts = parseTimestamp(data[9:14])
+		p.dts = parseTimestamp(data[14:19])
+	}
+	if p.dts < 0 {
+		p.dts = p.pts
+	}
+	p.data = data[headerEnd:end]
+	return p, nil
+}
+
+// parseTimestamp - 33-bit PTS or DTS with marker bits in 5 bytes
+func parseTimestamp(b []byte) int64 {
+	return int64(b[0]>>1&0x07)<<30 | int64(b[1])<<22 | int64(b[2]>>1)<<15 | int64(b[3])<<7 | int64(b[4]>>1)
+}
+
+// unwrapTimestamp - the value congruent to ts modulo 2^33 closest to ref
+func unwrapTimestamp(ts, ref int64) int64 {
+	diff := (ts - ref) % ptsWrap
+	switch {
+	case diff > ptsWrap/2:
+		diff -= ptsWrap
+	case diff < -ptsWrap/2:
+		diff += ptsWrap
+	}
+	return ref + diff
+}
+
+// encodeTimestamp - 33-bit PTS or DTS with 4-bit prefix and marker bits in 5 bytes
+func encodeTimestamp(prefix byte, ts int64) []byte {
+	ts &= ptsWrap - 1
+	return []byte{prefix<<4 | byte(ts>>29)&0x0e | 0x01, byte(ts >> 22), byte(ts>>14) | 0x01, byte(ts >> 7), byte(ts<<1) | 0x01}
+}
+
+// createPESHeader - PES header with PTS, and DTS if different from PTS.
+// PES_packet_length is 0 (unbounded) if not bounded, or if the packet is too big.
+func createPESHeader(streamID byte, pts, dts int64, dataLen int, bounded bool) []byte {
+	hdr := make([]byte, 9, 19)
+	copy(hdr, []byte{0, 0, 1, streamID, 0, 0, 0x80, 0x80, 5})
+	if dts != pts {
+		hdr[7], hdr[8] = 0xc0, 10
+		hdr = append(hdr, encodeTimestamp(3, pts)...)
+		hdr = append(hdr, encodeTimestamp(1, dts)...)
+	} else {
+		hdr = append(hdr, encodeTimestamp(2, pts)...)
+	}
+	if pesPacketLength := len(hdr) - 6 + dataLen; bounded && pesPacketLength <= 0xffff {
+		hdr[4], hdr[5] = byte(pesPacketLength>>8), byte(pesPacketLength)
+	}
+	return hdr
+}
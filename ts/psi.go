@@ -0,0 +1,231 @@
+package ts
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+const (
+	tableIDPAT    = 0x00
+	tableIDPMT    = 0x02
+	tableIDSCTE35 = 0xfc
+)
+
+var crcTable = makeCRCTable()
+
+// makeCRCTable - table for the MSB-first CRC-32 with polynomial 0x04c11db7 used in PSI sections
+func makeCRCTable() [256]uint32 {
+	var table [256]uint32
+	for i := range table {
+		crc := uint32(i) << 24
+		for j := 0; j < 8; j++ {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04c11db7
+			} else {
+				crc <<= 1
+			}
+		}
+		table[i] = crc
+	}
+	return table
+}
+
+// crc32MPEG2 - CRC_32 of section data (Annex A of ISO/IEC 13818-1). It is 0 over a section including its CRC
+func crc32MPEG2(data []byte) uint32 {
+	crc := uint32(0xffffffff)
+	for _, b := range data {
+		crc = crc<<8 ^ crcTable[byte(crc>>24)^b]
+	}
+	return crc
+}
+
+// sectionBuffer - reassembles PSI sections from packet payloads of one PID
+type sectionBuffer struct {
+	buf     []byte
+	started bool
+}
+
+// add - add packet payload and return complete sections
+func (s *sectionBuffer) add(payload []byte, pusi bool) ([][]byte, error) {
+	if pusi {
+		if len(payload) == 0 || int(payload[0]) >= len(payload) {
+			return nil, fmt.Errorf("bad pointer_field")
+		}
+		pointer := int(payload[0])
+		if s.started {
+			s.buf = append(s.buf, payload[1:1+pointer]...)
+		}
+		sections := s.complete()
+		s.buf = append(s.buf[:0], payload[1+pointer:]...)
+		s.started = true
+		return append(sections, s.complete()...), nil
+	}
+	if !s.started {
+		return nil, nil
+	}
+	s.buf = append(s.buf, payload...)
+	return s.complete(), nil
+}
+
+// reset - drop incomplete section
+func (s *sectionBuffer) reset() {
+	s.buf = s.buf[:0]
+	s.started = false
+}
+
+// complete - remove and return complete sections at the start of the buffer
+func (s *sectionBuffer) complete() [][]byte {
+	var sections [][]byte
+	for len(s.buf) >= 3 {
+		if s.buf[0] == 0xff { // Stuffing until next section start
+			s.reset()
+			break
+		}
+		total := 3 + int(binary.BigEndian.Uint16(s.buf[1:3])&0x0fff)
+		if len(s.buf) < total {
+			break
+		}
+		section := make([]byte, total)
+		copy(section, s.buf)
+		sections = append(sections, section)
+		s.buf = s.buf[total:]
+	}
+	return sections
+}
+
+// checkSection - check table_id, length and CRC of a long section, and return the data after the header
+func checkSection(section []byte, tableID byte) ([]byte, error) {
+	if section[0] != tableID {
+		return nil, fmt.Errorf("table_id %d instead of %d", section[0], tableID)
+	}
+	if len(section) < 12 {
+		return nil, fmt.Errorf("section too short")
+	}
+	if crc32MPEG2(section) != 0 {
+		return nil, fmt.Errorf("CRC error in section with table_id %d", tableID)
+	}
+	return section[8 : len(section)-4], nil
+}
+
+// parsePAT - PMT PIDs per program_number in a PAT section. Program 0 (network PID) is skipped
+func parsePAT(section []byte) (map[uint16]uint16, error) {
+	data, err := checkSection(section, tableIDPAT)
+	if err != nil {
+		return nil, err
+	}
+	programs := make(map[uint16]uint16)
+	for i := 0; i+4 <= len(data); i += 4 {
+		programNumber := binary.BigEndian.Uint16(data[i:])
+		pid := binary.BigEndian.Uint16(data[i+2:]) & 0x1fff
+		if programNumber != 0 {
+			programs[programNumber] = pid
+		}
+	}
+	return programs, nil
+}
+
+// descriptor - tag and data of a descriptor
+type descriptor struct {
+	tag  byte
+	data []byte
+}
+
+// pmtStream - elementary stream entry in PMT
+type pmtStream struct {
+	streamType  byte
+	pid         uint16
+	descriptors []descriptor
+}
+
+// pmt - the parts of a PMT section that are used
+type pmt struct {
+	programNumber uint16
+	pcrPID        uint16
+	streams       []pmtStream
+}
+
+// parsePMT - parse a PMT section
+func parsePMT(section []byte) (*pmt, error) {
+	data, err := checkSection(section, tableIDPMT)
+	if err != nil {
+		return nil, err
+	}
+	p := &pmt{programNumber: binary.BigEndian.Uint16(section[3:5])}
+	if len(data) < 4 {
+		return nil, fmt.Errorf("PMT too short")
+	}
+	p.pcrPID = binary.BigEndian.Uint16(data[0:2]) & 0x1fff
+	programInfoLength := int(binary.BigEndian.Uint16(data[2:4]) & 0x0fff)
+	pos := 4 + programInfoLength
+	for pos+5 <= len(data) {
+		s := pmtStream{
+			streamType: data[pos],
+			pid:        binary.BigEndian.Uint16(data[pos+1:]) & 0x1fff,
+		}
+		esInfoLength := int(binary.BigEndian.Uint16(data[pos+3:]) & 0x0fff)
+		pos += 5
+		if pos+esInfoLength > len(data) {
+			return nil, fmt.Errorf("ES_info_length %d too big", esInfoLength)
+		}
+		s.descriptors = parseDescriptors(data[pos : pos+esInfoLength])
+		pos += esInfoLength
+		p.streams = append(p.streams, s)
+	}
+	return p, nil
+}
+
+func parseDescriptors(data []byte) []descriptor {
+	var descs []descriptor
+	for pos := 0; pos+2 <= len(data); {
+		length := int(data[pos+1])
+		if pos+2+length > len(data) {
+			break
+		}
+		descs = append(descs, descriptor{tag: data[pos], data: data[pos+2 : pos+2+length]})
+		pos += 2 + length
+	}
+	return descs
+}
+
+// createLongSection - section with syntax header for version 0, a single section, and CRC_32
+func createLongSection(tableID byte, tableIDExtension uint16, body []byte) []byte {
+	sectionLength := 5 + len(body) + 4
+	s := make([]byte, 8, 3+sectionLength)
+	s[0] = tableID
+	binary.BigEndian.PutUint16(s[1:3], 0xb000|uint16(sectionLength))
+	binary.BigEndian.PutUint16(s[3:5], tableIDExtension)
+	s[5] = 0xc1 // version_number 0 and current_next_indicator 1
+	s = append(s, body...)
+	return appendCRC(s)
+}
+
+// appendCRC - append CRC_32 of section
+func appendCRC(section []byte) []byte {
+	crc := crc32MPEG2(section)
+	return append(section, byte(crc>>24), byte(crc>>16), byte(crc>>8), byte(crc))
+}
+
+// createPAT - PAT section with one program
+func createPAT(transportStreamID, programNumber, pmtPID uint16) []byte {
+	body := make([]byte, 4)
+	binary.BigEndian.PutUint16(body[0:2], programNumber)
+	binary.BigEndian.PutUint16(body[2:4], 0xe000|pmtPID)
+	return createLongSection(tableIDPAT, transportStreamID, body)
+}
+
+// encode - PMT section
+func (p *pmt) encode() []byte {
+	body := make([]byte, 4)
+	binary.BigEndian.PutUint16(body[0:2], 0xe000|p.pcrPID)
+	binary.BigEndian.PutUint16(body[2:4], 0xf000) // No program descriptors
+	for _, s := range p.streams {
+		var descs []byte
+		for _, d := range s.descriptors {
+			descs = append(descs, d.tag, byte(len(d.data)))
+			descs = append(descs, d.data...)
+		}
+		body = append(body, s.streamType, 0xe0|byte(s.pid>>8), byte(s.pid), 0xf0|byte(len(descs)>>8), byte(len(descs)))
+		body = append(body, descs...)
+	}
+	return createLongSection(tableIDPMT, p.programNumber, body)
+}